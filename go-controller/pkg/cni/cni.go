@@ -74,6 +74,10 @@ func extractPodBandwidth(podAnnotations map[string]string, dir direction) (int64
 }
 
 func (pr *PodRequest) String() string {
+	if pr.requestID != "" {
+		return fmt.Sprintf("[%s/%s %s network %s NAD %s request %s]", pr.PodNamespace, pr.PodName, pr.SandboxID, pr.netName,
+			pr.nadName, pr.requestID)
+	}
 	return fmt.Sprintf("[%s/%s %s network %s NAD %s]", pr.PodNamespace, pr.PodName, pr.SandboxID, pr.netName, pr.nadName)
 }
 
@@ -124,8 +124,9 @@ func cniRequestToPodRequest(cr *Request, vsClient libovsdbclient.Client) (*PodRe
 	}
 
 	req := &PodRequest{
-		Command:  command(cmd),
-		vsClient: vsClient,
+		Command:   command(cmd),
+		requestID: cr.ID,
+		vsClient:  vsClient,
 	}
 
 	req.SandboxID, ok = cr.Env["CNI_CONTAINERID"]
@@ -196,8 +197,7 @@ func cniRequestToPodRequest(cr *Request, vsClient libovsdbclient.Client) (*PodRe
 
 	req.CNIConf = conf
 	req.timestamp = time.Now()
-	// Match the Kubelet default CRI operation timeout of 2m
-	req.ctx, req.cancel = context.WithTimeout(context.Background(), 2*time.Minute)
+	req.ctx, req.cancel = context.WithTimeout(context.Background(), config.CNIRequestTimeout)
 	return req, nil
 }
 
@@ -231,6 +231,7 @@ func (s *Server) handleCNIMetrics(w http.ResponseWriter, r *http.Request) {
 		klog.Warningf("Failed to unmarshal JSON (%s) to CNIRequestMetrics struct: %v",
 			string(b), err)
 	} else {
+		klog.V(5).Infof("CNI %s request %s took %f seconds, error %t", cm.Command, cm.RequestID, cm.ElapsedTime, cm.HasErr)
 		hasErr := fmt.Sprintf("%t", cm.HasErr)
 		metrics.MetricCNIRequestDuration.WithLabelValues(string(cm.Command), hasErr).Observe(cm.ElapsedTime)
 	}
@@ -15,12 +15,14 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/containernetworking/cni/pkg/skel"
 	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/google/uuid"
 	libovsdbclient "github.com/ovn-org/libovsdb/client"
 	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 	kapi "k8s.io/api/core/v1"
@@ -63,9 +65,16 @@ func newCNIRequest(args *skel.CmdArgs) *Request {
 	return &Request{
 		Env:    envMap,
 		Config: args.StdinData,
+		ID:     uuid.NewString(),
 	}
 }
 
+// Returns a logger adding the ID of the given request to its log lines, so the
+// shim logs of a request can be correlated with the server ones
+func requestLogger(req *Request) klog.Logger {
+	return klog.LoggerWithValues(klog.Background(), "requestID", req.ID)
+}
+
 // Send a CNI request to the CNI server via JSON + HTTP over a root-owned unix socket,
 // and return the result
 func (p *Plugin) doCNI(url string, req interface{}) ([]byte, error) {
@@ -100,13 +109,36 @@ func (p *Plugin) doCNI(url string, req interface{}) ([]byte, error) {
 	return body, nil
 }
 
-func setupLogging(conf *ovntypes.NetConf) {
+// cniArgLogLevel is the CNI_ARGS key overriding the NetConf log level for a
+// single invocation of the shim, so that the requests of a specific pod can be
+// traced without changing the CNI configuration of the node.
+const cniArgLogLevel = "OVN_K8S_LOG_LEVEL"
+
+// logLevelFor returns the klog level of the shim invocation with the given
+// environment: the level set in its CNI_ARGS if valid, the NetConf one otherwise.
+func logLevelFor(conf *ovntypes.NetConf, env map[string]string) string {
+	cniArgs, err := gatherCNIArgs(env)
+	if err != nil {
+		return conf.LogLevel
+	}
+	logLevel, ok := cniArgs[cniArgLogLevel]
+	if !ok {
+		return conf.LogLevel
+	}
+	if level, err := strconv.ParseInt(logLevel, 10, 32); err != nil || level < 0 {
+		klog.Warningf("Ignoring invalid %s %q, using log level %q", cniArgLogLevel, logLevel, conf.LogLevel)
+		return conf.LogLevel
+	}
+	return logLevel
+}
+
+func setupLogging(conf *ovntypes.NetConf, env map[string]string) {
 	var err error
 	var level klog.Level
 
-	if conf.LogLevel != "" {
-		if err = level.Set(conf.LogLevel); err != nil {
-			klog.Warningf("Failed to set klog log level to %s: %v", conf.LogLevel, err)
+	if logLevel := logLevelFor(conf, env); logLevel != "" {
+		if err = level.Set(logLevel); err != nil {
+			klog.Warningf("Failed to set klog log level to %s: %v", logLevel, err)
 		}
 	}
 	if conf.LogFile != "" {
@@ -118,23 +150,57 @@ func setupLogging(conf *ovntypes.NetConf) {
 		if err := klogFlags.Set("alsologtostderr", "true"); err != nil {
 			klog.Warningf("Error setting klog alsologtostderr: %v", err)
 		}
-		klog.SetOutput(&lumberjack.Logger{
-			Filename:   conf.LogFile,
-			MaxSize:    conf.LogFileMaxSize, // megabytes
-			MaxBackups: conf.LogFileMaxBackups,
-			MaxAge:     conf.LogFileMaxAge, // days
-			Compress:   true,
-		})
+		klog.SetOutput(logFileRotatorFor(conf))
+	}
+}
+
+// Defaults of the CNI log file rotation, applied in place of invalid values
+const (
+	defaultLogFileMaxSize    = 100 // megabytes
+	defaultLogFileMaxBackups = 5
+	defaultLogFileMaxAge     = 5 // days
+)
+
+// logFileRotatorFor returns the rotating logger of the NetConf log file.
+// Non-positive sizes and negative retentions are replaced by the defaults, and
+// so is the number of backups when neither backups nor age would ever remove
+// old log files, which would grow without bound on the node.
+func logFileRotatorFor(conf *ovntypes.NetConf) *lumberjack.Logger {
+	maxSize, maxBackups, maxAge := conf.LogFileMaxSize, conf.LogFileMaxBackups, conf.LogFileMaxAge
+	if maxSize <= 0 {
+		klog.Warningf("Invalid log file max size %d, using %d megabytes", maxSize, defaultLogFileMaxSize)
+		maxSize = defaultLogFileMaxSize
+	}
+	if maxBackups < 0 {
+		klog.Warningf("Invalid log file max backups %d, using %d", maxBackups, defaultLogFileMaxBackups)
+		maxBackups = defaultLogFileMaxBackups
+	}
+	if maxAge < 0 {
+		klog.Warningf("Invalid log file max age %d, using %d days", maxAge, defaultLogFileMaxAge)
+		maxAge = defaultLogFileMaxAge
+	}
+	if maxBackups == 0 && maxAge == 0 {
+		klog.Warningf("Log file max backups and max age are both unset which disables the removal of rotated "+
+			"log files, keeping %d backups", defaultLogFileMaxBackups)
+		maxBackups = defaultLogFileMaxBackups
+	}
+	return &lumberjack.Logger{
+		Filename:   conf.LogFile,
+		MaxSize:    maxSize, // megabytes
+		MaxBackups: maxBackups,
+		MaxAge:     maxAge, // days
+		Compress:   true,
 	}
 }
 
 // report the CNI request processing time to CNI server. This is used for the cni_request_duration_seconds metrics
-func (p *Plugin) postMetrics(startTime time.Time, cmd command, err error) {
+func (p *Plugin) postMetrics(startTime time.Time, cmd command, requestID string, err error) {
 	elapsedTime := time.Since(startTime).Seconds()
 	_, _ = p.doCNI("http://dummy/metrics", &CNIRequestMetrics{
 		Command:     cmd,
 		ElapsedTime: elapsedTime,
 		HasErr:      err != nil,
+		RequestID:   requestID,
 	})
 }
 
@@ -188,7 +254,12 @@ func initPodRequestUnprivilegedMode(req *Request, isDPUHostMode bool) (*PodReque
 		}
 	}
 
-	vsClient, err := libovsdb.NewVSwitchClient(make(chan struct{}))
+	conf, err := config.ReadCNIConfig(req.Config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stdin args: %w", err)
+	}
+
+	vsClient, err = libovsdb.NewVSwitchClient(make(chan struct{}), time.Duration(conf.OVSTransactionTimeout)*time.Second)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create vswitchd database client: %w", err)
 	}
@@ -201,41 +272,42 @@ func initPodRequestUnprivilegedMode(req *Request, isDPUHostMode bool) (*PodReque
 	return pr, nil
 }
 
-func (p *Plugin) cmdCommon(args *skel.CmdArgs, detail string) (*Response, *Request, string, error) {
+func (p *Plugin) cmdCommon(req *Request, detail string) (*Response, string, error) {
 	// read the config stdin args to obtain cniVersion
-	conf, err := config.ReadCNIConfig(args.StdinData)
+	conf, err := config.ReadCNIConfig(req.Config)
 	if err != nil {
-		return nil, nil, "", fmt.Errorf("%s: invalid stdin args %w", detail, err)
+		return nil, "", fmt.Errorf("%s: invalid stdin args %w", detail, err)
 	}
-	setupLogging(conf)
+	setupLogging(conf, req.Env)
 
-	req := newCNIRequest(args)
 	body, err := p.doCNI("http://dummy/", req)
 	if err != nil {
-		return nil, nil, "", err
+		return nil, "", err
 	}
 
 	response := &Response{}
 	if err = json.Unmarshal(body, response); err != nil {
-		return nil, nil, "", fmt.Errorf("%s: failed to unmarshal response '%s': %v", detail, string(body), err)
+		return nil, "", fmt.Errorf("%s: failed to unmarshal response '%s': %v", detail, string(body), err)
 	}
 
-	return response, req, conf.CNIVersion, nil
+	return response, conf.CNIVersion, nil
 }
 
 // CmdAdd is the callback for 'add' cni calls from skel
 func (p *Plugin) CmdAdd(args *skel.CmdArgs) error {
 	var err, errR error
 
+	req := newCNIRequest(args)
+	logger := requestLogger(req)
 	startTime := time.Now()
 	defer func() {
-		p.postMetrics(startTime, CNIAdd, err)
+		p.postMetrics(startTime, CNIAdd, req.ID, err)
 		if err != nil {
-			klog.Errorf(err.Error())
+			logger.Error(err, "CNI request failed", "command", CNIAdd)
 		}
 	}()
 
-	response, req, cniVersion, errC := p.cmdCommon(args, "ADD")
+	response, cniVersion, errC := p.cmdCommon(req, "ADD")
 	if err != nil {
 		err = errC
 		return err
@@ -278,15 +350,17 @@ func (p *Plugin) CmdAdd(args *skel.CmdArgs) error {
 func (p *Plugin) CmdDel(args *skel.CmdArgs) error {
 	var err error
 
+	req := newCNIRequest(args)
+	logger := requestLogger(req)
 	startTime := time.Now()
 	defer func() {
-		p.postMetrics(startTime, CNIDel, err)
+		p.postMetrics(startTime, CNIDel, req.ID, err)
 		if err != nil {
-			klog.Errorf(err.Error())
+			logger.Error(err, "CNI request failed", "command", CNIDel)
 		}
 	}()
 
-	response, req, _, errC := p.cmdCommon(args, "DEL")
+	response, _, errC := p.cmdCommon(req, "DEL")
 	if err != nil {
 		err = errC
 		return err
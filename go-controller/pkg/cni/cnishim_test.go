@@ -0,0 +1,162 @@
+package cni
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"k8s.io/klog/v2"
+
+	ovntypes "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/cni/types"
+)
+
+func TestLogLevelFor(t *testing.T) {
+	conf := &ovntypes.NetConf{LogLevel: "4"}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "uses the conf level without CNI_ARGS",
+			env:  map[string]string{},
+			want: "4",
+		},
+		{
+			name: "uses the conf level without an override",
+			env:  map[string]string{"CNI_ARGS": "K8S_POD_NAMESPACE=ns;K8S_POD_NAME=pod"},
+			want: "4",
+		},
+		{
+			name: "the CNI_ARGS level overrides the conf level",
+			env:  map[string]string{"CNI_ARGS": "K8S_POD_NAMESPACE=ns;K8S_POD_NAME=pod;OVN_K8S_LOG_LEVEL=5"},
+			want: "5",
+		},
+		{
+			name: "ignores an invalid CNI_ARGS level",
+			env:  map[string]string{"CNI_ARGS": "K8S_POD_NAMESPACE=ns;K8S_POD_NAME=pod;OVN_K8S_LOG_LEVEL=debug"},
+			want: "4",
+		},
+		{
+			name: "ignores a negative CNI_ARGS level",
+			env:  map[string]string{"CNI_ARGS": "K8S_POD_NAMESPACE=ns;K8S_POD_NAME=pod;OVN_K8S_LOG_LEVEL=-1"},
+			want: "4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logLevelFor(conf, tt.env); got != tt.want {
+				t.Errorf("logLevelFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogFileRotatorFor(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxSize        int
+		maxBackups     int
+		maxAge         int
+		wantMaxSize    int
+		wantMaxBackups int
+		wantMaxAge     int
+	}{
+		{
+			name:           "keeps valid values",
+			maxSize:        10,
+			maxBackups:     2,
+			maxAge:         3,
+			wantMaxSize:    10,
+			wantMaxBackups: 2,
+			wantMaxAge:     3,
+		},
+		{
+			name:           "keeps a zero max backups bounded by the max age",
+			maxSize:        10,
+			maxAge:         3,
+			wantMaxSize:    10,
+			wantMaxBackups: 0,
+			wantMaxAge:     3,
+		},
+		{
+			name:           "keeps a zero max age bounded by the max backups",
+			maxSize:        10,
+			maxBackups:     2,
+			wantMaxSize:    10,
+			wantMaxBackups: 2,
+			wantMaxAge:     0,
+		},
+		{
+			name:           "defaults zero values",
+			wantMaxSize:    defaultLogFileMaxSize,
+			wantMaxBackups: defaultLogFileMaxBackups,
+			wantMaxAge:     0,
+		},
+		{
+			name:           "defaults negative values",
+			maxSize:        -1,
+			maxBackups:     -1,
+			maxAge:         -1,
+			wantMaxSize:    defaultLogFileMaxSize,
+			wantMaxBackups: defaultLogFileMaxBackups,
+			wantMaxAge:     defaultLogFileMaxAge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &ovntypes.NetConf{
+				LogFile:           "/var/log/ovn-kubernetes/ovn-k8s-cni-overlay.log",
+				LogFileMaxSize:    tt.maxSize,
+				LogFileMaxBackups: tt.maxBackups,
+				LogFileMaxAge:     tt.maxAge,
+			}
+			got := logFileRotatorFor(conf)
+			if got.Filename != conf.LogFile {
+				t.Errorf("logFileRotatorFor() filename = %q, want %q", got.Filename, conf.LogFile)
+			}
+			if got.MaxSize != tt.wantMaxSize || got.MaxBackups != tt.wantMaxBackups || got.MaxAge != tt.wantMaxAge {
+				t.Errorf("logFileRotatorFor() = (size %d, backups %d, age %d), want (size %d, backups %d, age %d)",
+					got.MaxSize, got.MaxBackups, got.MaxAge, tt.wantMaxSize, tt.wantMaxBackups, tt.wantMaxAge)
+			}
+		})
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	var out bytes.Buffer
+	klog.LogToStderr(false)
+	klog.SetOutput(&out)
+	t.Cleanup(func() {
+		klog.SetOutput(nil)
+		klog.LogToStderr(true)
+	})
+
+	args := &skel.CmdArgs{ContainerID: "container1", StdinData: []byte("{}")}
+	req := newCNIRequest(args)
+	if req.ID == "" {
+		t.Fatalf("newCNIRequest() did not set a request ID")
+	}
+	if other := newCNIRequest(args); other.ID == req.ID {
+		t.Errorf("newCNIRequest() returned the request ID %q for two requests", req.ID)
+	}
+
+	logger := requestLogger(req)
+	logger.Info("Sending CNI request")
+	logger.Error(fmt.Errorf("failed"), "CNI request failed")
+	klog.Flush()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("requestLogger() logged %d lines, want at least 2: %q", len(lines), out.String())
+	}
+	want := fmt.Sprintf("requestID=%q", req.ID)
+	for _, line := range lines {
+		if !strings.Contains(line, want) {
+			t.Errorf("requestLogger() line %q does not contain %s", line, want)
+		}
+	}
+}
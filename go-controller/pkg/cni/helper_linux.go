@@ -15,6 +15,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/libovsdbops"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
@@ -171,12 +172,31 @@ func setupNetwork(link netlink.Link, ifInfo *PodInterfaceInfo) error {
 			return fmt.Errorf("failed to add IP addr %s to %s: %v", ip, link.Attrs().Name, err)
 		}
 	}
-	for _, gw := range ifInfo.Gateways {
+	gateways, routes := ifInfo.Gateways, ifInfo.Routes
+	if len(gateways) == 0 {
+		// without gateways, the default routes are the fallback default path
+		var err error
+		if gateways, routes, err = splitDefaultRoutes(ifInfo.Routes); err != nil {
+			return err
+		}
+	}
+	for _, gw := range gateways {
+		if isPointToPointGateway(ifInfo.IPs, gw) {
+			// with a /32 or /128 pod IP the gateway is not on-link, add an
+			// explicit route to it before the default route through it
+			gwIP := gw
+			if gw4 := gw.To4(); gw4 != nil {
+				gwIP = gw4
+			}
+			if err := cniPluginLibOps.AddRoute(&net.IPNet{IP: gwIP, Mask: util.GetIPFullMask(gw)}, nil, link, ifInfo.RoutableMTU); err != nil {
+				return fmt.Errorf("failed to add on-link route to gateway %s: %v", gw, err)
+			}
+		}
 		if err := cniPluginLibOps.AddRoute(nil, gw, link, ifInfo.RoutableMTU); err != nil {
 			return fmt.Errorf("failed to add gateway route: %v", err)
 		}
 	}
-	for _, route := range ifInfo.Routes {
+	for _, route := range routes {
 		if err := cniPluginLibOps.AddRoute(route.Dest, route.NextHop, link, ifInfo.RoutableMTU); err != nil {
 			return fmt.Errorf("failed to add pod route %v via %v: %v", route.Dest, route.NextHop, err)
 		}
@@ -185,6 +205,46 @@ func setupNetwork(link netlink.Link, ifInfo *PodInterfaceInfo) error {
 	return nil
 }
 
+// isPointToPointGateway returns true if the pod IP of the family of the given
+// gateway is a /32 or /128 address, so that the gateway is not on-link
+func isPointToPointGateway(ips []*net.IPNet, gw net.IP) bool {
+	for _, ip := range ips {
+		if utilnet.IsIPv6(ip.IP) != utilnet.IsIPv6(gw) {
+			continue
+		}
+		ones, bits := ip.Mask.Size()
+		return ones == bits && !ip.IP.Equal(gw)
+	}
+	return false
+}
+
+// splitDefaultRoutes returns the next hops of the default routes among the given
+// routes, along with the other routes. Fails if there is more than one default
+// route of an IP family.
+func splitDefaultRoutes(routes []util.PodRoute) ([]net.IP, []util.PodRoute, error) {
+	var gateways []net.IP
+	var otherRoutes []util.PodRoute
+	defaultRoutes := map[bool]util.PodRoute{}
+	for _, route := range routes {
+		if route.Dest == nil {
+			otherRoutes = append(otherRoutes, route)
+			continue
+		}
+		if ones, _ := route.Dest.Mask.Size(); ones != 0 {
+			otherRoutes = append(otherRoutes, route)
+			continue
+		}
+		isIPv6 := utilnet.IsIPv6CIDR(route.Dest)
+		if defaultRoute, ok := defaultRoutes[isIPv6]; ok {
+			return nil, nil, fmt.Errorf("failed to add default route: multiple default routes %v via %v and %v via %v",
+				defaultRoute.Dest, defaultRoute.NextHop, route.Dest, route.NextHop)
+		}
+		defaultRoutes[isIPv6] = route
+		gateways = append(gateways, route.NextHop)
+	}
+	return gateways, otherRoutes, nil
+}
+
 func setupInterface(netns ns.NetNS, containerID, ifName string, ifInfo *PodInterfaceInfo) (*current.Interface, *current.Interface, error) {
 	hostIface := &current.Interface{}
 	contIface := &current.Interface{}
@@ -580,7 +640,15 @@ func (pr *PodRequest) UnconfigureInterface(ifInfo *PodInterfaceInfo) error {
 	//    so that we know the host-side interface name.
 	ifnameSuffix := ""
 	isSecondary := pr.netName != types.DefaultNetworkName
-	if pr.CNIConf.DeviceID != "" || (isSecondary && !ifInfo.IsDPUHostMode) {
+	// On abnormal pod teardowns the container namespace, and the container interface with it, may
+	// already be gone: skip the container side teardown so that the DEL doesn't fail forever.
+	netnsGone := false
+	if _, err := os.Stat(pr.Netns); os.IsNotExist(err) {
+		klog.Warningf("Container namespace %s %s no longer exists, skipping the container interface teardown",
+			pr.Netns, podDesc)
+		netnsGone = true
+	}
+	if !netnsGone && (pr.CNIConf.DeviceID != "" || (isSecondary && !ifInfo.IsDPUHostMode)) {
 		netns, err := ns.GetNS(pr.Netns)
 		if err != nil {
 			return fmt.Errorf("failed to get container namespace %s: %v", podDesc, err)
@@ -640,8 +708,15 @@ func (pr *PodRequest) UnconfigureInterface(ifInfo *PodInterfaceInfo) error {
 	}
 
 	// host side deletion of OVS port and kernel interface
-	ifName := pr.SandboxID[:(15-len(ifnameSuffix))] + ifnameSuffix
-	pr.deletePorts(pr.vsClient, ifName, pr.PodNamespace, pr.PodName)
+	ifNames := []string{pr.SandboxID[:(15-len(ifnameSuffix))] + ifnameSuffix}
+	if netnsGone && isSecondary {
+		// the host side interface name of secondary networks is suffixed with the index of the
+		// container interface, which is gone: find the interfaces through their OVS external IDs
+		ifNames = pr.findHostInterfaceNames()
+	}
+	for _, ifName := range ifNames {
+		pr.deletePorts(pr.vsClient, ifName, pr.PodNamespace, pr.PodName)
+	}
 
 	if err := libovsdbops.ClearPortQoSBySandboxID(pr.vsClient, pr.SandboxID); err != nil {
 		klog.Warningf("Failed to clearPodBandwidth sandbox %v %s: %v", pr.SandboxID, podDesc, err)
@@ -650,6 +725,23 @@ func (pr *PodRequest) UnconfigureInterface(ifInfo *PodInterfaceInfo) error {
 	return nil
 }
 
+// findHostInterfaceNames returns the names of the OVS interfaces of the request's sandbox and NAD
+func (pr *PodRequest) findHostInterfaceNames() []string {
+	p := func(item *vswitchdb.Interface) bool {
+		return item.ExternalIDs["sandbox"] == pr.SandboxID && item.ExternalIDs[types.NADExternalID] == pr.nadName
+	}
+	ifaces, err := libovsdbops.FindInterfacesWithPredicate(pr.vsClient, p)
+	if err != nil {
+		klog.Warningf("Failed to find the OVS interfaces of sandbox %s NAD %s: %v", pr.SandboxID, pr.nadName, err)
+		return nil
+	}
+	ifNames := make([]string, 0, len(ifaces))
+	for _, iface := range ifaces {
+		ifNames = append(ifNames, iface.Name)
+	}
+	return ifNames
+}
+
 func (pr *PodRequest) deletePodConntrack() {
 	if pr.CNIConf.PrevResult == nil {
 		return
@@ -297,6 +297,116 @@ func TestSetupNetwork(t *testing.T) {
 				{OnCallMethodName: "Attrs", OnCallMethodArgType: []string{}, RetArgList: []interface{}{&netlink.LinkAttrs{Name: "testIfaceName"}}},
 			},
 		},
+		{
+			desc:    "test default route from routes when gateways is empty",
+			inpLink: mockLink,
+			inpPodIfaceInfo: &PodInterfaceInfo{
+				PodAnnotation: util.PodAnnotation{
+					IPs: ovntest.MustParseIPNets("192.168.0.5/24", "fd00:10:244::5/64"),
+					MAC: ovntest.MustParseMAC("0A:58:FD:98:00:01"),
+					Routes: []util.PodRoute{
+						{
+							Dest:    ovntest.MustParseIPNet("0.0.0.0/0"),
+							NextHop: net.ParseIP("192.168.0.1"),
+						},
+						{
+							Dest:    ovntest.MustParseIPNet("192.168.1.0/24"),
+							NextHop: net.ParseIP("192.168.1.1"),
+						},
+						{
+							Dest:    ovntest.MustParseIPNet("::/0"),
+							NextHop: net.ParseIP("fd00:10:244::1"),
+						},
+					},
+				},
+			},
+			netLinkOpsMockHelper: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "LinkSetUp", OnCallMethodArgType: []string{"*mocks.Link"}, RetArgList: []interface{}{nil}},
+				{OnCallMethodName: "AddrAdd", OnCallMethodArgType: []string{"*mocks.Link", "*netlink.Addr"}, RetArgList: []interface{}{nil}},
+				{OnCallMethodName: "AddrAdd", OnCallMethodArgType: []string{"*mocks.Link", "*netlink.Addr"}, RetArgList: []interface{}{nil}},
+			},
+			cniPluginMockHelper: []ovntest.TestifyMockHelper{
+				// the default routes are added as gateway routes
+				{OnCallMethodName: "AddRoute", OnCallMethodArgs: []interface{}{(*net.IPNet)(nil), net.ParseIP("192.168.0.1"), mockLink, 0}, RetArgList: []interface{}{nil}},
+				{OnCallMethodName: "AddRoute", OnCallMethodArgs: []interface{}{(*net.IPNet)(nil), net.ParseIP("fd00:10:244::1"), mockLink, 0}, RetArgList: []interface{}{nil}},
+				{OnCallMethodName: "AddRoute", OnCallMethodArgs: []interface{}{ovntest.MustParseIPNet("192.168.1.0/24"), net.ParseIP("192.168.1.1"), mockLink, 0}, RetArgList: []interface{}{nil}},
+			},
+			linkMockHelper: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "Attrs", OnCallMethodArgType: []string{}, RetArgList: []interface{}{&netlink.LinkAttrs{Name: "testIfaceName"}}},
+			},
+		},
+		{
+			desc:    "test multiple default routes of a family when gateways is empty",
+			inpLink: mockLink,
+			inpPodIfaceInfo: &PodInterfaceInfo{
+				PodAnnotation: util.PodAnnotation{
+					IPs: ovntest.MustParseIPNets("192.168.0.5/24"),
+					MAC: ovntest.MustParseMAC("0A:58:FD:98:00:01"),
+					Routes: []util.PodRoute{
+						{
+							Dest:    ovntest.MustParseIPNet("0.0.0.0/0"),
+							NextHop: net.ParseIP("192.168.0.1"),
+						},
+						{
+							Dest:    ovntest.MustParseIPNet("0.0.0.0/0"),
+							NextHop: net.ParseIP("192.168.0.2"),
+						},
+					},
+				},
+			},
+			errMatch: fmt.Errorf("multiple default routes"),
+			netLinkOpsMockHelper: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "LinkSetUp", OnCallMethodArgType: []string{"*mocks.Link"}, RetArgList: []interface{}{nil}},
+				{OnCallMethodName: "AddrAdd", OnCallMethodArgType: []string{"*mocks.Link", "*netlink.Addr"}, RetArgList: []interface{}{nil}},
+			},
+			linkMockHelper: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "Attrs", OnCallMethodArgType: []string{}, RetArgList: []interface{}{&netlink.LinkAttrs{Name: "testIfaceName"}}},
+			},
+		},
+		{
+			desc:    "test on-link route to an off-subnet gateway of a /32 pod IP",
+			inpLink: mockLink,
+			inpPodIfaceInfo: &PodInterfaceInfo{
+				PodAnnotation: util.PodAnnotation{
+					IPs:      ovntest.MustParseIPNets("192.168.0.5/32"),
+					MAC:      ovntest.MustParseMAC("0A:58:FD:98:00:01"),
+					Gateways: ovntest.MustParseIPs("169.254.1.1"),
+				},
+			},
+			netLinkOpsMockHelper: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "LinkSetUp", OnCallMethodArgType: []string{"*mocks.Link"}, RetArgList: []interface{}{nil}},
+				{OnCallMethodName: "AddrAdd", OnCallMethodArgType: []string{"*mocks.Link", "*netlink.Addr"}, RetArgList: []interface{}{nil}},
+			},
+			cniPluginMockHelper: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "AddRoute", OnCallMethodArgs: []interface{}{ovntest.MustParseIPNet("169.254.1.1/32"), net.IP(nil), mockLink, 0}, RetArgList: []interface{}{nil}},
+				{OnCallMethodName: "AddRoute", OnCallMethodArgs: []interface{}{(*net.IPNet)(nil), net.ParseIP("169.254.1.1"), mockLink, 0}, RetArgList: []interface{}{nil}},
+			},
+			linkMockHelper: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "Attrs", OnCallMethodArgType: []string{}, RetArgList: []interface{}{&netlink.LinkAttrs{Name: "testIfaceName"}}},
+			},
+		},
+		{
+			desc:    "test code path when the on-link route to the gateway of a /128 pod IP returns error",
+			inpLink: mockLink,
+			inpPodIfaceInfo: &PodInterfaceInfo{
+				PodAnnotation: util.PodAnnotation{
+					IPs:      ovntest.MustParseIPNets("fd00:10:244::5/128"),
+					MAC:      ovntest.MustParseMAC("0A:58:FD:98:00:01"),
+					Gateways: ovntest.MustParseIPs("fe80::1"),
+				},
+			},
+			errMatch: fmt.Errorf("failed to add on-link route to gateway fe80::1"),
+			netLinkOpsMockHelper: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "LinkSetUp", OnCallMethodArgType: []string{"*mocks.Link"}, RetArgList: []interface{}{nil}},
+				{OnCallMethodName: "AddrAdd", OnCallMethodArgType: []string{"*mocks.Link", "*netlink.Addr"}, RetArgList: []interface{}{nil}},
+			},
+			cniPluginMockHelper: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "AddRoute", OnCallMethodArgs: []interface{}{ovntest.MustParseIPNet("fe80::1/128"), net.IP(nil), mockLink, 0}, RetArgList: []interface{}{fmt.Errorf("mock error")}},
+			},
+			linkMockHelper: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "Attrs", OnCallMethodArgType: []string{}, RetArgList: []interface{}{&netlink.LinkAttrs{Name: "testIfaceName"}}},
+			},
+		},
 		{
 			desc:    "test container link already set up",
 			inpLink: mockLink,
@@ -1249,13 +1359,16 @@ func createPodIfInfo(podName, podIP, podMAC string) *PodInterfaceInfo {
 type podGetter struct {
 	pod *kapi.Pod
 	err error
+	// calls is the number of times the pod was got
+	calls int
 }
 
 func newPodGetter(pod *kapi.Pod, err error) *podGetter {
-	return &podGetter{pod, err}
+	return &podGetter{pod: pod, err: err}
 }
 
 func (p *podGetter) getPod(namespace, name string) (*kapi.Pod, error) {
+	p.calls++
 	if p.err != nil {
 		return nil, p.err
 	}
@@ -1402,3 +1515,157 @@ func TestConfigureOVS(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigureOVSPollInterval(t *testing.T) {
+	const (
+		hostIfaceName string = "hostiface"
+		sandboxID     string = "1234567890"
+		podNS         string = "ns1"
+		podName       string = "apod"
+		podIP         string = "1.1.1.1/24"
+		podMAC        string = "00:11:22:33:44:55"
+		ovnDelay             = time.Second
+	)
+
+	// configureOVS returns the number of times the OVS port was polled for
+	// ovn-installed before it was set
+	configureOVS := func(t *testing.T, interval, maxInterval time.Duration) int {
+		initialVSDB := libovsdbtest.TestSetup{VSData: []libovsdbtest.TestData{
+			&vswitchdb.Bridge{
+				UUID: "bridge-uuid",
+				Name: "br-int",
+			},
+		}}
+		vsClient, cleanup, err := libovsdbtest.NewVSTestHarness(initialVSDB, nil)
+		if err != nil {
+			t.Fatalf("failed to create test harness: %v", err)
+		}
+		t.Cleanup(cleanup.Cleanup)
+
+		go func() {
+			<-time.After(ovnDelay)
+			err := libovsdbops.SetInterfaceOVNInstalled(vsClient, hostIfaceName, true)
+			assert.Nil(t, err)
+		}()
+
+		podIfInfo := createPodIfInfo(podName, podIP, podMAC)
+		podIfInfo.OVNInstalledPollInterval = interval
+		podIfInfo.OVNInstalledPollMaxInterval = maxInterval
+		getter := newPodGetter(createPod(t, podNS, podName, podIP, podMAC), nil)
+		ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+		t.Cleanup(cancel)
+		err = ConfigureOVS(vsClient, ctx, podNS, podName, hostIfaceName, podIfInfo, sandboxID, getter)
+		assert.Nil(t, err)
+		// the pod is checked for a canceled sandbox on each poll
+		return getter.calls
+	}
+
+	defaultPolls := configureOVS(t, 0, 0)
+	customPolls := configureOVS(t, 250*time.Millisecond, 0)
+	backoffPolls := configureOVS(t, 20*time.Millisecond, 400*time.Millisecond)
+
+	// the default 20ms interval polls about 50 times within the 1s delay
+	assert.Greater(t, defaultPolls, 20)
+	// a 250ms interval about 4 times
+	assert.LessOrEqual(t, customPolls, 5)
+	// and a 20ms interval doubled up to 400ms after 20, 40, 80, 160, 320 and 400ms
+	assert.LessOrEqual(t, backoffPolls, 7)
+}
+
+func TestUnconfigureInterfaceMissingNetns(t *testing.T) {
+	const (
+		sandboxID string = "1234567890abcdef"
+		podNS     string = "ns1"
+		podName   string = "apod"
+		portUUID  string = "port-uuid"
+		intfUUID  string = "intf-uuid"
+	)
+
+	tests := []struct {
+		desc     string
+		netName  string
+		nadName  string
+		deviceID string
+		portName string
+	}{
+		{
+			desc:     "secondary network interface",
+			netName:  "tenant",
+			nadName:  "ns1/tenant",
+			portName: sandboxID[:13] + "_5",
+		},
+		{
+			desc:     "SR-IOV default network interface",
+			netName:  pkgtypes.DefaultNetworkName,
+			nadName:  pkgtypes.DefaultNetworkName,
+			deviceID: "0000:05:00.1",
+			portName: sandboxID[:15],
+		},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.desc), func(t *testing.T) {
+			mockNetLinkOps := new(util_mocks.NetLinkOps)
+			util.SetNetLinkOpMockInst(mockNetLinkOps)
+			t.Cleanup(util.ResetNetLinkOpMockInst)
+			mockNetLinkOps.On("LinkByName", tc.portName).Return(nil, fmt.Errorf("link not found"))
+
+			initialVSDB := libovsdbtest.TestSetup{
+				VSData: []libovsdbtest.TestData{
+					&vswitchdb.Bridge{
+						UUID:  "bridge-uuid",
+						Name:  "br-int",
+						Ports: []string{portUUID},
+					},
+					&vswitchdb.Port{
+						UUID:       portUUID,
+						Name:       tc.portName,
+						Interfaces: []string{intfUUID},
+					},
+					&vswitchdb.Interface{
+						UUID: intfUUID,
+						Name: tc.portName,
+						ExternalIDs: map[string]string{
+							"sandbox":              sandboxID,
+							pkgtypes.NADExternalID: tc.nadName,
+						},
+					},
+				},
+			}
+			vsClient, cleanup, err := libovsdbtest.NewVSTestHarness(initialVSDB, nil)
+			if err != nil {
+				t.Fatal(fmt.Errorf("test: %q failed to create test harness: %v", tc.desc, err))
+			}
+			t.Cleanup(cleanup.Cleanup)
+
+			pr := &PodRequest{
+				Command:      CNIDel,
+				PodNamespace: podNS,
+				PodName:      podName,
+				SandboxID:    sandboxID,
+				Netns:        "/var/run/netns/" + sandboxID,
+				IfName:       "net1",
+				CNIConf:      &types.NetConf{DeviceID: tc.deviceID},
+				netName:      tc.netName,
+				nadName:      tc.nadName,
+				vsClient:     vsClient,
+			}
+			err = pr.UnconfigureInterface(&PodInterfaceInfo{})
+			assert.Nil(t, err)
+
+			// Ensure the OVS port was deleted
+			matcher := libovsdbtest.HaveData(&vswitchdb.Bridge{
+				UUID: "bridge-uuid",
+				Name: "br-int",
+			})
+			ok, err := matcher.Match(vsClient)
+			if !ok {
+				t.Fatal(fmt.Errorf("test ovsdb: \"%s\" didn't match expected with actual, err: %v", tc.desc, matcher.FailureMessage(vsClient)))
+			} else if err != nil {
+				t.Fatal(fmt.Errorf("test ovsdb: \"%s\" encountered error: %v", tc.desc, err))
+			}
+			if tc.deviceID == "" {
+				mockNetLinkOps.AssertExpectations(t)
+			}
+		})
+	}
+}
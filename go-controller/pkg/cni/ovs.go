@@ -250,6 +250,9 @@ func waitForPodInterface(vsClient client.Client, ctx context.Context,
 		}
 		ofPort = *ovsIface.Ofport
 	}
+	if ifInfo.OVNInstalledPollInterval > 0 {
+		waitTime = ifInfo.OVNInstalledPollInterval
+	}
 
 	mac := ifInfo.MAC.String()
 	ifAddrs := ifInfo.IPs
@@ -295,6 +298,13 @@ func waitForPodInterface(vsClient client.Client, ctx context.Context,
 			// try again later
 			time.Sleep(waitTime)
 			metrics.MetricOvsInterfaceUpWait.Add(waitTime.Seconds())
+			// back off up to the max interval, if any
+			if waitTime < ifInfo.OVNInstalledPollMaxInterval {
+				waitTime *= 2
+				if waitTime > ifInfo.OVNInstalledPollMaxInterval {
+					waitTime = ifInfo.OVNInstalledPollMaxInterval
+				}
+			}
 		}
 	}
 }
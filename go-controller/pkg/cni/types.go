@@ -51,6 +51,11 @@ type PodInterfaceInfo struct {
 	PodUID               string `json:"pod-uid"`
 	NetdevName           string `json:"vf-netdev-name"`
 	EnableUDPAggregation bool   `json:"enable-udp-aggregation"`
+	// OVNInstalledPollInterval and OVNInstalledPollMaxInterval are the interval
+	// at which the OVS port is polled for ovn-installed and the one up to which
+	// it is doubled after each poll, defaults are used if unset
+	OVNInstalledPollInterval    time.Duration `json:"ovn-installed-poll-interval,omitempty"`
+	OVNInstalledPollMaxInterval time.Duration `json:"ovn-installed-poll-max-interval,omitempty"`
 
 	// network name, for default network, it is "default", otherwise it is net-attach-def's netconf spec name
 	NetName string `json:"netName"`
@@ -79,6 +84,8 @@ type Request struct {
 	Env map[string]string `json:"env,omitempty"`
 	// CNI configuration passed via stdin to the CNI plugin
 	Config []byte `json:"config,omitempty"`
+	// ID of the request, shared by the shim and server logs of the request
+	ID string `json:"id,omitempty"`
 }
 
 // CNIRequestMetrics info to report from CNI shim to CNI server
@@ -86,6 +93,7 @@ type CNIRequestMetrics struct {
 	Command     command `json:"command"`
 	ElapsedTime float64 `json:"elapsedTime"`
 	HasErr      bool    `json:"hasErr"`
+	RequestID   string  `json:"requestID,omitempty"`
 }
 
 // Response sent to the OVN CNI plugin by the Server
@@ -145,6 +153,8 @@ type PodRequest struct {
 	CNIConf *types.NetConf
 	// Timestamp when the request was started
 	timestamp time.Time
+	// ID of the CNI request, shared with the shim logs of the request
+	requestID string
 	// ctx is a context tracking this request's lifetime
 	ctx context.Context
 	// cancel should be called to cancel this request
@@ -43,6 +43,10 @@ type NetConf struct {
 	// LogFileMaxAge represents the maximum number
 	// of days to retain old log files
 	LogFileMaxAge int `json:"logfile-maxage"`
+	// OVSTransactionTimeout is the timeout in seconds for the cni shim's
+	// OVS database client to connect and to run its transactions; when
+	// not specified the default OVSDB timeout is used
+	OVSTransactionTimeout int `json:"ovsTransactionTimeout,omitempty"`
 }
 
 // NetworkSelectionElement represents one element of the JSON format
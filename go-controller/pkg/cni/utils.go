@@ -129,6 +129,9 @@ func PodAnnotation2PodInfo(podAnnotation map[string]string, podNADAnnotation *ut
 		NetName:              netName,
 		NADName:              nadName,
 		EnableUDPAggregation: config.Default.EnableUDPAggregation,
+
+		OVNInstalledPollInterval:    time.Duration(config.CNI.OVNInstalledPollInterval) * time.Millisecond,
+		OVNInstalledPollMaxInterval: time.Duration(config.CNI.OVNInstalledPollMaxInterval) * time.Millisecond,
 	}
 	return podInterfaceInfo, nil
 }
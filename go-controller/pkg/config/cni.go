@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/containernetworking/cni/pkg/types"
 	"github.com/containernetworking/cni/pkg/version"
@@ -17,6 +18,18 @@ import (
 
 var ErrorAttachDefNotOvnManaged = errors.New("net-attach-def not managed by OVN")
 
+const (
+	// minOVSTransactionTimeout and maxOVSTransactionTimeout bound the OVS
+	// transaction timeout, in seconds, of the CNI shim's vswitch client. The
+	// upper bound matches the Kubelet default CRI operation timeout of 2m.
+	minOVSTransactionTimeout = 1
+	maxOVSTransactionTimeout = 120
+)
+
+// CNIRequestTimeout is the overall timeout of a CNI request, matching the
+// Kubelet default CRI operation timeout of 2m
+const CNIRequestTimeout = 2 * time.Minute
+
 // WriteCNIConfig writes a CNI JSON config file to directory given by global config
 // if the file doesn't already exist, or is different than the content that would
 // be written.
@@ -27,11 +40,12 @@ func WriteCNIConfig() error {
 			Name:       "ovn-kubernetes",
 			Type:       CNI.Plugin,
 		},
-		LogFile:           Logging.CNIFile,
-		LogLevel:          fmt.Sprintf("%d", Logging.Level),
-		LogFileMaxSize:    Logging.LogFileMaxSize,
-		LogFileMaxBackups: Logging.LogFileMaxBackups,
-		LogFileMaxAge:     Logging.LogFileMaxAge,
+		LogFile:               Logging.CNIFile,
+		LogLevel:              fmt.Sprintf("%d", Logging.Level),
+		LogFileMaxSize:        Logging.LogFileMaxSize,
+		LogFileMaxBackups:     Logging.LogFileMaxBackups,
+		LogFileMaxAge:         Logging.LogFileMaxAge,
+		OVSTransactionTimeout: CNI.OVSTransactionTimeout,
 	}
 
 	newBytes, err := json.Marshal(netConf)
@@ -107,5 +121,41 @@ func ReadCNIConfig(bytes []byte) (*ovncnitypes.NetConf, error) {
 			return nil, err
 		}
 	}
+	if err := validateOVSTransactionTimeout(conf.OVSTransactionTimeout); err != nil {
+		return nil, err
+	}
 	return conf, nil
 }
+
+// validateOVSTransactionTimeout checks that an OVS transaction timeout, in
+// seconds, is either unset or within sane bounds
+func validateOVSTransactionTimeout(timeout int) error {
+	if timeout == 0 {
+		return nil
+	}
+	if timeout < minOVSTransactionTimeout || timeout > maxOVSTransactionTimeout {
+		return fmt.Errorf("invalid OVS transaction timeout %d: expect a value between %d and %d seconds",
+			timeout, minOVSTransactionTimeout, maxOVSTransactionTimeout)
+	}
+	return nil
+}
+
+// validateOVNInstalledPolling checks that the ovn-installed poll interval and
+// max interval, in milliseconds, are either unset or shorter than the CNI
+// request timeout, and that the max interval is not shorter than the interval
+func validateOVNInstalledPolling(interval, maxInterval int) error {
+	timeout := int(CNIRequestTimeout.Milliseconds())
+	if interval < 0 || interval >= timeout {
+		return fmt.Errorf("invalid ovn-installed poll interval %d: expect a value between 0 and %d milliseconds",
+			interval, timeout-1)
+	}
+	if maxInterval < 0 || maxInterval >= timeout {
+		return fmt.Errorf("invalid ovn-installed poll max interval %d: expect a value between 0 and %d milliseconds",
+			maxInterval, timeout-1)
+	}
+	if maxInterval > 0 && maxInterval < interval {
+		return fmt.Errorf("invalid ovn-installed poll max interval %d: expect a value not lower than the poll interval %d",
+			maxInterval, interval)
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/urfave/cli/v2"
 	gcfg "gopkg.in/gcfg.v1"
@@ -119,6 +120,8 @@ var (
 	// OVNKubernetesFeatureConfig holds OVN-Kubernetes feature enhancement config file parameters and command-line overrides
 	OVNKubernetesFeature = OVNKubernetesFeatureConfig{
 		EgressIPReachabiltyTotalTimeout: 1,
+		EgressServiceReroutePriority:    types.EgressSVCReroutePriority,
+		EgressServiceRerouteTarget:      EgressServiceRerouteManagementPort,
 	}
 
 	// OvnNorth holds northbound OVN database client and server authentication and location details
@@ -129,8 +132,13 @@ var (
 
 	// Gateway holds node gateway-related parsed config file parameters and command-line overrides
 	Gateway = GatewayConfig{
-		V4JoinSubnet: "100.64.0.0/16",
-		V6JoinSubnet: "fd98::/64",
+		V4JoinSubnet:           "100.64.0.0/16",
+		V6JoinSubnet:           "fd98::/64",
+		ServiceCIDRFlowBudget:  64,
+		BFDPorts:               "3784",
+		FallbackAction:         GatewayFallbackActionNormal,
+		EgressGWFallbackAction: GatewayFallbackActionNormal,
+		IPv6AdvertisementFlood: GatewayIPv6AdvertisementFlood,
 	}
 
 	// MasterHA holds master HA related config options.
@@ -166,7 +174,8 @@ var (
 
 	// OvnKubeNode holds ovnkube-node parsed config file parameters and command-line overrides
 	OvnKubeNode = OvnKubeNodeConfig{
-		Mode: types.NodeModeFull,
+		Mode:                                types.NodeModeFull,
+		DPUHostRepresentorUnavailableAction: DPUHostRepresentorUnavailableActionHold,
 	}
 
 	ClusterManager = ClusterManagerConfig{
@@ -303,6 +312,16 @@ type CNIConfig struct {
 	ConfDir string `gcfg:"conf-dir"`
 	// Plugin specifies the name of the CNI plugin
 	Plugin string `gcfg:"plugin"`
+	// OVSTransactionTimeout specifies the timeout in seconds for the CNI shim's
+	// OVS database client when ovnkube-node runs in unprivileged mode
+	OVSTransactionTimeout int `gcfg:"ovs-transaction-timeout"`
+	// OVNInstalledPollInterval specifies the interval in milliseconds at which
+	// the OVS port of a pod is polled for ovn-installed, 0 for the default
+	OVNInstalledPollInterval int `gcfg:"ovn-installed-poll-interval"`
+	// OVNInstalledPollMaxInterval specifies the interval in milliseconds up to
+	// which the ovn-installed poll interval is doubled after each poll, 0 to
+	// poll at a constant interval
+	OVNInstalledPollMaxInterval int `gcfg:"ovn-installed-poll-max-interval"`
 }
 
 // KubernetesConfig holds Kubernetes-related parsed config file parameters and command-line overrides
@@ -365,8 +384,42 @@ type OVNKubernetesFeatureConfig struct {
 	EnableStatelessNetPol           bool `gcfg:"enable-stateless-netpol"`
 	EnableInterconnect              bool `gcfg:"enable-interconnect"`
 	EnableMultiExternalGateway      bool `gcfg:"enable-multi-external-gateway"`
+	// EgressService reroute logical router policies priority
+	EgressServiceReroutePriority int `gcfg:"egress-service-reroute-priority"`
+	// EgressServiceRerouteTarget is the port of the node hosting an EgressService its traffic is rerouted
+	// to, either "management-port" (default) or "gateway-router"
+	EgressServiceRerouteTarget string `gcfg:"egress-service-reroute-target"`
+	// EgressServiceRerouteExcludedCIDRs is a comma separated list of the destination CIDRs, like the cluster
+	// subnets, the traffic of EgressService endpoints towards is not rerouted to the node hosting the service,
+	// so that only their external bound traffic is. All the traffic of the endpoints is rerouted if empty.
+	EgressServiceRerouteExcludedCIDRs string `gcfg:"egress-service-reroute-excluded-cidrs"`
+}
+
+// GetEgressServiceRerouteExcludedCIDRs returns the destination CIDRs excluded
+// from the EgressService reroutes
+func (cfg *OVNKubernetesFeatureConfig) GetEgressServiceRerouteExcludedCIDRs() []*net.IPNet {
+	cidrs := []*net.IPNet{}
+	for _, cidr := range strings.Split(cfg.EgressServiceRerouteExcludedCIDRs, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if _, ipNet, err := utilnet.ParseCIDRSloppy(cidr); err == nil {
+			cidrs = append(cidrs, ipNet)
+		}
+	}
+	return cidrs
 }
 
+const (
+	// EgressServiceRerouteManagementPort reroutes the EgressService traffic to the management
+	// port of the node hosting the service
+	EgressServiceRerouteManagementPort = "management-port"
+	// EgressServiceRerouteGatewayRouter reroutes the EgressService traffic to the join port of
+	// the gateway router of the node hosting the service
+	EgressServiceRerouteGatewayRouter = "gateway-router"
+)
+
 // GatewayMode holds the node gateway mode
 type GatewayMode string
 
@@ -413,6 +466,283 @@ type GatewayConfig struct {
 	DisableForwarding bool `gcfg:"disable-forwarding"`
 	// AllowNoUplink (disabled by default) controls if the external gateway bridge without an uplink port is allowed in local gateway mode.
 	AllowNoUplink bool `gcfg:"allow-no-uplink"`
+	// FlowTablePrefixes is a comma separated list of the fields (e.g. "ip_dst,ipv6_dst") OVS
+	// should build prefix trees for when looking up flows in the gateway bridge flow tables.
+	FlowTablePrefixes string `gcfg:"flow-table-prefixes"`
+	// EndpointSliceCoalescingWindow is the time, in milliseconds, during which endpoint slice
+	// updates of a service are merged into a single recompute of its gateway rules. Disabled if 0.
+	EndpointSliceCoalescingWindow int `gcfg:"endpointslice-coalescing-window"`
+	// HostNetworkEndpointsAllHostIPs (disabled by default) controls if local service endpoints on any
+	// IP of the host interfaces, and not only on the node IPs, are considered host networked.
+	HostNetworkEndpointsAllHostIPs bool `gcfg:"host-network-endpoints-all-host-ips"`
+	// ConntrackDrainChunkSize is the maximum number of conntrack entries of a deleted service deleted at
+	// once, spreading the deletion of its entries over a short window. All entries are deleted at once if 0.
+	ConntrackDrainChunkSize int `gcfg:"conntrack-drain-chunk-size"`
+	// DisableConntrackFlush (disabled by default) disables the deletion of the conntrack entries of services
+	// and endpoints upon their changes, for clusters that manage conntrack externally.
+	DisableConntrackFlush bool `gcfg:"disable-conntrack-flush"`
+	// MaxServiceConntrackDeletes is the maximum number of conntrack entries deleted upon the deletion of a
+	// service, protecting the node from a runaway flush. Hitting it is logged as unusual. Unlimited if 0.
+	MaxServiceConntrackDeletes int `gcfg:"max-service-conntrack-deletes"`
+	// NodePortInterfaces is a comma separated list of the physical interfaces of the gateway bridge
+	// NodePort traffic is accepted on. NodePort traffic is accepted on the bridge uplink if empty.
+	NodePortInterfaces string `gcfg:"nodeport-interfaces"`
+	// PerServiceETPCookies makes the flows sending the traffic of externalTrafficPolicy=local services
+	// to their local host networked endpoints use per service cookies instead of a shared cookie.
+	PerServiceETPCookies bool `gcfg:"per-service-etp-cookies"`
+	// ServiceCIDRFlowBudget is the number of gateway bridge default flows for the service CIDRs above
+	// which a warning is logged at startup, as each service CIDR adds several flows. Disabled if 0.
+	ServiceCIDRFlowBudget int `gcfg:"service-cidr-flow-budget"`
+	// MasqueradeRouteSourceIPs is a comma separated list of at most one IP per family the route towards
+	// the OVN masquerade IP uses as source, instead of the node IP of the family. The IPs must be
+	// configured on the gateway interface.
+	MasqueradeRouteSourceIPs string `gcfg:"masquerade-route-source-ips"`
+	// ExternalNameServiceIPs (disabled by default) programs the gateway bridge flows of an externalIP for
+	// each of the IPs set in the k8s.ovn.org/external-name-ips annotation of ExternalName services.
+	ExternalNameServiceIPs bool `gcfg:"external-name-service-ips"`
+	// HeadlessServiceExternalIPs (disabled by default) programs the gateway bridge flows of the externalIPs of
+	// headless services, which are otherwise ignored like the rest of these services. Their traffic is sent to
+	// OVN, the backends being handled as pods.
+	HeadlessServiceExternalIPs bool `gcfg:"headless-service-external-ips"`
+	// SkipNodeIPExternalIPs (disabled by default) skips the gateway bridge flows of service externalIPs
+	// that are also IPs of the node, which would otherwise conflict with the traffic towards the node.
+	SkipNodeIPExternalIPs bool `gcfg:"skip-node-ip-external-ips"`
+	// BFDPorts is a comma separated list of the UDP destination ports of the BFD traffic the gateway
+	// bridge sends both to the host and to OVN, and from OVN directly out, bypassing conntrack.
+	BFDPorts string `gcfg:"bfd-ports"`
+	// ServiceVLANID is the VLAN tag of the externalIP and LoadBalancer service traffic the uplink of the
+	// gateway bridge carries. The external service flows match it, pop it from the incoming traffic and
+	// push it on the reply traffic. 0 (default) means the traffic is untagged.
+	ServiceVLANID uint `gcfg:"service-vlan-id"`
+	// ServiceCIDRExemptions is a comma separated list of IPs and CIDRs, like the IP of a node-local DNS cache,
+	// whose traffic from the host is neither SNAT-ed nor sent to OVN, and whose traffic from OVN is not
+	// dropped, by the gateway bridge flows for the service CIDRs.
+	ServiceCIDRExemptions string `gcfg:"service-cidr-exemptions"`
+	// FallbackAction is the action of the table 0 priority 0 flow of the gateway bridge, applied to
+	// the traffic no other flow matches, either "normal" (default) or "drop"
+	FallbackAction string `gcfg:"fallback-action"`
+	// EgressGWFallbackAction is the FallbackAction of the external gateway bridge
+	EgressGWFallbackAction string `gcfg:"egw-fallback-action"`
+	// ITPLocalMgmtPortRoutes (disabled by default) routes only the ClusterIPs of the
+	// internalTrafficPolicy=local services via the management port, rather than the whole
+	// service CIDRs, to steer the host traffic of these services into OVN.
+	ITPLocalMgmtPortRoutes bool `gcfg:"itp-local-mgmt-port-routes"`
+	// DisableARPBypassFlows (disabled by default) skips the gateway bridge flows flooding the ARP/NS
+	// requests for the externalIPs and LoadBalancer ingress IPs of services to all ports but OVN, for
+	// environments where OVN alone answers for these IPs.
+	DisableARPBypassFlows bool `gcfg:"disable-arp-bypass-flows"`
+	// AllowForceETPAnnotation (disabled by default) honors the k8s.ovn.org/force-etp service annotation,
+	// overriding the externalTrafficPolicy the gateway flows and rules of the service are generated for.
+	// It is meant for testing only: OVN still follows the externalTrafficPolicy of the service spec.
+	AllowForceETPAnnotation bool `gcfg:"allow-force-etp-annotation"`
+	// ForwardingBlockExemptions is a comma separated list of the interfaces and subnets whose traffic
+	// forwarded through the gateway bridges is still accepted when DisableForwarding is set.
+	ForwardingBlockExemptions string `gcfg:"forwarding-block-exemptions"`
+	// ARPBypassFlowsMeterRate, in packets per second, rate-limits the ARP/NS requests flooded by the ARP
+	// bypass flows with an OVS meter, against broadcast storms on large L2 segments. Not limited if 0.
+	ARPBypassFlowsMeterRate int `gcfg:"arp-bypass-flows-meter-rate"`
+	// DisableGeneveDirectToHostFlow (disabled by default) skips the gateway bridge flow sending the
+	// Geneve packets destined to the shared MAC directly to the host, bypassing conntrack, so that all
+	// the Geneve traffic takes the NORMAL path, as needed by some DPU/hardware offload setups.
+	DisableGeneveDirectToHostFlow bool `gcfg:"disable-geneve-direct-to-host-flow"`
+	// EgressSNATSourceIPs is a comma separated list of at most one IP per family the egressIP and
+	// egressService traffic leaving through the gateway bridge is SNAT-ed to, instead of the node IP of
+	// the family. The IPs must be configured on the gateway bridge.
+	EgressSNATSourceIPs string `gcfg:"egress-snat-source-ips"`
+	// NodePortAddresses is a comma separated list of CIDRs, like kube-proxy's --nodeport-addresses, the
+	// gateway bridge NodePort flows are restricted to by destination. All the node addresses if empty.
+	NodePortAddresses string `gcfg:"nodeport-addresses"`
+	// IPv6AdvertisementFlood is the handling of the ICMPv6 Router and Neighbor Advertisements by the
+	// gateway bridge, flooded to all its ports as they fail to create a conntrack entry: either "flood"
+	// (default), "restrict" to OVN and the host only, or "disable" to not flood them at all.
+	IPv6AdvertisementFlood string `gcfg:"ipv6-advertisement-flood"`
+	// HealthCheckNodePortAddresses is a comma separated list of at most one node IP per family the external
+	// traffic towards the healthCheckNodePorts of services is restricted to by the gateway bridge flows, so
+	// that load balancers can only probe them there. All the node addresses if empty.
+	HealthCheckNodePortAddresses string `gcfg:"health-check-nodeport-addresses"`
+	// ConntrackDeleteReplyDirection (disabled by default) also deletes the conntrack entries whose reply
+	// source is a service VIP, and not only the ones whose original destination is, when flushing the
+	// conntrack entries of services, for the NAT setups leaving entries the latter does not match.
+	ConntrackDeleteReplyDirection bool `gcfg:"conntrack-delete-reply-direction"`
+	// ExGWBridgeServices (disabled by default) also programs the externalIP and LoadBalancer ingress IP
+	// flows of the services with the k8s.ovn.org/exgw-bridge annotation set to "true" on the external
+	// gateway bridge, if any, exposing them through its uplink. Shared gateway mode only.
+	ExGWBridgeServices bool `gcfg:"exgw-bridge-services"`
+	// ITPLocalExemptions is a comma separated list of the interfaces, IPs and subnets whose host traffic
+	// towards the internalTrafficPolicy=local services is not marked to be steered to OVN, taking the normal
+	// path instead, like node-local monitoring. Interfaces match the traffic routed out of them, IPs and
+	// subnets its source.
+	ITPLocalExemptions string `gcfg:"itp-local-exemptions"`
+	// SyncServicesContinueOnError (disabled by default) keeps syncing the other services on startup when
+	// the endpointslices of a service cannot be retrieved, returning the errors once all the services are
+	// processed, instead of aborting the sync on the first one.
+	SyncServicesContinueOnError bool `gcfg:"sync-services-continue-on-error"`
+	// ExternalIPSourcePreservation (disabled by default) preserves the client source IP of the traffic
+	// towards the externalIPs of the externalTrafficPolicy=cluster services, by steering it to the host
+	// instead of the gateway router, from where it is policy routed into OVN via the management port
+	// without being SNAT-ed. The replies of the endpoints must be routed back through the node, which
+	// is left to the operator. Shared gateway mode only.
+	ExternalIPSourcePreservation bool `gcfg:"externalip-source-preservation"`
+}
+
+const (
+	// GatewayFallbackActionNormal switches the gateway bridge traffic no other flow matches
+	GatewayFallbackActionNormal = "normal"
+	// GatewayFallbackActionDrop drops the gateway bridge traffic no other flow matches
+	GatewayFallbackActionDrop = "drop"
+)
+
+const (
+	// GatewayIPv6AdvertisementFlood floods the ICMPv6 RA/NA to all the gateway bridge ports
+	GatewayIPv6AdvertisementFlood = "flood"
+	// GatewayIPv6AdvertisementRestrict sends the ICMPv6 RA/NA to OVN and the host only
+	GatewayIPv6AdvertisementRestrict = "restrict"
+	// GatewayIPv6AdvertisementDisable does not flood the ICMPv6 RA/NA
+	GatewayIPv6AdvertisementDisable = "disable"
+)
+
+// validFlowTablePrefixes are the fields OVS supports prefix tree lookups for
+var validFlowTablePrefixes = sets.New[string](
+	"tun_src", "tun_dst", "tun_ipv6_src", "tun_ipv6_dst",
+	"ip_src", "ip_dst", "nw_src", "nw_dst", "ipv6_src", "ipv6_dst",
+)
+
+// maxFlowTablePrefixes is the maximum number of prefix fields OVS supports per flow table
+const maxFlowTablePrefixes = 3
+
+// maxEndpointSliceCoalescingWindow bounds, in milliseconds, how long the gateway rules of a
+// service can lag behind its endpoint slices when endpoint slice updates are coalesced
+const maxEndpointSliceCoalescingWindow = 5000
+
+// GetFlowTablePrefixes returns the list of configured flow table prefix fields
+func (cfg *GatewayConfig) GetFlowTablePrefixes() []string {
+	prefixes := []string{}
+	for _, prefix := range strings.Split(cfg.FlowTablePrefixes, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// GetNodePortInterfaces returns the list of configured NodePort interfaces
+func (cfg *GatewayConfig) GetNodePortInterfaces() []string {
+	interfaces := []string{}
+	for _, iface := range strings.Split(cfg.NodePortInterfaces, ",") {
+		iface = strings.TrimSpace(iface)
+		if iface != "" {
+			interfaces = append(interfaces, iface)
+		}
+	}
+	return interfaces
+}
+
+// GetBFDPorts returns the list of configured BFD ports
+func (cfg *GatewayConfig) GetBFDPorts() []int {
+	ports := []int{}
+	for _, portStr := range strings.Split(cfg.BFDPorts, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(portStr))
+		if err == nil {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// GetServiceCIDRExemptions returns the list of configured service CIDR
+// exemptions, IPs being returned as host CIDRs
+func (cfg *GatewayConfig) GetServiceCIDRExemptions() []*net.IPNet {
+	// the exemptions other than IPs and CIDRs are rejected by the validation
+	_, exemptions := parseExemptions(cfg.ServiceCIDRExemptions)
+	return exemptions
+}
+
+// GetForwardingBlockExemptions returns the interfaces and the subnets of the
+// configured forwarding block exemptions, IPs being returned as host CIDRs
+func (cfg *GatewayConfig) GetForwardingBlockExemptions() ([]string, []*net.IPNet) {
+	return parseExemptions(cfg.ForwardingBlockExemptions)
+}
+
+// GetITPLocalExemptions returns the interfaces and the subnets of the
+// configured internalTrafficPolicy=local exemptions, IPs being returned as
+// host CIDRs
+func (cfg *GatewayConfig) GetITPLocalExemptions() ([]string, []*net.IPNet) {
+	return parseExemptions(cfg.ITPLocalExemptions)
+}
+
+// parseExemptions returns the interfaces and the subnets of a comma separated
+// list of interfaces, IPs and CIDRs, IPs being returned as host CIDRs
+func parseExemptions(str string) ([]string, []*net.IPNet) {
+	interfaces := []string{}
+	subnets := []*net.IPNet{}
+	for _, exemption := range strings.Split(str, ",") {
+		exemption = strings.TrimSpace(exemption)
+		if exemption == "" {
+			continue
+		}
+		if ip := utilnet.ParseIPSloppy(exemption); ip != nil {
+			if ip4 := ip.To4(); ip4 != nil {
+				subnets = append(subnets, &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)})
+			} else {
+				subnets = append(subnets, &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+			}
+			continue
+		}
+		if _, ipNet, err := utilnet.ParseCIDRSloppy(exemption); err == nil {
+			subnets = append(subnets, ipNet)
+			continue
+		}
+		interfaces = append(interfaces, exemption)
+	}
+	return interfaces, subnets
+}
+
+// GetMasqueradeRouteSourceIPs returns the list of configured masquerade route
+// source IPs
+func (cfg *GatewayConfig) GetMasqueradeRouteSourceIPs() []net.IP {
+	return parseIPList(cfg.MasqueradeRouteSourceIPs)
+}
+
+// parseIPList returns the IPs of a comma separated list of IPs, skipping the
+// invalid ones
+func parseIPList(str string) []net.IP {
+	ips := []net.IP{}
+	for _, ipStr := range strings.Split(str, ",") {
+		ipStr = strings.TrimSpace(ipStr)
+		if ipStr == "" {
+			continue
+		}
+		if ip := utilnet.ParseIPSloppy(ipStr); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// GetEgressSNATSourceIPs returns the list of configured egress SNAT source IPs
+func (cfg *GatewayConfig) GetEgressSNATSourceIPs() []net.IP {
+	return parseIPList(cfg.EgressSNATSourceIPs)
+}
+
+// GetHealthCheckNodePortAddresses returns the list of configured health check NodePort addresses
+func (cfg *GatewayConfig) GetHealthCheckNodePortAddresses() []net.IP {
+	return parseIPList(cfg.HealthCheckNodePortAddresses)
+}
+
+// GetNodePortAddresses returns the list of configured NodePort address CIDRs
+func (cfg *GatewayConfig) GetNodePortAddresses() []*net.IPNet {
+	addresses := []*net.IPNet{}
+	for _, address := range strings.Split(cfg.NodePortAddresses, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		if _, ipNet, err := utilnet.ParseCIDRSloppy(address); err == nil {
+			addresses = append(addresses, ipNet)
+		}
+	}
+	return addresses
 }
 
 // OvnAuthConfig holds client authentication and location details for
@@ -460,8 +790,24 @@ type OvnKubeNodeConfig struct {
 	DPResourceDeviceIdsMap map[string][]string
 	MgmtPortNetdev         string `gcfg:"mgmt-port-netdev"`
 	MgmtPortDPResourceName string `gcfg:"mgmt-port-dp-resource-name"`
+	// DPUHostRepresentor is the host representor port of the gateway bridge
+	// used in DPU mode instead of the one discovered from the port flavours.
+	DPUHostRepresentor string `gcfg:"dpu-host-representor"`
+	// DPUHostRepresentorUnavailableAction is what happens, in DPU mode, to the gateway bridge flows
+	// depending on the host representor while it has no valid ofport: "hold" (default) leaves them out
+	// until it is back, "drop" drops the traffic they output to it.
+	DPUHostRepresentorUnavailableAction string `gcfg:"dpu-host-representor-unavailable-action"`
 }
 
+const (
+	// DPUHostRepresentorUnavailableActionHold leaves the flows depending on an unavailable
+	// host representor out of the gateway bridge until it is back
+	DPUHostRepresentorUnavailableActionHold = "hold"
+	// DPUHostRepresentorUnavailableActionDrop drops the traffic the flows output to an
+	// unavailable host representor
+	DPUHostRepresentorUnavailableActionDrop = "drop"
+)
+
 // ClusterManagerConfig holds configuration for ovnkube-cluster-manager
 type ClusterManagerConfig struct {
 	// V4TransitSwitchSubnet to be used in the cluster for interconnecting multiple zones
@@ -914,6 +1260,26 @@ var CNIFlags = []cli.Flag{
 		Destination: &cliConfig.CNI.Plugin,
 		Value:       CNI.Plugin,
 	},
+	&cli.IntFlag{
+		Name:        "cni-ovs-transaction-timeout",
+		Usage:       "the timeout in seconds for the CNI shim's OVS database client to connect and run transactions (default: 10)",
+		Destination: &cliConfig.CNI.OVSTransactionTimeout,
+		Value:       CNI.OVSTransactionTimeout,
+	},
+	&cli.IntFlag{
+		Name: "cni-ovn-installed-poll-interval",
+		Usage: "the interval in milliseconds at which the OVS port of a pod is polled for ovn-installed " +
+			"(default: 20, 200 on DPU hosts)",
+		Destination: &cliConfig.CNI.OVNInstalledPollInterval,
+		Value:       CNI.OVNInstalledPollInterval,
+	},
+	&cli.IntFlag{
+		Name: "cni-ovn-installed-poll-max-interval",
+		Usage: "the interval in milliseconds up to which the ovn-installed poll interval is doubled after " +
+			"each poll, to reduce the OVS database load (default: 0, constant interval)",
+		Destination: &cliConfig.CNI.OVNInstalledPollMaxInterval,
+		Value:       CNI.OVNInstalledPollMaxInterval,
+	},
 }
 
 // OVNK8sFeatureFlags capture OVN-Kubernetes feature related options
@@ -983,6 +1349,25 @@ var OVNK8sFeatureFlags = []cli.Flag{
 		Destination: &cliConfig.OVNKubernetesFeature.EnableMultiExternalGateway,
 		Value:       OVNKubernetesFeature.EnableMultiExternalGateway,
 	},
+	&cli.IntFlag{
+		Name:        "egress-service-reroute-priority",
+		Usage:       "Priority of the logical router policies rerouting EgressService traffic (default: 101)",
+		Destination: &cliConfig.OVNKubernetesFeature.EgressServiceReroutePriority,
+		Value:       OVNKubernetesFeature.EgressServiceReroutePriority,
+	},
+	&cli.StringFlag{
+		Name: "egress-service-reroute-target",
+		Usage: "Port of the node hosting an EgressService its traffic is rerouted to. One of \"management-port\" " +
+			"or \"gateway-router\" (default: management-port)",
+		Destination: &cliConfig.OVNKubernetesFeature.EgressServiceRerouteTarget,
+		Value:       OVNKubernetesFeature.EgressServiceRerouteTarget,
+	},
+	&cli.StringFlag{
+		Name: "egress-service-reroute-excluded-cidrs",
+		Usage: "Comma separated list of the destination CIDRs, like the cluster subnets, the traffic of " +
+			"EgressService endpoints towards is not rerouted to the node hosting the service (default: none)",
+		Destination: &cliConfig.OVNKubernetesFeature.EgressServiceRerouteExcludedCIDRs,
+	},
 }
 
 // K8sFlags capture Kubernetes-related options
@@ -1303,6 +1688,205 @@ var OVNGatewayFlags = []cli.Flag{
 		Usage:       "Allow the external gateway bridge without an uplink port in local gateway mode",
 		Destination: &cliConfig.Gateway.AllowNoUplink,
 	},
+	&cli.StringFlag{
+		Name:        "gateway-flow-table-prefixes",
+		Usage:       "Comma separated list of fields (e.g. ip_dst,ipv6_dst) OVS should use prefix trees for when looking up flows in the gateway bridge flow tables",
+		Destination: &cliConfig.Gateway.FlowTablePrefixes,
+	},
+	&cli.IntFlag{
+		Name: "gateway-endpointslice-coalescing-window",
+		Usage: "Time in milliseconds during which endpoint slice updates of a service are merged into a single " +
+			"recompute of its gateway rules (default: 0, disabled)",
+		Destination: &cliConfig.Gateway.EndpointSliceCoalescingWindow,
+	},
+	&cli.BoolFlag{
+		Name:        "gateway-host-network-endpoints-all-host-ips",
+		Usage:       "Consider local service endpoints on any IP of the host interfaces, and not only on the node IPs, host networked",
+		Destination: &cliConfig.Gateway.HostNetworkEndpointsAllHostIPs,
+	},
+	&cli.IntFlag{
+		Name: "gateway-conntrack-drain-chunk-size",
+		Usage: "Maximum number of conntrack entries of a deleted service deleted at once, spreading the deletion " +
+			"of its entries over a short window (default: 0, all entries deleted at once)",
+		Destination: &cliConfig.Gateway.ConntrackDrainChunkSize,
+	},
+	&cli.BoolFlag{
+		Name:        "gateway-disable-conntrack-flush",
+		Usage:       "Disable the deletion of the conntrack entries of services and endpoints upon their changes",
+		Destination: &cliConfig.Gateway.DisableConntrackFlush,
+	},
+	&cli.IntFlag{
+		Name: "gateway-max-service-conntrack-deletes",
+		Usage: "Maximum number of conntrack entries deleted upon the deletion of a service, protecting the node " +
+			"from a runaway flush (default: 0, unlimited)",
+		Destination: &cliConfig.Gateway.MaxServiceConntrackDeletes,
+	},
+	&cli.StringFlag{
+		Name:        "gateway-nodeport-interfaces",
+		Usage:       "Comma separated list of the physical interfaces of the gateway bridge NodePort traffic is accepted on (default: the bridge uplink)",
+		Destination: &cliConfig.Gateway.NodePortInterfaces,
+	},
+	&cli.BoolFlag{
+		Name:        "gateway-per-service-etp-cookies",
+		Usage:       "Use per service cookies for the gateway bridge flows of externalTrafficPolicy=local services with local host networked endpoints, at the cost of more flows",
+		Destination: &cliConfig.Gateway.PerServiceETPCookies,
+	},
+	&cli.IntFlag{
+		Name: "gateway-service-cidr-flow-budget",
+		Usage: "Number of gateway bridge default flows for the service CIDRs above which a warning is " +
+			"logged at startup (0 disables the check)",
+		Value:       Gateway.ServiceCIDRFlowBudget,
+		Destination: &cliConfig.Gateway.ServiceCIDRFlowBudget,
+	},
+	&cli.StringFlag{
+		Name: "gateway-masquerade-route-source-ips",
+		Usage: "Comma separated list of at most one IP per family of the gateway interface the route towards " +
+			"the OVN masquerade IP uses as source (default: the node IPs)",
+		Destination: &cliConfig.Gateway.MasqueradeRouteSourceIPs,
+	},
+	&cli.BoolFlag{
+		Name: "gateway-external-name-service-ips",
+		Usage: "Program the gateway bridge flows of an externalIP for each of the IPs set in the " +
+			"k8s.ovn.org/external-name-ips annotation of ExternalName services",
+		Destination: &cliConfig.Gateway.ExternalNameServiceIPs,
+	},
+	&cli.BoolFlag{
+		Name: "gateway-headless-service-external-ips",
+		Usage: "Program the gateway bridge flows of the externalIPs of headless services, sending their " +
+			"traffic to OVN",
+		Destination: &cliConfig.Gateway.HeadlessServiceExternalIPs,
+	},
+	&cli.BoolFlag{
+		Name: "gateway-skip-node-ip-external-ips",
+		Usage: "Skip the gateway bridge flows of service externalIPs that are also IPs of the node " +
+			"(default: only a warning is logged)",
+		Destination: &cliConfig.Gateway.SkipNodeIPExternalIPs,
+	},
+	&cli.StringFlag{
+		Name: "gateway-bfd-ports",
+		Usage: "Comma separated list of the UDP destination ports of the BFD traffic the gateway bridge " +
+			"handles without conntrack",
+		Value:       Gateway.BFDPorts,
+		Destination: &cliConfig.Gateway.BFDPorts,
+	},
+	&cli.UintFlag{
+		Name: "gateway-service-vlan-id",
+		Usage: "The VLAN tag of the externalIP and LoadBalancer service traffic on the uplink of the " +
+			"gateway bridge (default: 0, untagged)",
+		Destination: &cliConfig.Gateway.ServiceVLANID,
+	},
+	&cli.StringFlag{
+		Name: "gateway-service-cidr-exemptions",
+		Usage: "Comma separated list of IPs and CIDRs, like the IP of a node-local DNS cache, exempted from " +
+			"the SNAT and drop gateway bridge flows for the service CIDRs",
+		Destination: &cliConfig.Gateway.ServiceCIDRExemptions,
+	},
+	&cli.StringFlag{
+		Name: "gateway-fallback-action",
+		Usage: "Action of the gateway bridge flow for the traffic no other flow matches. One of \"normal\" " +
+			"or \"drop\" (default: normal)",
+		Value:       Gateway.FallbackAction,
+		Destination: &cliConfig.Gateway.FallbackAction,
+	},
+	&cli.StringFlag{
+		Name: "gateway-exgw-fallback-action",
+		Usage: "Action of the external gateway bridge flow for the traffic no other flow matches. One of " +
+			"\"normal\" or \"drop\" (default: normal)",
+		Value:       Gateway.EgressGWFallbackAction,
+		Destination: &cliConfig.Gateway.EgressGWFallbackAction,
+	},
+	&cli.BoolFlag{
+		Name: "gateway-itp-local-mgmt-port-routes",
+		Usage: "Route only the ClusterIPs of internalTrafficPolicy=local services via the management port " +
+			"instead of the whole service CIDRs",
+		Destination: &cliConfig.Gateway.ITPLocalMgmtPortRoutes,
+	},
+	&cli.BoolFlag{
+		Name: "gateway-disable-arp-bypass-flows",
+		Usage: "Do not program the gateway bridge flows sending the ARP/NS requests for the externalIPs and " +
+			"LoadBalancer ingress IPs of services to all ports but OVN",
+		Destination: &cliConfig.Gateway.DisableARPBypassFlows,
+	},
+	&cli.BoolFlag{
+		Name: "gateway-allow-force-etp-annotation",
+		Usage: "Honor the k8s.ovn.org/force-etp service annotation overriding the externalTrafficPolicy " +
+			"the gateway flows and rules of the service are generated for. For testing only",
+		Destination: &cliConfig.Gateway.AllowForceETPAnnotation,
+	},
+	&cli.StringFlag{
+		Name: "gateway-forwarding-block-exemptions",
+		Usage: "Comma separated list of interfaces, IPs and CIDRs whose traffic forwarded through the gateway " +
+			"bridges is still accepted when forwarding is disabled",
+		Destination: &cliConfig.Gateway.ForwardingBlockExemptions,
+	},
+	&cli.IntFlag{
+		Name: "gateway-arp-bypass-flows-meter-rate",
+		Usage: "Rate, in packets per second, the ARP/NS requests flooded by the gateway bridge ARP bypass flows " +
+			"are limited to with an OVS meter (default: 0, not limited)",
+		Destination: &cliConfig.Gateway.ARPBypassFlowsMeterRate,
+	},
+	&cli.BoolFlag{
+		Name: "gateway-disable-geneve-direct-to-host-flow",
+		Usage: "Do not program the gateway bridge flow sending the Geneve packets destined to the shared MAC " +
+			"directly to the host, so that all the Geneve traffic takes the NORMAL path",
+		Destination: &cliConfig.Gateway.DisableGeneveDirectToHostFlow,
+	},
+	&cli.StringFlag{
+		Name: "gateway-egress-snat-source-ips",
+		Usage: "Comma separated list of at most one IP per family of the gateway bridge the egressIP and " +
+			"egressService traffic is SNAT-ed to (default: the node IPs)",
+		Destination: &cliConfig.Gateway.EgressSNATSourceIPs,
+	},
+	&cli.StringFlag{
+		Name: "gateway-nodeport-addresses",
+		Usage: "Comma separated list of CIDRs the destination of the NodePort traffic accepted by the gateway " +
+			"bridge is restricted to (default: all the node addresses)",
+		Destination: &cliConfig.Gateway.NodePortAddresses,
+	},
+	&cli.StringFlag{
+		Name: "gateway-ipv6-advertisement-flood",
+		Usage: "Handling of the ICMPv6 Router and Neighbor Advertisements by the gateway bridge. One of " +
+			"\"flood\" to all its ports, \"restrict\" to OVN and the host, or \"disable\" (default: flood)",
+		Value:       Gateway.IPv6AdvertisementFlood,
+		Destination: &cliConfig.Gateway.IPv6AdvertisementFlood,
+	},
+	&cli.StringFlag{
+		Name: "gateway-health-check-nodeport-addresses",
+		Usage: "Comma separated list of at most one node IP per family the destination of the external traffic " +
+			"towards the healthCheckNodePorts of services is restricted to (default: all the node addresses)",
+		Destination: &cliConfig.Gateway.HealthCheckNodePortAddresses,
+	},
+	&cli.BoolFlag{
+		Name: "gateway-conntrack-delete-reply-direction",
+		Usage: "Also delete the conntrack entries whose reply source is a service VIP, and not only the ones " +
+			"whose original destination is, when flushing the conntrack entries of services",
+		Destination: &cliConfig.Gateway.ConntrackDeleteReplyDirection,
+	},
+	&cli.BoolFlag{
+		Name: "gateway-exgw-bridge-services",
+		Usage: "Also program the externalIP and LoadBalancer ingress IP flows of the services annotated with " +
+			"k8s.ovn.org/exgw-bridge=true on the external gateway bridge (default: default gateway bridge only)",
+		Destination: &cliConfig.Gateway.ExGWBridgeServices,
+	},
+	&cli.StringFlag{
+		Name: "gateway-itp-local-exemptions",
+		Usage: "Comma separated list of the interfaces, IPs and subnets whose host traffic towards the " +
+			"internalTrafficPolicy=local services takes the normal path instead of being steered to OVN. " +
+			"Interfaces match the traffic routed out of them, IPs and subnets its source (default: none)",
+		Destination: &cliConfig.Gateway.ITPLocalExemptions,
+	},
+	&cli.BoolFlag{
+		Name: "gateway-sync-services-continue-on-error",
+		Usage: "Keep syncing the other services on startup when the endpointslices of a service cannot be " +
+			"retrieved, and report the errors at the end (default: abort the sync on the first error)",
+		Destination: &cliConfig.Gateway.SyncServicesContinueOnError,
+	},
+	&cli.BoolFlag{
+		Name: "gateway-externalip-source-preservation",
+		Usage: "Preserve the client source IP of the traffic towards the externalIPs of externalTrafficPolicy=cluster " +
+			"services by routing it into OVN via the management port. Shared gateway mode only",
+		Destination: &cliConfig.Gateway.ExternalIPSourcePreservation,
+	},
 	// Deprecated CLI options
 	&cli.BoolFlag{
 		Name:        "init-gateways",
@@ -1409,6 +1993,21 @@ var OvnKubeNodeFlags = []cli.Flag{
 		Value:       OvnKubeNode.MgmtPortDPResourceName,
 		Destination: &cliConfig.OvnKubeNode.MgmtPortDPResourceName,
 	},
+	&cli.StringFlag{
+		Name: "ovnkube-node-dpu-host-representor",
+		Usage: "When provided in dpu mode, use this port of the gateway bridge as the host representor " +
+			"instead of discovering it. Useful on DPUs with multiple host functions.",
+		Value:       OvnKubeNode.DPUHostRepresentor,
+		Destination: &cliConfig.OvnKubeNode.DPUHostRepresentor,
+	},
+	&cli.StringFlag{
+		Name: "ovnkube-node-dpu-host-representor-unavailable-action",
+		Usage: "In dpu mode, what happens to the gateway bridge flows depending on the host representor while " +
+			"it has no valid ofport. One of \"hold\", leaving them out until it is back, or \"drop\", dropping " +
+			"the traffic they output to it (default: hold)",
+		Value:       OvnKubeNode.DPUHostRepresentorUnavailableAction,
+		Destination: &cliConfig.OvnKubeNode.DPUHostRepresentorUnavailableAction,
+	},
 	&cli.BoolFlag{
 		Name:        "disable-ovn-iface-id-ver",
 		Usage:       "Deprecated; iface-id-ver is always enabled",
@@ -1721,6 +2320,160 @@ func buildGatewayConfig(ctx *cli.Context, cli, file *config) error {
 		return fmt.Errorf("gateway VLAN ID option: %d is supported only in shared gateway mode", Gateway.VLANID)
 	}
 
+	prefixes := Gateway.GetFlowTablePrefixes()
+	if len(prefixes) > maxFlowTablePrefixes {
+		return fmt.Errorf("invalid gateway flow table prefixes %q: at most %d prefixes are supported", Gateway.FlowTablePrefixes, maxFlowTablePrefixes)
+	}
+	for _, prefix := range prefixes {
+		if !validFlowTablePrefixes.Has(prefix) {
+			return fmt.Errorf("invalid gateway flow table prefix %q: expect one of %s", prefix, strings.Join(sets.List(validFlowTablePrefixes), ","))
+		}
+	}
+
+	if Gateway.EndpointSliceCoalescingWindow < 0 || Gateway.EndpointSliceCoalescingWindow > maxEndpointSliceCoalescingWindow {
+		return fmt.Errorf("invalid gateway endpointslice coalescing window %d: expect a value between 0 and %d milliseconds",
+			Gateway.EndpointSliceCoalescingWindow, maxEndpointSliceCoalescingWindow)
+	}
+
+	if Gateway.ConntrackDrainChunkSize < 0 {
+		return fmt.Errorf("invalid gateway conntrack drain chunk size %d: expect a value greater than or equal to 0",
+			Gateway.ConntrackDrainChunkSize)
+	}
+
+	if Gateway.MaxServiceConntrackDeletes < 0 {
+		return fmt.Errorf("invalid gateway max service conntrack deletes %d: expect a value greater than or equal to 0",
+			Gateway.MaxServiceConntrackDeletes)
+	}
+
+	if Gateway.ARPBypassFlowsMeterRate < 0 {
+		return fmt.Errorf("invalid gateway ARP bypass flows meter rate %d: expect a value greater than or equal to 0",
+			Gateway.ARPBypassFlowsMeterRate)
+	}
+
+	if Gateway.ServiceCIDRFlowBudget < 0 {
+		return fmt.Errorf("invalid gateway service CIDR flow budget %d: expect a value greater than or equal to 0",
+			Gateway.ServiceCIDRFlowBudget)
+	}
+
+	for _, portStr := range strings.Split(Gateway.BFDPorts, ",") {
+		portStr = strings.TrimSpace(portStr)
+		if portStr == "" {
+			continue
+		}
+		if port, err := strconv.Atoi(portStr); err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("invalid gateway BFD port %q: expect a port number between 1 and 65535", portStr)
+		}
+	}
+
+	if Gateway.ServiceVLANID > 4094 {
+		return fmt.Errorf("invalid gateway service VLAN ID %d: expect a value between 0 and 4094", Gateway.ServiceVLANID)
+	}
+
+	for _, exemption := range strings.Split(Gateway.ServiceCIDRExemptions, ",") {
+		exemption = strings.TrimSpace(exemption)
+		if exemption == "" {
+			continue
+		}
+		if _, _, err := utilnet.ParseCIDRSloppy(exemption); err != nil && utilnet.ParseIPSloppy(exemption) == nil {
+			return fmt.Errorf("invalid gateway service CIDR exemption %q: expect an IP or a CIDR", exemption)
+		}
+	}
+
+	for _, address := range strings.Split(Gateway.NodePortAddresses, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		if _, _, err := utilnet.ParseCIDRSloppy(address); err != nil {
+			return fmt.Errorf("invalid gateway NodePort address %q: expect a CIDR", address)
+		}
+	}
+
+	if err := validateExemptions("forwarding block exemption", Gateway.ForwardingBlockExemptions); err != nil {
+		return err
+	}
+
+	for _, action := range []string{Gateway.FallbackAction, Gateway.EgressGWFallbackAction} {
+		switch action {
+		case GatewayFallbackActionNormal, GatewayFallbackActionDrop:
+		default:
+			return fmt.Errorf("invalid gateway fallback action %q: expect one of %q or %q",
+				action, GatewayFallbackActionNormal, GatewayFallbackActionDrop)
+		}
+	}
+
+	switch Gateway.IPv6AdvertisementFlood {
+	case GatewayIPv6AdvertisementFlood, GatewayIPv6AdvertisementRestrict, GatewayIPv6AdvertisementDisable:
+	default:
+		return fmt.Errorf("invalid gateway IPv6 advertisement flood %q: expect one of %q, %q or %q",
+			Gateway.IPv6AdvertisementFlood, GatewayIPv6AdvertisementFlood, GatewayIPv6AdvertisementRestrict,
+			GatewayIPv6AdvertisementDisable)
+	}
+
+	if err := validateSourceIPsPerFamily("masquerade route source", Gateway.MasqueradeRouteSourceIPs); err != nil {
+		return err
+	}
+	if err := validateSourceIPsPerFamily("egress SNAT source", Gateway.EgressSNATSourceIPs); err != nil {
+		return err
+	}
+	if err := validateSourceIPsPerFamily("health check NodePort address", Gateway.HealthCheckNodePortAddresses); err != nil {
+		return err
+	}
+
+	if err := validateExemptions("internalTrafficPolicy=local exemption", Gateway.ITPLocalExemptions); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateExemptions validates a comma separated list of gateway exemptions,
+// interfaces, IPs or CIDRs, described by kind in the returned errors
+func validateExemptions(kind, exemptions string) error {
+	for _, exemption := range strings.Split(exemptions, ",") {
+		exemption = strings.TrimSpace(exemption)
+		if exemption == "" || utilnet.ParseIPSloppy(exemption) != nil {
+			continue
+		}
+		if strings.Contains(exemption, "/") {
+			if _, _, err := utilnet.ParseCIDRSloppy(exemption); err != nil {
+				return fmt.Errorf("invalid gateway %s %q: expect an interface, an IP or a CIDR", kind, exemption)
+			}
+			continue
+		}
+		// interface names are at most IFNAMSIZ - 1 characters long
+		if len(exemption) > 15 || strings.ContainsAny(exemption, " :") {
+			return fmt.Errorf("invalid gateway %s %q: expect an interface, an IP or a CIDR", kind, exemption)
+		}
+	}
+	return nil
+}
+
+// validateSourceIPsPerFamily validates a comma separated list of at most one
+// gateway source IP per family, described by kind in the returned errors
+func validateSourceIPsPerFamily(kind, sourceIPs string) error {
+	var hasV4SourceIP, hasV6SourceIP bool
+	for _, ipStr := range strings.Split(sourceIPs, ",") {
+		ipStr = strings.TrimSpace(ipStr)
+		if ipStr == "" {
+			continue
+		}
+		ip := utilnet.ParseIPSloppy(ipStr)
+		if ip == nil {
+			return fmt.Errorf("invalid gateway %s IP %q", kind, ipStr)
+		}
+		if utilnet.IsIPv6(ip) {
+			if hasV6SourceIP {
+				return fmt.Errorf("invalid gateway %s IPs %q: expect at most one IPv6 address", kind, sourceIPs)
+			}
+			hasV6SourceIP = true
+		} else {
+			if hasV4SourceIP {
+				return fmt.Errorf("invalid gateway %s IPs %q: expect at most one IPv4 address", kind, sourceIPs)
+			}
+			hasV4SourceIP = true
+		}
+	}
 	return nil
 }
 
@@ -1751,6 +2504,57 @@ func buildOVNKubernetesFeatureConfig(ctx *cli.Context, cli, file *config) error
 	if err := overrideFields(&OVNKubernetesFeature, &cli.OVNKubernetesFeature, &savedOVNKubernetesFeature); err != nil {
 		return err
 	}
+
+	if err := validateEgressServiceReroutePriority(OVNKubernetesFeature.EgressServiceReroutePriority); err != nil {
+		return err
+	}
+	switch OVNKubernetesFeature.EgressServiceRerouteTarget {
+	case EgressServiceRerouteManagementPort, EgressServiceRerouteGatewayRouter:
+	default:
+		return fmt.Errorf("invalid egress service reroute target %q: expect one of %q or %q",
+			OVNKubernetesFeature.EgressServiceRerouteTarget, EgressServiceRerouteManagementPort,
+			EgressServiceRerouteGatewayRouter)
+	}
+	for _, cidr := range strings.Split(OVNKubernetesFeature.EgressServiceRerouteExcludedCIDRs, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if _, _, err := utilnet.ParseCIDRSloppy(cidr); err != nil {
+			return fmt.Errorf("invalid egress service reroute excluded CIDR %q: %v", cidr, err)
+		}
+	}
+	return nil
+}
+
+// validateEgressServiceReroutePriority verifies that the egress service reroute
+// priority does not collide with the priorities of the other logical router
+// policies configured on the cluster router. It must be lower than the default
+// no reroute priority, otherwise the traffic of the endpoints towards the
+// cluster, service and join subnets would be rerouted to the egress node too.
+func validateEgressServiceReroutePriority(priority int) error {
+	if priority <= 0 || priority >= types.DefaultNoRereoutePriority {
+		return fmt.Errorf("invalid egress service reroute priority %d: must be between 1 and %d",
+			priority, types.DefaultNoRereoutePriority-1)
+	}
+
+	reserved := map[int]string{
+		types.HybridOverlaySubnetPriority:       "hybrid overlay subnet",
+		types.HybridOverlayReroutePriority:      "hybrid overlay reroute",
+		types.DefaultNoRereoutePriority:         "default no reroute",
+		types.EgressIPReroutePriority:           "egress IP reroute",
+		types.EgressLiveMigrationReroutePiority: "live migration reroute",
+	}
+	for _, p := range []string{types.MGMTPortPolicyPriority, types.NodeSubnetPolicyPriority, types.InterNodePolicyPriority} {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return err
+		}
+		reserved[v] = "node policies"
+	}
+	if feature, ok := reserved[priority]; ok {
+		return fmt.Errorf("invalid egress service reroute priority %d: it collides with the %s priority", priority, feature)
+	}
 	return nil
 }
 
@@ -2053,6 +2857,12 @@ func initConfigWithPath(ctx *cli.Context, exec kexec.Interface, saPath string, d
 	if err = overrideFields(&CNI, &cliConfig.CNI, &savedCNI); err != nil {
 		return "", err
 	}
+	if err = validateOVSTransactionTimeout(CNI.OVSTransactionTimeout); err != nil {
+		return "", err
+	}
+	if err = validateOVNInstalledPolling(CNI.OVNInstalledPollInterval, CNI.OVNInstalledPollMaxInterval); err != nil {
+		return "", err
+	}
 
 	// Logging setup
 	if err = overrideFields(&Logging, &cfg.Logging, &savedLogging); err != nil {
@@ -2491,5 +3301,15 @@ func buildOvnKubeNodeConfig(ctx *cli.Context, cli, file *config) error {
 	if OvnKubeNode.Mode == types.NodeModeDPUHost && OvnKubeNode.MgmtPortNetdev == "" && OvnKubeNode.MgmtPortDPResourceName == "" {
 		return fmt.Errorf("ovnkube-node-mgmt-port-netdev or ovnkube-node-mgmt-port-dp-resource-name must be provided")
 	}
+	if OvnKubeNode.Mode != types.NodeModeDPU && OvnKubeNode.DPUHostRepresentor != "" {
+		return fmt.Errorf("ovnkube-node-dpu-host-representor is only supported with ovnkube-node mode %s", types.NodeModeDPU)
+	}
+	switch OvnKubeNode.DPUHostRepresentorUnavailableAction {
+	case "", DPUHostRepresentorUnavailableActionHold, DPUHostRepresentorUnavailableActionDrop:
+	default:
+		return fmt.Errorf("invalid ovnkube-node-dpu-host-representor-unavailable-action %q: expect one of %q or %q",
+			OvnKubeNode.DPUHostRepresentorUnavailableAction, DPUHostRepresentorUnavailableActionHold,
+			DPUHostRepresentorUnavailableActionDrop)
+	}
 	return nil
 }
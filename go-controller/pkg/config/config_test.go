@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -313,12 +314,44 @@ var _ = Describe("Config Operations", func() {
 			gomega.Expect(OvnKubeNode.Mode).To(gomega.Equal(types.NodeModeFull))
 			gomega.Expect(OvnKubeNode.MgmtPortNetdev).To(gomega.Equal(""))
 			gomega.Expect(OvnKubeNode.MgmtPortDPResourceName).To(gomega.Equal(""))
+			gomega.Expect(OvnKubeNode.DPUHostRepresentor).To(gomega.Equal(""))
+			gomega.Expect(OvnKubeNode.DPUHostRepresentorUnavailableAction).To(gomega.Equal(DPUHostRepresentorUnavailableActionHold))
 			gomega.Expect(Gateway.RouterSubnet).To(gomega.Equal(""))
 			gomega.Expect(Gateway.SingleNode).To(gomega.BeFalse())
 			gomega.Expect(Gateway.DisableForwarding).To(gomega.BeFalse())
 			gomega.Expect(Gateway.AllowNoUplink).To(gomega.BeFalse())
+			gomega.Expect(Gateway.GetFlowTablePrefixes()).To(gomega.BeEmpty())
+			gomega.Expect(Gateway.EndpointSliceCoalescingWindow).To(gomega.Equal(0))
+			gomega.Expect(Gateway.HostNetworkEndpointsAllHostIPs).To(gomega.BeFalse())
+			gomega.Expect(Gateway.ConntrackDrainChunkSize).To(gomega.Equal(0))
+			gomega.Expect(Gateway.DisableConntrackFlush).To(gomega.BeFalse())
+			gomega.Expect(Gateway.MaxServiceConntrackDeletes).To(gomega.Equal(0))
+			gomega.Expect(Gateway.ConntrackDeleteReplyDirection).To(gomega.BeFalse())
+			gomega.Expect(Gateway.ExGWBridgeServices).To(gomega.BeFalse())
+			gomega.Expect(Gateway.SyncServicesContinueOnError).To(gomega.BeFalse())
+			gomega.Expect(Gateway.ExternalIPSourcePreservation).To(gomega.BeFalse())
+			interfaces, subnets := Gateway.GetITPLocalExemptions()
+			gomega.Expect(interfaces).To(gomega.BeEmpty())
+			gomega.Expect(subnets).To(gomega.BeEmpty())
+			gomega.Expect(Gateway.GetNodePortInterfaces()).To(gomega.BeEmpty())
+			gomega.Expect(Gateway.PerServiceETPCookies).To(gomega.BeFalse())
+			gomega.Expect(Gateway.ServiceCIDRFlowBudget).To(gomega.Equal(64))
+			gomega.Expect(Gateway.GetMasqueradeRouteSourceIPs()).To(gomega.BeEmpty())
+			gomega.Expect(Gateway.GetEgressSNATSourceIPs()).To(gomega.BeEmpty())
+			gomega.Expect(Gateway.GetNodePortAddresses()).To(gomega.BeEmpty())
+			gomega.Expect(Gateway.IPv6AdvertisementFlood).To(gomega.Equal(GatewayIPv6AdvertisementFlood))
+			gomega.Expect(Gateway.GetHealthCheckNodePortAddresses()).To(gomega.BeEmpty())
+			gomega.Expect(Gateway.GetServiceCIDRExemptions()).To(gomega.BeEmpty())
+			gomega.Expect(Gateway.ExternalNameServiceIPs).To(gomega.BeFalse())
+			gomega.Expect(Gateway.HeadlessServiceExternalIPs).To(gomega.BeFalse())
+			gomega.Expect(Gateway.SkipNodeIPExternalIPs).To(gomega.BeFalse())
+			gomega.Expect(Gateway.GetBFDPorts()).To(gomega.Equal([]int{3784}))
+			gomega.Expect(Gateway.ServiceVLANID).To(gomega.Equal(uint(0)))
 			gomega.Expect(OVNKubernetesFeature.EgressIPReachabiltyTotalTimeout).To(gomega.Equal(1))
 			gomega.Expect(OVNKubernetesFeature.EgressIPNodeHealthCheckPort).To(gomega.Equal(0))
+			gomega.Expect(OVNKubernetesFeature.EgressServiceReroutePriority).To(gomega.Equal(types.EgressSVCReroutePriority))
+			gomega.Expect(OVNKubernetesFeature.EgressServiceRerouteTarget).To(gomega.Equal(EgressServiceRerouteManagementPort))
+			gomega.Expect(OVNKubernetesFeature.GetEgressServiceRerouteExcludedCIDRs()).To(gomega.BeEmpty())
 			gomega.Expect(OVNKubernetesFeature.EnableMultiNetwork).To(gomega.BeFalse())
 			gomega.Expect(OVNKubernetesFeature.EnableMultiNetworkPolicy).To(gomega.BeFalse())
 			gomega.Expect(OVNKubernetesFeature.EnableInterconnect).To(gomega.BeFalse())
@@ -1056,6 +1089,446 @@ enable-pprof=true
 		err := app.Run(cliArgs)
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
 	})
+	It("returns an error when a gateway flow table prefix is invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("invalid gateway flow table prefix \"tp_dst\"")))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-flow-table-prefixes=ip_dst,tp_dst",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when too many gateway flow table prefixes are specified", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway flow table prefixes \"ip_dst,ipv6_dst,ip_src,ipv6_src\": at most 3 prefixes are supported"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-flow-table-prefixes=ip_dst,ipv6_dst,ip_src,ipv6_src",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("parses the gateway flow table prefixes", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(Gateway.GetFlowTablePrefixes()).To(gomega.Equal([]string{"ip_dst", "ipv6_dst"}))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-flow-table-prefixes=ip_dst, ipv6_dst",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the gateway endpointslice coalescing window is out of range", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway endpointslice coalescing window 10000: expect a value between 0 and 5000 milliseconds"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-endpointslice-coalescing-window=10000",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the gateway conntrack drain chunk size is negative", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway conntrack drain chunk size -1: expect a value greater than or equal to 0"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-conntrack-drain-chunk-size=-1",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the gateway max service conntrack deletes is negative", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway max service conntrack deletes -1: expect a value greater than or equal to 0"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-max-service-conntrack-deletes=-1",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the gateway masquerade route source IPs are invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway masquerade route source IPs \"10.0.0.5,10.0.0.6\": expect at most one IPv4 address"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-masquerade-route-source-ips=10.0.0.5,10.0.0.6",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("parses the gateway masquerade route source IPs", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(Gateway.GetMasqueradeRouteSourceIPs()).To(gomega.Equal(ovntest.MustParseIPs("10.0.0.5", "fd00::5")))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-masquerade-route-source-ips=10.0.0.5, fd00::5",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the gateway egress SNAT source IPs have several IPs of a family", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway egress SNAT source IPs \"fd00::5,fd00::6\": expect at most one IPv6 address"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-egress-snat-source-ips=fd00::5,fd00::6",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("parses the gateway egress SNAT source IPs", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(Gateway.GetEgressSNATSourceIPs()).To(gomega.Equal(ovntest.MustParseIPs("10.0.0.5", "fd00::5")))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-egress-snat-source-ips=10.0.0.5, fd00::5",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the gateway health check NodePort addresses have several IPs of a family", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError(
+				"invalid gateway health check NodePort address IPs \"10.0.0.5,10.0.0.6\": expect at most one IPv4 address"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-health-check-nodeport-addresses=10.0.0.5,10.0.0.6",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("parses the gateway health check NodePort addresses", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(Gateway.GetHealthCheckNodePortAddresses()).To(gomega.Equal(ovntest.MustParseIPs("10.0.0.5", "fd00::5")))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-health-check-nodeport-addresses=10.0.0.5,fd00::5",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when a gateway NodePort address is invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway NodePort address \"192.168.1.10\": expect a CIDR"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-nodeport-addresses=10.0.0.0/24,192.168.1.10",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("parses the gateway NodePort addresses", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(Gateway.GetNodePortAddresses()).To(gomega.Equal([]*net.IPNet{
+				ovntest.MustParseIPNet("10.0.0.0/24"),
+				ovntest.MustParseIPNet("fd00::/64"),
+			}))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-nodeport-addresses=10.0.0.0/24, fd00::/64",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when a gateway service CIDR exemption is invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway service CIDR exemption \"10.96.0.300\": expect an IP or a CIDR"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-service-cidr-exemptions=169.254.20.10,10.96.0.300",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("parses the gateway service CIDR exemptions", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(Gateway.GetServiceCIDRExemptions()).To(gomega.Equal([]*net.IPNet{
+				ovntest.MustParseIPNet("169.254.20.10/32"),
+				ovntest.MustParseIPNet("10.96.0.0/28"),
+				ovntest.MustParseIPNet("fd00::10/128"),
+			}))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-service-cidr-exemptions=169.254.20.10, 10.96.0.0/28,fd00::10",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when a gateway forwarding block exemption is invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway forwarding block exemption \"10.0.0.0/33\": expect an interface, an IP or a CIDR"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-forwarding-block-exemptions=eth2,10.0.0.0/33",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when a gateway internalTrafficPolicy=local exemption is invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway internalTrafficPolicy=local exemption \"fd00::/129\": expect an interface, an IP or a CIDR"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-itp-local-exemptions=eth2,fd00::/129",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("parses the gateway internalTrafficPolicy=local exemptions", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			interfaces, subnets := Gateway.GetITPLocalExemptions()
+			gomega.Expect(interfaces).To(gomega.Equal([]string{"mon0"}))
+			gomega.Expect(subnets).To(gomega.Equal([]*net.IPNet{
+				ovntest.MustParseIPNet("10.0.0.5/32"),
+				ovntest.MustParseIPNet("fd00::/64"),
+			}))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-itp-local-exemptions=mon0, 10.0.0.5,fd00::/64",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("parses the gateway forwarding block exemptions", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			interfaces, subnets := Gateway.GetForwardingBlockExemptions()
+			gomega.Expect(interfaces).To(gomega.Equal([]string{"eth2"}))
+			gomega.Expect(subnets).To(gomega.Equal([]*net.IPNet{
+				ovntest.MustParseIPNet("192.168.100.0/24"),
+				ovntest.MustParseIPNet("fd00::10/128"),
+			}))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-forwarding-block-exemptions=eth2, 192.168.100.0/24,fd00::10",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the gateway ARP bypass flows meter rate is negative", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway ARP bypass flows meter rate -1: expect a value greater than or equal to 0"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-arp-bypass-flows-meter-rate=-1",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the CNI OVS transaction timeout is out of range", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid OVS transaction timeout 600: expect a value between 1 and 120 seconds"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-cni-ovs-transaction-timeout=600",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("writes the CNI OVS transaction timeout to the CNI config", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(CNI.OVSTransactionTimeout).To(gomega.Equal(30))
+			gomega.Expect(WriteCNIConfig()).To(gomega.Succeed())
+			bytes, err := os.ReadFile(filepath.Join(CNI.ConfDir, CNIConfFileName))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			netConf, err := ReadCNIConfig(bytes)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(netConf.OVSTransactionTimeout).To(gomega.Equal(30))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-cni-conf-dir=" + tmpDir,
+			"-cni-ovs-transaction-timeout=30",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the ovn-installed poll interval is not shorter than the CNI request timeout", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid ovn-installed poll interval 120000: expect a value between 0 and 119999 milliseconds"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-cni-ovn-installed-poll-interval=120000",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the ovn-installed poll max interval is lower than the poll interval", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid ovn-installed poll max interval 100: expect a value not lower than the poll interval 500"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-cni-ovn-installed-poll-interval=500",
+			"-cni-ovn-installed-poll-max-interval=100",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the gateway fallback action is invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError(`invalid gateway fallback action "controller": expect one of "normal" or "drop"`))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-exgw-fallback-action=controller",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the gateway IPv6 advertisement flood is invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError(
+				`invalid gateway IPv6 advertisement flood "drop": expect one of "flood", "restrict" or "disable"`))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-ipv6-advertisement-flood=drop",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the gateway service CIDR flow budget is negative", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway service CIDR flow budget -1: expect a value greater than or equal to 0"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-service-cidr-flow-budget=-1",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when a gateway BFD port is invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway BFD port \"65536\": expect a port number between 1 and 65535"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-bfd-ports=3784,65536",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("parses the gateway BFD ports", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(Gateway.GetBFDPorts()).To(gomega.Equal([]int{3784, 3785}))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-bfd-ports=3784, 3785",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the gateway service VLAN ID is invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway service VLAN ID 4095: expect a value between 0 and 4094"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-service-vlan-id=4095",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
 	It("returns an error when the v4 join subnet specified is invalid", func() {
 		app.Action = func(ctx *cli.Context) error {
 			_, err := InitConfig(ctx, kexec.New(), nil)
@@ -1082,6 +1555,104 @@ enable-pprof=true
 		err := app.Run(cliArgs)
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
 	})
+	It("returns an error when the egress service reroute priority collides with another feature", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid egress service reroute priority 100: it collides with the egress IP reroute priority"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-egress-service-reroute-priority=100",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the egress service reroute priority is in the egress firewall range", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid egress service reroute priority 2000: must be between 1 and 101"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-egress-service-reroute-priority=2000",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the egress service reroute priority outranks the default no reroute priority", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid egress service reroute priority 500: must be between 1 and 101"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-egress-service-reroute-priority=500",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("allows configuring a custom egress service reroute priority", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(OVNKubernetesFeature.EgressServiceReroutePriority).To(gomega.Equal(90))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-egress-service-reroute-priority=90",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when the egress service reroute target is invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid egress service reroute target \"join-port\": expect one of \"management-port\" or \"gateway-router\""))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-egress-service-reroute-target=join-port",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("returns an error when an egress service reroute excluded CIDR is invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(
+				"invalid egress service reroute excluded CIDR \"10.128.0.0/33\"")))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-egress-service-reroute-excluded-cidrs=10.128.0.0/14,10.128.0.0/33",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+	It("parses the egress service reroute excluded CIDRs", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			cidrs := []string{}
+			for _, cidr := range OVNKubernetesFeature.GetEgressServiceRerouteExcludedCIDRs() {
+				cidrs = append(cidrs, cidr.String())
+			}
+			gomega.Expect(cidrs).To(gomega.Equal([]string{"10.128.0.0/14", "fd00:10:128::/48"}))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-egress-service-reroute-excluded-cidrs=10.128.0.0/14, fd00:10:128::/48",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
 	It("overrides config file and defaults with CLI options (multi-master)", func() {
 		kubeconfigFile, _, err := createTempFile("kubeconfig")
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
@@ -1781,6 +2352,31 @@ foo=bar
 			gomega.Expect(err.Error()).To(gomega.ContainSubstring("ovnkube-node-mgmt-port-netdev or ovnkube-node-mgmt-port-dp-resource-name must be provided"))
 		})
 
+		It("Fails if the host representor unavailable action is invalid", func() {
+			cliConfig := config{
+				OvnKubeNode: OvnKubeNodeConfig{
+					Mode:                                types.NodeModeDPU,
+					DPUHostRepresentorUnavailableAction: "normal",
+				},
+			}
+			err := buildOvnKubeNodeConfig(nil, &cliConfig, &config{})
+			gomega.Expect(err).To(gomega.HaveOccurred())
+			gomega.Expect(err.Error()).To(gomega.ContainSubstring("invalid ovnkube-node-dpu-host-representor-unavailable-action \"normal\": expect one of \"hold\" or \"drop\""))
+		})
+
+		It("Fails if the host representor is provided and ovnkube node mode is not dpu", func() {
+			cliConfig := config{
+				OvnKubeNode: OvnKubeNodeConfig{
+					Mode:               types.NodeModeDPUHost,
+					MgmtPortNetdev:     "enp1s0f0v0",
+					DPUHostRepresentor: "pf0hpf",
+				},
+			}
+			err := buildOvnKubeNodeConfig(nil, &cliConfig, &config{})
+			gomega.Expect(err).To(gomega.HaveOccurred())
+			gomega.Expect(err.Error()).To(gomega.ContainSubstring("ovnkube-node-dpu-host-representor is only supported with ovnkube-node mode dpu"))
+		})
+
 		It("Succeeds if management netdev provided in the full mode", func() {
 			cliConfig := config{
 				OvnKubeNode: OvnKubeNodeConfig{
@@ -62,6 +62,14 @@ type EgressServiceSpec struct {
 	// of a routing table which by omission uses the default host routing.
 	// +optional
 	Network string `json:"network,omitempty"`
+
+	// Restricts the egress service to the endpoints of a single IP family of a dual-stack service.
+	// When `IPv4` only the egress traffic of the IPv4 endpoints is handled, and when `IPv6` only
+	// the egress traffic of the IPv6 endpoints is. The endpoints of the other family egress
+	// the cluster as if the service was not an egress service.
+	// When it is not specified the endpoints of both IP families are handled.
+	// +optional
+	IPFamily IPFamilyMode `json:"ipFamily,omitempty"`
 }
 
 // +kubebuilder:validation:Enum=LoadBalancerIP;Network
@@ -75,6 +83,17 @@ const (
 	SourceIPNetwork SourceIPMode = "Network"
 )
 
+// +kubebuilder:validation:Enum=IPv4;IPv6
+type IPFamilyMode string
+
+const (
+	// IPFamilyIPv4 restricts the egress service to the IPv4 endpoints of the service.
+	IPFamilyIPv4 IPFamilyMode = "IPv4"
+
+	// IPFamilyIPv6 restricts the egress service to the IPv6 endpoints of the service.
+	IPFamilyIPv6 IPFamilyMode = "IPv6"
+)
+
 // EgressServiceStatus defines the observed state of EgressService
 type EgressServiceStatus struct {
 	// The name of the node selected to handle the service's traffic.
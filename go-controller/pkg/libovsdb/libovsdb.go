@@ -30,8 +30,20 @@ import (
 // the stopCh is required to ensure the goroutine for ssl cert
 // update is not leaked
 func newClient(cfg config.OvnAuthConfig, dbModel model.ClientDBModel, stopCh <-chan struct{}, opts ...client.Option) (client.Client, error) {
-	const connectTimeout time.Duration = types.OVSDBTimeout * 2
-	const inactivityTimeout time.Duration = types.OVSDBTimeout * 18
+	return newClientWithTimeout(cfg, dbModel, stopCh, types.OVSDBTimeout, opts...)
+}
+
+// clientTimeouts returns the connect and inactivity timeouts of a client
+// whose OVSDB transactions are expected to complete within timeout
+func clientTimeouts(timeout time.Duration) (connectTimeout, inactivityTimeout time.Duration) {
+	return timeout * 2, timeout * 18
+}
+
+// newClientWithTimeout creates a new client object given the provided config,
+// deriving its connect and inactivity timeouts from the provided OVSDB timeout
+func newClientWithTimeout(cfg config.OvnAuthConfig, dbModel model.ClientDBModel, stopCh <-chan struct{},
+	timeout time.Duration, opts ...client.Option) (client.Client, error) {
+	connectTimeout, inactivityTimeout := clientTimeouts(timeout)
 	logger := klogr.New()
 	options := []client.Option{
 		// Reading and parsing the DB after reconnect at scale can (unsurprisingly)
@@ -79,26 +91,34 @@ func newClient(cfg config.OvnAuthConfig, dbModel model.ClientDBModel, stopCh <-c
 	return client, nil
 }
 
-// NewVSwitchClient creates a new client to the local ovs-vswitchd database
-func NewVSwitchClient(stopCh <-chan struct{}) (client.Client, error) {
-	return NewVSwitchClientWithConfig(config.OvnAuthConfig{
+// NewVSwitchClient creates a new client to the local ovs-vswitchd database.
+// A zero timeout selects the default OVSDB timeout.
+func NewVSwitchClient(stopCh <-chan struct{}, timeout time.Duration) (client.Client, error) {
+	return newVSwitchClient(config.OvnAuthConfig{
 		Address: "unix:///var/run/openvswitch/db.sock",
 		Scheme:  config.OvnDBSchemeUnix,
-	}, stopCh)
+	}, stopCh, timeout)
 }
 
 // NewVSwitchClientWithConfig creates a new client with the given config
 func NewVSwitchClientWithConfig(cfg config.OvnAuthConfig, stopCh <-chan struct{}) (client.Client, error) {
+	return newVSwitchClient(cfg, stopCh, types.OVSDBTimeout)
+}
+
+func newVSwitchClient(cfg config.OvnAuthConfig, stopCh <-chan struct{}, timeout time.Duration) (client.Client, error) {
+	if timeout == 0 {
+		timeout = types.OVSDBTimeout
+	}
 	dbModel, err := vswitchdb.FullDatabaseModel()
 	if err != nil {
 		return nil, err
 	}
-	c, err := newClient(cfg, dbModel, stopCh)
+	c, err := newClientWithTimeout(cfg, dbModel, stopCh, timeout)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), types.OVSDBTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	go func() {
 		<-stopCh
 		cancel()
@@ -110,6 +130,7 @@ func NewVSwitchClientWithConfig(cfg config.OvnAuthConfig, stopCh <-chan struct{}
 			client.WithTable(&vswitchdb.Interface{}),
 			client.WithTable(&vswitchdb.Port{}),
 			client.WithTable(&vswitchdb.QoS{}),
+			client.WithTable(&vswitchdb.FlowTable{}),
 		),
 	)
 	if err != nil {
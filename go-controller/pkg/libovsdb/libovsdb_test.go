@@ -0,0 +1,41 @@
+package libovsdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
+)
+
+func TestClientTimeouts(t *testing.T) {
+	tests := []struct {
+		name                      string
+		timeout                   time.Duration
+		expectedConnectTimeout    time.Duration
+		expectedInactivityTimeout time.Duration
+	}{
+		{
+			name:                      "default OVSDB timeout",
+			timeout:                   types.OVSDBTimeout,
+			expectedConnectTimeout:    20 * time.Second,
+			expectedInactivityTimeout: 180 * time.Second,
+		},
+		{
+			name:                      "configured OVS transaction timeout",
+			timeout:                   30 * time.Second,
+			expectedConnectTimeout:    60 * time.Second,
+			expectedInactivityTimeout: 540 * time.Second,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			connectTimeout, inactivityTimeout := clientTimeouts(tc.timeout)
+			if connectTimeout != tc.expectedConnectTimeout {
+				t.Errorf("expected connect timeout %v, got %v", tc.expectedConnectTimeout, connectTimeout)
+			}
+			if inactivityTimeout != tc.expectedInactivityTimeout {
+				t.Errorf("expected inactivity timeout %v, got %v", tc.expectedInactivityTimeout, inactivityTimeout)
+			}
+		})
+	}
+}
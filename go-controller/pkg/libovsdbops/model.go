@@ -79,6 +79,8 @@ func getUUID(model model.Model) string {
 		return t.UUID
 	case *vswitchdb.QoS:
 		return t.UUID
+	case *vswitchdb.FlowTable:
+		return t.UUID
 	default:
 		panic(fmt.Sprintf("getUUID: unknown model %T", t))
 	}
@@ -150,6 +152,8 @@ func setUUID(model model.Model, uuid string) {
 		t.UUID = uuid
 	case *vswitchdb.QoS:
 		t.UUID = uuid
+	case *vswitchdb.FlowTable:
+		t.UUID = uuid
 	default:
 		panic(fmt.Sprintf("setUUID: unknown model %T", t))
 	}
@@ -316,6 +320,10 @@ func copyIndexes(model model.Model) model.Model {
 		return &vswitchdb.QoS{
 			UUID: t.UUID,
 		}
+	case *vswitchdb.FlowTable:
+		return &vswitchdb.FlowTable{
+			UUID: t.UUID,
+		}
 	default:
 		panic(fmt.Sprintf("copyIndexes: unknown model %T", t))
 	}
@@ -385,6 +393,8 @@ func getListFromModel(model model.Model) interface{} {
 		return &[]vswitchdb.Port{}
 	case *vswitchdb.QoS:
 		return &[]vswitchdb.QoS{}
+	case *vswitchdb.FlowTable:
+		return &[]vswitchdb.FlowTable{}
 	default:
 		panic(fmt.Sprintf("getModelList: unknown model %T", t))
 	}
@@ -106,6 +106,33 @@ func buildMutationsFromFields(fields []interface{}, mutator ovsdb.Mutator) ([]mo
 				Value:   *v,
 			}
 			mutations = append(mutations, mutation)
+		case *map[int]string:
+			if v == nil || len(*v) == 0 {
+				continue
+			}
+			// maps keyed by integers reference rows by a number (e.g. the
+			// bridge flow tables keyed by OpenFlow table number) so they are
+			// always mutated by key: existing keys are removed first and
+			// then, unless deleting, inserted with their new value.
+			removeKeys := make([]int, 0, len(*v))
+			for key := range *v {
+				removeKeys = append(removeKeys, key)
+			}
+			mutation := model.Mutation{
+				Field:   field,
+				Mutator: ovsdb.MutateOperationDelete,
+				Value:   removeKeys,
+			}
+			mutations = append(mutations, mutation)
+			if mutator == ovsdb.MutateOperationDelete {
+				continue
+			}
+			mutation = model.Mutation{
+				Field:   field,
+				Mutator: mutator,
+				Value:   *v,
+			}
+			mutations = append(mutations, mutation)
 		case *[]string:
 			if v == nil || len(*v) == 0 {
 				continue
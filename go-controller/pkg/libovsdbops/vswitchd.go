@@ -173,6 +173,199 @@ func FindBridgeByName(vsClient libovsdbclient.Client, bridgeName string) (*vswit
 	return bridge, nil
 }
 
+// NewFlowTable builds a Flow_Table with the given name, flow limit and overflow
+// policy. An empty name or policy, or a limit that is not positive, leaves the
+// corresponding column unset, in which case OVS does not limit the number of
+// flows and refuses new flows on overflow.
+func NewFlowTable(name string, limit int, policy vswitchdb.FlowTableOverflowPolicy) *vswitchdb.FlowTable {
+	flowTable := &vswitchdb.FlowTable{}
+	if name != "" {
+		flowTable.Name = &name
+	}
+	if limit > 0 {
+		flowTable.FlowLimit = &limit
+	}
+	if policy != "" {
+		flowTable.OverflowPolicy = &policy
+	}
+	return flowTable
+}
+
+// GetFlowTableName returns the Flow_Table name if it has one otherwise returns
+// an empty string.
+func GetFlowTableName(flowTable *vswitchdb.FlowTable) string {
+	if flowTable.Name != nil {
+		return *flowTable.Name
+	}
+	return ""
+}
+
+// GetFlowTableFlowLimit returns the Flow_Table flow limit and whether it is
+// set at all.
+func GetFlowTableFlowLimit(flowTable *vswitchdb.FlowTable) (int, bool) {
+	if flowTable.FlowLimit != nil {
+		return *flowTable.FlowLimit, true
+	}
+	return 0, false
+}
+
+// GetFlowTableOverflowPolicy returns the Flow_Table overflow policy if it has
+// one otherwise returns an empty string.
+func GetFlowTableOverflowPolicy(flowTable *vswitchdb.FlowTable) vswitchdb.FlowTableOverflowPolicy {
+	if flowTable.OverflowPolicy != nil {
+		return *flowTable.OverflowPolicy
+	}
+	return ""
+}
+
+// FindFlowTableByName looks up a Flow_Table from the cache by name
+func FindFlowTableByName(vsClient libovsdbclient.Client, name string) (*vswitchdb.FlowTable, error) {
+	found := []*vswitchdb.FlowTable{}
+	opModel := operationModel{
+		Model: &vswitchdb.FlowTable{},
+		ModelPredicate: func(item *vswitchdb.FlowTable) bool {
+			return GetFlowTableName(item) == name
+		},
+		ExistingResult: &found,
+		ErrNotFound:    true,
+		BulkOp:         false,
+	}
+
+	m := newModelClient(vsClient)
+	if err := m.Lookup(opModel); err != nil {
+		return nil, fmt.Errorf("error looking up Flow_Table %q: %w", name, err)
+	}
+
+	return found[0], nil
+}
+
+// SetFlowTablePrefixes sets the fields OVS builds prefix trees for when
+// looking up flows in the Flow_Table with the given name. An empty list of
+// prefixes disables prefix tree lookups.
+func SetFlowTablePrefixes(vsClient libovsdbclient.Client, name string, prefixes []string) error {
+	flowTable := &vswitchdb.FlowTable{
+		Prefixes: prefixes,
+	}
+	opModel := operationModel{
+		Model: flowTable,
+		ModelPredicate: func(item *vswitchdb.FlowTable) bool {
+			return GetFlowTableName(item) == name
+		},
+		OnModelUpdates: []interface{}{&flowTable.Prefixes},
+		ErrNotFound:    true,
+		BulkOp:         false,
+	}
+
+	m := newModelClient(vsClient)
+	if _, err := m.Update(opModel); err != nil {
+		return fmt.Errorf("failed to set prefixes %v on Flow_Table %q: %w", prefixes, name, err)
+	}
+	return nil
+}
+
+// CreateOrUpdateBridgeFlowTable creates or updates the provided Flow_Table,
+// looked up by name, and sets it as the flow table of the given OpenFlow table
+// number of the bridge. Both are done in the same transaction as a Flow_Table
+// that is not referenced by any bridge is garbage collected by OVSDB.
+func CreateOrUpdateBridgeFlowTable(vsClient libovsdbclient.Client, bridgeName string, tableNum int, flowTable *vswitchdb.FlowTable) error {
+	name := GetFlowTableName(flowTable)
+	if name == "" {
+		return fmt.Errorf("failed to create/update flow table %d of bridge %s: Flow_Table has no name", tableNum, bridgeName)
+	}
+	bridge := &vswitchdb.Bridge{
+		Name: bridgeName,
+	}
+	opModels := []operationModel{
+		{
+			Model: flowTable,
+			ModelPredicate: func(item *vswitchdb.FlowTable) bool {
+				return GetFlowTableName(item) == name
+			},
+			OnModelUpdates: onModelUpdatesAllNonDefault(),
+			DoAfter: func() {
+				bridge.FlowTables = map[int]string{tableNum: flowTable.UUID}
+			},
+			ErrNotFound: false,
+			BulkOp:      false,
+		},
+		{
+			Model:            bridge,
+			OnModelMutations: []interface{}{&bridge.FlowTables},
+			ErrNotFound:      true,
+			BulkOp:           false,
+		},
+	}
+
+	m := newModelClient(vsClient)
+	if _, err := m.CreateOrUpdate(opModels...); err != nil {
+		return fmt.Errorf("failed to create/update flow table %d of bridge %s: %w", tableNum, bridgeName, err)
+	}
+	return nil
+}
+
+// GetBridgeFlowTables returns the UUIDs of the Flow_Tables set for the
+// OpenFlow table numbers of the given bridge
+func GetBridgeFlowTables(vsClient libovsdbclient.Client, bridgeName string) (map[int]string, error) {
+	found := []*vswitchdb.Bridge{}
+	opModel := operationModel{
+		Model:          &vswitchdb.Bridge{Name: bridgeName},
+		ExistingResult: &found,
+		ErrNotFound:    true,
+		BulkOp:         false,
+	}
+
+	m := newModelClient(vsClient)
+	if err := m.Lookup(opModel); err != nil {
+		return nil, fmt.Errorf("error looking up Bridge %q: %w", bridgeName, err)
+	}
+	return found[0].FlowTables, nil
+}
+
+// SetBridgeFlowTable sets the Flow_Table with the given UUID as the flow table
+// of the given OpenFlow table number of the bridge, replacing any Flow_Table
+// previously set for that table number.
+func SetBridgeFlowTable(vsClient libovsdbclient.Client, bridgeName string, tableNum int, flowTableUUID string) error {
+	bridge := &vswitchdb.Bridge{
+		Name:       bridgeName,
+		FlowTables: map[int]string{tableNum: flowTableUUID},
+	}
+	opModel := operationModel{
+		Model:            bridge,
+		OnModelMutations: []interface{}{&bridge.FlowTables},
+		ErrNotFound:      true,
+		BulkOp:           false,
+	}
+
+	m := newModelClient(vsClient)
+	if _, err := m.CreateOrUpdate(opModel); err != nil {
+		return fmt.Errorf("failed to set flow table %d of bridge %s: %w", tableNum, bridgeName, err)
+	}
+	return nil
+}
+
+// RemoveBridgeFlowTable removes the Flow_Table set for the given OpenFlow
+// table number of the bridge, if any. The Flow_Table itself is garbage
+// collected by OVSDB once no bridge references it.
+func RemoveBridgeFlowTable(vsClient libovsdbclient.Client, bridgeName string, tableNum int) error {
+	bridge := &vswitchdb.Bridge{
+		Name: bridgeName,
+		// the value is ignored, the reference is removed by key
+		FlowTables: map[int]string{tableNum: ""},
+	}
+	opModel := operationModel{
+		Model:            bridge,
+		OnModelMutations: []interface{}{&bridge.FlowTables},
+		ErrNotFound:      false,
+		BulkOp:           false,
+	}
+
+	m := newModelClient(vsClient)
+	if err := m.Delete(opModel); err != nil {
+		return fmt.Errorf("failed to remove flow table %d of bridge %s: %w", tableNum, bridgeName, err)
+	}
+	return nil
+}
+
 // DeletePorts deletes the given OVS ports by name
 func DeletePort(vsClient libovsdbclient.Client, bridgeName, portName string) error {
 	m := newModelClient(vsClient)
@@ -0,0 +1,207 @@
+package libovsdbops
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	libovsdbtest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/libovsdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/vswitchdb"
+)
+
+func TestSetFlowTablePrefixes(t *testing.T) {
+	flowTableName := "ovnkube-services"
+	flowTableUUID := buildNamedUUID()
+
+	tests := []struct {
+		desc             string
+		initialPrefixes  []string
+		prefixes         []string
+		flowTableName    string
+		expectedPrefixes []string
+		expectErr        bool
+	}{
+		{
+			desc:             "sets the prefixes on a Flow_Table without prefixes",
+			prefixes:         []string{"ip_dst", "ipv6_dst"},
+			flowTableName:    flowTableName,
+			expectedPrefixes: []string{"ip_dst", "ipv6_dst"},
+		},
+		{
+			desc:             "updates the prefixes of a Flow_Table",
+			initialPrefixes:  []string{"ip_src"},
+			prefixes:         []string{"ip_dst"},
+			flowTableName:    flowTableName,
+			expectedPrefixes: []string{"ip_dst"},
+		},
+		{
+			desc:             "clears the prefixes of a Flow_Table",
+			initialPrefixes:  []string{"ip_dst", "ipv6_dst"},
+			prefixes:         []string{},
+			flowTableName:    flowTableName,
+			expectedPrefixes: nil,
+		},
+		{
+			desc:             "fails if the Flow_Table does not exist",
+			initialPrefixes:  []string{"ip_src"},
+			prefixes:         []string{"ip_dst"},
+			flowTableName:    "does-not-exist",
+			expectedPrefixes: []string{"ip_src"},
+			expectErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+
+			name := flowTableName
+			flowTable := &vswitchdb.FlowTable{
+				UUID:     flowTableUUID,
+				Name:     &name,
+				Prefixes: tt.initialPrefixes,
+			}
+			bridge := &vswitchdb.Bridge{
+				UUID:       buildNamedUUID(),
+				Name:       "br-ex",
+				FlowTables: map[int]string{0: flowTableUUID},
+			}
+			vsClient, cleanup, err := libovsdbtest.NewVSTestHarness(libovsdbtest.TestSetup{
+				VSData: []libovsdbtest.TestData{bridge, flowTable},
+			}, nil)
+			if err != nil {
+				t.Fatalf("test: \"%s\" failed to set up test harness: %v", tt.desc, err)
+			}
+			t.Cleanup(cleanup.Cleanup)
+
+			err = SetFlowTablePrefixes(vsClient, tt.flowTableName, tt.prefixes)
+			if tt.expectErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(gomega.HaveOccurred())
+			}
+
+			found, err := FindFlowTableByName(vsClient, flowTableName)
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			if tt.expectedPrefixes == nil {
+				g.Expect(found.Prefixes).To(gomega.BeEmpty())
+			} else {
+				g.Expect(found.Prefixes).To(gomega.ConsistOf(tt.expectedPrefixes))
+			}
+		})
+	}
+}
+
+func TestNewFlowTable(t *testing.T) {
+	tests := []struct {
+		desc           string
+		name           string
+		limit          int
+		policy         vswitchdb.FlowTableOverflowPolicy
+		expectedLimit  int
+		expectLimitSet bool
+	}{
+		{
+			desc: "leaves all columns unset",
+		},
+		{
+			desc:   "leaves the limit unset when not positive",
+			name:   "ovnkube-services",
+			limit:  -1,
+			policy: vswitchdb.FlowTableOverflowPolicyRefuse,
+		},
+		{
+			desc:           "sets all columns",
+			name:           "ovnkube-services",
+			limit:          1000,
+			policy:         vswitchdb.FlowTableOverflowPolicyEvict,
+			expectedLimit:  1000,
+			expectLimitSet: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+
+			flowTable := NewFlowTable(tt.name, tt.limit, tt.policy)
+			g.Expect(flowTable.Name == nil).To(gomega.Equal(tt.name == ""))
+			g.Expect(flowTable.FlowLimit == nil).To(gomega.Equal(!tt.expectLimitSet))
+			g.Expect(flowTable.OverflowPolicy == nil).To(gomega.Equal(tt.policy == ""))
+
+			g.Expect(GetFlowTableName(flowTable)).To(gomega.Equal(tt.name))
+			limit, ok := GetFlowTableFlowLimit(flowTable)
+			g.Expect(ok).To(gomega.Equal(tt.expectLimitSet))
+			g.Expect(limit).To(gomega.Equal(tt.expectedLimit))
+			g.Expect(GetFlowTableOverflowPolicy(flowTable)).To(gomega.Equal(tt.policy))
+		})
+	}
+}
+
+func TestBridgeFlowTable(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	bridge := &vswitchdb.Bridge{
+		UUID: buildNamedUUID(),
+		Name: "br-ex",
+	}
+	vsClient, cleanup, err := libovsdbtest.NewVSTestHarness(libovsdbtest.TestSetup{
+		VSData: []libovsdbtest.TestData{bridge},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to set up test harness: %v", err)
+	}
+	t.Cleanup(cleanup.Cleanup)
+
+	name := "ovnkube-services"
+	err = CreateOrUpdateBridgeFlowTable(vsClient, "br-ex", 0, NewFlowTable(name, 1000, vswitchdb.FlowTableOverflowPolicyEvict))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	flowTable, err := FindFlowTableByName(vsClient, name)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	limit, ok := GetFlowTableFlowLimit(flowTable)
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(limit).To(gomega.Equal(1000))
+	g.Expect(GetFlowTableOverflowPolicy(flowTable)).To(gomega.Equal(vswitchdb.FlowTableOverflowPolicyEvict))
+	flowTables, err := GetBridgeFlowTables(vsClient, "br-ex")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(flowTables).To(gomega.Equal(map[int]string{0: flowTable.UUID}))
+
+	// updating the Flow_Table keeps the same row referenced by the bridge
+	err = CreateOrUpdateBridgeFlowTable(vsClient, "br-ex", 0, NewFlowTable(name, 2000, ""))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	updated, err := FindFlowTableByName(vsClient, name)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(updated.UUID).To(gomega.Equal(flowTable.UUID))
+	limit, ok = GetFlowTableFlowLimit(updated)
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(limit).To(gomega.Equal(2000))
+
+	// the same Flow_Table can be set for a different table number and an
+	// existing table number reference is replaced
+	err = SetBridgeFlowTable(vsClient, "br-ex", 1, flowTable.UUID)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	flowTables, err = GetBridgeFlowTables(vsClient, "br-ex")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(flowTables).To(gomega.Equal(map[int]string{0: flowTable.UUID, 1: flowTable.UUID}))
+
+	err = SetBridgeFlowTable(vsClient, "br-ex", 0, flowTable.UUID)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	flowTables, err = GetBridgeFlowTables(vsClient, "br-ex")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(flowTables).To(gomega.Equal(map[int]string{0: flowTable.UUID, 1: flowTable.UUID}))
+
+	err = RemoveBridgeFlowTable(vsClient, "br-ex", 1)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	flowTables, err = GetBridgeFlowTables(vsClient, "br-ex")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(flowTables).To(gomega.Equal(map[int]string{0: flowTable.UUID}))
+
+	err = RemoveBridgeFlowTable(vsClient, "br-ex", 0)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	flowTables, err = GetBridgeFlowTables(vsClient, "br-ex")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(flowTables).To(gomega.BeEmpty())
+
+	// removing a table number without a Flow_Table is a no-op
+	err = RemoveBridgeFlowTable(vsClient, "br-ex", 0)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+}
@@ -3,6 +3,7 @@ package metrics
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -453,6 +454,174 @@ func stringFlagPutHandler(setter stringFlagSetterFunc) http.HandlerFunc {
 	})
 }
 
+// bridgeFlowSyncHandler renders the time of the last successful flow sync of
+// each bridge as a JSON object keyed by bridge name.
+func bridgeFlowSyncHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writePlainText(http.StatusNotAcceptable, "unsupported http method", w)
+		return
+	}
+	lastSyncTimes := map[string]string{}
+	for bridge, lastSyncTime := range getBridgeFlowSyncTimes() {
+		lastSyncTimes[bridge] = lastSyncTime.UTC().Format(time.RFC3339Nano)
+	}
+	writeJSON(http.StatusOK, lastSyncTimes, w)
+}
+
+// gatewayFlowReconcileHandler regenerates and applies all the gateway bridge
+// flows, replacing any flow modified out of band.
+func gatewayFlowReconcileHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writePlainText(http.StatusMethodNotAllowed, "unsupported http method", w)
+		return
+	}
+	if err := reconcileGatewayFlows(); err != nil {
+		writePlainText(http.StatusInternalServerError, err.Error(), w)
+		return
+	}
+	writePlainText(http.StatusOK, "gateway flows reconciled", w)
+}
+
+// serviceSteeringHandler renders where ingress traffic from the clientIP
+// query parameter towards the service given by the namespace and name query
+// parameters is steered, as a JSON object with the decision and its reason.
+func serviceSteeringHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writePlainText(http.StatusNotAcceptable, "unsupported http method", w)
+		return
+	}
+	query := req.URL.Query()
+	namespace, name := query.Get("namespace"), query.Get("name")
+	if namespace == "" || name == "" {
+		writePlainText(http.StatusBadRequest, "namespace and name are required", w)
+		return
+	}
+	decision, reason, err := getServiceTrafficSteering(namespace, name, query.Get("clientIP"))
+	if err != nil {
+		writePlainText(http.StatusBadRequest, err.Error(), w)
+		return
+	}
+	writeJSON(http.StatusOK, map[string]string{"decision": decision, "reason": reason}, w)
+}
+
+// externalIPOwnershipHandler renders whether this node programs the flows of
+// the ingress traffic towards the external IP given by the ip query parameter,
+// as a JSON object with the services exposing it.
+func externalIPOwnershipHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writePlainText(http.StatusNotAcceptable, "unsupported http method", w)
+		return
+	}
+	ip := req.URL.Query().Get("ip")
+	if ip == "" {
+		writePlainText(http.StatusBadRequest, "ip is required", w)
+		return
+	}
+	ownership, err := getExternalIPOwnership(ip)
+	if err != nil {
+		writePlainText(http.StatusBadRequest, err.Error(), w)
+		return
+	}
+	writeJSON(http.StatusOK, ownership, w)
+}
+
+// serviceConntrackHandler renders the VIP:port tuples, and their conntrack
+// zones, the node flushes from conntrack on the deletion of the service given
+// by the namespace and name query parameters, as JSON.
+func serviceConntrackHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writePlainText(http.StatusNotAcceptable, "unsupported http method", w)
+		return
+	}
+	query := req.URL.Query()
+	namespace, name := query.Get("namespace"), query.Get("name")
+	if namespace == "" || name == "" {
+		writePlainText(http.StatusBadRequest, "namespace and name are required", w)
+		return
+	}
+	flush, err := getServiceConntrackFlush(namespace, name)
+	if err != nil {
+		writePlainText(http.StatusBadRequest, err.Error(), w)
+		return
+	}
+	writeJSON(http.StatusOK, flush, w)
+}
+
+// gatewayReadinessHandler renders the result of the last readiness check of
+// the node gateway, with the reason it is not ready, as JSON.
+func gatewayReadinessHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writePlainText(http.StatusNotAcceptable, "unsupported http method", w)
+		return
+	}
+	readiness, err := getGatewayReadiness()
+	if err != nil {
+		writePlainText(http.StatusServiceUnavailable, err.Error(), w)
+		return
+	}
+	writeJSON(http.StatusOK, readiness, w)
+}
+
+// defaultServiceIPTRulesTop is the number of services reported by
+// serviceIPTRulesHandler when the top query parameter is not set
+const defaultServiceIPTRulesTop = 10
+
+// serviceIPTRulesHandler renders the services with the most gateway iptables
+// rules as a JSON list, the number of services given by the top query
+// parameter.
+func serviceIPTRulesHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writePlainText(http.StatusNotAcceptable, "unsupported http method", w)
+		return
+	}
+	top := defaultServiceIPTRulesTop
+	if value := req.URL.Query().Get("top"); value != "" {
+		var err error
+		if top, err = strconv.Atoi(value); err != nil || top <= 0 {
+			writePlainText(http.StatusBadRequest, fmt.Sprintf("invalid top %q: expect a positive integer", value), w)
+			return
+		}
+	}
+	counts := getTopServiceIPTRuleCounts(top)
+	if counts == nil {
+		counts = []ServiceIPTRuleCount{}
+	}
+	writeJSON(http.StatusOK, counts, w)
+}
+
+// serviceEndpointsHandler renders the last endpoint change of the services
+// whose endpoints have not converged as a JSON list, the oldest change first.
+func serviceEndpointsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writePlainText(http.StatusNotAcceptable, "unsupported http method", w)
+		return
+	}
+	writeJSON(http.StatusOK, getUnconvergedServiceEndpointChanges(), w)
+}
+
+// egressServicePlanHandler renders the plan of the OVN operations the next
+// sync of the egress service given by the namespace and name query parameters
+// would perform, one operation per line.
+func egressServicePlanHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writePlainText(http.StatusNotAcceptable, "unsupported http method", w)
+		return
+	}
+	query := req.URL.Query()
+	namespace, name := query.Get("namespace"), query.Get("name")
+	if namespace == "" || name == "" {
+		writePlainText(http.StatusBadRequest, "namespace and name are required", w)
+		return
+	}
+	plan, err := getEgressServicePlan(namespace, name)
+	if err != nil {
+		writePlainText(http.StatusBadRequest, err.Error(), w)
+		return
+	}
+	writePlainText(http.StatusOK, plan, w)
+}
+
 // writePlainText renders a simple string response.
 func writePlainText(statusCode int, text string, w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "text/plain")
@@ -461,10 +630,40 @@ func writePlainText(statusCode int, text string, w http.ResponseWriter) {
 	fmt.Fprintln(w, text)
 }
 
-// StartMetricsServer runs the prometheus listener so that OVN K8s metrics can be collected
-// It puts the endpoint behind TLS if certFile and keyFile are defined.
-func StartMetricsServer(bindAddress string, enablePprof bool, certFile string, keyFile string,
-	stopChan <-chan struct{}, wg *sync.WaitGroup) {
+// writeJSON renders v as a JSON response.
+func writeJSON(statusCode int, v interface{}, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		klog.Errorf("Failed to write the JSON response: %v", err)
+	}
+}
+
+// funcProvider holds a function, set by the package owning some data, that a
+// metric or a debug endpoint of this package gets that data from. It can be
+// set while the metrics are collected and the endpoints served.
+type funcProvider[F any] struct {
+	lock sync.RWMutex
+	fn   F
+}
+
+func (p *funcProvider[F]) set(fn F) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.fn = fn
+}
+
+// get returns the function, nil if it is not set
+func (p *funcProvider[F]) get() F {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.fn
+}
+
+// newMetricsServeMux returns the handler of the metrics server, serving the
+// debug endpoints only if enablePprof is set as they are not authenticated.
+func newMetricsServeMux(enablePprof bool) *http.ServeMux {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
 
@@ -477,7 +676,26 @@ func StartMetricsServer(bindAddress string, enablePprof bool, certFile string, k
 
 		// Allow changes to log level at runtime
 		mux.HandleFunc("/debug/flags/v", stringFlagPutHandler(klogSetter))
+
+		// Gateway and service state
+		mux.HandleFunc("/debug/flows/sync", bridgeFlowSyncHandler)
+		mux.HandleFunc("/debug/services/steering", serviceSteeringHandler)
+		mux.HandleFunc("/debug/egressservices/plan", egressServicePlanHandler)
+		mux.HandleFunc("/debug/flows/reconcile", gatewayFlowReconcileHandler)
+		mux.HandleFunc("/debug/services/externalip", externalIPOwnershipHandler)
+		mux.HandleFunc("/debug/services/iptables", serviceIPTRulesHandler)
+		mux.HandleFunc("/debug/gateway/readiness", gatewayReadinessHandler)
+		mux.HandleFunc("/debug/services/conntrack", serviceConntrackHandler)
+		mux.HandleFunc("/debug/services/endpoints", serviceEndpointsHandler)
 	}
+	return mux
+}
+
+// StartMetricsServer runs the prometheus listener so that OVN K8s metrics can be collected
+// It puts the endpoint behind TLS if certFile and keyFile are defined.
+func StartMetricsServer(bindAddress string, enablePprof bool, certFile string, keyFile string,
+	stopChan <-chan struct{}, wg *sync.WaitGroup) {
+	mux := newMetricsServeMux(enablePprof)
 	wg.Add(1)
 
 	go func() {
@@ -1,8 +1,16 @@
 package metrics
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
 )
 
 func Test_parseStopwatchShowOutput(t *testing.T) {
@@ -133,3 +141,458 @@ Statistics for 'ovn-northd-loop'
 		})
 	}
 }
+
+func Test_newMetricsServeMuxDebugEndpoints(t *testing.T) {
+	for _, path := range []string{
+		"/debug/flows/sync",
+		"/debug/services/steering",
+		"/debug/egressservices/plan",
+		"/debug/flows/reconcile",
+		"/debug/services/externalip",
+		"/debug/services/iptables",
+		"/debug/gateway/readiness",
+		"/debug/services/conntrack",
+		"/debug/services/endpoints",
+	} {
+		for _, enablePprof := range []bool{false, true} {
+			rec := httptest.NewRecorder()
+			newMetricsServeMux(enablePprof).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			if served := rec.Code != http.StatusNotFound; served != enablePprof {
+				t.Errorf("%s served = %v with enablePprof = %v, status %d", path, served, enablePprof, rec.Code)
+			}
+		}
+	}
+}
+
+func Test_bridgeFlowSync(t *testing.T) {
+	lastSyncTime := time.Now().Add(-time.Minute)
+	SetBridgeFlowSyncTimesFunc(func() map[string]time.Time {
+		return map[string]time.Time{"breth0": lastSyncTime}
+	})
+	t.Cleanup(func() { SetBridgeFlowSyncTimesFunc(nil) })
+
+	rec := httptest.NewRecorder()
+	bridgeFlowSyncHandler(rec, httptest.NewRequest(http.MethodGet, "/debug/flows/sync", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("bridgeFlowSyncHandler() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	got := map[string]string{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("bridgeFlowSyncHandler() returned invalid JSON %q: %v", rec.Body.String(), err)
+	}
+	want := map[string]string{"breth0": lastSyncTime.UTC().Format(time.RFC3339Nano)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bridgeFlowSyncHandler() = %v, want %v", got, want)
+	}
+
+	ch := make(chan prometheus.Metric, 1)
+	newBridgeFlowSyncAgeCollector().Collect(ch)
+	metric := &io_prometheus_client.Metric{}
+	if err := (<-ch).Write(metric); err != nil {
+		t.Fatalf("failed to write the bridge flow sync age metric: %v", err)
+	}
+	if age := metric.GetGauge().GetValue(); age < time.Minute.Seconds() {
+		t.Errorf("bridge flow sync age = %v, want at least %v", age, time.Minute.Seconds())
+	}
+}
+
+func Test_serviceSteering(t *testing.T) {
+	SetServiceTrafficSteeringFunc(func(namespace, name, clientIP string) (string, string, error) {
+		if clientIP == "invalid" {
+			return "", "", fmt.Errorf("invalid client IP %q", clientIP)
+		}
+		return "ovn", namespace + "/" + name + " from " + clientIP, nil
+	})
+	t.Cleanup(func() { SetServiceTrafficSteeringFunc(nil) })
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		want       map[string]string
+	}{
+		{
+			name:       "returns the decision and its reason",
+			target:     "/debug/services/steering?namespace=ns&name=svc&clientIP=1.1.1.1",
+			wantStatus: http.StatusOK,
+			want:       map[string]string{"decision": "ovn", "reason": "ns/svc from 1.1.1.1"},
+		},
+		{
+			name:       "requires the service namespace and name",
+			target:     "/debug/services/steering?name=svc",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "fails on query errors",
+			target:     "/debug/services/steering?namespace=ns&name=svc&clientIP=invalid",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			serviceSteeringHandler(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("serviceSteeringHandler() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.want == nil {
+				return
+			}
+			got := map[string]string{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("serviceSteeringHandler() returned invalid JSON %q: %v", rec.Body.String(), err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("serviceSteeringHandler() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_externalIPOwnership(t *testing.T) {
+	SetExternalIPOwnershipFunc(func(ip string) (*ExternalIPOwnership, error) {
+		if ip == "invalid" {
+			return nil, fmt.Errorf("invalid external IP %q", ip)
+		}
+		return &ExternalIPOwnership{
+			IP:         ip,
+			Programmed: true,
+			Services: []ExternalIPServiceOwnership{
+				{Service: "ns/svc", Type: "External", Programmed: true, InPort: "eth0", OutPort: "patch-breth0_ov", Bridge: "breth0", Reason: "shared gateway mode"},
+			},
+		}, nil
+	})
+	t.Cleanup(func() { SetExternalIPOwnershipFunc(nil) })
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		want       *ExternalIPOwnership
+	}{
+		{
+			name:       "returns the ownership of the external IP",
+			target:     "/debug/services/externalip?ip=1.1.1.1",
+			wantStatus: http.StatusOK,
+			want: &ExternalIPOwnership{
+				IP:         "1.1.1.1",
+				Programmed: true,
+				Services: []ExternalIPServiceOwnership{
+					{Service: "ns/svc", Type: "External", Programmed: true, InPort: "eth0", OutPort: "patch-breth0_ov", Bridge: "breth0", Reason: "shared gateway mode"},
+				},
+			},
+		},
+		{
+			name:       "requires the external IP",
+			target:     "/debug/services/externalip",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "fails on query errors",
+			target:     "/debug/services/externalip?ip=invalid",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			externalIPOwnershipHandler(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("externalIPOwnershipHandler() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.want == nil {
+				return
+			}
+			got := &ExternalIPOwnership{}
+			if err := json.Unmarshal(rec.Body.Bytes(), got); err != nil {
+				t.Fatalf("externalIPOwnershipHandler() returned invalid JSON %q: %v", rec.Body.String(), err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("externalIPOwnershipHandler() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_serviceIPTRules(t *testing.T) {
+	counts := []ServiceIPTRuleCount{{Service: "ns/c", Rules: 2}, {Service: "ns/a", Rules: 4}, {Service: "ns/b", Rules: 4}}
+	SetServiceIPTRuleCountsFunc(func() []ServiceIPTRuleCount { return counts })
+	t.Cleanup(func() { SetServiceIPTRuleCountsFunc(nil) })
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		want       []ServiceIPTRuleCount
+	}{
+		{
+			name:       "returns the services sorted by rule count",
+			target:     "/debug/services/iptables",
+			wantStatus: http.StatusOK,
+			want:       []ServiceIPTRuleCount{{Service: "ns/a", Rules: 4}, {Service: "ns/b", Rules: 4}, {Service: "ns/c", Rules: 2}},
+		},
+		{
+			name:       "returns the top services",
+			target:     "/debug/services/iptables?top=1",
+			wantStatus: http.StatusOK,
+			want:       []ServiceIPTRuleCount{{Service: "ns/a", Rules: 4}},
+		},
+		{
+			name:       "fails on invalid top",
+			target:     "/debug/services/iptables?top=0",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			serviceIPTRulesHandler(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("serviceIPTRulesHandler() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.want == nil {
+				return
+			}
+			var got []ServiceIPTRuleCount
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("serviceIPTRulesHandler() returned invalid JSON %q: %v", rec.Body.String(), err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("serviceIPTRulesHandler() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if got := getServiceIPTRules(); got != 10 {
+		t.Errorf("getServiceIPTRules() = %v, want 10", got)
+	}
+}
+
+func Test_serviceEndpoints(t *testing.T) {
+	now := time.Now().UTC()
+	changes := []ServiceEndpointChange{
+		{Service: "ns/a", LastChange: now.Add(-time.Minute), Converged: false},
+		{Service: "ns/b", LastChange: now.Add(-time.Hour), Converged: true},
+		{Service: "ns/c", LastChange: now.Add(-10 * time.Minute), Converged: false},
+	}
+	SetServiceEndpointChangesFunc(func() []ServiceEndpointChange { return changes })
+	t.Cleanup(func() { SetServiceEndpointChangesFunc(nil) })
+
+	rec := httptest.NewRecorder()
+	serviceEndpointsHandler(rec, httptest.NewRequest(http.MethodGet, "/debug/services/endpoints", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("serviceEndpointsHandler() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var got []ServiceEndpointChange
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("serviceEndpointsHandler() returned invalid JSON %q: %v", rec.Body.String(), err)
+	}
+	// only the unconverged services, the oldest change first
+	want := []ServiceEndpointChange{changes[2], changes[0]}
+	if len(got) != len(want) {
+		t.Fatalf("serviceEndpointsHandler() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Service != want[i].Service || !got[i].LastChange.Equal(want[i].LastChange) || got[i].Converged {
+			t.Errorf("serviceEndpointsHandler() = %+v, want %+v", got, want)
+		}
+	}
+	if age := getUnconvergedServiceEndpointsAge(); age < 10*time.Minute.Seconds() || age > time.Hour.Seconds() {
+		t.Errorf("getUnconvergedServiceEndpointsAge() = %v, want the age of the change of ns/c", age)
+	}
+
+	changes = changes[1:2]
+	if age := getUnconvergedServiceEndpointsAge(); age != 0 {
+		t.Errorf("getUnconvergedServiceEndpointsAge() = %v, want 0 once all the services converged", age)
+	}
+}
+
+func Test_serviceConntrack(t *testing.T) {
+	SetServiceConntrackFlushFunc(func(namespace, name string) (*ServiceConntrackFlush, error) {
+		if name == "missing" {
+			return nil, fmt.Errorf("service %s/%s not found", namespace, name)
+		}
+		return &ServiceConntrackFlush{
+			Service: namespace + "/" + name,
+			Tuples: []ServiceConntrackTuple{
+				{Type: "ClusterIP", IP: "10.96.0.10", Port: 80, Protocol: "TCP", Zones: []int{64001, 64002}},
+			},
+		}, nil
+	})
+	t.Cleanup(func() { SetServiceConntrackFlushFunc(nil) })
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		want       *ServiceConntrackFlush
+	}{
+		{
+			name:       "returns the tuples and zones flushed on delete",
+			target:     "/debug/services/conntrack?namespace=ns&name=svc",
+			wantStatus: http.StatusOK,
+			want: &ServiceConntrackFlush{
+				Service: "ns/svc",
+				Tuples: []ServiceConntrackTuple{
+					{Type: "ClusterIP", IP: "10.96.0.10", Port: 80, Protocol: "TCP", Zones: []int{64001, 64002}},
+				},
+			},
+		},
+		{
+			name:       "requires the service namespace and name",
+			target:     "/debug/services/conntrack?namespace=ns",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "fails on query errors",
+			target:     "/debug/services/conntrack?namespace=ns&name=missing",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			serviceConntrackHandler(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("serviceConntrackHandler() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.want == nil {
+				return
+			}
+			got := &ServiceConntrackFlush{}
+			if err := json.Unmarshal(rec.Body.Bytes(), got); err != nil {
+				t.Fatalf("serviceConntrackHandler() returned invalid JSON %q: %v", rec.Body.String(), err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("serviceConntrackHandler() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_gatewayReadiness(t *testing.T) {
+	rec := httptest.NewRecorder()
+	gatewayReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/debug/gateway/readiness", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("gatewayReadinessHandler() status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	SetGatewayReadinessFunc(func() (bool, string) {
+		return false, "patch port patch-breth1_node1-to-br-int of external gateway bridge breth1 is not ready"
+	})
+	t.Cleanup(func() { SetGatewayReadinessFunc(nil) })
+	rec = httptest.NewRecorder()
+	gatewayReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/debug/gateway/readiness", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("gatewayReadinessHandler() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	got := &GatewayReadiness{}
+	if err := json.Unmarshal(rec.Body.Bytes(), got); err != nil {
+		t.Fatalf("gatewayReadinessHandler() returned invalid JSON %q: %v", rec.Body.String(), err)
+	}
+	want := &GatewayReadiness{Reason: "patch port patch-breth1_node1-to-br-int of external gateway bridge breth1 is not ready"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("gatewayReadinessHandler() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_egressServicePlan(t *testing.T) {
+	SetEgressServicePlanFunc(func(namespace, name string) (string, error) {
+		if name == "invalid" {
+			return "", fmt.Errorf("failed to plan egress service %s/%s", namespace, name)
+		}
+		return "insert Logical_Router_Policy for " + namespace + "/" + name, nil
+	})
+	t.Cleanup(func() { SetEgressServicePlanFunc(nil) })
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "returns the plan of the egress service",
+			target:     "/debug/egressservices/plan?namespace=ns&name=svc",
+			wantStatus: http.StatusOK,
+			want:       "insert Logical_Router_Policy for ns/svc\n",
+		},
+		{
+			name:       "requires the egress service namespace and name",
+			target:     "/debug/egressservices/plan?namespace=ns",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "fails on planning errors",
+			target:     "/debug/egressservices/plan?namespace=ns&name=invalid",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			egressServicePlanHandler(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("egressServicePlanHandler() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.want != "" && rec.Body.String() != tt.want {
+				t.Errorf("egressServicePlanHandler() = %q, want %q", rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_gatewayFlowReconcile(t *testing.T) {
+	var reconciled int
+	var reconcileErr error
+	SetGatewayFlowReconcileFunc(func() error {
+		reconciled++
+		return reconcileErr
+	})
+	t.Cleanup(func() { SetGatewayFlowReconcileFunc(nil) })
+
+	tests := []struct {
+		name           string
+		method         string
+		err            error
+		wantStatus     int
+		wantReconciled int
+	}{
+		{
+			name:           "reconciles the gateway flows",
+			method:         http.MethodPost,
+			wantStatus:     http.StatusOK,
+			wantReconciled: 1,
+		},
+		{
+			name:       "requires a POST",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "rejects the other mutating methods",
+			method:     http.MethodPut,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "fails on reconcile errors",
+			method:         http.MethodPost,
+			err:            fmt.Errorf("failed to regenerate the gateway bridge default flows"),
+			wantStatus:     http.StatusInternalServerError,
+			wantReconciled: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reconciled = 0
+			reconcileErr = tt.err
+			rec := httptest.NewRecorder()
+			gatewayFlowReconcileHandler(rec, httptest.NewRequest(tt.method, "/debug/flows/reconcile", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("gatewayFlowReconcileHandler() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if reconciled != tt.wantReconciled {
+				t.Errorf("gatewayFlowReconcileHandler() reconciled %d times, want %d", reconciled, tt.wantReconciled)
+			}
+		})
+	}
+}
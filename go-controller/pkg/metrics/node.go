@@ -1,8 +1,11 @@
 package metrics
 
 import (
+	"fmt"
 	"runtime"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
 	"github.com/prometheus/client_golang/prometheus"
@@ -34,6 +37,402 @@ var metricOvnNodePortEnabled = prometheus.NewGauge(prometheus.GaugeOpts{
 	Help:      "Specifies if the node port is enabled on this node(1) or not(0).",
 })
 
+// MetricServiceFlowPaths is a prometheus metric that tracks the number of
+// services on each gateway flow path
+var MetricServiceFlowPaths = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: MetricOvnkubeNamespace,
+	Subsystem: MetricOvnkubeSubsystemNode,
+	Name:      "service_flow_paths",
+	Help: "The number of services whose ingress traffic is steered to the host (host), " +
+		"into OVN via the gateway router (ovn) or left to the default flows (default).",
+},
+	//labels
+	[]string{"path", "gateway_mode"},
+)
+
+// MetricServiceCIDRFlows is a prometheus metric that tracks the number of
+// gateway bridge default flows programmed for the service CIDRs
+var MetricServiceCIDRFlows = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: MetricOvnkubeNamespace,
+	Subsystem: MetricOvnkubeSubsystemNode,
+	Name:      "service_cidr_flows",
+	Help:      "The number of gateway bridge default flows programmed for the service CIDRs.",
+})
+
+// MetricGatewayFlowSyncStalls is a prometheus metric that tracks the number
+// of gateway flow syncs that were not picked up by the flow sync loop in time
+var MetricGatewayFlowSyncStalls = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: MetricOvnkubeNamespace,
+	Subsystem: MetricOvnkubeSubsystemNode,
+	Name:      "gateway_flow_sync_stalls_total",
+	Help:      "The number of requested gateway bridge flow syncs that were not picked up by the flow sync loop in time.",
+})
+
+// MetricServiceConntrackDeletes is a prometheus metric that tracks the number
+// of conntrack entries deleted for the services, by purpose of the deletion
+var MetricServiceConntrackDeletes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: MetricOvnkubeNamespace,
+	Subsystem: MetricOvnkubeSubsystemNode,
+	Name:      "service_conntrack_deletes_total",
+	Help: "The number of conntrack entries deleted for the services, on the deletion of a service (service-delete), " +
+		"of some of its endpoints (endpoint-change) or on a protocol change of its NodePorts (protocol-change).",
+},
+	//labels
+	[]string{"purpose"},
+)
+
+// bridgeFlowSyncTimes returns the time of the last successful flow sync of
+// each bridge
+var bridgeFlowSyncTimes funcProvider[func() map[string]time.Time]
+
+// SetBridgeFlowSyncTimesFunc sets the function providing the time of the last
+// successful flow sync of each bridge, reported by the flow sync age metric and
+// the flow sync debug endpoint.
+func SetBridgeFlowSyncTimesFunc(fn func() map[string]time.Time) {
+	bridgeFlowSyncTimes.set(fn)
+}
+
+func getBridgeFlowSyncTimes() map[string]time.Time {
+	fn := bridgeFlowSyncTimes.get()
+	if fn == nil {
+		return map[string]time.Time{}
+	}
+	return fn()
+}
+
+// etpLocalServicesWithoutLocalEndpoints returns the number of services with
+// externalTrafficPolicy=local without endpoints local to this node
+var etpLocalServicesWithoutLocalEndpoints funcProvider[func() int]
+
+// SetETPLocalServicesWithoutLocalEndpointsFunc sets the function counting the
+// services with externalTrafficPolicy=local without endpoints local to this
+// node, reported by MetricETPLocalServicesWithoutLocalEndpoints.
+func SetETPLocalServicesWithoutLocalEndpointsFunc(fn func() int) {
+	etpLocalServicesWithoutLocalEndpoints.set(fn)
+}
+
+func getETPLocalServicesWithoutLocalEndpoints() float64 {
+	fn := etpLocalServicesWithoutLocalEndpoints.get()
+	if fn == nil {
+		return 0
+	}
+	return float64(fn())
+}
+
+// MetricETPLocalServicesWithoutLocalEndpoints is a prometheus metric that
+// tracks the number of services with externalTrafficPolicy=local without
+// endpoints local to this node, whose ingress traffic to this node is dropped.
+// It is computed at collection time so that it follows the endpoint changes.
+var MetricETPLocalServicesWithoutLocalEndpoints = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+	Namespace: MetricOvnkubeNamespace,
+	Subsystem: MetricOvnkubeSubsystemNode,
+	Name:      "etp_local_services_without_local_endpoints",
+	Help:      "The number of services with externalTrafficPolicy=local without endpoints local to this node.",
+}, getETPLocalServicesWithoutLocalEndpoints)
+
+// ServiceIPTRuleCount is the number of gateway iptables rules programmed for a service
+type ServiceIPTRuleCount struct {
+	// Service is the namespace/name of the service
+	Service string `json:"service"`
+	Rules   int    `json:"rules"`
+}
+
+// serviceIPTRuleCounts returns the number of gateway iptables rules of each service
+var serviceIPTRuleCounts funcProvider[func() []ServiceIPTRuleCount]
+
+// SetServiceIPTRuleCountsFunc sets the function counting the gateway iptables
+// rules of each service, reported by MetricServiceIPTRules and queried through
+// the service iptables debug endpoint.
+func SetServiceIPTRuleCountsFunc(fn func() []ServiceIPTRuleCount) {
+	serviceIPTRuleCounts.set(fn)
+}
+
+func getServiceIPTRuleCounts() []ServiceIPTRuleCount {
+	fn := serviceIPTRuleCounts.get()
+	if fn == nil {
+		return nil
+	}
+	return fn()
+}
+
+// getTopServiceIPTRuleCounts returns the n services with the most gateway
+// iptables rules, the services with the same number of rules ordered by name
+func getTopServiceIPTRuleCounts(n int) []ServiceIPTRuleCount {
+	counts := getServiceIPTRuleCounts()
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Rules != counts[j].Rules {
+			return counts[i].Rules > counts[j].Rules
+		}
+		return counts[i].Service < counts[j].Service
+	})
+	if n < len(counts) {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+func getServiceIPTRules() float64 {
+	total := 0
+	for _, count := range getServiceIPTRuleCounts() {
+		total += count.Rules
+	}
+	return float64(total)
+}
+
+// MetricServiceIPTRules is a prometheus metric that tracks the number of
+// gateway iptables rules programmed for all the services. It is computed at
+// collection time so that it follows the service and endpoint changes.
+var MetricServiceIPTRules = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+	Namespace: MetricOvnkubeNamespace,
+	Subsystem: MetricOvnkubeSubsystemNode,
+	Name:      "service_iptables_rules",
+	Help:      "The number of gateway iptables rules programmed for the services.",
+}, getServiceIPTRules)
+
+// ServiceEndpointChange is when the last endpoint change of a service was
+// processed by the node, and whether its endpoints have converged since, i.e.
+// are all ready
+type ServiceEndpointChange struct {
+	// Service is the namespace/name of the service
+	Service    string    `json:"service"`
+	LastChange time.Time `json:"lastChange"`
+	Converged  bool      `json:"converged"`
+}
+
+// serviceEndpointChanges returns the last endpoint change of each service
+var serviceEndpointChanges funcProvider[func() []ServiceEndpointChange]
+
+// SetServiceEndpointChangesFunc sets the function providing the last endpoint
+// change of each service, reported by MetricUnconvergedServiceEndpointsAge and
+// queried through the service endpoints debug endpoint.
+func SetServiceEndpointChangesFunc(fn func() []ServiceEndpointChange) {
+	serviceEndpointChanges.set(fn)
+}
+
+func getServiceEndpointChanges() []ServiceEndpointChange {
+	fn := serviceEndpointChanges.get()
+	if fn == nil {
+		return nil
+	}
+	return fn()
+}
+
+// getUnconvergedServiceEndpointChanges returns the last endpoint change of the
+// services whose endpoints have not converged, the oldest change first
+func getUnconvergedServiceEndpointChanges() []ServiceEndpointChange {
+	unconverged := []ServiceEndpointChange{}
+	for _, change := range getServiceEndpointChanges() {
+		if !change.Converged {
+			unconverged = append(unconverged, change)
+		}
+	}
+	sort.Slice(unconverged, func(i, j int) bool {
+		if !unconverged[i].LastChange.Equal(unconverged[j].LastChange) {
+			return unconverged[i].LastChange.Before(unconverged[j].LastChange)
+		}
+		return unconverged[i].Service < unconverged[j].Service
+	})
+	return unconverged
+}
+
+func getUnconvergedServiceEndpointsAge() float64 {
+	unconverged := getUnconvergedServiceEndpointChanges()
+	if len(unconverged) == 0 {
+		return 0
+	}
+	return time.Since(unconverged[0].LastChange).Seconds()
+}
+
+// MetricUnconvergedServiceEndpointsAge is a prometheus metric that tracks the
+// time elapsed since the oldest last endpoint change of the services whose
+// endpoints have not converged, an ever increasing age pointing at a service
+// stuck mid-rollout. It is computed at collection time.
+var MetricUnconvergedServiceEndpointsAge = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+	Namespace: MetricOvnkubeNamespace,
+	Subsystem: MetricOvnkubeSubsystemNode,
+	Name:      "unconverged_service_endpoints_age_seconds",
+	Help: "The time elapsed since the oldest last endpoint change of the services whose endpoints " +
+		"have not converged, 0 if all have.",
+}, getUnconvergedServiceEndpointsAge)
+
+// serviceTrafficSteering returns where ingress traffic from a client IP
+// towards a service is steered and why
+var serviceTrafficSteering funcProvider[func(namespace, name, clientIP string) (string, string, error)]
+
+// SetServiceTrafficSteeringFunc sets the function answering where ingress
+// traffic from a client IP towards a service is steered, queried through the
+// service steering debug endpoint.
+func SetServiceTrafficSteeringFunc(fn func(namespace, name, clientIP string) (string, string, error)) {
+	serviceTrafficSteering.set(fn)
+}
+
+func getServiceTrafficSteering(namespace, name, clientIP string) (string, string, error) {
+	fn := serviceTrafficSteering.get()
+	if fn == nil {
+		return "", "", fmt.Errorf("service traffic steering is not available")
+	}
+	return fn(namespace, name, clientIP)
+}
+
+// ExternalIPOwnership is whether this node programs the gateway bridge flows
+// of the ingress traffic towards an external IP, for each service exposing it
+// as an externalIP or a LoadBalancer ingress IP
+type ExternalIPOwnership struct {
+	IP string `json:"ip"`
+	// Programmed is true if the flows of the IP are programmed for at least
+	// one of the services
+	Programmed bool                         `json:"programmed"`
+	Services   []ExternalIPServiceOwnership `json:"services"`
+}
+
+// ExternalIPServiceOwnership is whether this node programs the gateway bridge
+// flows of the ingress traffic towards an external IP of a service, and the
+// ofports that traffic is matched on and sent out of
+type ExternalIPServiceOwnership struct {
+	// Service is the namespace/name of the service
+	Service string `json:"service"`
+	// Type is either External or Ingress
+	Type       string `json:"type"`
+	Programmed bool   `json:"programmed"`
+	InPort     string `json:"inPort,omitempty"`
+	// OutPort is the patch port towards OVN or LOCAL towards the host
+	OutPort string `json:"outPort,omitempty"`
+	// Bridge is the gateway bridge, the default or the external gateway
+	// one, whose flow cache holds the flows of the IP
+	Bridge string `json:"bridge,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// externalIPOwnership returns whether this node programs the flows of an
+// external IP
+var externalIPOwnership funcProvider[func(ip string) (*ExternalIPOwnership, error)]
+
+// SetExternalIPOwnershipFunc sets the function answering whether this node
+// programs the flows of an external IP, queried through the external IP debug
+// endpoint.
+func SetExternalIPOwnershipFunc(fn func(ip string) (*ExternalIPOwnership, error)) {
+	externalIPOwnership.set(fn)
+}
+
+func getExternalIPOwnership(ip string) (*ExternalIPOwnership, error) {
+	fn := externalIPOwnership.get()
+	if fn == nil {
+		return nil, fmt.Errorf("external IP ownership is not available")
+	}
+	return fn(ip)
+}
+
+// ServiceConntrackFlush is what the node flushes from conntrack on the
+// deletion of a service
+type ServiceConntrackFlush struct {
+	// Service is the namespace/name of the service
+	Service string                  `json:"service"`
+	Tuples  []ServiceConntrackTuple `json:"tuples"`
+}
+
+// ServiceConntrackTuple is a VIP:port tuple of a service whose conntrack
+// entries are flushed, in any zone, on the deletion of the service, along with
+// the conntrack zones the gateway tracks the connections towards it in
+type ServiceConntrackTuple struct {
+	// Type is ClusterIP, NodePort, External or Ingress
+	Type     string `json:"type"`
+	IP       string `json:"ip"`
+	Port     int32  `json:"port"`
+	Protocol string `json:"protocol"`
+	Zones    []int  `json:"zones"`
+}
+
+// serviceConntrackFlush returns what the node flushes from conntrack on the
+// deletion of a service
+var serviceConntrackFlush funcProvider[func(namespace, name string) (*ServiceConntrackFlush, error)]
+
+// SetServiceConntrackFlushFunc sets the function answering what the node
+// flushes from conntrack on the deletion of a service, queried through the
+// service conntrack debug endpoint.
+func SetServiceConntrackFlushFunc(fn func(namespace, name string) (*ServiceConntrackFlush, error)) {
+	serviceConntrackFlush.set(fn)
+}
+
+func getServiceConntrackFlush(namespace, name string) (*ServiceConntrackFlush, error) {
+	fn := serviceConntrackFlush.get()
+	if fn == nil {
+		return nil, fmt.Errorf("service conntrack flush is not available")
+	}
+	return fn(namespace, name)
+}
+
+// GatewayReadiness is the result of the last readiness check of the node gateway
+type GatewayReadiness struct {
+	Ready bool `json:"ready"`
+	// Reason is why the gateway is not ready, e.g. the patch port that is not
+	Reason string `json:"reason,omitempty"`
+}
+
+// gatewayReadiness returns the result of the last readiness check of the node
+// gateway
+var gatewayReadiness funcProvider[func() (bool, string)]
+
+// SetGatewayReadinessFunc sets the function providing the result of the last
+// readiness check of the node gateway, queried through the gateway readiness
+// debug endpoint.
+func SetGatewayReadinessFunc(fn func() (bool, string)) {
+	gatewayReadiness.set(fn)
+}
+
+func getGatewayReadiness() (*GatewayReadiness, error) {
+	fn := gatewayReadiness.get()
+	if fn == nil {
+		return nil, fmt.Errorf("gateway readiness is not available")
+	}
+	ready, reason := fn()
+	return &GatewayReadiness{Ready: ready, Reason: reason}, nil
+}
+
+// gatewayFlowReconcile regenerates and applies all the gateway bridge flows
+var gatewayFlowReconcile funcProvider[func() error]
+
+// SetGatewayFlowReconcileFunc sets the function regenerating and applying all
+// the gateway bridge flows, triggered through the flow reconcile debug
+// endpoint.
+func SetGatewayFlowReconcileFunc(fn func() error) {
+	gatewayFlowReconcile.set(fn)
+}
+
+func reconcileGatewayFlows() error {
+	fn := gatewayFlowReconcile.get()
+	if fn == nil {
+		return fmt.Errorf("gateway flow reconciliation is not available")
+	}
+	return fn()
+}
+
+// bridgeFlowSyncAgeCollector reports the time elapsed since the last
+// successful flow sync of each bridge, computed at collection time so that a
+// stuck sync shows as an ever increasing age.
+type bridgeFlowSyncAgeCollector struct {
+	desc *prometheus.Desc
+}
+
+func newBridgeFlowSyncAgeCollector() *bridgeFlowSyncAgeCollector {
+	return &bridgeFlowSyncAgeCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(MetricOvnkubeNamespace, MetricOvnkubeSubsystemNode, "bridge_flow_sync_age_seconds"),
+			"The time elapsed since the last successful flow sync of a bridge.",
+			[]string{"bridge"}, nil,
+		),
+	}
+}
+
+func (c *bridgeFlowSyncAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *bridgeFlowSyncAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	for bridge, lastSyncTime := range getBridgeFlowSyncTimes() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, time.Since(lastSyncTime).Seconds(), bridge)
+	}
+}
+
 var registerNodeMetricsOnce sync.Once
 
 func RegisterNodeMetrics() {
@@ -42,6 +441,14 @@ func RegisterNodeMetrics() {
 		prometheus.MustRegister(MetricCNIRequestDuration)
 		prometheus.MustRegister(MetricNodeReadyDuration)
 		prometheus.MustRegister(metricOvnNodePortEnabled)
+		prometheus.MustRegister(MetricServiceFlowPaths)
+		prometheus.MustRegister(MetricServiceCIDRFlows)
+		prometheus.MustRegister(MetricGatewayFlowSyncStalls)
+		prometheus.MustRegister(MetricServiceConntrackDeletes)
+		prometheus.MustRegister(MetricETPLocalServicesWithoutLocalEndpoints)
+		prometheus.MustRegister(MetricServiceIPTRules)
+		prometheus.MustRegister(MetricUnconvergedServiceEndpointsAge)
+		prometheus.MustRegister(newBridgeFlowSyncAgeCollector())
 		prometheus.MustRegister(prometheus.NewGaugeFunc(
 			prometheus.GaugeOpts{
 				Namespace: MetricOvnkubeNamespace,
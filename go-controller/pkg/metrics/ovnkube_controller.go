@@ -578,6 +578,25 @@ func DecrementEgressFirewallCount() {
 	metricEgressFirewallCount.Dec()
 }
 
+// egressServicePlan returns the plan of the OVN operations the next sync of
+// an egress service would perform
+var egressServicePlan funcProvider[func(namespace, name string) (string, error)]
+
+// SetEgressServicePlanFunc sets the function returning the plan of the OVN
+// operations the next sync of an egress service would perform, queried through
+// the egress service plan debug endpoint.
+func SetEgressServicePlanFunc(fn func(namespace, name string) (string, error)) {
+	egressServicePlan.set(fn)
+}
+
+func getEgressServicePlan(namespace, name string) (string, error) {
+	fn := egressServicePlan.get()
+	if fn == nil {
+		return "", fmt.Errorf("egress service plans are not available")
+	}
+	return fn(namespace, name)
+}
+
 type (
 	timestampType int
 	operation     int
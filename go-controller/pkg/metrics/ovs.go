@@ -176,6 +176,47 @@ var metricOvsBridgeFlowsTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 	},
 )
 
+// ovsFlowTableNearLimitRatio is the ratio of a Flow_Table flow_limit at which
+// the OpenFlow table using it is reported to be near its limit. OVSDB does not
+// report flow evictions, so polling the flow count against the limit is the
+// only indication of flow pressure before flows are evicted or refused.
+const ovsFlowTableNearLimitRatio = 0.9
+
+// ovs flow table metrics
+var metricOvsFlowTableFlowsTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: MetricOvsNamespace,
+	Subsystem: MetricOvsSubsystemVswitchd,
+	Name:      "flow_table_flows_total",
+	Help:      "Represents the number of OpenFlow flows in an OpenFlow table of the OVS bridge with a flow limit."},
+	[]string{
+		"bridge",
+		"table",
+	},
+)
+
+var metricOvsFlowTableFlowLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: MetricOvsNamespace,
+	Subsystem: MetricOvsSubsystemVswitchd,
+	Name:      "flow_table_flow_limit",
+	Help:      "Represents the flow limit of the Flow_Table set for an OpenFlow table of the OVS bridge."},
+	[]string{
+		"bridge",
+		"table",
+	},
+)
+
+var metricOvsFlowTableNearLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: MetricOvsNamespace,
+	Subsystem: MetricOvsSubsystemVswitchd,
+	Name:      "flow_table_near_limit",
+	Help: "Set to 1 when the number of OpenFlow flows in an OpenFlow table of the OVS bridge reaches 90% of " +
+		"the flow limit of its Flow_Table, 0 otherwise."},
+	[]string{
+		"bridge",
+		"table",
+	},
+)
+
 // ovs interface metrics
 var metricOvsInterfaceResetsTotal = prometheus.NewGauge(prometheus.GaugeOpts{
 	Namespace: MetricOvsNamespace,
@@ -479,7 +520,14 @@ func updateOvsBridgeMetrics(ovsVsctl, ovsOfctl ovsClient) error {
 // getOvsBridgeOpenFlowsCount returns the number of openflow flows
 // in an ovs-bridge
 func getOvsBridgeOpenFlowsCount(ovsOfctl ovsClient, bridgeName string) (float64, error) {
-	stdout, stderr, err := ovsOfctl("-t", "5", "dump-aggregate", bridgeName)
+	return getOvsOpenFlowsCount(ovsOfctl, bridgeName)
+}
+
+// getOvsOpenFlowsCount returns the number of openflow flows in an ovs-bridge
+// matching the optional flow match
+func getOvsOpenFlowsCount(ovsOfctl ovsClient, bridgeName string, flowMatch ...string) (float64, error) {
+	args := append([]string{"-t", "5", "dump-aggregate", bridgeName}, flowMatch...)
+	stdout, stderr, err := ovsOfctl(args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get flow count for %s, stderr(%s): (%v)",
 			bridgeName, stderr, err)
@@ -501,6 +549,110 @@ func getOvsBridgeOpenFlowsCount(ovsOfctl ovsClient, bridgeName string) (float64,
 		"flow_count field", bridgeName)
 }
 
+// ovsFlowTableMetricsUpdater updates the metrics of the OpenFlow tables with
+// a Flow_Table flow limit
+func ovsFlowTableMetricsUpdater(ovsVsctl, ovsOfctl ovsClient, tickPeriod time.Duration, stopChan <-chan struct{}) {
+	ticker := time.NewTicker(tickPeriod)
+	defer ticker.Stop()
+	var err error
+	for {
+		select {
+		case <-ticker.C:
+			if err = updateOvsFlowTableMetrics(ovsVsctl, ovsOfctl); err != nil {
+				klog.Errorf("Updating OVS flow table metrics failed: %s", err.Error())
+			}
+		case <-stopChan:
+			return
+		}
+	}
+}
+
+// getOvsFlowTableLimits returns the flow limit of the Flow_Tables that have
+// one, keyed by Flow_Table UUID
+func getOvsFlowTableLimits(ovsVsctl ovsClient) (map[string]int, error) {
+	stdout, stderr, err := ovsVsctl("--no-headings", "--data=bare",
+		"--format=csv", "--columns=_uuid,flow_limit", "list", "Flow_Table")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Flow_Table flow limits, stderr(%s): (%v)", stderr, err)
+	}
+	if stderr != "" {
+		return nil, fmt.Errorf("failed to get Flow_Table flow limits due to stderr: %s", stderr)
+	}
+
+	// output will be of format :(a5a5a3a4-a1d1-4f7b-9c5a-2b1d3e0f6c7d,1000)
+	limits := map[string]int{}
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) != 2 || fields[1] == "" {
+			continue
+		}
+		limit, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse flow limit of Flow_Table %s: %v", fields[0], err)
+		}
+		limits[fields[0]] = limit
+	}
+	return limits, nil
+}
+
+// updateOvsFlowTableMetrics polls the number of flows of every OpenFlow table
+// with a Flow_Table flow limit and reports the tables that are close to it
+func updateOvsFlowTableMetrics(ovsVsctl, ovsOfctl ovsClient) error {
+	limits, err := getOvsFlowTableLimits(ovsVsctl)
+	if err != nil {
+		return err
+	}
+	metricOvsFlowTableFlowsTotal.Reset()
+	metricOvsFlowTableFlowLimit.Reset()
+	metricOvsFlowTableNearLimit.Reset()
+	if len(limits) == 0 {
+		return nil
+	}
+
+	stdout, stderr, err := ovsVsctl("--no-headings", "--data=bare",
+		"--format=csv", "--columns=name,flow_tables", "list", "Bridge")
+	if err != nil {
+		return fmt.Errorf("failed to get bridge flow tables, stderr(%s): (%v)", stderr, err)
+	}
+	if stderr != "" {
+		return fmt.Errorf("failed to get bridge flow tables due to stderr: %s", stderr)
+	}
+
+	// output will be of format :(br-ex,0=a5a5a3a4-a1d1-4f7b-9c5a-2b1d3e0f6c7d
+	// 1=0d2c7a43-9e8f-4b8c-8d9e-6f5a4b3c2d1e)
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) != 2 || fields[0] == "" {
+			continue
+		}
+		bridgeName := fields[0]
+		for _, flowTable := range strings.Fields(fields[1]) {
+			table, flowTableUUID, found := strings.Cut(flowTable, "=")
+			if !found {
+				continue
+			}
+			limit, ok := limits[flowTableUUID]
+			if !ok {
+				continue
+			}
+			count, err := getOvsOpenFlowsCount(ovsOfctl, bridgeName, "table="+table)
+			if err != nil {
+				return err
+			}
+			metricOvsFlowTableFlowsTotal.WithLabelValues(bridgeName, table).Set(count)
+			metricOvsFlowTableFlowLimit.WithLabelValues(bridgeName, table).Set(float64(limit))
+			if count >= ovsFlowTableNearLimitRatio*float64(limit) {
+				klog.Warningf("OVS bridge %s table %s has %.0f flows, close to its flow limit of %d: "+
+					"flows may soon be evicted or refused", bridgeName, table, count, limit)
+				metricOvsFlowTableNearLimit.WithLabelValues(bridgeName, table).Set(1)
+			} else {
+				metricOvsFlowTableNearLimit.WithLabelValues(bridgeName, table).Set(0)
+			}
+		}
+	}
+	return nil
+}
+
 func ovsInterfaceMetricsUpdater(ovsVsctl ovsClient, tickPeriod time.Duration, stopChan <-chan struct{}) {
 	ticker := time.NewTicker(tickPeriod)
 	defer ticker.Stop()
@@ -887,6 +1039,10 @@ func registerOvsMetrics(registry prometheus.Registerer, stopChan <-chan struct{}
 		registry.MustRegister(metricOvsBridge)
 		registry.MustRegister(metricOvsBridgePortsTotal)
 		registry.MustRegister(metricOvsBridgeFlowsTotal)
+		// Register OVS flow table metrics
+		registry.MustRegister(metricOvsFlowTableFlowsTotal)
+		registry.MustRegister(metricOvsFlowTableFlowLimit)
+		registry.MustRegister(metricOvsFlowTableNearLimit)
 		// Register ovs Memory metrics
 		registry.MustRegister(metricOvsHandlersTotal)
 		registry.MustRegister(metricOvsRevalidatorsTotal)
@@ -918,6 +1074,8 @@ func registerOvsMetrics(registry prometheus.Registerer, stopChan <-chan struct{}
 		go ovsDatapathMetricsUpdater(util.RunOVSAppctl, 30*time.Second, stopChan)
 		// OVS bridge metrics updater
 		go ovsBridgeMetricsUpdater(util.RunOVSVsctl, util.RunOVSOfctl, 30*time.Second, stopChan)
+		// OVS flow table metrics updater
+		go ovsFlowTableMetricsUpdater(util.RunOVSVsctl, util.RunOVSOfctl, 30*time.Second, stopChan)
 		// OVS interface metrics updater
 		go ovsInterfaceMetricsUpdater(util.RunOVSVsctl, 30*time.Second, stopChan)
 		// OVS memory metrics updater
@@ -8,6 +8,7 @@ import (
 	"github.com/onsi/ginkgo"
 	"github.com/onsi/gomega"
 	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
 )
 
 type clientOutput struct {
@@ -34,9 +35,18 @@ func (c *fakeOVSClient) FakeCall(args ...string) (string, string, error) {
 const (
 	ovsAppctlDumpAggregateSampleOutput = "NXST_AGGREGATE reply (xid=0x4): packet_count=856244 byte_count=3464651294 flow_count=30"
 	ovsVsctlListBridgeOutput           = "br-int,porta portb portc\nbr-ex,portd porte"
+	ovsVsctlListFlowTableOutput        = "a5a5a3a4-a1d1-4f7b-9c5a-2b1d3e0f6c7d,1000\n0d2c7a43-9e8f-4b8c-8d9e-6f5a4b3c2d1e,"
+	ovsVsctlListBridgeFlowTableOutput  = "br-int,\nbr-ex,0=a5a5a3a4-a1d1-4f7b-9c5a-2b1d3e0f6c7d 1=0d2c7a43-9e8f-4b8c-8d9e-6f5a4b3c2d1e"
 	ovsVsctlListInterfaceOutput        = "1,collisions=10 rx_bytes=0 rx_crc_err=0 rx_dropped=5 rx_errors=100 rx_frame_err=0 rx_missed_errors=0 rx_over_err=0 rx_packets=0 tx_bytes=0 tx_dropped=50 tx_errors=20 tx_packets=0\n1,rx_bytes=0 rx_packets=1000 tx_bytes=0 tx_packets=80\n0,collisions=10 rx_bytes=0 rx_crc_err=0 rx_dropped=5 rx_errors=100 rx_frame_err=0 rx_missed_errors=0 rx_over_err=0 rx_packets=0 tx_bytes=0 tx_dropped=50 tx_errors=20 tx_packets=0"
 )
 
+func getGaugeVecValue(gaugeVec *prometheus.GaugeVec, labels ...string) float64 {
+	metric := &io_prometheus_client.Metric{}
+	err := gaugeVec.WithLabelValues(labels...).Write(metric)
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	return metric.GetGauge().GetValue()
+}
+
 var _ = ginkgo.Describe("OVS metrics", func() {
 	var stopChan chan struct{}
 	var resetsTotalMock, rxDroppedTotalMock, txDroppedTotalMock *mocks.GaugeMock
@@ -272,4 +282,63 @@ var _ = ginkgo.Describe("OVS metrics", func() {
 			gomega.Expect(err).ToNot(gomega.BeNil())
 		})
 	})
+
+	ginkgo.Context("On update of OVS flow table metrics", func() {
+		ginkgo.It("reports a table near its flow limit", func() {
+			ovsVsctl := NewFakeOVSClient([]clientOutput{
+				{stdout: ovsVsctlListFlowTableOutput},
+				{stdout: ovsVsctlListBridgeFlowTableOutput},
+			})
+			ovsOfctl := NewFakeOVSClient([]clientOutput{
+				{stdout: "NXST_AGGREGATE reply (xid=0x4): packet_count=856244 byte_count=3464651294 flow_count=950"},
+			})
+			err := updateOvsFlowTableMetrics(ovsVsctl.FakeCall, ovsOfctl.FakeCall)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			// only table 0 of br-ex has a Flow_Table with a flow limit
+			gomega.Expect(ovsOfctl.dataIndex).To(gomega.Equal(1))
+			gomega.Expect(getGaugeVecValue(metricOvsFlowTableFlowsTotal, "br-ex", "0")).To(gomega.BeNumerically("==", 950))
+			gomega.Expect(getGaugeVecValue(metricOvsFlowTableFlowLimit, "br-ex", "0")).To(gomega.BeNumerically("==", 1000))
+			gomega.Expect(getGaugeVecValue(metricOvsFlowTableNearLimit, "br-ex", "0")).To(gomega.BeNumerically("==", 1))
+		})
+
+		ginkgo.It("does not report a table below its flow limit", func() {
+			ovsVsctl := NewFakeOVSClient([]clientOutput{
+				{stdout: ovsVsctlListFlowTableOutput},
+				{stdout: ovsVsctlListBridgeFlowTableOutput},
+			})
+			ovsOfctl := NewFakeOVSClient([]clientOutput{
+				{stdout: ovsAppctlDumpAggregateSampleOutput},
+			})
+			err := updateOvsFlowTableMetrics(ovsVsctl.FakeCall, ovsOfctl.FakeCall)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(getGaugeVecValue(metricOvsFlowTableFlowsTotal, "br-ex", "0")).To(gomega.BeNumerically("==", 30))
+			gomega.Expect(getGaugeVecValue(metricOvsFlowTableNearLimit, "br-ex", "0")).To(gomega.BeNumerically("==", 0))
+		})
+
+		ginkgo.It("does nothing when there are no Flow_Tables", func() {
+			ovsVsctl := NewFakeOVSClient([]clientOutput{
+				{stdout: ""},
+			})
+			ovsOfctl := NewFakeOVSClient(nil)
+			err := updateOvsFlowTableMetrics(ovsVsctl.FakeCall, ovsOfctl.FakeCall)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(ovsVsctl.dataIndex).To(gomega.Equal(1))
+			ch := make(chan prometheus.Metric, 10)
+			defer close(ch)
+			metricOvsFlowTableNearLimit.Collect(ch)
+			gomega.Expect(ch).To(gomega.BeEmpty())
+		})
+
+		ginkgo.It("returns error when OVS ofctl client returns an error", func() {
+			ovsVsctl := NewFakeOVSClient([]clientOutput{
+				{stdout: ovsVsctlListFlowTableOutput},
+				{stdout: ovsVsctlListBridgeFlowTableOutput},
+			})
+			ovsOfctl := NewFakeOVSClient([]clientOutput{
+				{err: fmt.Errorf("failed to connect to bridge")},
+			})
+			err := updateOvsFlowTableMetrics(ovsVsctl.FakeCall, ovsOfctl.FakeCall)
+			gomega.Expect(err).To(gomega.HaveOccurred())
+		})
+	})
 })
@@ -824,6 +824,7 @@ func (c *Controller) syncEgressService(key string) error {
 }
 
 // Returns all of the non-host endpoints for the given service grouped by IPv4/IPv6.
+// The addresses that are not IPs of the address type of their slice are skipped.
 func (c *Controller) allEndpointsFor(svc *corev1.Service, localOnly bool) (sets.Set[string], sets.Set[string], error) {
 	// Get the endpoint slices associated to the Service
 	esLabelSelector := labels.Set(map[string]string{
@@ -839,13 +840,18 @@ func (c *Controller) allEndpointsFor(svc *corev1.Service, localOnly bool) (sets.
 	v6Endpoints := sets.New[string]()
 
 	for _, eps := range endpointSlices {
-		if eps.AddressType == discoveryv1.AddressTypeFQDN {
-			continue
-		}
-
-		epsToInsert := v4Endpoints
-		if eps.AddressType == discoveryv1.AddressTypeIPv6 {
+		var epsToInsert sets.Set[string]
+		switch eps.AddressType {
+		case discoveryv1.AddressTypeIPv4:
+			epsToInsert = v4Endpoints
+		case discoveryv1.AddressTypeIPv6:
 			epsToInsert = v6Endpoints
+		case discoveryv1.AddressTypeFQDN:
+			continue
+		default:
+			klog.Warningf("Ignoring endpointslice %s/%s of service %s/%s with unsupported address type %q",
+				eps.Namespace, eps.Name, svc.Namespace, svc.Name, eps.AddressType)
+			continue
 		}
 
 		for _, ep := range eps.Endpoints {
@@ -853,7 +859,13 @@ func (c *Controller) allEndpointsFor(svc *corev1.Service, localOnly bool) (sets.
 				continue
 			}
 			for _, ip := range ep.Addresses {
-				ipStr := utilnet.ParseIPSloppy(ip).String()
+				parsedIP := utilnet.ParseIPSloppy(ip)
+				if parsedIP == nil || utilnet.IsIPv6(parsedIP) != (eps.AddressType == discoveryv1.AddressTypeIPv6) {
+					klog.Warningf("Ignoring invalid address %q of endpointslice %s/%s of service %s/%s with address type %q",
+						ip, eps.Namespace, eps.Name, svc.Namespace, svc.Name, eps.AddressType)
+					continue
+				}
+				ipStr := parsedIP.String()
 				if !services.IsHostEndpoint(ipStr) {
 					epsToInsert.Insert(ipStr)
 				}
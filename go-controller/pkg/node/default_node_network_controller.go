@@ -740,7 +740,7 @@ func (nc *DefaultNodeNetworkController) Start(ctx context.Context) error {
 			}
 		}
 
-		nc.vsClient, err = libovsdb.NewVSwitchClient(nc.stopChan)
+		nc.vsClient, err = libovsdb.NewVSwitchClient(nc.stopChan, 0)
 		if err != nil {
 			return fmt.Errorf("failed to create vswitchd database client: %w", err)
 		}
@@ -1187,6 +1187,11 @@ func (nc *DefaultNodeNetworkController) reconcileConntrackUponEndpointSliceEvent
 		// nothing to do upon an add event
 		return nil
 	}
+	if config.Gateway.DisableConntrackFlush {
+		klog.V(4).Infof("Conntrack flush disabled, skipping the conntrack reconciliation of endpointslice %s/%s",
+			oldEndpointSlice.Namespace, oldEndpointSlice.Name)
+		return nil
+	}
 	namespacedName, err := util.ServiceNamespacedNameFromEndpointSlice(oldEndpointSlice)
 	if err != nil {
 		return fmt.Errorf("cannot reconcile conntrack: %v", err)
@@ -1209,8 +1214,10 @@ func (nc *DefaultNodeNetworkController) reconcileConntrackUponEndpointSliceEvent
 					continue
 				}
 				// upon update and delete events, flush conntrack only for UDP
-				if err := util.DeleteConntrackServicePort(oldIPStr, *oldPort.Port, *oldPort.Protocol,
-					netlink.ConntrackReplyAnyIP, nil); err != nil {
+				deleted, err := util.DeleteConntrackServicePortUpTo(oldIPStr, *oldPort.Port, *oldPort.Protocol,
+					netlink.ConntrackReplyAnyIP, nil, 0)
+				recordServiceConntrackDeletes(conntrackDeletePurposeEndpointChange, deleted)
+				if err != nil {
 					klog.Errorf("Failed to delete conntrack entry for %s: %v", oldIPStr, err)
 				}
 			}
@@ -0,0 +1,65 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// endpointSliceCoalescer merges the endpoint slice events of a service that
+// are received within a window into a single sync of that service, run once
+// the window since the first of those events elapses. The sync recomputes the
+// service state from the informer caches rather than from the events, so it
+// always converges to the state of the last event and lags behind it by at
+// most the window.
+type endpointSliceCoalescer struct {
+	window time.Duration
+	syncFn func(name ktypes.NamespacedName) error
+
+	// pending holds the services with a scheduled sync
+	pending     sets.Set[ktypes.NamespacedName]
+	pendingLock sync.Mutex
+	// syncLock serializes syncs so that a sync taking longer than the window
+	// does not race with the next one
+	syncLock sync.Mutex
+}
+
+func newEndpointSliceCoalescer(window time.Duration, syncFn func(name ktypes.NamespacedName) error) *endpointSliceCoalescer {
+	return &endpointSliceCoalescer{
+		window:  window,
+		syncFn:  syncFn,
+		pending: sets.New[ktypes.NamespacedName](),
+	}
+}
+
+// enqueue schedules a sync of the given service after the window, unless one
+// is already scheduled, in which case the event is merged into it.
+func (c *endpointSliceCoalescer) enqueue(name ktypes.NamespacedName) {
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+	if c.pending.Has(name) {
+		return
+	}
+	c.pending.Insert(name)
+	time.AfterFunc(c.window, func() {
+		c.sync(name)
+	})
+}
+
+func (c *endpointSliceCoalescer) sync(name ktypes.NamespacedName) {
+	// events received from now on need a new sync as this one might not see
+	// them anymore
+	c.pendingLock.Lock()
+	c.pending.Delete(name)
+	c.pendingLock.Unlock()
+
+	c.syncLock.Lock()
+	defer c.syncLock.Unlock()
+	if err := c.syncFn(name); err != nil {
+		klog.Errorf("Failed to sync endpoints of service %s, retrying in %v: %v", name, c.window, err)
+		c.enqueue(name)
+	}
+}
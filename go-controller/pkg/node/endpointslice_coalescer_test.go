@@ -0,0 +1,89 @@
+package node
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	ktypes "k8s.io/apimachinery/pkg/types"
+)
+
+func TestEndpointSliceCoalescer(t *testing.T) {
+	svc1 := ktypes.NamespacedName{Namespace: "namespace1", Name: "service1"}
+	svc2 := ktypes.NamespacedName{Namespace: "namespace1", Name: "service2"}
+
+	tests := []struct {
+		desc string
+		// failures is the number of times the sync of a service fails before
+		// succeeding
+		failures      int
+		expectedSyncs map[ktypes.NamespacedName]int
+	}{
+		{
+			desc:          "merges rapid updates of a service into a single sync",
+			expectedSyncs: map[ktypes.NamespacedName]int{svc1: 1, svc2: 1},
+		},
+		{
+			desc:          "retries a failed sync",
+			failures:      1,
+			expectedSyncs: map[ktypes.NamespacedName]int{svc1: 2, svc2: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+
+			var lock sync.Mutex
+			// state is what the informer caches would hold for each service
+			state := map[ktypes.NamespacedName]int{}
+			synced := map[ktypes.NamespacedName]int{}
+			syncs := map[ktypes.NamespacedName]int{}
+			syncFn := func(name ktypes.NamespacedName) error {
+				lock.Lock()
+				defer lock.Unlock()
+				syncs[name]++
+				if syncs[name] <= tt.failures {
+					return fmt.Errorf("failed to sync %s", name)
+				}
+				synced[name] = state[name]
+				return nil
+			}
+			c := newEndpointSliceCoalescer(200*time.Millisecond, syncFn)
+
+			// issue rapid updates of both services, well within the window
+			for i := 1; i <= 50; i++ {
+				for _, name := range []ktypes.NamespacedName{svc1, svc2} {
+					lock.Lock()
+					state[name] = i
+					lock.Unlock()
+					c.enqueue(name)
+				}
+			}
+
+			getSynced := func() map[ktypes.NamespacedName]int {
+				lock.Lock()
+				defer lock.Unlock()
+				out := map[ktypes.NamespacedName]int{}
+				for name, value := range synced {
+					out[name] = value
+				}
+				return out
+			}
+			getSyncs := func() map[ktypes.NamespacedName]int {
+				lock.Lock()
+				defer lock.Unlock()
+				out := map[ktypes.NamespacedName]int{}
+				for name, value := range syncs {
+					out[name] = value
+				}
+				return out
+			}
+			// the last update is eventually applied
+			g.Eventually(getSynced, 2*time.Second).Should(gomega.Equal(map[ktypes.NamespacedName]int{svc1: 50, svc2: 50}))
+			g.Consistently(getSyncs, 500*time.Millisecond).Should(gomega.Equal(tt.expectedSyncs))
+		})
+	}
+}
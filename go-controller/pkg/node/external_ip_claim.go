@@ -0,0 +1,162 @@
+package node
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	kapi "k8s.io/api/core/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+const externalIPConflictEventReason = "ExternalIPConflict"
+
+// externalIPClaim is an externalIP, protocol and port the gateway bridge
+// flows of a service match on
+type externalIPClaim struct {
+	ip       string
+	protocol kapi.Protocol
+	port     int32
+}
+
+func (c externalIPClaim) String() string {
+	return fmt.Sprintf("%s:%d/%s", c.ip, c.port, c.protocol)
+}
+
+// externalIPClaimWatcher detects the externalIP, protocol and port claimed by
+// several services, whose gateway bridge flows have the same priority and
+// match and conflict, and reports them with a warning and an event on each of
+// the services
+type externalIPClaimWatcher struct {
+	recorder record.EventRecorder
+	// claimsLock protects claims
+	claimsLock sync.Mutex
+	// claims holds the services claiming each externalIP, protocol and port
+	claims map[externalIPClaim]sets.Set[ktypes.NamespacedName]
+}
+
+func newExternalIPClaimWatcher(recorder record.EventRecorder) *externalIPClaimWatcher {
+	return &externalIPClaimWatcher{
+		recorder: recorder,
+		claims:   map[externalIPClaim]sets.Set[ktypes.NamespacedName]{},
+	}
+}
+
+// getExternalIPClaims returns the externalIP, protocol and port claimed by
+// the service
+func getExternalIPClaims(svc *kapi.Service) []externalIPClaim {
+	var claims []externalIPClaim
+	for _, externalIP := range svc.Spec.ExternalIPs {
+		for _, svcPort := range svc.Spec.Ports {
+			claims = append(claims, externalIPClaim{
+				ip:       normalizeExternalIP(externalIP),
+				protocol: svcPort.Protocol,
+				port:     svcPort.Port,
+			})
+		}
+	}
+	return claims
+}
+
+func (e *externalIPClaimWatcher) AddService(svc *kapi.Service) error {
+	e.claimsLock.Lock()
+	defer e.claimsLock.Unlock()
+	e.addClaims(svc)
+	return nil
+}
+
+func (e *externalIPClaimWatcher) UpdateService(old, new *kapi.Service) error {
+	// do not report the conflicts again on updates not changing the claims
+	if reflect.DeepEqual(getExternalIPClaims(old), getExternalIPClaims(new)) {
+		return nil
+	}
+	e.claimsLock.Lock()
+	defer e.claimsLock.Unlock()
+	e.deleteClaims(old)
+	e.addClaims(new)
+	return nil
+}
+
+func (e *externalIPClaimWatcher) DeleteService(svc *kapi.Service) error {
+	e.claimsLock.Lock()
+	defer e.claimsLock.Unlock()
+	e.deleteClaims(svc)
+	return nil
+}
+
+func (e *externalIPClaimWatcher) SyncServices(objs []interface{}) error {
+	e.claimsLock.Lock()
+	defer e.claimsLock.Unlock()
+	e.claims = map[externalIPClaim]sets.Set[ktypes.NamespacedName]{}
+	for _, obj := range objs {
+		svc, ok := obj.(*kapi.Service)
+		if !ok {
+			klog.Errorf("Spurious object in syncServices: %v", obj)
+			continue
+		}
+		e.addClaims(svc)
+	}
+	return nil
+}
+
+// addClaims records the claims of the service and reports the ones already
+// claimed by other services. Must be called with claimsLock held.
+func (e *externalIPClaimWatcher) addClaims(svc *kapi.Service) {
+	name := ktypes.NamespacedName{Namespace: svc.Namespace, Name: svc.Name}
+	for _, claim := range getExternalIPClaims(svc) {
+		claimants, ok := e.claims[claim]
+		if !ok {
+			claimants = sets.New[ktypes.NamespacedName]()
+			e.claims[claim] = claimants
+		}
+		if claimants.Has(name) {
+			continue
+		}
+		claimants.Insert(name)
+		if claimants.Len() > 1 {
+			e.reportConflict(claim, claimants)
+		}
+	}
+}
+
+// deleteClaims drops the claims of the service. Must be called with
+// claimsLock held.
+func (e *externalIPClaimWatcher) deleteClaims(svc *kapi.Service) {
+	name := ktypes.NamespacedName{Namespace: svc.Namespace, Name: svc.Name}
+	for _, claim := range getExternalIPClaims(svc) {
+		claimants, ok := e.claims[claim]
+		if !ok {
+			continue
+		}
+		claimants.Delete(name)
+		if claimants.Len() == 0 {
+			delete(e.claims, claim)
+		}
+	}
+}
+
+// reportConflict warns about the claim shared by several services and emits
+// an event on each of them
+func (e *externalIPClaimWatcher) reportConflict(claim externalIPClaim, claimants sets.Set[ktypes.NamespacedName]) {
+	names := make([]string, 0, claimants.Len())
+	for name := range claimants {
+		names = append(names, name.String())
+	}
+	sort.Strings(names)
+	klog.Warningf("ExternalIP %s is claimed by several services, their gateway flows conflict: %s",
+		claim, strings.Join(names, ", "))
+	for name := range claimants {
+		serviceRef := kapi.ObjectReference{
+			Kind:      "Service",
+			Namespace: name.Namespace,
+			Name:      name.Name,
+		}
+		e.recorder.Eventf(&serviceRef, kapi.EventTypeWarning, externalIPConflictEventReason,
+			"ExternalIP %s is claimed by several services: %s", claim, strings.Join(names, ", "))
+	}
+}
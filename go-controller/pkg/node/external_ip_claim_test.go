@@ -0,0 +1,95 @@
+package node
+
+import (
+	"testing"
+
+	kapi "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestExternalIPClaimWatcherConflicts(t *testing.T) {
+	ports := []kapi.ServicePort{
+		{Name: "http", Port: 8080, Protocol: kapi.ProtocolTCP},
+	}
+	service1 := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeClusterIP,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{}, false, false)
+	service2 := newService("service2", "namespace1", "10.96.0.11", ports, kapi.ServiceTypeClusterIP,
+		[]string{"1.1.1.1", "1.1.1.2"}, kapi.ServiceStatus{}, false, false)
+	otherPorts := []kapi.ServicePort{
+		{Name: "http", Port: 8080, Protocol: kapi.ProtocolUDP},
+		{Name: "https", Port: 8443, Protocol: kapi.ProtocolTCP},
+	}
+	service3 := newService("service3", "namespace1", "10.96.0.12", otherPorts, kapi.ServiceTypeClusterIP,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{}, false, false)
+
+	recorder := record.NewFakeRecorder(10)
+	watcher := newExternalIPClaimWatcher(recorder)
+	expectEvents := func(expected ...string) {
+		t.Helper()
+		received := map[string]int{}
+		for len(recorder.Events) > 0 {
+			received[<-recorder.Events]++
+		}
+		wanted := map[string]int{}
+		for _, event := range expected {
+			wanted[event]++
+		}
+		if len(received) != len(wanted) {
+			t.Fatalf("expected events %v, got %v", wanted, received)
+		}
+		for event, count := range wanted {
+			if received[event] != count {
+				t.Errorf("expected events %v, got %v", wanted, received)
+			}
+		}
+	}
+	conflict := "Warning ExternalIPConflict ExternalIP 1.1.1.1:8080/TCP is claimed by several services: " +
+		"namespace1/service1, namespace1/service2"
+
+	if err := watcher.SyncServices([]interface{}{service1, service3}); err != nil {
+		t.Fatal(err)
+	}
+	// services claiming the same externalIP on other protocols or ports do not conflict
+	expectEvents()
+
+	// the conflict is reported on both services
+	if err := watcher.AddService(service2); err != nil {
+		t.Fatal(err)
+	}
+	expectEvents(conflict, conflict)
+
+	// updates not changing the claims do not report the conflict again
+	updated := service2.DeepCopy()
+	updated.Spec.ClusterIP = "10.96.0.13"
+	if err := watcher.UpdateService(service2, updated); err != nil {
+		t.Fatal(err)
+	}
+	expectEvents()
+
+	// no conflict once one of the services released the claim
+	if err := watcher.DeleteService(service1); err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.AddService(service1); err != nil {
+		t.Fatal(err)
+	}
+	expectEvents(conflict, conflict)
+	if err := watcher.DeleteService(service1); err != nil {
+		t.Fatal(err)
+	}
+	updated = service2.DeepCopy()
+	updated.Spec.ExternalIPs = []string{"1.1.1.2"}
+	if err := watcher.UpdateService(service2, updated); err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.AddService(service1); err != nil {
+		t.Fatal(err)
+	}
+	expectEvents()
+
+	// syncing the services reports the conflicts again
+	if err := watcher.SyncServices([]interface{}{service1, service2, service3}); err != nil {
+		t.Fatal(err)
+	}
+	expectEvents(conflict, conflict)
+}
@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/informer"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/kube"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/metrics"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
 	util "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 	"github.com/pkg/errors"
@@ -16,9 +18,17 @@ import (
 	kapi "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1"
 	apierrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 )
 
+// masqueradeIPVerifyPeriod is the period the node masquerade IPs are verified on the gateway bridge at
+const masqueradeIPVerifyPeriod = 30 * time.Second
+
+// ovsReconnectCheckPeriod is the period the vswitchd database connection is checked for a reconnect at
+const ovsReconnectCheckPeriod = time.Second
+
 // Gateway responds to Service and Endpoint K8s events
 // and programs OVN gateway functionality.
 // It may also spawn threads to ensure the flow tables
@@ -35,14 +45,29 @@ type gateway struct {
 	loadBalancerHealthChecker informer.ServiceAndEndpointsEventHandler
 	// portClaimWatcher is for reserving ports for virtual IPs allocated by the cluster on the host
 	portClaimWatcher informer.ServiceEventHandler
+	// externalIPClaimWatcher reports the externalIPs and ports claimed by several services
+	externalIPClaimWatcher informer.ServiceEventHandler
 	// nodePortWatcherIptables is used in Shared GW mode to handle nodePort IPTable rules
 	nodePortWatcherIptables informer.ServiceEventHandler
 	// nodePortWatcher is used in Local+Shared GW modes to handle nodePort flows in shared OVS bridge
 	nodePortWatcher informer.ServiceAndEndpointsEventHandler
 	openflowManager *openflowManager
 	nodeIPManager   *addressManager
-	initFunc        func() error
-	readyFunc       func() (bool, error)
+	subnets         []*net.IPNet // node subnets the default bridge flows are generated for
+	// masqueradeIPBridge, if set, is the bridge the node masquerade IPs are periodically verified on
+	masqueradeIPBridge string
+	// ovsConnected, if set, reports whether the vswitchd database is connected, the flows being
+	// resynced when it reconnects
+	ovsConnected func() bool
+	initFunc     func() error
+	// readyFunc returns whether the gateway is ready and, if not, why
+	readyFunc func() (bool, string, error)
+	// readinessLock protects ready and notReadyReason, the result of the last readiness check
+	readinessLock  sync.Mutex
+	ready          bool
+	notReadyReason string
+	// recorder, if set, records the events of the gateway on the services
+	recorder record.EventRecorder
 
 	watchFactory *factory.WatchFactory // used for retry
 	stopChan     <-chan struct{}
@@ -58,6 +83,11 @@ func (g *gateway) AddService(svc *kapi.Service) error {
 			errors = append(errors, err)
 		}
 	}
+	if g.externalIPClaimWatcher != nil {
+		if err = g.externalIPClaimWatcher.AddService(svc); err != nil {
+			errors = append(errors, err)
+		}
+	}
 	if g.loadBalancerHealthChecker != nil {
 		if err = g.loadBalancerHealthChecker.AddService(svc); err != nil {
 			errors = append(errors, err)
@@ -85,6 +115,11 @@ func (g *gateway) UpdateService(old, new *kapi.Service) error {
 			errors = append(errors, err)
 		}
 	}
+	if g.externalIPClaimWatcher != nil {
+		if err = g.externalIPClaimWatcher.UpdateService(old, new); err != nil {
+			errors = append(errors, err)
+		}
+	}
 	if g.loadBalancerHealthChecker != nil {
 		if err = g.loadBalancerHealthChecker.UpdateService(old, new); err != nil {
 			errors = append(errors, err)
@@ -112,6 +147,11 @@ func (g *gateway) DeleteService(svc *kapi.Service) error {
 			errors = append(errors, err)
 		}
 	}
+	if g.externalIPClaimWatcher != nil {
+		if err = g.externalIPClaimWatcher.DeleteService(svc); err != nil {
+			errors = append(errors, err)
+		}
+	}
 	if g.loadBalancerHealthChecker != nil {
 		if err = g.loadBalancerHealthChecker.DeleteService(svc); err != nil {
 			errors = append(errors, err)
@@ -135,6 +175,9 @@ func (g *gateway) SyncServices(objs []interface{}) error {
 	if g.portClaimWatcher != nil {
 		err = g.portClaimWatcher.SyncServices(objs)
 	}
+	if err == nil && g.externalIPClaimWatcher != nil {
+		err = g.externalIPClaimWatcher.SyncServices(objs)
+	}
 	if err == nil && g.loadBalancerHealthChecker != nil {
 		err = g.loadBalancerHealthChecker.SyncServices(objs)
 	}
@@ -212,6 +255,9 @@ func (g *gateway) Init(wf factory.NodeWatchFactory, stopChan <-chan struct{}, wg
 	if err = g.initFunc(); err != nil {
 		return err
 	}
+	if g.openflowManager != nil {
+		metrics.SetGatewayFlowReconcileFunc(g.ReconcileFlows)
+	}
 	servicesRetryFramework := g.newRetryFrameworkNode(factory.ServiceForGatewayType)
 	if _, err = servicesRetryFramework.WatchResource(); err != nil {
 		return fmt.Errorf("gateway init failed to start watching services: %v", err)
@@ -224,15 +270,91 @@ func (g *gateway) Init(wf factory.NodeWatchFactory, stopChan <-chan struct{}, wg
 	return nil
 }
 
+// ReconcileFlows regenerates the whole flow cache of the gateway bridges, the
+// default flows and the flows of all services, and applies it, replacing any
+// flow modified or added out of band. It is safe to call concurrently with the
+// gateway event handlers.
+func (g *gateway) ReconcileFlows() error {
+	if g.openflowManager == nil {
+		return fmt.Errorf("gateway bridge flows are not managed")
+	}
+	klog.Info("Reconciling the gateway bridge flows")
+	if err := g.openflowManager.updateBridgeFlowCache(g.subnets, g.nodeIPManager.ListAddresses()); err != nil {
+		return fmt.Errorf("failed to regenerate the gateway bridge default flows: %w", err)
+	}
+	if npw, ok := g.nodePortWatcher.(*nodePortWatcher); ok {
+		if err := npw.regenerateServiceFlows(); err != nil {
+			return fmt.Errorf("failed to regenerate the gateway bridge service flows: %w", err)
+		}
+	}
+	g.openflowManager.syncFlows()
+	return nil
+}
+
+// onNodeIPsChanged resyncs the gateway bridge flows on a node IP change, and
+// reconciles the neighbor entries of the masquerade IPs on the bridge as they
+// may have been flushed along with the addresses
+func (g *gateway) onNodeIPsChanged() {
+	klog.V(5).Info("Node addresses changed, re-syncing bridge flows")
+	if err := g.openflowManager.updateBridgeFlowCache(g.subnets, g.nodeIPManager.ListAddresses()); err != nil {
+		// very unlikely - somehow node has lost its IP address
+		klog.Errorf("Failed to re-generate gateway flows after address change: %v", err)
+	}
+	// update gateway IPs for service openflows programmed by nodePortWatcher interface
+	if npw, ok := g.nodePortWatcher.(*nodePortWatcher); ok {
+		npw.updateGatewayIPs(g.nodeIPManager)
+	}
+	g.openflowManager.requestFlowSync()
+	if err := addHostMACBindings(g.openflowManager.defaultBridge.bridgeName); err != nil {
+		klog.Errorf("Failed to reconcile the MAC bindings for service routing after address change: %v", err)
+	}
+}
+
 func (g *gateway) Start() {
 	if g.nodeIPManager != nil {
 		g.nodeIPManager.Run(g.stopChan, g.wg)
 	}
 
 	if g.openflowManager != nil {
+		g.openflowManager.logFlowDiff()
 		klog.Info("Spawning Conntrack Rule Check Thread")
 		g.openflowManager.Run(g.stopChan, g.wg)
 	}
+
+	if g.masqueradeIPBridge != "" {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			wait.Until(func() {
+				verifyNodeMasqueradeIPOnExtBridge(g.masqueradeIPBridge)
+			}, masqueradeIPVerifyPeriod, g.stopChan)
+		}()
+	}
+
+	if g.openflowManager != nil && g.ovsConnected != nil {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			watchOVSReconnect(g.ovsConnected, g.openflowManager.requestFlowSync, ovsReconnectCheckPeriod, g.stopChan)
+		}()
+	}
+}
+
+// watchOVSReconnect calls onReconnect each time connected reports the vswitchd database connection
+// back after it was lost, checking it every period until stopChan is closed. OVS may have been
+// restarted in the meantime, losing the flows programmed on the bridges.
+func watchOVSReconnect(connected func() bool, onReconnect func(), period time.Duration, stopChan <-chan struct{}) {
+	wasConnected := connected()
+	wait.Until(func() {
+		isConnected := connected()
+		if isConnected && !wasConnected {
+			klog.Info("Reconnected to the vswitchd database, requesting a full gateway flow sync")
+			onReconnect()
+		} else if !isConnected && wasConnected {
+			klog.Warning("Lost the connection to the vswitchd database")
+		}
+		wasConnected = isConnected
+	}, period, stopChan)
 }
 
 // sets up an uplink interface for UDP Generic Receive Offload forwarding as part of
@@ -345,14 +467,63 @@ func gatewayInitInternal(nodeName, gwIntf, egressGatewayIntf string, gwNextHops
 	return gatewayBridge, egressGWBridge, err
 }
 
-func gatewayReady(patchPort string) (bool, error) {
+// gatewayConfigExternalID is the external-id of the gateway bridge recording the
+// gateway mode and options its flows are programmed with, for diagnostics
+const gatewayConfigExternalID = "ovn-k8s-gateway-config"
+
+// setGatewayConfigExternalID records the gateway mode and options in the
+// external-ids of the gateway bridge, replacing the ones of a previous run
+func setGatewayConfigExternalID(bridgeName string) error {
+	gwConfig := fmt.Sprintf("mode=%s,disable-snat-multiple-gws=%t,allow-no-uplink=%t,disable-forwarding=%t",
+		config.Gateway.Mode, config.Gateway.DisableSNATMultipleGWs, config.Gateway.AllowNoUplink,
+		config.Gateway.DisableForwarding)
+	_, stderr, err := util.RunOVSVsctl("set", "bridge", bridgeName,
+		fmt.Sprintf("external_ids:%s=\"%s\"", gatewayConfigExternalID, gwConfig))
+	if err != nil {
+		return fmt.Errorf("failed to set the gateway config external-id on bridge %s, stderr: %q, error: %v",
+			bridgeName, stderr, err)
+	}
+	return nil
+}
+
+func gatewayReady(patchPort string) bool {
 	// Get ofport of patchPort
 	ofport, _, err := util.GetOVSOfPort("--if-exists", "get", "interface", patchPort, "ofport")
-	if err != nil || len(ofport) == 0 {
-		return false, nil
+	return err == nil && len(ofport) != 0
+}
+
+// gatewayBridgesReady returns whether the patch ports of the gateway bridge and, if any, of the
+// external gateway bridge are ready and, if not, which one is not
+func gatewayBridgesReady(gwBridge, exGwBridge *bridgeConfiguration) (bool, string, error) {
+	if !gatewayReady(gwBridge.patchPort) {
+		return false, fmt.Sprintf("patch port %s of gateway bridge %s is not ready", gwBridge.patchPort, gwBridge.bridgeName), nil
+	}
+	if exGwBridge != nil && !gatewayReady(exGwBridge.patchPort) {
+		return false, fmt.Sprintf("patch port %s of external gateway bridge %s is not ready", exGwBridge.patchPort, exGwBridge.bridgeName), nil
 	}
 	klog.Info("Gateway is ready")
-	return true, nil
+	return true, "", nil
+}
+
+// setReadiness records the result of a readiness check of the gateway, logging
+// the reason it is not ready whenever it changes
+func (g *gateway) setReadiness(ready bool, reason string) {
+	g.readinessLock.Lock()
+	defer g.readinessLock.Unlock()
+	if ready {
+		reason = ""
+	} else if reason != g.notReadyReason {
+		klog.Infof("Gateway is not ready: %s", reason)
+	}
+	g.ready = ready
+	g.notReadyReason = reason
+}
+
+// getReadiness returns the result of the last readiness check of the gateway
+func (g *gateway) getReadiness() (bool, string) {
+	g.readinessLock.Lock()
+	defer g.readinessLock.Unlock()
+	return g.ready, g.notReadyReason
 }
 
 func (g *gateway) GetGatewayBridgeIface() string {
@@ -370,6 +541,9 @@ type bridgeConfiguration struct {
 	ofPortPatch string
 	ofPortPhys  string
 	ofPortHost  string
+	// hostRepresentor is the DPU host representor port whose ofport is
+	// ofPortHost, set in DPU mode only
+	hostRepresentor string
 }
 
 // updateInterfaceIPAddresses sets and returns the bridge's current ips
@@ -0,0 +1,173 @@
+//go:build linux && datapath_selftest
+
+// The datapath self-test programs the gateway bridge flows of synthetic
+// services on a scratch bridge of the local OVS and traces packets through
+// them with ofproto/trace, to validate the service datapath end-to-end against
+// a real OVS rather than the generated flow strings. It requires root and a
+// running ovs-vswitchd, and is only built with the datapath_selftest tag:
+//
+//	go test -tags datapath_selftest ./pkg/node -run TestDatapathSelfTest
+package node
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	kapi "k8s.io/api/core/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	kexec "k8s.io/utils/exec"
+)
+
+const (
+	// selfTestBridge is the scratch bridge the self-test flows are programmed
+	// on, the node bridges are never touched
+	selfTestBridge    = "brselftest0"
+	selfTestPhysPort  = "selftest-phys"
+	selfTestPatchPort = "selftest-patch"
+	selfTestNodeIP    = "192.168.100.2"
+)
+
+// traceOutputRegex matches the output actions executed in an ofproto/trace
+var traceOutputRegex = regexp.MustCompile(`^\s*output:(\S+)$`)
+
+// datapathSelfTest is a scratch gateway bridge with a physical and a patch
+// port, and the node port watcher programming its service flows
+type datapathSelfTest struct {
+	t   *testing.T
+	npw *nodePortWatcher
+	// ofport of the physical and patch ports of the bridge
+	ofPortPhys  string
+	ofPortPatch string
+}
+
+// newDatapathSelfTest creates the scratch bridge, deleted once the test
+// completes. The test is skipped if there is no OVS to run against.
+func newDatapathSelfTest(t *testing.T) *datapathSelfTest {
+	if os.Getenv("NOROOT") == "TRUE" {
+		t.Skip("Test requires root privileges")
+	}
+	if err := util.SetExec(kexec.New()); err != nil {
+		t.Skipf("Test requires the OVS utilities: %v", err)
+	}
+	if _, stderr, err := util.RunOVSVsctl("show"); err != nil {
+		t.Skipf("Test requires a running OVS, stderr: %q, error: %v", stderr, err)
+	}
+
+	s := &datapathSelfTest{t: t}
+	s.run("--if-exists", "del-br", selfTestBridge)
+	s.run("add-br", selfTestBridge, "--", "set", "Bridge", selfTestBridge, "fail_mode=secure")
+	t.Cleanup(func() {
+		s.run("--if-exists", "del-br", selfTestBridge)
+	})
+	s.ofPortPhys = s.addPort(selfTestPhysPort, 1)
+	s.ofPortPatch = s.addPort(selfTestPatchPort, 2)
+
+	s.npw = &nodePortWatcher{
+		ofportPhys:    s.ofPortPhys,
+		ofportPatch:   s.ofPortPatch,
+		gwBridge:      selfTestBridge,
+		gatewayIPv4:   selfTestNodeIP,
+		serviceInfo:   map[ktypes.NamespacedName]*serviceConfig{},
+		nodeIPManager: &addressManager{addresses: sets.New(selfTestNodeIP)},
+		ofm: &openflowManager{
+			defaultBridge: &bridgeConfiguration{bridgeName: selfTestBridge},
+			flowCache:     map[string][]string{},
+			flowChan:      make(chan struct{}, 1),
+			lastSyncTime:  map[string]time.Time{},
+		},
+	}
+	return s
+}
+
+func (s *datapathSelfTest) run(args ...string) string {
+	s.t.Helper()
+	stdout, stderr, err := util.RunOVSVsctl(args...)
+	if err != nil {
+		s.t.Fatalf("Failed to run ovs-vsctl %v, stderr: %q, error: %v", args, stderr, err)
+	}
+	return stdout
+}
+
+// addPort adds an internal port to the scratch bridge and returns its ofport
+func (s *datapathSelfTest) addPort(name string, ofport int) string {
+	s.t.Helper()
+	s.run("add-port", selfTestBridge, name, "--", "set", "Interface", name, "type=internal",
+		fmt.Sprintf("ofport_request=%d", ofport))
+	return s.run("get", "Interface", name, "ofport")
+}
+
+// programService generates the gateway bridge flows of the service and
+// replaces the flows of the scratch bridge with them
+func (s *datapathSelfTest) programService(service *kapi.Service, hasLocalHostNetworkEp bool) {
+	s.t.Helper()
+	if err := s.npw.updateServiceFlowCache(service, true, hasLocalHostNetworkEp); err != nil {
+		s.t.Fatalf("Failed to generate the flows of service %s/%s: %v", service.Namespace, service.Name, err)
+	}
+	s.npw.ofm.syncFlows()
+	if _, ok := s.npw.ofm.getLastSyncTimes()[selfTestBridge]; !ok {
+		s.t.Fatalf("Failed to program the flows of service %s/%s on bridge %s", service.Namespace, service.Name, selfTestBridge)
+	}
+}
+
+// traceOutputs traces the packet described by flow through the scratch bridge
+// and returns the ports it is output to
+func (s *datapathSelfTest) traceOutputs(flow string) []string {
+	s.t.Helper()
+	stdout, stderr, err := util.RunOVSAppctl("ofproto/trace", selfTestBridge, flow)
+	if err != nil {
+		s.t.Fatalf("Failed to trace %q, stderr: %q, error: %v", flow, stderr, err)
+	}
+	outputs := []string{}
+	for _, line := range strings.Split(stdout, "\n") {
+		if match := traceOutputRegex.FindStringSubmatch(line); match != nil {
+			outputs = append(outputs, match[1])
+		}
+	}
+	return outputs
+}
+
+// expectOutput asserts that the packet described by flow is only output to
+// ofport
+func (s *datapathSelfTest) expectOutput(flow, ofport string) {
+	s.t.Helper()
+	if outputs := s.traceOutputs(flow); len(outputs) != 1 || outputs[0] != ofport {
+		s.t.Errorf("Expected %q to be output to port %s, got outputs %v", flow, ofport, outputs)
+	}
+}
+
+func TestDatapathSelfTestNodePort(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+	s := newDatapathSelfTest(t)
+
+	ports := []kapi.ServicePort{
+		{Name: "http", Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)},
+	}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, false, false)
+	s.programService(service, false)
+
+	// the NodePort traffic entering the bridge is sent to OVN through the patch port
+	s.expectOutput(fmt.Sprintf("in_port=%s,tcp,nw_src=172.16.0.5,nw_dst=%s,tp_src=40000,tp_dst=31111",
+		s.ofPortPhys, selfTestNodeIP), s.ofPortPatch)
+	// and its replies are sent back out the physical port
+	s.expectOutput(fmt.Sprintf("in_port=%s,tcp,nw_src=%s,nw_dst=172.16.0.5,tp_src=31111,tp_dst=40000",
+		s.ofPortPatch, selfTestNodeIP), s.ofPortPhys)
+	// while the traffic towards other ports does not match the service flows
+	if outputs := s.traceOutputs(fmt.Sprintf("in_port=%s,tcp,nw_src=172.16.0.5,nw_dst=%s,tp_src=40000,tp_dst=31112",
+		s.ofPortPhys, selfTestNodeIP)); len(outputs) != 0 {
+		t.Errorf("Expected the traffic towards another port to be dropped, got outputs %v", outputs)
+	}
+}
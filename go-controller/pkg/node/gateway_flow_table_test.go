@@ -0,0 +1,76 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/libovsdbops"
+	libovsdbtest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/libovsdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/vswitchdb"
+)
+
+func TestSyncGatewayBridgeFlowTable(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	foreignName := "foreign"
+	vsClient, cleanup, err := libovsdbtest.NewVSTestHarness(libovsdbtest.TestSetup{
+		VSData: []libovsdbtest.TestData{
+			&vswitchdb.Bridge{UUID: "breth0-uuid", Name: "breth0"},
+			&vswitchdb.Bridge{UUID: "brfoo-uuid", Name: "brfoo", FlowTables: map[int]string{0: "foreign-uuid"}},
+			&vswitchdb.FlowTable{UUID: "foreign-uuid", Name: &foreignName},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to set up test harness: %v", err)
+	}
+	t.Cleanup(cleanup.Cleanup)
+
+	// returns the Flow_Table with the given name if it is the one referenced by
+	// the service flows table of the bridge
+	bridgeFlowTable := func(bridgeName, name string) *vswitchdb.FlowTable {
+		flowTables, err := libovsdbops.GetBridgeFlowTables(vsClient, bridgeName)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		uuid, ok := flowTables[gatewayBridgeFlowTableNum]
+		if !ok {
+			return nil
+		}
+		flowTable, err := libovsdbops.FindFlowTableByName(vsClient, name)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(flowTable.UUID).To(gomega.Equal(uuid))
+		return flowTable
+	}
+	name := gatewayBridgeFlowTableName("breth0")
+
+	// nothing is configured without prefixes
+	g.Expect(syncGatewayBridgeFlowTable(vsClient, "breth0")).To(gomega.Succeed())
+	g.Expect(bridgeFlowTable("breth0", name)).To(gomega.BeNil())
+
+	// the configured prefixes are set on the Flow_Table of the service flows table
+	config.Gateway.FlowTablePrefixes = "ip_dst,ipv6_dst"
+	g.Expect(syncGatewayBridgeFlowTable(vsClient, "breth0")).To(gomega.Succeed())
+	flowTable := bridgeFlowTable("breth0", name)
+	g.Expect(flowTable).NotTo(gomega.BeNil())
+	g.Expect(flowTable.Prefixes).To(gomega.ConsistOf("ip_dst", "ipv6_dst"))
+
+	// and updated in place when they change
+	config.Gateway.FlowTablePrefixes = "nw_dst"
+	g.Expect(syncGatewayBridgeFlowTable(vsClient, "breth0")).To(gomega.Succeed())
+	updated := bridgeFlowTable("breth0", name)
+	g.Expect(updated).NotTo(gomega.BeNil())
+	g.Expect(updated.UUID).To(gomega.Equal(flowTable.UUID))
+	g.Expect(updated.Prefixes).To(gomega.ConsistOf("nw_dst"))
+
+	// the Flow_Table is removed from the bridge once the option is cleared
+	config.Gateway.FlowTablePrefixes = ""
+	g.Expect(syncGatewayBridgeFlowTable(vsClient, "breth0")).To(gomega.Succeed())
+	g.Expect(bridgeFlowTable("breth0", name)).To(gomega.BeNil())
+
+	// while a Flow_Table not managed by ovnkube is left in place
+	g.Expect(syncGatewayBridgeFlowTable(vsClient, "brfoo")).To(gomega.Succeed())
+	g.Expect(bridgeFlowTable("brfoo", foreignName)).NotTo(gomega.BeNil())
+}
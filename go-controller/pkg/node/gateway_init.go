@@ -9,13 +9,56 @@ import (
 	"k8s.io/klog/v2"
 	utilnet "k8s.io/utils/net"
 
+	libovsdbclient "github.com/ovn-org/libovsdb/client"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/kube"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/libovsdbops"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/metrics"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
 	util "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 )
 
+// gatewayBridgeFlowTableNum is the OpenFlow table of the gateway bridge the
+// service flows are programmed in
+const gatewayBridgeFlowTableNum = 0
+
+// gatewayBridgeFlowTableName returns the name of the Flow_Table managed for
+// the service flows table of the given gateway bridge
+func gatewayBridgeFlowTableName(bridgeName string) string {
+	return "ovn-k8s-" + bridgeName
+}
+
+// syncGatewayBridgeFlowTable sets the configured flow table prefixes on the
+// Flow_Table of the service flows table of the gateway bridge. When no prefix
+// is configured the Flow_Table is removed from the bridge, unless the table is
+// configured with a Flow_Table that is not managed by ovnkube.
+func syncGatewayBridgeFlowTable(vsClient libovsdbclient.Client, bridgeName string) error {
+	name := gatewayBridgeFlowTableName(bridgeName)
+	prefixes := config.Gateway.GetFlowTablePrefixes()
+	if len(prefixes) > 0 {
+		flowTable := libovsdbops.NewFlowTable(name, 0, "")
+		flowTable.Prefixes = prefixes
+		return libovsdbops.CreateOrUpdateBridgeFlowTable(vsClient, bridgeName, gatewayBridgeFlowTableNum, flowTable)
+	}
+
+	flowTable, err := libovsdbops.FindFlowTableByName(vsClient, name)
+	if errors.Is(err, libovsdbclient.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	flowTables, err := libovsdbops.GetBridgeFlowTables(vsClient, bridgeName)
+	if err != nil {
+		return err
+	}
+	if flowTables[gatewayBridgeFlowTableNum] != flowTable.UUID {
+		return nil
+	}
+	return libovsdbops.RemoveBridgeFlowTable(vsClient, bridgeName, gatewayBridgeFlowTableNum)
+}
+
 // bridgedGatewayNodeSetup makes the bridge's MAC address permanent (if needed), sets up
 // the physical network name mappings for the bridge, and returns an ifaceID
 // created from the bridge name and the node name
@@ -339,6 +382,7 @@ func (nc *DefaultNodeNetworkController) initGateway(subnets []*net.IPNet, nodeAn
 
 	var loadBalancerHealthChecker *loadBalancerHealthChecker
 	var portClaimWatcher *portClaimWatcher
+	var externalIPClaimWatcher *externalIPClaimWatcher
 
 	if config.Gateway.NodeportEnable && config.OvnKubeNode.Mode == types.NodeModeFull {
 		loadBalancerHealthChecker = newLoadBalancerHealthChecker(nc.name, nc.watchFactory)
@@ -346,6 +390,7 @@ func (nc *DefaultNodeNetworkController) initGateway(subnets []*net.IPNet, nodeAn
 		if err != nil {
 			return err
 		}
+		externalIPClaimWatcher = newExternalIPClaimWatcher(nc.recorder)
 	}
 
 	gatewayNextHops, gatewayIntf, err := getGatewayNextHops()
@@ -391,7 +436,7 @@ func (nc *DefaultNodeNetworkController) initGateway(subnets []*net.IPNet, nodeAn
 		klog.Info("Gateway Mode is disabled")
 		gw = &gateway{
 			initFunc:     func() error { return nil },
-			readyFunc:    func() (bool, error) { return true, nil },
+			readyFunc:    func() (bool, string, error) { return true, "", nil },
 			watchFactory: nc.watchFactory.(*factory.WatchFactory),
 		}
 		chassisID, err = util.GetNodeChassisID()
@@ -417,19 +462,47 @@ func (nc *DefaultNodeNetworkController) initGateway(subnets []*net.IPNet, nodeAn
 	if portClaimWatcher != nil {
 		gw.portClaimWatcher = portClaimWatcher
 	}
+	if externalIPClaimWatcher != nil {
+		gw.externalIPClaimWatcher = externalIPClaimWatcher
+	}
+	if nc.vsClient != nil {
+		gw.ovsConnected = nc.vsClient.Connected
+	}
+	gw.recorder = nc.recorder
 
 	initGwFunc := func() error {
-		return gw.Init(nc.watchFactory, nc.stopChan, nc.wg)
+		if err := gw.Init(nc.watchFactory, nc.stopChan, nc.wg); err != nil {
+			return err
+		}
+		// the gateway bridge is only known once the gateway is initialized
+		if nc.vsClient != nil && gw.openflowManager != nil {
+			bridgeName := gw.openflowManager.defaultBridge.bridgeName
+			if err := syncGatewayBridgeFlowTable(nc.vsClient, bridgeName); err != nil {
+				return fmt.Errorf("failed to configure the flow table of bridge %s: %w", bridgeName, err)
+			}
+		}
+		return nil
 	}
 
 	readyGwFunc := func() (bool, error) {
 		controllerReady, err := isOVNControllerReady()
-		if err != nil || !controllerReady {
+		if err != nil {
+			gw.setReadiness(false, fmt.Sprintf("failed to check the ovn-controller readiness: %v", err))
 			return false, err
 		}
+		if !controllerReady {
+			gw.setReadiness(false, "ovn-controller is not ready")
+			return false, nil
+		}
 
-		return gw.readyFunc()
+		ready, reason, err := gw.readyFunc()
+		if err != nil {
+			reason = err.Error()
+		}
+		gw.setReadiness(ready, reason)
+		return ready, err
 	}
+	metrics.SetGatewayReadinessFunc(gw.getReadiness)
 
 	waiter.AddWait(readyGwFunc, initGwFunc)
 	nc.gateway = gw
@@ -495,7 +568,7 @@ func (nc *DefaultNodeNetworkController) initGatewayDPUHost(kubeNodeIP net.IP) er
 
 	gw := &gateway{
 		initFunc:     func() error { return nil },
-		readyFunc:    func() (bool, error) { return true, nil },
+		readyFunc:    func() (bool, string, error) { return true, "", nil },
 		watchFactory: nc.watchFactory.(*factory.WatchFactory),
 	}
 
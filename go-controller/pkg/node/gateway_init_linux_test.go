@@ -141,6 +141,9 @@ func shareGatewayInterfaceTest(app *cli.App, testNS ns.NetNS,
 			Cmd:    "ovs-vsctl --timeout=15 --if-exists get Open_vSwitch . other_config:hw-offload",
 			Output: fmt.Sprintf("%t", hwOffload),
 		})
+		fexec.AddFakeCmdsNoOutputNoError([]string{
+			"ovs-vsctl --timeout=15 set bridge breth0 external_ids:ovn-k8s-gateway-config=\"mode=shared,disable-snat-multiple-gws=false,allow-no-uplink=false,disable-forwarding=false\"",
+		})
 		fexec.AddFakeCmd(&ovntest.ExpectedCmd{
 			Cmd:    "ovs-vsctl --timeout=15 get Interface patch-breth0_node1-to-br-int ofport",
 			Output: "5",
@@ -188,6 +191,14 @@ func shareGatewayInterfaceTest(app *cli.App, testNS ns.NetNS,
 			Cmd:    "ovs-vsctl --timeout=15 --if-exists get interface eth0 ofport",
 			Output: "7",
 		})
+		fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+			Cmd:    "ovs-vsctl --timeout=15 port-to-br patch-breth0_" + nodeName + "-to-br-int",
+			Output: "breth0",
+		})
+		fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+			Cmd:    "ovs-vsctl --timeout=15 port-to-br eth0",
+			Output: "breth0",
+		})
 		// syncServices()
 
 		err := util.SetExec(fexec)
@@ -496,6 +507,9 @@ func shareGatewayInterfaceDPUTest(app *cli.App, testNS ns.NetNS,
 			Cmd:    "ovs-vsctl --timeout=15 --if-exists get Open_vSwitch . other_config:hw-offload",
 			Output: "false",
 		})
+		fexec.AddFakeCmdsNoOutputNoError([]string{
+			"ovs-vsctl --timeout=15 set bridge " + brphys + " external_ids:ovn-k8s-gateway-config=\"mode=shared,disable-snat-multiple-gws=false,allow-no-uplink=false,disable-forwarding=false\"",
+		})
 		// GetDPUHostInterface
 		fexec.AddFakeCmd(&ovntest.ExpectedCmd{
 			Cmd:    "ovs-vsctl --timeout=15 list-ports " + brphys,
@@ -558,6 +572,14 @@ func shareGatewayInterfaceDPUTest(app *cli.App, testNS ns.NetNS,
 			Cmd:    "ovs-vsctl --timeout=15 --if-exists get interface " + uplinkPort + " ofport",
 			Output: "7",
 		})
+		fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+			Cmd:    "ovs-vsctl --timeout=15 port-to-br patch-" + brphys + "_" + nodeName + "-to-br-int",
+			Output: brphys,
+		})
+		fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+			Cmd:    "ovs-vsctl --timeout=15 port-to-br " + uplinkPort,
+			Output: brphys,
+		})
 		// syncServices()
 
 		err := util.SetExec(fexec)
@@ -908,6 +930,9 @@ func localGatewayInterfaceTest(app *cli.App, testNS ns.NetNS,
 			Cmd:    "ovs-vsctl --timeout=15 --if-exists get Open_vSwitch . other_config:hw-offload",
 			Output: "false",
 		})
+		fexec.AddFakeCmdsNoOutputNoError([]string{
+			"ovs-vsctl --timeout=15 set bridge breth0 external_ids:ovn-k8s-gateway-config=\"mode=local,disable-snat-multiple-gws=false,allow-no-uplink=false,disable-forwarding=true\"",
+		})
 		fexec.AddFakeCmd(&ovntest.ExpectedCmd{
 			Cmd:    "ovs-vsctl --timeout=15 get Interface patch-breth0_node1-to-br-int ofport",
 			Output: "5",
@@ -971,6 +996,14 @@ OFPT_GET_CONFIG_REPLY (xid=0x4): frags=normal miss_send_len=0`,
 			Cmd:    "ovs-vsctl --timeout=15 --if-exists get interface eth0 ofport",
 			Output: "7",
 		})
+		fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+			Cmd:    "ovs-vsctl --timeout=15 port-to-br patch-breth0_" + nodeName + "-to-br-int",
+			Output: "breth0",
+		})
+		fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+			Cmd:    "ovs-vsctl --timeout=15 port-to-br eth0",
+			Output: "breth0",
+		})
 		// syncServices()
 
 		err := util.SetExec(fexec)
@@ -1877,4 +1910,229 @@ var _ = Describe("Gateway unit tests", func() {
 			})
 		})
 	})
+
+	Context("setBridgeOfPorts", func() {
+		var fexec *ovntest.FakeExec
+		var bridge *bridgeConfiguration
+
+		BeforeEach(func() {
+			fexec = ovntest.NewFakeExec()
+			Expect(util.SetExec(fexec)).To(Succeed())
+			bridge = &bridgeConfiguration{
+				bridgeName: "breth0",
+				uplinkName: "bond0",
+				patchPort:  "patch-breth0_node1-to-br-int",
+			}
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 get Interface patch-breth0_node1-to-br-int ofport",
+				Output: "5",
+			})
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 get interface bond0 ofport",
+				Stderr: "ovs-vsctl: no row \"bond0\" in table Interface",
+				Err:    fmt.Errorf("exit status 1"),
+			})
+		})
+
+		It("resolves the ofport of a bond uplink", func() {
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 --if-exists get Port bond0 interfaces",
+				Output: "[1e2a0f3c-4b5d-4e6f-8a9b-0c1d2e3f4a5b, 6f7a8b9c-0d1e-4f2a-8b3c-4d5e6f7a8b9c]",
+			})
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd: "ovs-vsctl --timeout=15 --no-headings --data=bare --format=csv --columns=name,ofport,link_state " +
+					"list Interface 1e2a0f3c-4b5d-4e6f-8a9b-0c1d2e3f4a5b 6f7a8b9c-0d1e-4f2a-8b3c-4d5e6f7a8b9c",
+				Output: "eth1,8,up\neth0,7,down",
+			})
+
+			Expect(setBridgeOfPorts(bridge)).To(Succeed())
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+			Expect(bridge.ofPortPatch).To(Equal("5"))
+			// the member with the lowest ofport is used regardless of its link state
+			Expect(bridge.ofPortPhys).To(Equal("7"))
+			Expect(bridge.ofPortHost).To(Equal(ovsLocalPort))
+		})
+
+		It("fails if no bond member has its link up", func() {
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 --if-exists get Port bond0 interfaces",
+				Output: "[1e2a0f3c-4b5d-4e6f-8a9b-0c1d2e3f4a5b, 6f7a8b9c-0d1e-4f2a-8b3c-4d5e6f7a8b9c]",
+			})
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd: "ovs-vsctl --timeout=15 --no-headings --data=bare --format=csv --columns=name,ofport,link_state " +
+					"list Interface 1e2a0f3c-4b5d-4e6f-8a9b-0c1d2e3f4a5b 6f7a8b9c-0d1e-4f2a-8b3c-4d5e6f7a8b9c",
+				Output: "eth1,8,down\neth0,7,down",
+			})
+
+			err := setBridgeOfPorts(bridge)
+			Expect(err).To(MatchError(ContainSubstring("no member of bond bond0 has its link up")))
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+		})
+
+		It("fails if the uplink is neither an interface nor a bond", func() {
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 --if-exists get Port bond0 interfaces",
+				Output: "",
+			})
+
+			err := setBridgeOfPorts(bridge)
+			Expect(err).To(MatchError(ContainSubstring("failed to get ofport of bond0")))
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+		})
+	})
+
+	Context("setBridgeOfPorts in DPU mode", func() {
+		var fexec *ovntest.FakeExec
+		var bridge *bridgeConfiguration
+
+		BeforeEach(func() {
+			config.OvnKubeNode.Mode = types.NodeModeDPU
+			fexec = ovntest.NewFakeExec()
+			Expect(util.SetExec(fexec)).To(Succeed())
+			bridge = &bridgeConfiguration{
+				bridgeName: "breth0",
+				uplinkName: "eth0",
+				patchPort:  "patch-breth0_node1-to-br-int",
+			}
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 get Interface patch-breth0_node1-to-br-int ofport",
+				Output: "5",
+			})
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 get interface eth0 ofport",
+				Output: "7",
+			})
+		})
+
+		It("uses the ofport of the configured host representor", func() {
+			config.OvnKubeNode.DPUHostRepresentor = "pf1hpf"
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 port-to-br pf1hpf",
+				Output: "breth0",
+			})
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 get interface pf1hpf ofport",
+				Output: "9",
+			})
+
+			Expect(setBridgeOfPorts(bridge)).To(Succeed())
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+			Expect(bridge.ofPortPatch).To(Equal("5"))
+			Expect(bridge.ofPortPhys).To(Equal("7"))
+			Expect(bridge.ofPortHost).To(Equal("9"))
+		})
+
+		It("marks the host representor unavailable if it has no valid ofport", func() {
+			config.OvnKubeNode.DPUHostRepresentor = "pf1hpf"
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 port-to-br pf1hpf",
+				Output: "breth0",
+			})
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 get interface pf1hpf ofport",
+				Output: "-1",
+			})
+
+			Expect(setBridgeOfPorts(bridge)).To(Succeed())
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+			Expect(bridge.ofPortHost).To(Equal(ofPortHostUnavailable))
+			Expect(bridge.hostRepresentor).To(Equal("pf1hpf"))
+		})
+
+		It("fails if the configured host representor is not a port of the bridge", func() {
+			config.OvnKubeNode.DPUHostRepresentor = "pf1hpf"
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 port-to-br pf1hpf",
+				Output: "breth1",
+			})
+
+			err := setBridgeOfPorts(bridge)
+			Expect(err).To(MatchError(ContainSubstring("host representor pf1hpf is a port of bridge breth1 instead of bridge breth0")))
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+		})
+	})
+
+	Context("validateBridgePorts", func() {
+		var fexec *ovntest.FakeExec
+		var bridge *bridgeConfiguration
+
+		BeforeEach(func() {
+			fexec = ovntest.NewFakeExec()
+			Expect(util.SetExec(fexec)).To(Succeed())
+			bridge = &bridgeConfiguration{
+				bridgeName: "breth0",
+				uplinkName: "eth0",
+				patchPort:  "patch-breth0_node1-to-br-int",
+			}
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 port-to-br patch-breth0_node1-to-br-int",
+				Output: "breth0",
+			})
+		})
+
+		It("accepts the ports of the gateway bridge", func() {
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 port-to-br eth0",
+				Output: "breth0",
+			})
+
+			Expect(validateBridgePorts(bridge)).To(Succeed())
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+		})
+
+		It("fails if the uplink is a port of another bridge", func() {
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 port-to-br eth0",
+				Output: "breth1",
+			})
+
+			err := validateBridgePorts(bridge)
+			Expect(err).To(MatchError("port eth0 is a port of bridge breth1 instead of gateway bridge breth0"))
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+		})
+
+		It("fails if the uplink is not a port of any bridge", func() {
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    "ovs-vsctl --timeout=15 port-to-br eth0",
+				Stderr: "ovs-vsctl: no port named eth0",
+				Err:    fmt.Errorf("exit status 1"),
+			})
+
+			err := validateBridgePorts(bridge)
+			Expect(err).To(MatchError(ContainSubstring("failed to get the bridge of port eth0")))
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+		})
+	})
+
+	Context("setGatewayConfigExternalID", func() {
+		var fexec *ovntest.FakeExec
+
+		BeforeEach(func() {
+			fexec = ovntest.NewFakeExec()
+			Expect(util.SetExec(fexec)).To(Succeed())
+		})
+
+		It("records the gateway mode and options on the bridge", func() {
+			config.Gateway.Mode = config.GatewayModeShared
+			fexec.AddFakeCmdsNoOutputNoError([]string{
+				"ovs-vsctl --timeout=15 set bridge breth0 external_ids:ovn-k8s-gateway-config=\"mode=shared,disable-snat-multiple-gws=false,allow-no-uplink=false,disable-forwarding=false\"",
+			})
+
+			Expect(setGatewayConfigExternalID("breth0")).To(Succeed())
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+		})
+
+		It("replaces the gateway mode and options of a previous run", func() {
+			config.Gateway.Mode = config.GatewayModeLocal
+			config.Gateway.DisableSNATMultipleGWs = true
+			config.Gateway.AllowNoUplink = true
+			config.Gateway.DisableForwarding = true
+			fexec.AddFakeCmdsNoOutputNoError([]string{
+				"ovs-vsctl --timeout=15 set bridge breth0 external_ids:ovn-k8s-gateway-config=\"mode=local,disable-snat-multiple-gws=true,allow-no-uplink=true,disable-forwarding=true\"",
+			})
+
+			Expect(setGatewayConfigExternalID("breth0")).To(Succeed())
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+		})
+	})
 })
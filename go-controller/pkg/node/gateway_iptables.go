@@ -6,6 +6,7 @@ package node
 import (
 	"fmt"
 	"net"
+	"strconv"
 
 	"github.com/coreos/go-iptables/iptables"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
@@ -15,6 +16,7 @@ import (
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 	kapi "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	utilnet "k8s.io/utils/net"
 )
@@ -24,6 +26,11 @@ const (
 	iptableExternalIPChain = "OVN-KUBE-EXTERNALIP" // called from nat-PREROUTING and nat-OUTPUT
 	iptableETPChain        = "OVN-KUBE-ETP"        // called from nat-PREROUTING only
 	iptableITPChain        = "OVN-KUBE-ITP"        // called from mangle-OUTPUT and nat-OUTPUT
+	iptableSvcMarkChain    = "OVN-KUBE-SVC-MARK"   // called from mangle-PREROUTING and mangle-OUTPUT, only set up if a service has a mark
+
+	// ovnServiceMarkAnnotation is the service annotation specifying a fwmark, in decimal or hex, that is set on
+	// the traffic towards the service VIPs so that host firewall rules can classify it.
+	ovnServiceMarkAnnotation = "k8s.ovn.org/service-mark"
 )
 
 func clusterIPTablesProtocols() []iptables.Protocol {
@@ -165,7 +172,7 @@ func getITPLocalIPTRules(svcPort kapi.ServicePort, clusterIP string, svcHasLocal
 			},
 		}
 	}
-	return []nodeipt.Rule{
+	rules := []nodeipt.Rule{
 		{
 			Table: "mangle",
 			Chain: iptableITPChain,
@@ -179,6 +186,72 @@ func getITPLocalIPTRules(svcPort kapi.ServicePort, clusterIP string, svcHasLocal
 			Protocol: getIPTablesProtocol(clusterIP),
 		},
 	}
+	return append(rules, getITPLocalExemptionIPTRules(svcPort, clusterIP)...)
+}
+
+// getITPLocalExemptionIPTRules returns the IPTable RETURN rules skipping the MARK rule of getITPLocalIPTRules
+// for the host traffic of the interfaces and subnets of Gateway.ITPLocalExemptions, which then takes the normal
+// path. The rules are inserted after, and so end up ahead of, the MARK rule.
+// `svcPort` corresponds to port details for this service as specified in the service object
+// `clusterIP` is clusterIP is the VIP of the service to match on
+func getITPLocalExemptionIPTRules(svcPort kapi.ServicePort, clusterIP string) []nodeipt.Rule {
+	var rules []nodeipt.Rule
+	protocol := getIPTablesProtocol(clusterIP)
+	interfaces, subnets := config.Gateway.GetITPLocalExemptions()
+	var exemptions [][]string
+	for _, ifName := range interfaces {
+		exemptions = append(exemptions, []string{"-o", ifName})
+	}
+	for _, subnet := range subnets {
+		if getIPTablesProtocol(subnet.IP.String()) == protocol {
+			exemptions = append(exemptions, []string{"-s", subnet.String()})
+		}
+	}
+	for _, exemption := range exemptions {
+		args := append([]string{"-p", string(svcPort.Protocol)}, exemption...)
+		args = append(args,
+			"-d", clusterIP,
+			"--dport", fmt.Sprintf("%d", svcPort.Port),
+			"-j", "RETURN",
+		)
+		rules = append(rules, nodeipt.Rule{
+			Table:    "mangle",
+			Chain:    iptableITPChain,
+			Args:     args,
+			Protocol: protocol,
+		})
+	}
+	return rules
+}
+
+// getITPLocalNodePortIPTRules returns the IPTable REDIRECT rule sending the host-originated traffic towards the
+// nodePort of a service with ITP=local and local host-networked endpoints straight to the targetPort on the host,
+// instead of DNAT-ing it to the clusterIP through OVN. It is in iptableNodePortChain, jumped from nat-OUTPUT, and
+// only matches local sources so that the external NodePort traffic is left to the ETP handling.
+// It fails if the targetPort is not a port number, like an unresolved named port, which would make an invalid rule.
+// `svcPort` corresponds to port details for this service as specified in the service object
+// `clusterIP` is only used to select the IP family of the rule
+func getITPLocalNodePortIPTRules(svcPort kapi.ServicePort, clusterIP string) ([]nodeipt.Rule, error) {
+	targetPort, err := getServiceTargetPort(&svcPort)
+	if err != nil {
+		return nil, err
+	}
+	return []nodeipt.Rule{
+		{
+			Table: "nat",
+			Chain: iptableNodePortChain,
+			Args: []string{
+				"-p", string(svcPort.Protocol),
+				"-m", "addrtype",
+				"--src-type", "LOCAL",
+				"--dst-type", "LOCAL",
+				"--dport", fmt.Sprintf("%d", svcPort.NodePort),
+				"-j", "REDIRECT",
+				"--to-port", fmt.Sprintf("%d", targetPort),
+			},
+			Protocol: getIPTablesProtocol(clusterIP),
+		},
+	}, nil
 }
 
 // getNodePortETPLocalIPTRules returns the IPTable REDIRECT or RETURN rules for a service of type nodePort if ETP=local
@@ -200,6 +273,112 @@ func getNodePortETPLocalIPTRules(svcPort kapi.ServicePort, targetIP string) []no
 	}
 }
 
+// getServiceMark returns the fwmark set in the ovnServiceMarkAnnotation of the service, in hex, or "" if the
+// service has no mark. The mark has to fit in 32 bits, can't be 0 and can't be any of the marks used by ovnkube.
+func getServiceMark(service *kapi.Service) (string, error) {
+	value, ok := service.Annotations[ovnServiceMarkAnnotation]
+	if !ok {
+		return "", nil
+	}
+	mark, err := strconv.ParseUint(value, 0, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s annotation %q: expected a 32 bit mark: %v", ovnServiceMarkAnnotation, value, err)
+	}
+	if mark == 0 {
+		return "", fmt.Errorf("invalid %s annotation %q: the mark must not be 0", ovnServiceMarkAnnotation, value)
+	}
+	for _, reserved := range []string{ovnkubeITPMark, ovnkubeExternalIPSrcMark, ovnKubeNodeSNATMark} {
+		if reservedMark, _ := strconv.ParseUint(reserved, 0, 32); mark == reservedMark {
+			return "", fmt.Errorf("invalid %s annotation %q: the mark is reserved by ovnkube", ovnServiceMarkAnnotation, value)
+		}
+	}
+	return fmt.Sprintf("0x%x", mark), nil
+}
+
+// getServiceMarkIPTRules returns the IPTable MARK rules for the traffic towards a service VIP
+// `svcPort` corresponds to port details for this service as specified in the service object
+// `vip` is the clusterIP, externalIP or LB ingress IP to match on. If empty the NodePort is matched instead,
+// with `ip` only used to select the IP family of the rule.
+// `mark` is the fwmark to set
+func getServiceMarkIPTRules(svcPort kapi.ServicePort, vip, ip, mark string) []nodeipt.Rule {
+	args := []string{"-p", string(svcPort.Protocol)}
+	if vip != "" {
+		args = append(args, "-d", vip, "--dport", fmt.Sprintf("%d", svcPort.Port))
+		ip = vip
+	} else {
+		args = append(args, "-m", "addrtype", "--dst-type", "LOCAL", "--dport", fmt.Sprintf("%d", svcPort.NodePort))
+	}
+	return []nodeipt.Rule{
+		{
+			Table:    "mangle",
+			Chain:    iptableSvcMarkChain,
+			Args:     append(args, "-j", "MARK", "--set-xmark", mark),
+			Protocol: getIPTablesProtocol(ip),
+		},
+	}
+}
+
+// getServiceMarkJumpRules returns the rules jumping to iptableSvcMarkChain for the IP families of the given rules,
+// if any of them is in that chain.
+func getServiceMarkJumpRules(rules []nodeipt.Rule) []nodeipt.Rule {
+	jumpRules := []nodeipt.Rule{}
+	protocols := map[iptables.Protocol]bool{}
+	for _, rule := range rules {
+		if rule.Table != "mangle" || rule.Chain != iptableSvcMarkChain || protocols[rule.Protocol] {
+			continue
+		}
+		protocols[rule.Protocol] = true
+		for _, chain := range []string{"PREROUTING", "OUTPUT"} {
+			jumpRules = append(jumpRules, nodeipt.Rule{
+				Table:    "mangle",
+				Chain:    chain,
+				Args:     []string{"-j", iptableSvcMarkChain},
+				Protocol: rule.Protocol,
+			})
+		}
+	}
+	return jumpRules
+}
+
+// recreateServiceMarkIPTRules recreates iptableSvcMarkChain with the given rules that are in that chain. The chain
+// is only set up if any service has a mark, otherwise only stale rules are flushed from it if it exists.
+func recreateServiceMarkIPTRules(keepIPTRules []nodeipt.Rule) error {
+	markRules := []nodeipt.Rule{}
+	for _, rule := range keepIPTRules {
+		if rule.Table == "mangle" && rule.Chain == iptableSvcMarkChain {
+			markRules = append(markRules, rule)
+		}
+	}
+	if len(markRules) > 0 {
+		if err := insertIptRules(getServiceMarkJumpRules(markRules)); err != nil {
+			return err
+		}
+		return recreateIPTRules("mangle", iptableSvcMarkChain, markRules)
+	}
+	var errors []error
+	for _, proto := range clusterIPTablesProtocols() {
+		ipt, err := util.GetIPTablesHelper(proto)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		chains, err := ipt.ListChains("mangle")
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		for _, chain := range chains {
+			if chain != iptableSvcMarkChain {
+				continue
+			}
+			if err = ipt.ClearChain("mangle", chain); err != nil {
+				errors = append(errors, fmt.Errorf("error clearing Chain: %s in Table: mangle, err: %v", chain, err))
+			}
+		}
+	}
+	return apierrors.NewAggregate(errors)
+}
+
 func computeProbability(n, i int) string {
 	return fmt.Sprintf("%0.10f", 1.0/float64(n-i+1))
 }
@@ -280,6 +459,44 @@ func getExternalIPTRules(svcPort kapi.ServicePort, externalIP, dstIP string, svc
 	}
 }
 
+// getExternalIPSourcePreservationIPTRules returns the IPTable rules routing the external traffic towards the
+// externalIP of an ETP=cluster service, steered to the host by the gateway bridge flows, into OVN via the
+// management port with its source IP, see Gateway.ExternalIPSourcePreservation. The traffic is marked before
+// being DNAT-ed to the clusterIP, so that the ovnkubeExternalIPSrcMark routing rule sends it to the management
+// port, and a RETURN rule in iptableMgmPortChain prevents its SNAT to the management port IP.
+// `svcPort` corresponds to port details for this service as specified in the service object
+// `externalIP` is the externalIP of the service to match on
+// `clusterIP` is the clusterIP of the same family the traffic is DNAT-ed to
+func getExternalIPSourcePreservationIPTRules(svcPort kapi.ServicePort, externalIP, clusterIP string) []nodeipt.Rule {
+	return []nodeipt.Rule{
+		{
+			Table: "mangle",
+			Chain: iptableSvcMarkChain,
+			Args: []string{
+				"-p", string(svcPort.Protocol),
+				"-m", "addrtype", "!", "--src-type", "LOCAL",
+				"-d", externalIP,
+				"--dport", fmt.Sprintf("%d", svcPort.Port),
+				"-j", "MARK",
+				"--set-xmark", ovnkubeExternalIPSrcMark,
+			},
+			Protocol: getIPTablesProtocol(externalIP),
+		},
+		{
+			Table: "nat",
+			Chain: iptableMgmPortChain,
+			Args: []string{
+				"-p", string(svcPort.Protocol),
+				"-d", clusterIP,
+				"--dport", fmt.Sprintf("%d", svcPort.Port),
+				"-m", "mark", "--mark", ovnkubeExternalIPSrcMark,
+				"-j", "RETURN",
+			},
+			Protocol: getIPTablesProtocol(clusterIP),
+		},
+	}
+}
+
 func getGatewayForwardRules(svcCIDR *net.IPNet) []nodeipt.Rule {
 	protocol := getIPTablesProtocol(svcCIDR.IP.String())
 	masqueradeIP := types.V4OVNMasqueradeIP
@@ -353,6 +570,49 @@ func getGatewayDropRules(ifName string) []nodeipt.Rule {
 	return dropRules
 }
 
+// getGatewayForwardingExemptionRules returns the iptables rules accepting the traffic forwarded through the
+// ifName bridge from and to the interfaces and subnets of Gateway.ForwardingBlockExemptions, ahead of the
+// rules of getGatewayDropRules
+func getGatewayForwardingExemptionRules(ifName string) []nodeipt.Rule {
+	var acceptRules []nodeipt.Rule
+	interfaces, subnets := config.Gateway.GetForwardingBlockExemptions()
+	for _, protocol := range clusterIPTablesProtocols() {
+		for _, exemptIfName := range interfaces {
+			acceptRules = append(acceptRules, []nodeipt.Rule{
+				{
+					Table:    "filter",
+					Chain:    "FORWARD",
+					Args:     []string{"-i", ifName, "-o", exemptIfName, "-j", "ACCEPT"},
+					Protocol: protocol,
+				},
+				{
+					Table:    "filter",
+					Chain:    "FORWARD",
+					Args:     []string{"-i", exemptIfName, "-o", ifName, "-j", "ACCEPT"},
+					Protocol: protocol,
+				},
+			}...)
+		}
+	}
+	for _, subnet := range subnets {
+		protocol := getIPTablesProtocol(subnet.IP.String())
+		if (protocol == iptables.ProtocolIPv4 && !config.IPv4Mode) || (protocol == iptables.ProtocolIPv6 && !config.IPv6Mode) {
+			continue
+		}
+		for _, dir := range []string{"-i", "-o"} {
+			for _, addr := range []string{"-s", "-d"} {
+				acceptRules = append(acceptRules, nodeipt.Rule{
+					Table:    "filter",
+					Chain:    "FORWARD",
+					Args:     []string{dir, ifName, addr, subnet.String(), "-j", "ACCEPT"},
+					Protocol: protocol,
+				})
+			}
+		}
+	}
+	return acceptRules
+}
+
 // initExternalBridgeForwardingRules sets up iptables rules for br-* interface svc traffic forwarding
 // -A FORWARD -s 10.96.0.0/16 -j ACCEPT
 // -A FORWARD -d 10.96.0.0/16 -j ACCEPT
@@ -366,7 +626,14 @@ func initExternalBridgeServiceForwardingRules(cidr *net.IPNet) error {
 // in br-* interfaces (also for 2ndary bridge) - we block for v4 and v6 based on clusterStack
 // -A FORWARD -i breth1 -j DROP
 // -A FORWARD -o breth1 -j DROP
+// The traffic of the forwarding block exemptions is accepted ahead of them, e.g. for interface eth2:
+// -I FORWARD -i breth1 -o eth2 -j ACCEPT
+// -I FORWARD -i eth2 -o breth1 -j ACCEPT
 func initExternalBridgeDropForwardingRules(ifName string) error {
+	// the exemptions are inserted so that they also precede the drop rules appended on a previous run
+	if err := insertIptRules(getGatewayForwardingExemptionRules(ifName)); err != nil {
+		return err
+	}
 	return appendIptRules(getGatewayDropRules(ifName))
 }
 
@@ -517,12 +784,29 @@ func recreateIPTRules(table, chain string, keepIPTRules []nodeipt.Rule) error {
 //
 // case3: if svcHasLocalHostNetEndPnt and svcTypeIsITPLocal, rule that redirects clusterIP traffic to host targetPort is added.
 //
+//	if the service also has a NodePort, a rule that redirects the host-originated nodePort traffic to the host
+//	targetPort is added. Listed after, and so inserted ahead of, the case2 DNAT rule of the nodePort.
 //	if !svcHasLocalHostNetEndPnt and svcTypeIsITPLocal, rule that marks clusterIP traffic to steer it to ovn-k8s-mp0 is added.
+//
+// case4: if the service has a mark annotation, rules that set that mark on the traffic towards all the service VIPs
+// are added to the mangle table.
+//
+// case5: if Gateway.ExternalIPSourcePreservation is set and the service is ETP=cluster, rules that mark the external
+// traffic towards its externalIPs to route it into OVN via ovn-k8s-mp0, and skip its SNAT there, are added. The mark
+// annotation of the service is not applied to the traffic towards these externalIPs.
 func getGatewayIPTRules(service *kapi.Service, localEndpoints []string, svcHasLocalHostNetEndPnt bool) []nodeipt.Rule {
 	rules := make([]nodeipt.Rule, 0)
 	clusterIPs := util.GetClusterIPs(service)
-	svcTypeIsETPLocal := util.ServiceExternalTrafficPolicyLocal(service)
+	svcTypeIsETPLocal := serviceExternalTrafficPolicyLocal(service)
 	svcTypeIsITPLocal := util.ServiceInternalTrafficPolicyLocal(service)
+	svcMark, err := getServiceMark(service)
+	if err != nil {
+		klog.Errorf("Skipping mark rules of service %s/%s: %v", service.Namespace, service.Name, err)
+	}
+	preservedExternalIPs := sets.New[string]()
+	if isExternalIPSourcePreserved(service) {
+		preservedExternalIPs.Insert(service.Spec.ExternalIPs...)
+	}
 	for _, svcPort := range service.Spec.Ports {
 		if util.ServiceTypeHasNodePort(service) {
 			err := util.ValidatePort(svcPort.Protocol, svcPort.NodePort)
@@ -573,10 +857,46 @@ func getGatewayIPTRules(service *kapi.Service, localEndpoints []string, svcHasLo
 				rules = append(rules, getExternalIPTRules(svcPort, externalIP, clusterIP, svcHasLocalHostNetEndPnt, false)...)
 			}
 		}
+		if preservedExternalIPs.Len() > 0 && util.ValidatePort(svcPort.Protocol, svcPort.Port) == nil {
+			// case5 (see function description for details)
+			for _, externalIP := range service.Spec.ExternalIPs {
+				if clusterIP, err := util.MatchIPStringFamily(utilnet.IsIPv6String(externalIP), clusterIPs); err == nil {
+					rules = append(rules, getExternalIPSourcePreservationIPTRules(svcPort, externalIP, clusterIP)...)
+				}
+			}
+		}
 		if svcTypeIsITPLocal {
 			// case3 (see function decription for details)
 			for _, clusterIP := range clusterIPs {
 				rules = append(rules, getITPLocalIPTRules(svcPort, clusterIP, svcHasLocalHostNetEndPnt)...)
+				if svcHasLocalHostNetEndPnt && util.ServiceTypeHasNodePort(service) &&
+					util.ValidatePort(svcPort.Protocol, svcPort.NodePort) == nil {
+					nodePortRules, err := getITPLocalNodePortIPTRules(svcPort, clusterIP)
+					if err != nil {
+						klog.Errorf("Skipping the ITP=local nodePort redirect rule of service %s/%s: %v",
+							service.Namespace, service.Name, err)
+						continue
+					}
+					rules = append(rules, nodePortRules...)
+				}
+			}
+		}
+		if svcMark != "" && util.ValidatePort(svcPort.Protocol, svcPort.Port) == nil {
+			// case4 (see function decription for details)
+			for _, clusterIP := range clusterIPs {
+				rules = append(rules, getServiceMarkIPTRules(svcPort, clusterIP, clusterIP, svcMark)...)
+				if util.ServiceTypeHasNodePort(service) && util.ValidatePort(svcPort.Protocol, svcPort.NodePort) == nil {
+					rules = append(rules, getServiceMarkIPTRules(svcPort, "", clusterIP, svcMark)...)
+				}
+			}
+			for _, externalIP := range externalIPs {
+				if preservedExternalIPs.Has(externalIP) {
+					// the traffic must keep the mark routing it via ovn-k8s-mp0, see case5
+					continue
+				}
+				if _, err := util.MatchIPStringFamily(utilnet.IsIPv6String(externalIP), clusterIPs); err == nil {
+					rules = append(rules, getServiceMarkIPTRules(svcPort, externalIP, externalIP, svcMark)...)
+				}
 			}
 		}
 	}
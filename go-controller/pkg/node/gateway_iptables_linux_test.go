@@ -0,0 +1,355 @@
+//go:build linux
+// +build linux
+
+package node
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+	kapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// renderServiceIPTRules returns the iptables rules of a NodePort service with an externalIP for the given traffic
+// policies and local host-networked endpoints, rendered as the commands appending them and sorted.
+func renderServiceIPTRules(etpLocal, itpLocal, svcHasLocalHostNetEndPnt bool) []string {
+	ports := []kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)}}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{}, etpLocal, itpLocal)
+	rendered := []string{}
+	for _, rule := range getGatewayIPTRules(service, nil, svcHasLocalHostNetEndPnt) {
+		rendered = append(rendered, rule.String())
+	}
+	sort.Strings(rendered)
+	return rendered
+}
+
+// TestGetGatewayIPTRulesMatrix covers the traffic policy and local host-networked endpoint matrix documented in
+// delServiceRules, the rules deleted there being the ones rendered here.
+func TestGetGatewayIPTRulesMatrix(t *testing.T) {
+	const (
+		nodePortDNAT    = "iptables -t nat -A OVN-KUBE-NODEPORT -p TCP -m addrtype --dst-type LOCAL --dport 31111 -j DNAT --to-destination 10.96.0.10:80"
+		externalIPDNAT  = "iptables -t nat -A OVN-KUBE-EXTERNALIP -p TCP -d 1.1.1.1 --dport 80 -j DNAT --to-destination 10.96.0.10:80"
+		itpMark         = "iptables -t mangle -A OVN-KUBE-ITP -p TCP -d 10.96.0.10 --dport 80 -j MARK --set-xmark 0x1745ec"
+		itpRedirect     = "iptables -t nat -A OVN-KUBE-ITP -p TCP -d 10.96.0.10 --dport 80 -j REDIRECT --to-port 8080"
+		itpNodePort     = "iptables -t nat -A OVN-KUBE-NODEPORT -p TCP -m addrtype --src-type LOCAL --dst-type LOCAL --dport 31111 -j REDIRECT --to-port 8080"
+		etpSkipSNAT     = "iptables -t nat -A OVN-KUBE-SNAT-MGMTPORT -p TCP --dport 31111 -j RETURN"
+		etpNodePortDNAT = "iptables -t nat -A OVN-KUBE-ETP -p TCP -m addrtype --dst-type LOCAL --dport 31111 -j DNAT --to-destination 169.254.169.3:31111"
+		etpExternalDNAT = "iptables -t nat -A OVN-KUBE-ETP -p TCP -d 1.1.1.1 --dport 80 -j DNAT --to-destination 169.254.169.3:31111"
+	)
+	tests := []struct {
+		desc                     string
+		gatewayMode              config.GatewayMode
+		etpLocal                 bool
+		itpLocal                 bool
+		svcHasLocalHostNetEndPnt bool
+		want                     []string
+	}{
+		{
+			desc:        "ETP=cluster ITP=cluster without local host-networked endpoints",
+			gatewayMode: config.GatewayModeShared,
+			want:        []string{nodePortDNAT, externalIPDNAT},
+		},
+		{
+			desc:                     "ETP=cluster ITP=cluster with local host-networked endpoints",
+			gatewayMode:              config.GatewayModeShared,
+			svcHasLocalHostNetEndPnt: true,
+			want:                     []string{nodePortDNAT, externalIPDNAT},
+		},
+		{
+			desc:        "ETP=cluster ITP=local without local host-networked endpoints",
+			gatewayMode: config.GatewayModeShared,
+			itpLocal:    true,
+			want:        []string{nodePortDNAT, externalIPDNAT, itpMark},
+		},
+		{
+			desc:                     "ETP=cluster ITP=local with local host-networked endpoints",
+			gatewayMode:              config.GatewayModeShared,
+			itpLocal:                 true,
+			svcHasLocalHostNetEndPnt: true,
+			want:                     []string{nodePortDNAT, externalIPDNAT, itpRedirect, itpNodePort},
+		},
+		{
+			desc:        "ETP=local ITP=cluster without local host-networked endpoints, SGW",
+			gatewayMode: config.GatewayModeShared,
+			etpLocal:    true,
+			want:        []string{nodePortDNAT, externalIPDNAT, etpSkipSNAT, etpExternalDNAT},
+		},
+		{
+			desc:        "ETP=local ITP=cluster without local host-networked endpoints, LGW",
+			gatewayMode: config.GatewayModeLocal,
+			etpLocal:    true,
+			want:        []string{nodePortDNAT, externalIPDNAT, etpSkipSNAT, etpNodePortDNAT, etpExternalDNAT},
+		},
+		{
+			desc:        "ETP=local ITP=local without local host-networked endpoints, LGW",
+			gatewayMode: config.GatewayModeLocal,
+			etpLocal:    true,
+			itpLocal:    true,
+			want:        []string{nodePortDNAT, externalIPDNAT, etpSkipSNAT, etpNodePortDNAT, etpExternalDNAT, itpMark},
+		},
+		{
+			desc:                     "ETP=local ITP=local with local host-networked endpoints",
+			gatewayMode:              config.GatewayModeShared,
+			etpLocal:                 true,
+			itpLocal:                 true,
+			svcHasLocalHostNetEndPnt: true,
+			want:                     []string{nodePortDNAT, externalIPDNAT, itpRedirect, itpNodePort},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.IPv4Mode = true
+			config.Gateway.Mode = tt.gatewayMode
+
+			want := append([]string{}, tt.want...)
+			sort.Strings(want)
+			if got := renderServiceIPTRules(tt.etpLocal, tt.itpLocal, tt.svcHasLocalHostNetEndPnt); !reflect.DeepEqual(got, want) {
+				t.Errorf("getGatewayIPTRules() got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		})
+	}
+}
+
+func TestITPLocalNodePortIPTRulesNamedTargetPort(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.IPv4Mode = true
+
+	ports := []kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromString("http")}}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, false, true)
+
+	// the unresolved named port makes no REDIRECT rule rather than one to port 0
+	if _, err := getITPLocalNodePortIPTRules(ports[0], "10.96.0.10"); err == nil {
+		t.Error("expected an error for the named target port")
+	}
+	for _, rule := range getGatewayIPTRules(service, nil, true) {
+		if rule.Chain == iptableNodePortChain && strings.Contains(rule.String(), "REDIRECT") {
+			t.Errorf("unexpected nodePort REDIRECT rule for a named target port: %s", rule.String())
+		}
+	}
+}
+
+func TestForwardingBlockExemptions(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.IPv4Mode = true
+	config.IPv6Mode = false
+	config.Gateway.DisableForwarding = true
+	iptV4, _ := util.SetFakeIPTablesHelpers()
+	// the drop rules of a previous run without exemptions
+	if err := initExternalBridgeDropForwardingRules("breth0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the IPv6 subnet is ignored in a single stack IPv4 cluster
+	config.Gateway.ForwardingBlockExemptions = "eth2, 192.168.100.0/24, fd00::/64"
+	if err := initExternalBridgeDropForwardingRules("breth0"); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := iptV4.List("filter", "FORWARD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(rules[:len(rules)-2])
+	expected := []string{
+		"-i breth0 -d 192.168.100.0/24 -j ACCEPT",
+		"-i breth0 -o eth2 -j ACCEPT",
+		"-i breth0 -s 192.168.100.0/24 -j ACCEPT",
+		"-i eth2 -o breth0 -j ACCEPT",
+		"-o breth0 -d 192.168.100.0/24 -j ACCEPT",
+		"-o breth0 -s 192.168.100.0/24 -j ACCEPT",
+		// the exempted traffic is accepted ahead of the drop rules
+		"-i breth0 -j DROP",
+		"-o breth0 -j DROP",
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected FORWARD rules:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(rules, "\n"))
+	}
+}
+
+func TestITPLocalExemptions(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.IPv4Mode = true
+	config.IPv6Mode = true
+	iptV4, iptV6 := util.SetFakeIPTablesHelpers()
+
+	ports := []kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP, TargetPort: intstr.FromInt(8080)}}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeClusterIP,
+		nil, kapi.ServiceStatus{}, false, true)
+	service.Spec.ClusterIPs = []string{"10.96.0.10", "fd00:10:96::10"}
+
+	// no exemptions by default
+	rendered := []string{}
+	for _, rule := range getGatewayIPTRules(service, nil, false) {
+		rendered = append(rendered, rule.String())
+	}
+	expected := []string{
+		"iptables -t mangle -A OVN-KUBE-ITP -p TCP -d 10.96.0.10 --dport 80 -j MARK --set-xmark 0x1745ec",
+		"ip6tables -t mangle -A OVN-KUBE-ITP -p TCP -d fd00:10:96::10 --dport 80 -j MARK --set-xmark 0x1745ec",
+	}
+	if !reflect.DeepEqual(rendered, expected) {
+		t.Errorf("expected ITP rules:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(rendered, "\n"))
+	}
+
+	// the exempt host traffic returns ahead of the MARK rule of each family
+	config.Gateway.ITPLocalExemptions = "mon0, 10.0.0.5, fd00::/64"
+	if err := insertIptRules(getGatewayIPTRules(service, nil, false)); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		ipt      util.IPTablesHelper
+		expected []string
+	}{
+		{
+			ipt: iptV4,
+			expected: []string{
+				"-p TCP -s 10.0.0.5/32 -d 10.96.0.10 --dport 80 -j RETURN",
+				"-p TCP -o mon0 -d 10.96.0.10 --dport 80 -j RETURN",
+				"-p TCP -d 10.96.0.10 --dport 80 -j MARK --set-xmark 0x1745ec",
+			},
+		},
+		{
+			ipt: iptV6,
+			expected: []string{
+				"-p TCP -s fd00::/64 -d fd00:10:96::10 --dport 80 -j RETURN",
+				"-p TCP -o mon0 -d fd00:10:96::10 --dport 80 -j RETURN",
+				"-p TCP -d fd00:10:96::10 --dport 80 -j MARK --set-xmark 0x1745ec",
+			},
+		},
+	} {
+		rules, err := tt.ipt.List("mangle", iptableITPChain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(rules, tt.expected) {
+			t.Errorf("expected ITP rules:\n%s\ngot:\n%s", strings.Join(tt.expected, "\n"), strings.Join(rules, "\n"))
+		}
+	}
+}
+
+func TestExternalIPSourcePreservationIPTRules(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+
+	ports := []kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)}}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{}, false, false)
+	service.Annotations = map[string]string{ovnServiceMarkAnnotation: "0x10"}
+	etpLocalService := newService("service2", "namespace1", "10.96.0.11", ports, kapi.ServiceTypeNodePort,
+		[]string{"2.2.2.2"}, kapi.ServiceStatus{}, true, false)
+
+	// renders the mangle and management port rules of the service
+	render := func(service *kapi.Service) []string {
+		rendered := []string{}
+		for _, rule := range getGatewayIPTRules(service, nil, false) {
+			if rule.Table == "mangle" || rule.Chain == iptableMgmPortChain {
+				rendered = append(rendered, rule.String())
+			}
+		}
+		return rendered
+	}
+
+	// the service mark is set on the externalIP traffic by default
+	expected := []string{
+		"iptables -t mangle -A OVN-KUBE-SVC-MARK -p TCP -d 10.96.0.10 --dport 80 -j MARK --set-xmark 0x10",
+		"iptables -t mangle -A OVN-KUBE-SVC-MARK -p TCP -m addrtype --dst-type LOCAL --dport 31111 -j MARK --set-xmark 0x10",
+		"iptables -t mangle -A OVN-KUBE-SVC-MARK -p TCP -d 1.1.1.1 --dport 80 -j MARK --set-xmark 0x10",
+	}
+	if rendered := render(service); !reflect.DeepEqual(rendered, expected) {
+		t.Errorf("expected rules:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(rendered, "\n"))
+	}
+
+	// the external traffic towards the externalIP is marked to be routed via the management port, and not SNAT-ed there
+	config.Gateway.ExternalIPSourcePreservation = true
+	expected = []string{
+		"iptables -t mangle -A OVN-KUBE-SVC-MARK -p TCP -m addrtype ! --src-type LOCAL -d 1.1.1.1 --dport 80 -j MARK --set-xmark 0x1745ed",
+		"iptables -t nat -A OVN-KUBE-SNAT-MGMTPORT -p TCP -d 10.96.0.10 --dport 80 -m mark --mark 0x1745ed -j RETURN",
+		"iptables -t mangle -A OVN-KUBE-SVC-MARK -p TCP -d 10.96.0.10 --dport 80 -j MARK --set-xmark 0x10",
+		"iptables -t mangle -A OVN-KUBE-SVC-MARK -p TCP -m addrtype --dst-type LOCAL --dport 31111 -j MARK --set-xmark 0x10",
+	}
+	if rendered := render(service); !reflect.DeepEqual(rendered, expected) {
+		t.Errorf("expected rules:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(rendered, "\n"))
+	}
+
+	// the ETP=local services already preserve the source IP
+	expected = []string{
+		"iptables -t nat -A OVN-KUBE-SNAT-MGMTPORT -p TCP --dport 31111 -j RETURN",
+	}
+	if rendered := render(etpLocalService); !reflect.DeepEqual(rendered, expected) {
+		t.Errorf("expected rules:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(rendered, "\n"))
+	}
+
+	// and the source IP is only preserved in shared gateway mode
+	config.Gateway.Mode = config.GatewayModeLocal
+	for _, rule := range render(service) {
+		if strings.Contains(rule, ovnkubeExternalIPSrcMark) {
+			t.Errorf("unexpected rule in local gateway mode: %s", rule)
+		}
+	}
+}
+
+// TestGetGatewayIPTRulesDualStackITPLocal checks that the ITP=local rules of a dual-stack service are rendered for
+// both of its ClusterIPs, each in the iptables of its family.
+func TestGetGatewayIPTRulesDualStackITPLocal(t *testing.T) {
+	tests := []struct {
+		desc                     string
+		svcHasLocalHostNetEndPnt bool
+		want                     []string
+	}{
+		{
+			desc: "traffic marked to be routed via the management port",
+			want: []string{
+				"iptables -t mangle -A OVN-KUBE-ITP -p TCP -d 10.96.0.10 --dport 80 -j MARK --set-xmark 0x1745ec",
+				"ip6tables -t mangle -A OVN-KUBE-ITP -p TCP -d fd00:10:96::10 --dport 80 -j MARK --set-xmark 0x1745ec",
+			},
+		},
+		{
+			desc:                     "traffic redirected to the local host-networked endpoints",
+			svcHasLocalHostNetEndPnt: true,
+			want: []string{
+				"iptables -t nat -A OVN-KUBE-ITP -p TCP -d 10.96.0.10 --dport 80 -j REDIRECT --to-port 8080",
+				"ip6tables -t nat -A OVN-KUBE-ITP -p TCP -d fd00:10:96::10 --dport 80 -j REDIRECT --to-port 8080",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.IPv4Mode = true
+			config.IPv6Mode = true
+
+			ports := []kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP, TargetPort: intstr.FromInt(8080)}}
+			service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeClusterIP,
+				nil, kapi.ServiceStatus{}, false, true)
+			service.Spec.ClusterIPs = []string{"10.96.0.10", "fd00:10:96::10"}
+			got := []string{}
+			for _, rule := range getGatewayIPTRules(service, nil, tt.svcHasLocalHostNetEndPnt) {
+				if rule.Chain == iptableITPChain {
+					got = append(got, rule.String())
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getGatewayIPTRules() got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
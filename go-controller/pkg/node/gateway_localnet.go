@@ -47,23 +47,12 @@ func newLocalGateway(nodeName string, hostSubnets []*net.IPNet, gwNextHops []net
 	if err != nil {
 		return nil, err
 	}
+	if err := setGatewayConfigExternalID(gwBridge.bridgeName); err != nil {
+		return nil, err
+	}
 
-	if exGwBridge != nil {
-		gw.readyFunc = func() (bool, error) {
-			ready, err := gatewayReady(gwBridge.patchPort)
-			if err != nil {
-				return false, err
-			}
-			exGWReady, err := gatewayReady(exGwBridge.patchPort)
-			if err != nil {
-				return false, err
-			}
-			return ready && exGWReady, nil
-		}
-	} else {
-		gw.readyFunc = func() (bool, error) {
-			return gatewayReady(gwBridge.patchPort)
-		}
+	gw.readyFunc = func() (bool, string, error) {
+		return gatewayBridgesReady(gwBridge, exGwBridge)
 	}
 
 	gw.initFunc = func() error {
@@ -89,39 +78,38 @@ func newLocalGateway(nodeName string, hostSubnets []*net.IPNet, gwNextHops []net
 		if err := setNodeMasqueradeIPOnExtBridge(gwBridge.bridgeName); err != nil {
 			return fmt.Errorf("failed to set the node masquerade IP on the ext bridge %s: %v", gwBridge.bridgeName, err)
 		}
+		gw.masqueradeIPBridge = gwBridge.bridgeName
 
 		if err := addMasqueradeRoute(routeManager, gwBridge.bridgeName, nodeName, gwIPs, watchFactory); err != nil {
 			return fmt.Errorf("failed to set the node masquerade route to OVN: %v", err)
 		}
 
+		gw.subnets = hostSubnets
 		gw.openflowManager, err = newGatewayOpenFlowManager(gwBridge, exGwBridge, hostSubnets, gw.nodeIPManager.ListAddresses())
 		if err != nil {
 			return err
 		}
 		// resync flows on IP change
-		gw.nodeIPManager.OnChanged = func() {
-			klog.V(5).Info("Node addresses changed, re-syncing bridge flows")
-			if err := gw.openflowManager.updateBridgeFlowCache(hostSubnets, gw.nodeIPManager.ListAddresses()); err != nil {
-				// very unlikely - somehow node has lost its IP address
-				klog.Errorf("Failed to re-generate gateway flows after address change: %v", err)
-			}
-			// update gateway IPs for service openflows programmed by nodePortWatcher interface
-			npw, _ := gw.nodePortWatcher.(*nodePortWatcher)
-			npw.updateGatewayIPs(gw.nodeIPManager)
-			gw.openflowManager.requestFlowSync()
-		}
+		gw.nodeIPManager.OnChanged = gw.onNodeIPsChanged
 
 		if config.Gateway.NodeportEnable {
 			if config.OvnKubeNode.Mode == types.NodeModeFull {
 				// (TODO): Internal Traffic Policy is not supported in DPU mode
-				if err := initSvcViaMgmPortRoutingRules(hostSubnets); err != nil {
+				if err := initSvcViaMgmPortRoutingRules(hostSubnets, types.K8sMgmtIntfName); err != nil {
 					return err
 				}
 			}
-			gw.nodePortWatcher, err = newNodePortWatcher(gwBridge, gw.openflowManager, gw.nodeIPManager, watchFactory)
+			npw, err := newNodePortWatcher(gwBridge, gw.openflowManager, gw.nodeIPManager, watchFactory)
 			if err != nil {
 				return err
 			}
+			if config.OvnKubeNode.Mode == types.NodeModeFull && config.Gateway.ITPLocalMgmtPortRoutes {
+				npw.svcViaMgmPortRoutes = newSvcViaMgmPortRoutes(hostSubnets, types.K8sMgmtIntfName)
+			}
+			if gw.recorder != nil {
+				npw.flowErrorReporter = newServiceFlowErrorReporter(gw.recorder)
+			}
+			gw.nodePortWatcher = npw
 		} else {
 			// no service OpenFlows, request to sync flows now.
 			gw.openflowManager.requestFlowSync()
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
+	"github.com/coreos/go-iptables/iptables"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
@@ -148,6 +150,17 @@ func newService(name, namespace, ip string, ports []v1.ServicePort, serviceType
 	}
 }
 
+// deleteCountingIPTables counts the iptables rules deleted through it
+type deleteCountingIPTables struct {
+	*util.FakeIPTables
+	deletes int
+}
+
+func (ipt *deleteCountingIPTables) Delete(tableName, chainName string, rulespec ...string) error {
+	ipt.deletes++
+	return ipt.FakeIPTables.Delete(tableName, chainName, rulespec...)
+}
+
 func newServiceWithoutNodePortAllocation(name, namespace, ip string, ports []v1.ServicePort, serviceType v1.ServiceType,
 	externalIPs []string, serviceStatus v1.ServiceStatus, isETPLocal, isITPLocal bool) *v1.Service {
 	doNotAllocateNodePorts := false
@@ -527,11 +540,8 @@ var _ = Describe("Node Operations", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("inits iptables rules and openflows with NodePort where ETP=local, LGW", func() {
+		It("inits iptables rules with NodePort when the service is added after its endpointslice", func() {
 			app.Action = func(ctx *cli.Context) error {
-				config.Gateway.Mode = config.GatewayModeLocal
-				epPortName := "https"
-				epPortValue := int32(443)
 				service := *newService("service1", "namespace1", "10.129.0.2",
 					[]v1.ServicePort{
 						{
@@ -543,22 +553,70 @@ var _ = Describe("Node Operations", func() {
 					v1.ServiceTypeNodePort,
 					nil,
 					v1.ServiceStatus{},
+					false, false,
+				)
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{},
+					[]discovery.EndpointPort{})
+
+				fakeOvnNode.start(ctx, &endpointSlice)
+
+				// no service handler is set up so that the rules can only be
+				// programmed by the endpointslice add
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(initLocalGatewayIPTables()).To(Succeed())
+				k := &kube.Kube{KClient: fakeOvnNode.fakeClient.KubeClient}
+				fNPW.nodeIPManager = newAddressManagerInternal(fakeNodeName, k, &fakeMgmtPortConfig, fNPW.watchFactory, nil, false)
+
+				err := fNPW.AddEndpointSlice(&endpointSlice)
+				Expect(err).NotTo(HaveOccurred())
+				_, exists := fNPW.getServiceInfo(k8stypes.NamespacedName{Namespace: "namespace1", Name: "service1"})
+				Expect(exists).To(BeFalse())
+
+				_, err = fakeOvnNode.fakeClient.KubeClient.CoreV1().Services(service.Namespace).Create(
+					context.TODO(), &service, metav1.CreateOptions{})
+				Expect(err).NotTo(HaveOccurred())
+
+				f4 := iptV4.(*util.FakeIPTables)
+				Eventually(func() ([]string, error) {
+					return f4.List("nat", "OVN-KUBE-NODEPORT")
+				}, 5*time.Second).Should(Equal([]string{
+					fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+				}))
+				_, exists = fNPW.getServiceInfo(k8stypes.NamespacedName{Namespace: "namespace1", Name: "service1"})
+				Expect(exists).To(BeTrue())
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("programs a single set of rules when the service and its endpointslice are added concurrently", func() {
+			app.Action = func(ctx *cli.Context) error {
+				config.Gateway.Mode = config.GatewayModeShared
+				epPortName := "https"
+				epPortValue := int32(443)
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort:   int32(31111),
+							Protocol:   v1.ProtocolTCP,
+							Port:       int32(8080),
+							TargetPort: intstr.FromInt(443),
+						},
+					},
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
 					true, false,
 				)
-				ep1 := discovery.Endpoint{
-					Addresses: []string{"10.244.0.3"},
-				}
-				epPort1 := discovery.EndpointPort{
-					Name: &epPortName,
-					Port: &epPortValue,
-				}
-				// endpointSlice.Endpoints is ovn-networked so this will
-				// come under !hasLocalHostNetEp case
 				endpointSlice := *newEndpointSlice(
 					"service1",
 					"namespace1",
-					[]discovery.Endpoint{ep1},
-					[]discovery.EndpointPort{epPort1})
+					[]discovery.Endpoint{{Addresses: []string{"192.168.18.15"}, NodeName: &fakeNodeName}},
+					[]discovery.EndpointPort{{Name: &epPortName, Port: &epPortValue}})
 
 				fakeOvnNode.start(ctx,
 					&v1.ServiceList{
@@ -571,90 +629,85 @@ var _ = Describe("Node Operations", func() {
 
 				fNPW.watchFactory = fakeOvnNode.watcher
 				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
-				err := fNPW.AddService(&service)
-				Expect(err).NotTo(HaveOccurred())
-
-				expectedTables := map[string]util.FakeTable{
-					"nat": {
-						"PREROUTING": []string{
-							"-j OVN-KUBE-ETP",
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-						},
-						"OUTPUT": []string{
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-							"-j OVN-KUBE-ITP",
-						},
-						"POSTROUTING": []string{
-							"-j OVN-KUBE-EGRESS-SVC",
-						},
-						"OVN-KUBE-NODEPORT": []string{
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-						},
-						"OVN-KUBE-EXTERNALIP": []string{},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{
-							fmt.Sprintf("-p TCP --dport %v -j RETURN", service.Spec.Ports[0].NodePort),
-						},
-						"OVN-KUBE-ETP": []string{
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
-						},
-						"OVN-KUBE-ITP":        []string{},
-						"OVN-KUBE-EGRESS-SVC": []string{},
-					},
-					"filter": {},
-					"mangle": {
-						"OUTPUT": []string{
-							"-j OVN-KUBE-ITP",
-						},
-						"OVN-KUBE-ITP": []string{},
-					},
+				f4 := iptV4.(*util.FakeIPTables)
+				flowKey := "NodePort_namespace1_service1_tcp_31111"
+				chains := []string{iptableNodePortChain, iptableETPChain, iptableITPChain, iptableExternalIPChain}
+				getRules := func() map[string][]string {
+					rules := map[string][]string{}
+					for _, chain := range chains {
+						chainRules, err := f4.List("nat", chain)
+						Expect(err).NotTo(HaveOccurred())
+						rules[chain] = chainRules
+					}
+					return rules
+				}
+				deleteService := func() {
+					addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 8080}, {"192.168.18.15", 31111}})
+					Expect(fNPW.DeleteService(&service)).To(Succeed())
 				}
 
-				f4 := iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
-				Expect(err).NotTo(HaveOccurred())
-				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
-				Expect(flows).To(BeNil())
+				// the rules programmed by the service add alone
+				deleteService()
+				Expect(fNPW.AddService(&service)).To(Succeed())
+				expectedRules := getRules()
+				expectedFlows := fNPW.ofm.flowCache[flowKey]
+				Expect(expectedRules[iptableNodePortChain]).To(HaveLen(1))
+				Expect(expectedFlows).NotTo(BeEmpty())
+
+				for i := 0; i < 10; i++ {
+					deleteService()
+					errs := make(chan error, 2)
+					wg := &sync.WaitGroup{}
+					wg.Add(2)
+					go func() {
+						defer wg.Done()
+						errs <- fNPW.AddService(&service)
+					}()
+					go func() {
+						defer wg.Done()
+						errs <- fNPW.AddEndpointSlice(&endpointSlice)
+					}()
+					wg.Wait()
+					close(errs)
+					for err := range errs {
+						Expect(err).NotTo(HaveOccurred())
+					}
+					Expect(getRules()).To(Equal(expectedRules))
+					Expect(fNPW.ofm.flowCache[flowKey]).To(Equal(expectedFlows))
+					svcConfig, exists := fNPW.getServiceInfo(k8stypes.NamespacedName{Namespace: "namespace1", Name: "service1"})
+					Expect(exists).To(BeTrue())
+					Expect(svcConfig.hasLocalHostNetworkEp).To(BeTrue())
+				}
 				return nil
 			}
 			err := app.Run([]string{app.Name})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("inits iptables rules with LoadBalancer", func() {
+		It("does not re-create the rules of a service deleted before or concurrently with the add of its endpointslice", func() {
 			app.Action = func(ctx *cli.Context) error {
-				externalIP := "1.1.1.1"
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-				})
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-				})
+				config.Gateway.Mode = config.GatewayModeShared
+				epPortName := "https"
+				epPortValue := int32(443)
 				service := *newService("service1", "namespace1", "10.129.0.2",
 					[]v1.ServicePort{
 						{
-							NodePort: int32(31111),
-							Protocol: v1.ProtocolTCP,
-							Port:     int32(8080),
-						},
-					},
-					v1.ServiceTypeLoadBalancer,
-					[]string{externalIP},
-					v1.ServiceStatus{
-						LoadBalancer: v1.LoadBalancerStatus{
-							Ingress: []v1.LoadBalancerIngress{{
-								IP: "5.5.5.5",
-							}},
+							NodePort:   int32(31111),
+							Protocol:   v1.ProtocolTCP,
+							Port:       int32(8080),
+							TargetPort: intstr.FromInt(443),
 						},
 					},
-					false, false,
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					true, false,
 				)
 				endpointSlice := *newEndpointSlice(
 					"service1",
 					"namespace1",
-					[]discovery.Endpoint{},
-					[]discovery.EndpointPort{})
+					[]discovery.Endpoint{{Addresses: []string{"192.168.18.15"}, NodeName: &fakeNodeName}},
+					[]discovery.EndpointPort{{Name: &epPortName, Port: &epPortValue}})
 
 				fakeOvnNode.start(ctx,
 					&v1.ServiceList{
@@ -667,94 +720,97 @@ var _ = Describe("Node Operations", func() {
 
 				fNPW.watchFactory = fakeOvnNode.watcher
 				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
-				err := fNPW.AddService(&service)
-				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
-
-				expectedTables := map[string]util.FakeTable{
-					"nat": {
-						"PREROUTING": []string{
-							"-j OVN-KUBE-ETP",
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-						},
-						"OUTPUT": []string{
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-							"-j OVN-KUBE-ITP",
-						},
-						"POSTROUTING": []string{
-							"-j OVN-KUBE-EGRESS-SVC",
-						},
-						"OVN-KUBE-NODEPORT": []string{
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-						},
-						"OVN-KUBE-EXTERNALIP": []string{
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-						},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-ETP":           []string{},
-						"OVN-KUBE-ITP":           []string{},
-						"OVN-KUBE-EGRESS-SVC":    []string{},
-					},
-					"filter": {},
-					"mangle": {
-						"OUTPUT": []string{
-							"-j OVN-KUBE-ITP",
-						},
-						"OVN-KUBE-ITP": []string{},
-					},
+				f4 := iptV4.(*util.FakeIPTables)
+				flowKey := "NodePort_namespace1_service1_tcp_31111"
+				name := k8stypes.NamespacedName{Namespace: "namespace1", Name: "service1"}
+				expectNoRules := func() {
+					for _, chain := range []string{iptableNodePortChain, iptableETPChain, iptableITPChain, iptableExternalIPChain} {
+						rules, err := f4.List("nat", chain)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(rules).To(BeEmpty(), "chain %s", chain)
+					}
+					Expect(fNPW.ofm.flowCache).NotTo(HaveKey(flowKey))
+					_, exists := fNPW.getServiceInfo(name)
+					Expect(exists).To(BeFalse())
 				}
 
-				f4 := iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
-				Expect(err).NotTo(HaveOccurred())
+				// the service is still in the informer cache, as when the endpointslice
+				// handler retrieved it before the service was deleted
+				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 8080}, {"192.168.18.15", 31111}})
+				Expect(fNPW.DeleteService(&service)).To(Succeed())
+				Expect(fNPW.AddEndpointSlice(&endpointSlice)).To(Succeed())
+				expectNoRules()
+
+				for i := 0; i < 10; i++ {
+					Expect(fNPW.AddService(&service)).To(Succeed())
+					Expect(fNPW.ofm.flowCache[flowKey]).NotTo(BeEmpty())
+
+					addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 8080}, {"192.168.18.15", 31111}})
+					errs := make(chan error, 2)
+					wg := &sync.WaitGroup{}
+					wg.Add(2)
+					go func() {
+						defer wg.Done()
+						errs <- fNPW.DeleteService(&service)
+					}()
+					go func() {
+						defer wg.Done()
+						errs <- fNPW.AddEndpointSlice(&endpointSlice)
+					}()
+					wg.Wait()
+					close(errs)
+					for err := range errs {
+						Expect(err).NotTo(HaveOccurred())
+					}
+					expectNoRules()
+				}
 				return nil
 			}
 			err := app.Run([]string{app.Name})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("inits iptables rules and openflows with LoadBalancer where ETP=local, LGW mode", func() {
+		It("programs the rules of a service converted from ExternalName to NodePort in a single pass", func() {
 			app.Action = func(ctx *cli.Context) error {
-				externalIP := "1.1.1.1"
-				config.Gateway.Mode = config.GatewayModeLocal
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
-				})
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
-				})
-				service := *newService("service1", "namespace1", "10.129.0.2",
+				config.Gateway.Mode = config.GatewayModeShared
+				epPortName := "https"
+				epPortValue := int32(443)
+				externalNameService := *newService("service1", "namespace1", "",
 					[]v1.ServicePort{
 						{
-							NodePort: int32(31111),
 							Protocol: v1.ProtocolTCP,
 							Port:     int32(8080),
 						},
 					},
-					v1.ServiceTypeLoadBalancer,
-					[]string{externalIP},
-					v1.ServiceStatus{
-						LoadBalancer: v1.LoadBalancerStatus{
-							Ingress: []v1.LoadBalancerIngress{{
-								IP: "5.5.5.5",
-							}},
+					v1.ServiceTypeExternalName,
+					nil,
+					v1.ServiceStatus{},
+					true, false,
+				)
+				externalNameService.Spec.ClusterIPs = nil
+				externalNameService.Spec.ExternalName = "www.example.com"
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort:   int32(31111),
+							Protocol:   v1.ProtocolTCP,
+							Port:       int32(8080),
+							TargetPort: intstr.FromInt(443),
 						},
 					},
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
 					true, false,
 				)
-				// endpointSlice.Endpoints is empty and yet this will come under
-				// !hasLocalHostNetEp case
+				oldEndpointSlice := *newEndpointSlice("service1", "namespace1", []discovery.Endpoint{}, []discovery.EndpointPort{})
 				endpointSlice := *newEndpointSlice(
 					"service1",
 					"namespace1",
-					[]discovery.Endpoint{},
-					[]discovery.EndpointPort{})
+					[]discovery.Endpoint{{Addresses: []string{"192.168.18.15"}, NodeName: &fakeNodeName}},
+					[]discovery.EndpointPort{{Name: &epPortName, Port: &epPortValue}})
 
+				// the informers have the converted service and its endpointslice
 				fakeOvnNode.start(ctx,
 					&v1.ServiceList{
 						Items: []v1.Service{
@@ -763,94 +819,268 @@ var _ = Describe("Node Operations", func() {
 					},
 					&endpointSlice,
 				)
-
 				fNPW.watchFactory = fakeOvnNode.watcher
-				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
-				err := fNPW.AddService(&service)
+				k := &kube.Kube{KClient: fakeOvnNode.fakeClient.KubeClient}
+				fNPW.nodeIPManager = newAddressManagerInternal(fakeNodeName, k, &fakeMgmtPortConfig, fNPW.watchFactory, nil, false)
+				fNPW.nodeIPManager.addAddr(net.ParseIP("192.168.18.15"))
+				Expect(initLocalGatewayIPTables()).To(Succeed())
+				Expect(fNPW.AddService(&externalNameService)).To(Succeed())
+				_, exists := fNPW.getServiceInfo(k8stypes.NamespacedName{Namespace: "namespace1", Name: "service1"})
+				Expect(exists).To(BeFalse())
+
+				ipt := &deleteCountingIPTables{FakeIPTables: iptV4.(*util.FakeIPTables)}
+				util.SetIPTablesHelper(iptables.ProtocolIPv4, ipt)
+				flowKey := "NodePort_namespace1_service1_tcp_31111"
+
+				// the service update programs the rules from the local endpoints
+				Expect(fNPW.UpdateService(&externalNameService, &service)).To(Succeed())
+				svcConfig, exists := fNPW.getServiceInfo(k8stypes.NamespacedName{Namespace: "namespace1", Name: "service1"})
+				Expect(exists).To(BeTrue())
+				Expect(svcConfig.hasLocalHostNetworkEp).To(BeTrue())
+				rules, err := ipt.List("nat", iptableNodePortChain)
 				Expect(err).NotTo(HaveOccurred())
+				Expect(rules).To(HaveLen(1))
+				flows := fNPW.ofm.flowCache[flowKey]
+				Expect(flows).NotTo(BeEmpty())
+
+				// and the endpointslice events that follow leave them alone
+				Expect(fNPW.AddEndpointSlice(&endpointSlice)).To(Succeed())
+				Expect(fNPW.UpdateEndpointSlice(&oldEndpointSlice, &endpointSlice)).To(Succeed())
+				Expect(ipt.deletes).To(BeZero())
+				Expect(ipt.List("nat", iptableNodePortChain)).To(Equal(rules))
+				Expect(fNPW.ofm.flowCache[flowKey]).To(Equal(flows))
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
 
-				expectedTables := map[string]util.FakeTable{
-					"nat": {
-						"PREROUTING": []string{
-							"-j OVN-KUBE-ETP",
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-						},
-						"OUTPUT": []string{
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-							"-j OVN-KUBE-ITP",
-						},
-						"POSTROUTING": []string{
-							"-j OVN-KUBE-EGRESS-SVC",
-						},
-						"OVN-KUBE-NODEPORT": []string{
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+		It("does not churn the rules of a service when an endpoint address moves between its endpointslices", func() {
+			app.Action = func(ctx *cli.Context) error {
+				config.Gateway.Mode = config.GatewayModeShared
+				epPortName := "https"
+				epPortValue := int32(443)
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort:   int32(31111),
+							Protocol:   v1.ProtocolTCP,
+							Port:       int32(8080),
+							TargetPort: intstr.FromInt(443),
 						},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{
-							fmt.Sprintf("-p TCP --dport %v -j RETURN", service.Spec.Ports[0].NodePort),
-						},
-						"OVN-KUBE-EXTERNALIP": []string{
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+					},
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					true, false,
+				)
+				endpoints := []discovery.Endpoint{{Addresses: []string{"10.244.0.5"}, NodeName: &fakeNodeName}}
+				ports := []discovery.EndpointPort{{Name: &epPortName, Port: &epPortValue}}
+				oldEndpointSlice := *newEndpointSlice("service1", "namespace1", endpoints, ports)
+				// the address is added to the second endpointslice before it is removed from the first one
+				endpointSlice := *newEndpointSlice("service1", "namespace1", []discovery.Endpoint{}, ports)
+				otherEndpointSlice := *newEndpointSlice("service1", "namespace1", endpoints, ports)
+				otherEndpointSlice.Name = "service1cd45"
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
 						},
-						"OVN-KUBE-ETP": []string{
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
+					},
+					&endpointSlice,
+					&otherEndpointSlice,
+				)
+				fNPW.watchFactory = fakeOvnNode.watcher
+				k := &kube.Kube{KClient: fakeOvnNode.fakeClient.KubeClient}
+				fNPW.nodeIPManager = newAddressManagerInternal(fakeNodeName, k, &fakeMgmtPortConfig, fNPW.watchFactory, nil, false)
+				Expect(initLocalGatewayIPTables()).To(Succeed())
+				Expect(fNPW.AddService(&service)).To(Succeed())
+				svcConfig, exists := fNPW.getServiceInfo(k8stypes.NamespacedName{Namespace: "namespace1", Name: "service1"})
+				Expect(exists).To(BeTrue())
+				Expect(svcConfig.localEndpoints.UnsortedList()).To(ConsistOf("10.244.0.5"))
+
+				ipt := &deleteCountingIPTables{FakeIPTables: iptV4.(*util.FakeIPTables)}
+				util.SetIPTablesHelper(iptables.ProtocolIPv4, ipt)
+				flowKey := "NodePort_namespace1_service1_tcp_31111"
+				rules, err := ipt.List("nat", iptableNodePortChain)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rules).To(HaveLen(1))
+				flows := fNPW.ofm.flowCache[flowKey]
+				Expect(flows).NotTo(BeEmpty())
+
+				// the address is still an endpoint of the service: neither its rules nor its
+				// conntrack entries are touched, the netlink mock failing on any conntrack flush
+				Expect(fNPW.UpdateEndpointSlice(&oldEndpointSlice, &endpointSlice)).To(Succeed())
+				Expect(ipt.deletes).To(BeZero())
+				Expect(ipt.List("nat", iptableNodePortChain)).To(Equal(rules))
+				Expect(fNPW.ofm.flowCache[flowKey]).To(Equal(flows))
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("records the time of the last endpoint change of a service", func() {
+			app.Action = func(ctx *cli.Context) error {
+				config.Gateway.Mode = config.GatewayModeShared
+				epPortName := "https"
+				epPortValue := int32(443)
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort:   int32(31111),
+							Protocol:   v1.ProtocolTCP,
+							Port:       int32(8080),
+							TargetPort: intstr.FromInt(443),
 						},
-						"OVN-KUBE-ITP":        []string{},
-						"OVN-KUBE-EGRESS-SVC": []string{},
 					},
-					"filter": {},
-					"mangle": {
-						"OUTPUT": []string{
-							"-j OVN-KUBE-ITP",
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					false, false,
+				)
+				ports := []discovery.EndpointPort{{Name: &epPortName, Port: &epPortValue}}
+				endpointSlice := *newEndpointSlice("service1", "namespace1",
+					[]discovery.Endpoint{{Addresses: []string{"10.244.0.5"}, NodeName: &fakeNodeName}}, ports)
+				updatedEndpointSlice := *newEndpointSlice("service1", "namespace1",
+					[]discovery.Endpoint{
+						{Addresses: []string{"10.244.0.5"}, NodeName: &fakeNodeName},
+						{Addresses: []string{"10.244.0.6"}, NodeName: &fakeNodeName},
+					}, ports)
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
 						},
-						"OVN-KUBE-ITP": []string{},
 					},
-				}
-				expectedLBIngressFlows := []string{
-					"cookie=0x10c6b89e483ea111, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=5.5.5.5, actions=output:LOCAL",
-				}
-				expectedLBExternalIPFlows := []string{
-					"cookie=0x71765945a31dc2f1, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=1.1.1.1, actions=output:LOCAL",
+					&endpointSlice,
+				)
+				fNPW.watchFactory = fakeOvnNode.watcher
+				k := &kube.Kube{KClient: fakeOvnNode.fakeClient.KubeClient}
+				fNPW.nodeIPManager = newAddressManagerInternal(fakeNodeName, k, &fakeMgmtPortConfig, fNPW.watchFactory, nil, false)
+				Expect(initLocalGatewayIPTables()).To(Succeed())
+				name := k8stypes.NamespacedName{Namespace: "namespace1", Name: "service1"}
+				lastEndpointChange := func() time.Time {
+					svcConfig, exists := fNPW.getServiceInfo(name)
+					Expect(exists).To(BeTrue())
+					return svcConfig.lastEndpointChange
 				}
 
-				f4 := iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
-				Expect(err).NotTo(HaveOccurred())
-				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
-				Expect(flows).To(BeNil())
-				flows = fNPW.ofm.flowCache["Ingress_namespace1_service1_5.5.5.5_8080"]
-				Expect(flows).To(Equal(expectedLBIngressFlows))
-				flows = fNPW.ofm.flowCache["External_namespace1_service1_1.1.1.1_8080"]
-				Expect(flows).To(Equal(expectedLBExternalIPFlows))
+				Expect(fNPW.AddService(&service)).To(Succeed())
+				added := lastEndpointChange()
+				Expect(added).NotTo(BeZero())
+
+				// an endpoint added to the service updates the timestamp
+				time.Sleep(10 * time.Millisecond)
+				Expect(fNPW.UpdateEndpointSlice(&endpointSlice, &updatedEndpointSlice)).To(Succeed())
+				updated := lastEndpointChange()
+				Expect(updated).To(BeTemporally(">", added))
+
+				// and so does the deletion of its endpointslice
+				time.Sleep(10 * time.Millisecond)
+				Expect(fNPW.DeleteEndpointSlice(&updatedEndpointSlice)).To(Succeed())
+				Expect(lastEndpointChange()).To(BeTemporally(">", updated))
+
+				// the endpoints in the informer have no ready condition, they are ready
+				changes := fNPW.queryServiceEndpointChanges()
+				Expect(changes).To(HaveLen(1))
+				Expect(changes[0].Service).To(Equal("namespace1/service1"))
+				Expect(changes[0].LastChange).To(Equal(lastEndpointChange()))
+				Expect(changes[0].Converged).To(BeTrue())
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("inits openflows with NodePort under distinct keys for services with underscores in their names", func() {
+			app.Action = func(ctx *cli.Context) error {
+				// both services would get the flow cache key
+				// NodePort_ns_a_svc_tcp_31111 by just joining their fields
+				service1 := *newService("svc", "ns_a", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort: int32(31111),
+							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
+						},
+					},
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					false, false,
+				)
+				service2 := *newService("a_svc", "ns", "10.129.0.3",
+					[]v1.ServicePort{
+						{
+							NodePort: int32(31111),
+							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
+						},
+					},
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					false, false,
+				)
+				endpointSlice1 := *newEndpointSlice("svc", "ns_a", []discovery.Endpoint{}, []discovery.EndpointPort{})
+				endpointSlice2 := *newEndpointSlice("a_svc", "ns", []discovery.Endpoint{}, []discovery.EndpointPort{})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service1,
+							service2,
+						},
+					},
+					&endpointSlice1,
+					&endpointSlice2,
+				)
 
+				config.Gateway.Mode = config.GatewayModeShared
+				// a flow cache entry shared by both services is cleaned up on sync
+				staleKey := "NodePort_ns_a_svc_tcp_31111"
+				fNPW.ofm.flowCache[staleKey] = []string{"stale"}
+
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+
+				key1 := `NodePort_ns\_a_svc_tcp_31111`
+				key2 := `NodePort_ns_a\_svc_tcp_31111`
+				Expect(serviceFlowCacheKey("NodePort", "ns_a", "svc", "tcp", "31111")).To(Equal(key1))
+				Expect(serviceFlowCacheKey("NodePort", "ns", "a_svc", "tcp", "31111")).To(Equal(key2))
+				Expect(isStaleServiceFlowCacheKey(staleKey)).To(BeTrue())
+				Expect(isStaleServiceFlowCacheKey(key1)).To(BeFalse())
+				Expect(isStaleServiceFlowCacheKey(key2)).To(BeFalse())
+
+				Expect(fNPW.ofm.flowCache).NotTo(HaveKey(staleKey))
+				Expect(fNPW.ofm.flowCache).To(HaveKey(key1))
+				Expect(fNPW.ofm.flowCache).To(HaveKey(key2))
 				return nil
 			}
 			err := app.Run([]string{app.Name})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("inits iptables rules and openflows with LoadBalancer where AllocateLoadBalancerNodePorts=False, ETP=local, LGW mode", func() {
+		It("inits openflows under distinct keys for services exposing the same port on TCP and UDP", func() {
 			app.Action = func(ctx *cli.Context) error {
+				config.IPv4Mode = true
 				externalIP := "1.1.1.1"
-				config.Gateway.Mode = config.GatewayModeLocal
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
-				})
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
-				})
-				service := *newServiceWithoutNodePortAllocation("service1", "namespace1", "10.129.0.2",
+				service := *newService("service1", "namespace1", "10.129.0.2",
 					[]v1.ServicePort{
 						{
+							NodePort:   int32(31111),
 							Protocol:   v1.ProtocolTCP,
-							Port:       int32(80),
-							TargetPort: intstr.FromInt(int(int32(8080))),
+							Port:       int32(53),
+							TargetPort: intstr.FromInt(5353),
+						},
+						{
+							NodePort:   int32(31111),
+							Protocol:   v1.ProtocolUDP,
+							Port:       int32(53),
+							TargetPort: intstr.FromInt(5353),
 						},
 					},
 					v1.ServiceTypeLoadBalancer,
@@ -864,45 +1094,1471 @@ var _ = Describe("Node Operations", func() {
 					},
 					true, false,
 				)
-				ep1 := discovery.Endpoint{
-					Addresses: []string{"10.244.0.3"},
-					NodeName:  &fakeNodeName,
+				fakeOvnNode.start(ctx)
+
+				// ETP=local with local host networked endpoints programs the return traffic flows
+				Expect(fNPW.updateServiceFlowCache(&service, true, true)).To(Succeed())
+
+				keys := map[string][]string{}
+				for _, protocol := range []string{"tcp", "udp"} {
+					keys[protocol] = []string{
+						serviceFlowCacheKey("NodePort", "namespace1", "service1", protocol, "31111"),
+						serviceFlowCacheKey("External", "namespace1", "service1", externalIP, protocol, "53"),
+						serviceFlowCacheKey("Ingress", "namespace1", "service1", "5.5.5.5", protocol, "53"),
+					}
+					for _, key := range keys[protocol] {
+						Expect(fNPW.ofm.flowCache).To(HaveKey(key))
+						Expect(fNPW.ofm.flowCache[key]).To(ContainElement(
+							ContainSubstring(fmt.Sprintf("in_port=LOCAL, %s, tp_src=5353", protocol))))
+					}
 				}
-				otherNodeName := "node2"
-				nonLocalEndpoint := discovery.Endpoint{
-					Addresses: []string{"10.244.1.3"}, // is not picked since its not local to the node
-					NodeName:  &otherNodeName,
+
+				// deleting the flows of the UDP port leaves those of the TCP port intact
+				udpService := service.DeepCopy()
+				udpService.Spec.Ports = udpService.Spec.Ports[1:]
+				Expect(fNPW.updateServiceFlowCache(udpService, false, true)).To(Succeed())
+				for _, key := range keys["udp"] {
+					Expect(fNPW.ofm.flowCache).NotTo(HaveKey(key))
 				}
-				ep2 := discovery.Endpoint{
-					Addresses: []string{"10.244.0.4"},
-					NodeName:  &fakeNodeName,
+				for _, key := range keys["tcp"] {
+					Expect(fNPW.ofm.flowCache).To(HaveKey(key))
 				}
-				epPortName := "http"
-				epPortValue := int32(8080)
-				epPort1 := discovery.EndpointPort{
-					Name: &epPortName,
-					Port: &epPortValue,
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("inits openflows of h2c service ports under keys of their own", func() {
+			app.Action = func(ctx *cli.Context) error {
+				config.IPv4Mode = true
+				externalIP := "1.1.1.1"
+				appProtocol := "kubernetes.io/h2c"
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort:    int32(31111),
+							Protocol:    v1.ProtocolTCP,
+							Port:        int32(8080),
+							TargetPort:  intstr.FromInt(8080),
+							AppProtocol: &appProtocol,
+						},
+					},
+					v1.ServiceTypeNodePort,
+					[]string{externalIP},
+					v1.ServiceStatus{},
+					true, false,
+				)
+				fakeOvnNode.start(ctx)
+
+				Expect(fNPW.updateServiceFlowCache(&service, true, true)).To(Succeed())
+
+				h2cKeys := []string{
+					serviceFlowCacheKey("NodePort", "namespace1", "service1", "tcp", "31111", "h2c"),
+					serviceFlowCacheKey("External", "namespace1", "service1", externalIP, "tcp", "8080", "h2c"),
 				}
-				// endpointSlice.Endpoints is ovn-networked so this will
-				// come under !hasLocalHostNetEp case
+				for _, key := range h2cKeys {
+					Expect(isStaleServiceFlowCacheKey(key)).To(BeFalse())
+					Expect(fNPW.ofm.flowCache).To(HaveKey(key))
+				}
+				Expect(fNPW.ofm.flowCache).NotTo(HaveKey(serviceFlowCacheKey("NodePort", "namespace1", "service1", "tcp", "31111")))
+				Expect(fNPW.ofm.flowCache).NotTo(HaveKey(serviceFlowCacheKey("External", "namespace1", "service1", externalIP, "tcp", "8080")))
+				h2cFlows := map[string][]string{}
+				for _, key := range h2cKeys {
+					h2cFlows[key] = fNPW.ofm.flowCache[key]
+				}
+
+				// the flows are the same as the ones of a port without appProtocol
+				Expect(fNPW.updateServiceFlowCache(&service, false, true)).To(Succeed())
+				for _, key := range h2cKeys {
+					Expect(fNPW.ofm.flowCache).NotTo(HaveKey(key))
+				}
+				service.Spec.Ports[0].AppProtocol = nil
+				Expect(fNPW.updateServiceFlowCache(&service, true, true)).To(Succeed())
+				Expect(fNPW.ofm.flowCache[serviceFlowCacheKey("NodePort", "namespace1", "service1", "tcp", "31111")]).To(Equal(h2cFlows[h2cKeys[0]]))
+				Expect(fNPW.ofm.flowCache[serviceFlowCacheKey("External", "namespace1", "service1", externalIP, "tcp", "8080")]).To(Equal(h2cFlows[h2cKeys[1]]))
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("refreshes the openflows with NodePort when the patch port ofport of the bridge differs", func() {
+			app.Action = func(ctx *cli.Context) error {
+				config.Gateway.Mode = config.GatewayModeShared
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort: int32(31111),
+							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
+						},
+					},
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					false, false,
+				)
 				endpointSlice := *newEndpointSlice(
 					"service1",
 					"namespace1",
-					[]discovery.Endpoint{ep1, ep2, nonLocalEndpoint},
-					[]discovery.EndpointPort{epPort1})
+					[]discovery.Endpoint{},
+					[]discovery.EndpointPort{})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+					&endpointSlice,
+				)
+
+				fNPW.ofm.defaultBridge = &bridgeConfiguration{ofPortPatch: fNPW.ofportPatch}
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				err := fNPW.AddService(&service)
+				Expect(err).NotTo(HaveOccurred())
+
+				// nothing to refresh while the ofports match
+				Expect(fNPW.refreshOfportPatch()).To(BeFalse())
+				Expect(fNPW.ofportPatch).To(Equal("patch-breth0_ov"))
+
+				fNPW.ofm.defaultBridge.ofPortPatch = "patch-breth0_new"
+				Expect(fNPW.refreshOfportPatch()).To(BeTrue())
+				Expect(fNPW.ofportPatch).To(Equal("patch-breth0_new"))
+				Expect(fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]).To(Equal([]string{
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=output:patch-breth0_new",
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=patch-breth0_new, tcp, tp_src=31111, actions=output:eth0",
+				}))
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("inits iptables mark rules with NodePort and ExternalIP with the service mark annotation", func() {
+			app.Action = func(ctx *cli.Context) error {
+				externalIP := "1.1.1.1"
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort: int32(31111),
+							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
+						},
+					},
+					v1.ServiceTypeNodePort,
+					[]string{externalIP},
+					v1.ServiceStatus{},
+					false, false,
+				)
+				service.Annotations[ovnServiceMarkAnnotation] = "4096"
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{},
+					[]discovery.EndpointPort{})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+					&endpointSlice,
+				)
 
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				err := fNPW.AddService(&service)
+				Expect(err).NotTo(HaveOccurred())
+
+				expectedTables := map[string]util.FakeTable{
+					"nat": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
+						},
+						"OVN-KUBE-NODEPORT": []string{
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-EXTERNALIP": []string{
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{},
+						"OVN-KUBE-ETP":           []string{},
+						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-EGRESS-SVC":    []string{},
+					},
+					"filter": {},
+					"mangle": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-SVC-MARK",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-SVC-MARK",
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+						"OVN-KUBE-SVC-MARK": []string{
+							fmt.Sprintf("-p %s -d %s --dport %v -j MARK --set-xmark 0x1000", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port),
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j MARK --set-xmark 0x1000", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort),
+							fmt.Sprintf("-p %s -d %s --dport %v -j MARK --set-xmark 0x1000", service.Spec.Ports[0].Protocol, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+					},
+				}
+
+				f4 := iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+
+				// removing the annotation removes the mark rules
+				newService := service.DeepCopy()
+				delete(newService.Annotations, ovnServiceMarkAnnotation)
+				err = fNPW.UpdateService(&service, newService)
+				Expect(err).NotTo(HaveOccurred())
+				expectedTables["mangle"]["OVN-KUBE-SVC-MARK"] = []string{}
+				err = f4.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+
+				// invalid or reserved marks are not set
+				for _, mark := range []string{"0", "-1", "0x100000000", "foo", ovnkubeITPMark, ovnKubeNodeSNATMark} {
+					newService.Annotations[ovnServiceMarkAnnotation] = mark
+					_, err = getServiceMark(newService)
+					Expect(err).To(HaveOccurred(), "mark %s", mark)
+					Expect(getGatewayIPTRules(newService, nil, false)).NotTo(ContainElement(
+						HaveField("Chain", iptableSvcMarkChain)))
+				}
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("inits iptables rules and openflows with NodePort where ETP=local, LGW", func() {
+			app.Action = func(ctx *cli.Context) error {
+				config.Gateway.Mode = config.GatewayModeLocal
+				epPortName := "https"
+				epPortValue := int32(443)
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort: int32(31111),
+							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
+						},
+					},
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					true, false,
+				)
+				ep1 := discovery.Endpoint{
+					Addresses: []string{"10.244.0.3"},
+				}
+				epPort1 := discovery.EndpointPort{
+					Name: &epPortName,
+					Port: &epPortValue,
+				}
+				// endpointSlice.Endpoints is ovn-networked so this will
+				// come under !hasLocalHostNetEp case
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{ep1},
+					[]discovery.EndpointPort{epPort1})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+					&endpointSlice,
+				)
+
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				err := fNPW.AddService(&service)
+				Expect(err).NotTo(HaveOccurred())
+
+				expectedTables := map[string]util.FakeTable{
+					"nat": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
+						},
+						"OVN-KUBE-NODEPORT": []string{
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-EXTERNALIP": []string{},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{
+							fmt.Sprintf("-p TCP --dport %v -j RETURN", service.Spec.Ports[0].NodePort),
+						},
+						"OVN-KUBE-ETP": []string{
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
+						},
+						"OVN-KUBE-ITP":        []string{},
+						"OVN-KUBE-EGRESS-SVC": []string{},
+					},
+					"filter": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
+				}
+
+				f4 := iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
+				Expect(flows).To(BeNil())
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("inits iptables rules with LoadBalancer", func() {
+			app.Action = func(ctx *cli.Context) error {
+				externalIP := "1.1.1.1"
+				// the ARP bypass flows of all the IPs of the service list the bridge ports once
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+				})
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort: int32(31111),
+							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
+						},
+					},
+					v1.ServiceTypeLoadBalancer,
+					[]string{externalIP},
+					v1.ServiceStatus{
+						LoadBalancer: v1.LoadBalancerStatus{
+							Ingress: []v1.LoadBalancerIngress{{
+								IP: "5.5.5.5",
+							}},
+						},
+					},
+					false, false,
+				)
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{},
+					[]discovery.EndpointPort{})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+					&endpointSlice,
+				)
+
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				err := fNPW.AddService(&service)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
+
+				expectedTables := map[string]util.FakeTable{
+					"nat": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
+						},
+						"OVN-KUBE-NODEPORT": []string{
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-EXTERNALIP": []string{
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{},
+						"OVN-KUBE-ETP":           []string{},
+						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-EGRESS-SVC":    []string{},
+					},
+					"filter": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
+				}
+
+				f4 := iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("inits iptables rules and openflows with LoadBalancer where ETP=local, LGW mode", func() {
+			app.Action = func(ctx *cli.Context) error {
+				externalIP := "1.1.1.1"
+				config.Gateway.Mode = config.GatewayModeLocal
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
+				})
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
+				})
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort: int32(31111),
+							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
+						},
+					},
+					v1.ServiceTypeLoadBalancer,
+					[]string{externalIP},
+					v1.ServiceStatus{
+						LoadBalancer: v1.LoadBalancerStatus{
+							Ingress: []v1.LoadBalancerIngress{{
+								IP: "5.5.5.5",
+							}},
+						},
+					},
+					true, false,
+				)
+				// endpointSlice.Endpoints is empty and yet this will come under
+				// !hasLocalHostNetEp case
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{},
+					[]discovery.EndpointPort{})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+					&endpointSlice,
+				)
+
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				err := fNPW.AddService(&service)
+				Expect(err).NotTo(HaveOccurred())
+
+				expectedTables := map[string]util.FakeTable{
+					"nat": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
+						},
+						"OVN-KUBE-NODEPORT": []string{
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{
+							fmt.Sprintf("-p TCP --dport %v -j RETURN", service.Spec.Ports[0].NodePort),
+						},
+						"OVN-KUBE-EXTERNALIP": []string{
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-ETP": []string{
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
+						},
+						"OVN-KUBE-ITP":        []string{},
+						"OVN-KUBE-EGRESS-SVC": []string{},
+					},
+					"filter": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
+				}
+				expectedLBIngressFlows := []string{
+					"cookie=0x10c6b89e483ea111, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=5.5.5.5, actions=output:LOCAL",
+				}
+				expectedLBExternalIPFlows := []string{
+					"cookie=0x71765945a31dc2f1, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=1.1.1.1, actions=output:LOCAL",
+				}
+
+				f4 := iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
+				Expect(flows).To(BeNil())
+				flows = fNPW.ofm.flowCache["Ingress_namespace1_service1_5.5.5.5_tcp_8080"]
+				Expect(flows).To(Equal(expectedLBIngressFlows))
+				flows = fNPW.ofm.flowCache["External_namespace1_service1_1.1.1.1_tcp_8080"]
+				Expect(flows).To(Equal(expectedLBExternalIPFlows))
+
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("inits iptables rules and openflows with LoadBalancer where AllocateLoadBalancerNodePorts=False, ETP=local, LGW mode", func() {
+			app.Action = func(ctx *cli.Context) error {
+				externalIP := "1.1.1.1"
+				config.Gateway.Mode = config.GatewayModeLocal
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
+				})
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
+				})
+				service := *newServiceWithoutNodePortAllocation("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							Protocol:   v1.ProtocolTCP,
+							Port:       int32(80),
+							TargetPort: intstr.FromInt(int(int32(8080))),
+						},
+					},
+					v1.ServiceTypeLoadBalancer,
+					[]string{externalIP},
+					v1.ServiceStatus{
+						LoadBalancer: v1.LoadBalancerStatus{
+							Ingress: []v1.LoadBalancerIngress{{
+								IP: "5.5.5.5",
+							}},
+						},
+					},
+					true, false,
+				)
+				ep1 := discovery.Endpoint{
+					Addresses: []string{"10.244.0.3"},
+					NodeName:  &fakeNodeName,
+				}
+				otherNodeName := "node2"
+				nonLocalEndpoint := discovery.Endpoint{
+					Addresses: []string{"10.244.1.3"}, // is not picked since its not local to the node
+					NodeName:  &otherNodeName,
+				}
+				ep2 := discovery.Endpoint{
+					Addresses: []string{"10.244.0.4"},
+					NodeName:  &fakeNodeName,
+				}
+				epPortName := "http"
+				epPortValue := int32(8080)
+				epPort1 := discovery.EndpointPort{
+					Name: &epPortName,
+					Port: &epPortValue,
+				}
+				// endpointSlice.Endpoints is ovn-networked so this will
+				// come under !hasLocalHostNetEp case
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{ep1, ep2, nonLocalEndpoint},
+					[]discovery.EndpointPort{epPort1})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+					&endpointSlice,
+				)
+
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				fNPW.AddService(&service)
+
+				expectedTables := map[string]util.FakeTable{
+					"nat": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
+						},
+						"OVN-KUBE-NODEPORT": []string{},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{
+							fmt.Sprintf("-p TCP -d %s --dport %d -j RETURN", ep1.Addresses[0], int32(service.Spec.Ports[0].TargetPort.IntValue())),
+							fmt.Sprintf("-p TCP -d %s --dport %d -j RETURN", ep2.Addresses[0], int32(service.Spec.Ports[0].TargetPort.IntValue())),
+						},
+						"OVN-KUBE-EXTERNALIP": []string{
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-ETP": []string{
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%d -m statistic --mode random --probability 0.5000000000", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, ep1.Addresses[0], int32(service.Spec.Ports[0].TargetPort.IntValue())),
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%d -m statistic --mode random --probability 1.0000000000", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, ep2.Addresses[0], int32(service.Spec.Ports[0].TargetPort.IntValue())),
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%d -m statistic --mode random --probability 0.5000000000", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, ep1.Addresses[0], int32(service.Spec.Ports[0].TargetPort.IntValue())),
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%d -m statistic --mode random --probability 1.0000000000", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, ep2.Addresses[0], int32(service.Spec.Ports[0].TargetPort.IntValue())),
+						},
+						"OVN-KUBE-ITP":        []string{},
+						"OVN-KUBE-EGRESS-SVC": []string{},
+					},
+					"filter": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
+				}
+				expectedLBIngressFlows := []string{
+					"cookie=0xd8c1fe514f305bc1, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=5.5.5.5, actions=output:LOCAL",
+				}
+				expectedLBExternalIPFlows := []string{
+					"cookie=0x799e0efe5404e9a1, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=1.1.1.1, actions=output:LOCAL",
+				}
+
+				f4 := iptV4.(*util.FakeIPTables)
+				Expect(f4.MatchState(expectedTables)).To(Succeed())
+				Expect(fNPW.ofm.flowCache["Ingress_namespace1_service1_5.5.5.5_tcp_80"]).To(Equal(expectedLBIngressFlows))
+				Expect(fNPW.ofm.flowCache["External_namespace1_service1_1.1.1.1_tcp_80"]).To(Equal(expectedLBExternalIPFlows))
+				return nil
+			}
+			Expect(app.Run([]string{app.Name})).To(Succeed())
+		})
+
+		It("inits iptables rules and openflows with LoadBalancer where ETP=cluster, LGW mode", func() {
+			app.Action = func(ctx *cli.Context) error {
+				externalIP := "1.1.1.1"
+				config.Gateway.Mode = config.GatewayModeLocal
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
+				})
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
+				})
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort: int32(31111),
+							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
+						},
+					},
+					v1.ServiceTypeLoadBalancer,
+					[]string{externalIP},
+					v1.ServiceStatus{
+						LoadBalancer: v1.LoadBalancerStatus{
+							Ingress: []v1.LoadBalancerIngress{{
+								IP: "5.5.5.5",
+							}},
+						},
+					},
+					false, false, // ETP=cluster
+				)
+				// endpointSlice.Endpoints is empty and yet this will come under
+				// !hasLocalHostNetEp case
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{},
+					[]discovery.EndpointPort{})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+					&endpointSlice,
+				)
+
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				err := fNPW.AddService(&service)
+				Expect(err).NotTo(HaveOccurred())
+
+				expectedTables := map[string]util.FakeTable{
+					"nat": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
+						},
+						"OVN-KUBE-NODEPORT": []string{
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{},
+						"OVN-KUBE-EXTERNALIP": []string{
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-ETP":        []string{},
+						"OVN-KUBE-ITP":        []string{},
+						"OVN-KUBE-EGRESS-SVC": []string{},
+					},
+					"filter": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
+				}
+
+				expectedLBIngressFlows := []string{
+					"cookie=0x10c6b89e483ea111, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=5.5.5.5, actions=output:LOCAL",
+				}
+				expectedLBExternalIPFlows := []string{
+					"cookie=0x71765945a31dc2f1, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=1.1.1.1, actions=output:LOCAL",
+				}
+
+				f4 := iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
+				Expect(flows).To(BeNil())
+				flows = fNPW.ofm.flowCache["Ingress_namespace1_service1_5.5.5.5_tcp_8080"]
+				Expect(flows).To(Equal(expectedLBIngressFlows))
+				flows = fNPW.ofm.flowCache["External_namespace1_service1_1.1.1.1_tcp_8080"]
+				Expect(flows).To(Equal(expectedLBExternalIPFlows))
+
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("inits iptables rules and openflows with LoadBalancer where ETP=local, SGW mode", func() {
+			app.Action = func(ctx *cli.Context) error {
+				externalIP := "1.1.1.1"
+				config.Gateway.Mode = config.GatewayModeShared
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
+				})
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
+				})
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort: int32(31111),
+							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
+						},
+					},
+					v1.ServiceTypeLoadBalancer,
+					[]string{externalIP},
+					v1.ServiceStatus{
+						LoadBalancer: v1.LoadBalancerStatus{
+							Ingress: []v1.LoadBalancerIngress{{
+								IP: "5.5.5.5",
+							}},
+						},
+					},
+					true, false,
+				)
+				// endpointSlice.Endpoints is empty and yet this will come
+				// under !hasLocalHostNetEp case
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{},
+					[]discovery.EndpointPort{})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+					&endpointSlice,
+				)
+
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				err := fNPW.AddService(&service)
+				Expect(err).NotTo(HaveOccurred())
+
+				expectedTables := map[string]util.FakeTable{
+					"nat": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
+						},
+						"OVN-KUBE-NODEPORT": []string{
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{
+							fmt.Sprintf("-p TCP --dport %v -j RETURN", service.Spec.Ports[0].NodePort),
+						},
+						"OVN-KUBE-EXTERNALIP": []string{
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-ETP": []string{
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
+						},
+						"OVN-KUBE-ITP":        []string{},
+						"OVN-KUBE-EGRESS-SVC": []string{},
+					},
+					"filter": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
+				}
+				expectedNodePortFlows := []string{
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=output:patch-breth0_ov",
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=patch-breth0_ov, tcp, tp_src=31111, actions=output:eth0",
+				}
+				expectedLBIngressFlows := []string{
+					"cookie=0x10c6b89e483ea111, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=5.5.5.5, actions=output:LOCAL",
+					"cookie=0x10c6b89e483ea111, priority=110, in_port=eth0, tcp, nw_dst=5.5.5.5, tp_dst=8080, actions=output:patch-breth0_ov",
+					"cookie=0x10c6b89e483ea111, priority=110, in_port=patch-breth0_ov, tcp, nw_src=5.5.5.5, tp_src=8080, actions=output:eth0",
+				}
+				expectedLBExternalIPFlows := []string{
+					"cookie=0x71765945a31dc2f1, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=1.1.1.1, actions=output:LOCAL",
+					"cookie=0x71765945a31dc2f1, priority=110, in_port=eth0, tcp, nw_dst=1.1.1.1, tp_dst=8080, actions=output:patch-breth0_ov",
+					"cookie=0x71765945a31dc2f1, priority=110, in_port=patch-breth0_ov, tcp, nw_src=1.1.1.1, tp_src=8080, actions=output:eth0",
+				}
+
+				f4 := iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
+				Expect(flows).To(Equal(expectedNodePortFlows))
+				flows = fNPW.ofm.flowCache["Ingress_namespace1_service1_5.5.5.5_tcp_8080"]
+				Expect(flows).To(Equal(expectedLBIngressFlows))
+				flows = fNPW.ofm.flowCache["External_namespace1_service1_1.1.1.1_tcp_8080"]
+				Expect(flows).To(Equal(expectedLBExternalIPFlows))
+
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("inits iptables rules with DualStack NodePort", func() {
+			app.Action = func(ctx *cli.Context) error {
+				nodePort := int32(31111)
+
+				fNPW.gatewayIPv6 = v6localnetGatewayIP
+
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort: nodePort,
+							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
+						},
+					},
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					false, false,
+				)
+				service.Spec.ClusterIPs = []string{"10.129.0.2", "fd00:10:96::10"}
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{},
+					[]discovery.EndpointPort{})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+					&endpointSlice,
+				)
+
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				err := fNPW.AddService(&service)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
+
+				expectedTables4 := map[string]util.FakeTable{
+					"nat": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
+						},
+						"OVN-KUBE-NODEPORT": []string{
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIPs[0], service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-EXTERNALIP":    []string{},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{},
+						"OVN-KUBE-ETP":           []string{},
+						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-EGRESS-SVC":    []string{},
+					},
+					"filter": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
+				}
+
+				f4 := iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables4)
+				Expect(err).NotTo(HaveOccurred())
+
+				expectedTables6 := map[string]util.FakeTable{
+					"nat": {
+						"OVN-KUBE-NODEPORT": []string{
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination [%s]:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIPs[1], service.Spec.Ports[0].Port),
+						},
+					},
+					"filter": {},
+					"mangle": {},
+				}
+				f6 := iptV6.(*util.FakeIPTables)
+				err = f6.MatchState(expectedTables6)
+				Expect(err).NotTo(HaveOccurred())
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("inits iptables rules for ExternalIP with DualStack", func() {
+			app.Action = func(ctx *cli.Context) error {
+
+				externalIPv4 := "10.10.10.1"
+				externalIPv6 := "fd00:96:1::1"
+				clusterIPv4 := "10.129.0.2"
+				clusterIPv6 := "fd00:10:96::10"
+				fNPW.gatewayIPv6 = v6localnetGatewayIP
+				// the ARP bypass flows of all the IPs of the service list the bridge ports once
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+				})
+
+				service := *newService("service1", "namespace1", clusterIPv4,
+					[]v1.ServicePort{
+						{
+							Port:     8032,
+							Protocol: v1.ProtocolTCP,
+						},
+					},
+					v1.ServiceTypeClusterIP,
+					[]string{externalIPv4, externalIPv6},
+					v1.ServiceStatus{},
+					false, false,
+				)
+				service.Spec.ClusterIPs = []string{clusterIPv4, clusterIPv6}
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+				)
+
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				err := fNPW.AddService(&service)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
+
+				expectedTables4 := map[string]util.FakeTable{
+					"nat": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
+						},
+						"OVN-KUBE-EXTERNALIP": []string{
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIPv4, service.Spec.Ports[0].Port, clusterIPv4, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-NODEPORT":      []string{},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{},
+						"OVN-KUBE-ETP":           []string{},
+						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-EGRESS-SVC":    []string{},
+					},
+					"filter": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
+				}
+
+				f4 := iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables4)
+				Expect(err).NotTo(HaveOccurred())
+
+				expectedTables6 := map[string]util.FakeTable{
+					"nat": {
+						"OVN-KUBE-EXTERNALIP": []string{
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination [%s]:%v", service.Spec.Ports[0].Protocol, externalIPv6, service.Spec.Ports[0].Port, clusterIPv6, service.Spec.Ports[0].Port),
+						},
+					},
+					"filter": {},
+					"mangle": {},
+				}
+
+				f6 := iptV6.(*util.FakeIPTables)
+				err = f6.MatchState(expectedTables6)
+				Expect(err).NotTo(HaveOccurred())
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("on delete", func() {
+		It("deletes iptables rules with ExternalIP", func() {
+			app.Action = func(ctx *cli.Context) error {
+				externalIP := "1.1.1.1"
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+				})
+
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							Port:     8032,
+							Protocol: v1.ProtocolTCP,
+						},
+					},
+					v1.ServiceTypeClusterIP,
+					[]string{externalIP},
+					v1.ServiceStatus{},
+					false, false,
+				)
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+				)
+
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+
+				addConntrackMocks(netlinkMock, []ctFilterDesc{{"1.1.1.1", 8032}, {"10.129.0.2", 8032}})
+				err := fNPW.DeleteService(&service)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
+
+				expectedTables := map[string]util.FakeTable{
+					"nat": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
+						},
+						"OVN-KUBE-NODEPORT":      []string{},
+						"OVN-KUBE-EXTERNALIP":    []string{},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{},
+						"OVN-KUBE-ETP":           []string{},
+						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-EGRESS-SVC":    []string{},
+					},
+					"filter": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
+				}
+
+				f4 := iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+				expectedTables = map[string]util.FakeTable{
+					"nat":    {},
+					"filter": {},
+					"mangle": {},
+				}
+				f6 := iptV6.(*util.FakeIPTables)
+				err = f6.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("deletes iptables rules for NodePort", func() {
+			app.Action = func(ctx *cli.Context) error {
+				nodePort := int32(31111)
+
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort: nodePort,
+							Protocol: v1.ProtocolTCP,
+						},
+					},
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					false, false,
+				)
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+				)
+
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+
+				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 0}, {"192.168.18.15", 31111}})
+				err := fNPW.DeleteService(&service)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
+
+				expectedTables := map[string]util.FakeTable{
+					"nat": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
+						},
+						"OVN-KUBE-NODEPORT":      []string{},
+						"OVN-KUBE-EXTERNALIP":    []string{},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{},
+						"OVN-KUBE-ETP":           []string{},
+						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-EGRESS-SVC":    []string{},
+					},
+					"filter": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
+				}
+
+				f4 := iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+
+				expectedTables = map[string]util.FakeTable{
+					"nat":    {},
+					"filter": {},
+					"mangle": {},
+				}
+
+				f6 := iptV6.(*util.FakeIPTables)
+				err = f6.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("deletes NodePort conntrack entries only for the node IPs of the service IP families", func() {
+			app.Action = func(ctx *cli.Context) error {
+				nodePort := int32(31111)
+
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							Port:     8080,
+							NodePort: nodePort,
+							Protocol: v1.ProtocolTCP,
+						},
+					},
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					false, false,
+				)
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+				)
+
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				// an IPv6 node IP is not used for the NodePorts of the IPv4
+				// service
+				fNPW.nodeIPManager.addAddr(net.ParseIP("fd00:10:244::15"))
+				Expect(fNPW.nodePortIPsForService(&service)).To(Equal([]net.IP{net.ParseIP("192.168.18.15")}))
+
+				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 8080}, {"192.168.18.15", 31111}})
+				err := fNPW.DeleteService(&service)
+				Expect(err).NotTo(HaveOccurred())
+				netlinkMock.AssertNumberOfCalls(GinkgoT(), "ConntrackDeleteFilter", 2)
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("selects the ETP=local DNAT target from the node IP in the subnet of the ingress IP", func() {
+			app.Action = func(ctx *cli.Context) error {
+				_, ipNet1, _ := net.ParseCIDR("10.0.0.0/24")
+				ipNet1.IP = net.ParseIP("10.0.0.2")
+				_, ipNet2, _ := net.ParseCIDR("192.168.1.0/24")
+				ipNet2.IP = net.ParseIP("192.168.1.2")
+				fNPW.gatewayIPv4 = "10.0.0.2"
+				fNPW.gatewayIPs = []*net.IPNet{ipNet1, ipNet2}
+
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							Port:     8080,
+							NodePort: int32(31111),
+							Protocol: v1.ProtocolTCP,
+						},
+					},
+					v1.ServiceTypeLoadBalancer,
+					nil,
+					v1.ServiceStatus{
+						LoadBalancer: v1.LoadBalancerStatus{
+							Ingress: []v1.LoadBalancerIngress{{IP: "192.168.1.100"}},
+						},
+					},
+					true, false,
+				)
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+				)
+
+				Expect(fNPW.getETPLocalDNATTarget(&service, "192.168.1.100", false)).To(Equal("192.168.1.2"))
+				// NodePorts match the service LB ingress IPs
+				Expect(fNPW.getETPLocalDNATTarget(&service, "", false)).To(Equal("192.168.1.2"))
+				// an ingress IP outside of the node subnets falls back to the gateway IP
+				Expect(fNPW.getETPLocalDNATTarget(&service, "172.16.0.1", false)).To(Equal("10.0.0.2"))
+				Expect(fNPW.getETPLocalDNATTarget(&service, "", true)).To(Equal(v6localnetGatewayIP))
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("renders a single set of openflows for a CIDR ExternalIP", func() {
+			app.Action = func(ctx *cli.Context) error {
+				config.Gateway.Mode = config.GatewayModeShared
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
+				})
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
+						},
+					},
+					v1.ServiceTypeClusterIP,
+					[]string{"10.10.10.5/29", "fd00:10:10::/124"},
+					v1.ServiceStatus{},
+					false, false,
+				)
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+				)
+
+				Expect(normalizeExternalIP("10.10.10.5/29")).To(Equal("10.10.10.0/29"))
+				Expect(normalizeExternalIP("10.10.10.5/32")).To(Equal("10.10.10.5"))
+				Expect(normalizeExternalIP("10.10.10.5")).To(Equal("10.10.10.5"))
+				Expect(normalizeExternalIP("10.10.10")).To(Equal("10.10.10"))
+
+				// a CIDR is not accepted for a LB ingress IP
+				err := fNPW.createLbAndExternalSvcFlows(&service, &service.Spec.Ports[0], true, false, "tcp",
+					"output:patch-breth0_ov", "10.10.10.0/29", "Ingress", nil)
+				Expect(err).To(HaveOccurred())
+
+				err = fNPW.updateServiceFlowCache(&service, true, false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fakeOvnNode.fakeExec.ErrorDesc)
+
+				cookie, err := svcToCookie("namespace1", "service1", "10.10.10.0/29", 8080)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fNPW.ofm.flowCache["External_namespace1_service1_10.10.10.0/29_tcp_8080"]).To(Equal([]string{
+					fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=10.10.10.0/29, actions=output:LOCAL", cookie),
+					fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, tcp, nw_dst=10.10.10.0/29, tp_dst=8080, actions=output:patch-breth0_ov", cookie),
+					fmt.Sprintf("cookie=%s, priority=110, in_port=patch-breth0_ov, tcp, nw_src=10.10.10.0/29, tp_src=8080, actions=output:eth0", cookie),
+				}))
+				cookie, err = svcToCookie("namespace1", "service1", "fd00:10:10::/124", 8080)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fNPW.ofm.flowCache["External_namespace1_service1_fd00:10:10::/124_tcp6_8080"]).To(Equal([]string{
+					fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, icmp6, icmp_type=135, icmp_code=0, nd_target=fd00:10:10::/124, actions=output:LOCAL", cookie),
+					fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, tcp6, ipv6_dst=fd00:10:10::/124, tp_dst=8080, actions=output:patch-breth0_ov", cookie),
+					fmt.Sprintf("cookie=%s, priority=110, in_port=patch-breth0_ov, tcp6, ipv6_src=fd00:10:10::/124, tp_src=8080, actions=output:eth0", cookie),
+				}))
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("on add and delete", func() {
+		It("manages iptables rules with ExternalIP", func() {
+			app.Action = func(ctx *cli.Context) error {
+				externalIP := "10.10.10.1"
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+				})
+				externalIPPort := int32(8034)
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							Port:     externalIPPort,
+							Protocol: v1.ProtocolTCP,
+						},
+					},
+					v1.ServiceTypeClusterIP,
+					[]string{externalIP},
+					v1.ServiceStatus{},
+					false, false,
+				)
 				fakeOvnNode.start(ctx,
 					&v1.ServiceList{
 						Items: []v1.Service{
 							service,
 						},
 					},
-					&endpointSlice,
 				)
-
 				fNPW.watchFactory = fakeOvnNode.watcher
 				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
-				fNPW.AddService(&service)
+				err := fNPW.AddService(&service)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
 
 				expectedTables := map[string]util.FakeTable{
 					"nat": {
@@ -919,23 +2575,16 @@ var _ = Describe("Node Operations", func() {
 						"POSTROUTING": []string{
 							"-j OVN-KUBE-EGRESS-SVC",
 						},
-						"OVN-KUBE-NODEPORT": []string{},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{
-							fmt.Sprintf("-p TCP -d %s --dport %d -j RETURN", ep1.Addresses[0], int32(service.Spec.Ports[0].TargetPort.IntValue())),
-							fmt.Sprintf("-p TCP -d %s --dport %d -j RETURN", ep2.Addresses[0], int32(service.Spec.Ports[0].TargetPort.IntValue())),
-						},
 						"OVN-KUBE-EXTERNALIP": []string{
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-						},
-						"OVN-KUBE-ETP": []string{
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%d -m statistic --mode random --probability 0.5000000000", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, ep1.Addresses[0], int32(service.Spec.Ports[0].TargetPort.IntValue())),
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%d -m statistic --mode random --probability 1.0000000000", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, ep2.Addresses[0], int32(service.Spec.Ports[0].TargetPort.IntValue())),
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%d -m statistic --mode random --probability 0.5000000000", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, ep1.Addresses[0], int32(service.Spec.Ports[0].TargetPort.IntValue())),
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%d -m statistic --mode random --probability 1.0000000000", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, ep2.Addresses[0], int32(service.Spec.Ports[0].TargetPort.IntValue())),
+							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v",
+								service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port,
+								service.Spec.ClusterIP, service.Spec.Ports[0].Port),
 						},
-						"OVN-KUBE-ITP":        []string{},
-						"OVN-KUBE-EGRESS-SVC": []string{},
+						"OVN-KUBE-NODEPORT":      []string{},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{},
+						"OVN-KUBE-ETP":           []string{},
+						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-EGRESS-SVC":    []string{},
 					},
 					"filter": {},
 					"mangle": {
@@ -945,77 +2594,19 @@ var _ = Describe("Node Operations", func() {
 						"OVN-KUBE-ITP": []string{},
 					},
 				}
-				expectedLBIngressFlows := []string{
-					"cookie=0xd8c1fe514f305bc1, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=5.5.5.5, actions=output:LOCAL",
-				}
-				expectedLBExternalIPFlows := []string{
-					"cookie=0x799e0efe5404e9a1, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=1.1.1.1, actions=output:LOCAL",
-				}
 
 				f4 := iptV4.(*util.FakeIPTables)
-				Expect(f4.MatchState(expectedTables)).To(Succeed())
-				Expect(fNPW.ofm.flowCache["Ingress_namespace1_service1_5.5.5.5_80"]).To(Equal(expectedLBIngressFlows))
-				Expect(fNPW.ofm.flowCache["External_namespace1_service1_1.1.1.1_80"]).To(Equal(expectedLBExternalIPFlows))
-				return nil
-			}
-			Expect(app.Run([]string{app.Name})).To(Succeed())
-		})
-
-		It("inits iptables rules and openflows with LoadBalancer where ETP=cluster, LGW mode", func() {
-			app.Action = func(ctx *cli.Context) error {
-				externalIP := "1.1.1.1"
-				config.Gateway.Mode = config.GatewayModeLocal
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
-				})
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
-				})
-				service := *newService("service1", "namespace1", "10.129.0.2",
-					[]v1.ServicePort{
-						{
-							NodePort: int32(31111),
-							Protocol: v1.ProtocolTCP,
-							Port:     int32(8080),
-						},
-					},
-					v1.ServiceTypeLoadBalancer,
-					[]string{externalIP},
-					v1.ServiceStatus{
-						LoadBalancer: v1.LoadBalancerStatus{
-							Ingress: []v1.LoadBalancerIngress{{
-								IP: "5.5.5.5",
-							}},
-						},
-					},
-					false, false, // ETP=cluster
-				)
-				// endpointSlice.Endpoints is empty and yet this will come under
-				// !hasLocalHostNetEp case
-				endpointSlice := *newEndpointSlice(
-					"service1",
-					"namespace1",
-					[]discovery.Endpoint{},
-					[]discovery.EndpointPort{})
-
-				fakeOvnNode.start(ctx,
-					&v1.ServiceList{
-						Items: []v1.Service{
-							service,
-						},
-					},
-					&endpointSlice,
-				)
+				err = f4.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
 
-				fNPW.watchFactory = fakeOvnNode.watcher
-				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
-				err := fNPW.AddService(&service)
+				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.10.10.1", 8034}, {"10.129.0.2", 8034}})
+				err = fNPW.DeleteService(&service)
 				Expect(err).NotTo(HaveOccurred())
 
-				expectedTables := map[string]util.FakeTable{
+				expectedTables = map[string]util.FakeTable{
 					"nat": {
+						"OVN-KUBE-EXTERNALIP": []string{},
+						"OVN-KUBE-NODEPORT":   []string{},
 						"PREROUTING": []string{
 							"-j OVN-KUBE-ETP",
 							"-j OVN-KUBE-EXTERNALIP",
@@ -1029,17 +2620,10 @@ var _ = Describe("Node Operations", func() {
 						"POSTROUTING": []string{
 							"-j OVN-KUBE-EGRESS-SVC",
 						},
-						"OVN-KUBE-NODEPORT": []string{
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-						},
 						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-EXTERNALIP": []string{
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-						},
-						"OVN-KUBE-ETP":        []string{},
-						"OVN-KUBE-ITP":        []string{},
-						"OVN-KUBE-EGRESS-SVC": []string{},
+						"OVN-KUBE-ETP":           []string{},
+						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-EGRESS-SVC":    []string{},
 					},
 					"filter": {},
 					"mangle": {
@@ -1050,82 +2634,59 @@ var _ = Describe("Node Operations", func() {
 					},
 				}
 
-				expectedLBIngressFlows := []string{
-					"cookie=0x10c6b89e483ea111, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=5.5.5.5, actions=output:LOCAL",
-				}
-				expectedLBExternalIPFlows := []string{
-					"cookie=0x71765945a31dc2f1, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=1.1.1.1, actions=output:LOCAL",
-				}
-
-				f4 := iptV4.(*util.FakeIPTables)
+				f4 = iptV4.(*util.FakeIPTables)
 				err = f4.MatchState(expectedTables)
 				Expect(err).NotTo(HaveOccurred())
-				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
-				Expect(flows).To(BeNil())
-				flows = fNPW.ofm.flowCache["Ingress_namespace1_service1_5.5.5.5_8080"]
-				Expect(flows).To(Equal(expectedLBIngressFlows))
-				flows = fNPW.ofm.flowCache["External_namespace1_service1_1.1.1.1_8080"]
-				Expect(flows).To(Equal(expectedLBExternalIPFlows))
-
 				return nil
 			}
 			err := app.Run([]string{app.Name})
 			Expect(err).NotTo(HaveOccurred())
+
 		})
 
-		It("inits iptables rules and openflows with LoadBalancer where ETP=local, SGW mode", func() {
+		It("manages iptables rules with ExternalIP through retry logic", func() {
 			app.Action = func(ctx *cli.Context) error {
-				externalIP := "1.1.1.1"
-				config.Gateway.Mode = config.GatewayModeShared
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
-				})
+				var nodePortWatcherRetry *retry.RetryFramework
+				var err error
+				badExternalIP := "10.10.10.aa"
+				goodExternalIP := "10.10.10.1"
 				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
 					Cmd: "ovs-ofctl show ",
-					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
 				})
-				service := *newService("service1", "namespace1", "10.129.0.2",
+
+				externalIPPort := int32(8034)
+				service_ns := "namespace1"
+				service_name := "service1"
+				service := *newService(service_name, service_ns, "10.129.0.2",
 					[]v1.ServicePort{
 						{
-							NodePort: int32(31111),
+							Port:     externalIPPort,
 							Protocol: v1.ProtocolTCP,
-							Port:     int32(8080),
-						},
-					},
-					v1.ServiceTypeLoadBalancer,
-					[]string{externalIP},
-					v1.ServiceStatus{
-						LoadBalancer: v1.LoadBalancerStatus{
-							Ingress: []v1.LoadBalancerIngress{{
-								IP: "5.5.5.5",
-							}},
 						},
 					},
-					true, false,
+					v1.ServiceTypeClusterIP,
+					[]string{badExternalIP}, // first use an incorrect IP
+					v1.ServiceStatus{},
+					false, false,
 				)
-				// endpointSlice.Endpoints is empty and yet this will come
-				// under !hasLocalHostNetEp case
-				endpointSlice := *newEndpointSlice(
-					"service1",
-					"namespace1",
-					[]discovery.Endpoint{},
-					[]discovery.EndpointPort{})
 
-				fakeOvnNode.start(ctx,
-					&v1.ServiceList{
-						Items: []v1.Service{
-							service,
-						},
-					},
-					&endpointSlice,
-				)
+				fakeOvnNode.start(ctx)
 
+				By("starting node port watcher retry framework")
 				fNPW.watchFactory = fakeOvnNode.watcher
-				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
-				err := fNPW.AddService(&service)
+				nodePortWatcherRetry, err = startNodePortWatcherWithRetry(
+					fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig, fakeOvnNode.stopChan, fakeOvnNode.wg)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(nodePortWatcherRetry).NotTo(BeNil())
+
+				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeFalse(), fExec.ErrorDesc) // no command is executed
+
+				By("add service with incorrect external IP")
+				_, err = fakeOvnNode.fakeClient.KubeClient.CoreV1().Services(service_ns).Create(
+					context.TODO(), &service, metav1.CreateOptions{})
 				Expect(err).NotTo(HaveOccurred())
 
+				// expected ip tables with no external IP set
 				expectedTables := map[string]util.FakeTable{
 					"nat": {
 						"PREROUTING": []string{
@@ -1141,20 +2702,9 @@ var _ = Describe("Node Operations", func() {
 						"POSTROUTING": []string{
 							"-j OVN-KUBE-EGRESS-SVC",
 						},
-						"OVN-KUBE-NODEPORT": []string{
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-						},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{
-							fmt.Sprintf("-p TCP --dport %v -j RETURN", service.Spec.Ports[0].NodePort),
-						},
-						"OVN-KUBE-EXTERNALIP": []string{
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-						},
-						"OVN-KUBE-ETP": []string{
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Status.LoadBalancer.Ingress[0].IP, service.Spec.Ports[0].Port, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
-						},
+						"OVN-KUBE-EXTERNALIP": []string{},
+						"OVN-KUBE-NODEPORT":   []string{},
+						"OVN-KUBE-ETP":        []string{},
 						"OVN-KUBE-ITP":        []string{},
 						"OVN-KUBE-EGRESS-SVC": []string{},
 					},
@@ -1166,42 +2716,52 @@ var _ = Describe("Node Operations", func() {
 						"OVN-KUBE-ITP": []string{},
 					},
 				}
-				expectedNodePortFlows := []string{
-					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=output:patch-breth0_ov",
-					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=patch-breth0_ov, tcp, tp_src=31111, actions=output:eth0",
-				}
-				expectedLBIngressFlows := []string{
-					"cookie=0x10c6b89e483ea111, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=5.5.5.5, actions=output:LOCAL",
-					"cookie=0x10c6b89e483ea111, priority=110, in_port=eth0, tcp, nw_dst=5.5.5.5, tp_dst=8080, actions=output:patch-breth0_ov",
-					"cookie=0x10c6b89e483ea111, priority=110, in_port=patch-breth0_ov, tcp, nw_src=5.5.5.5, tp_src=8080, actions=output:eth0",
-				}
-				expectedLBExternalIPFlows := []string{
-					"cookie=0x71765945a31dc2f1, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=1.1.1.1, actions=output:LOCAL",
-					"cookie=0x71765945a31dc2f1, priority=110, in_port=eth0, tcp, nw_dst=1.1.1.1, tp_dst=8080, actions=output:patch-breth0_ov",
-					"cookie=0x71765945a31dc2f1, priority=110, in_port=patch-breth0_ov, tcp, nw_src=1.1.1.1, tp_src=8080, actions=output:eth0",
-				}
+				By("verify that a new retry entry for this service exists")
+				key, err := retry.GetResourceKey(&service)
+				Expect(err).NotTo(HaveOccurred())
+				retry.CheckRetryObjectEventually(key, true, nodePortWatcherRetry)
 
+				// check iptables
 				f4 := iptV4.(*util.FakeIPTables)
 				err = f4.MatchState(expectedTables)
 				Expect(err).NotTo(HaveOccurred())
-				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
-				Expect(flows).To(Equal(expectedNodePortFlows))
-				flows = fNPW.ofm.flowCache["Ingress_namespace1_service1_5.5.5.5_8080"]
-				Expect(flows).To(Equal(expectedLBIngressFlows))
-				flows = fNPW.ofm.flowCache["External_namespace1_service1_1.1.1.1_8080"]
-				Expect(flows).To(Equal(expectedLBExternalIPFlows))
 
+				// HACK: Fix the service by setting a correct external IP address in newObj field
+				// of the retry entry
+				newObj := retry.GetNewObjFieldFromRetryObj(key, nodePortWatcherRetry)
+				Expect(newObj).ToNot(BeNil())
+				svc := newObj.(*v1.Service)
+				svc.Spec.ExternalIPs = []string{goodExternalIP}
+				ok := retry.SetNewObjFieldInRetryObj(key, nodePortWatcherRetry, svc)
+				Expect(ok).To(BeTrue())
+
+				By("trigger immediate retry")
+				retry.SetRetryObjWithNoBackoff(key, nodePortWatcherRetry)
+				nodePortWatcherRetry.RequestRetryObjs()
+				retry.CheckRetryObjectEventually(key, false, nodePortWatcherRetry) // entry should be gone
+
+				// now expect ip tables to show the external IP
+				ovn_kube_external_ip_field := []string{
+					fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v",
+						service.Spec.Ports[0].Protocol, goodExternalIP, service.Spec.Ports[0].Port,
+						service.Spec.ClusterIP, service.Spec.Ports[0].Port)}
+				expectedTables["nat"]["OVN-KUBE-EXTERNALIP"] = ovn_kube_external_ip_field
+				Eventually(func(g Gomega) {
+					f4 := iptV4.(*util.FakeIPTables)
+					err = f4.MatchState(expectedTables)
+					g.Expect(err).NotTo(HaveOccurred())
+				})
+
+				// TODO Make delete operation fail, check retry entry, run a successful delete
 				return nil
 			}
 			err := app.Run([]string{app.Name})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("inits iptables rules with DualStack NodePort", func() {
+		It("manages iptables rules for NodePort", func() {
 			app.Action = func(ctx *cli.Context) error {
-				nodePort := int32(31111)
-
-				fNPW.gatewayIPv6 = v6localnetGatewayIP
+				nodePort := int32(38034)
 
 				service := *newService("service1", "namespace1", "10.129.0.2",
 					[]v1.ServicePort{
@@ -1216,12 +2776,6 @@ var _ = Describe("Node Operations", func() {
 					v1.ServiceStatus{},
 					false, false,
 				)
-				service.Spec.ClusterIPs = []string{"10.129.0.2", "fd00:10:96::10"}
-				endpointSlice := *newEndpointSlice(
-					"service1",
-					"namespace1",
-					[]discovery.Endpoint{},
-					[]discovery.EndpointPort{})
 
 				fakeOvnNode.start(ctx,
 					&v1.ServiceList{
@@ -1229,16 +2783,14 @@ var _ = Describe("Node Operations", func() {
 							service,
 						},
 					},
-					&endpointSlice,
 				)
-
 				fNPW.watchFactory = fakeOvnNode.watcher
 				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
 				err := fNPW.AddService(&service)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
 
-				expectedTables4 := map[string]util.FakeTable{
+				expectedTables := map[string]util.FakeTable{
 					"nat": {
 						"PREROUTING": []string{
 							"-j OVN-KUBE-ETP",
@@ -1254,7 +2806,7 @@ var _ = Describe("Node Operations", func() {
 							"-j OVN-KUBE-EGRESS-SVC",
 						},
 						"OVN-KUBE-NODEPORT": []string{
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIPs[0], service.Spec.Ports[0].Port),
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, nodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
 						},
 						"OVN-KUBE-EXTERNALIP":    []string{},
 						"OVN-KUBE-SNAT-MGMTPORT": []string{},
@@ -1272,70 +2824,102 @@ var _ = Describe("Node Operations", func() {
 				}
 
 				f4 := iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables4)
+				err = f4.MatchState(expectedTables)
 				Expect(err).NotTo(HaveOccurred())
 
-				expectedTables6 := map[string]util.FakeTable{
+				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 8080}, {"192.168.18.15", 38034}})
+				err = fNPW.DeleteService(&service)
+				Expect(err).NotTo(HaveOccurred())
+
+				expectedTables = map[string]util.FakeTable{
 					"nat": {
-						"OVN-KUBE-NODEPORT": []string{
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination [%s]:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIPs[1], service.Spec.Ports[0].Port),
+						"OVN-KUBE-EXTERNALIP": []string{},
+						"OVN-KUBE-NODEPORT":   []string{},
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
 						},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{},
+						"OVN-KUBE-ETP":           []string{},
+						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-EGRESS-SVC":    []string{},
 					},
 					"filter": {},
-					"mangle": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
 				}
-				f6 := iptV6.(*util.FakeIPTables)
-				err = f6.MatchState(expectedTables6)
+
+				f4 = iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables)
 				Expect(err).NotTo(HaveOccurred())
+
 				return nil
 			}
 			err := app.Run([]string{app.Name})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("inits iptables rules for ExternalIP with DualStack", func() {
+		It("manages iptables rules and openflows for NodePort backed by ovn-k pods where ETP=local, LGW", func() {
 			app.Action = func(ctx *cli.Context) error {
-
-				externalIPv4 := "10.10.10.1"
-				externalIPv6 := "fd00:96:1::1"
-				clusterIPv4 := "10.129.0.2"
-				clusterIPv6 := "fd00:10:96::10"
-				fNPW.gatewayIPv6 = v6localnetGatewayIP
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-				})
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-				})
-
-				service := *newService("service1", "namespace1", clusterIPv4,
+				config.Gateway.Mode = config.GatewayModeLocal
+				epPortName := "https"
+				epPortValue := int32(443)
+				service := *newService("service1", "namespace1", "10.129.0.2",
 					[]v1.ServicePort{
 						{
-							Port:     8032,
+							NodePort: int32(31111),
 							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
 						},
 					},
-					v1.ServiceTypeClusterIP,
-					[]string{externalIPv4, externalIPv6},
+					v1.ServiceTypeNodePort,
+					nil,
 					v1.ServiceStatus{},
-					false, false,
+					true, false,
 				)
-				service.Spec.ClusterIPs = []string{clusterIPv4, clusterIPv6}
+				ep1 := discovery.Endpoint{
+					Addresses: []string{"10.244.0.3"},
+				}
+				epPort1 := discovery.EndpointPort{
+					Name: &epPortName,
+					Port: &epPortValue,
+				}
+				// endpointSlice.Endpoints is ovn-networked so this will come
+				// under !hasLocalHostNetEp case
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{ep1},
+					[]discovery.EndpointPort{epPort1})
+
 				fakeOvnNode.start(ctx,
 					&v1.ServiceList{
 						Items: []v1.Service{
 							service,
 						},
 					},
+					&endpointSlice,
 				)
 
 				fNPW.watchFactory = fakeOvnNode.watcher
 				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
 				err := fNPW.AddService(&service)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
 
-				expectedTables4 := map[string]util.FakeTable{
+				expectedTables := map[string]util.FakeTable{
 					"nat": {
 						"PREROUTING": []string{
 							"-j OVN-KUBE-ETP",
@@ -1350,14 +2934,18 @@ var _ = Describe("Node Operations", func() {
 						"POSTROUTING": []string{
 							"-j OVN-KUBE-EGRESS-SVC",
 						},
-						"OVN-KUBE-EXTERNALIP": []string{
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, externalIPv4, service.Spec.Ports[0].Port, clusterIPv4, service.Spec.Ports[0].Port),
+						"OVN-KUBE-NODEPORT": []string{
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
 						},
-						"OVN-KUBE-NODEPORT":      []string{},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-ETP":           []string{},
-						"OVN-KUBE-ITP":           []string{},
-						"OVN-KUBE-EGRESS-SVC":    []string{},
+						"OVN-KUBE-EXTERNALIP": []string{},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{
+							fmt.Sprintf("-p TCP --dport %v -j RETURN", service.Spec.Ports[0].NodePort),
+						},
+						"OVN-KUBE-ETP": []string{
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
+						},
+						"OVN-KUBE-ITP":        []string{},
+						"OVN-KUBE-EGRESS-SVC": []string{},
 					},
 					"filter": {},
 					"mangle": {
@@ -1369,68 +2957,161 @@ var _ = Describe("Node Operations", func() {
 				}
 
 				f4 := iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables4)
+				err = f4.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
+				Expect(flows).To(BeNil())
+
+				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 8080}, {"192.168.18.15", 31111}})
+				err = fNPW.DeleteService(&service)
 				Expect(err).NotTo(HaveOccurred())
 
-				expectedTables6 := map[string]util.FakeTable{
+				expectedTables = map[string]util.FakeTable{
 					"nat": {
-						"OVN-KUBE-EXTERNALIP": []string{
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination [%s]:%v", service.Spec.Ports[0].Protocol, externalIPv6, service.Spec.Ports[0].Port, clusterIPv6, service.Spec.Ports[0].Port),
+						"OVN-KUBE-EXTERNALIP": []string{},
+						"OVN-KUBE-NODEPORT":   []string{},
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
 						},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{},
+						"OVN-KUBE-ETP":           []string{},
+						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-EGRESS-SVC":    []string{},
 					},
 					"filter": {},
-					"mangle": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
 				}
 
-				f6 := iptV6.(*util.FakeIPTables)
-				err = f6.MatchState(expectedTables6)
+				f4 = iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables)
 				Expect(err).NotTo(HaveOccurred())
+
+				flows = fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
+				Expect(flows).To(BeNil())
+
 				return nil
 			}
 			err := app.Run([]string{app.Name})
 			Expect(err).NotTo(HaveOccurred())
 		})
-	})
 
-	Context("on delete", func() {
-		It("deletes iptables rules with ExternalIP", func() {
+		It("manages iptables rules and openflows for NodePort backed by ovn-k pods where ETP=local, SGW", func() {
 			app.Action = func(ctx *cli.Context) error {
-				externalIP := "1.1.1.1"
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-				})
-
+				config.Gateway.Mode = config.GatewayModeShared
+				epPortName := "https"
+				epPortValue := int32(443)
 				service := *newService("service1", "namespace1", "10.129.0.2",
 					[]v1.ServicePort{
 						{
-							Port:     8032,
+							NodePort: int32(31111),
 							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
 						},
 					},
-					v1.ServiceTypeClusterIP,
-					[]string{externalIP},
+					v1.ServiceTypeNodePort,
+					nil,
 					v1.ServiceStatus{},
-					false, false,
+					true, false,
 				)
 
+				ep1 := discovery.Endpoint{
+					Addresses: []string{"10.244.0.3"},
+				}
+				epPort1 := discovery.EndpointPort{
+					Name: &epPortName,
+					Port: &epPortValue,
+				}
+				// endpointSlice.Endpoints is ovn-networked so this will come
+				// under !hasLocalHostNetEp case
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{ep1},
+					[]discovery.EndpointPort{epPort1})
+
 				fakeOvnNode.start(ctx,
 					&v1.ServiceList{
 						Items: []v1.Service{
 							service,
 						},
 					},
+					&endpointSlice,
 				)
 
-				fNPW.watchFactory = fakeOvnNode.watcher
-				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				err := fNPW.AddService(&service)
+				Expect(err).NotTo(HaveOccurred())
+
+				expectedTables := map[string]util.FakeTable{
+					"nat": {
+						"PREROUTING": []string{
+							"-j OVN-KUBE-ETP",
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+						},
+						"OUTPUT": []string{
+							"-j OVN-KUBE-EXTERNALIP",
+							"-j OVN-KUBE-NODEPORT",
+							"-j OVN-KUBE-ITP",
+						},
+						"POSTROUTING": []string{
+							"-j OVN-KUBE-EGRESS-SVC",
+						},
+						"OVN-KUBE-NODEPORT": []string{
+							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
+						},
+						"OVN-KUBE-EXTERNALIP": []string{},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{
+							fmt.Sprintf("-p TCP --dport %v -j RETURN", service.Spec.Ports[0].NodePort),
+						},
+						"OVN-KUBE-ETP":        []string{},
+						"OVN-KUBE-ITP":        []string{},
+						"OVN-KUBE-EGRESS-SVC": []string{},
+					},
+					"filter": {},
+					"mangle": {
+						"OUTPUT": []string{
+							"-j OVN-KUBE-ITP",
+						},
+						"OVN-KUBE-ITP": []string{},
+					},
+				}
+				expectedFlows := []string{
+					// default
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=output:patch-breth0_ov",
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=patch-breth0_ov, tcp, tp_src=31111, actions=output:eth0",
+				}
+
+				f4 := iptV4.(*util.FakeIPTables)
+				err = f4.MatchState(expectedTables)
+				Expect(err).NotTo(HaveOccurred())
+				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
+				Expect(flows).To(Equal(expectedFlows))
 
-				addConntrackMocks(netlinkMock, []ctFilterDesc{{"1.1.1.1", 8032}, {"10.129.0.2", 8032}})
-				err := fNPW.DeleteService(&service)
+				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 8080}, {"192.168.18.15", 31111}})
+				err = fNPW.DeleteService(&service)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
 
-				expectedTables := map[string]util.FakeTable{
+				expectedTables = map[string]util.FakeTable{
 					"nat": {
+						"OVN-KUBE-EXTERNALIP": []string{},
+						"OVN-KUBE-NODEPORT":   []string{},
 						"PREROUTING": []string{
 							"-j OVN-KUBE-ETP",
 							"-j OVN-KUBE-EXTERNALIP",
@@ -1444,8 +3125,6 @@ var _ = Describe("Node Operations", func() {
 						"POSTROUTING": []string{
 							"-j OVN-KUBE-EGRESS-SVC",
 						},
-						"OVN-KUBE-NODEPORT":      []string{},
-						"OVN-KUBE-EXTERNALIP":    []string{},
 						"OVN-KUBE-SNAT-MGMTPORT": []string{},
 						"OVN-KUBE-ETP":           []string{},
 						"OVN-KUBE-ITP":           []string{},
@@ -1460,32 +3139,30 @@ var _ = Describe("Node Operations", func() {
 					},
 				}
 
-				f4 := iptV4.(*util.FakeIPTables)
+				f4 = iptV4.(*util.FakeIPTables)
 				err = f4.MatchState(expectedTables)
 				Expect(err).NotTo(HaveOccurred())
-				expectedTables = map[string]util.FakeTable{
-					"nat":    {},
-					"filter": {},
-					"mangle": {},
-				}
-				f6 := iptV6.(*util.FakeIPTables)
-				err = f6.MatchState(expectedTables)
-				Expect(err).NotTo(HaveOccurred())
+
+				flows = fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
+				Expect(flows).To(BeNil())
+
 				return nil
 			}
 			err := app.Run([]string{app.Name})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("deletes iptables rules for NodePort", func() {
+		It("restricts the openflows for NodePort to the configured uplink, SGW", func() {
 			app.Action = func(ctx *cli.Context) error {
-				nodePort := int32(31111)
-
+				config.Gateway.Mode = config.GatewayModeShared
+				epPortName := "https"
+				epPortValue := int32(443)
 				service := *newService("service1", "namespace1", "10.129.0.2",
 					[]v1.ServicePort{
 						{
-							NodePort: nodePort,
+							NodePort: int32(31111),
 							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
 						},
 					},
 					v1.ServiceTypeNodePort,
@@ -1493,6 +3170,11 @@ var _ = Describe("Node Operations", func() {
 					v1.ServiceStatus{},
 					false, false,
 				)
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{{Addresses: []string{"10.244.0.3"}}},
+					[]discovery.EndpointPort{{Name: &epPortName, Port: &epPortValue}})
 
 				fakeOvnNode.start(ctx,
 					&v1.ServiceList{
@@ -1500,316 +3182,314 @@ var _ = Describe("Node Operations", func() {
 							service,
 						},
 					},
+					&endpointSlice,
 				)
 
+				// the bridge has the uplinks eth0 and eth1, NodePort traffic is only accepted on eth1
+				fNPW.nodePortOfports = []string{"eth1"}
 				fNPW.watchFactory = fakeOvnNode.watcher
 				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
-
-				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 0}, {"192.168.18.15", 31111}})
-				err := fNPW.DeleteService(&service)
+				err := fNPW.AddService(&service)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
 
-				expectedTables := map[string]util.FakeTable{
-					"nat": {
-						"PREROUTING": []string{
-							"-j OVN-KUBE-ETP",
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-						},
-						"OUTPUT": []string{
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-							"-j OVN-KUBE-ITP",
-						},
-						"POSTROUTING": []string{
-							"-j OVN-KUBE-EGRESS-SVC",
+				expectedFlows := []string{
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth1, tcp, tp_dst=31111, actions=output:patch-breth0_ov",
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=patch-breth0_ov, tcp, tp_src=31111, actions=output:eth0",
+				}
+				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
+				Expect(flows).To(Equal(expectedFlows))
+
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("does not program IPv6 host DNAT openflows for a PreferDualStack service with only IPv4 local-host-networked endpoints where ETP=local, SGW", func() {
+			app.Action = func(ctx *cli.Context) error {
+				config.Gateway.Mode = config.GatewayModeShared
+				fNPW.gatewayIPv6 = v6localnetGatewayIP
+				outport := int32(443)
+				epPortName := "https"
+				epPortValue := int32(443)
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort:   int32(31111),
+							Protocol:   v1.ProtocolTCP,
+							Port:       int32(8080),
+							TargetPort: intstr.FromInt(int(outport)),
 						},
-						"OVN-KUBE-NODEPORT":      []string{},
-						"OVN-KUBE-EXTERNALIP":    []string{},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-ETP":           []string{},
-						"OVN-KUBE-ITP":           []string{},
-						"OVN-KUBE-EGRESS-SVC":    []string{},
 					},
-					"filter": {},
-					"mangle": {
-						"OUTPUT": []string{
-							"-j OVN-KUBE-ITP",
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					true, false,
+				)
+				preferDualStack := v1.IPFamilyPolicyPreferDualStack
+				service.Spec.IPFamilyPolicy = &preferDualStack
+				service.Spec.IPFamilies = []v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol}
+				service.Spec.ClusterIPs = []string{"10.129.0.2", "fd00:10:96::10"}
+				// only an IPv4 host-networked endpoint local to this node
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{{Addresses: []string{"192.168.18.15"}, NodeName: &fakeNodeName}},
+					[]discovery.EndpointPort{{Name: &epPortName, Port: &epPortValue}})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
 						},
-						"OVN-KUBE-ITP": []string{},
 					},
-				}
+					&endpointSlice,
+				)
 
-				f4 := iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
+				config.IPv4Mode = true
+				config.IPv6Mode = true
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				fNPW.nodeIPManager.addAddr(net.ParseIP("fd00:10:244::15"))
+				err := fNPW.AddService(&service)
 				Expect(err).NotTo(HaveOccurred())
 
-				expectedTables = map[string]util.FakeTable{
-					"nat":    {},
-					"filter": {},
-					"mangle": {},
+				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
+				Expect(flows).To(ContainElement(
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=ct(commit,zone=64003,nat(dst=10.244.0.1:443),table=6)"))
+
+				flows = fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp6_31111"]
+				Expect(flows).NotTo(BeEmpty())
+				for _, flow := range flows {
+					Expect(flow).NotTo(ContainSubstring("nat(dst="))
+					Expect(flow).NotTo(ContainSubstring("actions=output:LOCAL"))
 				}
+				Expect(flows[0]).To(HaveSuffix("in_port=eth0, tcp6, tp_dst=31111, actions=output:patch-breth0_ov"))
 
-				f6 := iptV6.(*util.FakeIPTables)
-				err = f6.MatchState(expectedTables)
-				Expect(err).NotTo(HaveOccurred())
 				return nil
 			}
 			err := app.Run([]string{app.Name})
 			Expect(err).NotTo(HaveOccurred())
 		})
-	})
 
-	Context("on add and delete", func() {
-		It("manages iptables rules with ExternalIP", func() {
+		It("distributes the NodePort traffic across the ports of several local-host-networked endpoints where ETP=local, SGW", func() {
 			app.Action = func(ctx *cli.Context) error {
-				externalIP := "10.10.10.1"
-				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
-					Cmd: "ovs-ofctl show ",
-				})
-				externalIPPort := int32(8034)
+				config.Gateway.Mode = config.GatewayModeShared
 				service := *newService("service1", "namespace1", "10.129.0.2",
 					[]v1.ServicePort{
 						{
-							Port:     externalIPPort,
-							Protocol: v1.ProtocolTCP,
+							NodePort:   int32(31111),
+							Protocol:   v1.ProtocolTCP,
+							Port:       int32(8080),
+							TargetPort: intstr.FromString("https"),
 						},
 					},
-					v1.ServiceTypeClusterIP,
-					[]string{externalIP},
+					v1.ServiceTypeNodePort,
+					nil,
 					v1.ServiceStatus{},
-					false, false,
+					true, false,
 				)
+				// two host-networked endpoints local to this node, whose named target
+				// port resolves to distinct ports
+				epPort1 := int32(8443)
+				endpointSlice1 := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{{Addresses: []string{"192.168.18.15"}, NodeName: &fakeNodeName}},
+					[]discovery.EndpointPort{{Port: &epPort1}})
+				epPort2 := int32(9443)
+				endpointSlice2 := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{{Addresses: []string{"192.168.18.15"}, NodeName: &fakeNodeName}},
+					[]discovery.EndpointPort{{Port: &epPort2}})
+				endpointSlice2.Name = "service1cd45"
+
 				fakeOvnNode.start(ctx,
 					&v1.ServiceList{
 						Items: []v1.Service{
 							service,
 						},
 					},
+					&endpointSlice1,
+					&endpointSlice2,
 				)
+
 				fNPW.watchFactory = fakeOvnNode.watcher
 				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
 				err := fNPW.AddService(&service)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
-
-				expectedTables := map[string]util.FakeTable{
-					"nat": {
-						"PREROUTING": []string{
-							"-j OVN-KUBE-ETP",
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-						},
-						"OUTPUT": []string{
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-							"-j OVN-KUBE-ITP",
-						},
-						"POSTROUTING": []string{
-							"-j OVN-KUBE-EGRESS-SVC",
-						},
-						"OVN-KUBE-EXTERNALIP": []string{
-							fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v",
-								service.Spec.Ports[0].Protocol, externalIP, service.Spec.Ports[0].Port,
-								service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-						},
-						"OVN-KUBE-NODEPORT":      []string{},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-ETP":           []string{},
-						"OVN-KUBE-ITP":           []string{},
-						"OVN-KUBE-EGRESS-SVC":    []string{},
-					},
-					"filter": {},
-					"mangle": {
-						"OUTPUT": []string{
-							"-j OVN-KUBE-ITP",
-						},
-						"OVN-KUBE-ITP": []string{},
-					},
-				}
 
-				f4 := iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
-				Expect(err).NotTo(HaveOccurred())
+				key := "NodePort_namespace1_service1_tcp_31111"
+				Expect(fNPW.ofm.flowCache[key]).To(Equal([]string{
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=group:1",
+					"cookie=0xe745ecf105, priority=110, table=6, actions=output:LOCAL",
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=LOCAL, tcp, tp_src=8443, actions=ct(zone=64003 nat,table=7)",
+					"cookie=0xe745ecf105, priority=110, table=7, actions=output:eth0",
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=LOCAL, tcp, tp_src=9443, actions=ct(zone=64003 nat,table=7)",
+				}))
+				Expect(fNPW.ofm.groupCache[key]).To(Equal("group_id=1,type=select," +
+					"bucket=actions=ct(commit,zone=64003,nat(dst=10.244.0.1:8443),table=6)," +
+					"bucket=actions=ct(commit,zone=64003,nat(dst=10.244.0.1:9443),table=6)"))
 
-				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.10.10.1", 8034}, {"10.129.0.2", 8034}})
+				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 8080}, {"192.168.18.15", 31111}})
 				err = fNPW.DeleteService(&service)
 				Expect(err).NotTo(HaveOccurred())
+				Expect(fNPW.ofm.flowCache[key]).To(BeNil())
+				Expect(fNPW.ofm.groupCache).NotTo(HaveKey(key))
 
-				expectedTables = map[string]util.FakeTable{
-					"nat": {
-						"OVN-KUBE-EXTERNALIP": []string{},
-						"OVN-KUBE-NODEPORT":   []string{},
-						"PREROUTING": []string{
-							"-j OVN-KUBE-ETP",
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-						},
-						"OUTPUT": []string{
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-							"-j OVN-KUBE-ITP",
-						},
-						"POSTROUTING": []string{
-							"-j OVN-KUBE-EGRESS-SVC",
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("DNATs the NodePort traffic to the single port of a local-host-networked endpoint where ETP=local, SGW", func() {
+			app.Action = func(ctx *cli.Context) error {
+				config.Gateway.Mode = config.GatewayModeShared
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{
+						{
+							NodePort:   int32(31111),
+							Protocol:   v1.ProtocolTCP,
+							Port:       int32(8080),
+							TargetPort: intstr.FromInt(8443),
 						},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-ETP":           []string{},
-						"OVN-KUBE-ITP":           []string{},
-						"OVN-KUBE-EGRESS-SVC":    []string{},
 					},
-					"filter": {},
-					"mangle": {
-						"OUTPUT": []string{
-							"-j OVN-KUBE-ITP",
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					true, false,
+				)
+				epPort := int32(8443)
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{{Addresses: []string{"192.168.18.15"}, NodeName: &fakeNodeName}},
+					[]discovery.EndpointPort{{Port: &epPort}})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
 						},
-						"OVN-KUBE-ITP": []string{},
 					},
-				}
+					&endpointSlice,
+				)
 
-				f4 = iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				err := fNPW.AddService(&service)
 				Expect(err).NotTo(HaveOccurred())
+
+				key := "NodePort_namespace1_service1_tcp_31111"
+				Expect(fNPW.ofm.flowCache[key]).To(Equal([]string{
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=ct(commit,zone=64003,nat(dst=10.244.0.1:8443),table=6)",
+					"cookie=0xe745ecf105, priority=110, table=6, actions=output:LOCAL",
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=LOCAL, tcp, tp_src=8443, actions=ct(zone=64003 nat,table=7)",
+					"cookie=0xe745ecf105, priority=110, table=7, actions=output:eth0",
+				}))
+				Expect(fNPW.ofm.groupCache).NotTo(HaveKey(key))
+
 				return nil
 			}
 			err := app.Run([]string{app.Name})
 			Expect(err).NotTo(HaveOccurred())
-
 		})
 
-		It("manages iptables rules with ExternalIP through retry logic", func() {
+		It("manages openflows for an ExternalName service with external name IPs, SGW", func() {
 			app.Action = func(ctx *cli.Context) error {
-				var nodePortWatcherRetry *retry.RetryFramework
-				var err error
-				badExternalIP := "10.10.10.aa"
-				goodExternalIP := "10.10.10.1"
+				config.Gateway.Mode = config.GatewayModeShared
 				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
 					Cmd: "ovs-ofctl show ",
+					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
 				})
-
-				externalIPPort := int32(8034)
-				service_ns := "namespace1"
-				service_name := "service1"
-				service := *newService(service_name, service_ns, "10.129.0.2",
+				service := *newService("service1", "namespace1", "",
 					[]v1.ServicePort{
 						{
-							Port:     externalIPPort,
 							Protocol: v1.ProtocolTCP,
+							Port:     int32(80),
 						},
 					},
-					v1.ServiceTypeClusterIP,
-					[]string{badExternalIP}, // first use an incorrect IP
+					v1.ServiceTypeExternalName,
+					nil,
 					v1.ServiceStatus{},
 					false, false,
 				)
+				service.Spec.ClusterIPs = nil
+				service.Spec.ExternalName = "www.example.com"
+				service.Annotations = map[string]string{ovnExternalNameIPsAnnotation: "10.10.10.1"}
 
-				fakeOvnNode.start(ctx)
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
+						},
+					},
+				)
 
-				By("starting node port watcher retry framework")
 				fNPW.watchFactory = fakeOvnNode.watcher
-				nodePortWatcherRetry, err = startNodePortWatcherWithRetry(
-					fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig, fakeOvnNode.stopChan, fakeOvnNode.wg)
-				Expect(err).NotTo(HaveOccurred())
-				Expect(nodePortWatcherRetry).NotTo(BeNil())
-
-				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeFalse(), fExec.ErrorDesc) // no command is executed
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				key := "External_namespace1_service1_10.10.10.1_tcp_80"
 
-				By("add service with incorrect external IP")
-				_, err = fakeOvnNode.fakeClient.KubeClient.CoreV1().Services(service_ns).Create(
-					context.TODO(), &service, metav1.CreateOptions{})
+				// disabled by default
+				err := fNPW.AddService(&service)
 				Expect(err).NotTo(HaveOccurred())
+				Expect(fNPW.ofm.flowCache).NotTo(HaveKey(key))
 
-				// expected ip tables with no external IP set
-				expectedTables := map[string]util.FakeTable{
-					"nat": {
-						"PREROUTING": []string{
-							"-j OVN-KUBE-ETP",
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-						},
-						"OUTPUT": []string{
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-							"-j OVN-KUBE-ITP",
-						},
-						"POSTROUTING": []string{
-							"-j OVN-KUBE-EGRESS-SVC",
-						},
-						"OVN-KUBE-EXTERNALIP": []string{},
-						"OVN-KUBE-NODEPORT":   []string{},
-						"OVN-KUBE-ETP":        []string{},
-						"OVN-KUBE-ITP":        []string{},
-						"OVN-KUBE-EGRESS-SVC": []string{},
-					},
-					"filter": {},
-					"mangle": {
-						"OUTPUT": []string{
-							"-j OVN-KUBE-ITP",
-						},
-						"OVN-KUBE-ITP": []string{},
-					},
-				}
-				By("verify that a new retry entry for this service exists")
-				key, err := retry.GetResourceKey(&service)
+				config.Gateway.ExternalNameServiceIPs = true
+				err = fNPW.AddService(&service)
 				Expect(err).NotTo(HaveOccurred())
-				retry.CheckRetryObjectEventually(key, true, nodePortWatcherRetry)
+				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fakeOvnNode.fakeExec.ErrorDesc)
 
-				// check iptables
-				f4 := iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
+				cookie, err := svcToCookie("namespace1", "service1", "10.10.10.1", 80)
 				Expect(err).NotTo(HaveOccurred())
+				Expect(fNPW.ofm.flowCache[key]).To(Equal([]string{
+					fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=10.10.10.1, actions=output:LOCAL", cookie),
+					fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, tcp, nw_dst=10.10.10.1, tp_dst=80, actions=output:patch-breth0_ov", cookie),
+					fmt.Sprintf("cookie=%s, priority=110, in_port=patch-breth0_ov, tcp, nw_src=10.10.10.1, tp_src=80, actions=output:eth0", cookie),
+				}))
 
-				// HACK: Fix the service by setting a correct external IP address in newObj field
-				// of the retry entry
-				newObj := retry.GetNewObjFieldFromRetryObj(key, nodePortWatcherRetry)
-				Expect(newObj).ToNot(BeNil())
-				svc := newObj.(*v1.Service)
-				svc.Spec.ExternalIPs = []string{goodExternalIP}
-				ok := retry.SetNewObjFieldInRetryObj(key, nodePortWatcherRetry, svc)
-				Expect(ok).To(BeTrue())
-
-				By("trigger immediate retry")
-				retry.SetRetryObjWithNoBackoff(key, nodePortWatcherRetry)
-				nodePortWatcherRetry.RequestRetryObjs()
-				retry.CheckRetryObjectEventually(key, false, nodePortWatcherRetry) // entry should be gone
+				// an invalid IP is rejected
+				invalid := service.DeepCopy()
+				invalid.Annotations[ovnExternalNameIPsAnnotation] = "www.example.com"
+				Expect(fNPW.AddService(invalid)).NotTo(Succeed())
 
-				// now expect ip tables to show the external IP
-				ovn_kube_external_ip_field := []string{
-					fmt.Sprintf("-p %s -d %s --dport %v -j DNAT --to-destination %s:%v",
-						service.Spec.Ports[0].Protocol, goodExternalIP, service.Spec.Ports[0].Port,
-						service.Spec.ClusterIP, service.Spec.Ports[0].Port)}
-				expectedTables["nat"]["OVN-KUBE-EXTERNALIP"] = ovn_kube_external_ip_field
-				Eventually(func(g Gomega) {
-					f4 := iptV4.(*util.FakeIPTables)
-					err = f4.MatchState(expectedTables)
-					g.Expect(err).NotTo(HaveOccurred())
-				})
+				err = fNPW.DeleteService(&service)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fNPW.ofm.flowCache).NotTo(HaveKey(key))
 
-				// TODO Make delete operation fail, check retry entry, run a successful delete
 				return nil
 			}
 			err := app.Run([]string{app.Name})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("manages iptables rules for NodePort", func() {
+		It("manages openflows for the externalIPs of a headless service, SGW", func() {
 			app.Action = func(ctx *cli.Context) error {
-				nodePort := int32(38034)
-
-				service := *newService("service1", "namespace1", "10.129.0.2",
+				config.Gateway.Mode = config.GatewayModeShared
+				fakeOvnNode.fakeExec.AddFakeCmd(&ovntest.ExpectedCmd{
+					Cmd: "ovs-ofctl show ",
+					Err: fmt.Errorf("deliberate error to fall back to output:LOCAL"),
+				})
+				service := *newService("service1", "namespace1", v1.ClusterIPNone,
 					[]v1.ServicePort{
 						{
-							NodePort: nodePort,
 							Protocol: v1.ProtocolTCP,
-							Port:     int32(8080),
+							Port:     int32(80),
 						},
 					},
-					v1.ServiceTypeNodePort,
-					nil,
+					v1.ServiceTypeClusterIP,
+					[]string{"1.1.1.1"},
 					v1.ServiceStatus{},
-					false, false,
+					true, false,
 				)
+				// a local host networked backend, the traffic is still sent to OVN
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{{Addresses: []string{"192.168.18.15"}, NodeName: &fakeNodeName}},
+					[]discovery.EndpointPort{{Port: &[]int32{80}[0]}})
 
 				fakeOvnNode.start(ctx,
 					&v1.ServiceList{
@@ -1817,88 +3497,81 @@ var _ = Describe("Node Operations", func() {
 							service,
 						},
 					},
+					&endpointSlice,
 				)
+
 				fNPW.watchFactory = fakeOvnNode.watcher
 				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
-				err := fNPW.AddService(&service)
-				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fExec.ErrorDesc)
+				key := "External_namespace1_service1_1.1.1.1_tcp_80"
 
-				expectedTables := map[string]util.FakeTable{
-					"nat": {
-						"PREROUTING": []string{
-							"-j OVN-KUBE-ETP",
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-						},
-						"OUTPUT": []string{
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-							"-j OVN-KUBE-ITP",
-						},
-						"POSTROUTING": []string{
-							"-j OVN-KUBE-EGRESS-SVC",
-						},
-						"OVN-KUBE-NODEPORT": []string{
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, nodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-						},
-						"OVN-KUBE-EXTERNALIP":    []string{},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-ETP":           []string{},
-						"OVN-KUBE-ITP":           []string{},
-						"OVN-KUBE-EGRESS-SVC":    []string{},
-					},
-					"filter": {},
-					"mangle": {
-						"OUTPUT": []string{
-							"-j OVN-KUBE-ITP",
-						},
-						"OVN-KUBE-ITP": []string{},
-					},
-				}
+				// disabled by default
+				Expect(fNPW.AddService(&service)).To(Succeed())
+				Expect(fNPW.ofm.flowCache).NotTo(HaveKey(key))
 
-				f4 := iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
-				Expect(err).NotTo(HaveOccurred())
+				config.Gateway.HeadlessServiceExternalIPs = true
+				Expect(fNPW.AddService(&service)).To(Succeed())
+				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fakeOvnNode.fakeExec.ErrorDesc)
 
-				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 8080}, {"192.168.18.15", 38034}})
-				err = fNPW.DeleteService(&service)
+				cookie, err := svcToCookie("namespace1", "service1", "1.1.1.1", 80)
 				Expect(err).NotTo(HaveOccurred())
+				Expect(fNPW.ofm.flowCache[key]).To(Equal([]string{
+					fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=1.1.1.1, actions=output:LOCAL", cookie),
+					fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, tcp, nw_dst=1.1.1.1, tp_dst=80, actions=output:patch-breth0_ov", cookie),
+					fmt.Sprintf("cookie=%s, priority=110, in_port=patch-breth0_ov, tcp, nw_src=1.1.1.1, tp_src=80, actions=output:eth0", cookie),
+				}))
+				// headless services are still not cached
+				_, exists := fNPW.getServiceInfo(k8stypes.NamespacedName{Namespace: "namespace1", Name: "service1"})
+				Expect(exists).To(BeFalse())
+
+				// the flows follow the externalIPs
+				updated := service.DeepCopy()
+				updated.Spec.ExternalIPs = []string{"1.1.1.2"}
+				Expect(fNPW.UpdateService(&service, updated)).To(Succeed())
+				Expect(fNPW.ofm.flowCache).NotTo(HaveKey(key))
+				Expect(fNPW.ofm.flowCache).To(HaveKey("External_namespace1_service1_1.1.1.2_tcp_80"))
+
+				Expect(fNPW.DeleteService(updated)).To(Succeed())
+				Expect(fNPW.ofm.flowCache).To(BeEmpty())
 
-				expectedTables = map[string]util.FakeTable{
-					"nat": {
-						"OVN-KUBE-EXTERNALIP": []string{},
-						"OVN-KUBE-NODEPORT":   []string{},
-						"PREROUTING": []string{
-							"-j OVN-KUBE-ETP",
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-						},
-						"OUTPUT": []string{
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-							"-j OVN-KUBE-ITP",
-						},
-						"POSTROUTING": []string{
-							"-j OVN-KUBE-EGRESS-SVC",
-						},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-ETP":           []string{},
-						"OVN-KUBE-ITP":           []string{},
-						"OVN-KUBE-EGRESS-SVC":    []string{},
-					},
-					"filter": {},
-					"mangle": {
-						"OUTPUT": []string{
-							"-j OVN-KUBE-ITP",
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("does not cache a service without ports", func() {
+			app.Action = func(ctx *cli.Context) error {
+				config.Gateway.Mode = config.GatewayModeShared
+				service := *newService("service1", "namespace1", "10.129.0.2",
+					[]v1.ServicePort{},
+					v1.ServiceTypeClusterIP,
+					nil,
+					v1.ServiceStatus{},
+					false, false,
+				)
+				endpointSlice := *newEndpointSlice(
+					"service1",
+					"namespace1",
+					[]discovery.Endpoint{{Addresses: []string{"10.244.0.3"}}},
+					[]discovery.EndpointPort{})
+
+				fakeOvnNode.start(ctx,
+					&v1.ServiceList{
+						Items: []v1.Service{
+							service,
 						},
-						"OVN-KUBE-ITP": []string{},
 					},
-				}
+					&endpointSlice,
+				)
 
-				f4 = iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
-				Expect(err).NotTo(HaveOccurred())
+				fNPW.watchFactory = fakeOvnNode.watcher
+				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				Expect(fNPW.AddService(&service)).To(Succeed())
+				Expect(fNPW.AddEndpointSlice(&endpointSlice)).To(Succeed())
+
+				_, exists := fNPW.getServiceInfo(k8stypes.NamespacedName{Namespace: "namespace1", Name: "service1"})
+				Expect(exists).To(BeFalse())
+				Expect(fNPW.ofm.flowCache).To(BeEmpty())
 
 				return nil
 			}
@@ -1906,38 +3579,22 @@ var _ = Describe("Node Operations", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("manages iptables rules and openflows for NodePort backed by ovn-k pods where ETP=local, LGW", func() {
+		It("removes the rules and cache entry of a service losing all its ports", func() {
 			app.Action = func(ctx *cli.Context) error {
-				config.Gateway.Mode = config.GatewayModeLocal
-				epPortName := "https"
-				epPortValue := int32(443)
+				config.Gateway.Mode = config.GatewayModeShared
 				service := *newService("service1", "namespace1", "10.129.0.2",
 					[]v1.ServicePort{
 						{
 							NodePort: int32(31111),
 							Protocol: v1.ProtocolTCP,
 							Port:     int32(8080),
-						},
-					},
-					v1.ServiceTypeNodePort,
-					nil,
-					v1.ServiceStatus{},
-					true, false,
-				)
-				ep1 := discovery.Endpoint{
-					Addresses: []string{"10.244.0.3"},
-				}
-				epPort1 := discovery.EndpointPort{
-					Name: &epPortName,
-					Port: &epPortValue,
-				}
-				// endpointSlice.Endpoints is ovn-networked so this will come
-				// under !hasLocalHostNetEp case
-				endpointSlice := *newEndpointSlice(
-					"service1",
-					"namespace1",
-					[]discovery.Endpoint{ep1},
-					[]discovery.EndpointPort{epPort1})
+						},
+					},
+					v1.ServiceTypeNodePort,
+					nil,
+					v1.ServiceStatus{},
+					false, false,
+				)
 
 				fakeOvnNode.start(ctx,
 					&v1.ServiceList{
@@ -1945,98 +3602,31 @@ var _ = Describe("Node Operations", func() {
 							service,
 						},
 					},
-					&endpointSlice,
 				)
 
 				fNPW.watchFactory = fakeOvnNode.watcher
 				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
-				err := fNPW.AddService(&service)
-				Expect(err).NotTo(HaveOccurred())
-
-				expectedTables := map[string]util.FakeTable{
-					"nat": {
-						"PREROUTING": []string{
-							"-j OVN-KUBE-ETP",
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-						},
-						"OUTPUT": []string{
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-							"-j OVN-KUBE-ITP",
-						},
-						"POSTROUTING": []string{
-							"-j OVN-KUBE-EGRESS-SVC",
-						},
-						"OVN-KUBE-NODEPORT": []string{
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-						},
-						"OVN-KUBE-EXTERNALIP": []string{},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{
-							fmt.Sprintf("-p TCP --dport %v -j RETURN", service.Spec.Ports[0].NodePort),
-						},
-						"OVN-KUBE-ETP": []string{
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, types.V4HostETPLocalMasqueradeIP, service.Spec.Ports[0].NodePort),
-						},
-						"OVN-KUBE-ITP":        []string{},
-						"OVN-KUBE-EGRESS-SVC": []string{},
-					},
-					"filter": {},
-					"mangle": {
-						"OUTPUT": []string{
-							"-j OVN-KUBE-ITP",
-						},
-						"OVN-KUBE-ITP": []string{},
-					},
-				}
-
-				f4 := iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
-				Expect(err).NotTo(HaveOccurred())
-				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
-				Expect(flows).To(BeNil())
+				Expect(fNPW.AddService(&service)).To(Succeed())
 
-				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 8080}, {"192.168.18.15", 31111}})
-				err = fNPW.DeleteService(&service)
+				name := k8stypes.NamespacedName{Namespace: "namespace1", Name: "service1"}
+				_, exists := fNPW.getServiceInfo(name)
+				Expect(exists).To(BeTrue())
+				Expect(fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]).NotTo(BeEmpty())
+				nodePortRules, err := iptV4.List("nat", "OVN-KUBE-NODEPORT")
 				Expect(err).NotTo(HaveOccurred())
+				Expect(nodePortRules).NotTo(BeEmpty())
 
-				expectedTables = map[string]util.FakeTable{
-					"nat": {
-						"OVN-KUBE-EXTERNALIP": []string{},
-						"OVN-KUBE-NODEPORT":   []string{},
-						"PREROUTING": []string{
-							"-j OVN-KUBE-ETP",
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-						},
-						"OUTPUT": []string{
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-							"-j OVN-KUBE-ITP",
-						},
-						"POSTROUTING": []string{
-							"-j OVN-KUBE-EGRESS-SVC",
-						},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-ETP":           []string{},
-						"OVN-KUBE-ITP":           []string{},
-						"OVN-KUBE-EGRESS-SVC":    []string{},
-					},
-					"filter": {},
-					"mangle": {
-						"OUTPUT": []string{
-							"-j OVN-KUBE-ITP",
-						},
-						"OVN-KUBE-ITP": []string{},
-					},
-				}
+				portless := service.DeepCopy()
+				portless.Spec.Type = v1.ServiceTypeClusterIP
+				portless.Spec.Ports = nil
+				Expect(fNPW.UpdateService(&service, portless)).To(Succeed())
 
-				f4 = iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
+				_, exists = fNPW.getServiceInfo(name)
+				Expect(exists).To(BeFalse())
+				Expect(fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]).To(BeNil())
+				nodePortRules, err = iptV4.List("nat", "OVN-KUBE-NODEPORT")
 				Expect(err).NotTo(HaveOccurred())
-
-				flows = fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
-				Expect(flows).To(BeNil())
+				Expect(nodePortRules).To(BeEmpty())
 
 				return nil
 			}
@@ -2044,17 +3634,19 @@ var _ = Describe("Node Operations", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("manages iptables rules and openflows for NodePort backed by ovn-k pods where ETP=local, SGW", func() {
+		It("manages iptables rules and openflows for NodePort backed by local-host-networked pods where ETP=local, LGW", func() {
 			app.Action = func(ctx *cli.Context) error {
-				config.Gateway.Mode = config.GatewayModeShared
+				config.Gateway.Mode = config.GatewayModeLocal
+				outport := int32(443)
 				epPortName := "https"
 				epPortValue := int32(443)
 				service := *newService("service1", "namespace1", "10.129.0.2",
 					[]v1.ServicePort{
 						{
-							NodePort: int32(31111),
-							Protocol: v1.ProtocolTCP,
-							Port:     int32(8080),
+							NodePort:   int32(31111),
+							Protocol:   v1.ProtocolTCP,
+							Port:       int32(8080),
+							TargetPort: intstr.FromInt(int(outport)),
 						},
 					},
 					v1.ServiceTypeNodePort,
@@ -2064,14 +3656,15 @@ var _ = Describe("Node Operations", func() {
 				)
 
 				ep1 := discovery.Endpoint{
-					Addresses: []string{"10.244.0.3"},
+					Addresses: []string{"192.168.18.15"}, // host-networked endpoint local to this node
+					NodeName:  &fakeNodeName,
 				}
 				epPort1 := discovery.EndpointPort{
 					Name: &epPortName,
 					Port: &epPortValue,
 				}
-				// endpointSlice.Endpoints is ovn-networked so this will come
-				// under !hasLocalHostNetEp case
+				// endpointSlice.Endpoints is ovn-networked so this will
+				// come under !hasLocalHostNetEp case
 				endpointSlice := *newEndpointSlice(
 					"service1",
 					"namespace1",
@@ -2089,6 +3682,9 @@ var _ = Describe("Node Operations", func() {
 
 				fNPW.watchFactory = fakeOvnNode.watcher
 				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
+				// to ensure the endpoint is local-host-networked
+				res := fNPW.nodeIPManager.addresses.Has(ep1.Addresses[0])
+				Expect(res).To(BeTrue())
 				err := fNPW.AddService(&service)
 				Expect(err).NotTo(HaveOccurred())
 
@@ -2110,13 +3706,11 @@ var _ = Describe("Node Operations", func() {
 						"OVN-KUBE-NODEPORT": []string{
 							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
 						},
-						"OVN-KUBE-EXTERNALIP": []string{},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{
-							fmt.Sprintf("-p TCP --dport %v -j RETURN", service.Spec.Ports[0].NodePort),
-						},
-						"OVN-KUBE-ETP":        []string{},
-						"OVN-KUBE-ITP":        []string{},
-						"OVN-KUBE-EGRESS-SVC": []string{},
+						"OVN-KUBE-EXTERNALIP":    []string{},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{},
+						"OVN-KUBE-ETP":           []string{},
+						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-EGRESS-SVC":    []string{},
 					},
 					"filter": {},
 					"mangle": {
@@ -2127,9 +3721,10 @@ var _ = Describe("Node Operations", func() {
 					},
 				}
 				expectedFlows := []string{
-					// default
-					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=output:patch-breth0_ov",
-					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=patch-breth0_ov, tcp, tp_src=31111, actions=output:eth0",
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=ct(commit,zone=64003,nat(dst=10.244.0.1:443),table=6)",
+					"cookie=0xe745ecf105, priority=110, table=6, actions=output:LOCAL",
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=LOCAL, tcp, tp_src=443, actions=ct(zone=64003 nat,table=7)",
+					"cookie=0xe745ecf105, priority=110, table=7, actions=output:eth0",
 				}
 
 				f4 := iptV4.(*util.FakeIPTables)
@@ -2160,8 +3755,8 @@ var _ = Describe("Node Operations", func() {
 							"-j OVN-KUBE-EGRESS-SVC",
 						},
 						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-ETP":           []string{},
 						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-ETP":           []string{},
 						"OVN-KUBE-EGRESS-SVC":    []string{},
 					},
 					"filter": {},
@@ -2186,30 +3781,26 @@ var _ = Describe("Node Operations", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("manages iptables rules and openflows for NodePort backed by local-host-networked pods where ETP=local, LGW", func() {
+		It("manages iptables rules and openflows for NodePort backed by ovn-k pods where ITP=local and ETP=local", func() {
 			app.Action = func(ctx *cli.Context) error {
-				config.Gateway.Mode = config.GatewayModeLocal
-				outport := int32(443)
+				config.Gateway.Mode = config.GatewayModeShared
 				epPortName := "https"
 				epPortValue := int32(443)
 				service := *newService("service1", "namespace1", "10.129.0.2",
 					[]v1.ServicePort{
 						{
-							NodePort:   int32(31111),
-							Protocol:   v1.ProtocolTCP,
-							Port:       int32(8080),
-							TargetPort: intstr.FromInt(int(outport)),
+							NodePort: int32(31111),
+							Protocol: v1.ProtocolTCP,
+							Port:     int32(8080),
 						},
 					},
 					v1.ServiceTypeNodePort,
 					nil,
 					v1.ServiceStatus{},
-					true, false,
+					true, true,
 				)
-
 				ep1 := discovery.Endpoint{
-					Addresses: []string{"192.168.18.15"}, // host-networked endpoint local to this node
-					NodeName:  &fakeNodeName,
+					Addresses: []string{"10.244.0.3"},
 				}
 				epPort1 := discovery.EndpointPort{
 					Name: &epPortName,
@@ -2234,9 +3825,6 @@ var _ = Describe("Node Operations", func() {
 
 				fNPW.watchFactory = fakeOvnNode.watcher
 				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
-				// to ensure the endpoint is local-host-networked
-				res := fNPW.nodeIPManager.addresses.Has(ep1.Addresses[0])
-				Expect(res).To(BeTrue())
 				err := fNPW.AddService(&service)
 				Expect(err).NotTo(HaveOccurred())
 
@@ -2258,25 +3846,28 @@ var _ = Describe("Node Operations", func() {
 						"OVN-KUBE-NODEPORT": []string{
 							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
 						},
-						"OVN-KUBE-EXTERNALIP":    []string{},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-ETP":           []string{},
-						"OVN-KUBE-ITP":           []string{},
-						"OVN-KUBE-EGRESS-SVC":    []string{},
+						"OVN-KUBE-EXTERNALIP": []string{},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{
+							fmt.Sprintf("-p TCP --dport %v -j RETURN", service.Spec.Ports[0].NodePort),
+						},
+						"OVN-KUBE-ITP":        []string{},
+						"OVN-KUBE-ETP":        []string{},
+						"OVN-KUBE-EGRESS-SVC": []string{},
 					},
 					"filter": {},
 					"mangle": {
 						"OUTPUT": []string{
 							"-j OVN-KUBE-ITP",
 						},
-						"OVN-KUBE-ITP": []string{},
+						"OVN-KUBE-ITP": []string{
+							fmt.Sprintf("-p %s -d %s --dport %d -j MARK --set-xmark %s", service.Spec.Ports[0].Protocol, service.Spec.ClusterIP, service.Spec.Ports[0].Port, ovnkubeITPMark),
+						},
 					},
 				}
 				expectedFlows := []string{
-					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=ct(commit,zone=64003,nat(dst=10.244.0.1:443),table=6)",
-					"cookie=0xe745ecf105, priority=110, table=6, actions=output:LOCAL",
-					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=LOCAL, tcp, tp_src=443, actions=ct(zone=64003 nat,table=7)",
-					"cookie=0xe745ecf105, priority=110, table=7, actions=output:eth0",
+					// default
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=output:patch-breth0_ov",
+					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=patch-breth0_ov, tcp, tp_src=31111, actions=output:eth0",
 				}
 
 				f4 := iptV4.(*util.FakeIPTables)
@@ -2293,6 +3884,7 @@ var _ = Describe("Node Operations", func() {
 					"nat": {
 						"OVN-KUBE-EXTERNALIP": []string{},
 						"OVN-KUBE-NODEPORT":   []string{},
+						"OVN-KUBE-ITP":        []string{},
 						"PREROUTING": []string{
 							"-j OVN-KUBE-ETP",
 							"-j OVN-KUBE-EXTERNALIP",
@@ -2307,7 +3899,6 @@ var _ = Describe("Node Operations", func() {
 							"-j OVN-KUBE-EGRESS-SVC",
 						},
 						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-ITP":           []string{},
 						"OVN-KUBE-ETP":           []string{},
 						"OVN-KUBE-EGRESS-SVC":    []string{},
 					},
@@ -2333,7 +3924,7 @@ var _ = Describe("Node Operations", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("manages iptables rules and openflows for NodePort backed by ovn-k pods where ITP=local and ETP=local", func() {
+		It("manages iptables rules and management port routes without openflows for a ClusterIP service where ITP=local", func() {
 			app.Action = func(ctx *cli.Context) error {
 				config.Gateway.Mode = config.GatewayModeShared
 				epPortName := "https"
@@ -2341,15 +3932,14 @@ var _ = Describe("Node Operations", func() {
 				service := *newService("service1", "namespace1", "10.129.0.2",
 					[]v1.ServicePort{
 						{
-							NodePort: int32(31111),
 							Protocol: v1.ProtocolTCP,
 							Port:     int32(8080),
 						},
 					},
-					v1.ServiceTypeNodePort,
+					v1.ServiceTypeClusterIP,
 					nil,
 					v1.ServiceStatus{},
-					true, true,
+					false, true,
 				)
 				ep1 := discovery.Endpoint{
 					Addresses: []string{"10.244.0.3"},
@@ -2366,6 +3956,12 @@ var _ = Describe("Node Operations", func() {
 					[]discovery.Endpoint{ep1},
 					[]discovery.EndpointPort{epPort1})
 
+				// the ClusterIP is routed via the management port once, on the initial sync of the services
+				fakeOvnNode.fakeExec.AddFakeCmdsNoOutputNoError([]string{
+					"ip route replace table 7 10.129.0.2 via 10.1.1.1 dev ovn-k8s-mp0",
+				})
+				fNPW.svcViaMgmPortRoutes = newSvcViaMgmPortRoutes([]*net.IPNet{ovntest.MustParseIPNet("10.1.1.0/24")}, types.K8sMgmtIntfName)
+
 				fakeOvnNode.start(ctx,
 					&v1.ServiceList{
 						Items: []v1.Service{
@@ -2377,8 +3973,7 @@ var _ = Describe("Node Operations", func() {
 
 				fNPW.watchFactory = fakeOvnNode.watcher
 				Expect(startNodePortWatcher(fNPW, fakeOvnNode.fakeClient, &fakeMgmtPortConfig)).To(Succeed())
-				err := fNPW.AddService(&service)
-				Expect(err).NotTo(HaveOccurred())
+				Expect(fNPW.AddService(&service)).To(Succeed())
 
 				expectedTables := map[string]util.FakeTable{
 					"nat": {
@@ -2395,16 +3990,12 @@ var _ = Describe("Node Operations", func() {
 						"POSTROUTING": []string{
 							"-j OVN-KUBE-EGRESS-SVC",
 						},
-						"OVN-KUBE-NODEPORT": []string{
-							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
-						},
-						"OVN-KUBE-EXTERNALIP": []string{},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{
-							fmt.Sprintf("-p TCP --dport %v -j RETURN", service.Spec.Ports[0].NodePort),
-						},
-						"OVN-KUBE-ITP":        []string{},
-						"OVN-KUBE-ETP":        []string{},
-						"OVN-KUBE-EGRESS-SVC": []string{},
+						"OVN-KUBE-NODEPORT":      []string{},
+						"OVN-KUBE-EXTERNALIP":    []string{},
+						"OVN-KUBE-SNAT-MGMTPORT": []string{},
+						"OVN-KUBE-ITP":           []string{},
+						"OVN-KUBE-ETP":           []string{},
+						"OVN-KUBE-EGRESS-SVC":    []string{},
 					},
 					"filter": {},
 					"mangle": {
@@ -2416,59 +4007,28 @@ var _ = Describe("Node Operations", func() {
 						},
 					},
 				}
-				expectedFlows := []string{
-					// default
-					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=output:patch-breth0_ov",
-					"cookie=0x453ae29bcbbc08bd, priority=110, in_port=patch-breth0_ov, tcp, tp_src=31111, actions=output:eth0",
-				}
 
 				f4 := iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
-				Expect(err).NotTo(HaveOccurred())
-				flows := fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
-				Expect(flows).To(Equal(expectedFlows))
-
-				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 8080}, {"192.168.18.15", 31111}})
-				err = fNPW.DeleteService(&service)
-				Expect(err).NotTo(HaveOccurred())
+				Expect(f4.MatchState(expectedTables)).To(Succeed())
+				// no flows on the gateway bridge
+				Expect(fNPW.ofm.flowCache).To(BeEmpty())
 
-				expectedTables = map[string]util.FakeTable{
-					"nat": {
-						"OVN-KUBE-EXTERNALIP": []string{},
-						"OVN-KUBE-NODEPORT":   []string{},
-						"OVN-KUBE-ITP":        []string{},
-						"PREROUTING": []string{
-							"-j OVN-KUBE-ETP",
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-						},
-						"OUTPUT": []string{
-							"-j OVN-KUBE-EXTERNALIP",
-							"-j OVN-KUBE-NODEPORT",
-							"-j OVN-KUBE-ITP",
-						},
-						"POSTROUTING": []string{
-							"-j OVN-KUBE-EGRESS-SVC",
-						},
-						"OVN-KUBE-SNAT-MGMTPORT": []string{},
-						"OVN-KUBE-ETP":           []string{},
-						"OVN-KUBE-EGRESS-SVC":    []string{},
-					},
-					"filter": {},
-					"mangle": {
-						"OUTPUT": []string{
-							"-j OVN-KUBE-ITP",
-						},
-						"OVN-KUBE-ITP": []string{},
-					},
-				}
+				// the rules of the ITP chain are kept as is when the services are synced again
+				Expect(fNPW.SyncServices([]interface{}{&service})).To(Succeed())
+				Expect(f4.MatchState(expectedTables)).To(Succeed())
+				Expect(fNPW.ofm.flowCache).To(BeEmpty())
+				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fakeOvnNode.fakeExec.ErrorDesc)
 
-				f4 = iptV4.(*util.FakeIPTables)
-				err = f4.MatchState(expectedTables)
-				Expect(err).NotTo(HaveOccurred())
+				fakeOvnNode.fakeExec.AddFakeCmdsNoOutputNoError([]string{
+					"ip route del table 7 10.129.0.2 via 10.1.1.1 dev ovn-k8s-mp0",
+				})
+				addConntrackMocks(netlinkMock, []ctFilterDesc{{"10.129.0.2", 8080}})
+				Expect(fNPW.DeleteService(&service)).To(Succeed())
 
-				flows = fNPW.ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]
-				Expect(flows).To(BeNil())
+				expectedTables["mangle"]["OVN-KUBE-ITP"] = []string{}
+				Expect(f4.MatchState(expectedTables)).To(Succeed())
+				Expect(fNPW.ofm.flowCache).To(BeEmpty())
+				Expect(fakeOvnNode.fakeExec.CalledMatchesExpected()).To(BeTrue(), fakeOvnNode.fakeExec.ErrorDesc)
 
 				return nil
 			}
@@ -2543,6 +4103,8 @@ var _ = Describe("Node Operations", func() {
 							"-j OVN-KUBE-EGRESS-SVC",
 						},
 						"OVN-KUBE-NODEPORT": []string{
+							// the host-originated nodePort traffic goes straight to the local host-networked endpoint
+							fmt.Sprintf("-p %s -m addrtype --src-type LOCAL --dst-type LOCAL --dport %v -j REDIRECT --to-port %d", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, int32(service.Spec.Ports[0].TargetPort.IntValue())),
 							fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %v -j DNAT --to-destination %s:%v", service.Spec.Ports[0].Protocol, service.Spec.Ports[0].NodePort, service.Spec.ClusterIP, service.Spec.Ports[0].Port),
 						},
 						"OVN-KUBE-EXTERNALIP":    []string{},
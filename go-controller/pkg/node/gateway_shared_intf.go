@@ -1,16 +1,21 @@
 package node
 
 import (
+	"errors"
 	"fmt"
 	"hash/fnv"
 	"net"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/kube"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/metrics"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/node/controllers/egressservice"
 	nodeipt "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/node/iptables"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
@@ -26,18 +31,37 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	utilnet "k8s.io/utils/net"
+	"k8s.io/utils/pointer"
 )
 
 const (
 	// defaultOpenFlowCookie identifies default open flow rules added to the host OVS bridge.
 	// The hex number 0xdeff105, aka defflos, is meant to sound like default flows.
 	defaultOpenFlowCookie = "0xdeff105"
+	// The default flows of the following features use their own cookie, derived from defaultOpenFlowCookie
+	// with a per feature suffix, so that they can be told apart when dumping the bridge flows. The values
+	// must be kept stable as operators filter flows on them.
+	// geneveOpenFlowCookie identifies the default flows letting Geneve traffic bypass conntrack.
+	geneveOpenFlowCookie = defaultOpenFlowCookie + "01"
+	// hairpinOpenFlowCookie identifies the default flows handling service traffic hairpinned to the host.
+	hairpinOpenFlowCookie = defaultOpenFlowCookie + "02"
+	// egressIPOpenFlowCookie identifies the default flows of egress IP and egress service traffic.
+	egressIPOpenFlowCookie = defaultOpenFlowCookie + "03"
+	// bfdOpenFlowCookie identifies the default flows forwarding BFD traffic.
+	bfdOpenFlowCookie = defaultOpenFlowCookie + "04"
+	// conntrackOpenFlowCookie identifies the default flows committing connections to and matching them
+	// in the default conntrack zone.
+	conntrackOpenFlowCookie = defaultOpenFlowCookie + "05"
 	// etpSvcOpenFlowCookie identifies constant open flow rules added to the host OVS
 	// bridge to move packets between host and external for etp=local traffic.
 	// The hex number 0xe745ecf105, represents etp(e74)-service(5ec)-flows which makes it easier for debugging.
 	etpSvcOpenFlowCookie = "0xe745ecf105"
 	// ovsLocalPort is the name of the OVS bridge local port
 	ovsLocalPort = "LOCAL"
+	// ofPortHostUnavailable is the ofPortHost of a bridge whose DPU host
+	// representor has no valid ofport, the flows referencing it are not
+	// programmed as is, see withoutHostRepresentorFlows
+	ofPortHostUnavailable = "HOST_REPRESENTOR_UNAVAILABLE"
 	// ctMarkOVN is the conntrack mark value for OVN traffic
 	ctMarkOVN = "0x1"
 	// ctMarkHost is the conntrack mark value for host traffic
@@ -49,14 +73,45 @@ const (
 	// ovnkubeSvcViaMgmPortRT is the number of the custom routing table used to steer host->service
 	// traffic packets into OVN via ovn-k8s-mp0. Currently only used for ITP=local traffic.
 	ovnkubeSvcViaMgmPortRT = "7"
+	// ovnkubeExternalIPSrcMark is the fwmark of the external traffic towards the externalIPs of the
+	// ETP=cluster services routed into OVN via ovn-k8s-mp0, through ovnkubeSvcViaMgmPortRT, and not
+	// SNAT-ed there so that its source IP is preserved. See Gateway.ExternalIPSourcePreservation.
+	ovnkubeExternalIPSrcMark = "0x1745ed"
 	// ovnKubeNodeSNATMark is used to mark packets that need to be SNAT-ed to nodeIP for
 	// traffic originating from egressIP and egressService controlled pods towards other nodes in the cluster.
 	ovnKubeNodeSNATMark = "0x3f0"
+	// ovnExternalNameIPsAnnotation is the ExternalName service annotation listing, comma separated, the IPs
+	// its external name resolves to. The gateway bridge flows of an externalIP are programmed for each of them
+	// if Gateway.ExternalNameServiceIPs is set.
+	ovnExternalNameIPsAnnotation = "k8s.ovn.org/external-name-ips"
+	// ovnForceETPAnnotation is the service annotation overriding, for testing, the externalTrafficPolicy
+	// the gateway flows and rules of the service are generated for, "local" or "cluster". It is only
+	// honored if Gateway.AllowForceETPAnnotation is set.
+	ovnForceETPAnnotation = "k8s.ovn.org/force-etp"
+	// ovnFlowIdleTimeoutAnnotation and ovnFlowHardTimeoutAnnotation are the service annotations setting,
+	// in seconds, the idle_timeout and hard_timeout of the gateway bridge flows of the service, so that
+	// they expire if ovnkube misses the service delete. The expired flows of an existing service are
+	// added back by the next flow sync. The flows are permanent by default.
+	ovnFlowIdleTimeoutAnnotation = "k8s.ovn.org/flow-idle-timeout"
+	ovnFlowHardTimeoutAnnotation = "k8s.ovn.org/flow-hard-timeout"
+	// ovnExGWBridgeAnnotation is the service annotation that, set to "true", also programs the externalIP
+	// and LoadBalancer ingress IP flows of the service on the external gateway bridge. It is only honored
+	// if Gateway.ExGWBridgeServices is set.
+	ovnExGWBridgeAnnotation = "k8s.ovn.org/exgw-bridge"
+	// h2cAppProtocol is the appProtocol of the cleartext HTTP/2 service ports
+	h2cAppProtocol = "kubernetes.io/h2c"
+	// serviceFlowCacheKeyH2C is the trailing field of the flow cache keys of
+	// the h2c service ports, so that their flows can be told apart from the
+	// ones of other ports
+	serviceFlowCacheKeyH2C = "h2c"
 )
 
 var (
-	HostMasqCTZone     = config.Default.ConntrackZone + 1 //64001
-	OVNMasqCTZone      = HostMasqCTZone + 1               //64002
+	HostMasqCTZone = config.Default.ConntrackZone + 1 //64001
+	OVNMasqCTZone  = HostMasqCTZone + 1               //64002
+	// HostNodePortCTZone is the zone the service DNAT flows commit to. They
+	// never pass an alg= argument to ct(): OVS has no argument disabling a
+	// conntrack ALG helper, it only attaches one when requested.
 	HostNodePortCTZone = config.Default.ConntrackZone + 3 //64003
 )
 
@@ -72,21 +127,60 @@ func newNodePortWatcherIptables() *nodePortWatcherIptables {
 // nodePortWatcher manages OpenFlow and iptables rules
 // to ensure that services using NodePorts are accessible
 type nodePortWatcher struct {
-	dpuMode       bool
-	gatewayIPv4   string
-	gatewayIPv6   string
+	dpuMode     bool
+	gatewayIPv4 string
+	gatewayIPv6 string
+	// gatewayIPs holds all the IPs, with their subnet, of the gateway bridge
+	gatewayIPs []*net.IPNet
+	// gatewayIPLock also protects ofportPatch, which can be refreshed
 	gatewayIPLock sync.Mutex
 	ofportPhys    string
 	ofportPatch   string
-	gwBridge      string
+	// nodePortOfports, if set, restricts the NodePort traffic accepted on the
+	// gateway bridge to these physical ofports instead of ofportPhys
+	nodePortOfports []string
+	gwBridge        string
 	// Map of service name to programmed iptables/OF rules
 	serviceInfo     map[ktypes.NamespacedName]*serviceConfig
 	serviceInfoLock sync.Mutex
+	// deletedServices are the services deleted and not added back since, the
+	// events of their endpoint slices are ignored so that they do not
+	// re-create the rules of the services. Protected by serviceInfoLock.
+	deletedServices sets.Set[ktypes.NamespacedName]
 	ofm             *openflowManager
 	nodeIPManager   *addressManager
 	watchFactory    factory.NodeWatchFactory
+	// endpointSliceCoalescer, if set, merges endpoint slice updates of a
+	// service into a single recompute of its rules
+	endpointSliceCoalescer *endpointSliceCoalescer
+	// Map of service name to the flow path of its ingress traffic
+	serviceFlowPaths     map[ktypes.NamespacedName]serviceFlowPath
+	serviceFlowPathsLock sync.Mutex
+	// svcViaMgmPortRoutes, if set, routes the ClusterIPs of the ITP=local
+	// services via the management port instead of the service CIDRs
+	svcViaMgmPortRoutes *svcViaMgmPortRoutes
+	// endpointLocality decides which endpoints of the services are local to
+	// the node, e.g. by zone or label rather than by node name,
+	// util.IsEndpointOnNode if nil
+	endpointLocality util.EndpointLocalityFunc
+	// flowErrorReporter, if set, reports the flow generation errors of the
+	// services as events on them
+	flowErrorReporter *serviceFlowErrorReporter
 }
 
+// serviceFlowPath is the path taken by the ingress traffic of a service on
+// the gateway bridge, see updateServiceFlowCache
+type serviceFlowPath string
+
+const (
+	// serviceFlowPathHost is case1, traffic is steered to the host
+	serviceFlowPathHost serviceFlowPath = "host"
+	// serviceFlowPathOVN is case2, traffic is steered into OVN via the GR
+	serviceFlowPathOVN serviceFlowPath = "ovn"
+	// serviceFlowPathDefault is traffic left to the default flows
+	serviceFlowPathDefault serviceFlowPath = "default"
+)
+
 type serviceConfig struct {
 	// Contains the current service
 	service *kapi.Service
@@ -94,6 +188,8 @@ type serviceConfig struct {
 	hasLocalHostNetworkEp bool
 	// localEndpoints stores all the local non-host-networked endpoints for this service
 	localEndpoints sets.Set[string]
+	// lastEndpointChange is the time the last endpoint change of the service was processed
+	lastEndpointChange time.Time
 }
 
 type cidrAndFlags struct {
@@ -105,30 +201,583 @@ func (npw *nodePortWatcher) updateGatewayIPs(addressManager *addressManager) {
 	// Get Physical IPs of Node, Can be IPV4 IPV6 or both
 	addressManager.gatewayBridge.Lock()
 	gatewayIPv4, gatewayIPv6 := getGatewayFamilyAddrs(addressManager.gatewayBridge.ips)
+	gatewayIPs := append([]*net.IPNet{}, addressManager.gatewayBridge.ips...)
 	addressManager.gatewayBridge.Unlock()
 
 	npw.gatewayIPLock.Lock()
 	defer npw.gatewayIPLock.Unlock()
 	npw.gatewayIPv4 = gatewayIPv4
 	npw.gatewayIPv6 = gatewayIPv6
+	npw.gatewayIPs = gatewayIPs
+}
+
+// refreshOfportPatch updates the patch port ofport cached by the watcher if it
+// diverged from the one of the gateway bridge, as happens if ovn-controller
+// recreated the patch port in between both were looked up, and regenerates the
+// flows of all services with it. Returns true if the ofport was refreshed.
+func (npw *nodePortWatcher) refreshOfportPatch() bool {
+	npw.ofm.defaultBridge.Lock()
+	ofportPatch := npw.ofm.defaultBridge.ofPortPatch
+	npw.ofm.defaultBridge.Unlock()
+
+	npw.gatewayIPLock.Lock()
+	if ofportPatch == "" || npw.ofportPatch == ofportPatch {
+		npw.gatewayIPLock.Unlock()
+		return false
+	}
+	klog.Warningf("Patch port ofport of the node port watcher %s differs from the one of bridge %s %s, refreshing it",
+		npw.ofportPatch, npw.gwBridge, ofportPatch)
+	npw.ofportPatch = ofportPatch
+	npw.gatewayIPLock.Unlock()
+
+	if err := npw.regenerateServiceFlows(); err != nil {
+		klog.Errorf("Failed to regenerate the service flows with the patch port ofport %s: %v", ofportPatch, err)
+	}
+	npw.ofm.requestFlowSync()
+	return true
+}
+
+// regenerateServiceFlows regenerates the flows of all services. The service
+// cache is locked throughout so that the flows of a service concurrently
+// deleted are not added back.
+func (npw *nodePortWatcher) regenerateServiceFlows() error {
+	var errors []error
+	npw.serviceInfoLock.Lock()
+	defer npw.serviceInfoLock.Unlock()
+	for _, svcConfig := range npw.serviceInfo {
+		if err := npw.updateServiceFlowCache(svcConfig.service, false, svcConfig.hasLocalHostNetworkEp); err != nil {
+			errors = append(errors, err)
+		}
+		if err := npw.updateServiceFlowCache(svcConfig.service, true, svcConfig.hasLocalHostNetworkEp); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	return apierrors.NewAggregate(errors)
+}
+
+// getETPLocalDNATTarget returns the node IP of the given family that traffic
+// towards an ETP=local service is DNAT-ed to when the service has local host
+// networked endpoints. If the node has multiple IPs of that family, the one in
+// the same subnet as the given ingress IP (externalIP or LB ingress IP) is
+// preferred, as that is the one an external load balancer routes to. If no
+// ingress IP is given, as for NodePorts, the service LB ingress IPs are matched
+// instead. Defaults to the gateway IP of that family.
+// npw.gatewayIPLock must be held by the caller.
+func (npw *nodePortWatcher) getETPLocalDNATTarget(service *kapi.Service, ingressIP string, isIPv6 bool) string {
+	ingressIPs := []string{ingressIP}
+	if ingressIP == "" {
+		ingressIPs = ingressIPs[:0]
+		for _, ing := range service.Status.LoadBalancer.Ingress {
+			if len(ing.IP) > 0 {
+				ingressIPs = append(ingressIPs, ing.IP)
+			}
+		}
+	}
+	for _, ipStr := range ingressIPs {
+		ip := utilnet.ParseIPSloppy(ipStr)
+		if ip == nil || utilnet.IsIPv6(ip) != isIPv6 {
+			continue
+		}
+		for _, gatewayIP := range npw.gatewayIPs {
+			if gatewayIP.Contains(ip) {
+				return gatewayIP.IP.String()
+			}
+		}
+	}
+	if isIPv6 {
+		return npw.gatewayIPv6
+	}
+	return npw.gatewayIPv4
+}
+
+// serviceTrafficSteering is where the gateway bridge steers ingress traffic
+// towards a service
+type serviceTrafficSteering string
+
+const (
+	// serviceTrafficSteeringHost is traffic sent to the host, DNAT-ed to a
+	// host networked endpoint or handled by the host in LGW mode
+	serviceTrafficSteeringHost serviceTrafficSteering = "host"
+	// serviceTrafficSteeringOVN is traffic sent to OVN through the patch port
+	serviceTrafficSteeringOVN serviceTrafficSteering = "ovn"
+	// serviceTrafficSteeringDrop is traffic with no endpoint to be sent to
+	serviceTrafficSteeringDrop serviceTrafficSteering = "drop"
+)
+
+// getServiceTrafficSteering returns where ingress traffic from the given client
+// IP towards the service of the given serviceConfig is steered in the given
+// gateway mode, along with the reason for it. It mirrors the cases described in
+// updateServiceFlowCache and createLbAndExternalSvcFlows.
+func getServiceTrafficSteering(svcConfig *serviceConfig, gatewayMode config.GatewayMode, clientIP net.IP) (serviceTrafficSteering, string) {
+	if svcConfig == nil || svcConfig.service == nil {
+		return serviceTrafficSteeringDrop, "service not found"
+	}
+	service := svcConfig.service
+	if clientIP != nil && len(service.Spec.IPFamilies) > 0 {
+		family := kapi.IPv4Protocol
+		if utilnet.IsIPv6(clientIP) {
+			family = kapi.IPv6Protocol
+		}
+		served := false
+		for _, ipFamily := range service.Spec.IPFamilies {
+			if ipFamily == family {
+				served = true
+				break
+			}
+		}
+		if !served {
+			return serviceTrafficSteeringDrop, fmt.Sprintf("service does not serve %s", family)
+		}
+	}
+	if serviceExternalTrafficPolicyLocal(service) {
+		if svcConfig.hasLocalHostNetworkEp {
+			// case1
+			return serviceTrafficSteeringHost, "externalTrafficPolicy=local with local host networked endpoints"
+		}
+		if len(svcConfig.localEndpoints) == 0 {
+			return serviceTrafficSteeringDrop, "externalTrafficPolicy=local without local endpoints"
+		}
+	}
+	if gatewayMode == config.GatewayModeShared {
+		// case2
+		return serviceTrafficSteeringOVN, "shared gateway mode"
+	}
+	return serviceTrafficSteeringHost, "local gateway mode"
+}
+
+// isETPLocalWithoutLocalEndpoints returns true if a service has
+// externalTrafficPolicy=local and neither host networked nor non host
+// networked endpoints local to this node, its ingress traffic to this node
+// being dropped then
+func isETPLocalWithoutLocalEndpoints(svcConfig *serviceConfig) bool {
+	return serviceExternalTrafficPolicyLocal(svcConfig.service) &&
+		!svcConfig.hasLocalHostNetworkEp && len(svcConfig.localEndpoints) == 0
+}
+
+// countETPLocalServicesWithoutLocalEndpoints returns the number of services of
+// the serviceInfo cache with externalTrafficPolicy=local without local
+// endpoints, see isETPLocalWithoutLocalEndpoints
+func (npw *nodePortWatcher) countETPLocalServicesWithoutLocalEndpoints() int {
+	npw.serviceInfoLock.Lock()
+	defer npw.serviceInfoLock.Unlock()
+	count := 0
+	for _, svcConfig := range npw.serviceInfo {
+		if isETPLocalWithoutLocalEndpoints(svcConfig) {
+			count++
+		}
+	}
+	return count
+}
+
+// recordEndpointChange records that an endpoint change of the cached service
+// was processed, even if it did not change its local endpoints
+func (npw *nodePortWatcher) recordEndpointChange(name ktypes.NamespacedName) {
+	npw.serviceInfoLock.Lock()
+	defer npw.serviceInfoLock.Unlock()
+	if svcConfig, exists := npw.serviceInfo[name]; exists {
+		svcConfig.lastEndpointChange = time.Now()
+	}
+}
+
+// queryServiceEndpointChanges returns the last endpoint change of each cached
+// service, and whether all its endpoints are ready since
+func (npw *nodePortWatcher) queryServiceEndpointChanges() []metrics.ServiceEndpointChange {
+	lastChanges := map[ktypes.NamespacedName]time.Time{}
+	npw.serviceInfoLock.Lock()
+	for name, svcConfig := range npw.serviceInfo {
+		lastChanges[name] = svcConfig.lastEndpointChange
+	}
+	npw.serviceInfoLock.Unlock()
+	changes := make([]metrics.ServiceEndpointChange, 0, len(lastChanges))
+	for name, lastChange := range lastChanges {
+		converged := true
+		epSlices, err := npw.watchFactory.GetEndpointSlices(name.Namespace, name.Name)
+		if err != nil && !kerrors.IsNotFound(err) {
+			klog.Warningf("Failed to get the endpoint slices of service %s: %v", name, err)
+			converged = false
+		}
+		for _, epSlice := range epSlices {
+			for _, endpoint := range epSlice.Endpoints {
+				// a nil ready condition is to be interpreted as ready
+				if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+					converged = false
+				}
+			}
+		}
+		changes = append(changes, metrics.ServiceEndpointChange{
+			Service:    name.String(),
+			LastChange: lastChange,
+			Converged:  converged,
+		})
+	}
+	return changes
+}
+
+// countServiceIPTRules returns the number of gateway iptables rules programmed
+// for each cached service, none in DPU mode where iptables are not touched
+func (npw *nodePortWatcher) countServiceIPTRules() []metrics.ServiceIPTRuleCount {
+	if npw.dpuMode {
+		return nil
+	}
+	npw.serviceInfoLock.Lock()
+	defer npw.serviceInfoLock.Unlock()
+	counts := make([]metrics.ServiceIPTRuleCount, 0, len(npw.serviceInfo))
+	for name, svcConfig := range npw.serviceInfo {
+		rules := getGatewayIPTRules(svcConfig.service, sets.List(svcConfig.localEndpoints), svcConfig.hasLocalHostNetworkEp)
+		counts = append(counts, metrics.ServiceIPTRuleCount{Service: name.String(), Rules: len(rules)})
+	}
+	return counts
+}
+
+// queryServiceTrafficSteering returns where ingress traffic from the given
+// client IP towards the given service is steered, see getServiceTrafficSteering
+func (npw *nodePortWatcher) queryServiceTrafficSteering(namespace, name, clientIP string) (string, string, error) {
+	var ip net.IP
+	if clientIP != "" {
+		if ip = utilnet.ParseIPSloppy(clientIP); ip == nil {
+			return "", "", fmt.Errorf("invalid client IP %q", clientIP)
+		}
+	}
+	var svcConfig *serviceConfig
+	npw.serviceInfoLock.Lock()
+	if out, exists := npw.serviceInfo[ktypes.NamespacedName{Namespace: namespace, Name: name}]; exists {
+		// the cached serviceConfig is updated in place, copy it under the lock
+		svcConfig = &serviceConfig{
+			service:               out.service,
+			hasLocalHostNetworkEp: out.hasLocalHostNetworkEp,
+			localEndpoints:        out.localEndpoints.Clone(),
+		}
+	}
+	npw.serviceInfoLock.Unlock()
+	steering, reason := getServiceTrafficSteering(svcConfig, config.Gateway.Mode, ip)
+	return string(steering), reason, nil
+}
+
+// externalIPExposure is a service of the serviceInfo cache exposing an external
+// IP, as the externalIP or LoadBalancer ingress IP externalIPOrLBIngressIP
+type externalIPExposure struct {
+	svcConfig               *serviceConfig
+	ipType                  string
+	externalIPOrLBIngressIP string
+}
+
+// queryExternalIPOwnership returns whether this node programs the gateway
+// bridge flows of the ingress traffic towards an external IP, for each service
+// of the serviceInfo cache exposing it as an externalIP, possibly within an
+// externalIP CIDR, or as a LoadBalancer ingress IP
+func (npw *nodePortWatcher) queryExternalIPOwnership(externalIP string) (*metrics.ExternalIPOwnership, error) {
+	ip := utilnet.ParseIPSloppy(externalIP)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid external IP %q", externalIP)
+	}
+	var exposures []externalIPExposure
+	npw.serviceInfoLock.Lock()
+	for _, out := range npw.serviceInfo {
+		// the cached serviceConfig is updated in place, copy it under the lock
+		svcConfig := &serviceConfig{
+			service:               out.service,
+			hasLocalHostNetworkEp: out.hasLocalHostNetworkEp,
+		}
+		for _, svcExternalIP := range out.service.Spec.ExternalIPs {
+			if svcIP := utilnet.ParseIPSloppy(svcExternalIP); svcIP != nil && svcIP.Equal(ip) {
+				exposures = append(exposures, externalIPExposure{svcConfig, "External", normalizeExternalIP(svcExternalIP)})
+				break
+			}
+			if _, ipNet, err := net.ParseCIDR(svcExternalIP); err == nil && ipNet.Contains(ip) {
+				exposures = append(exposures, externalIPExposure{svcConfig, "External", normalizeExternalIP(svcExternalIP)})
+				break
+			}
+		}
+		for _, ing := range out.service.Status.LoadBalancer.Ingress {
+			if ingIP := utilnet.ParseIPSloppy(ing.IP); ingIP != nil && ingIP.Equal(ip) {
+				exposures = append(exposures, externalIPExposure{svcConfig, "Ingress", ingIP.String()})
+				break
+			}
+		}
+	}
+	npw.serviceInfoLock.Unlock()
+
+	ownership := &metrics.ExternalIPOwnership{IP: ip.String(), Services: []metrics.ExternalIPServiceOwnership{}}
+	for _, exposure := range exposures {
+		svcOwnership := npw.getExternalIPServiceOwnership(exposure, ip)
+		ownership.Programmed = ownership.Programmed || svcOwnership.Programmed
+		ownership.Services = append(ownership.Services, svcOwnership)
+	}
+	sort.Slice(ownership.Services, func(i, j int) bool {
+		if ownership.Services[i].Service != ownership.Services[j].Service {
+			return ownership.Services[i].Service < ownership.Services[j].Service
+		}
+		return ownership.Services[i].Type < ownership.Services[j].Type
+	})
+	return ownership, nil
+}
+
+// getExternalIPServiceOwnership returns whether this node programs the gateway
+// bridge flows of the ingress traffic towards an external IP of a service. It
+// mirrors the cases described in createLbAndExternalSvcFlows.
+func (npw *nodePortWatcher) getExternalIPServiceOwnership(exposure externalIPExposure, ip net.IP) metrics.ExternalIPServiceOwnership {
+	service := exposure.svcConfig.service
+	ownership := metrics.ExternalIPServiceOwnership{
+		Service: service.Namespace + "/" + service.Name,
+		Type:    exposure.ipType,
+	}
+	npw.gatewayIPLock.Lock()
+	ofportPhys, ofportPatch := npw.ofportPhys, npw.ofportPatch
+	npw.gatewayIPLock.Unlock()
+	if config.Gateway.Mode == config.GatewayModeLocal && config.Gateway.AllowNoUplink && ofportPhys == "" {
+		ownership.Reason = "local gateway mode without uplink, the traffic enters the host from the node interface"
+		return ownership
+	}
+	if exposure.ipType == "External" && config.Gateway.SkipNodeIPExternalIPs && npw.isNodeIP(exposure.externalIPOrLBIngressIP) {
+		ownership.Reason = "externalIP is an IP of the node"
+		return ownership
+	}
+	ownership.Programmed = true
+	ownership.InPort = ofportPhys
+	hasLocalHostNetworkEpV4, hasLocalHostNetworkEpV6 := npw.getLocalHostNetworkEpFamilies(service, true, exposure.svcConfig.hasLocalHostNetworkEp)
+	hasLocalHostNetworkEp := hasLocalHostNetworkEpV4
+	if utilnet.IsIPv6(ip) {
+		hasLocalHostNetworkEp = hasLocalHostNetworkEpV6
+	}
+	ownership.Bridge = npw.getExternalIPFlowsBridge(exposure, ip)
+	switch {
+	case serviceExternalTrafficPolicyLocal(service) && hasLocalHostNetworkEp:
+		// case1
+		ownership.OutPort = "LOCAL"
+		ownership.Reason = "externalTrafficPolicy=local with local host networked endpoints"
+	case config.Gateway.Mode == config.GatewayModeShared:
+		// case2
+		ownership.OutPort = ofportPatch
+		ownership.Reason = "shared gateway mode"
+	default:
+		ownership.OutPort = "LOCAL"
+		ownership.Reason = "local gateway mode, only the ARP bypass flow is programmed and the default flows send the traffic to the host"
+	}
+	return ownership
+}
+
+// getExternalIPFlowsBridge returns the name of the gateway bridge whose flow
+// cache holds the flows of an external IP of a service, for any of its ports,
+// and an empty string if they are not cached yet
+func (npw *nodePortWatcher) getExternalIPFlowsBridge(exposure externalIPExposure, ip net.IP) string {
+	if npw.ofm == nil {
+		return ""
+	}
+	service := exposure.svcConfig.service
+	for _, svcPort := range service.Spec.Ports {
+		flowProtocol := strings.ToLower(string(svcPort.Protocol))
+		if utilnet.IsIPv6(ip) {
+			flowProtocol += "6"
+		}
+		key := servicePortFlowCacheKey(exposure.ipType, &svcPort, service.Namespace, service.Name,
+			exposure.externalIPOrLBIngressIP, flowProtocol, fmt.Sprintf("%d", svcPort.Port))
+		if bridge, ok := npw.ofm.getFlowCacheBridge(key); ok {
+			return bridge
+		}
+	}
+	return ""
+}
+
+// getForcedETP returns the externalTrafficPolicy the ovnForceETPAnnotation of the service forces,
+// and false if it has none, it is invalid or it is not honored
+func getForcedETP(service *kapi.Service) (kapi.ServiceExternalTrafficPolicyType, bool) {
+	if !config.Gateway.AllowForceETPAnnotation {
+		return "", false
+	}
+	switch strings.ToLower(service.Annotations[ovnForceETPAnnotation]) {
+	case "local":
+		return kapi.ServiceExternalTrafficPolicyTypeLocal, true
+	case "cluster":
+		return kapi.ServiceExternalTrafficPolicyTypeCluster, true
+	}
+	return "", false
+}
+
+// serviceExternalTrafficPolicyLocal returns whether the gateway flows and rules of the service are
+// generated for externalTrafficPolicy=local, as forced by its ovnForceETPAnnotation or else as set
+// in its spec
+func serviceExternalTrafficPolicyLocal(service *kapi.Service) bool {
+	if etp, forced := getForcedETP(service); forced {
+		return etp == kapi.ServiceExternalTrafficPolicyTypeLocal
+	}
+	return util.ServiceExternalTrafficPolicyLocal(service)
+}
+
+// isExternalIPSourcePreserved returns whether the client source IP of the traffic towards the externalIPs of
+// the service is preserved, the traffic being steered to the host and routed into OVN via the management
+// port rather than sent to the gateway router, which SNATs it. See Gateway.ExternalIPSourcePreservation.
+func isExternalIPSourcePreserved(service *kapi.Service) bool {
+	return config.Gateway.ExternalIPSourcePreservation && config.Gateway.Mode == config.GatewayModeShared &&
+		len(service.Spec.ExternalIPs) > 0 && !serviceExternalTrafficPolicyLocal(service)
+}
+
+// logForcedETP logs the externalTrafficPolicy forced by the ovnForceETPAnnotation of the service, or
+// why the annotation is ignored
+func logForcedETP(service *kapi.Service) {
+	value, ok := service.Annotations[ovnForceETPAnnotation]
+	if !ok {
+		return
+	}
+	if etp, forced := getForcedETP(service); forced {
+		klog.Warningf("Forcing externalTrafficPolicy %s instead of %q for the gateway flows and rules of service %s/%s "+
+			"as set by its %s annotation, for testing only", etp, service.Spec.ExternalTrafficPolicy,
+			service.Namespace, service.Name, ovnForceETPAnnotation)
+	} else if !config.Gateway.AllowForceETPAnnotation {
+		klog.Warningf("Ignoring the %s annotation of service %s/%s: not allowed by the configuration",
+			ovnForceETPAnnotation, service.Namespace, service.Name)
+	} else {
+		klog.Warningf("Ignoring the invalid %s annotation %q of service %s/%s: expected local or cluster",
+			ovnForceETPAnnotation, value, service.Namespace, service.Name)
+	}
+}
+
+// getServiceFlowTimeouts returns the idle_timeout and hard_timeout fields the flows of the service are
+// programmed with, as set by its ovnFlowIdleTimeoutAnnotation and ovnFlowHardTimeoutAnnotation, and
+// an empty string if it sets none. Invalid timeouts are ignored, see logServiceFlowTimeouts.
+func getServiceFlowTimeouts(service *kapi.Service) string {
+	var fields []string
+	for _, timeout := range []struct{ annotation, field string }{
+		{ovnFlowIdleTimeoutAnnotation, "idle_timeout"},
+		{ovnFlowHardTimeoutAnnotation, "hard_timeout"},
+	} {
+		if seconds, err := parseFlowTimeout(service.Annotations[timeout.annotation]); err == nil && seconds > 0 {
+			fields = append(fields, fmt.Sprintf("%s=%d, ", timeout.field, seconds))
+		}
+	}
+	return strings.Join(fields, "")
+}
+
+// parseFlowTimeout parses a flow timeout annotation, 0 if it is empty
+func parseFlowTimeout(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 1 || seconds > 65535 {
+		return 0, fmt.Errorf("expected a number of seconds within 1-65535")
+	}
+	return seconds, nil
+}
+
+// logServiceFlowTimeouts logs why the flow timeout annotations of the service are ignored, if invalid
+func logServiceFlowTimeouts(service *kapi.Service) {
+	for _, annotation := range []string{ovnFlowIdleTimeoutAnnotation, ovnFlowHardTimeoutAnnotation} {
+		if _, err := parseFlowTimeout(service.Annotations[annotation]); err != nil {
+			klog.Warningf("Ignoring the invalid %s annotation %q of service %s/%s: %v",
+				annotation, service.Annotations[annotation], service.Namespace, service.Name, err)
+		}
+	}
+}
+
+// withFlowTimeouts returns the flows with the given timeout fields, see
+// getServiceFlowTimeouts, inserted after their cookie. The etp svc host flows
+// shared by all the services are left permanent.
+func withFlowTimeouts(flows []string, timeouts string) []string {
+	if timeouts == "" {
+		return flows
+	}
+	timedFlows := make([]string, 0, len(flows))
+	for _, flow := range flows {
+		cookie, rest, found := strings.Cut(flow, ", ")
+		if !found || cookie == "cookie="+etpSvcOpenFlowCookie {
+			timedFlows = append(timedFlows, flow)
+			continue
+		}
+		timedFlows = append(timedFlows, cookie+", "+timeouts+rest)
+	}
+	return timedFlows
+}
+
+// getServiceFlowPath returns the flow path of the ingress traffic of a
+// service, see updateServiceFlowCache
+func getServiceFlowPath(service *kapi.Service, hasLocalHostNetworkEp bool, gatewayMode config.GatewayMode) serviceFlowPath {
+	if serviceExternalTrafficPolicyLocal(service) && hasLocalHostNetworkEp {
+		return serviceFlowPathHost
+	}
+	if gatewayMode == config.GatewayModeShared {
+		return serviceFlowPathOVN
+	}
+	return serviceFlowPathDefault
+}
+
+// updateServiceFlowPath records the flow path of the ingress traffic of a
+// service, or forgets it if the service flows are removed, and updates the
+// service flow path metric
+func (npw *nodePortWatcher) updateServiceFlowPath(service *kapi.Service, add, hasLocalHostNetworkEp bool) {
+	npw.serviceFlowPathsLock.Lock()
+	defer npw.serviceFlowPathsLock.Unlock()
+	if npw.serviceFlowPaths == nil {
+		npw.serviceFlowPaths = map[ktypes.NamespacedName]serviceFlowPath{}
+	}
+	name := ktypes.NamespacedName{Namespace: service.Namespace, Name: service.Name}
+	if add {
+		npw.serviceFlowPaths[name] = getServiceFlowPath(service, hasLocalHostNetworkEp, config.Gateway.Mode)
+	} else {
+		delete(npw.serviceFlowPaths, name)
+	}
+	npw.publishServiceFlowPaths()
+}
+
+// resetServiceFlowPaths forgets the flow paths of all services so that they
+// are recomputed from scratch, as on a services sync
+func (npw *nodePortWatcher) resetServiceFlowPaths() {
+	npw.serviceFlowPathsLock.Lock()
+	defer npw.serviceFlowPathsLock.Unlock()
+	npw.serviceFlowPaths = map[ktypes.NamespacedName]serviceFlowPath{}
+	npw.publishServiceFlowPaths()
+}
+
+// publishServiceFlowPaths sets the service flow path metric from the recorded
+// flow paths, must be called with serviceFlowPathsLock held
+func (npw *nodePortWatcher) publishServiceFlowPaths() {
+	counts := map[serviceFlowPath]int{
+		serviceFlowPathHost:    0,
+		serviceFlowPathOVN:     0,
+		serviceFlowPathDefault: 0,
+	}
+	for _, path := range npw.serviceFlowPaths {
+		counts[path]++
+	}
+	// drop the series of a previous gateway mode
+	metrics.MetricServiceFlowPaths.Reset()
+	for path, count := range counts {
+		metrics.MetricServiceFlowPaths.WithLabelValues(string(path), string(config.Gateway.Mode)).Set(float64(count))
+	}
 }
 
 // updateServiceFlowCache handles managing breth0 gateway flows for ingress traffic towards kubernetes services
-// (nodeport, external, ingress). By default incoming traffic into the node is steered directly into OVN (case3 below).
+// (nodeport, external, ingress). By default incoming traffic into the node is steered directly into OVN (case2 below).
 //
 // case1: If a service has externalTrafficPolicy=local, and has host-networked endpoints local to the node (hasLocalHostNetworkEp),
-// traffic instead will be steered directly into the host and DNAT-ed to the targetPort on the host.
+// traffic instead will be steered directly into the host and DNAT-ed to the targetPort on the host. For PreferDualStack
+// services, this only applies to the IP families such endpoints exist for. If several such endpoints listen on distinct
+// ports, the NodePort traffic is distributed across them by a select group DNAT-ing to each of their ports.
 //
 // case2: All other types of services in SGW mode i.e:
 //
 //	case2a: if externalTrafficPolicy=cluster + SGW mode, traffic will be steered into OVN via GR.
 //	case2b: if externalTrafficPolicy=local + !hasLocalHostNetworkEp + SGW mode, traffic will be steered into OVN via GR.
+//	The patch port leads to the GR of this node, whose load balancer for externalTrafficPolicy=local only has the
+//	local cluster-networked endpoints and skips SNAT, so the traffic is not SNAT-ed on the bridge nor in OVN and the
+//	client source IP is preserved.
+//
+// case3: If Gateway.ExternalIPSourcePreservation is set, the traffic towards the externalIPs of the services with
+// externalTrafficPolicy=cluster in SGW mode is instead steered into the host, which DNATs it to the clusterIP and
+// policy routes it into OVN via ovn-k8s-mp0 without SNAT-ing it, preserving its source IP. The replies routed back
+// by the host are sent out the physical port. Not supported on DPUs.
 //
 // NOTE: If LGW mode, the default flow will take care of sending traffic to host irrespective of service flow type.
 //
+// Services that only expose ClusterIPs get no flows, see serviceHasGatewayFlows.
+//
 // `add` parameter indicates if the flows should exist or be removed from the cache
 // `hasLocalHostNetworkEp` indicates if at least one host networked endpoint exists for this service which is local to this node.
 func (npw *nodePortWatcher) updateServiceFlowCache(service *kapi.Service, add, hasLocalHostNetworkEp bool) error {
+	npw.updateServiceFlowPath(service, add, hasLocalHostNetworkEp)
+	if !serviceHasGatewayFlows(service) {
+		klog.V(5).Infof("Service %s/%s only exposes ClusterIPs, it has no flows on the gateway bridge", service.Namespace, service.Name)
+		return nil
+	}
 	if config.Gateway.Mode == config.GatewayModeLocal && config.Gateway.AllowNoUplink && npw.ofportPhys == "" {
 		// if LGW mode and no uplink gateway bridge, ingress traffic enters host from node physical interface instead of the breth0. Skip adding these service flows to br-ex.
 		return nil
@@ -139,29 +788,31 @@ func (npw *nodePortWatcher) updateServiceFlowCache(service *kapi.Service, add, h
 	var err error
 	var errors []error
 
-	isServiceTypeETPLocal := util.ServiceExternalTrafficPolicyLocal(service)
+	isServiceTypeETPLocal := serviceExternalTrafficPolicyLocal(service)
+	hasLocalHostNetworkEpV4, hasLocalHostNetworkEpV6 := npw.getLocalHostNetworkEpFamilies(service, add, hasLocalHostNetworkEp)
+	flowTimeouts := getServiceFlowTimeouts(service)
 
 	actions := fmt.Sprintf("output:%s", npw.ofportPatch)
+	// the ARP bypass flows of all the externalIPs and LB ingress IPs output to the same bridge ports
+	arpPorts := &arpBypassPorts{}
+
+	npw.updateHealthCheckNodePortFlows(service, add)
+	npw.updateExGWBridgeServiceFlows(service, add)
 
 	// cookie is only used for debugging purpose. so it is not fatal error if cookie is failed to be generated.
 	for _, svcPort := range service.Spec.Ports {
 		protocol := strings.ToLower(string(svcPort.Protocol))
+		// ports without a NodePort, like the ones of LoadBalancer services with allocateLoadBalancerNodePorts=false,
+		// only get the flows of the ingress and external IPs below
 		if svcPort.NodePort > 0 {
-			flowProtocols := []string{}
-			if config.IPv4Mode {
-				flowProtocols = append(flowProtocols, protocol)
-			}
-			if config.IPv6Mode {
-				flowProtocols = append(flowProtocols, protocol+"6")
-			}
-			for _, flowProtocol := range flowProtocols {
+			for _, flowProtocol := range serviceFlowProtocols(service, protocol) {
 				cookie, err = svcToCookie(service.Namespace, service.Name, flowProtocol, svcPort.NodePort)
 				if err != nil {
 					klog.Warningf("Unable to generate cookie for nodePort svc: %s, %s, %s, %d, error: %v",
 						service.Namespace, service.Name, flowProtocol, svcPort.Port, err)
 					cookie = "0"
 				}
-				key = strings.Join([]string{"NodePort", service.Namespace, service.Name, flowProtocol, fmt.Sprintf("%d", svcPort.NodePort)}, "_")
+				key = servicePortFlowCacheKey("NodePort", &svcPort, service.Namespace, service.Name, flowProtocol, fmt.Sprintf("%d", svcPort.NodePort))
 				// Delete if needed and skip to next protocol
 				if !add {
 					npw.ofm.deleteFlowsByKey(key)
@@ -171,45 +822,92 @@ func (npw *nodePortWatcher) updateServiceFlowCache(service *kapi.Service, add, h
 				// set to Local, and the backend pod is HostNetworked. We need to add
 				// Flows that will DNAT all traffic coming into nodeport to the nodeIP:Port and
 				// ensure that the return traffic is UnDNATed to correct the nodeIP:Nodeport
-				if isServiceTypeETPLocal && hasLocalHostNetworkEp {
+				if isServiceTypeETPLocal && (strings.Contains(flowProtocol, "6") && hasLocalHostNetworkEpV6 ||
+					!strings.Contains(flowProtocol, "6") && hasLocalHostNetworkEpV4) {
 					// case1 (see function description for details)
 					var nodeportFlows []string
 					klog.V(5).Infof("Adding flows on breth0 for Nodeport Service %s in Namespace: %s since ExternalTrafficPolicy=local", service.Name, service.Namespace)
-					// table 0, This rule matches on all traffic with dst port == NodePort, DNAT's the nodePort to the svc targetPort
+					isIPv6 := strings.Contains(flowProtocol, "6")
 					// If ipv6 make sure to choose the ipv6 node address for rule
-					if strings.Contains(flowProtocol, "6") {
-						nodeportFlows = append(nodeportFlows,
-							fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, tp_dst=%d, actions=ct(commit,zone=%d,nat(dst=[%s]:%s),table=6)",
-								cookie, npw.ofportPhys, flowProtocol, svcPort.NodePort, HostNodePortCTZone, npw.gatewayIPv6, svcPort.TargetPort.String()))
+					dnatTarget := npw.getETPLocalDNATTarget(service, "", isIPv6)
+					if isIPv6 {
+						dnatTarget = "[" + dnatTarget + "]"
+					}
+					var targetPorts []string
+					var dnatAction, group string
+					if epPorts := npw.getLocalHostNetworkEpPorts(service, &svcPort, isIPv6); len(epPorts) > 1 {
+						// several local host networked endpoints listen on distinct ports, distribute
+						// the traffic across them with a select group DNAT-ing to each of their ports
+						groupID := npw.ofm.getGroupID(key)
+						buckets := make([]string, 0, len(epPorts))
+						for _, epPort := range epPorts {
+							targetPorts = append(targetPorts, fmt.Sprintf("%d", epPort))
+							buckets = append(buckets, fmt.Sprintf("bucket=actions=ct(commit,zone=%d,nat(dst=%s:%d),table=6)",
+								HostNodePortCTZone, dnatTarget, epPort))
+						}
+						group = fmt.Sprintf("group_id=%d,type=select,%s", groupID, strings.Join(buckets, ","))
+						dnatAction = fmt.Sprintf("group:%d", groupID)
 					} else {
+						targetPort, err := getServiceTargetPort(&svcPort)
+						if err != nil {
+							klog.Errorf("Skipping the NodePort flows of service %s/%s: %v", service.Namespace, service.Name, err)
+							errors = append(errors, fmt.Errorf("failed to add the NodePort flows of service %s/%s: %w",
+								service.Namespace, service.Name, err))
+							npw.ofm.deleteFlowsByKey(key)
+							continue
+						}
+						targetPorts = []string{fmt.Sprintf("%d", targetPort)}
+						dnatAction = fmt.Sprintf("ct(commit,zone=%d,nat(dst=%s:%d),table=6)", HostNodePortCTZone, dnatTarget, targetPort)
+					}
+					// table 0, This rule matches on all traffic with dst port == NodePort, DNAT's the nodePort to the svc targetPort
+					for _, ofport := range npw.getNodePortOfports() {
+						for _, dstMatch := range nodePortAddressMatches(isIPv6) {
+							nodeportFlows = append(nodeportFlows,
+								fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, %stp_dst=%d, actions=%s",
+									cookie, ofport, flowProtocol, dstMatch, svcPort.NodePort, dnatAction))
+						}
+					}
+					hostFlows := sets.New[string]()
+					for _, targetPort := range targetPorts {
+						hostFlow, returnFlow := npw.etpSvcHostFlows(cookie, flowProtocol, targetPort, svcPort.NodePort)
+						// without per service cookies the host flows are shared by all the target ports
+						if !hostFlows.Has(hostFlow) {
+							// table 6, Sends the packet to the host
+							nodeportFlows = append(nodeportFlows, hostFlow)
+						}
 						nodeportFlows = append(nodeportFlows,
-							fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, tp_dst=%d, actions=ct(commit,zone=%d,nat(dst=%s:%s),table=6)",
-								cookie, npw.ofportPhys, flowProtocol, svcPort.NodePort, HostNodePortCTZone, npw.gatewayIPv4, svcPort.TargetPort.String()))
+							// table 0, Matches on return traffic, i.e traffic coming from the host networked pod's port, and unDNATs
+							etpSvcHostReturnFlow(cookie, flowProtocol, targetPort))
+						if !hostFlows.Has(hostFlow) {
+							// table 7, Sends the packet back out eth0 to the external client
+							nodeportFlows = append(nodeportFlows, returnFlow)
+						}
+						hostFlows.Insert(hostFlow)
+					}
+					nodeportFlows = withFlowTimeouts(nodeportFlows, flowTimeouts)
+					if group != "" {
+						npw.ofm.updateFlowCacheEntryWithGroup(key, nodeportFlows, group)
+					} else {
+						npw.ofm.updateFlowCacheEntry(key, nodeportFlows)
 					}
-					nodeportFlows = append(nodeportFlows,
-						// table 6, Sends the packet to the host. Note that the constant etp svc cookie is used since this flow would be
-						// same for all such services.
-						fmt.Sprintf("cookie=%s, priority=110, table=6, actions=output:LOCAL",
-							etpSvcOpenFlowCookie),
-						// table 0, Matches on return traffic, i.e traffic coming from the host networked pod's port, and unDNATs
-						fmt.Sprintf("cookie=%s, priority=110, in_port=LOCAL, %s, tp_src=%s, actions=ct(zone=%d nat,table=7)",
-							cookie, flowProtocol, svcPort.TargetPort.String(), HostNodePortCTZone),
-						// table 7, Sends the packet back out eth0 to the external client. Note that the constant etp svc
-						// cookie is used since this would be same for all such services.
-						fmt.Sprintf("cookie=%s, priority=110, table=7, "+
-							"actions=output:%s", etpSvcOpenFlowCookie, npw.ofportPhys))
-					npw.ofm.updateFlowCacheEntry(key, nodeportFlows)
 				} else if config.Gateway.Mode == config.GatewayModeShared {
 					// case2 (see function description for details)
-					npw.ofm.updateFlowCacheEntry(key, []string{
-						// table=0, matches on service traffic towards nodePort and sends it to OVN pipeline
-						fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, tp_dst=%d, "+
-							"actions=%s",
-							cookie, npw.ofportPhys, flowProtocol, svcPort.NodePort, actions),
+					var nodeportFlows []string
+					for _, ofport := range npw.getNodePortOfports() {
+						for _, dstMatch := range nodePortAddressMatches(strings.Contains(flowProtocol, "6")) {
+							// table=0, matches on service traffic towards nodePort and sends it to OVN pipeline
+							nodeportFlows = append(nodeportFlows,
+								fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, %stp_dst=%d, "+
+									"actions=%s",
+									cookie, ofport, flowProtocol, dstMatch, svcPort.NodePort, actions))
+						}
+					}
+					nodeportFlows = append(nodeportFlows,
 						// table=0, matches on return traffic from service nodePort and sends it out to primary node interface (br-ex)
 						fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, tp_src=%d, "+
 							"actions=output:%s",
-							cookie, npw.ofportPatch, flowProtocol, svcPort.NodePort, npw.ofportPhys)})
+							cookie, npw.ofportPatch, flowProtocol, svcPort.NodePort, npw.ofportPhys))
+					npw.ofm.updateFlowCacheEntry(key, withFlowTimeouts(nodeportFlows, flowTimeouts))
 				}
 			}
 		}
@@ -219,24 +917,215 @@ func (npw *nodePortWatcher) updateServiceFlowCache(service *kapi.Service, add, h
 		// NodePort/Ingress access in the OVS bridge will only ever come from outside of the host
 		for _, ing := range service.Status.LoadBalancer.Ingress {
 			if len(ing.IP) > 0 {
-				if err = npw.createLbAndExternalSvcFlows(service, &svcPort, add, hasLocalHostNetworkEp, protocol, actions, utilnet.ParseIPSloppy(ing.IP).String(), "Ingress"); err != nil {
+				hasLocalHostNetworkEpForIP := hasLocalHostNetworkEpV4
+				if utilnet.IsIPv6String(ing.IP) {
+					hasLocalHostNetworkEpForIP = hasLocalHostNetworkEpV6
+				}
+				if err = npw.createLbAndExternalSvcFlows(service, &svcPort, add, hasLocalHostNetworkEpForIP, protocol, actions, utilnet.ParseIPSloppy(ing.IP).String(), "Ingress", arpPorts); err != nil {
 					errors = append(errors, err)
 				}
 			}
 		}
 		// flows for externalIPs
 		for _, externalIP := range service.Spec.ExternalIPs {
-			if err = npw.createLbAndExternalSvcFlows(service, &svcPort, add, hasLocalHostNetworkEp, protocol, actions, utilnet.ParseIPSloppy(externalIP).String(), "External"); err != nil {
+			hasLocalHostNetworkEpForIP := hasLocalHostNetworkEpV4
+			if utilnet.IsIPv6String(externalIP) || utilnet.IsIPv6CIDRString(externalIP) {
+				hasLocalHostNetworkEpForIP = hasLocalHostNetworkEpV6
+			}
+			if err = npw.createLbAndExternalSvcFlows(service, &svcPort, add, hasLocalHostNetworkEpForIP, protocol, actions, normalizeExternalIP(externalIP), "External", arpPorts); err != nil {
 				errors = append(errors, err)
 			}
 		}
 	}
-	return apierrors.NewAggregate(errors)
+	err = apierrors.NewAggregate(errors)
+	if add && npw.flowErrorReporter != nil {
+		npw.flowErrorReporter.report(service, err)
+	}
+	return err
+}
 
+// updateHealthCheckNodePortFlows restricts the external traffic towards the
+// healthCheckNodePort of the service to the configured health check NodePort
+// addresses: the traffic destined to one of them goes through conntrack like
+// any other external traffic, the rest is dropped. The traffic of the families
+// without a configured address is not restricted and gets no flows.
+func (npw *nodePortWatcher) updateHealthCheckNodePortFlows(service *kapi.Service, add bool) {
+	if service.Spec.HealthCheckNodePort == 0 {
+		return
+	}
+	key := serviceFlowCacheKey("HealthCheckNodePort", service.Namespace, service.Name,
+		fmt.Sprintf("%d", service.Spec.HealthCheckNodePort))
+	addresses := config.Gateway.GetHealthCheckNodePortAddresses()
+	if !add || len(addresses) == 0 {
+		npw.ofm.deleteFlowsByKey(key)
+		return
+	}
+	cookie, err := svcToCookie(service.Namespace, service.Name, "tcp", service.Spec.HealthCheckNodePort)
+	if err != nil {
+		klog.Warningf("Unable to generate cookie for the healthCheckNodePort of svc: %s, %s, %d, error: %v",
+			service.Namespace, service.Name, service.Spec.HealthCheckNodePort, err)
+		cookie = "0"
+	}
+	var healthCheckFlows []string
+	for _, address := range addresses {
+		flowProtocol, dstField := "tcp", "nw_dst"
+		if utilnet.IsIPv6(address) {
+			flowProtocol, dstField = "tcp6", "ipv6_dst"
+		}
+		for _, ofport := range npw.getNodePortOfports() {
+			healthCheckFlows = append(healthCheckFlows,
+				// table=0, matches on the health checks towards the configured address
+				fmt.Sprintf("cookie=%s, priority=111, in_port=%s, %s, %s=%s, tp_dst=%d, actions=ct(zone=%d, nat, table=1)",
+					cookie, ofport, flowProtocol, dstField, address, service.Spec.HealthCheckNodePort,
+					config.Default.ConntrackZone),
+				// table=0, drops the health checks towards any other address
+				fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, tp_dst=%d, actions=drop",
+					cookie, ofport, flowProtocol, service.Spec.HealthCheckNodePort))
+		}
+	}
+	npw.ofm.updateFlowCacheEntry(key, healthCheckFlows)
+}
+
+// isExGWBridgeService returns true if the flows of the service are also to be
+// programmed on the external gateway bridge
+func isExGWBridgeService(service *kapi.Service) bool {
+	return config.Gateway.ExGWBridgeServices && service.Annotations[ovnExGWBridgeAnnotation] == "true"
+}
+
+// exGWBridgeFlowCacheKey returns the flow cache key of the flows of a service
+// on the given external gateway bridge, the key of the same flows on the
+// default bridge namespaced by the bridge name
+func exGWBridgeFlowCacheKey(bridgeName, key string) string {
+	return bridgeName + "_" + key
+}
+
+// updateExGWBridgeServiceFlows adds or removes the externalIP and LoadBalancer
+// ingress IP flows of the service on the external gateway bridge, if any. They
+// send the traffic entering its uplink to OVN through its patch port, like the
+// shared gateway flows of the default bridge, and the replies back out. The
+// traffic is never steered to the host from this bridge.
+func (npw *nodePortWatcher) updateExGWBridgeServiceFlows(service *kapi.Service, add bool) {
+	bridge := npw.ofm.externalGatewayBridge
+	if bridge == nil {
+		return
+	}
+	bridge.Lock()
+	bridgeName, ofportPhys, ofportPatch := bridge.bridgeName, bridge.ofPortPhys, bridge.ofPortPatch
+	bridge.Unlock()
+	add = add && isExGWBridgeService(service) && config.Gateway.Mode == config.GatewayModeShared
+	if add && (ofportPhys == "" || ofportPatch == "") {
+		klog.Warningf("Skipping the flows of service %s/%s on bridge %s: its uplink or patch port has no ofport",
+			service.Namespace, service.Name, bridgeName)
+		add = false
+	}
+	var ips, ipTypes []string
+	for _, ing := range service.Status.LoadBalancer.Ingress {
+		if ip := utilnet.ParseIPSloppy(ing.IP); ip != nil {
+			ips = append(ips, ip.String())
+			ipTypes = append(ipTypes, "Ingress")
+		}
+	}
+	for _, externalIP := range service.Spec.ExternalIPs {
+		ips = append(ips, normalizeExternalIP(externalIP))
+		ipTypes = append(ipTypes, "External")
+	}
+	flowTimeouts := getServiceFlowTimeouts(service)
+	for _, svcPort := range service.Spec.Ports {
+		protocol := strings.ToLower(string(svcPort.Protocol))
+		for i, ip := range ips {
+			flowProtocol, nwDst, nwSrc := protocol, "nw_dst", "nw_src"
+			if utilnet.IsIPv6String(ip) || utilnet.IsIPv6CIDRString(ip) {
+				flowProtocol, nwDst, nwSrc = protocol+"6", "ipv6_dst", "ipv6_src"
+			}
+			key := exGWBridgeFlowCacheKey(bridgeName, servicePortFlowCacheKey(ipTypes[i], &svcPort,
+				service.Namespace, service.Name, ip, flowProtocol, fmt.Sprintf("%d", svcPort.Port)))
+			if !add {
+				npw.ofm.deleteExBridgeFlowsByKey(key)
+				continue
+			}
+			cookie, err := svcToCookie(service.Namespace, service.Name, ip, svcPort.Port)
+			if err != nil {
+				klog.Warningf("Unable to generate cookie for %s svc: %s, %s, %s, %d, error: %v",
+					ipTypes[i], service.Namespace, service.Name, ip, svcPort.Port, err)
+				cookie = "0"
+			}
+			npw.ofm.updateExBridgeFlowCacheEntry(key, withFlowTimeouts([]string{
+				// table=0, matches on service traffic towards externalIP or LB ingress and sends it to OVN pipeline
+				fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, %s=%s, tp_dst=%d, actions=output:%s",
+					cookie, ofportPhys, flowProtocol, nwDst, ip, svcPort.Port, ofportPatch),
+				// table=0, matches on return traffic from service externalIP or LB ingress and sends it out the uplink
+				fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, %s=%s, tp_src=%d, actions=output:%s",
+					cookie, ofportPatch, flowProtocol, nwSrc, ip, svcPort.Port, ofportPhys),
+			}, flowTimeouts))
+		}
+	}
+}
+
+// nodePortAddressMatches returns the destination matches, each followed by a
+// separator, of the NodePort flows of the given family: a single empty match
+// if no NodePort addresses are configured, one match per configured address
+// of the family otherwise, none if no address of the family is configured
+func nodePortAddressMatches(isIPv6 bool) []string {
+	addresses := config.Gateway.GetNodePortAddresses()
+	if len(addresses) == 0 {
+		return []string{""}
+	}
+	var matches []string
+	for _, address := range addresses {
+		if utilnet.IsIPv6CIDR(address) != isIPv6 {
+			continue
+		}
+		if isIPv6 {
+			matches = append(matches, fmt.Sprintf("ipv6_dst=%s, ", address))
+		} else {
+			matches = append(matches, fmt.Sprintf("nw_dst=%s, ", address))
+		}
+	}
+	return matches
+}
+
+// serviceHasGatewayFlows returns true if the ingress traffic of the service enters through the gateway
+// bridge, towards a NodePort, an externalIP or a LoadBalancer ingress IP. The traffic towards the
+// ClusterIPs of a service, including the ITP=local ones that are handled by the OVN-KUBE-ITP rules and
+// the management port routes, never does.
+func serviceHasGatewayFlows(service *kapi.Service) bool {
+	if len(service.Spec.ExternalIPs) > 0 {
+		return true
+	}
+	for _, ing := range service.Status.LoadBalancer.Ingress {
+		if len(ing.IP) > 0 {
+			return true
+		}
+	}
+	for _, svcPort := range service.Spec.Ports {
+		if svcPort.NodePort > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceFlowProtocols returns the flow protocols of the enabled IP families
+// for the given lower case service port protocol, the one of the primary IP
+// family of the service, as set by spec.ipFamilies, first so that its flows
+// are programmed first. The priorities of the flows do not depend on the
+// family since the IPv4 and IPv6 flows never match the same packets.
+func serviceFlowProtocols(service *kapi.Service, protocol string) []string {
+	flowProtocols := []string{}
+	if config.IPv4Mode {
+		flowProtocols = append(flowProtocols, protocol)
+	}
+	if config.IPv6Mode {
+		flowProtocols = append(flowProtocols, protocol+"6")
+	}
+	if len(flowProtocols) > 1 && len(service.Spec.IPFamilies) > 0 && service.Spec.IPFamilies[0] == kapi.IPv6Protocol {
+		flowProtocols[0], flowProtocols[1] = flowProtocols[1], flowProtocols[0]
+	}
+	return flowProtocols
 }
 
 // createLbAndExternalSvcFlows handles managing breth0 gateway flows for ingress traffic towards kubernetes services
-// (externalIP and LoadBalancer types). By default incoming traffic into the node is steered directly into OVN (case3 below).
+// (externalIP and LoadBalancer types). By default incoming traffic into the node is steered directly into OVN (case2 below).
 //
 // case1: If a service has externalTrafficPolicy=local, and has host-networked endpoints local to the node (hasLocalHostNetworkEp),
 // traffic instead will be steered directly into the host and DNAT-ed to the targetPort on the host.
@@ -246,22 +1135,42 @@ func (npw *nodePortWatcher) updateServiceFlowCache(service *kapi.Service, add, h
 //	case2a: if externalTrafficPolicy=cluster + SGW mode, traffic will be steered into OVN via GR.
 //	case2b: if externalTrafficPolicy=local + !hasLocalHostNetworkEp + SGW mode, traffic will be steered into OVN via GR.
 //
+// case3: if Gateway.ExternalIPSourcePreservation is set, externalIP + externalTrafficPolicy=cluster + SGW mode, traffic
+// will be steered into the host, which routes it into OVN via ovn-k8s-mp0 with its source IP, see getGatewayIPTRules.
+//
 // NOTE: If LGW mode, the default flow will take care of sending traffic to host irrespective of service flow type.
 //
+// If Gateway.ServiceVLANID is set, the incoming service traffic is matched on that VLAN and untagged, and the
+// reply traffic is tagged with it before being sent out.
+//
+// SCTP ports get the same flows, matched on sctp/sctp6. SCTP multihoming is not supported: only the association
+// path towards the externalIP or LB ingress IP is steered, the other addresses a multihomed client or endpoint
+// advertises in its INIT/INIT-ACK chunks are neither matched nor NAT-ed, so the association can't fail over to them.
+//
 // `add` parameter indicates if the flows should exist or be removed from the cache
 // `hasLocalHostNetworkEp` indicates if at least one host networked endpoint exists for this service which is local to this node.
 // `protocol` is TCP/UDP/SCTP as set in the svc.Port
 // `actions`: "send to patchport"
-// `externalIPOrLBIngressIP` is either externalIP.IP or LB.status.ingress.IP
+// `externalIPOrLBIngressIP` is either externalIP.IP or LB.status.ingress.IP. An externalIP can also be a CIDR, in
+// which case a single set of flows matching the whole range is programmed.
 // `ipType` is either "External" or "Ingress"
-func (npw *nodePortWatcher) createLbAndExternalSvcFlows(service *kapi.Service, svcPort *kapi.ServicePort, add bool, hasLocalHostNetworkEp bool, protocol string, actions string, externalIPOrLBIngressIP string, ipType string) error {
-	if net.ParseIP(externalIPOrLBIngressIP) == nil {
+// `arpPorts` caches the bridge ports the ARP bypass flows output to across the IPs of the service, looked up
+// on each call if nil
+func (npw *nodePortWatcher) createLbAndExternalSvcFlows(service *kapi.Service, svcPort *kapi.ServicePort, add bool, hasLocalHostNetworkEp bool, protocol string, actions string, externalIPOrLBIngressIP string, ipType string, arpPorts *arpBypassPorts) error {
+	ip := net.ParseIP(externalIPOrLBIngressIP)
+	if ip == nil && ipType == "External" {
+		var ipNet *net.IPNet
+		if _, ipNet, _ = net.ParseCIDR(externalIPOrLBIngressIP); ipNet != nil && ipNet.String() == externalIPOrLBIngressIP {
+			ip = ipNet.IP
+		}
+	}
+	if ip == nil {
 		return fmt.Errorf("failed to parse %s IP: %q", ipType, externalIPOrLBIngressIP)
 	}
 	flowProtocol := protocol
 	nwDst := "nw_dst"
 	nwSrc := "nw_src"
-	if utilnet.IsIPv6String(externalIPOrLBIngressIP) {
+	if utilnet.IsIPv6(ip) {
 		flowProtocol = protocol + "6"
 		nwDst = "ipv6_dst"
 		nwSrc = "ipv6_src"
@@ -272,86 +1181,398 @@ func (npw *nodePortWatcher) createLbAndExternalSvcFlows(service *kapi.Service, s
 			ipType, service.Namespace, service.Name, externalIPOrLBIngressIP, svcPort.Port, err)
 		cookie = "0"
 	}
-	key := strings.Join([]string{ipType, service.Namespace, service.Name, externalIPOrLBIngressIP, fmt.Sprintf("%d", svcPort.Port)}, "_")
+	// the protocol is part of the key as the same port may be exposed on different protocols
+	key := servicePortFlowCacheKey(ipType, svcPort, service.Namespace, service.Name, externalIPOrLBIngressIP, flowProtocol, fmt.Sprintf("%d", svcPort.Port))
 	// Delete if needed and skip to next protocol
 	if !add {
 		npw.ofm.deleteFlowsByKey(key)
 		return nil
 	}
-	// add the ARP bypass flow regardless of service type or gateway modes since its applicable in all scenarios.
-	arpFlow := npw.generateArpBypassFlow(protocol, externalIPOrLBIngressIP, cookie)
-	externalIPFlows := []string{arpFlow}
+	if ipType == "External" && npw.isNodeIP(externalIPOrLBIngressIP) {
+		if config.Gateway.SkipNodeIPExternalIPs {
+			klog.Warningf("Skipping the flows of externalIP %s of service %s/%s as it is an IP of the node",
+				externalIPOrLBIngressIP, service.Namespace, service.Name)
+			npw.ofm.deleteFlowsByKey(key)
+			return nil
+		}
+		klog.Warningf("ExternalIP %s of service %s/%s is an IP of the node, its flows might conflict with the traffic towards the node",
+			externalIPOrLBIngressIP, service.Namespace, service.Name)
+	}
+	externalIPFlows := []string{}
+	// add the ARP bypass flow regardless of service type or gateway modes since its applicable in all scenarios,
+	// unless OVN alone is configured to answer for the IPs.
+	if !config.Gateway.DisableARPBypassFlows {
+		externalIPFlows = append(externalIPFlows, npw.generateArpBypassFlow(protocol, externalIPOrLBIngressIP, cookie, arpPorts))
+	}
 	// This allows external traffic ingress when the svc's ExternalTrafficPolicy is
 	// set to Local, and the backend pod is HostNetworked. We need to add
 	// Flows that will DNAT all external traffic destined for the lb/externalIP service
 	// to the nodeIP / nodeIP:port of the host networked backend.
 	// And then ensure that return traffic is UnDNATed correctly back
 	// to the ingress / external IP
-	isServiceTypeETPLocal := util.ServiceExternalTrafficPolicyLocal(service)
+	vlanMatch, popVLAN, pushVLAN := serviceVLANFlowParts()
+	isServiceTypeETPLocal := serviceExternalTrafficPolicyLocal(service)
 	if isServiceTypeETPLocal && hasLocalHostNetworkEp {
 		// case1 (see function description for details)
+		targetPort, err := getServiceTargetPort(svcPort)
+		if err != nil {
+			klog.Errorf("Skipping the %s flows of service %s/%s for %s: %v", ipType, service.Namespace, service.Name, externalIPOrLBIngressIP, err)
+			npw.ofm.deleteFlowsByKey(key)
+			return fmt.Errorf("failed to add the %s flows of service %s/%s for %s: %w", ipType, service.Namespace, service.Name, externalIPOrLBIngressIP, err)
+		}
+		// the DNAT target must be a gateway IP of the same family as the externalIP / LB ingress IP, which a
+		// dual-stack service may have in a family other than the node's primary one
+		isIPv6 := utilnet.IsIPv6(ip)
+		dnatTarget := npw.getETPLocalDNATTarget(service, ip.String(), isIPv6)
+		if dnatTarget == "" {
+			family := "IPv4"
+			if isIPv6 {
+				family = "IPv6"
+			}
+			klog.Errorf("Skipping the %s flows of service %s/%s for %s: the node has no %s gateway IP to DNAT to",
+				ipType, service.Namespace, service.Name, externalIPOrLBIngressIP, family)
+			npw.ofm.deleteFlowsByKey(key)
+			return nil
+		}
 		klog.V(5).Infof("Adding flows on breth0 for %s Service %s in Namespace: %s since ExternalTrafficPolicy=local", ipType, service.Name, service.Namespace)
 		// table 0, This rule matches on all traffic with dst ip == LoadbalancerIP / externalIP, DNAT's the nodePort to the svc targetPort
 		// If ipv6 make sure to choose the ipv6 node address for rule
-		if strings.Contains(flowProtocol, "6") {
+		if isIPv6 {
 			externalIPFlows = append(externalIPFlows,
-				fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, %s=%s, tp_dst=%d, actions=ct(commit,zone=%d,nat(dst=[%s]:%s),table=6)",
-					cookie, npw.ofportPhys, flowProtocol, nwDst, externalIPOrLBIngressIP, svcPort.Port, HostNodePortCTZone, npw.gatewayIPv6, svcPort.TargetPort.String()))
+				fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s%s, %s=%s, tp_dst=%d, actions=%sct(commit,zone=%d,nat(dst=[%s]:%d),table=6)",
+					cookie, npw.ofportPhys, vlanMatch, flowProtocol, nwDst, externalIPOrLBIngressIP, svcPort.Port, popVLAN, HostNodePortCTZone, dnatTarget, targetPort))
 		} else {
 			externalIPFlows = append(externalIPFlows,
-				fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, %s=%s, tp_dst=%d, actions=ct(commit,zone=%d,nat(dst=%s:%s),table=6)",
-					cookie, npw.ofportPhys, flowProtocol, nwDst, externalIPOrLBIngressIP, svcPort.Port, HostNodePortCTZone, npw.gatewayIPv4, svcPort.TargetPort.String()))
+				fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s%s, %s=%s, tp_dst=%d, actions=%sct(commit,zone=%d,nat(dst=%s:%d),table=6)",
+					cookie, npw.ofportPhys, vlanMatch, flowProtocol, nwDst, externalIPOrLBIngressIP, svcPort.Port, popVLAN, HostNodePortCTZone, dnatTarget, targetPort))
 		}
+		if pushVLAN != "" {
+			// table 7, the unDNAT-ed reply traffic of the service is tagged before
+			// being sent back out eth0, ahead of the untagged returnFlow below
+			externalIPFlows = append(externalIPFlows,
+				fmt.Sprintf("cookie=%s, priority=111, table=7, %s, %s=%s, tp_src=%d, actions=%soutput:%s",
+					cookie, flowProtocol, nwSrc, externalIPOrLBIngressIP, svcPort.Port, pushVLAN, npw.ofportPhys))
+		}
+		hostFlow, returnFlow := npw.etpSvcHostFlows(cookie, flowProtocol, fmt.Sprintf("%d", targetPort), svcPort.Port)
 		externalIPFlows = append(externalIPFlows,
-			// table 6, Sends the packet to Host. Note that the constant etp svc cookie is used since this flow would be
-			// same for all such services.
-			fmt.Sprintf("cookie=%s, priority=110, table=6, actions=output:LOCAL",
-				etpSvcOpenFlowCookie),
+			// table 6, Sends the packet to Host
+			hostFlow,
 			// table 0, Matches on return traffic, i.e traffic coming from the host networked pod's port, and unDNATs
-			fmt.Sprintf("cookie=%s, priority=110, in_port=LOCAL, %s, tp_src=%s, actions=ct(commit,zone=%d nat,table=7)",
-				cookie, flowProtocol, svcPort.TargetPort.String(), HostNodePortCTZone),
-			// table 7, Sends the reply packet back out eth0 to the external client. Note that the constant etp svc
-			// cookie is used since this would be same for all such services.
-			fmt.Sprintf("cookie=%s, priority=110, table=7, actions=output:%s",
-				etpSvcOpenFlowCookie, npw.ofportPhys))
+			etpSvcHostReturnFlow(cookie, flowProtocol, fmt.Sprintf("%d", targetPort)),
+			// table 7, Sends the reply packet back out eth0 to the external client
+			returnFlow)
+	} else if ipType == "External" && !npw.dpuMode && isExternalIPSourcePreserved(service) {
+		// case3 (see function description for details)
+		klog.V(5).Infof("Adding flows on breth0 steering the traffic of externalIP %s of Service %s in Namespace: %s to the host to preserve its source IP",
+			externalIPOrLBIngressIP, service.Name, service.Namespace)
+		externalIPFlows = append(externalIPFlows,
+			// table=0, matches on service traffic towards externalIP and sends it to the host, which routes it into OVN
+			fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s%s, %s=%s, tp_dst=%d, "+
+				"actions=%soutput:%s",
+				cookie, npw.ofportPhys, vlanMatch, flowProtocol, nwDst, externalIPOrLBIngressIP, svcPort.Port, popVLAN, ovsLocalPort),
+			// table=0, matches on return traffic from service externalIP routed by the host and sends it out to primary node interface (br-ex)
+			fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, %s=%s, tp_src=%d, "+
+				"actions=%soutput:%s",
+				cookie, ovsLocalPort, flowProtocol, nwSrc, externalIPOrLBIngressIP, svcPort.Port, pushVLAN, npw.ofportPhys))
 	} else if config.Gateway.Mode == config.GatewayModeShared {
 		// case2 (see function description for details)
 		externalIPFlows = append(externalIPFlows,
 			// table=0, matches on service traffic towards externalIP or LB ingress and sends it to OVN pipeline
-			fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, %s=%s, tp_dst=%d, "+
-				"actions=%s",
-				cookie, npw.ofportPhys, flowProtocol, nwDst, externalIPOrLBIngressIP, svcPort.Port, actions),
+			fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s%s, %s=%s, tp_dst=%d, "+
+				"actions=%s%s",
+				cookie, npw.ofportPhys, vlanMatch, flowProtocol, nwDst, externalIPOrLBIngressIP, svcPort.Port, popVLAN, actions),
 			// table=0, matches on return traffic from service externalIP or LB ingress and sends it out to primary node interface (br-ex)
 			fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, %s=%s, tp_src=%d, "+
-				"actions=output:%s",
-				cookie, npw.ofportPatch, flowProtocol, nwSrc, externalIPOrLBIngressIP, svcPort.Port, npw.ofportPhys))
+				"actions=%soutput:%s",
+				cookie, npw.ofportPatch, flowProtocol, nwSrc, externalIPOrLBIngressIP, svcPort.Port, pushVLAN, npw.ofportPhys))
 	}
-	npw.ofm.updateFlowCacheEntry(key, externalIPFlows)
+	npw.ofm.updateFlowCacheEntry(key, withFlowTimeouts(externalIPFlows, getServiceFlowTimeouts(service)))
 
 	return nil
 }
 
+// getServiceTargetPort returns the target port of a service port the host
+// DNAT flows are built with. It fails if the target port is not a port number
+// within 1-65535, like an unresolved named port, which would make an invalid
+// flow.
+func getServiceTargetPort(svcPort *kapi.ServicePort) (int32, error) {
+	targetPort := svcPort.TargetPort.IntValue()
+	if targetPort < 1 || targetPort > 65535 {
+		return 0, fmt.Errorf("invalid target port %q of service port %s/%d: expect a port number within 1-65535",
+			svcPort.TargetPort.String(), svcPort.Protocol, svcPort.Port)
+	}
+	return int32(targetPort), nil
+}
+
+// serviceVLANFlowParts returns the match on the VLAN tag of the externalIP and
+// LoadBalancer service traffic, and the actions popping it from the incoming
+// traffic and pushing it on the reply traffic. All of them are empty if
+// Gateway.ServiceVLANID is not set, the traffic is then untagged.
+func serviceVLANFlowParts() (string, string, string) {
+	if config.Gateway.ServiceVLANID == 0 {
+		return "", "", ""
+	}
+	return fmt.Sprintf("dl_vlan=%d, ", config.Gateway.ServiceVLANID), "strip_vlan,",
+		fmt.Sprintf("mod_vlan_vid:%d,", config.Gateway.ServiceVLANID)
+}
+
+// isNodeIP returns true if ip is one of the IPs of the node
+func (npw *nodePortWatcher) isNodeIP(ip string) bool {
+	if npw.nodeIPManager == nil {
+		return false
+	}
+	for _, nodeIP := range npw.nodeIPManager.ListAddresses() {
+		if nodeIP.String() == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// etpSvcHostFlows returns the table 6 flow sending the DNAT-ed service traffic
+// to the host and the table 7 flow sending the unDNAT-ed reply traffic back out
+// to the external client, for services with externalTrafficPolicy=local and
+// local host networked endpoints. By default these flows are the same for all
+// such services and use the constant etp svc cookie. If
+// Gateway.PerServiceETPCookies is set, they instead match on the service
+// traffic and use the service cookie, which allows to tell services apart when
+// debugging at the cost of a pair of flows per service port.
+func (npw *nodePortWatcher) etpSvcHostFlows(cookie, flowProtocol, targetPort string, port int32) (string, string) {
+	if !config.Gateway.PerServiceETPCookies {
+		return fmt.Sprintf("cookie=%s, priority=110, table=6, actions=output:LOCAL", etpSvcOpenFlowCookie),
+			fmt.Sprintf("cookie=%s, priority=110, table=7, actions=output:%s", etpSvcOpenFlowCookie, npw.ofportPhys)
+	}
+	return fmt.Sprintf("cookie=%s, priority=110, table=6, %s, tp_dst=%s, actions=output:LOCAL",
+			cookie, flowProtocol, targetPort),
+		fmt.Sprintf("cookie=%s, priority=110, table=7, %s, tp_src=%d, actions=output:%s",
+			cookie, flowProtocol, port, npw.ofportPhys)
+}
+
+// etpSvcHostReturnFlow returns the table 0 flow unDNAT-ing the reply traffic from the targetPort of the
+// local host networked endpoints of an ETP=local NodePort, externalIP or LB ingress IP. The connection is
+// committed by the DNAT flow of the request direction, so the reply only has to be looked up in
+// HostNodePortCTZone. It is not committed: that would also create conntrack entries for any other host
+// traffic sent from that port, while the reply of a committed connection needs no commit to be unDNAT-ed.
+func etpSvcHostReturnFlow(cookie, flowProtocol, targetPort string) string {
+	return fmt.Sprintf("cookie=%s, priority=110, in_port=LOCAL, %s, tp_src=%s, actions=ct(zone=%d nat,table=7)",
+		cookie, flowProtocol, targetPort, HostNodePortCTZone)
+}
+
+// isPreferDualStackService returns true if the service has the PreferDualStack
+// IP family policy
+func isPreferDualStackService(service *kapi.Service) bool {
+	return service.Spec.IPFamilyPolicy != nil && *service.Spec.IPFamilyPolicy == kapi.IPFamilyPolicyPreferDualStack
+}
+
+// getLocalHostNetworkEpPorts returns the sorted distinct ports the host
+// networked endpoints local to this node of the given IP family serve a port
+// of a service on. Several such endpoints can only coexist listening on
+// distinct ports, as they share the node IP.
+func (npw *nodePortWatcher) getLocalHostNetworkEpPorts(service *kapi.Service, svcPort *kapi.ServicePort, isIPv6 bool) []int32 {
+	if npw.watchFactory == nil {
+		return nil
+	}
+	epSlices, err := npw.watchFactory.GetEndpointSlices(service.Namespace, service.Name)
+	if err != nil {
+		klog.Warningf("Unable to get the endpointslices of service %s/%s, assuming a single local host networked endpoint: %v",
+			service.Namespace, service.Name, err)
+		return nil
+	}
+	nodeIPs := sets.New[string]()
+	for _, nodeIP := range npw.getHostNetworkEndpointNodeIPs() {
+		if utilnet.IsIPv6(nodeIP) == isIPv6 {
+			nodeIPs.Insert(nodeIP.String())
+		}
+	}
+	ports := sets.New[int32]()
+	for _, epSlice := range epSlices {
+		var port *int32
+		for _, epPort := range epSlice.Ports {
+			if epPort.Port != nil && pointer.StringDeref(epPort.Name, "") == svcPort.Name &&
+				(epPort.Protocol == nil || *epPort.Protocol == svcPort.Protocol) {
+				port = epPort.Port
+				break
+			}
+		}
+		if port == nil {
+			continue
+		}
+		for _, endpoint := range epSlice.Endpoints {
+			if !util.IsEndpointEligible(endpoint, service.Spec.PublishNotReadyAddresses) ||
+				endpoint.NodeName == nil || *endpoint.NodeName != npw.nodeIPManager.nodeName {
+				continue
+			}
+			for _, address := range endpoint.Addresses {
+				if ip := utilnet.ParseIPSloppy(address); ip != nil && nodeIPs.Has(ip.String()) {
+					ports.Insert(*port)
+				}
+			}
+		}
+	}
+	return sets.List(ports)
+}
+
+// getLocalHostNetworkEpFamilies returns whether a service has host networked
+// endpoints local to this node of the IPv4 and IPv6 families respectively. A
+// PreferDualStack service might only have endpoints of one of its families, in
+// which case the externalTrafficPolicy=local flows towards the host must not be
+// programmed for the other one. Other services are considered to have such
+// endpoints in all families as soon as they have one.
+func (npw *nodePortWatcher) getLocalHostNetworkEpFamilies(service *kapi.Service, add, hasLocalHostNetworkEp bool) (bool, bool) {
+	if !add || !hasLocalHostNetworkEp || !isPreferDualStackService(service) || npw.watchFactory == nil {
+		return hasLocalHostNetworkEp, hasLocalHostNetworkEp
+	}
+	epSlices, err := npw.watchFactory.GetEndpointSlices(service.Namespace, service.Name)
+	if err != nil {
+		klog.Warningf("Unable to get the endpointslices of service %s/%s, assuming it has local host networked endpoints of all IP families: %v",
+			service.Namespace, service.Name, err)
+		return hasLocalHostNetworkEp, hasLocalHostNetworkEp
+	}
+	localEndpoints := npw.GetLocalEndpointAddresses(epSlices, service)
+	var hasIPv4, hasIPv6 bool
+	for _, nodeIP := range npw.getHostNetworkEndpointNodeIPs() {
+		if !localEndpoints.Has(nodeIP.String()) {
+			continue
+		}
+		if utilnet.IsIPv6(nodeIP) {
+			hasIPv6 = true
+		} else {
+			hasIPv4 = true
+		}
+	}
+	return hasIPv4, hasIPv6
+}
+
+// isExternalNameServiceWithIPs returns true if the service is an ExternalName
+// service whose resolved IPs are to be handled like externalIPs
+func isExternalNameServiceWithIPs(service *kapi.Service) bool {
+	return config.Gateway.ExternalNameServiceIPs && service.Spec.Type == kapi.ServiceTypeExternalName &&
+		service.Annotations[ovnExternalNameIPsAnnotation] != ""
+}
+
+// getExternalNameServiceIPs returns the IPs set in the
+// ovnExternalNameIPsAnnotation of an ExternalName service
+func getExternalNameServiceIPs(service *kapi.Service) ([]string, error) {
+	var ips []string
+	for _, ipStr := range strings.Split(service.Annotations[ovnExternalNameIPsAnnotation], ",") {
+		ip := utilnet.ParseIPSloppy(strings.TrimSpace(ipStr))
+		if ip == nil {
+			return nil, fmt.Errorf("invalid %s annotation %q of service %s/%s: %q is not an IP",
+				ovnExternalNameIPsAnnotation, service.Annotations[ovnExternalNameIPsAnnotation],
+				service.Namespace, service.Name, ipStr)
+		}
+		ips = append(ips, ip.String())
+	}
+	return ips, nil
+}
+
+// updateExternalNameServiceFlows adds or removes the gateway bridge flows of
+// an ExternalName service with IPs set in its ovnExternalNameIPsAnnotation.
+// These are the flows of an externalIP for each of the IPs: as such services
+// have no endpoints, they are never steered to the host.
+func (npw *nodePortWatcher) updateExternalNameServiceFlows(service *kapi.Service, add bool) error {
+	ips, err := getExternalNameServiceIPs(service)
+	if err != nil {
+		return err
+	}
+	if config.Gateway.Mode == config.GatewayModeLocal && config.Gateway.AllowNoUplink && npw.ofportPhys == "" {
+		// no uplink gateway bridge, see updateServiceFlowCache
+		return nil
+	}
+	var errors []error
+	npw.gatewayIPLock.Lock()
+	actions := fmt.Sprintf("output:%s", npw.ofportPatch)
+	arpPorts := &arpBypassPorts{}
+	for _, svcPort := range service.Spec.Ports {
+		protocol := strings.ToLower(string(svcPort.Protocol))
+		for _, ip := range ips {
+			if err = npw.createLbAndExternalSvcFlows(service, &svcPort, add, false, protocol, actions, ip, "External", arpPorts); err != nil {
+				errors = append(errors, err)
+			}
+		}
+	}
+	npw.gatewayIPLock.Unlock()
+	npw.ofm.requestFlowSync()
+	return apierrors.NewAggregate(errors)
+}
+
+// isHeadlessServiceWithExternalIPs returns true if the service is a headless
+// service whose externalIPs are to be handled
+func isHeadlessServiceWithExternalIPs(service *kapi.Service) bool {
+	return config.Gateway.HeadlessServiceExternalIPs && util.ServiceTypeHasClusterIP(service) &&
+		service.Spec.ClusterIP == kapi.ClusterIPNone && len(service.Spec.ExternalIPs) > 0
+}
+
+// updateHeadlessServiceFlows adds or removes the gateway bridge flows of the
+// externalIPs of a headless service. Their traffic is sent to OVN, the backends
+// of such services being handled as pods: it is never steered to the host.
+func (npw *nodePortWatcher) updateHeadlessServiceFlows(service *kapi.Service, add bool) error {
+	if config.Gateway.Mode == config.GatewayModeLocal && config.Gateway.AllowNoUplink && npw.ofportPhys == "" {
+		// no uplink gateway bridge, see updateServiceFlowCache
+		return nil
+	}
+	var errors []error
+	npw.gatewayIPLock.Lock()
+	actions := fmt.Sprintf("output:%s", npw.ofportPatch)
+	arpPorts := &arpBypassPorts{}
+	for _, svcPort := range service.Spec.Ports {
+		protocol := strings.ToLower(string(svcPort.Protocol))
+		for _, externalIP := range service.Spec.ExternalIPs {
+			if err := npw.createLbAndExternalSvcFlows(service, &svcPort, add, false, protocol, actions,
+				normalizeExternalIP(externalIP), "External", arpPorts); err != nil {
+				errors = append(errors, err)
+			}
+		}
+	}
+	npw.gatewayIPLock.Unlock()
+	npw.ofm.requestFlowSync()
+	return apierrors.NewAggregate(errors)
+}
+
+// normalizeExternalIP returns the canonical form of an externalIP given either
+// as an IP or as a CIDR. A CIDR covering a single IP is returned as that IP.
+// An invalid externalIP is returned as is, for the errors about it to name it.
+func normalizeExternalIP(externalIP string) string {
+	if _, ipNet, err := net.ParseCIDR(externalIP); err == nil {
+		if ones, bits := ipNet.Mask.Size(); ones < bits {
+			return ipNet.String()
+		}
+		return ipNet.IP.String()
+	}
+	if ip := utilnet.ParseIPSloppy(externalIP); ip != nil {
+		return ip.String()
+	}
+	return externalIP
+}
+
 // generate ARP/NS bypass flow which will send the ARP/NS request everywhere *but* to OVN
 // OpenFlow will not do hairpin switching, so we can safely add the origin port to the list of ports, too
-func (npw *nodePortWatcher) generateArpBypassFlow(protocol string, ipAddr string, cookie string) string {
+// `ipAddr` can also be a CIDR, matching the ARP/NS requests for any IP in it
+func (npw *nodePortWatcher) generateArpBypassFlow(protocol string, ipAddr string, cookie string, cache *arpBypassPorts) string {
 	addrResDst := "arp_tpa"
 	addrResProto := "arp, arp_op=1"
-	if utilnet.IsIPv6String(ipAddr) {
+	if utilnet.IsIPv6String(ipAddr) || utilnet.IsIPv6CIDRString(ipAddr) {
 		addrResDst = "nd_target"
 		addrResProto = "icmp6, icmp_type=135, icmp_code=0"
 	}
 
+	// rate-limit the flood, the meter instruction must come first
+	var meterAction string
+	if config.Gateway.ARPBypassFlowsMeterRate > 0 {
+		meterAction = fmt.Sprintf("meter:%d,", arpBypassMeterID)
+	}
+
 	var arpFlow string
 	var arpPortsFiltered []string
-	arpPorts, err := util.GetOpenFlowPorts(npw.gwBridge, false)
+	arpPorts, err := npw.getArpBypassPorts(cache)
 	if err != nil {
 		// in the odd case that getting all ports from the bridge should not work,
 		// simply output to LOCAL (this should work well in the vast majority of cases, anyway)
 		klog.Warningf("Unable to get port list from bridge. Using ovsLocalPort as output only: error: %v",
 			err)
 		arpFlow = fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, %s=%s, "+
-			"actions=output:%s",
-			cookie, npw.ofportPhys, addrResProto, addrResDst, ipAddr, ovsLocalPort)
+			"actions=%soutput:%s",
+			cookie, npw.ofportPhys, addrResProto, addrResDst, ipAddr, meterAction, ovsLocalPort)
 	} else {
 		// cover the case where breth0 has more than 3 ports, e.g. if an admin adds a 4th port
 		// and the ExternalIP would be on that port
@@ -365,13 +1586,69 @@ func (npw *nodePortWatcher) generateArpBypassFlow(protocol string, ipAddr string
 			arpPortsFiltered = append(arpPortsFiltered, port)
 		}
 		arpFlow = fmt.Sprintf("cookie=%s, priority=110, in_port=%s, %s, %s=%s, "+
-			"actions=output:%s",
-			cookie, npw.ofportPhys, addrResProto, addrResDst, ipAddr, strings.Join(arpPortsFiltered, ","))
+			"actions=%soutput:%s",
+			cookie, npw.ofportPhys, addrResProto, addrResDst, ipAddr, meterAction, strings.Join(arpPortsFiltered, ","))
 	}
 
 	return arpFlow
 }
 
+// arpBypassPorts caches the ports of the gateway bridge for the ARP bypass
+// flows generated in a batch, like the ones of all the externalIPs and LB
+// ingress IPs of a service, so that the bridge is queried once for the batch
+// rather than once per IP and port
+type arpBypassPorts struct {
+	fetched bool
+	ports   []string
+	err     error
+}
+
+// getArpBypassPorts returns the ports of the gateway bridge, from the cache if
+// already looked up in the batch. The ports are looked up on each call if cache
+// is nil.
+func (npw *nodePortWatcher) getArpBypassPorts(cache *arpBypassPorts) ([]string, error) {
+	if cache != nil && cache.fetched {
+		return cache.ports, cache.err
+	}
+	ports, err := util.GetOpenFlowPorts(npw.gwBridge, false)
+	if cache != nil {
+		cache.fetched, cache.ports, cache.err = true, ports, err
+	}
+	return ports, err
+}
+
+// arpBypassMeterID is the ID of the OVS meter rate-limiting the ARP bypass
+// flows, see Gateway.ARPBypassFlowsMeterRate
+const arpBypassMeterID = 1
+
+// ensureArpBypassMeter creates or updates, on the given bridge, the meter
+// dropping the ARP/NS requests flooded by the ARP bypass flows beyond
+// Gateway.ARPBypassFlowsMeterRate
+func ensureArpBypassMeter(bridgeName string) error {
+	meter := fmt.Sprintf("meter=%d,pktps,band=type=drop,rate=%d", arpBypassMeterID, config.Gateway.ARPBypassFlowsMeterRate)
+	if _, stderr, err := util.SetOFMeter(bridgeName, meter); err != nil {
+		return fmt.Errorf("failed to set the ARP bypass meter %q on bridge %s, stderr: %q, error: %v",
+			meter, bridgeName, stderr, err)
+	}
+	return nil
+}
+
+// getHostNetworkEndpointNodeIPs returns the node IPs local service endpoints
+// are considered host networked on: the node IPs and, if configured, any other
+// IP of the host interfaces
+func (npw *nodePortWatcher) getHostNetworkEndpointNodeIPs() []net.IP {
+	nodeIPs := npw.nodeIPManager.ListAddresses()
+	if !config.Gateway.HostNetworkEndpointsAllHostIPs {
+		return nodeIPs
+	}
+	hostIPs, err := util.GetHostIPs()
+	if err != nil {
+		klog.Errorf("Failed to get the host IPs, only considering the node IPs for host networked endpoints: %v", err)
+		return nodeIPs
+	}
+	return append(nodeIPs, hostIPs...)
+}
+
 // getAndDeleteServiceInfo returns the serviceConfig for a service and if it exists and then deletes the entry
 func (npw *nodePortWatcher) getAndDeleteServiceInfo(index ktypes.NamespacedName) (out *serviceConfig, exists bool) {
 	npw.serviceInfoLock.Lock()
@@ -381,6 +1658,21 @@ func (npw *nodePortWatcher) getAndDeleteServiceInfo(index ktypes.NamespacedName)
 	return out, exists
 }
 
+// getAndDeleteServiceInfoOnDelete returns the serviceConfig for a deleted
+// service and if it exists, deletes the entry and marks the service as
+// deleted until it is added back
+func (npw *nodePortWatcher) getAndDeleteServiceInfoOnDelete(index ktypes.NamespacedName) (out *serviceConfig, exists bool) {
+	npw.serviceInfoLock.Lock()
+	defer npw.serviceInfoLock.Unlock()
+	out, exists = npw.serviceInfo[index]
+	delete(npw.serviceInfo, index)
+	if npw.deletedServices == nil {
+		npw.deletedServices = sets.New[ktypes.NamespacedName]()
+	}
+	npw.deletedServices.Insert(index)
+	return out, exists
+}
+
 // getServiceInfo returns the serviceConfig for a service and if it exists
 func (npw *nodePortWatcher) getServiceInfo(index ktypes.NamespacedName) (out *serviceConfig, exists bool) {
 	npw.serviceInfoLock.Lock()
@@ -399,24 +1691,11 @@ func (npw *nodePortWatcher) getAndSetServiceInfo(index ktypes.NamespacedName, se
 	if exists {
 		ptrCopy = *old
 	}
-	npw.serviceInfo[index] = &serviceConfig{service: service, hasLocalHostNetworkEp: hasLocalHostNetworkEp, localEndpoints: localEndpoints}
+	npw.serviceInfo[index] = &serviceConfig{service: service, hasLocalHostNetworkEp: hasLocalHostNetworkEp, localEndpoints: localEndpoints,
+		lastEndpointChange: time.Now()}
 	return &ptrCopy, exists
 }
 
-// addOrSetServiceInfo creates and sets the serviceConfig if it doesn't exist
-func (npw *nodePortWatcher) addOrSetServiceInfo(index ktypes.NamespacedName, service *kapi.Service, hasLocalHostNetworkEp bool, localEndpoints sets.Set[string]) (exists bool) {
-	npw.serviceInfoLock.Lock()
-	defer npw.serviceInfoLock.Unlock()
-
-	if _, exists := npw.serviceInfo[index]; !exists {
-		// Only set this if it doesn't exist
-		npw.serviceInfo[index] = &serviceConfig{service: service, hasLocalHostNetworkEp: hasLocalHostNetworkEp, localEndpoints: localEndpoints}
-		return false
-	}
-	return true
-
-}
-
 // updateServiceInfo sets the serviceConfig for a service and returns the existing serviceConfig, if inputs are nil
 // do not update those fields, if it does not exist return nil.
 func (npw *nodePortWatcher) updateServiceInfo(index ktypes.NamespacedName, service *kapi.Service, hasLocalHostNetworkEp *bool, localEndpoints sets.Set[string]) (old *serviceConfig, exists bool) {
@@ -441,6 +1720,10 @@ func (npw *nodePortWatcher) updateServiceInfo(index ktypes.NamespacedName, servi
 		npw.serviceInfo[index].localEndpoints = localEndpoints
 	}
 
+	if hasLocalHostNetworkEp != nil || localEndpoints != nil {
+		npw.serviceInfo[index].lastEndpointChange = time.Now()
+	}
+
 	return &ptrCopy, exists
 }
 
@@ -460,6 +1743,11 @@ func addServiceRules(service *kapi.Service, localEndpoints []string, svcHasLocal
 			if err = addGatewayIptRules(service, localEndpoints, svcHasLocalHostNetEndPnt); err != nil {
 				errors = append(errors, err)
 			}
+			if npw.svcViaMgmPortRoutes != nil {
+				if err = npw.svcViaMgmPortRoutes.sync(service); err != nil {
+					errors = append(errors, err)
+				}
+			}
 		}
 	} else {
 		// For Host Only Mode
@@ -529,30 +1817,69 @@ func delServiceRules(service *kapi.Service, localEndpoints []string, npw *nodePo
 	return apierrors.NewAggregate(errors)
 }
 
+// sameIPs returns whether the lists of IPs have the same members, regardless
+// of their order
+func sameIPs(a, b []string) bool {
+	return len(a) == len(b) && sets.New(a...).Equal(sets.New(b...))
+}
+
 func serviceUpdateNotNeeded(old, new *kapi.Service) bool {
+	// the order of the cluster IPs and external IPs does not matter to the rules and flows
 	return reflect.DeepEqual(new.Spec.Ports, old.Spec.Ports) &&
-		reflect.DeepEqual(new.Spec.ExternalIPs, old.Spec.ExternalIPs) &&
+		sameIPs(new.Spec.ExternalIPs, old.Spec.ExternalIPs) &&
 		reflect.DeepEqual(new.Spec.ClusterIP, old.Spec.ClusterIP) &&
-		reflect.DeepEqual(new.Spec.ClusterIPs, old.Spec.ClusterIPs) &&
+		sameIPs(new.Spec.ClusterIPs, old.Spec.ClusterIPs) &&
 		reflect.DeepEqual(new.Spec.Type, old.Spec.Type) &&
 		reflect.DeepEqual(new.Status.LoadBalancer.Ingress, old.Status.LoadBalancer.Ingress) &&
 		reflect.DeepEqual(new.Spec.ExternalTrafficPolicy, old.Spec.ExternalTrafficPolicy) &&
-		(new.Spec.InternalTrafficPolicy != nil && old.Spec.InternalTrafficPolicy != nil &&
-			reflect.DeepEqual(*new.Spec.InternalTrafficPolicy, *old.Spec.InternalTrafficPolicy)) &&
-		(new.Spec.AllocateLoadBalancerNodePorts != nil && old.Spec.AllocateLoadBalancerNodePorts != nil &&
-			reflect.DeepEqual(*new.Spec.AllocateLoadBalancerNodePorts, *old.Spec.AllocateLoadBalancerNodePorts))
+		new.Annotations[ovnServiceMarkAnnotation] == old.Annotations[ovnServiceMarkAnnotation] &&
+		new.Annotations[ovnExternalNameIPsAnnotation] == old.Annotations[ovnExternalNameIPsAnnotation] &&
+		new.Annotations[ovnForceETPAnnotation] == old.Annotations[ovnForceETPAnnotation] &&
+		new.Annotations[ovnFlowIdleTimeoutAnnotation] == old.Annotations[ovnFlowIdleTimeoutAnnotation] &&
+		new.Annotations[ovnFlowHardTimeoutAnnotation] == old.Annotations[ovnFlowHardTimeoutAnnotation] &&
+		new.Annotations[ovnExGWBridgeAnnotation] == old.Annotations[ovnExGWBridgeAnnotation] &&
+		// unset pointers are equal to each other, set ones are compared by value
+		reflect.DeepEqual(new.Spec.InternalTrafficPolicy, old.Spec.InternalTrafficPolicy) &&
+		reflect.DeepEqual(new.Spec.AllocateLoadBalancerNodePorts, old.Spec.AllocateLoadBalancerNodePorts)
 }
 
 // AddService handles configuring shared gateway bridge flows to steer External IP, Node Port, Ingress LB traffic into OVN
 func (npw *nodePortWatcher) AddService(service *kapi.Service) error {
 	var localEndpoints sets.Set[string]
 	var hasLocalHostNetworkEp bool
+	if isExternalNameServiceWithIPs(service) {
+		if err := npw.updateExternalNameServiceFlows(service, true); err != nil {
+			return fmt.Errorf("AddService failed for nodePortWatcher: %v", err)
+		}
+		return nil
+	}
+	if isHeadlessServiceWithExternalIPs(service) {
+		if err := npw.updateHeadlessServiceFlows(service, true); err != nil {
+			return fmt.Errorf("AddService failed for nodePortWatcher: %v", err)
+		}
+		return nil
+	}
 	if !util.ServiceTypeHasClusterIP(service) || !util.IsClusterIPSet(service) {
 		return nil
 	}
+	if len(service.Spec.Ports) == 0 {
+		klog.V(5).Infof("Skipping service %s in namespace %s without ports", service.Name, service.Namespace)
+		return nil
+	}
 
 	klog.V(5).Infof("Adding service %s in namespace %s", service.Name, service.Namespace)
+	logForcedETP(service)
+	logServiceFlowTimeouts(service)
 	name := ktypes.NamespacedName{Namespace: service.Namespace, Name: service.Name}
+	// Lock the cache mutex while the rules are programmed, so that a concurrent endpointslice
+	// add does not program them at the same time, possibly from a different state
+	npw.serviceInfoLock.Lock()
+	defer npw.serviceInfoLock.Unlock()
+	npw.deletedServices.Delete(name)
+	if _, exists := npw.serviceInfo[name]; exists {
+		klog.V(5).Infof("Rules already programmed for %s in namespace %s", service.Name, service.Namespace)
+		return nil
+	}
 	epSlices, err := npw.watchFactory.GetEndpointSlices(service.Namespace, service.Name)
 	if err != nil {
 		if !kerrors.IsNotFound(err) {
@@ -564,19 +1891,16 @@ func (npw *nodePortWatcher) AddService(service *kapi.Service) error {
 		// No endpoint object exists yet so default to false
 		hasLocalHostNetworkEp = false
 	} else {
-		nodeIPs := npw.nodeIPManager.ListAddresses()
+		nodeIPs := npw.getHostNetworkEndpointNodeIPs()
 		localEndpoints = npw.GetLocalEndpointAddresses(epSlices, service)
 		hasLocalHostNetworkEp = util.HasLocalHostNetworkEndpoints(localEndpoints, nodeIPs)
 	}
-	// If something didn't already do it add correct Service rules
-	if exists := npw.addOrSetServiceInfo(name, service, hasLocalHostNetworkEp, localEndpoints); !exists {
-		klog.V(5).Infof("Service Add %s event in namespace %s came before endpoint event setting svcConfig",
-			service.Name, service.Namespace)
-		if err := addServiceRules(service, sets.List(localEndpoints), hasLocalHostNetworkEp, npw); err != nil {
-			return fmt.Errorf("AddService failed for nodePortWatcher: %v", err)
-		}
-	} else {
-		klog.V(5).Infof("Rules already programmed for %s in namespace %s", service.Name, service.Namespace)
+	klog.V(5).Infof("Service Add %s event in namespace %s came before endpoint event setting svcConfig",
+		service.Name, service.Namespace)
+	npw.serviceInfo[name] = &serviceConfig{service: service, hasLocalHostNetworkEp: hasLocalHostNetworkEp, localEndpoints: localEndpoints,
+		lastEndpointChange: time.Now()}
+	if err := addServiceRules(service, sets.List(localEndpoints), hasLocalHostNetworkEp, npw); err != nil {
+		return fmt.Errorf("AddService failed for nodePortWatcher: %v", err)
 	}
 	return nil
 }
@@ -589,9 +1913,63 @@ func (npw *nodePortWatcher) UpdateService(old, new *kapi.Service) error {
 	if serviceUpdateNotNeeded(old, new) {
 		klog.V(5).Infof("Skipping service update for: %s as change does not apply to any of .Spec.Ports, "+
 			".Spec.ExternalIP, .Spec.ClusterIP, .Spec.ClusterIPs, .Spec.Type, .Status.LoadBalancer.Ingress, "+
-			".Spec.ExternalTrafficPolicy, .Spec.InternalTrafficPolicy", new.Name)
+			".Spec.ExternalTrafficPolicy, .Spec.InternalTrafficPolicy, the service mark, external name IPs, force ETP or flow timeout annotations", new.Name)
+		return nil
+	}
+	if new.Annotations[ovnForceETPAnnotation] != old.Annotations[ovnForceETPAnnotation] {
+		logForcedETP(new)
+	}
+	if new.Annotations[ovnFlowIdleTimeoutAnnotation] != old.Annotations[ovnFlowIdleTimeoutAnnotation] ||
+		new.Annotations[ovnFlowHardTimeoutAnnotation] != old.Annotations[ovnFlowHardTimeoutAnnotation] {
+		logServiceFlowTimeouts(new)
+	}
+	if isExternalNameServiceWithIPs(old) {
+		// no flows were programmed if the old IPs are invalid, nothing to retry
+		if err = npw.updateExternalNameServiceFlows(old, false); err != nil {
+			klog.Errorf("Failed to delete the flows of ExternalName service %s in namespace %s: %v", old.Name, old.Namespace, err)
+		}
+	}
+	if isExternalNameServiceWithIPs(new) {
+		if err = npw.updateExternalNameServiceFlows(new, true); err != nil {
+			return fmt.Errorf("UpdateService failed for nodePortWatcher: %v", err)
+		}
+		return nil
+	}
+	if isHeadlessServiceWithExternalIPs(old) {
+		if err = npw.updateHeadlessServiceFlows(old, false); err != nil {
+			klog.Errorf("Failed to delete the flows of headless service %s in namespace %s: %v", old.Name, old.Namespace, err)
+		}
+	}
+	if isHeadlessServiceWithExternalIPs(new) {
+		if err = npw.updateHeadlessServiceFlows(new, true); err != nil {
+			return fmt.Errorf("UpdateService failed for nodePortWatcher: %v", err)
+		}
+		return nil
+	}
+	if len(new.Spec.Ports) == 0 {
+		// services without ports are not cached, remove the rules of the
+		// service if it had ports
+		klog.V(5).Infof("Service %s in namespace %s has no ports", new.Name, new.Namespace)
+		if svcConfig, exists := npw.getAndDeleteServiceInfo(name); exists {
+			if err = delServiceRules(svcConfig.service, sets.List(svcConfig.localEndpoints), npw); err != nil {
+				return fmt.Errorf("UpdateService failed for nodePortWatcher: %v", err)
+			}
+			if err = npw.deleteSvcViaMgmPortRoutes(name); err != nil {
+				return fmt.Errorf("UpdateService failed for nodePortWatcher: %v", err)
+			}
+		}
 		return nil
 	}
+	if len(old.Spec.Ports) == 0 {
+		// the service was not cached without ports, add it
+		return npw.AddService(new)
+	}
+	if !util.ServiceTypeHasClusterIP(old) || !util.IsClusterIPSet(old) {
+		// the service was not cached as an ExternalName or headless service,
+		// program the rules of its cluster IP once from its current endpoints
+		klog.V(5).Infof("Service %s in namespace %s converted from %s to %s", new.Name, new.Namespace, old.Spec.Type, new.Spec.Type)
+		return npw.AddService(new)
+	}
 	// Update the service in svcConfig if we need to so that other handler
 	// threads do the correct thing, leave hasLocalHostNetworkEp and localEndpoints alone in the cache
 	svcConfig, exists := npw.updateServiceInfo(name, new, nil, nil)
@@ -615,6 +1993,13 @@ func (npw *nodePortWatcher) UpdateService(old, new *kapi.Service) error {
 			errors = append(errors, err)
 		}
 	}
+	// the connections of the old protocol of a NodePort reused with another protocol would
+	// otherwise be kept by their conntrack entries
+	if changedPorts := nodePortProtocolChanges(old, new); len(changedPorts) > 0 {
+		if err = npw.deleteConntrackForNodePorts(old, changedPorts); err != nil {
+			errors = append(errors, err)
+		}
+	}
 	if err = apierrors.NewAggregate(errors); err != nil {
 		return fmt.Errorf("UpdateService failed for nodePortWatcher: %v", err)
 	}
@@ -622,44 +2007,346 @@ func (npw *nodePortWatcher) UpdateService(old, new *kapi.Service) error {
 
 }
 
-// deleteConntrackForServiceVIP deletes the conntrack entries for the provided svcVIP:svcPort by comparing them to ConntrackOrigDstIP:ConntrackOrigDstPort
-func deleteConntrackForServiceVIP(svcVIPs []string, svcPorts []kapi.ServicePort, ns, name string) error {
-	for _, svcVIP := range svcVIPs {
+// nodePortProtocolChanges returns the ports of the old service whose NodePort
+// the new service uses with another protocol only
+func nodePortProtocolChanges(old, new *kapi.Service) []kapi.ServicePort {
+	if !util.ServiceTypeHasNodePort(old) || !util.ServiceTypeHasNodePort(new) {
+		return nil
+	}
+	newProtocols := map[int32]sets.Set[kapi.Protocol]{}
+	for _, svcPort := range new.Spec.Ports {
+		if svcPort.NodePort > 0 {
+			if newProtocols[svcPort.NodePort] == nil {
+				newProtocols[svcPort.NodePort] = sets.New[kapi.Protocol]()
+			}
+			newProtocols[svcPort.NodePort].Insert(svcPort.Protocol)
+		}
+	}
+	var changedPorts []kapi.ServicePort
+	for _, svcPort := range old.Spec.Ports {
+		if protocols, ok := newProtocols[svcPort.NodePort]; ok && !protocols.Has(svcPort.Protocol) {
+			changedPorts = append(changedPorts, svcPort)
+		}
+	}
+	return changedPorts
+}
+
+// deleteConntrackForNodePorts deletes the conntrack entries towards the given
+// NodePorts of the service, with their protocol
+func (npw *nodePortWatcher) deleteConntrackForNodePorts(service *kapi.Service, svcPorts []kapi.ServicePort) error {
+	if config.Gateway.DisableConntrackFlush {
+		klog.V(4).Infof("Conntrack flush disabled, skipping the deletion of the conntrack entries of the changed NodePorts of service %s/%s",
+			service.Namespace, service.Name)
+		return nil
+	}
+	deleter := &serviceConntrackDeleter{purpose: conntrackDeletePurposeProtocolChange}
+	var errors []error
+	for _, nodeIP := range npw.nodePortIPsForService(service) {
 		for _, svcPort := range svcPorts {
-			if err := util.DeleteConntrackServicePort(svcVIP, svcPort.Port, svcPort.Protocol,
-				netlink.ConntrackOrigDstIP, nil); err != nil {
-				return fmt.Errorf("failed to delete conntrack entry for service %s/%s with svcVIP %s, svcPort %d, protocol %s: %v",
-					ns, name, svcVIP, svcPort.Port, svcPort.Protocol, err)
+			klog.V(5).Infof("Deleting the %s conntrack entries of NodePort %d of service %s/%s on node IP %s, its protocol changed",
+				svcPort.Protocol, svcPort.NodePort, service.Namespace, service.Name, nodeIP)
+			if err := deleter.delete(nodeIP.String(), svcPort.NodePort, svcPort.Protocol); err != nil {
+				errors = append(errors, fmt.Errorf("failed to delete conntrack entries for service %s/%s with nodeIP %s, nodePort %d, protocol %s: %v",
+					service.Namespace, service.Name, nodeIP, svcPort.NodePort, svcPort.Protocol, err))
+			}
+		}
+	}
+	return apierrors.NewAggregate(errors)
+}
+
+// nodePortIPsForService returns the node IPs the NodePorts of the provided
+// service are exposed on, that is, the node IPs of the IP families of the
+// service cluster IPs
+func (npw *nodePortWatcher) nodePortIPsForService(service *kapi.Service) []net.IP {
+	var hasV4, hasV6 bool
+	for _, clusterIP := range util.GetClusterIPs(service) {
+		if utilnet.IsIPv6String(clusterIP) {
+			hasV6 = true
+		} else {
+			hasV4 = true
+		}
+	}
+	nodeIPs := npw.nodeIPManager.ListAddresses()
+	nodePortIPs := make([]net.IP, 0, len(nodeIPs))
+	for _, nodeIP := range nodeIPs {
+		if utilnet.IsIPv6(nodeIP) {
+			if hasV6 {
+				nodePortIPs = append(nodePortIPs, nodeIP)
 			}
+		} else if hasV4 {
+			nodePortIPs = append(nodePortIPs, nodeIP)
+		}
+	}
+	return nodePortIPs
+}
+
+// getNodePortOfports returns the physical ofports the NodePort traffic is
+// accepted on, see Gateway.NodePortInterfaces
+func (npw *nodePortWatcher) getNodePortOfports() []string {
+	if len(npw.nodePortOfports) > 0 {
+		return npw.nodePortOfports
+	}
+	return []string{npw.ofportPhys}
+}
+
+// serviceConntrackDrainInterval is the time between the deletion of two chunks
+// of conntrack entries of a deleted service, see Gateway.ConntrackDrainChunkSize
+var serviceConntrackDrainInterval = 100 * time.Millisecond
+
+// serviceConntrackDrainWindow bounds the time the deletion of the conntrack
+// entries of a deleted service is spread over, the remaining entries are
+// deleted at once after it
+var serviceConntrackDrainWindow = 2 * time.Second
+
+// deleteConntrackServicePortUpTo and deleteConntrackServicePortInChunks delete
+// the conntrack entries of a service port, overridden in tests
+var (
+	deleteConntrackServicePortUpTo     = util.DeleteConntrackServicePortUpTo
+	deleteConntrackServicePortInChunks = util.DeleteConntrackServicePortInChunks
+)
+
+// serviceConntrackFilterTypes returns the directions the conntrack entries of
+// a service VIP are matched in for deletion, see
+// Gateway.ConntrackDeleteReplyDirection
+func serviceConntrackFilterTypes() []netlink.ConntrackFilterType {
+	if config.Gateway.ConntrackDeleteReplyDirection {
+		return []netlink.ConntrackFilterType{netlink.ConntrackOrigDstIP, netlink.ConntrackReplySrcIP}
+	}
+	return []netlink.ConntrackFilterType{netlink.ConntrackOrigDstIP}
+}
+
+// The purposes of the deletions of service conntrack entries, as reported by
+// the MetricServiceConntrackDeletes metric
+const (
+	conntrackDeletePurposeServiceDelete  = "service-delete"
+	conntrackDeletePurposeEndpointChange = "endpoint-change"
+	conntrackDeletePurposeProtocolChange = "protocol-change"
+)
+
+// recordServiceConntrackDeletes reports the given number of service conntrack
+// entries deleted for the given purpose
+func recordServiceConntrackDeletes(purpose string, deleted uint) {
+	if deleted > 0 {
+		metrics.MetricServiceConntrackDeletes.WithLabelValues(purpose).Add(float64(deleted))
+	}
+}
+
+// serviceConntrackDeleter deletes the conntrack entries of a deleted service,
+// in chunks until the deadline if configured, and at most
+// Gateway.MaxServiceConntrackDeletes of them overall
+type serviceConntrackDeleter struct {
+	deadline time.Time
+	deleted  uint
+	// purpose the deleted entries are reported for, conntrackDeletePurposeServiceDelete if empty
+	purpose string
+}
+
+// capped returns true if the maximum number of conntrack entries of the
+// service have been deleted
+func (d *serviceConntrackDeleter) capped() bool {
+	return config.Gateway.MaxServiceConntrackDeletes > 0 && d.deleted >= uint(config.Gateway.MaxServiceConntrackDeletes)
+}
+
+// delete deletes the conntrack entries towards the given service IP and port,
+// and the ones replied from it if Gateway.ConntrackDeleteReplyDirection is set
+func (d *serviceConntrackDeleter) delete(ip string, port int32, protocol kapi.Protocol) error {
+	purpose := d.purpose
+	if purpose == "" {
+		purpose = conntrackDeletePurposeServiceDelete
+	}
+	for _, ipFilterType := range serviceConntrackFilterTypes() {
+		if d.capped() {
+			return nil
+		}
+		var limit uint
+		if config.Gateway.MaxServiceConntrackDeletes > 0 {
+			limit = uint(config.Gateway.MaxServiceConntrackDeletes) - d.deleted
+		}
+		var deleted uint
+		var err error
+		if config.Gateway.ConntrackDrainChunkSize > 0 {
+			deleted, err = deleteConntrackServicePortInChunks(ip, port, protocol, ipFilterType, nil,
+				uint(config.Gateway.ConntrackDrainChunkSize), serviceConntrackDrainInterval, d.deadline, limit)
+		} else {
+			deleted, err = deleteConntrackServicePortUpTo(ip, port, protocol, ipFilterType, nil, limit)
+		}
+		d.deleted += deleted
+		recordServiceConntrackDeletes(purpose, deleted)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// deleteConntrackForService deletes the conntrack entries corresponding to the service VIPs of the provided service
-func (npw *nodePortWatcher) deleteConntrackForService(service *kapi.Service) error {
+// deleteConntrackForRemovedEndpoints deletes the conntrack entries of the
+// connections towards the service VIPs of the provided service that were DNATed
+// to one of the given removed endpoints, so that they do not outlive the
+// endpoints. Only the VIPs of the IP family of each endpoint are considered.
+func (npw *nodePortWatcher) deleteConntrackForRemovedEndpoints(service *kapi.Service, removedEndpoints sets.Set[string]) error {
+	if removedEndpoints.Len() == 0 {
+		return nil
+	}
+	if config.Gateway.DisableConntrackFlush {
+		klog.V(4).Infof("Conntrack flush disabled, skipping the deletion of the conntrack entries of the removed endpoints of service %s/%s",
+			service.Namespace, service.Name)
+		return nil
+	}
+	svcVIPs := append(util.GetClusterIPs(service), util.GetExternalAndLBIPs(service)...)
+	var nodePortIPs []net.IP
+	if util.ServiceTypeHasNodePort(service) && npw.nodeIPManager != nil {
+		nodePortIPs = npw.nodePortIPsForService(service)
+	}
+	var errors []error
+	for _, endpointIP := range sets.List(removedEndpoints) {
+		isIPv6Endpoint := utilnet.IsIPv6String(endpointIP)
+		for _, svcPort := range service.Spec.Ports {
+			for _, svcVIP := range svcVIPs {
+				if utilnet.IsIPv6String(svcVIP) != isIPv6Endpoint {
+					continue
+				}
+				deleted, err := util.DeleteConntrackServiceEndpoint(svcVIP, svcPort.Port, svcPort.Protocol, endpointIP)
+				recordServiceConntrackDeletes(conntrackDeletePurposeEndpointChange, deleted)
+				if err != nil {
+					errors = append(errors, fmt.Errorf("failed to delete conntrack entries for service %s/%s with svcVIP %s, svcPort %d, protocol %s, endpoint %s: %v",
+						service.Namespace, service.Name, svcVIP, svcPort.Port, svcPort.Protocol, endpointIP, err))
+				}
+			}
+			for _, nodeIP := range nodePortIPs {
+				if utilnet.IsIPv6(nodeIP) != isIPv6Endpoint {
+					continue
+				}
+				deleted, err := util.DeleteConntrackServiceEndpoint(nodeIP.String(), svcPort.NodePort, svcPort.Protocol, endpointIP)
+				recordServiceConntrackDeletes(conntrackDeletePurposeEndpointChange, deleted)
+				if err != nil {
+					errors = append(errors, fmt.Errorf("failed to delete conntrack entries for service %s/%s with nodeIP %s, nodePort %d, protocol %s, endpoint %s: %v",
+						service.Namespace, service.Name, nodeIP, svcPort.NodePort, svcPort.Protocol, endpointIP, err))
+				}
+			}
+		}
+	}
+	return apierrors.NewAggregate(errors)
+}
+
+// serviceConntrackTuple is a VIP:port tuple of a service whose conntrack
+// entries are deleted on the deletion of the service
+type serviceConntrackTuple struct {
+	// vipType is ClusterIP, NodePort, External or Ingress
+	vipType  string
+	ip       string
+	port     int32
+	protocol kapi.Protocol
+}
+
+// serviceConntrackTuples returns the tuples of the service whose conntrack
+// entries are deleted on its deletion, in the order they are deleted: the
+// LoadBalancer ingress and external IPs first, then the NodePorts on the node
+// IPs and lastly the ClusterIPs
+func (npw *nodePortWatcher) serviceConntrackTuples(service *kapi.Service) []serviceConntrackTuple {
+	var tuples []serviceConntrackTuple
+	addVIPTuples := func(vipType string, vips []string) {
+		for _, vip := range vips {
+			for _, svcPort := range service.Spec.Ports {
+				tuples = append(tuples, serviceConntrackTuple{vipType: vipType, ip: vip, port: svcPort.Port, protocol: svcPort.Protocol})
+			}
+		}
+	}
 	// remove conntrack entries for LB VIPs and External IPs
-	externalIPs := util.GetExternalAndLBIPs(service)
-	if err := deleteConntrackForServiceVIP(externalIPs, service.Spec.Ports, service.Namespace, service.Name); err != nil {
-		return err
+	var externalIPs, ingressIPs []string
+	for _, externalIP := range service.Spec.ExternalIPs {
+		if ip := utilnet.ParseIPSloppy(externalIP); ip != nil {
+			externalIPs = append(externalIPs, ip.String())
+		}
+	}
+	if util.ServiceTypeHasLoadBalancer(service) {
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			if ip := utilnet.ParseIPSloppy(ingress.IP); ip != nil {
+				ingressIPs = append(ingressIPs, ip.String())
+			}
+		}
 	}
+	addVIPTuples("External", externalIPs)
+	addVIPTuples("Ingress", ingressIPs)
 	if util.ServiceTypeHasNodePort(service) {
 		// remove conntrack entries for NodePorts
-		nodeIPs := npw.nodeIPManager.ListAddresses()
-		for _, nodeIP := range nodeIPs {
+		for _, nodeIP := range npw.nodePortIPsForService(service) {
 			for _, svcPort := range service.Spec.Ports {
-				if err := util.DeleteConntrackServicePort(nodeIP.String(), svcPort.NodePort, svcPort.Protocol,
-					netlink.ConntrackOrigDstIP, nil); err != nil {
-					return fmt.Errorf("failed to delete conntrack entry for service %s/%s with nodeIP %s, nodePort %d, protocol %s: %v",
-						service.Namespace, service.Name, nodeIP, svcPort.Port, svcPort.Protocol, err)
-				}
+				tuples = append(tuples, serviceConntrackTuple{vipType: "NodePort", ip: nodeIP.String(), port: svcPort.NodePort, protocol: svcPort.Protocol})
 			}
 		}
 	}
 	// remove conntrack entries for ClusterIPs
-	clusterIPs := util.GetClusterIPs(service)
-	if err := deleteConntrackForServiceVIP(clusterIPs, service.Spec.Ports, service.Namespace, service.Name); err != nil {
-		return err
+	addVIPTuples("ClusterIP", util.GetClusterIPs(service))
+	return tuples
+}
+
+// zones returns the conntrack zones the gateway bridge tracks the connections
+// towards the tuple in: the masquerade zones for the host traffic towards the
+// ClusterIPs, the default zone for the ingress traffic and, if it is steered
+// to the local host networked endpoints, the host NodePort zone. The conntrack
+// entries of the tuple are deleted in any zone though, the host kernel ones
+// included.
+func (t serviceConntrackTuple) zones(hostSteered bool) []int {
+	if t.vipType == "ClusterIP" {
+		return []int{HostMasqCTZone, OVNMasqCTZone}
+	}
+	if hostSteered {
+		return []int{config.Default.ConntrackZone, HostNodePortCTZone}
+	}
+	return []int{config.Default.ConntrackZone}
+}
+
+// queryServiceConntrackFlush returns the tuples, and their zones, whose
+// conntrack entries would be deleted on the deletion of the service
+func (npw *nodePortWatcher) queryServiceConntrackFlush(namespace, name string) (*metrics.ServiceConntrackFlush, error) {
+	var service *kapi.Service
+	var hasLocalHostNetworkEp bool
+	npw.serviceInfoLock.Lock()
+	if svcConfig, exists := npw.serviceInfo[ktypes.NamespacedName{Namespace: namespace, Name: name}]; exists {
+		service, hasLocalHostNetworkEp = svcConfig.service, svcConfig.hasLocalHostNetworkEp
+	}
+	npw.serviceInfoLock.Unlock()
+	if service == nil {
+		return nil, fmt.Errorf("service %s/%s not found", namespace, name)
+	}
+	flush := &metrics.ServiceConntrackFlush{Service: namespace + "/" + name, Tuples: []metrics.ServiceConntrackTuple{}}
+	if config.Gateway.DisableConntrackFlush {
+		return flush, nil
+	}
+	hostSteered := serviceExternalTrafficPolicyLocal(service) && hasLocalHostNetworkEp
+	for _, tuple := range npw.serviceConntrackTuples(service) {
+		flush.Tuples = append(flush.Tuples, metrics.ServiceConntrackTuple{
+			Type:     tuple.vipType,
+			IP:       tuple.ip,
+			Port:     tuple.port,
+			Protocol: string(tuple.protocol),
+			Zones:    tuple.zones(hostSteered),
+		})
+	}
+	return flush, nil
+}
+
+// deleteConntrackForService deletes the conntrack entries corresponding to the service VIPs of the provided service
+func (npw *nodePortWatcher) deleteConntrackForService(service *kapi.Service) error {
+	if config.Gateway.DisableConntrackFlush {
+		klog.V(4).Infof("Conntrack flush disabled, skipping the deletion of the conntrack entries of service %s/%s",
+			service.Namespace, service.Name)
+		return nil
+	}
+	// the deletion of all the entries of the service is bounded by the drain window
+	deleter := &serviceConntrackDeleter{deadline: time.Now().Add(serviceConntrackDrainWindow)}
+	defer func() {
+		if deleter.capped() {
+			klog.Warningf("Deleted the maximum of %d conntrack entries for service %s/%s, some of its entries "+
+				"might remain: this is unusual, check the service", config.Gateway.MaxServiceConntrackDeletes,
+				service.Namespace, service.Name)
+		}
+	}()
+	for _, tuple := range npw.serviceConntrackTuples(service) {
+		if err := deleter.delete(tuple.ip, tuple.port, tuple.protocol); err != nil {
+			return fmt.Errorf("failed to delete conntrack entry for service %s/%s with %s %s, port %d, protocol %s: %v",
+				service.Namespace, service.Name, tuple.vipType, tuple.ip, tuple.port, tuple.protocol, err)
+		}
 	}
 	return nil
 }
@@ -667,17 +2354,36 @@ func (npw *nodePortWatcher) deleteConntrackForService(service *kapi.Service) err
 func (npw *nodePortWatcher) DeleteService(service *kapi.Service) error {
 	var err error
 	var errors []error
+	if isExternalNameServiceWithIPs(service) {
+		if err = npw.updateExternalNameServiceFlows(service, false); err != nil {
+			return fmt.Errorf("DeleteService failed for nodePortWatcher: %v", err)
+		}
+		return nil
+	}
+	if isHeadlessServiceWithExternalIPs(service) {
+		if err = npw.updateHeadlessServiceFlows(service, false); err != nil {
+			return fmt.Errorf("DeleteService failed for nodePortWatcher: %v", err)
+		}
+		return nil
+	}
 	if !util.ServiceTypeHasClusterIP(service) || !util.IsClusterIPSet(service) {
 		return nil
 	}
 
 	klog.V(5).Infof("Deleting service %s in namespace %s", service.Name, service.Namespace)
 	name := ktypes.NamespacedName{Namespace: service.Namespace, Name: service.Name}
-	if svcConfig, exists := npw.getAndDeleteServiceInfo(name); exists {
+	if svcConfig, exists := npw.getAndDeleteServiceInfoOnDelete(name); exists {
 		if err = delServiceRules(svcConfig.service, sets.List(svcConfig.localEndpoints), npw); err != nil {
 			errors = append(errors, err)
 		}
-	} else {
+		if err = npw.deleteSvcViaMgmPortRoutes(name); err != nil {
+			errors = append(errors, err)
+		}
+		if npw.flowErrorReporter != nil {
+			npw.flowErrorReporter.report(service, nil)
+		}
+	} else if len(service.Spec.Ports) > 0 {
+		// services without ports are not cached
 		klog.Warningf("Delete service: no service found in cache for endpoint %s in namespace %s", service.Name, service.Namespace)
 	}
 	// Remove all conntrack entries for the serviceVIPs of this service irrespective of protocol stack
@@ -698,6 +2404,11 @@ func (npw *nodePortWatcher) SyncServices(services []interface{}) error {
 	var err error
 	var errors []error
 	keepIPTRules := []nodeipt.Rule{}
+	// remove any service flows cached under keys that might collide, they
+	// are cached again below under the proper keys
+	npw.ofm.deleteFlowsByKeyFunc(isStaleServiceFlowCacheKey)
+	// recompute the flow paths of the services below
+	npw.resetServiceFlowPaths()
 	for _, serviceInterface := range services {
 		name := ktypes.NamespacedName{Namespace: serviceInterface.(*kapi.Service).Namespace, Name: serviceInterface.(*kapi.Service).Name}
 
@@ -707,17 +2418,43 @@ func (npw *nodePortWatcher) SyncServices(services []interface{}) error {
 				serviceInterface)
 			continue
 		}
+		if len(service.Spec.Ports) == 0 {
+			klog.V(5).Infof("Skipping service %s in namespace %s without ports during sync", service.Name, service.Namespace)
+			continue
+		}
+		if isExternalNameServiceWithIPs(service) {
+			if err = npw.updateExternalNameServiceFlows(service, true); err != nil {
+				errors = append(errors, err)
+			}
+			continue
+		}
+		if isHeadlessServiceWithExternalIPs(service) {
+			if err = npw.updateHeadlessServiceFlows(service, true); err != nil {
+				errors = append(errors, err)
+			}
+			continue
+		}
+		if !util.ServiceTypeHasClusterIP(service) || !util.IsClusterIPSet(service) {
+			// like on add, ExternalName and headless services get no rules
+			continue
+		}
 
 		epSlices, err := npw.watchFactory.GetEndpointSlices(service.Namespace, service.Name)
 		if err != nil {
 			if !kerrors.IsNotFound(err) {
-				return fmt.Errorf("error retrieving all endpointslices for service %s/%s during SyncServices: %w",
+				err = fmt.Errorf("error retrieving all endpointslices for service %s/%s during SyncServices: %w",
 					service.Namespace, service.Name, err)
+				if !config.Gateway.SyncServicesContinueOnError {
+					return err
+				}
+				// keep syncing the other services, the error is returned with the others
+				errors = append(errors, err)
+				continue
 			}
 			klog.V(5).Infof("No endpointslice found for service %s in namespace %s during sync", service.Name, service.Namespace)
 			continue
 		}
-		nodeIPs := npw.nodeIPManager.ListAddresses()
+		nodeIPs := npw.getHostNetworkEndpointNodeIPs()
 		localEndpoints := npw.GetLocalEndpointAddresses(epSlices, service)
 		hasLocalHostNetworkEp := util.HasLocalHostNetworkEndpoints(localEndpoints, nodeIPs)
 		npw.getAndSetServiceInfo(name, service, hasLocalHostNetworkEp, localEndpoints)
@@ -732,6 +2469,12 @@ func (npw *nodePortWatcher) SyncServices(services []interface{}) error {
 		// Add correct iptables rules only for Full mode
 		if !npw.dpuMode {
 			keepIPTRules = append(keepIPTRules, getGatewayIPTRules(service, sets.List(localEndpoints), hasLocalHostNetworkEp)...)
+			// the services cached here are not added again, route the ClusterIPs of the ITP=local ones now
+			if npw.svcViaMgmPortRoutes != nil {
+				if err = npw.svcViaMgmPortRoutes.sync(service); err != nil {
+					errors = append(errors, err)
+				}
+			}
 		}
 	}
 
@@ -748,10 +2491,49 @@ func (npw *nodePortWatcher) SyncServices(services []interface{}) error {
 		if err = recreateIPTRules("mangle", iptableITPChain, keepIPTRules); err != nil {
 			errors = append(errors, err)
 		}
+		if err = recreateServiceMarkIPTRules(keepIPTRules); err != nil {
+			errors = append(errors, err)
+		}
 	}
 	return apierrors.NewAggregate(errors)
 }
 
+const (
+	// endpointSliceServiceRetryInterval is the interval at which the service
+	// of an added endpoint slice is looked up again when not found
+	endpointSliceServiceRetryInterval = time.Second
+	// endpointSliceServiceMaxRetries is the number of times the service of an
+	// added endpoint slice is looked up again when not found
+	endpointSliceServiceMaxRetries = 5
+)
+
+// requeueEndpointSliceAdd retries the add of an endpoint slice whose service
+// was not found, as the service informer might lag behind the endpoint slice
+// informer and no further endpoint slice event might come to program the
+// service rules. Retries are bounded since endpoint slices might legitimately
+// exist without a service.
+func (npw *nodePortWatcher) requeueEndpointSliceAdd(epSlice *discovery.EndpointSlice, attempt int) {
+	if attempt > endpointSliceServiceMaxRetries {
+		klog.V(5).Infof("No service found for endpointslice %s in namespace %s after %d retries, giving up",
+			epSlice.Name, epSlice.Namespace, endpointSliceServiceMaxRetries)
+		return
+	}
+	time.AfterFunc(endpointSliceServiceRetryInterval, func() {
+		svcName := epSlice.Labels[discovery.LabelServiceName]
+		if _, err := npw.watchFactory.GetService(epSlice.Namespace, svcName); err != nil {
+			if kerrors.IsNotFound(err) {
+				npw.requeueEndpointSliceAdd(epSlice, attempt+1)
+				return
+			}
+			klog.Errorf("Error retrieving service %s/%s during endpointslice add retry: %v", epSlice.Namespace, svcName, err)
+			return
+		}
+		if err := npw.AddEndpointSlice(epSlice); err != nil {
+			klog.Errorf("Failed to add endpointslice %s in namespace %s on retry: %v", epSlice.Name, epSlice.Namespace, err)
+		}
+	})
+}
+
 func (npw *nodePortWatcher) AddEndpointSlice(epSlice *discovery.EndpointSlice) error {
 	var err error
 	var errors []error
@@ -765,18 +2547,33 @@ func (npw *nodePortWatcher) AddEndpointSlice(epSlice *discovery.EndpointSlice) e
 				epSlice.Namespace, svcName, err)
 		}
 		// This is not necessarily an error. For e.g when there are endpoints
-		// without a corresponding service.
+		// without a corresponding service. But it could also be that the
+		// service informer lags behind, so retry for a while.
 		klog.V(5).Infof("No service found for endpointslice %s in namespace %s during endpointslice add",
 			epSlice.Name, epSlice.Namespace)
+		npw.requeueEndpointSliceAdd(epSlice, 1)
 		return nil
 	}
 
-	if !util.ServiceTypeHasClusterIP(svc) || !util.IsClusterIPSet(svc) {
+	if !util.ServiceTypeHasClusterIP(svc) || !util.IsClusterIPSet(svc) || len(svc.Spec.Ports) == 0 {
 		return nil
 	}
 
 	klog.V(5).Infof("Adding endpointslice %s in namespace %s", epSlice.Name, epSlice.Namespace)
-	nodeIPs := npw.nodeIPManager.ListAddresses()
+	namespacedName, err := util.ServiceNamespacedNameFromEndpointSlice(epSlice)
+	if err != nil {
+		return fmt.Errorf("cannot add %s/%s to nodePortWatcher: %v", epSlice.Namespace, epSlice.Name, err)
+	}
+	// Lock the cache mutex while the rules are programmed, so that a concurrent service add
+	// does not program them at the same time, possibly from a different state
+	npw.serviceInfoLock.Lock()
+	defer npw.serviceInfoLock.Unlock()
+	if npw.deletedServices.Has(namespacedName) {
+		klog.V(5).Infof("Ignoring endpointslice %s ADD event in namespace %s of a deleted service", epSlice.Name, epSlice.Namespace)
+		return nil
+	}
+
+	nodeIPs := npw.getHostNetworkEndpointNodeIPs()
 	epSlices, err := npw.watchFactory.GetEndpointSlices(svc.Namespace, svc.Name)
 	if err != nil {
 		// No need to continue adding the new endpoint slice, if we can't retrieve all slices for this service
@@ -788,18 +2585,17 @@ func (npw *nodePortWatcher) AddEndpointSlice(epSlice *discovery.EndpointSlice) e
 	// Here we make sure the correct rules are programmed whenever an AddEndpointSlice event is
 	// received, only alter flows if we need to, i.e if cache wasn't set or if it was and
 	// hasLocalHostNetworkEp or localEndpoints state (for LB svc where NPs=0) changed, to prevent flow churn
-	namespacedName, err := util.ServiceNamespacedNameFromEndpointSlice(epSlice)
-	if err != nil {
-		return fmt.Errorf("cannot add %s/%s to nodePortWatcher: %v", epSlice.Namespace, epSlice.Name, err)
-	}
-	out, exists := npw.getAndSetServiceInfo(namespacedName, svc, hasLocalHostNetworkEp, localEndpoints)
+	out, exists := npw.serviceInfo[namespacedName]
+	npw.serviceInfo[namespacedName] = &serviceConfig{service: svc, hasLocalHostNetworkEp: hasLocalHostNetworkEp, localEndpoints: localEndpoints,
+		lastEndpointChange: time.Now()}
 	if !exists {
 		klog.V(5).Infof("Endpointslice %s ADD event in namespace %s is creating rules", epSlice.Name, epSlice.Namespace)
 		return addServiceRules(svc, sets.List(localEndpoints), hasLocalHostNetworkEp, npw)
 	}
 
 	if out.hasLocalHostNetworkEp != hasLocalHostNetworkEp ||
-		(!util.LoadBalancerServiceHasNodePortAllocation(svc) && !reflect.DeepEqual(out.localEndpoints, localEndpoints)) {
+		((!util.LoadBalancerServiceHasNodePortAllocation(svc) || isPreferDualStackService(svc)) &&
+			!reflect.DeepEqual(out.localEndpoints, localEndpoints)) {
 		klog.V(5).Infof("Endpointslice %s ADD event in namespace %s is updating rules", epSlice.Name, epSlice.Namespace)
 		if err = delServiceRules(svc, sets.List(out.localEndpoints), npw); err != nil {
 			errors = append(errors, err)
@@ -825,6 +2621,12 @@ func (npw *nodePortWatcher) DeleteEndpointSlice(epSlice *discovery.EndpointSlice
 		return fmt.Errorf("cannot delete %s/%s from nodePortWatcher: %v", epSlice.Namespace, epSlice.Name, err)
 	}
 	epSlices, err := npw.watchFactory.GetEndpointSlices(epSlice.Namespace, epSlice.Labels[discovery.LabelServiceName])
+	if err == nil && len(epSlices) == 0 || kerrors.IsNotFound(err) {
+		// the endpoint slices of a deleted service are gone, stop tracking it
+		npw.serviceInfoLock.Lock()
+		npw.deletedServices.Delete(namespacedName)
+		npw.serviceInfoLock.Unlock()
+	}
 	if err != nil {
 		if !kerrors.IsNotFound(err) {
 			return fmt.Errorf("error retrieving all endpointslices for service %s/%s during endpointslice delete on %s: %w",
@@ -861,7 +2663,50 @@ func (npw *nodePortWatcher) DeleteEndpointSlice(epSlice *discovery.EndpointSlice
 
 // GetLocalEndpointAddresses returns a list of eligible endpoints that are local to the node
 func (npw *nodePortWatcher) GetLocalEndpointAddresses(endpointSlices []*discovery.EndpointSlice, service *kapi.Service) sets.Set[string] {
-	return util.GetLocalEndpointAddresses(endpointSlices, service, npw.nodeIPManager.nodeName)
+	localEndpoints := util.GetLocalEndpointAddressesWithLocality(endpointSlices, service, npw.nodeIPManager.nodeName, npw.endpointLocality)
+	if klogV := klog.V(5); klogV.Enabled() && service != nil {
+		klogV.Infof("Selected local endpoints %v of service %s/%s on node %s: %s", sets.List(localEndpoints),
+			service.Namespace, service.Name, npw.nodeIPManager.nodeName,
+			describeLocalEndpointSelection(endpointSlices, service, npw.nodeIPManager.nodeName, npw.endpointLocality))
+	}
+	return localEndpoints
+}
+
+// describeLocalEndpointSelection returns, for debugging, the reason each
+// endpoint of a service was or was not selected as local to the node by
+// util.GetLocalEndpointAddressesWithLocality
+func describeLocalEndpointSelection(endpointSlices []*discovery.EndpointSlice, service *kapi.Service, nodeName string,
+	isLocal util.EndpointLocalityFunc) string {
+	match := "locality match"
+	if isLocal == nil {
+		isLocal = util.IsEndpointOnNode
+		match = "node name match"
+	}
+	var reasons []string
+	for _, endpointSlice := range endpointSlices {
+		for _, endpoint := range endpointSlice.Endpoints {
+			var reason string
+			switch {
+			case !util.IsEndpointEligible(endpoint, service.Spec.PublishNotReadyAddresses):
+				reason = "ignored: not serving"
+			case !isLocal(endpoint, nodeName):
+				reason = "remote"
+				if endpoint.NodeName != nil {
+					reason = fmt.Sprintf("remote: on node %s", *endpoint.NodeName)
+				}
+			case !util.IsEndpointServing(endpoint):
+				reason = fmt.Sprintf("local: %s, not serving but not ready addresses are published", match)
+			case endpoint.Conditions.Terminating != nil && *endpoint.Conditions.Terminating:
+				reason = fmt.Sprintf("local: %s, terminating but serving", match)
+			default:
+				reason = "local: " + match
+			}
+			for _, ip := range endpoint.Addresses {
+				reasons = append(reasons, fmt.Sprintf("%s (%s)", utilnet.ParseIPSloppy(ip), reason))
+			}
+		}
+	}
+	return strings.Join(reasons, ", ")
 }
 
 func (npw *nodePortWatcher) UpdateEndpointSlice(oldEpSlice, newEpSlice *discovery.EndpointSlice) error {
@@ -881,23 +2726,53 @@ func (npw *nodePortWatcher) UpdateEndpointSlice(oldEpSlice, newEpSlice *discover
 			namespacedName.Namespace, namespacedName.Name, newEpSlice.Name, err)
 	}
 
-	oldEndpointAddresses := util.GetEndpointAddresses([]*discovery.EndpointSlice{oldEpSlice}, svc)
-	newEndpointAddresses := util.GetEndpointAddresses([]*discovery.EndpointSlice{newEpSlice}, svc)
+	epSlices, err := npw.watchFactory.GetEndpointSlices(newEpSlice.Namespace, newEpSlice.Labels[discovery.LabelServiceName])
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return fmt.Errorf("error retrieving all endpointslices for service %s/%s during endpointslice update on %s: %w",
+				namespacedName.Namespace, namespacedName.Name, newEpSlice.Name, err)
+		}
+		klog.V(5).Infof("No endpointslices found for service %s/%s during endpointslice update on %s: %v",
+			namespacedName.Namespace, namespacedName.Name, newEpSlice.Name, err)
+	}
+
+	// Compare the endpoint addresses of the service across all its endpoint slices, so that an
+	// address moving between slices, or duplicated in several of them, is not seen as a change
+	oldEndpointAddresses := serviceEndpointAddresses(epSlices, oldEpSlice, svc)
+	newEndpointAddresses := serviceEndpointAddresses(epSlices, newEpSlice, svc)
 	if reflect.DeepEqual(oldEndpointAddresses, newEndpointAddresses) {
 		return nil
 	}
 
+	// flush the connections to the removed endpoints before the old endpoint
+	// slice is lost to the coalescer
+	if svc != nil {
+		removedEndpoints := oldEndpointAddresses.Difference(newEndpointAddresses)
+		if err = npw.deleteConntrackForRemovedEndpoints(svc, removedEndpoints); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if npw.endpointSliceCoalescer != nil {
+		klog.V(5).Infof("Coalescing update of endpointslice %s in namespace %s", newEpSlice.Name, newEpSlice.Namespace)
+		npw.endpointSliceCoalescer.enqueue(namespacedName)
+		return apierrors.NewAggregate(errors)
+	}
+
 	klog.V(5).Infof("Updating endpointslice %s in namespace %s", oldEpSlice.Name, oldEpSlice.Namespace)
+	npw.recordEndpointChange(namespacedName)
 
 	var serviceInfo *serviceConfig
 	var exists bool
 	if serviceInfo, exists = npw.getServiceInfo(namespacedName); !exists {
-		// When a service is updated from externalName to nodeport type, it won't be
-		// in nodePortWatcher cache (npw): in this case, have the new nodeport IPtable rules
-		// installed.
+		// When a service is updated from externalName to nodeport type before
+		// the service update is handled, it won't be in nodePortWatcher cache
+		// (npw): in this case, have the new nodeport IPtable rules installed
+		// from all the current endpoint slices, nothing else to update.
 		if err = npw.AddEndpointSlice(newEpSlice); err != nil {
 			errors = append(errors, err)
 		}
+		return apierrors.NewAggregate(errors)
 	} else if len(newEndpointAddresses) == 0 {
 		// With no endpoint addresses in new endpointslice, delete old endpoint rules
 		// and add normal ones back
@@ -907,29 +2782,15 @@ func (npw *nodePortWatcher) UpdateEndpointSlice(oldEpSlice, newEpSlice *discover
 	}
 
 	// Update rules and service cache if hasHostNetworkEndpoints status changed or localEndpoints changed
-	nodeIPs := npw.nodeIPManager.ListAddresses()
-	epSlices, err := npw.watchFactory.GetEndpointSlices(newEpSlice.Namespace, newEpSlice.Labels[discovery.LabelServiceName])
-	if err != nil {
-		if !kerrors.IsNotFound(err) {
-			return fmt.Errorf("error retrieving all endpointslices for service %s/%s during endpointslice update on %s: %w",
-				namespacedName.Namespace, namespacedName.Name, newEpSlice.Name, err)
-		}
-		klog.V(5).Infof("No endpointslices found for service %s/%s during endpointslice update on %s: %v",
-			namespacedName.Namespace, namespacedName.Name, newEpSlice.Name, err)
-	}
+	nodeIPs := npw.getHostNetworkEndpointNodeIPs()
 
 	// Delete old endpoint slice and add new one when local endpoints have changed or the presence of local host-network
-	// endpoints has changed. For this second comparison, check first between the old endpoint slice and all current
-	// endpointslices for this service. This is a partial comparison, in case serviceInfo is not set. When it is set, compare
-	// between /all/ old endpoint slices and all new ones.
-	oldLocalEndpoints := npw.GetLocalEndpointAddresses([]*discovery.EndpointSlice{oldEpSlice}, svc)
+	// endpoints has changed, comparing between /all/ old endpoint slices, as cached in serviceInfo, and all new ones.
 	newLocalEndpoints := npw.GetLocalEndpointAddresses(epSlices, svc)
-	hasLocalHostNetworkEpOld := util.HasLocalHostNetworkEndpoints(oldLocalEndpoints, nodeIPs)
 	hasLocalHostNetworkEpNew := util.HasLocalHostNetworkEndpoints(newLocalEndpoints, nodeIPs)
 
-	localEndpointsHaveChanged := serviceInfo != nil && !reflect.DeepEqual(serviceInfo.localEndpoints, newLocalEndpoints)
-	localHostNetworkEndpointsPresenceHasChanged := hasLocalHostNetworkEpOld != hasLocalHostNetworkEpNew ||
-		serviceInfo != nil && serviceInfo.hasLocalHostNetworkEp != hasLocalHostNetworkEpNew
+	localEndpointsHaveChanged := !reflect.DeepEqual(serviceInfo.localEndpoints, newLocalEndpoints)
+	localHostNetworkEndpointsPresenceHasChanged := serviceInfo.hasLocalHostNetworkEp != hasLocalHostNetworkEpNew
 
 	if localEndpointsHaveChanged || localHostNetworkEndpointsPresenceHasChanged {
 		if err = npw.DeleteEndpointSlice(oldEpSlice); err != nil {
@@ -944,6 +2805,74 @@ func (npw *nodePortWatcher) UpdateEndpointSlice(oldEpSlice, newEpSlice *discover
 	return apierrors.NewAggregate(errors)
 }
 
+// serviceEndpointAddresses returns the eligible endpoint addresses of a
+// service from its endpoint slices, with the given version of one of them in
+// place of the one in epSlices.
+func serviceEndpointAddresses(epSlices []*discovery.EndpointSlice, epSlice *discovery.EndpointSlice, svc *kapi.Service) sets.Set[string] {
+	slices := []*discovery.EndpointSlice{epSlice}
+	for _, slice := range epSlices {
+		if slice.Name != epSlice.Name {
+			slices = append(slices, slice)
+		}
+	}
+	return util.GetEndpointAddresses(slices, svc)
+}
+
+// syncServiceEndpoints recomputes the local endpoints of the given service
+// from all of its current endpoint slices and updates its rules if they
+// changed. It is used to apply coalesced endpoint slice updates.
+func (npw *nodePortWatcher) syncServiceEndpoints(namespacedName ktypes.NamespacedName) error {
+	var errors []error
+	svc, err := npw.watchFactory.GetService(namespacedName.Namespace, namespacedName.Name)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			// the rules are removed with the service
+			return nil
+		}
+		return fmt.Errorf("error retrieving service %s/%s during endpoints sync: %w",
+			namespacedName.Namespace, namespacedName.Name, err)
+	}
+	if !util.ServiceTypeHasClusterIP(svc) || !util.IsClusterIPSet(svc) || len(svc.Spec.Ports) == 0 {
+		return nil
+	}
+
+	// Lock the cache mutex while the rules are programmed, so that a concurrent service or
+	// endpointslice add does not program them at the same time, possibly from a different state
+	npw.serviceInfoLock.Lock()
+	defer npw.serviceInfoLock.Unlock()
+	if npw.deletedServices.Has(namespacedName) {
+		klog.V(5).Infof("Ignoring endpoints sync of deleted service %s", namespacedName)
+		return nil
+	}
+	epSlices, err := npw.watchFactory.GetEndpointSlices(svc.Namespace, svc.Name)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("error retrieving endpointslices for service %s/%s during endpoints sync: %w",
+			svc.Namespace, svc.Name, err)
+	}
+	localEndpoints := npw.GetLocalEndpointAddresses(epSlices, svc)
+	hasLocalHostNetworkEp := util.HasLocalHostNetworkEndpoints(localEndpoints, npw.getHostNetworkEndpointNodeIPs())
+
+	out, exists := npw.serviceInfo[namespacedName]
+	npw.serviceInfo[namespacedName] = &serviceConfig{service: svc, hasLocalHostNetworkEp: hasLocalHostNetworkEp, localEndpoints: localEndpoints,
+		lastEndpointChange: time.Now()}
+	if !exists {
+		klog.V(5).Infof("Endpoints sync of service %s is creating rules", namespacedName)
+		return addServiceRules(svc, sets.List(localEndpoints), hasLocalHostNetworkEp, npw)
+	}
+	if out.hasLocalHostNetworkEp == hasLocalHostNetworkEp && reflect.DeepEqual(out.localEndpoints, localEndpoints) {
+		return nil
+	}
+
+	klog.V(5).Infof("Endpoints sync of service %s is updating rules", namespacedName)
+	if err = delServiceRules(out.service, sets.List(out.localEndpoints), npw); err != nil {
+		errors = append(errors, err)
+	}
+	if err = addServiceRules(svc, sets.List(localEndpoints), hasLocalHostNetworkEp, npw); err != nil {
+		errors = append(errors, err)
+	}
+	return apierrors.NewAggregate(errors)
+}
+
 func (npwipt *nodePortWatcherIptables) AddService(service *kapi.Service) error {
 	// don't process headless service or services that doesn't have NodePorts or ExternalIPs
 	if !util.ServiceTypeHasClusterIP(service) || !util.IsClusterIPSet(service) {
@@ -1027,6 +2956,24 @@ func (npwipt *nodePortWatcherIptables) SyncServices(services []interface{}) erro
 //
 // -- to handle host -> service access, via masquerading from the host to OVN GR
 // -- to handle external -> service(ExternalTrafficPolicy: Local) -> host access without SNAT
+// defaultBridgeFlowsPerServiceCIDR is the number of default flows added to the
+// gateway bridge for each service CIDR, see flowsForDefaultBridge
+const defaultBridgeFlowsPerServiceCIDR = 3
+
+// checkServiceCIDRFlowBudget reports the number of gateway bridge default flows
+// for the service CIDRs and warns if it exceeds Gateway.ServiceCIDRFlowBudget,
+// returning whether it does
+func checkServiceCIDRFlowBudget() bool {
+	flows := len(config.Kubernetes.ServiceCIDRs) * defaultBridgeFlowsPerServiceCIDR
+	metrics.MetricServiceCIDRFlows.Set(float64(flows))
+	if config.Gateway.ServiceCIDRFlowBudget == 0 || flows <= config.Gateway.ServiceCIDRFlowBudget {
+		return false
+	}
+	klog.Warningf("The %d service CIDRs add %d default flows to the gateway bridge, exceeding the budget of %d flows",
+		len(config.Kubernetes.ServiceCIDRs), flows, config.Gateway.ServiceCIDRFlowBudget)
+	return true
+}
+
 func newGatewayOpenFlowManager(gwBridge, exGWBridge *bridgeConfiguration, subnets []*net.IPNet, extraIPs []net.IP) (*openflowManager, error) {
 	// add health check function to check default OpenFlow flows are on the shared gateway bridge
 	ofm := &openflowManager{
@@ -1037,11 +2984,16 @@ func newGatewayOpenFlowManager(gwBridge, exGWBridge *bridgeConfiguration, subnet
 		exGWFlowCache:         make(map[string][]string),
 		exGWFlowMutex:         sync.Mutex{},
 		flowChan:              make(chan struct{}, 1),
+		lastSyncTime:          make(map[string]time.Time),
+		// the table 1 flows of the pod subnets depend on it
+		disableSNATMultipleGWs: config.Gateway.DisableSNATMultipleGWs,
 	}
 
+	checkServiceCIDRFlowBudget()
 	if err := ofm.updateBridgeFlowCache(subnets, extraIPs); err != nil {
 		return nil, err
 	}
+	metrics.SetBridgeFlowSyncTimesFunc(ofm.getLastSyncTimes)
 
 	// defer flowSync until syncService() to prevent the existing service OpenFlows being deleted
 	return ofm, nil
@@ -1054,31 +3006,80 @@ func (ofm *openflowManager) updateBridgeFlowCache(subnets []*net.IPNet, extraIPs
 	ofm.defaultBridge.Lock()
 	defer ofm.defaultBridge.Unlock()
 
+	if config.Gateway.DisableSNATMultipleGWs != ofm.disableSNATMultipleGWs {
+		return fmt.Errorf("disable-snat-multiple-gws changed from %t to %t after the gateway started, "+
+			"it is immutable and can only be changed with a restart", ofm.disableSNATMultipleGWs,
+			config.Gateway.DisableSNATMultipleGWs)
+	}
+
 	dftFlows, err := flowsForDefaultBridge(ofm.defaultBridge, extraIPs)
 	if err != nil {
 		return err
 	}
+	if err := validateEgressSNATSourceIPs(ofm.defaultBridge.ips); err != nil {
+		return err
+	}
 	dftCommonFlows, err := commonFlows(subnets, ofm.defaultBridge)
 	if err != nil {
 		return err
 	}
 	dftFlows = append(dftFlows, dftCommonFlows...)
+	if ofm.defaultBridge.ofPortHost == ofPortHostUnavailable {
+		dftFlows = withoutHostRepresentorFlows(dftFlows)
+	}
 
-	ofm.updateFlowCacheEntry("NORMAL", []string{fmt.Sprintf("table=0,priority=0,actions=%s\n", util.NormalAction)})
+	ofm.updateFlowCacheEntry("NORMAL", []string{fallbackFlow(config.Gateway.FallbackAction)})
 	ofm.updateFlowCacheEntry("DEFAULT", dftFlows)
 
+	return ofm.updateExBridgeFlowCache(subnets)
+}
+
+// updateExBridgeFlowCache drains and regenerates the "static" flows of the
+// external gateway bridge, if any, without touching the ones of the default
+// bridge. Used on its own for changes only affecting the external gateway
+// bridge, followed by syncExBridgeFlows.
+func (ofm *openflowManager) updateExBridgeFlowCache(subnets []*net.IPNet) error {
 	// we consume ex gw bridge flows only if that is enabled
-	if ofm.externalGatewayBridge != nil {
-		ofm.updateExBridgeFlowCacheEntry("NORMAL", []string{fmt.Sprintf("table=0,priority=0,actions=%s\n", util.NormalAction)})
-		exGWBridgeDftFlows, err := commonFlows(subnets, ofm.externalGatewayBridge)
-		if err != nil {
-			return err
+	if ofm.externalGatewayBridge == nil {
+		return nil
+	}
+	ofm.externalGatewayBridge.Lock()
+	defer ofm.externalGatewayBridge.Unlock()
+
+	exGWBridgeDftFlows, err := commonFlows(subnets, ofm.externalGatewayBridge)
+	if err != nil {
+		return err
+	}
+	if ofm.externalGatewayBridge.ofPortHost == ofPortHostUnavailable {
+		exGWBridgeDftFlows = withoutHostRepresentorFlows(exGWBridgeDftFlows)
+	}
+
+	ofm.exGWFlowMutex.Lock()
+	defer ofm.exGWFlowMutex.Unlock()
+	exGWFlowCache := map[string][]string{
+		"NORMAL":  {fallbackFlow(config.Gateway.EgressGWFallbackAction)},
+		"DEFAULT": exGWBridgeDftFlows,
+	}
+	// the service flows of the bridge, see updateExGWBridgeServiceFlows, are
+	// not static and are kept
+	for key, flows := range ofm.exGWFlowCache {
+		if strings.HasPrefix(key, exGWBridgeFlowCacheKey(ofm.externalGatewayBridge.bridgeName, "")) {
+			exGWFlowCache[key] = flows
 		}
-		ofm.updateExBridgeFlowCacheEntry("DEFAULT", exGWBridgeDftFlows)
 	}
+	ofm.exGWFlowCache = exGWFlowCache
 	return nil
 }
 
+// fallbackFlow returns the table 0 priority 0 flow of a bridge, applying the
+// given config.Gateway fallback action to the traffic no other flow matches
+func fallbackFlow(action string) string {
+	if action == config.GatewayFallbackActionDrop {
+		return "table=0,priority=0,actions=drop\n"
+	}
+	return fmt.Sprintf("table=0,priority=0,actions=%s\n", util.NormalAction)
+}
+
 func flowsForDefaultBridge(bridge *bridgeConfiguration, extraIPs []net.IP) ([]string, error) {
 	ofPortPhys := bridge.ofPortPhys
 	bridgeMacAddress := bridge.macAddress.String()
@@ -1090,21 +3091,23 @@ func flowsForDefaultBridge(bridge *bridgeConfiguration, extraIPs []net.IP) ([]st
 
 	if config.IPv4Mode {
 		// table0, Geneve packets coming from external. Skip conntrack and go directly to host
-		// if dest mac is the shared mac send directly to host.
+		// if dest mac is the shared mac send directly to host, unless disabled for offload setups.
 		if ofPortPhys != "" {
-			dftFlows = append(dftFlows,
-				fmt.Sprintf("cookie=%s, priority=205, in_port=%s, dl_dst=%s, udp, udp_dst=%d, "+
-					"actions=output:%s", defaultOpenFlowCookie, ofPortPhys, bridgeMacAddress, config.Default.EncapPort,
-					ofPortHost))
+			if !config.Gateway.DisableGeneveDirectToHostFlow {
+				dftFlows = append(dftFlows,
+					fmt.Sprintf("cookie=%s, priority=205, in_port=%s, dl_dst=%s, udp, udp_dst=%d, "+
+						"actions=output:%s", geneveOpenFlowCookie, ofPortPhys, bridgeMacAddress, config.Default.EncapPort,
+						ofPortHost))
+			}
 			// perform NORMAL action otherwise.
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=200, in_port=%s, udp, udp_dst=%d, "+
-					"actions=NORMAL", defaultOpenFlowCookie, ofPortPhys, config.Default.EncapPort))
+					"actions=NORMAL", geneveOpenFlowCookie, ofPortPhys, config.Default.EncapPort))
 
 			// table0, Geneve packets coming from LOCAL. Skip conntrack and go directly to external
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=200, in_port=%s, udp, udp_dst=%d, "+
-					"actions=output:%s", defaultOpenFlowCookie, ovsLocalPort, config.Default.EncapPort, ofPortPhys))
+					"actions=output:%s", geneveOpenFlowCookie, ovsLocalPort, config.Default.EncapPort, ofPortPhys))
 		}
 		physicalIP, err := util.MatchFirstIPNetFamily(false, bridgeIPs)
 		if err != nil {
@@ -1114,7 +3117,7 @@ func flowsForDefaultBridge(bridge *bridgeConfiguration, extraIPs []net.IP) ([]st
 		dftFlows = append(dftFlows,
 			fmt.Sprintf("cookie=%s, priority=500, in_port=%s, ip, ip_dst=%s, ip_src=%s,"+
 				"actions=ct(commit,zone=%d,nat(dst=%s),table=4)",
-				defaultOpenFlowCookie, ofPortPatch, types.V4HostMasqueradeIP, physicalIP.IP,
+				hairpinOpenFlowCookie, ofPortPatch, types.V4HostMasqueradeIP, physicalIP.IP,
 				HostMasqCTZone, physicalIP.IP))
 
 		// table 0, hairpin from OVN destined to local host (but an additional node IP), send to table 4
@@ -1135,7 +3138,7 @@ func flowsForDefaultBridge(bridge *bridgeConfiguration, extraIPs []net.IP) ([]st
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=500, in_port=%s, ip, ip_dst=%s, ip_src=%s,"+
 					"actions=ct(commit,zone=%d,table=4)",
-					defaultOpenFlowCookie, ofPortPatch, ip.String(), physicalIP.IP,
+					hairpinOpenFlowCookie, ofPortPatch, ip.String(), physicalIP.IP,
 					HostMasqCTZone))
 		}
 
@@ -1143,25 +3146,27 @@ func flowsForDefaultBridge(bridge *bridgeConfiguration, extraIPs []net.IP) ([]st
 		dftFlows = append(dftFlows,
 			fmt.Sprintf("cookie=%s, priority=500, in_port=%s, ip, ip_dst=%s,"+
 				"actions=ct(zone=%d,nat,table=5)",
-				defaultOpenFlowCookie, ofPortHost, types.V4OVNMasqueradeIP, OVNMasqCTZone))
+				hairpinOpenFlowCookie, ofPortHost, types.V4OVNMasqueradeIP, OVNMasqCTZone))
 	}
 	if config.IPv6Mode {
 		if ofPortPhys != "" {
 			// table0, Geneve packets coming from external. Skip conntrack and go directly to host
-			// if dest mac is the shared mac send directly to host.
-			dftFlows = append(dftFlows,
-				fmt.Sprintf("cookie=%s, priority=205, in_port=%s, dl_dst=%s, udp6, udp_dst=%d, "+
-					"actions=output:%s", defaultOpenFlowCookie, ofPortPhys, bridgeMacAddress, config.Default.EncapPort,
-					ofPortHost))
+			// if dest mac is the shared mac send directly to host, unless disabled for offload setups.
+			if !config.Gateway.DisableGeneveDirectToHostFlow {
+				dftFlows = append(dftFlows,
+					fmt.Sprintf("cookie=%s, priority=205, in_port=%s, dl_dst=%s, udp6, udp_dst=%d, "+
+						"actions=output:%s", geneveOpenFlowCookie, ofPortPhys, bridgeMacAddress, config.Default.EncapPort,
+						ofPortHost))
+			}
 			// perform NORMAL action otherwise.
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=200, in_port=%s, udp6, udp_dst=%d, "+
-					"actions=NORMAL", defaultOpenFlowCookie, ofPortPhys, config.Default.EncapPort))
+					"actions=NORMAL", geneveOpenFlowCookie, ofPortPhys, config.Default.EncapPort))
 
 			// table0, Geneve packets coming from LOCAL. Skip conntrack and send to external
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=200, in_port=%s, udp6, udp_dst=%d, "+
-					"actions=output:%s", defaultOpenFlowCookie, ovsLocalPort, config.Default.EncapPort, ofPortPhys))
+					"actions=output:%s", geneveOpenFlowCookie, ovsLocalPort, config.Default.EncapPort, ofPortPhys))
 		}
 
 		physicalIP, err := util.MatchFirstIPNetFamily(true, bridgeIPs)
@@ -1172,7 +3177,7 @@ func flowsForDefaultBridge(bridge *bridgeConfiguration, extraIPs []net.IP) ([]st
 		dftFlows = append(dftFlows,
 			fmt.Sprintf("cookie=%s, priority=500, in_port=%s, ipv6, ipv6_dst=%s, ipv6_src=%s,"+
 				"actions=ct(commit,zone=%d,nat(dst=%s),table=4)",
-				defaultOpenFlowCookie, ofPortPatch, types.V6HostMasqueradeIP, physicalIP.IP,
+				hairpinOpenFlowCookie, ofPortPatch, types.V6HostMasqueradeIP, physicalIP.IP,
 				HostMasqCTZone, physicalIP.IP))
 
 		// table 0, hairpin from OVN destined to local host (but an additional node IP), send to table 4
@@ -1190,10 +3195,17 @@ func flowsForDefaultBridge(bridge *bridgeConfiguration, extraIPs []net.IP) ([]st
 				continue
 			}
 
+			// link local IPs are scoped to the zone of their interface and the host
+			// can't route hairpin traffic to them, as it is not received on that link
+			if ip.IsLinkLocalUnicast() {
+				klog.V(5).Infof("Skipping hairpin flow for link local IP %s of bridge %s", ip, bridge.bridgeName)
+				continue
+			}
+
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=500, in_port=%s, ipv6, ipv6_dst=%s, ipv6_src=%s,"+
 					"actions=ct(commit,zone=%d,table=4)",
-					defaultOpenFlowCookie, ofPortPatch, ip.String(), physicalIP.IP,
+					hairpinOpenFlowCookie, ofPortPatch, ip.String(), physicalIP.IP,
 					HostMasqCTZone))
 		}
 
@@ -1201,12 +3213,33 @@ func flowsForDefaultBridge(bridge *bridgeConfiguration, extraIPs []net.IP) ([]st
 		dftFlows = append(dftFlows,
 			fmt.Sprintf("cookie=%s, priority=500, in_port=%s, ipv6, ipv6_dst=%s,"+
 				"actions=ct(zone=%d,nat,table=5)",
-				defaultOpenFlowCookie, ofPortHost, types.V6OVNMasqueradeIP, OVNMasqCTZone))
+				hairpinOpenFlowCookie, ofPortHost, types.V6OVNMasqueradeIP, OVNMasqCTZone))
 	}
 
 	var protoPrefix string
 	var masqIP string
 
+	// table 0, packets between the host or OVN and the service CIDR exemptions, like a node-local DNS
+	// cache, bypass the Host -> Service SNAT and the drop of the OVN -> Service traffic below
+	for _, exemption := range config.Gateway.GetServiceCIDRExemptions() {
+		if utilnet.IsIPv4CIDR(exemption) {
+			if !config.IPv4Mode {
+				continue
+			}
+			protoPrefix = "ip"
+		} else {
+			if !config.IPv6Mode {
+				continue
+			}
+			protoPrefix = "ipv6"
+		}
+		dftFlows = append(dftFlows,
+			fmt.Sprintf("cookie=%s, priority=550, in_port=%s, %s, %s_dst=%s, actions=NORMAL",
+				defaultOpenFlowCookie, ofPortHost, protoPrefix, protoPrefix, exemption),
+			fmt.Sprintf("cookie=%s, priority=106, in_port=%s, %s, %s_dst=%s, actions=NORMAL",
+				defaultOpenFlowCookie, ofPortPatch, protoPrefix, protoPrefix, exemption))
+	}
+
 	// table 0, packets coming from Host -> Service
 	for _, svcCIDR := range config.Kubernetes.ServiceCIDRs {
 		if utilnet.IsIPv4CIDR(svcCIDR) {
@@ -1246,23 +3279,23 @@ func flowsForDefaultBridge(bridge *bridgeConfiguration, extraIPs []net.IP) ([]st
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=100, table=1, ip, ct_state=+trk+est, ct_mark=%s, "+
 					"actions=%s",
-					defaultOpenFlowCookie, ctMarkOVN, actions))
+					conntrackOpenFlowCookie, ctMarkOVN, actions))
 
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=100, table=1, ip, ct_state=+trk+rel, ct_mark=%s, "+
 					"actions=%s",
-					defaultOpenFlowCookie, ctMarkOVN, actions))
+					conntrackOpenFlowCookie, ctMarkOVN, actions))
 
 			// table 1, established and related connections in zone 64000 with ct_mark ctMarkHost go to host
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=100, table=1, ip, ct_state=+trk+est, ct_mark=%s, "+
 					"actions=output:%s",
-					defaultOpenFlowCookie, ctMarkHost, ofPortHost))
+					conntrackOpenFlowCookie, ctMarkHost, ofPortHost))
 
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=100, table=1, ip, ct_state=+trk+rel, ct_mark=%s, "+
 					"actions=output:%s",
-					defaultOpenFlowCookie, ctMarkHost, ofPortHost))
+					conntrackOpenFlowCookie, ctMarkHost, ofPortHost))
 		}
 
 		if config.IPv6Mode {
@@ -1270,23 +3303,23 @@ func flowsForDefaultBridge(bridge *bridgeConfiguration, extraIPs []net.IP) ([]st
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=100, table=1, ipv6, ct_state=+trk+est, ct_mark=%s, "+
 					"actions=%s",
-					defaultOpenFlowCookie, ctMarkOVN, actions))
+					conntrackOpenFlowCookie, ctMarkOVN, actions))
 
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=100, table=1, ipv6, ct_state=+trk+rel, ct_mark=%s, "+
 					"actions=%s",
-					defaultOpenFlowCookie, ctMarkOVN, actions))
+					conntrackOpenFlowCookie, ctMarkOVN, actions))
 
 			// table 1, established and related connections in zone 64000 with ct_mark ctMarkHost go to host
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=100, table=1, ip6, ct_state=+trk+est, ct_mark=%s, "+
 					"actions=output:%s",
-					defaultOpenFlowCookie, ctMarkHost, ofPortHost))
+					conntrackOpenFlowCookie, ctMarkHost, ofPortHost))
 
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=100, table=1, ip6, ct_state=+trk+rel, ct_mark=%s, "+
 					"actions=output:%s",
-					defaultOpenFlowCookie, ctMarkHost, ofPortHost))
+					conntrackOpenFlowCookie, ctMarkHost, ofPortHost))
 		}
 
 		// table 1, we check to see if this dest mac is the shared mac, if so send to host
@@ -1312,30 +3345,79 @@ func flowsForDefaultBridge(bridge *bridgeConfiguration, extraIPs []net.IP) ([]st
 		dftFlows = append(dftFlows,
 			fmt.Sprintf("cookie=%s, table=4,ip,"+
 				"actions=ct(commit,zone=%d,nat(src=%s),table=3)",
-				defaultOpenFlowCookie, OVNMasqCTZone, types.V4OVNMasqueradeIP))
+				hairpinOpenFlowCookie, OVNMasqCTZone, types.V4OVNMasqueradeIP))
 	}
 	if config.IPv6Mode {
 		dftFlows = append(dftFlows,
 			fmt.Sprintf("cookie=%s, table=4,ipv6, "+
 				"actions=ct(commit,zone=%d,nat(src=%s),table=3)",
-				defaultOpenFlowCookie, OVNMasqCTZone, types.V6OVNMasqueradeIP))
+				hairpinOpenFlowCookie, OVNMasqCTZone, types.V6OVNMasqueradeIP))
 	}
 	// table 5, Host Reply traffic to hairpinned svc, need to unDNAT, send to table 2
 	if config.IPv4Mode {
 		dftFlows = append(dftFlows,
 			fmt.Sprintf("cookie=%s, table=5, ip, "+
 				"actions=ct(commit,zone=%d,nat,table=2)",
-				defaultOpenFlowCookie, HostMasqCTZone))
+				hairpinOpenFlowCookie, HostMasqCTZone))
 	}
 	if config.IPv6Mode {
 		dftFlows = append(dftFlows,
 			fmt.Sprintf("cookie=%s, table=5, ipv6, "+
 				"actions=ct(commit,zone=%d,nat,table=2)",
-				defaultOpenFlowCookie, HostMasqCTZone))
+				hairpinOpenFlowCookie, HostMasqCTZone))
 	}
 	return dftFlows, nil
 }
 
+// getEgressSNATSourceIP returns the IP of the given family the egressIP and
+// egressService marked traffic leaving through a bridge is SNAT-ed to: the
+// configured egress SNAT source IP if it is one of the bridge IPs, otherwise
+// the physical IP of the bridge
+func getEgressSNATSourceIP(ipv6 bool, physicalIP net.IP, bridgeIPs []*net.IPNet) net.IP {
+	for _, ip := range config.Gateway.GetEgressSNATSourceIPs() {
+		if utilnet.IsIPv6(ip) != ipv6 {
+			continue
+		}
+		for _, bridgeIP := range bridgeIPs {
+			if bridgeIP.IP.Equal(ip) {
+				return ip
+			}
+		}
+	}
+	return physicalIP
+}
+
+// validateEgressSNATSourceIPs checks that the configured egress SNAT source
+// IPs are configured on the gateway bridge
+func validateEgressSNATSourceIPs(bridgeIPs []*net.IPNet) error {
+	for _, ip := range config.Gateway.GetEgressSNATSourceIPs() {
+		found := false
+		for _, bridgeIP := range bridgeIPs {
+			if bridgeIP.IP.Equal(ip) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("egress SNAT source IP %s is not configured on the gateway bridge", ip)
+		}
+	}
+	return nil
+}
+
+// ipv6AdvertisementFloodActions returns the actions of the flows flooding the ICMPv6 RA/NA
+// on the gateway bridge, empty if these are not to be flooded
+func ipv6AdvertisementFloodActions(ofPortPatch, ofPortHost string) string {
+	switch config.Gateway.IPv6AdvertisementFlood {
+	case config.GatewayIPv6AdvertisementDisable:
+		return ""
+	case config.GatewayIPv6AdvertisementRestrict:
+		return fmt.Sprintf("output:%s,output:%s", ofPortPatch, ofPortHost)
+	default:
+		return "FLOOD"
+	}
+}
+
 func commonFlows(subnets []*net.IPNet, bridge *bridgeConfiguration) ([]string, error) {
 	ofPortPhys := bridge.ofPortPhys
 	bridgeMacAddress := bridge.macAddress.String()
@@ -1360,27 +3442,29 @@ func commonFlows(subnets []*net.IPNet, bridge *bridgeConfiguration) ([]string, e
 		if ofPortPhys != "" {
 			// table0, packets coming from egressIP pods that have mark 1008 on them
 			// will be DNAT-ed a final time into nodeIP to maintain consistency in traffic even if the GR
-			// DNATs these into egressIP prior to reaching external bridge.
+			// DNATs these into egressIP prior to reaching external bridge. The configured
+			// egress SNAT source IP, if any, is used instead of nodeIP.
 			// egressService pods will also undergo this SNAT to nodeIP since these features are tied
 			// together at the OVN policy level on the distributed router.
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=105, in_port=%s, ip, pkt_mark=%s "+
 					"actions=ct(commit, zone=%d, nat(src=%s), exec(set_field:%s->ct_mark)),output:%s",
-					defaultOpenFlowCookie, ofPortPatch, ovnKubeNodeSNATMark, config.Default.ConntrackZone, physicalIP.IP, ctMarkOVN, ofPortPhys))
+					egressIPOpenFlowCookie, ofPortPatch, ovnKubeNodeSNATMark, config.Default.ConntrackZone,
+					getEgressSNATSourceIP(false, physicalIP.IP, bridgeIPs), ctMarkOVN, ofPortPhys))
 
 			// table 0, packets coming from pods headed externally. Commit connections with ct_mark ctMarkOVN
 			// so that reverse direction goes back to the pods.
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=100, in_port=%s, ip, "+
 					"actions=ct(commit, zone=%d, exec(set_field:%s->ct_mark)), output:%s",
-					defaultOpenFlowCookie, ofPortPatch, config.Default.ConntrackZone, ctMarkOVN, ofPortPhys))
+					conntrackOpenFlowCookie, ofPortPatch, config.Default.ConntrackZone, ctMarkOVN, ofPortPhys))
 
 			// table 0, packets coming from host Commit connections with ct_mark ctMarkHost
 			// so that reverse direction goes back to the host.
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=100, in_port=%s, ip, "+
 					"actions=ct(commit, zone=%d, exec(set_field:%s->ct_mark)), output:%s",
-					defaultOpenFlowCookie, ofPortHost, config.Default.ConntrackZone, ctMarkHost, ofPortPhys))
+					conntrackOpenFlowCookie, ofPortHost, config.Default.ConntrackZone, ctMarkHost, ofPortPhys))
 		}
 		if config.Gateway.Mode == config.GatewayModeLocal {
 			// table 0, any packet coming from OVN send to host in LGW mode, host will take care of sending it outside if needed.
@@ -1399,9 +3483,11 @@ func commonFlows(subnets []*net.IPNet, bridge *bridgeConfiguration) ([]string, e
 					defaultOpenFlowCookie, ofPortPatch, physicalIP.IP, HostMasqCTZone))
 			// We send BFD traffic coming from OVN to outside directly using a higher priority flow
 			if ofPortPhys != "" {
-				dftFlows = append(dftFlows,
-					fmt.Sprintf("cookie=%s, priority=650, table=0, in_port=%s, udp, tp_dst=3784, actions=output:%s",
-						defaultOpenFlowCookie, ofPortPatch, ofPortPhys))
+				for _, bfdPort := range config.Gateway.GetBFDPorts() {
+					dftFlows = append(dftFlows,
+						fmt.Sprintf("cookie=%s, priority=650, table=0, in_port=%s, udp, tp_dst=%d, actions=output:%s",
+							bfdOpenFlowCookie, ofPortPatch, bfdPort, ofPortPhys))
+				}
 			}
 		}
 
@@ -1410,7 +3496,7 @@ func commonFlows(subnets []*net.IPNet, bridge *bridgeConfiguration) ([]string, e
 			// resubmit to table 1 to know the state and mark of the connection.
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=50, in_port=%s, ip, "+
-					"actions=ct(zone=%d, nat, table=1)", defaultOpenFlowCookie, ofPortPhys, config.Default.ConntrackZone))
+					"actions=ct(zone=%d, nat, table=1)", conntrackOpenFlowCookie, ofPortPhys, config.Default.ConntrackZone))
 		}
 	}
 	if config.IPv6Mode {
@@ -1421,27 +3507,29 @@ func commonFlows(subnets []*net.IPNet, bridge *bridgeConfiguration) ([]string, e
 		if ofPortPhys != "" {
 			// table0, packets coming from egressIP pods that have mark 1008 on them
 			// will be DNAT-ed a final time into nodeIP to maintain consistency in traffic even if the GR
-			// DNATs these into egressIP prior to reaching external bridge.
+			// DNATs these into egressIP prior to reaching external bridge. The configured
+			// egress SNAT source IP, if any, is used instead of nodeIP.
 			// egressService pods will also undergo this SNAT to nodeIP since these features are tied
 			// together at the OVN policy level on the distributed router.
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=105, in_port=%s, ipv6, pkt_mark=%s "+
 					"actions=ct(commit, zone=%d, nat(src=%s), exec(set_field:%s->ct_mark)),output:%s",
-					defaultOpenFlowCookie, ofPortPatch, ovnKubeNodeSNATMark, config.Default.ConntrackZone, physicalIP.IP, ctMarkOVN, ofPortPhys))
+					egressIPOpenFlowCookie, ofPortPatch, ovnKubeNodeSNATMark, config.Default.ConntrackZone,
+					getEgressSNATSourceIP(true, physicalIP.IP, bridgeIPs), ctMarkOVN, ofPortPhys))
 
 			// table 0, packets coming from pods headed externally. Commit connections with ct_mark ctMarkOVN
 			// so that reverse direction goes back to the pods.
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=100, in_port=%s, ipv6, "+
 					"actions=ct(commit, zone=%d, exec(set_field:%s->ct_mark)), output:%s",
-					defaultOpenFlowCookie, ofPortPatch, config.Default.ConntrackZone, ctMarkOVN, ofPortPhys))
+					conntrackOpenFlowCookie, ofPortPatch, config.Default.ConntrackZone, ctMarkOVN, ofPortPhys))
 
 			// table 0, packets coming from host. Commit connections with ct_mark ctMarkHost
 			// so that reverse direction goes back to the host.
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=100, in_port=%s, ipv6, "+
 					"actions=ct(commit, zone=%d, exec(set_field:%s->ct_mark)), output:%s",
-					defaultOpenFlowCookie, ofPortHost, config.Default.ConntrackZone, ctMarkHost, ofPortPhys))
+					conntrackOpenFlowCookie, ofPortHost, config.Default.ConntrackZone, ctMarkHost, ofPortPhys))
 		}
 		if config.Gateway.Mode == config.GatewayModeLocal {
 			// table 0, any packet coming from OVN send to host in LGW mode, host will take care of sending it outside if needed.
@@ -1460,9 +3548,11 @@ func commonFlows(subnets []*net.IPNet, bridge *bridgeConfiguration) ([]string, e
 					defaultOpenFlowCookie, ofPortPatch, physicalIP.IP, HostMasqCTZone))
 			if ofPortPhys != "" {
 				// We send BFD traffic coming from OVN to outside directly using a higher priority flow
-				dftFlows = append(dftFlows,
-					fmt.Sprintf("cookie=%s, priority=650, table=0, in_port=%s, udp6, tp_dst=3784, actions=output:%s",
-						defaultOpenFlowCookie, ofPortPatch, ofPortPhys))
+				for _, bfdPort := range config.Gateway.GetBFDPorts() {
+					dftFlows = append(dftFlows,
+						fmt.Sprintf("cookie=%s, priority=650, table=0, in_port=%s, udp6, tp_dst=%d, actions=output:%s",
+							bfdOpenFlowCookie, ofPortPatch, bfdPort, ofPortPhys))
+				}
 			}
 		}
 		if ofPortPhys != "" {
@@ -1470,7 +3560,7 @@ func commonFlows(subnets []*net.IPNet, bridge *bridgeConfiguration) ([]string, e
 			// resubmit to table 1 to know the state and mark of the connection.
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=50, in_port=%s, ipv6, "+
-					"actions=ct(zone=%d, nat, table=1)", defaultOpenFlowCookie, ofPortPhys, config.Default.ConntrackZone))
+					"actions=ct(zone=%d, nat, table=1)", conntrackOpenFlowCookie, ofPortPhys, config.Default.ConntrackZone))
 		}
 	}
 	// Egress IP is often configured on a node different from the one hosting the affected pod.
@@ -1487,7 +3577,7 @@ func commonFlows(subnets []*net.IPNet, bridge *bridgeConfiguration) ([]string, e
 			// table 0, drop packets coming from pods headed externally that were not SNATed.
 			dftFlows = append(dftFlows,
 				fmt.Sprintf("cookie=%s, priority=104, in_port=%s, %s, %s_src=%s, actions=drop",
-					defaultOpenFlowCookie, ofPortPatch, ipPrefix, ipPrefix, cidr))
+					egressIPOpenFlowCookie, ofPortPatch, ipPrefix, ipPrefix, cidr))
 		}
 		for _, subnet := range subnets {
 			ipPrefix := "ip"
@@ -1500,7 +3590,7 @@ func commonFlows(subnets []*net.IPNet, bridge *bridgeConfiguration) ([]string, e
 				dftFlows = append(dftFlows,
 					fmt.Sprintf("cookie=%s, priority=109, in_port=%s, %s, %s_src=%s"+
 						"actions=ct(commit, zone=%d, exec(set_field:%s->ct_mark)), output:%s",
-						defaultOpenFlowCookie, ofPortPatch, ipPrefix, ipPrefix, subnet, config.Default.ConntrackZone, ctMarkOVN, ofPortPhys))
+						egressIPOpenFlowCookie, ofPortPatch, ipPrefix, ipPrefix, subnet, config.Default.ConntrackZone, ctMarkOVN, ofPortPhys))
 			}
 		}
 	}
@@ -1532,26 +3622,33 @@ func commonFlows(subnets []*net.IPNet, bridge *bridgeConfiguration) ([]string, e
 
 		if config.IPv6Mode {
 			// REMOVEME(trozet) when https://bugzilla.kernel.org/show_bug.cgi?id=11797 is resolved
-			// must flood icmpv6 Route Advertisement and Neighbor Advertisement traffic as it fails to create a CT entry
-			for _, icmpType := range []int{types.RouteAdvertisementICMPType, types.NeighborAdvertisementICMPType} {
-				dftFlows = append(dftFlows,
-					fmt.Sprintf("cookie=%s, priority=14, table=1,icmp6,icmpv6_type=%d actions=FLOOD",
-						defaultOpenFlowCookie, icmpType))
+			// must flood icmpv6 Route Advertisement and Neighbor Advertisement traffic as it fails to create a CT entry,
+			// unless configured to restrict it to OVN and the host or not to flood it at all
+			if icmpActions := ipv6AdvertisementFloodActions(ofPortPatch, ofPortHost); icmpActions != "" {
+				for _, icmpType := range []int{types.RouteAdvertisementICMPType, types.NeighborAdvertisementICMPType} {
+					dftFlows = append(dftFlows,
+						fmt.Sprintf("cookie=%s, priority=14, table=1,icmp6,icmpv6_type=%d actions=%s",
+							defaultOpenFlowCookie, icmpType, icmpActions))
+				}
 			}
 			if ofPortPhys != "" {
 				// We send BFD traffic both on the host and in ovn
-				dftFlows = append(dftFlows,
-					fmt.Sprintf("cookie=%s, priority=13, table=1, in_port=%s, udp6, tp_dst=3784, actions=output:%s,output:%s",
-						defaultOpenFlowCookie, ofPortPhys, ofPortPatch, ofPortHost))
+				for _, bfdPort := range config.Gateway.GetBFDPorts() {
+					dftFlows = append(dftFlows,
+						fmt.Sprintf("cookie=%s, priority=13, table=1, in_port=%s, udp6, tp_dst=%d, actions=output:%s,output:%s",
+							bfdOpenFlowCookie, ofPortPhys, bfdPort, ofPortPatch, ofPortHost))
+				}
 			}
 		}
 
 		if config.IPv4Mode {
 			if ofPortPhys != "" {
 				// We send BFD traffic both on the host and in ovn
-				dftFlows = append(dftFlows,
-					fmt.Sprintf("cookie=%s, priority=13, table=1, in_port=%s, udp, tp_dst=3784, actions=output:%s,output:%s",
-						defaultOpenFlowCookie, ofPortPhys, ofPortPatch, ofPortHost))
+				for _, bfdPort := range config.Gateway.GetBFDPorts() {
+					dftFlows = append(dftFlows,
+						fmt.Sprintf("cookie=%s, priority=13, table=1, in_port=%s, udp, tp_dst=%d, actions=output:%s,output:%s",
+							bfdOpenFlowCookie, ofPortPhys, bfdPort, ofPortPatch, ofPortHost))
+				}
 			}
 		}
 		// table 1, all other connections do normal processing
@@ -1575,24 +3672,35 @@ func setBridgeOfPorts(bridge *bridgeConfiguration) error {
 		// Get ofport of physical interface
 		ofportPhys, stderr, err := util.GetOVSOfPort("get", "interface", bridge.uplinkName, "ofport")
 		if err != nil {
-			return fmt.Errorf("failed to get ofport of %s, stderr: %q, error: %v",
-				bridge.uplinkName, stderr, err)
+			// the uplink might be an OVS bond, which has no interface of its own
+			bondOfport, bondErr := getBondOfPort(bridge.uplinkName, true)
+			if bondErr == errNotABond {
+				return fmt.Errorf("failed to get ofport of %s, stderr: %q, error: %v",
+					bridge.uplinkName, stderr, err)
+			}
+			if bondErr != nil {
+				return bondErr
+			}
+			ofportPhys = bondOfport
 		}
 		bridge.ofPortPhys = ofportPhys
 	}
 
 	// Get ofport represeting the host. That is, host representor port in case of DPUs, ovsLocalPort otherwise.
 	if config.OvnKubeNode.Mode == types.NodeModeDPU {
-		var stderr string
-		hostRep, err := util.GetDPUHostInterface(bridge.bridgeName)
+		hostRep, err := getDPUHostRepresentor(bridge.bridgeName)
 		if err != nil {
 			return err
 		}
 
-		bridge.ofPortHost, stderr, err = util.RunOVSVsctl("get", "interface", hostRep, "ofport")
+		bridge.hostRepresentor = hostRep
+		bridge.ofPortHost, err = getHostRepresentorOfPort(hostRep)
 		if err != nil {
-			return fmt.Errorf("failed to get ofport of host interface %s, stderr: %q, error: %v",
-				hostRep, stderr, err)
+			return err
+		}
+		if bridge.ofPortHost == ofPortHostUnavailable {
+			klog.Errorf("Host representor %s of bridge %s has no valid ofport, the flows depending on it are "+
+				"%s until it is back", hostRep, bridge.bridgeName, hostRepresentorUnavailableVerb())
 		}
 	} else {
 		bridge.ofPortHost = ovsLocalPort
@@ -1601,19 +3709,166 @@ func setBridgeOfPorts(bridge *bridgeConfiguration) error {
 	return nil
 }
 
+// getHostRepresentorOfPort returns the ofport of the DPU host representor, or
+// ofPortHostUnavailable if it has no valid ofport, like while it is recreated
+func getHostRepresentorOfPort(hostRep string) (string, error) {
+	ofport, stderr, err := util.RunOVSVsctl("get", "interface", hostRep, "ofport")
+	if err != nil {
+		return "", fmt.Errorf("failed to get ofport of host interface %s, stderr: %q, error: %v",
+			hostRep, stderr, err)
+	}
+	// the ofport is -1 if the interface could not be created and [] until it is assigned one
+	if n, err := strconv.Atoi(ofport); err != nil || n <= 0 {
+		return ofPortHostUnavailable, nil
+	}
+	return ofport, nil
+}
+
+// hostRepresentorUnavailableVerb describes what happens to the flows depending
+// on an unavailable host representor, for logging
+func hostRepresentorUnavailableVerb() string {
+	if config.OvnKubeNode.DPUHostRepresentorUnavailableAction == config.DPUHostRepresentorUnavailableActionDrop {
+		return "dropping the traffic they output to it"
+	}
+	return "held"
+}
+
+// withoutHostRepresentorFlows applies the configured
+// DPUHostRepresentorUnavailableAction to the flows depending on the
+// unavailable host representor of the bridge, instead of programming output
+// actions towards, or matches on, an invalid ofport. The flows matching on
+// traffic from the host representor are always held, there is none of it.
+// The other ones are held too, or have their output to the host representor
+// removed, becoming drop flows if it was their only output.
+func withoutHostRepresentorFlows(flows []string) []string {
+	drop := config.OvnKubeNode.DPUHostRepresentorUnavailableAction == config.DPUHostRepresentorUnavailableActionDrop
+	output := "output:" + ofPortHostUnavailable
+	var kept []string
+	for _, flow := range flows {
+		if !strings.Contains(flow, ofPortHostUnavailable) {
+			kept = append(kept, flow)
+			continue
+		}
+		if !drop || strings.Contains(flow, "in_port="+ofPortHostUnavailable) {
+			continue
+		}
+		for _, action := range []string{", " + output, "," + output, output + ",", output} {
+			flow = strings.ReplaceAll(flow, action, "")
+		}
+		if strings.HasSuffix(flow, "actions=") {
+			flow += "drop"
+		}
+		kept = append(kept, flow)
+	}
+	return kept
+}
+
+// getDPUHostRepresentor returns the host representor port of the given
+// bridge. The configured host representor, which must be a port of the
+// bridge, takes precedence over the one discovered from the port flavours.
+func getDPUHostRepresentor(bridgeName string) (string, error) {
+	hostRep := config.OvnKubeNode.DPUHostRepresentor
+	if hostRep == "" {
+		return util.GetDPUHostInterface(bridgeName)
+	}
+	portBridge, stderr, err := util.RunOVSVsctl("port-to-br", hostRep)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the bridge of host representor %s, stderr: %q, error: %v",
+			hostRep, stderr, err)
+	}
+	if portBridge != bridgeName {
+		return "", fmt.Errorf("host representor %s is a port of bridge %s instead of bridge %s",
+			hostRep, portBridge, bridgeName)
+	}
+	return hostRep, nil
+}
+
+var errNotABond = errors.New("not an OVS bond")
+
+// getBondOfPort returns the ofport used for the OVS bond with the given port
+// name. A bond has no interface of its own, so the ofport of the bond member
+// with the lowest ofport is used: it does not depend on the link state of the
+// members, so it does not change when members flap. If requireLiveMember is
+// set, an error is returned unless at least one member has its link up.
+// errNotABond is returned if there is no port with more than one interface
+// with that name.
+func getBondOfPort(bondName string, requireLiveMember bool) (string, error) {
+	stdout, stderr, err := util.RunOVSVsctl("--if-exists", "get", "Port", bondName, "interfaces")
+	if err != nil {
+		return "", fmt.Errorf("failed to get interfaces of port %s, stderr: %q, error: %v", bondName, stderr, err)
+	}
+	members := strings.Fields(strings.NewReplacer("[", "", "]", "", ",", " ").Replace(stdout))
+	if len(members) < 2 {
+		return "", errNotABond
+	}
+
+	args := append([]string{"--no-headings", "--data=bare", "--format=csv",
+		"--columns=name,ofport,link_state", "list", "Interface"}, members...)
+	stdout, stderr, err = util.RunOVSVsctl(args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to get members of bond %s, stderr: %q, error: %v", bondName, stderr, err)
+	}
+	ofport := -1
+	var liveMembers []string
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			continue
+		}
+		memberOfport, err := strconv.Atoi(fields[1])
+		if err != nil || memberOfport <= 0 {
+			klog.Warningf("Bond %s member %s has no valid ofport %q", bondName, fields[0], fields[1])
+			continue
+		}
+		if ofport == -1 || memberOfport < ofport {
+			ofport = memberOfport
+		}
+		if fields[2] == "up" {
+			liveMembers = append(liveMembers, fields[0])
+		}
+	}
+	if ofport == -1 {
+		return "", fmt.Errorf("no member of bond %s has a valid ofport", bondName)
+	}
+	if requireLiveMember && len(liveMembers) == 0 {
+		return "", fmt.Errorf("no member of bond %s has its link up", bondName)
+	}
+	klog.V(5).Infof("Using ofport %d for bond %s with members up: %v", ofport, bondName, liveMembers)
+	return strconv.Itoa(ofport), nil
+}
+
 // initSvcViaMgmPortRoutingRules creates the svc2managementport routing table, routes and rules
-// that let's us forward service traffic to ovn-k8s-mp0 as opposed to the default route towards breth0
-func initSvcViaMgmPortRoutingRules(hostSubnets []*net.IPNet) error {
-	// create ovnkubeSvcViaMgmPortRT and service route towards ovn-k8s-mp0
+// that let's us forward service traffic to the management port mgmtIntfName, usually
+// types.K8sMgmtIntfName, as opposed to the default route towards breth0
+func initSvcViaMgmPortRoutingRules(hostSubnets []*net.IPNet, mgmtIntfName string) error {
+	// create ovnkubeSvcViaMgmPortRT and service route towards the management port
 	for _, hostSubnet := range hostSubnets {
 		isIPv6 := utilnet.IsIPv6CIDR(hostSubnet)
+		ipFamily := "IPv4"
+		if isIPv6 {
+			ipFamily = "IPv6"
+		}
 		gatewayIP := util.GetNodeGatewayIfAddr(hostSubnet).IP.String()
+		if config.Gateway.ITPLocalMgmtPortRoutes {
+			// the routes of the ClusterIPs of the ITP=local services are added by the nodePortWatcher,
+			// flush the service CIDR routes and the routes of the services deleted while not running
+			family := "-4"
+			if isIPv6 {
+				family = "-6"
+			}
+			if stdout, stderr, err := util.RunIP(family, "route", "flush", "table", ovnkubeSvcViaMgmPortRT); err != nil {
+				return fmt.Errorf("error flushing %s routes of custom routing table %s: stdout: %s, stderr: %s, err: %v",
+					ipFamily, ovnkubeSvcViaMgmPortRT, stdout, stderr, err)
+			}
+			continue
+		}
 		for _, svcCIDR := range config.Kubernetes.ServiceCIDRs {
 			if isIPv6 == utilnet.IsIPv6CIDR(svcCIDR) {
-				if stdout, stderr, err := util.RunIP("route", "replace", "table", ovnkubeSvcViaMgmPortRT, svcCIDR.String(), "via", gatewayIP, "dev", types.K8sMgmtIntfName); err != nil {
-					return fmt.Errorf("error adding routing table entry into custom routing table: %s: stdout: %s, stderr: %s, err: %v", ovnkubeSvcViaMgmPortRT, stdout, stderr, err)
+				if stdout, stderr, err := util.RunIP("route", "replace", "table", ovnkubeSvcViaMgmPortRT, svcCIDR.String(), "via", gatewayIP, "dev", mgmtIntfName); err != nil {
+					return fmt.Errorf("error adding %s route %s via %s into custom routing table: %s: stdout: %s, stderr: %s, err: %v",
+						ipFamily, svcCIDR, gatewayIP, ovnkubeSvcViaMgmPortRT, stdout, stderr, err)
 				}
-				klog.V(5).Infof("Successfully added route into custom routing table: %s", ovnkubeSvcViaMgmPortRT)
+				klog.V(5).Infof("Successfully added %s route %s via %s into custom routing table: %s", ipFamily, svcCIDR, gatewayIP, ovnkubeSvcViaMgmPortRT)
 			}
 		}
 	}
@@ -1621,17 +3876,25 @@ func initSvcViaMgmPortRoutingRules(hostSubnets []*net.IPNet) error {
 	createRule := func(family string) error {
 		stdout, stderr, err := util.RunIP(family, "rule")
 		if err != nil {
-			return fmt.Errorf("error listing routing rules, stdout: %s, stderr: %s, err: %v", stdout, stderr, err)
+			return fmt.Errorf("error listing %s routing rules, stdout: %s, stderr: %s, err: %v", family, stdout, stderr, err)
+		}
+		marks := []string{ovnkubeITPMark}
+		if config.Gateway.ExternalIPSourcePreservation && config.Gateway.Mode == config.GatewayModeShared {
+			marks = append(marks, ovnkubeExternalIPSrcMark)
 		}
-		if !strings.Contains(stdout, fmt.Sprintf("from all fwmark %s lookup %s", ovnkubeITPMark, ovnkubeSvcViaMgmPortRT)) {
-			if stdout, stderr, err := util.RunIP(family, "rule", "add", "fwmark", ovnkubeITPMark, "lookup", ovnkubeSvcViaMgmPortRT, "prio", "30"); err != nil {
-				return fmt.Errorf("error adding routing rule for service via management table (%s): stdout: %s, stderr: %s, err: %v", ovnkubeSvcViaMgmPortRT, stdout, stderr, err)
+		for _, mark := range marks {
+			if strings.Contains(stdout, fmt.Sprintf("from all fwmark %s lookup %s", mark, ovnkubeSvcViaMgmPortRT)) {
+				continue
+			}
+			if stdout, stderr, err := util.RunIP(family, "rule", "add", "fwmark", mark, "lookup", ovnkubeSvcViaMgmPortRT, "prio", "30"); err != nil {
+				return fmt.Errorf("error adding %s routing rule for service via management table (%s): stdout: %s, stderr: %s, err: %v", family, ovnkubeSvcViaMgmPortRT, stdout, stderr, err)
 			}
 		}
 		return nil
 	}
 
-	// create ip rule that will forward ovnkubeITPMark marked packets to ovnkubeITPRoutingTable
+	// create ip rule that will forward ovnkubeITPMark, and ovnkubeExternalIPSrcMark if the source IP of the
+	// externalIP traffic is preserved, marked packets to ovnkubeITPRoutingTable
 	if config.IPv4Mode {
 		if err := createRule("-4"); err != nil {
 			return fmt.Errorf("could not add IPv4 rule: %v", err)
@@ -1643,20 +3906,130 @@ func initSvcViaMgmPortRoutingRules(hostSubnets []*net.IPNet) error {
 		}
 	}
 
-	// lastly update the reverse path filtering options for ovn-k8s-mp0 interface to avoid dropping return packets
+	// lastly update the reverse path filtering options for the management port to avoid dropping return packets
 	// NOTE: v6 doesn't have rp_filter strict mode block
 	rpFilterLooseMode := "2"
 	// TODO: Convert testing framework to mock golang module utilities. Example:
-	// result, err := sysctl.Sysctl(fmt.Sprintf("net/ipv4/conf/%s/rp_filter", types.K8sMgmtIntfName), rpFilterLooseMode)
-	stdout, stderr, err := util.RunSysctl("-w", fmt.Sprintf("net.ipv4.conf.%s.rp_filter=%s", types.K8sMgmtIntfName, rpFilterLooseMode))
-	if err != nil || stdout != fmt.Sprintf("net.ipv4.conf.%s.rp_filter = %s", types.K8sMgmtIntfName, rpFilterLooseMode) {
+	// result, err := sysctl.Sysctl(fmt.Sprintf("net/ipv4/conf/%s/rp_filter", mgmtIntfName), rpFilterLooseMode)
+	stdout, stderr, err := util.RunSysctl("-w", fmt.Sprintf("net.ipv4.conf.%s.rp_filter=%s", mgmtIntfName, rpFilterLooseMode))
+	if err != nil || stdout != fmt.Sprintf("net.ipv4.conf.%s.rp_filter = %s", mgmtIntfName, rpFilterLooseMode) {
 		return fmt.Errorf("could not set the correct rp_filter value for interface %s: stdout: %v, stderr: %v, err: %v",
-			types.K8sMgmtIntfName, stdout, stderr, err)
+			mgmtIntfName, stdout, stderr, err)
+	}
+
+	// v6 service traffic routed via the management port needs forwarding on it, and
+	// router advertisements must not install routes on it
+	if config.IPv6Mode {
+		for _, setting := range []string{"forwarding=1", "accept_ra=0"} {
+			key, value, _ := strings.Cut(setting, "=")
+			stdout, stderr, err := util.RunSysctl("-w", fmt.Sprintf("net.ipv6.conf.%s.%s", mgmtIntfName, setting))
+			if err != nil || stdout != fmt.Sprintf("net.ipv6.conf.%s.%s = %s", mgmtIntfName, key, value) {
+				return fmt.Errorf("could not set the correct IPv6 %s value for interface %s: stdout: %v, stderr: %v, err: %v",
+					key, mgmtIntfName, stdout, stderr, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// svcViaMgmPortRoutes keeps a route towards ovn-k8s-mp0 in the svc2managementport routing table
+// for each ClusterIP of the ITP=local services and of the services whose externalIP traffic keeps its
+// source IP, when the routes are scoped to these services
+type svcViaMgmPortRoutes struct {
+	sync.Mutex
+	// management port the routes are towards
+	mgmtIntfName string
+	// gateway IPs of the management port the routes are via, by IP family
+	gatewayIPv4 string
+	gatewayIPv6 string
+	// ClusterIPs routed via the management port, by service
+	clusterIPs map[ktypes.NamespacedName]sets.Set[string]
+}
+
+func newSvcViaMgmPortRoutes(hostSubnets []*net.IPNet, mgmtIntfName string) *svcViaMgmPortRoutes {
+	routes := &svcViaMgmPortRoutes{mgmtIntfName: mgmtIntfName, clusterIPs: map[ktypes.NamespacedName]sets.Set[string]{}}
+	for _, hostSubnet := range hostSubnets {
+		gatewayIP := util.GetNodeGatewayIfAddr(hostSubnet).IP.String()
+		if utilnet.IsIPv6CIDR(hostSubnet) {
+			routes.gatewayIPv6 = gatewayIP
+		} else {
+			routes.gatewayIPv4 = gatewayIP
+		}
+	}
+	return routes
+}
+
+// sync routes the ClusterIPs of the service via the management port if it is an
+// ITP=local service, or an externalIP service whose source IP is preserved, and removes the routes of its ClusterIPs that are no longer needed
+func (r *svcViaMgmPortRoutes) sync(service *kapi.Service) error {
+	name := ktypes.NamespacedName{Namespace: service.Namespace, Name: service.Name}
+	clusterIPs := sets.New[string]()
+	if util.ServiceInternalTrafficPolicyLocal(service) || isExternalIPSourcePreserved(service) {
+		clusterIPs.Insert(util.GetClusterIPs(service)...)
+	}
+	return r.update(name, clusterIPs)
+}
+
+// delete removes the routes of the ClusterIPs of the service
+func (r *svcViaMgmPortRoutes) delete(name ktypes.NamespacedName) error {
+	return r.update(name, sets.New[string]())
+}
+
+func (r *svcViaMgmPortRoutes) update(name ktypes.NamespacedName, clusterIPs sets.Set[string]) error {
+	r.Lock()
+	defer r.Unlock()
+	routed := r.clusterIPs[name]
+	if routed == nil {
+		routed = sets.New[string]()
+	}
+	var errors []error
+	for _, clusterIP := range sets.List(clusterIPs.Difference(routed)) {
+		if err := r.route("replace", clusterIP); err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		routed.Insert(clusterIP)
+	}
+	for _, clusterIP := range sets.List(routed.Difference(clusterIPs)) {
+		if err := r.route("del", clusterIP); err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		routed.Delete(clusterIP)
 	}
+	if len(routed) == 0 {
+		delete(r.clusterIPs, name)
+	} else {
+		r.clusterIPs[name] = routed
+	}
+	return apierrors.NewAggregate(errors)
+}
 
+func (r *svcViaMgmPortRoutes) route(action, clusterIP string) error {
+	gatewayIP := r.gatewayIPv4
+	if utilnet.IsIPv6String(clusterIP) {
+		gatewayIP = r.gatewayIPv6
+	}
+	if gatewayIP == "" {
+		return fmt.Errorf("no management port gateway IP of the family of ClusterIP %s", clusterIP)
+	}
+	if stdout, stderr, err := util.RunIP("route", action, "table", ovnkubeSvcViaMgmPortRT, clusterIP, "via", gatewayIP, "dev", r.mgmtIntfName); err != nil {
+		return fmt.Errorf("error running %s of route %s via %s in custom routing table %s: stdout: %s, stderr: %s, err: %v",
+			action, clusterIP, gatewayIP, ovnkubeSvcViaMgmPortRT, stdout, stderr, err)
+	}
+	klog.V(5).Infof("Successfully ran %s of route %s via %s in custom routing table %s", action, clusterIP, gatewayIP, ovnkubeSvcViaMgmPortRT)
 	return nil
 }
 
+// deleteSvcViaMgmPortRoutes removes the management port routes of the ClusterIPs of the service, if any
+func (npw *nodePortWatcher) deleteSvcViaMgmPortRoutes(name ktypes.NamespacedName) error {
+	if npw.svcViaMgmPortRoutes == nil {
+		return nil
+	}
+	return npw.svcViaMgmPortRoutes.delete(name)
+}
+
 func newSharedGateway(nodeName string, subnets []*net.IPNet, gwNextHops []net.IP, gwIntf, egressGWIntf string,
 	gwIPs []*net.IPNet, nodeAnnotator kube.Annotator, kube kube.Interface, cfg *managementPortConfig,
 	watchFactory factory.NodeWatchFactory, routeManager *routeManager) (*gateway, error) {
@@ -1668,23 +4041,12 @@ func newSharedGateway(nodeName string, subnets []*net.IPNet, gwNextHops []net.IP
 	if err != nil {
 		return nil, err
 	}
+	if err := setGatewayConfigExternalID(gwBridge.bridgeName); err != nil {
+		return nil, err
+	}
 
-	if exGwBridge != nil {
-		gw.readyFunc = func() (bool, error) {
-			ready, err := gatewayReady(gwBridge.patchPort)
-			if err != nil {
-				return false, err
-			}
-			exGWReady, err := gatewayReady(exGwBridge.patchPort)
-			if err != nil {
-				return false, err
-			}
-			return ready && exGWReady, nil
-		}
-	} else {
-		gw.readyFunc = func() (bool, error) {
-			return gatewayReady(gwBridge.patchPort)
-		}
+	gw.readyFunc = func() (bool, string, error) {
+		return gatewayBridgesReady(gwBridge, exGwBridge)
 	}
 
 	gw.initFunc = func() error {
@@ -1713,41 +4075,48 @@ func newSharedGateway(nodeName string, subnets []*net.IPNet, gwNextHops []net.IP
 			if err := setNodeMasqueradeIPOnExtBridge(gwBridge.bridgeName); err != nil {
 				return fmt.Errorf("failed to set the node masquerade IP on the ext bridge %s: %v", gwBridge.bridgeName, err)
 			}
+			gw.masqueradeIPBridge = gwBridge.bridgeName
 
 			if err := addMasqueradeRoute(routeManager, gwBridge.bridgeName, nodeName, gwIPs, watchFactory); err != nil {
 				return fmt.Errorf("failed to set the node masquerade route to OVN: %v", err)
 			}
 		}
 
+		gw.subnets = subnets
 		gw.openflowManager, err = newGatewayOpenFlowManager(gwBridge, exGwBridge, subnets, nodeIPs)
 		if err != nil {
 			return err
 		}
 
-		// resync flows on IP change
-		gw.nodeIPManager.OnChanged = func() {
-			klog.V(5).Info("Node addresses changed, re-syncing bridge flows")
-			if err := gw.openflowManager.updateBridgeFlowCache(subnets, gw.nodeIPManager.ListAddresses()); err != nil {
-				// very unlikely - somehow node has lost its IP address
-				klog.Errorf("Failed to re-generate gateway flows after address change: %v", err)
-			}
-			npw, _ := gw.nodePortWatcher.(*nodePortWatcher)
-			npw.updateGatewayIPs(gw.nodeIPManager)
-			gw.openflowManager.requestFlowSync()
+		// regenerate the flows depending on the DPU host representor once it is back
+		gw.openflowManager.onHostRepresentorAvailable = func() {
+			if err := gw.ReconcileFlows(); err != nil {
+				klog.Errorf("Failed to regenerate the gateway flows once the host representor is back: %v", err)
+			}
 		}
 
+		// resync flows on IP change
+		gw.nodeIPManager.OnChanged = gw.onNodeIPsChanged
+
 		if config.Gateway.NodeportEnable {
 			if config.OvnKubeNode.Mode == types.NodeModeFull {
 				// (TODO): Internal Traffic Policy is not supported in DPU mode
-				if err := initSvcViaMgmPortRoutingRules(subnets); err != nil {
+				if err := initSvcViaMgmPortRoutingRules(subnets, types.K8sMgmtIntfName); err != nil {
 					return err
 				}
 			}
 			klog.Info("Creating Shared Gateway Node Port Watcher")
-			gw.nodePortWatcher, err = newNodePortWatcher(gwBridge, gw.openflowManager, gw.nodeIPManager, watchFactory)
+			npw, err := newNodePortWatcher(gwBridge, gw.openflowManager, gw.nodeIPManager, watchFactory)
 			if err != nil {
 				return err
 			}
+			if config.OvnKubeNode.Mode == types.NodeModeFull && config.Gateway.ITPLocalMgmtPortRoutes {
+				npw.svcViaMgmPortRoutes = newSvcViaMgmPortRoutes(subnets, types.K8sMgmtIntfName)
+			}
+			if gw.recorder != nil {
+				npw.flowErrorReporter = newServiceFlowErrorReporter(gw.recorder)
+			}
+			gw.nodePortWatcher = npw
 		} else {
 			// no service OpenFlows, request to sync flows now.
 			gw.openflowManager.requestFlowSync()
@@ -1764,6 +4133,27 @@ func newSharedGateway(nodeName string, subnets []*net.IPNet, gwNextHops []net.IP
 	return gw, nil
 }
 
+// validateBridgePorts makes sure the patch port and the uplink of the gateway
+// bridge are ports of the bridge, as the flows matching on the ofports of ports
+// of another bridge would never match
+func validateBridgePorts(gwBridge *bridgeConfiguration) error {
+	for _, port := range []string{gwBridge.patchPort, gwBridge.uplinkName} {
+		// the bridge may have no uplink, see Gateway.AllowNoUplink
+		if port == "" {
+			continue
+		}
+		bridgeName, stderr, err := util.RunOVSVsctl("port-to-br", port)
+		if err != nil {
+			return fmt.Errorf("failed to get the bridge of port %s, stderr: %q, error: %v", port, stderr, err)
+		}
+		if bridgeName != gwBridge.bridgeName {
+			return fmt.Errorf("port %s is a port of bridge %s instead of gateway bridge %s",
+				port, bridgeName, gwBridge.bridgeName)
+		}
+	}
+	return nil
+}
+
 func newNodePortWatcher(gwBridge *bridgeConfiguration, ofm *openflowManager,
 	nodeIPManager *addressManager, watchFactory factory.NodeWatchFactory) (*nodePortWatcher, error) {
 	// Get ofport of patchPort
@@ -1781,6 +4171,35 @@ func newNodePortWatcher(gwBridge *bridgeConfiguration, ofm *openflowManager,
 		return nil, fmt.Errorf("failed to get ofport of %s, stderr: %q, error: %v",
 			gwBridge.uplinkName, stderr, err)
 	}
+	if ofportPhys == "" && gwBridge.uplinkName != "" {
+		ofportPhys, err = getBondOfPort(gwBridge.uplinkName, false)
+		if err != nil && err != errNotABond {
+			return nil, fmt.Errorf("failed to get ofport of bond %s: %v", gwBridge.uplinkName, err)
+		}
+	}
+	if err := validateBridgePorts(gwBridge); err != nil {
+		return nil, err
+	}
+
+	// Get ofports of the physical interfaces NodePort traffic is restricted to
+	var nodePortOfports []string
+	for _, ifName := range config.Gateway.GetNodePortInterfaces() {
+		ofport, stderr, err := util.GetOVSOfPort("--if-exists", "get", "interface", ifName, "ofport")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ofport of NodePort interface %s, stderr: %q, error: %v",
+				ifName, stderr, err)
+		}
+		if ofport == "" {
+			return nil, fmt.Errorf("NodePort interface %s not found on bridge %s", ifName, gwBridge.bridgeName)
+		}
+		nodePortOfports = append(nodePortOfports, ofport)
+	}
+
+	if config.Gateway.ARPBypassFlowsMeterRate > 0 && !config.Gateway.DisableARPBypassFlows {
+		if err := ensureArpBypassMeter(gwBridge.bridgeName); err != nil {
+			return nil, err
+		}
+	}
 
 	// In the shared gateway mode, the NodePort service is handled by the OpenFlow flows configured
 	// on the OVS bridge in the host. These flows act only on the packets coming in from outside
@@ -1821,17 +4240,32 @@ func newNodePortWatcher(gwBridge *bridgeConfiguration, ofm *openflowManager,
 	gatewayIPv4, gatewayIPv6 := getGatewayFamilyAddrs(gwBridge.ips)
 
 	npw := &nodePortWatcher{
-		dpuMode:       dpuMode,
-		gatewayIPv4:   gatewayIPv4,
-		gatewayIPv6:   gatewayIPv6,
-		ofportPhys:    ofportPhys,
-		ofportPatch:   ofportPatch,
-		gwBridge:      gwBridge.bridgeName,
-		serviceInfo:   make(map[ktypes.NamespacedName]*serviceConfig),
-		nodeIPManager: nodeIPManager,
-		ofm:           ofm,
-		watchFactory:  watchFactory,
+		dpuMode:         dpuMode,
+		gatewayIPv4:     gatewayIPv4,
+		gatewayIPv6:     gatewayIPv6,
+		gatewayIPs:      append([]*net.IPNet{}, gwBridge.ips...),
+		ofportPhys:      ofportPhys,
+		ofportPatch:     ofportPatch,
+		nodePortOfports: nodePortOfports,
+		gwBridge:        gwBridge.bridgeName,
+		serviceInfo:     make(map[ktypes.NamespacedName]*serviceConfig),
+		nodeIPManager:   nodeIPManager,
+		ofm:             ofm,
+		watchFactory:    watchFactory,
 	}
+	if config.Gateway.EndpointSliceCoalescingWindow > 0 {
+		npw.endpointSliceCoalescer = newEndpointSliceCoalescer(
+			time.Duration(config.Gateway.EndpointSliceCoalescingWindow)*time.Millisecond, npw.syncServiceEndpoints)
+	}
+	// the patch port ofport was looked up separately for the bridge, make sure
+	// both stay consistent
+	ofm.onPortsChecked = func() { npw.refreshOfportPatch() }
+	metrics.SetServiceTrafficSteeringFunc(npw.queryServiceTrafficSteering)
+	metrics.SetETPLocalServicesWithoutLocalEndpointsFunc(npw.countETPLocalServicesWithoutLocalEndpoints)
+	metrics.SetExternalIPOwnershipFunc(npw.queryExternalIPOwnership)
+	metrics.SetServiceConntrackFlushFunc(npw.queryServiceConntrackFlush)
+	metrics.SetServiceIPTRuleCountsFunc(npw.countServiceIPTRules)
+	metrics.SetServiceEndpointChangesFunc(npw.queryServiceEndpointChanges)
 	return npw, nil
 }
 
@@ -1880,6 +4314,90 @@ func cleanupSharedGateway() error {
 	return nil
 }
 
+// serviceFlowCacheKeyFields are the types of the service flow cache keys and
+// their number of fields, including the type but not the trailing field of the
+// h2c service ports
+var serviceFlowCacheKeyFields = map[string]int{
+	// NodePort, namespace, name, protocol, nodePort
+	"NodePort": 5,
+	// External, namespace, name, externalIP, protocol, port
+	"External": 6,
+	// Ingress, namespace, name, LB ingress IP, protocol, port
+	"Ingress": 6,
+	// HealthCheckNodePort, namespace, name, healthCheckNodePort
+	"HealthCheckNodePort": 4,
+}
+
+// serviceFlowCacheKey builds the flow cache key of a service from its type and
+// fields, joined with "_". Backslashes and underscores within a field are
+// escaped so that different fields can never build the same key.
+func serviceFlowCacheKey(keyType string, fields ...string) string {
+	escaped := []string{keyType}
+	for _, field := range fields {
+		field = strings.ReplaceAll(field, `\`, `\\`)
+		escaped = append(escaped, strings.ReplaceAll(field, "_", `\_`))
+	}
+	return strings.Join(escaped, "_")
+}
+
+// servicePortFlowCacheKey builds the flow cache key of a service port, see
+// serviceFlowCacheKey. The keys of the h2c ports end with an extra field, for
+// L7 handling to hook in. Their flows are the same as the ones of other ports
+// for now.
+func servicePortFlowCacheKey(keyType string, svcPort *kapi.ServicePort, fields ...string) string {
+	if isH2CServicePort(svcPort) {
+		fields = append(fields, serviceFlowCacheKeyH2C)
+	}
+	return serviceFlowCacheKey(keyType, fields...)
+}
+
+// isH2CServicePort returns true if the appProtocol of a service port is h2c
+func isH2CServicePort(svcPort *kapi.ServicePort) bool {
+	return svcPort.AppProtocol != nil && *svcPort.AppProtocol == h2cAppProtocol
+}
+
+// splitServiceFlowCacheKey splits a flow cache key built by
+// serviceFlowCacheKey back into its type and unescaped fields. Returns false if
+// the key is not escaped properly.
+func splitServiceFlowCacheKey(key string) ([]string, bool) {
+	fields := []string{}
+	var field strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch key[i] {
+		case '\\':
+			i++
+			if i == len(key) || (key[i] != '\\' && key[i] != '_') {
+				return nil, false
+			}
+			field.WriteByte(key[i])
+		case '_':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(key[i])
+		}
+	}
+	return append(fields, field.String()), true
+}
+
+// isStaleServiceFlowCacheKey returns true for the service flow cache keys that
+// were not built by serviceFlowCacheKey, like those built by just joining the
+// fields of services with underscores in them, which might be shared by
+// multiple services, or the externalIP and LB ingress keys without the
+// protocol, which might be shared by multiple ports.
+func isStaleServiceFlowCacheKey(key string) bool {
+	keyType, _, _ := strings.Cut(key, "_")
+	keyFields, ok := serviceFlowCacheKeyFields[keyType]
+	if !ok {
+		return false
+	}
+	fields, ok := splitServiceFlowCacheKey(key)
+	if ok && len(fields) == keyFields+1 && fields[keyFields] == serviceFlowCacheKeyH2C {
+		return false
+	}
+	return !ok || len(fields) != keyFields
+}
+
 func svcToCookie(namespace string, name string, token string, port int32) (string, error) {
 	id := fmt.Sprintf("%s%s%s%d", namespace, name, token, port)
 	h := fnv.New64a()
@@ -1890,8 +4408,29 @@ func svcToCookie(namespace string, name string, token string, port int32) (strin
 	return fmt.Sprintf("0x%x", h.Sum64()), nil
 }
 
-func addMasqueradeRoute(routeManager *routeManager, netIfaceName, nodeName string, ifAddrs []*net.IPNet, watchFactory factory.NodeWatchFactory) error {
+// getMasqueradeRouteSourceIPs returns the IPv4 and IPv6 source IPs of the
+// route towards the OVN masquerade IP: the configured ones, if any, and the
+// node IPs otherwise
+func getMasqueradeRouteSourceIPs(node *kapi.Node, ifAddrs []*net.IPNet) (net.IP, net.IP, error) {
 	var ipv4, ipv6 net.IP
+	// configured source IPs take precedence, they must be on the interface
+	for _, ip := range config.Gateway.GetMasqueradeRouteSourceIPs() {
+		found := false
+		for _, ifAddr := range ifAddrs {
+			if ifAddr.IP.Equal(ip) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("masquerade route source IP %s is not configured on the gateway interface", ip)
+		}
+		if utilnet.IsIPv6(ip) {
+			ipv6 = ip
+		} else {
+			ipv4 = ip
+		}
+	}
 	findIPs := func(ips []net.IP) error {
 		var err error
 		if config.IPv4Mode && ipv4 == nil {
@@ -1919,10 +4458,6 @@ func addMasqueradeRoute(routeManager *routeManager, netIfaceName, nodeName strin
 	// cause problems.
 
 	var nodeIPs []net.IP
-	node, err := watchFactory.GetNode(nodeName)
-	if err != nil {
-		return err
-	}
 	for _, nodeAddr := range node.Status.Addresses {
 		if nodeAddr.Type != kapi.NodeInternalIP {
 			continue
@@ -1931,7 +4466,7 @@ func addMasqueradeRoute(routeManager *routeManager, netIfaceName, nodeName strin
 		nodeIPs = append(nodeIPs, nodeIP)
 	}
 
-	err = findIPs(nodeIPs)
+	err := findIPs(nodeIPs)
 	if err != nil {
 		klog.Warningf("Unable to add OVN masquerade route to host using source node status IPs: %v", err)
 		// fallback to the interface IPs
@@ -1941,10 +4476,23 @@ func addMasqueradeRoute(routeManager *routeManager, netIfaceName, nodeName strin
 		}
 		err := findIPs(ifIPs)
 		if err != nil {
-			return fmt.Errorf("unable to add OVN masquerade route to host using interface IPs: %v", err)
+			return nil, nil, fmt.Errorf("unable to add OVN masquerade route to host using interface IPs: %v", err)
 		}
 	}
 
+	return ipv4, ipv6, nil
+}
+
+func addMasqueradeRoute(routeManager *routeManager, netIfaceName, nodeName string, ifAddrs []*net.IPNet, watchFactory factory.NodeWatchFactory) error {
+	node, err := watchFactory.GetNode(nodeName)
+	if err != nil {
+		return err
+	}
+	ipv4, ipv6, err := getMasqueradeRouteSourceIPs(node, ifAddrs)
+	if err != nil {
+		return err
+	}
+
 	netIfaceLink, err := util.LinkSetUp(netIfaceName)
 	if err != nil {
 		return fmt.Errorf("unable to find shared gw bridge interface: %s", netIfaceName)
@@ -1982,9 +4530,29 @@ func addMasqueradeRoute(routeManager *routeManager, netIfaceName, nodeName strin
 }
 
 func setNodeMasqueradeIPOnExtBridge(extBridgeName string) error {
+	_, err := ensureNodeMasqueradeIPOnExtBridge(extBridgeName)
+	return err
+}
+
+// verifyNodeMasqueradeIPOnExtBridge re-adds the node masquerade IPs missing from the ext bridge,
+// removed by an interface flap or an external tool, without which the service hairpin breaks
+func verifyNodeMasqueradeIPOnExtBridge(extBridgeName string) {
+	added, err := ensureNodeMasqueradeIPOnExtBridge(extBridgeName)
+	if err != nil {
+		klog.Errorf("Failed to verify the node masquerade IPs on the ext bridge %s: %v", extBridgeName, err)
+		return
+	}
+	for _, ipNet := range added {
+		klog.Warningf("Re-added the missing node masquerade IP %s on the ext bridge %s", ipNet, extBridgeName)
+	}
+}
+
+// ensureNodeMasqueradeIPOnExtBridge adds the node masquerade IPs missing from the ext bridge
+// and returns the ones it added
+func ensureNodeMasqueradeIPOnExtBridge(extBridgeName string) ([]*net.IPNet, error) {
 	extBridge, err := util.LinkSetUp(extBridgeName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var bridgeCIDRs []cidrAndFlags
@@ -2000,18 +4568,20 @@ func setNodeMasqueradeIPOnExtBridge(extBridgeName string) error {
 		bridgeCIDRs = append(bridgeCIDRs, cidrAndFlags{ipNet: masqIPNet, flags: unix.IFA_F_NODAD})
 	}
 
+	var added []*net.IPNet
 	for _, bridgeCIDR := range bridgeCIDRs {
 		if exists, err := util.LinkAddrExist(extBridge, bridgeCIDR.ipNet); err == nil && !exists {
 			if err := util.LinkAddrAdd(extBridge, bridgeCIDR.ipNet, bridgeCIDR.flags); err != nil {
-				return err
+				return added, err
 			}
+			added = append(added, bridgeCIDR.ipNet)
 		} else if err != nil {
-			return fmt.Errorf(
+			return added, fmt.Errorf(
 				"failed to check existence of addr %s in bridge %s: %v", bridgeCIDR.ipNet, extBridgeName, err)
 		}
 	}
 
-	return nil
+	return added, nil
 }
 
 func addHostMACBindings(bridgeName string) error {
@@ -58,6 +58,9 @@ func deleteLocalNodeAccessBridge() error {
 // addGatewayIptRules adds the necessary iptable rules for a service on the node
 func addGatewayIptRules(service *kapi.Service, localEndpoints []string, svcHasLocalHostNetEndPnt bool) error {
 	rules := getGatewayIPTRules(service, localEndpoints, svcHasLocalHostNetEndPnt)
+	// the jumps to the mark chain are only set up once a service has a mark and are never removed
+	// on service delete, as they might be needed by other services
+	rules = append(getServiceMarkJumpRules(rules), rules...)
 
 	if err := insertIptRules(rules); err != nil {
 		return fmt.Errorf("failed to add iptables rules for service %s/%s: %v",
@@ -0,0 +1,4338 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/metrics"
+	ovntest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing"
+	linkMocks "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/mocks/github.com/vishvananda/netlink"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util/mocks"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/mock"
+	"github.com/vishvananda/netlink"
+
+	kapi "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+)
+
+func TestServiceUpdateNotNeeded(t *testing.T) {
+	local := kapi.ServiceInternalTrafficPolicyLocal
+	cluster := kapi.ServiceInternalTrafficPolicyCluster
+
+	tests := []struct {
+		desc                               string
+		oldITP, newITP                     *kapi.ServiceInternalTrafficPolicyType
+		oldAllocateLBNPs, newAllocateLBNPs *bool
+		want                               bool
+	}{
+		{
+			desc: "ITP and AllocateLoadBalancerNodePorts unset",
+			want: true,
+		},
+		{
+			desc:             "ITP and AllocateLoadBalancerNodePorts equal",
+			oldITP:           &local,
+			newITP:           &local,
+			oldAllocateLBNPs: pointer.Bool(false),
+			newAllocateLBNPs: pointer.Bool(false),
+			want:             true,
+		},
+		{
+			desc:   "ITP set",
+			newITP: &local,
+			want:   false,
+		},
+		{
+			desc:   "ITP unset",
+			oldITP: &local,
+			want:   false,
+		},
+		{
+			desc:   "ITP changed",
+			oldITP: &cluster,
+			newITP: &local,
+			want:   false,
+		},
+		{
+			desc:             "AllocateLoadBalancerNodePorts set",
+			newAllocateLBNPs: pointer.Bool(true),
+			want:             false,
+		},
+		{
+			desc:             "AllocateLoadBalancerNodePorts unset",
+			oldAllocateLBNPs: pointer.Bool(true),
+			want:             false,
+		},
+		{
+			desc:             "AllocateLoadBalancerNodePorts changed",
+			oldAllocateLBNPs: pointer.Bool(true),
+			newAllocateLBNPs: pointer.Bool(false),
+			want:             false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			old := newService("service1", "namespace1", "10.129.0.2", nil, kapi.ServiceTypeLoadBalancer, nil, kapi.ServiceStatus{}, false, false)
+			old.Spec.InternalTrafficPolicy = tt.oldITP
+			old.Spec.AllocateLoadBalancerNodePorts = tt.oldAllocateLBNPs
+			new := old.DeepCopy()
+			new.Spec.InternalTrafficPolicy = tt.newITP
+			new.Spec.AllocateLoadBalancerNodePorts = tt.newAllocateLBNPs
+			if got := serviceUpdateNotNeeded(old, new); got != tt.want {
+				t.Errorf("serviceUpdateNotNeeded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceUpdateNotNeededIPOrder(t *testing.T) {
+	tests := []struct {
+		desc                         string
+		oldClusterIPs, newClusterIPs []string
+		oldExternalIPs               []string
+		newExternalIPs               []string
+		want                         bool
+	}{
+		{
+			desc:           "IPs reordered",
+			oldClusterIPs:  []string{"10.129.0.2", "fd00:10:96::2"},
+			newClusterIPs:  []string{"fd00:10:96::2", "10.129.0.2"},
+			oldExternalIPs: []string{"1.1.1.1", "2.2.2.2"},
+			newExternalIPs: []string{"2.2.2.2", "1.1.1.1"},
+			want:           true,
+		},
+		{
+			desc:          "cluster IP removed",
+			oldClusterIPs: []string{"10.129.0.2", "fd00:10:96::2"},
+			newClusterIPs: []string{"10.129.0.2"},
+			want:          false,
+		},
+		{
+			desc:           "external IP replaced",
+			oldClusterIPs:  []string{"10.129.0.2"},
+			newClusterIPs:  []string{"10.129.0.2"},
+			oldExternalIPs: []string{"1.1.1.1", "2.2.2.2"},
+			newExternalIPs: []string{"2.2.2.2", "3.3.3.3"},
+			want:           false,
+		},
+		{
+			desc:           "external IP duplicated",
+			oldClusterIPs:  []string{"10.129.0.2"},
+			newClusterIPs:  []string{"10.129.0.2"},
+			oldExternalIPs: []string{"1.1.1.1", "2.2.2.2"},
+			newExternalIPs: []string{"1.1.1.1", "2.2.2.2", "1.1.1.1"},
+			want:           false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			old := newService("service1", "namespace1", "10.129.0.2", nil, kapi.ServiceTypeLoadBalancer, tt.oldExternalIPs, kapi.ServiceStatus{}, false, false)
+			old.Spec.ClusterIPs = tt.oldClusterIPs
+			new := old.DeepCopy()
+			new.Spec.ClusterIPs = tt.newClusterIPs
+			new.Spec.ExternalIPs = tt.newExternalIPs
+			if got := serviceUpdateNotNeeded(old, new); got != tt.want {
+				t.Errorf("serviceUpdateNotNeeded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetServiceTrafficSteering(t *testing.T) {
+	newSvcConfig := func(isETPLocal, hasLocalHostNetworkEp bool, localEndpoints ...string) *serviceConfig {
+		service := newService("svc", "ns", "10.96.0.10", []kapi.ServicePort{{Port: 80, NodePort: 30080, Protocol: kapi.ProtocolTCP}},
+			kapi.ServiceTypeNodePort, nil, kapi.ServiceStatus{}, isETPLocal, false)
+		service.Spec.IPFamilies = []kapi.IPFamily{kapi.IPv4Protocol}
+		return &serviceConfig{service: service, hasLocalHostNetworkEp: hasLocalHostNetworkEp, localEndpoints: sets.New(localEndpoints...)}
+	}
+	clientIP := net.ParseIP("192.168.1.10")
+
+	tests := []struct {
+		desc        string
+		svcConfig   *serviceConfig
+		gatewayMode config.GatewayMode
+		clientIP    net.IP
+		want        serviceTrafficSteering
+	}{
+		{
+			desc:        "service not found",
+			gatewayMode: config.GatewayModeShared,
+			clientIP:    clientIP,
+			want:        serviceTrafficSteeringDrop,
+		},
+		{
+			desc:        "client IP family not served by the service",
+			svcConfig:   newSvcConfig(false, false),
+			gatewayMode: config.GatewayModeShared,
+			clientIP:    net.ParseIP("fd00::10"),
+			want:        serviceTrafficSteeringDrop,
+		},
+		{
+			desc:        "ETP=local with local host networked endpoints",
+			svcConfig:   newSvcConfig(true, true),
+			gatewayMode: config.GatewayModeShared,
+			clientIP:    clientIP,
+			want:        serviceTrafficSteeringHost,
+		},
+		{
+			desc:        "ETP=local without local endpoints",
+			svcConfig:   newSvcConfig(true, false),
+			gatewayMode: config.GatewayModeShared,
+			clientIP:    clientIP,
+			want:        serviceTrafficSteeringDrop,
+		},
+		{
+			desc:        "ETP=local with local endpoints in shared gateway mode",
+			svcConfig:   newSvcConfig(true, false, "10.244.0.5"),
+			gatewayMode: config.GatewayModeShared,
+			clientIP:    clientIP,
+			want:        serviceTrafficSteeringOVN,
+		},
+		{
+			desc:        "ETP=cluster in shared gateway mode",
+			svcConfig:   newSvcConfig(false, false),
+			gatewayMode: config.GatewayModeShared,
+			clientIP:    clientIP,
+			want:        serviceTrafficSteeringOVN,
+		},
+		{
+			desc:        "ETP=cluster in local gateway mode",
+			svcConfig:   newSvcConfig(false, false),
+			gatewayMode: config.GatewayModeLocal,
+			clientIP:    clientIP,
+			want:        serviceTrafficSteeringHost,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got, reason := getServiceTrafficSteering(tt.svcConfig, tt.gatewayMode, tt.clientIP); got != tt.want {
+				t.Errorf("getServiceTrafficSteering() = %v (%s), want %v", got, reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostNetworkEndpointOnNonManagedNodeIP(t *testing.T) {
+	// 192.168.18.16 is a secondary host IP not managed as a node IP
+	localEndpoints := sets.New("192.168.18.16")
+
+	netlinkMock := &mocks.NetLinkOps{}
+	netlinkMock.On("AddrList", nil, netlink.FAMILY_ALL).Return([]netlink.Addr{
+		{IPNet: &net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)}},
+		{IPNet: &net.IPNet{IP: net.ParseIP("192.168.18.15"), Mask: net.CIDRMask(24, 32)}},
+		{IPNet: &net.IPNet{IP: net.ParseIP("192.168.18.16"), Mask: net.CIDRMask(24, 32)}},
+	}, nil)
+	origNetlinkInst := util.GetNetLinkOps()
+	util.SetNetLinkOpMockInst(netlinkMock)
+	t.Cleanup(func() { util.SetNetLinkOpMockInst(origNetlinkInst) })
+
+	npw := &nodePortWatcher{nodeIPManager: &addressManager{addresses: sets.New("192.168.18.15")}}
+
+	tests := []struct {
+		desc       string
+		allHostIPs bool
+		want       bool
+	}{
+		{
+			desc: "only node IPs are considered by default",
+			want: false,
+		},
+		{
+			desc:       "all host IPs are considered when enabled",
+			allHostIPs: true,
+			want:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.Gateway.HostNetworkEndpointsAllHostIPs = tt.allHostIPs
+			if got := util.HasLocalHostNetworkEndpoints(localEndpoints, npw.getHostNetworkEndpointNodeIPs()); got != tt.want {
+				t.Errorf("HasLocalHostNetworkEndpoints() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitSvcViaMgmPortRoutingRulesIPv6(t *testing.T) {
+	_, hostSubnet, _ := net.ParseCIDR("fd00:10:244:1::/64")
+	_, svcCIDR, _ := net.ParseCIDR("fd00:10:96::/112")
+	routeCmd := "ip route replace table 7 fd00:10:96::/112 via fd00:10:244:1::1 dev ovn-k8s-mp0"
+	listRulesCmd := "ip -6 rule"
+	addRuleCmd := "ip -6 rule add fwmark 0x1745ec lookup 7 prio 30"
+	rpFilterCmd := &ovntest.ExpectedCmd{
+		Cmd:    "sysctl -w net.ipv4.conf.ovn-k8s-mp0.rp_filter=2",
+		Output: "net.ipv4.conf.ovn-k8s-mp0.rp_filter = 2",
+	}
+	forwardingCmd := &ovntest.ExpectedCmd{
+		Cmd:    "sysctl -w net.ipv6.conf.ovn-k8s-mp0.forwarding=1",
+		Output: "net.ipv6.conf.ovn-k8s-mp0.forwarding = 1",
+	}
+	acceptRACmd := &ovntest.ExpectedCmd{
+		Cmd:    "sysctl -w net.ipv6.conf.ovn-k8s-mp0.accept_ra=0",
+		Output: "net.ipv6.conf.ovn-k8s-mp0.accept_ra = 0",
+	}
+
+	tests := []struct {
+		desc    string
+		cmds    []*ovntest.ExpectedCmd
+		wantErr string
+	}{
+		{
+			desc: "adds the v6 route, rule and sysctls",
+			cmds: []*ovntest.ExpectedCmd{
+				{Cmd: routeCmd},
+				{Cmd: listRulesCmd},
+				{Cmd: addRuleCmd},
+				rpFilterCmd,
+				forwardingCmd,
+				acceptRACmd,
+			},
+		},
+		{
+			desc: "does not add the v6 rule if it exists",
+			cmds: []*ovntest.ExpectedCmd{
+				{Cmd: routeCmd},
+				{Cmd: listRulesCmd, Output: "30:	from all fwmark 0x1745ec lookup 7"},
+				rpFilterCmd,
+				forwardingCmd,
+				acceptRACmd,
+			},
+		},
+		{
+			desc: "fails on v6 route errors",
+			cmds: []*ovntest.ExpectedCmd{
+				{Cmd: routeCmd, Err: fmt.Errorf("failed")},
+			},
+			wantErr: "error adding IPv6 route fd00:10:96::/112 via fd00:10:244:1::1 into custom routing table",
+		},
+		{
+			desc: "fails on v6 rule errors",
+			cmds: []*ovntest.ExpectedCmd{
+				{Cmd: routeCmd},
+				{Cmd: listRulesCmd},
+				{Cmd: addRuleCmd, Err: fmt.Errorf("failed")},
+			},
+			wantErr: "could not add IPv6 rule: error adding -6 routing rule",
+		},
+		{
+			desc: "fails on v6 sysctl errors",
+			cmds: []*ovntest.ExpectedCmd{
+				{Cmd: routeCmd},
+				{Cmd: listRulesCmd},
+				{Cmd: addRuleCmd},
+				rpFilterCmd,
+				forwardingCmd,
+				{Cmd: acceptRACmd.Cmd, Err: fmt.Errorf("failed")},
+			},
+			wantErr: "could not set the correct IPv6 accept_ra value for interface ovn-k8s-mp0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.IPv4Mode = false
+			config.IPv6Mode = true
+			config.Kubernetes.ServiceCIDRs = []*net.IPNet{svcCIDR}
+
+			fexec := ovntest.NewFakeExec()
+			for _, cmd := range tt.cmds {
+				// the expected commands are shared by the test cases
+				cmd := *cmd
+				fexec.AddFakeCmd(&cmd)
+			}
+			if err := util.SetExec(fexec); err != nil {
+				t.Fatal(err)
+			}
+
+			err := initSvcViaMgmPortRoutingRules([]*net.IPNet{hostSubnet}, types.K8sMgmtIntfName)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("initSvcViaMgmPortRoutingRules() unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("initSvcViaMgmPortRoutingRules() error = %v, want %q", err, tt.wantErr)
+			}
+			if !fexec.CalledMatchesExpected() {
+				t.Error(fexec.ErrorDesc())
+			}
+		})
+	}
+}
+
+func TestInitSvcViaMgmPortRoutingRulesITPLocalRoutes(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.IPv4Mode = true
+	config.IPv6Mode = false
+	config.Gateway.ITPLocalMgmtPortRoutes = true
+	config.Kubernetes.ServiceCIDRs = []*net.IPNet{ovntest.MustParseIPNet("10.96.0.0/16")}
+
+	fexec := ovntest.NewFakeExec()
+	// the service CIDR route is not added, the routes of the services are flushed instead
+	fexec.AddFakeCmdsNoOutputNoError([]string{
+		"ip -4 route flush table 7",
+		"ip -4 rule",
+		"ip -4 rule add fwmark 0x1745ec lookup 7 prio 30",
+	})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+		Cmd:    "sysctl -w net.ipv4.conf.ovn-k8s-mp0.rp_filter=2",
+		Output: "net.ipv4.conf.ovn-k8s-mp0.rp_filter = 2",
+	})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := initSvcViaMgmPortRoutingRules([]*net.IPNet{ovntest.MustParseIPNet("10.244.1.0/24")}, types.K8sMgmtIntfName); err != nil {
+		t.Fatalf("initSvcViaMgmPortRoutingRules() unexpected error: %v", err)
+	}
+	if !fexec.CalledMatchesExpected() {
+		t.Error(fexec.ErrorDesc())
+	}
+}
+
+func TestInitSvcViaMgmPortRoutingRulesExternalIPSourcePreservation(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.IPv4Mode = true
+	config.IPv6Mode = false
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.ExternalIPSourcePreservation = true
+	config.Kubernetes.ServiceCIDRs = []*net.IPNet{ovntest.MustParseIPNet("10.96.0.0/16")}
+
+	fexec := ovntest.NewFakeExec()
+	// the externalIP traffic keeping its source IP is routed via the management port like the ITP=local one
+	fexec.AddFakeCmdsNoOutputNoError([]string{
+		"ip route replace table 7 10.96.0.0/16 via 10.244.1.1 dev ovn-k8s-mp0",
+		"ip -4 rule",
+		"ip -4 rule add fwmark 0x1745ec lookup 7 prio 30",
+		"ip -4 rule add fwmark 0x1745ed lookup 7 prio 30",
+	})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+		Cmd:    "sysctl -w net.ipv4.conf.ovn-k8s-mp0.rp_filter=2",
+		Output: "net.ipv4.conf.ovn-k8s-mp0.rp_filter = 2",
+	})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := initSvcViaMgmPortRoutingRules([]*net.IPNet{ovntest.MustParseIPNet("10.244.1.0/24")}, types.K8sMgmtIntfName); err != nil {
+		t.Fatalf("initSvcViaMgmPortRoutingRules() unexpected error: %v", err)
+	}
+	if !fexec.CalledMatchesExpected() {
+		t.Error(fexec.ErrorDesc())
+	}
+}
+
+func TestInitSvcViaMgmPortRoutingRulesCustomInterface(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.IPv4Mode = true
+	config.IPv6Mode = true
+	config.Kubernetes.ServiceCIDRs = []*net.IPNet{
+		ovntest.MustParseIPNet("10.96.0.0/16"),
+		ovntest.MustParseIPNet("fd00:10:96::/112"),
+	}
+	hostSubnets := []*net.IPNet{
+		ovntest.MustParseIPNet("10.244.1.0/24"),
+		ovntest.MustParseIPNet("fd00:10:244:1::/64"),
+	}
+
+	// the routes and the sysctls target the given management port
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmdsNoOutputNoError([]string{
+		"ip route replace table 7 10.96.0.0/16 via 10.244.1.1 dev ovn-k8s-mp1",
+		"ip route replace table 7 fd00:10:96::/112 via fd00:10:244:1::1 dev ovn-k8s-mp1",
+		"ip -4 rule",
+		"ip -4 rule add fwmark 0x1745ec lookup 7 prio 30",
+		"ip -6 rule",
+		"ip -6 rule add fwmark 0x1745ec lookup 7 prio 30",
+	})
+	for _, sysctl := range []string{
+		"net.ipv4.conf.ovn-k8s-mp1.rp_filter = 2",
+		"net.ipv6.conf.ovn-k8s-mp1.forwarding = 1",
+		"net.ipv6.conf.ovn-k8s-mp1.accept_ra = 0",
+	} {
+		fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+			Cmd:    "sysctl -w " + strings.ReplaceAll(sysctl, " ", ""),
+			Output: sysctl,
+		})
+	}
+	fexec.AddFakeCmdsNoOutputNoError([]string{
+		"ip route replace table 7 10.96.0.10 via 10.244.1.1 dev ovn-k8s-mp1",
+	})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := initSvcViaMgmPortRoutingRules(hostSubnets, "ovn-k8s-mp1"); err != nil {
+		t.Fatalf("initSvcViaMgmPortRoutingRules() unexpected error: %v", err)
+	}
+	if err := newSvcViaMgmPortRoutes(hostSubnets, "ovn-k8s-mp1").route("replace", "10.96.0.10"); err != nil {
+		t.Fatalf("route() unexpected error: %v", err)
+	}
+	if !fexec.CalledMatchesExpected() {
+		t.Error(fexec.ErrorDesc())
+	}
+}
+
+func TestInitSvcViaMgmPortRoutingRulesDualStackITPLocalRoutes(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.IPv4Mode = true
+	config.IPv6Mode = true
+	config.Gateway.ITPLocalMgmtPortRoutes = true
+	config.Kubernetes.ServiceCIDRs = []*net.IPNet{
+		ovntest.MustParseIPNet("10.96.0.0/16"),
+		ovntest.MustParseIPNet("fd00:10:96::/112"),
+	}
+	hostSubnets := []*net.IPNet{
+		ovntest.MustParseIPNet("10.244.1.0/24"),
+		ovntest.MustParseIPNet("fd00:10:244:1::/64"),
+	}
+
+	// the routes and the rule of the marked ITP=local traffic are set up for both families
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmdsNoOutputNoError([]string{
+		"ip -4 route flush table 7",
+		"ip -6 route flush table 7",
+		"ip -4 rule",
+		"ip -4 rule add fwmark 0x1745ec lookup 7 prio 30",
+		"ip -6 rule",
+		"ip -6 rule add fwmark 0x1745ec lookup 7 prio 30",
+	})
+	for _, sysctl := range []string{
+		"net.ipv4.conf.ovn-k8s-mp0.rp_filter = 2",
+		"net.ipv6.conf.ovn-k8s-mp0.forwarding = 1",
+		"net.ipv6.conf.ovn-k8s-mp0.accept_ra = 0",
+	} {
+		fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+			Cmd:    "sysctl -w " + strings.ReplaceAll(sysctl, " ", ""),
+			Output: sysctl,
+		})
+	}
+	// both ClusterIPs of a dual-stack ITP=local service are routed via the management port
+	fexec.AddFakeCmdsNoOutputNoError([]string{
+		"ip route replace table 7 10.96.0.10 via 10.244.1.1 dev ovn-k8s-mp0",
+		"ip route replace table 7 fd00:10:96::10 via fd00:10:244:1::1 dev ovn-k8s-mp0",
+	})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := initSvcViaMgmPortRoutingRules(hostSubnets, types.K8sMgmtIntfName); err != nil {
+		t.Fatalf("initSvcViaMgmPortRoutingRules() unexpected error: %v", err)
+	}
+	ports := []kapi.ServicePort{{Name: "http", Protocol: kapi.ProtocolTCP, Port: 80}}
+	service := newService("svc1", "ns", "10.96.0.10", ports, kapi.ServiceTypeClusterIP, nil, kapi.ServiceStatus{}, false, true)
+	service.Spec.ClusterIPs = []string{"10.96.0.10", "fd00:10:96::10"}
+	if err := newSvcViaMgmPortRoutes(hostSubnets, types.K8sMgmtIntfName).sync(service); err != nil {
+		t.Fatalf("sync() unexpected error: %v", err)
+	}
+	if !fexec.CalledMatchesExpected() {
+		t.Error(fexec.ErrorDesc())
+	}
+}
+
+func TestSvcViaMgmPortRoutes(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	routes := newSvcViaMgmPortRoutes([]*net.IPNet{
+		ovntest.MustParseIPNet("10.244.1.0/24"),
+		ovntest.MustParseIPNet("fd00:10:244:1::/64"),
+	}, types.K8sMgmtIntfName)
+	ports := []kapi.ServicePort{{Name: "http", Protocol: kapi.ProtocolTCP, Port: 80}}
+	itpLocal := newService("svc1", "ns", "10.96.0.10", ports, kapi.ServiceTypeClusterIP, nil, kapi.ServiceStatus{}, false, true)
+	itpLocal.Spec.ClusterIPs = []string{"10.96.0.10", "fd00:10:96::10"}
+	otherITPLocal := newService("svc2", "ns", "10.96.0.20", ports, kapi.ServiceTypeClusterIP, nil, kapi.ServiceStatus{}, false, true)
+	itpCluster := newService("svc1", "ns", "10.96.0.10", ports, kapi.ServiceTypeClusterIP, nil, kapi.ServiceStatus{}, false, false)
+
+	steps := []struct {
+		desc string
+		run  func() error
+		cmds []string
+	}{
+		{
+			desc: "routes the ClusterIPs of an ITP=local service",
+			run:  func() error { return routes.sync(itpLocal) },
+			cmds: []string{
+				"ip route replace table 7 10.96.0.10 via 10.244.1.1 dev ovn-k8s-mp0",
+				"ip route replace table 7 fd00:10:96::10 via fd00:10:244:1::1 dev ovn-k8s-mp0",
+			},
+		},
+		{
+			desc: "does not route the ClusterIPs of a routed service again",
+			run:  func() error { return routes.sync(itpLocal) },
+		},
+		{
+			desc: "routes the ClusterIPs of another ITP=local service",
+			run:  func() error { return routes.sync(otherITPLocal) },
+			cmds: []string{
+				"ip route replace table 7 10.96.0.20 via 10.244.1.1 dev ovn-k8s-mp0",
+			},
+		},
+		{
+			desc: "removes the routes of a service no longer ITP=local",
+			run:  func() error { return routes.sync(itpCluster) },
+			cmds: []string{
+				"ip route del table 7 10.96.0.10 via 10.244.1.1 dev ovn-k8s-mp0",
+				"ip route del table 7 fd00:10:96::10 via fd00:10:244:1::1 dev ovn-k8s-mp0",
+			},
+		},
+		{
+			desc: "removes the routes of a deleted service",
+			run:  func() error { return routes.delete(ktypes.NamespacedName{Namespace: "ns", Name: "svc2"}) },
+			cmds: []string{
+				"ip route del table 7 10.96.0.20 via 10.244.1.1 dev ovn-k8s-mp0",
+			},
+		},
+	}
+	for _, step := range steps {
+		fexec := ovntest.NewFakeExec()
+		fexec.AddFakeCmdsNoOutputNoError(step.cmds)
+		if err := util.SetExec(fexec); err != nil {
+			t.Fatal(err)
+		}
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.desc, err)
+		}
+		if !fexec.CalledMatchesExpected() {
+			t.Errorf("%s: %s", step.desc, fexec.ErrorDesc())
+		}
+	}
+	if len(routes.clusterIPs) != 0 {
+		t.Errorf("expected no routed ClusterIPs once no ITP=local service remains, got %v", routes.clusterIPs)
+	}
+
+	// a failed route is retried by the next sync of the service
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ip route replace table 7 10.96.0.20 via 10.244.1.1 dev ovn-k8s-mp0", Err: fmt.Errorf("failed")})
+	fexec.AddFakeCmdsNoOutputNoError([]string{"ip route replace table 7 10.96.0.20 via 10.244.1.1 dev ovn-k8s-mp0"})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+	if err := routes.sync(otherITPLocal); err == nil {
+		t.Fatal("expected the failed route to be reported")
+	}
+	if err := routes.sync(otherITPLocal); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !fexec.CalledMatchesExpected() {
+		t.Error(fexec.ErrorDesc())
+	}
+}
+
+func TestWatchOVSReconnect(t *testing.T) {
+	ofm := &openflowManager{flowChan: make(chan struct{}, 1)}
+	// connected, then the connection drops and is back
+	states := make(chan bool, 4)
+	for _, state := range []bool{true, true, false, true} {
+		states <- state
+	}
+	connected := func() bool {
+		select {
+		case state := <-states:
+			return state
+		default:
+			return true
+		}
+	}
+	reconnects := make(chan struct{}, 4)
+	onReconnect := func() {
+		ofm.requestFlowSync()
+		reconnects <- struct{}{}
+	}
+
+	stopChan := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watchOVSReconnect(connected, onReconnect, time.Millisecond, stopChan)
+	}()
+
+	select {
+	case <-ofm.flowChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a flow sync to be requested on the vswitchd database reconnect")
+	}
+	// the connection stays up, no other sync is requested
+	time.Sleep(20 * time.Millisecond)
+	close(stopChan)
+	<-done
+	if len(reconnects) != 1 {
+		t.Errorf("expected a single reconnect, got %d", len(reconnects))
+	}
+	if len(ofm.flowChan) != 0 {
+		t.Error("expected no other flow sync to be requested")
+	}
+}
+
+func TestGatewayBridgesReady(t *testing.T) {
+	gwBridge := &bridgeConfiguration{bridgeName: "breth0", patchPort: "patch-breth0_node1-to-br-int"}
+	exGwBridge := &bridgeConfiguration{bridgeName: "breth1", patchPort: "patch-breth1_node1-to-br-int"}
+	ofportCmd := func(patchPort, ofport string) *ovntest.ExpectedCmd {
+		return &ovntest.ExpectedCmd{
+			Cmd:    "ovs-vsctl --timeout=15 --if-exists get interface " + patchPort + " ofport",
+			Output: ofport,
+		}
+	}
+	for _, tc := range []struct {
+		desc       string
+		exGwBridge *bridgeConfiguration
+		cmds       []*ovntest.ExpectedCmd
+		wantReady  bool
+		wantReason string
+	}{
+		{
+			desc:      "ready",
+			cmds:      []*ovntest.ExpectedCmd{ofportCmd(gwBridge.patchPort, "5")},
+			wantReady: true,
+		},
+		{
+			desc:       "gateway bridge patch port not ready",
+			exGwBridge: exGwBridge,
+			cmds:       []*ovntest.ExpectedCmd{ofportCmd(gwBridge.patchPort, "")},
+			wantReason: "patch port patch-breth0_node1-to-br-int of gateway bridge breth0 is not ready",
+		},
+		{
+			desc:       "external gateway bridge patch port not ready",
+			exGwBridge: exGwBridge,
+			cmds:       []*ovntest.ExpectedCmd{ofportCmd(gwBridge.patchPort, "5"), ofportCmd(exGwBridge.patchPort, "-1")},
+			wantReason: "patch port patch-breth1_node1-to-br-int of external gateway bridge breth1 is not ready",
+		},
+		{
+			desc:       "both bridges ready",
+			exGwBridge: exGwBridge,
+			cmds:       []*ovntest.ExpectedCmd{ofportCmd(gwBridge.patchPort, "5"), ofportCmd(exGwBridge.patchPort, "6")},
+			wantReady:  true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			fexec := ovntest.NewFakeExec()
+			for _, cmd := range tc.cmds {
+				fexec.AddFakeCmd(cmd)
+			}
+			if err := util.SetExec(fexec); err != nil {
+				t.Fatal(err)
+			}
+			gw := &gateway{readyFunc: func() (bool, string, error) {
+				return gatewayBridgesReady(gwBridge, tc.exGwBridge)
+			}}
+			ready, reason, err := gw.readyFunc()
+			if err != nil {
+				t.Fatal(err)
+			}
+			gw.setReadiness(ready, reason)
+			if ready != tc.wantReady || reason != tc.wantReason {
+				t.Errorf("gatewayBridgesReady() = %v, %q, want %v, %q", ready, reason, tc.wantReady, tc.wantReason)
+			}
+			// the reason is reported by the gateway readiness debug endpoint
+			if ready, reason := gw.getReadiness(); ready != tc.wantReady || reason != tc.wantReason {
+				t.Errorf("getReadiness() = %v, %q, want %v, %q", ready, reason, tc.wantReady, tc.wantReason)
+			}
+			if !fexec.CalledMatchesExpected() {
+				t.Error(fexec.ErrorDesc())
+			}
+		})
+	}
+}
+
+func TestVerifyNodeMasqueradeIPOnExtBridge(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.IPv4Mode = true
+	config.IPv6Mode = false
+	masqIPNet := &net.IPNet{IP: net.ParseIP(types.V4HostMasqueradeIP), Mask: net.CIDRMask(29, 32)}
+
+	linkMock := &linkMocks.Link{}
+	netlinkMock := &mocks.NetLinkOps{}
+	netlinkMock.On("LinkByName", "breth0").Return(linkMock, nil)
+	netlinkMock.On("LinkSetUp", linkMock).Return(nil)
+	// the masquerade IP is present on the first check, removed on the second one
+	netlinkMock.On("AddrList", linkMock, netlink.FAMILY_V4).Return([]netlink.Addr{{IPNet: masqIPNet}}, nil).Once()
+	netlinkMock.On("AddrList", linkMock, netlink.FAMILY_V4).Return([]netlink.Addr{}, nil).Once()
+	netlinkMock.On("AddrAdd", linkMock, &netlink.Addr{IPNet: masqIPNet, Flags: 0}).Return(nil).Once()
+	origNetlinkInst := util.GetNetLinkOps()
+	util.SetNetLinkOpMockInst(netlinkMock)
+	t.Cleanup(func() { util.SetNetLinkOpMockInst(origNetlinkInst) })
+
+	verifyNodeMasqueradeIPOnExtBridge("breth0")
+	netlinkMock.AssertNotCalled(t, "AddrAdd", mock.Anything, mock.Anything)
+
+	verifyNodeMasqueradeIPOnExtBridge("breth0")
+	netlinkMock.AssertExpectations(t)
+}
+
+func TestDeleteConntrackForServiceInChunks(t *testing.T) {
+	service := newService("service1", "namespace1", "10.96.0.10",
+		[]kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP}}, kapi.ServiceTypeClusterIP,
+		nil, kapi.ServiceStatus{}, false, false)
+	npw := &nodePortWatcher{}
+
+	tests := []struct {
+		desc        string
+		chunkSize   int
+		drainWindow time.Duration
+		want        []uint
+	}{
+		{
+			desc: "all entries are deleted at once by default",
+			want: []uint{25},
+		},
+		{
+			desc:        "entries are deleted in chunks",
+			chunkSize:   10,
+			drainWindow: time.Minute,
+			want:        []uint{10, 10, 5},
+		},
+		{
+			desc:        "remaining entries are deleted at once after the drain window",
+			chunkSize:   10,
+			drainWindow: 0,
+			want:        []uint{25},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.Gateway.ConntrackDrainChunkSize = tt.chunkSize
+			origDrainWindow, origDrainInterval := serviceConntrackDrainWindow, serviceConntrackDrainInterval
+			serviceConntrackDrainWindow, serviceConntrackDrainInterval = tt.drainWindow, time.Millisecond
+			t.Cleanup(func() {
+				serviceConntrackDrainWindow, serviceConntrackDrainInterval = origDrainWindow, origDrainInterval
+			})
+
+			// a conntrack table with many entries towards the service
+			var flows []*netlink.ConntrackFlow
+			for i := 0; i < 25; i++ {
+				flow := &netlink.ConntrackFlow{FamilyType: netlink.FAMILY_V4}
+				flow.Forward.Protocol = 6
+				flow.Forward.SrcIP = net.ParseIP("192.168.1.10")
+				flow.Forward.DstIP = net.ParseIP("10.96.0.10")
+				flow.Forward.SrcPort = uint16(40000 + i)
+				flow.Forward.DstPort = 80
+				flows = append(flows, flow)
+			}
+			var got []uint
+			deleteFilter := func(_ netlink.ConntrackTableType, _ netlink.InetFamily, filter netlink.CustomConntrackFilter) uint {
+				var remaining []*netlink.ConntrackFlow
+				for _, flow := range flows {
+					if !filter.MatchConntrackFlow(flow) {
+						remaining = append(remaining, flow)
+					}
+				}
+				deleted := uint(len(flows) - len(remaining))
+				flows = remaining
+				got = append(got, deleted)
+				return deleted
+			}
+			netlinkMock := &mocks.NetLinkOps{}
+			netlinkMock.On("ConntrackDeleteFilter", netlink.ConntrackTableType(netlink.ConntrackTable),
+				netlink.InetFamily(netlink.FAMILY_V4), mock.Anything).Return(deleteFilter, nil)
+			origNetlinkInst := util.GetNetLinkOps()
+			util.SetNetLinkOpMockInst(netlinkMock)
+			t.Cleanup(func() { util.SetNetLinkOpMockInst(origNetlinkInst) })
+
+			if err := npw.deleteConntrackForService(service); err != nil {
+				t.Fatalf("deleteConntrackForService() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deleteConntrackForService() deleted %v entries per call, want %v", got, tt.want)
+			}
+			if len(flows) != 0 {
+				t.Errorf("deleteConntrackForService() left %d entries", len(flows))
+			}
+		})
+	}
+}
+
+func TestDeleteConntrackForServiceCapped(t *testing.T) {
+	service := newService("service1", "namespace1", "10.96.0.10",
+		[]kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP}}, kapi.ServiceTypeClusterIP,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{}, false, false)
+	npw := &nodePortWatcher{}
+
+	tests := []struct {
+		desc      string
+		chunkSize int
+		want      []uint
+	}{
+		{
+			desc: "entries deleted at once",
+			want: []uint{25, 5},
+		},
+		{
+			desc:      "entries deleted in chunks",
+			chunkSize: 10,
+			want:      []uint{10, 10, 5, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.Gateway.ConntrackDrainChunkSize = tt.chunkSize
+			config.Gateway.MaxServiceConntrackDeletes = 30
+			origDrainWindow, origDrainInterval := serviceConntrackDrainWindow, serviceConntrackDrainInterval
+			serviceConntrackDrainWindow, serviceConntrackDrainInterval = time.Minute, time.Millisecond
+			var out bytes.Buffer
+			klog.LogToStderr(false)
+			klog.SetOutput(&out)
+			t.Cleanup(func() {
+				serviceConntrackDrainWindow, serviceConntrackDrainInterval = origDrainWindow, origDrainInterval
+				klog.SetOutput(nil)
+				klog.LogToStderr(true)
+			})
+
+			// a conntrack table with many entries towards the external IP and
+			// the cluster IP of the service
+			var flows []*netlink.ConntrackFlow
+			for _, dstIP := range []string{"1.1.1.1", "10.96.0.10"} {
+				for i := 0; i < 25; i++ {
+					flow := &netlink.ConntrackFlow{FamilyType: netlink.FAMILY_V4}
+					flow.Forward.Protocol = 6
+					flow.Forward.SrcIP = net.ParseIP("192.168.1.10")
+					flow.Forward.DstIP = net.ParseIP(dstIP)
+					flow.Forward.SrcPort = uint16(40000 + i)
+					flow.Forward.DstPort = 80
+					flows = append(flows, flow)
+				}
+			}
+			var got []uint
+			deleteFilter := func(_ netlink.ConntrackTableType, _ netlink.InetFamily, filter netlink.CustomConntrackFilter) uint {
+				var remaining []*netlink.ConntrackFlow
+				for _, flow := range flows {
+					if !filter.MatchConntrackFlow(flow) {
+						remaining = append(remaining, flow)
+					}
+				}
+				deleted := uint(len(flows) - len(remaining))
+				flows = remaining
+				got = append(got, deleted)
+				return deleted
+			}
+			netlinkMock := &mocks.NetLinkOps{}
+			netlinkMock.On("ConntrackDeleteFilter", netlink.ConntrackTableType(netlink.ConntrackTable),
+				netlink.InetFamily(netlink.FAMILY_V4), mock.Anything).Return(deleteFilter, nil)
+			origNetlinkInst := util.GetNetLinkOps()
+			util.SetNetLinkOpMockInst(netlinkMock)
+			t.Cleanup(func() { util.SetNetLinkOpMockInst(origNetlinkInst) })
+
+			if err := npw.deleteConntrackForService(service); err != nil {
+				t.Fatalf("deleteConntrackForService() unexpected error: %v", err)
+			}
+			klog.Flush()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deleteConntrackForService() deleted %v entries per call, want %v", got, tt.want)
+			}
+			if len(flows) != 20 {
+				t.Errorf("deleteConntrackForService() left %d entries, want 20", len(flows))
+			}
+			if !strings.Contains(out.String(), "Deleted the maximum of 30 conntrack entries for service namespace1/service1") {
+				t.Errorf("expected a warning about the maximum of conntrack entries being deleted, got: %s", out.String())
+			}
+		})
+	}
+}
+
+func TestDeleteConntrackForServiceReplyDirection(t *testing.T) {
+	service := newService("service1", "namespace1", "10.96.0.10",
+		[]kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP}}, kapi.ServiceTypeClusterIP,
+		nil, kapi.ServiceStatus{}, false, false)
+	npw := &nodePortWatcher{}
+
+	type deletion struct {
+		ip           string
+		ipFilterType netlink.ConntrackFilterType
+	}
+	tests := []struct {
+		desc           string
+		replyDirection bool
+		chunkSize      int
+		want           []deletion
+	}{
+		{
+			desc: "original direction only",
+			want: []deletion{{"10.96.0.10", netlink.ConntrackOrigDstIP}},
+		},
+		{
+			desc:           "both directions",
+			replyDirection: true,
+			want: []deletion{
+				{"10.96.0.10", netlink.ConntrackOrigDstIP},
+				{"10.96.0.10", netlink.ConntrackReplySrcIP},
+			},
+		},
+		{
+			desc:           "both directions in chunks",
+			replyDirection: true,
+			chunkSize:      10,
+			want: []deletion{
+				{"10.96.0.10", netlink.ConntrackOrigDstIP},
+				{"10.96.0.10", netlink.ConntrackReplySrcIP},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.Gateway.ConntrackDeleteReplyDirection = tt.replyDirection
+			config.Gateway.ConntrackDrainChunkSize = tt.chunkSize
+			var got []deletion
+			record := func(ip string, ipFilterType netlink.ConntrackFilterType) {
+				got = append(got, deletion{ip, ipFilterType})
+			}
+			origUpTo, origInChunks := deleteConntrackServicePortUpTo, deleteConntrackServicePortInChunks
+			deleteConntrackServicePortUpTo = func(ip string, _ int32, _ kapi.Protocol, ipFilterType netlink.ConntrackFilterType,
+				_ [][]byte, _ uint) (uint, error) {
+				if tt.chunkSize > 0 {
+					t.Errorf("unexpected deletion of all the entries at once")
+				}
+				record(ip, ipFilterType)
+				return 1, nil
+			}
+			deleteConntrackServicePortInChunks = func(ip string, _ int32, _ kapi.Protocol, ipFilterType netlink.ConntrackFilterType,
+				_ [][]byte, _ uint, _ time.Duration, _ time.Time, _ uint) (uint, error) {
+				if tt.chunkSize == 0 {
+					t.Errorf("unexpected deletion of the entries in chunks")
+				}
+				record(ip, ipFilterType)
+				return 1, nil
+			}
+			t.Cleanup(func() {
+				deleteConntrackServicePortUpTo, deleteConntrackServicePortInChunks = origUpTo, origInChunks
+			})
+
+			if err := npw.deleteConntrackForService(service); err != nil {
+				t.Fatalf("deleteConntrackForService() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deleteConntrackForService() deleted %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteConntrackForRemovedEndpoints(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	service := newService("service1", "namespace1", "10.96.0.10",
+		[]kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP}}, kapi.ServiceTypeClusterIP,
+		nil, kapi.ServiceStatus{}, false, false)
+	npw := &nodePortWatcher{}
+
+	// a conntrack table with entries towards the service for each of its endpoints
+	var flows []*netlink.ConntrackFlow
+	for i, endpointIP := range []string{"10.128.0.5", "10.128.0.6", "10.128.0.5", "10.128.0.6"} {
+		flow := &netlink.ConntrackFlow{FamilyType: netlink.FAMILY_V4}
+		flow.Forward.Protocol = 6
+		flow.Forward.SrcIP = net.ParseIP("192.168.1.10")
+		flow.Forward.DstIP = net.ParseIP("10.96.0.10")
+		flow.Forward.SrcPort = uint16(40000 + i)
+		flow.Forward.DstPort = 80
+		flow.Reverse.Protocol = 6
+		flow.Reverse.SrcIP = net.ParseIP(endpointIP)
+		flow.Reverse.DstIP = net.ParseIP("192.168.1.10")
+		flow.Reverse.SrcPort = 8080
+		flow.Reverse.DstPort = uint16(40000 + i)
+		flows = append(flows, flow)
+	}
+	deleteFilter := func(_ netlink.ConntrackTableType, _ netlink.InetFamily, filter netlink.CustomConntrackFilter) uint {
+		var remaining []*netlink.ConntrackFlow
+		for _, flow := range flows {
+			if !filter.MatchConntrackFlow(flow) {
+				remaining = append(remaining, flow)
+			}
+		}
+		deleted := uint(len(flows) - len(remaining))
+		flows = remaining
+		return deleted
+	}
+	netlinkMock := &mocks.NetLinkOps{}
+	netlinkMock.On("ConntrackDeleteFilter", netlink.ConntrackTableType(netlink.ConntrackTable),
+		netlink.InetFamily(netlink.FAMILY_V4), mock.Anything).Return(deleteFilter, nil)
+	origNetlinkInst := util.GetNetLinkOps()
+	util.SetNetLinkOpMockInst(netlinkMock)
+	t.Cleanup(func() { util.SetNetLinkOpMockInst(origNetlinkInst) })
+
+	if err := npw.deleteConntrackForRemovedEndpoints(service, sets.New("10.128.0.5")); err != nil {
+		t.Fatalf("deleteConntrackForRemovedEndpoints() unexpected error: %v", err)
+	}
+	if len(flows) != 2 {
+		t.Fatalf("deleteConntrackForRemovedEndpoints() left %d entries, want 2", len(flows))
+	}
+	for _, flow := range flows {
+		if !flow.Reverse.SrcIP.Equal(net.ParseIP("10.128.0.6")) {
+			t.Errorf("deleteConntrackForRemovedEndpoints() left an entry of endpoint %s", flow.Reverse.SrcIP)
+		}
+	}
+}
+
+func TestNodePortProtocolChangeConntrack(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	oldSvc := newService("service1", "namespace1", "10.96.0.10",
+		[]kapi.ServicePort{
+			{Name: "dns", Port: 53, Protocol: kapi.ProtocolTCP, NodePort: 30053},
+			{Name: "http", Port: 80, Protocol: kapi.ProtocolTCP, NodePort: 30080},
+		}, kapi.ServiceTypeNodePort, nil, kapi.ServiceStatus{}, false, false)
+	newSvc := oldSvc.DeepCopy()
+	newSvc.Spec.Ports[0].Protocol = kapi.ProtocolUDP
+	newSvc.Spec.Ports[1].Port = 8080
+
+	// only the NodePort reused with another protocol changed
+	changedPorts := nodePortProtocolChanges(oldSvc, newSvc)
+	if !reflect.DeepEqual(changedPorts, oldSvc.Spec.Ports[:1]) {
+		t.Fatalf("expected the changed ports %v, got %v", oldSvc.Spec.Ports[:1], changedPorts)
+	}
+	// a NodePort served with both protocols keeps its entries
+	bothProtocols := newSvc.DeepCopy()
+	bothProtocols.Spec.Ports = append(bothProtocols.Spec.Ports,
+		kapi.ServicePort{Name: "dns-tcp", Port: 53, Protocol: kapi.ProtocolTCP, NodePort: 30053})
+	if changedPorts := nodePortProtocolChanges(oldSvc, bothProtocols); len(changedPorts) != 0 {
+		t.Errorf("expected no changed ports, got %v", changedPorts)
+	}
+
+	// a conntrack table with TCP entries towards the NodePort before the change and UDP ones after
+	var flows []*netlink.ConntrackFlow
+	for i, protocol := range []uint8{6, 6, 17} {
+		flow := &netlink.ConntrackFlow{FamilyType: netlink.FAMILY_V4}
+		flow.Forward.Protocol = protocol
+		flow.Forward.SrcIP = net.ParseIP("192.168.1.10")
+		flow.Forward.DstIP = net.ParseIP("192.168.18.15")
+		flow.Forward.SrcPort = uint16(40000 + i)
+		flow.Forward.DstPort = 30053
+		flows = append(flows, flow)
+	}
+	deleteFilter := func(_ netlink.ConntrackTableType, _ netlink.InetFamily, filter netlink.CustomConntrackFilter) uint {
+		var remaining []*netlink.ConntrackFlow
+		for _, flow := range flows {
+			if !filter.MatchConntrackFlow(flow) {
+				remaining = append(remaining, flow)
+			}
+		}
+		deleted := uint(len(flows) - len(remaining))
+		flows = remaining
+		return deleted
+	}
+	netlinkMock := &mocks.NetLinkOps{}
+	netlinkMock.On("ConntrackDeleteFilter", netlink.ConntrackTableType(netlink.ConntrackTable),
+		netlink.InetFamily(netlink.FAMILY_V4), mock.Anything).Return(deleteFilter, nil)
+	origNetlinkInst := util.GetNetLinkOps()
+	util.SetNetLinkOpMockInst(netlinkMock)
+	t.Cleanup(func() { util.SetNetLinkOpMockInst(origNetlinkInst) })
+
+	npw := &nodePortWatcher{nodeIPManager: &addressManager{addresses: sets.New("192.168.18.15")}}
+	if err := npw.deleteConntrackForNodePorts(oldSvc, changedPorts); err != nil {
+		t.Fatalf("deleteConntrackForNodePorts() unexpected error: %v", err)
+	}
+	if len(flows) != 1 || flows[0].Forward.Protocol != 17 {
+		t.Errorf("expected only the UDP conntrack entry to remain, got %v", flows)
+	}
+}
+
+func TestQueryServiceConntrackFlush(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	service := newService("service1", "namespace1", "10.96.0.10",
+		[]kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP, NodePort: 30080}}, kapi.ServiceTypeNodePort,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{}, true, false)
+	name := ktypes.NamespacedName{Namespace: "namespace1", Name: "service1"}
+	npw := &nodePortWatcher{
+		nodeIPManager: &addressManager{addresses: sets.New("192.168.18.15")},
+		serviceInfo:   map[ktypes.NamespacedName]*serviceConfig{name: {service: service, localEndpoints: sets.New[string]()}},
+	}
+
+	expectFlush := func(ingressZones []int) {
+		t.Helper()
+		flush, err := npw.queryServiceConntrackFlush("namespace1", "service1")
+		if err != nil {
+			t.Fatalf("queryServiceConntrackFlush() unexpected error: %v", err)
+		}
+		expected := &metrics.ServiceConntrackFlush{
+			Service: "namespace1/service1",
+			Tuples: []metrics.ServiceConntrackTuple{
+				{Type: "External", IP: "1.1.1.1", Port: 80, Protocol: "TCP", Zones: ingressZones},
+				{Type: "NodePort", IP: "192.168.18.15", Port: 30080, Protocol: "TCP", Zones: ingressZones},
+				{Type: "ClusterIP", IP: "10.96.0.10", Port: 80, Protocol: "TCP", Zones: []int{64001, 64002}},
+			},
+		}
+		if !reflect.DeepEqual(flush, expected) {
+			t.Errorf("expected the conntrack flush %+v, got %+v", expected, flush)
+		}
+	}
+	expectFlush([]int{64000})
+	// the ingress traffic steered to the local host networked endpoints is also tracked in the host NodePort zone
+	npw.serviceInfo[name].hasLocalHostNetworkEp = true
+	expectFlush([]int{64000, 64003})
+
+	if _, err := npw.queryServiceConntrackFlush("namespace1", "service2"); err == nil {
+		t.Error("expected an error for an unknown service")
+	}
+	config.Gateway.DisableConntrackFlush = true
+	if flush, err := npw.queryServiceConntrackFlush("namespace1", "service1"); err != nil || len(flush.Tuples) != 0 {
+		t.Errorf("expected no tuples flushed with the conntrack flush disabled, got %v, %v", flush, err)
+	}
+}
+
+func TestDeleteConntrackDisabled(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.DisableConntrackFlush = true
+
+	netlinkMock := &mocks.NetLinkOps{}
+	origNetlinkInst := util.GetNetLinkOps()
+	util.SetNetLinkOpMockInst(netlinkMock)
+	t.Cleanup(func() { util.SetNetLinkOpMockInst(origNetlinkInst) })
+
+	service := newService("service1", "namespace1", "10.96.0.10",
+		[]kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolUDP, NodePort: 30080}}, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, false, false)
+	npw := &nodePortWatcher{}
+	if err := npw.deleteConntrackForService(service); err != nil {
+		t.Fatalf("deleteConntrackForService() unexpected error: %v", err)
+	}
+
+	udp := kapi.ProtocolUDP
+	port := int32(53)
+	endpointSlice := &discovery.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "service1-ab12",
+			Namespace: "namespace1",
+			Labels:    map[string]string{discovery.LabelServiceName: "service1"},
+		},
+		AddressType: discovery.AddressTypeIPv4,
+		Ports:       []discovery.EndpointPort{{Protocol: &udp, Port: &port}},
+		Endpoints:   []discovery.Endpoint{{Addresses: []string{"10.128.0.5"}}},
+	}
+	nc := &DefaultNodeNetworkController{}
+	if err := nc.reconcileConntrackUponEndpointSliceEvents(endpointSlice, nil); err != nil {
+		t.Fatalf("reconcileConntrackUponEndpointSliceEvents() unexpected error: %v", err)
+	}
+
+	netlinkMock.AssertNotCalled(t, "ConntrackDeleteFilter", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestServiceConntrackDeletesMetric(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	service := newService("service1", "namespace1", "10.96.0.10",
+		[]kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP}}, kapi.ServiceTypeClusterIP,
+		nil, kapi.ServiceStatus{}, false, false)
+	npw := &nodePortWatcher{}
+
+	// a conntrack table with 3 entries towards the service for an endpoint and 2 for another
+	var flows []*netlink.ConntrackFlow
+	for i, endpointIP := range []string{"10.128.0.5", "10.128.0.6", "10.128.0.5", "10.128.0.6", "10.128.0.5"} {
+		flow := &netlink.ConntrackFlow{FamilyType: netlink.FAMILY_V4}
+		flow.Forward.Protocol = 6
+		flow.Forward.SrcIP = net.ParseIP("192.168.1.10")
+		flow.Forward.DstIP = net.ParseIP("10.96.0.10")
+		flow.Forward.SrcPort = uint16(40000 + i)
+		flow.Forward.DstPort = 80
+		flow.Reverse.Protocol = 6
+		flow.Reverse.SrcIP = net.ParseIP(endpointIP)
+		flow.Reverse.DstIP = net.ParseIP("192.168.1.10")
+		flow.Reverse.SrcPort = 8080
+		flow.Reverse.DstPort = uint16(40000 + i)
+		flows = append(flows, flow)
+	}
+	deleteFilter := func(_ netlink.ConntrackTableType, _ netlink.InetFamily, filter netlink.CustomConntrackFilter) uint {
+		var remaining []*netlink.ConntrackFlow
+		for _, flow := range flows {
+			if !filter.MatchConntrackFlow(flow) {
+				remaining = append(remaining, flow)
+			}
+		}
+		deleted := uint(len(flows) - len(remaining))
+		flows = remaining
+		return deleted
+	}
+	netlinkMock := &mocks.NetLinkOps{}
+	netlinkMock.On("ConntrackDeleteFilter", netlink.ConntrackTableType(netlink.ConntrackTable),
+		netlink.InetFamily(netlink.FAMILY_V4), mock.Anything).Return(deleteFilter, nil)
+	origNetlinkInst := util.GetNetLinkOps()
+	util.SetNetLinkOpMockInst(netlinkMock)
+	t.Cleanup(func() { util.SetNetLinkOpMockInst(origNetlinkInst) })
+
+	getCount := func(purpose string) float64 {
+		t.Helper()
+		m := &dto.Metric{}
+		if err := metrics.MetricServiceConntrackDeletes.WithLabelValues(purpose).Write(m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetCounter().GetValue()
+	}
+	endpointChange := getCount(conntrackDeletePurposeEndpointChange)
+	serviceDelete := getCount(conntrackDeletePurposeServiceDelete)
+
+	if err := npw.deleteConntrackForRemovedEndpoints(service, sets.New("10.128.0.5")); err != nil {
+		t.Fatalf("deleteConntrackForRemovedEndpoints() unexpected error: %v", err)
+	}
+	if got := getCount(conntrackDeletePurposeEndpointChange) - endpointChange; got != 3 {
+		t.Errorf("expected 3 conntrack entries deleted on endpoint change, got %v", got)
+	}
+
+	if err := npw.deleteConntrackForService(service); err != nil {
+		t.Fatalf("deleteConntrackForService() unexpected error: %v", err)
+	}
+	if got := getCount(conntrackDeletePurposeServiceDelete) - serviceDelete; got != 2 {
+		t.Errorf("expected 2 conntrack entries deleted on service delete, got %v", got)
+	}
+	if got := getCount(conntrackDeletePurposeEndpointChange) - endpointChange; got != 3 {
+		t.Errorf("expected the conntrack entries deleted on endpoint change to remain 3, got %v", got)
+	}
+}
+
+func TestServiceFlowPathsMetric(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+
+	getCount := func(path serviceFlowPath, mode config.GatewayMode) float64 {
+		t.Helper()
+		m := &dto.Metric{}
+		if err := metrics.MetricServiceFlowPaths.WithLabelValues(string(path), string(mode)).Write(m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetGauge().GetValue()
+	}
+	expectCounts := func(mode config.GatewayMode, host, ovn, def float64) {
+		t.Helper()
+		for path, want := range map[serviceFlowPath]float64{
+			serviceFlowPathHost:    host,
+			serviceFlowPathOVN:     ovn,
+			serviceFlowPathDefault: def,
+		} {
+			if got := getCount(path, mode); got != want {
+				t.Errorf("expected %v services on the %s path in %s mode, got %v", want, path, mode, got)
+			}
+		}
+	}
+
+	ports := []kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP, NodePort: 30080, TargetPort: intstr.FromInt(8080)}}
+	etpLocalHostEp := newService("etp-local-host-ep", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, true, false)
+	etpLocal := newService("etp-local", "namespace1", "10.96.0.11", ports, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, true, false)
+	etpCluster := newService("etp-cluster", "namespace1", "10.96.0.12", ports, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, false, false)
+
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		ofm:         &openflowManager{flowCache: map[string][]string{}},
+	}
+	for _, svc := range []struct {
+		service               *kapi.Service
+		hasLocalHostNetworkEp bool
+	}{
+		{etpLocalHostEp, true},
+		{etpLocal, false},
+		{etpCluster, false},
+	} {
+		if err := npw.updateServiceFlowCache(svc.service, true, svc.hasLocalHostNetworkEp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectCounts(config.GatewayModeShared, 1, 2, 0)
+
+	if err := npw.updateServiceFlowCache(etpLocal, false, false); err != nil {
+		t.Fatal(err)
+	}
+	expectCounts(config.GatewayModeShared, 1, 1, 0)
+
+	// a sync in local gateway mode recomputes the paths from scratch
+	config.Gateway.Mode = config.GatewayModeLocal
+	npw.resetServiceFlowPaths()
+	for _, svc := range []struct {
+		service               *kapi.Service
+		hasLocalHostNetworkEp bool
+	}{
+		{etpLocalHostEp, true},
+		{etpCluster, false},
+	} {
+		if err := npw.updateServiceFlowCache(svc.service, true, svc.hasLocalHostNetworkEp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectCounts(config.GatewayModeLocal, 1, 0, 1)
+	expectCounts(config.GatewayModeShared, 0, 0, 0)
+}
+
+func TestETPLocalServicesWithoutLocalEndpointsMetric(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	ports := []kapi.ServicePort{{Port: 80, Protocol: kapi.ProtocolTCP, NodePort: 30080, TargetPort: intstr.FromInt(8080)}}
+	etpLocalHostEp := newService("etp-local-host-ep", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, true, false)
+	etpLocalEp := newService("etp-local-ep", "namespace1", "10.96.0.11", ports, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, true, false)
+	etpLocalNoEp := newService("etp-local-no-ep", "namespace1", "10.96.0.12", ports, kapi.ServiceTypeLoadBalancer,
+		nil, kapi.ServiceStatus{}, true, false)
+	etpClusterNoEp := newService("etp-cluster-no-ep", "namespace1", "10.96.0.13", ports, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, false, false)
+	npw := &nodePortWatcher{
+		serviceInfo: map[ktypes.NamespacedName]*serviceConfig{
+			{Namespace: "namespace1", Name: "etp-local-host-ep"}: {service: etpLocalHostEp, hasLocalHostNetworkEp: true},
+			{Namespace: "namespace1", Name: "etp-local-ep"}:      {service: etpLocalEp, localEndpoints: sets.New("10.244.0.5")},
+			{Namespace: "namespace1", Name: "etp-local-no-ep"}:   {service: etpLocalNoEp, localEndpoints: sets.New[string]()},
+			{Namespace: "namespace1", Name: "etp-cluster-no-ep"}: {service: etpClusterNoEp, localEndpoints: sets.New[string]()},
+		},
+	}
+	metrics.SetETPLocalServicesWithoutLocalEndpointsFunc(npw.countETPLocalServicesWithoutLocalEndpoints)
+	defer metrics.SetETPLocalServicesWithoutLocalEndpointsFunc(nil)
+
+	getGauge := func() float64 {
+		t.Helper()
+		m := &dto.Metric{}
+		if err := metrics.MetricETPLocalServicesWithoutLocalEndpoints.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetGauge().GetValue()
+	}
+
+	// only the ETP=local service without any local endpoint is counted
+	if got := getGauge(); got != 1 {
+		t.Errorf("expected 1 ETP=local service without local endpoints, got %v", got)
+	}
+
+	// the gauge follows the endpoint changes
+	npw.updateServiceInfo(ktypes.NamespacedName{Namespace: "namespace1", Name: "etp-local-no-ep"}, nil, nil, sets.New("10.244.0.6"))
+	npw.updateServiceInfo(ktypes.NamespacedName{Namespace: "namespace1", Name: "etp-local-ep"}, nil, nil, sets.New[string]())
+	hasLocalHostNetworkEp := false
+	npw.updateServiceInfo(ktypes.NamespacedName{Namespace: "namespace1", Name: "etp-local-host-ep"}, nil, &hasLocalHostNetworkEp, nil)
+	if got := getGauge(); got != 2 {
+		t.Errorf("expected 2 ETP=local services without local endpoints, got %v", got)
+	}
+}
+
+func TestServiceIPTRulesMetric(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+
+	ports := []kapi.ServicePort{{Name: "http", Port: 80, Protocol: kapi.ProtocolTCP, NodePort: 30080, TargetPort: intstr.FromInt(8080)}}
+	etpLocal := newService("etp-local", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{}, true, false)
+	etpLocalHostEp := newService("etp-local-host-ep", "namespace1", "10.96.0.11", ports, kapi.ServiceTypeNodePort,
+		[]string{"2.2.2.2"}, kapi.ServiceStatus{}, true, false)
+	clusterIP := newService("cluster-ip", "namespace1", "10.96.0.12", ports, kapi.ServiceTypeClusterIP,
+		nil, kapi.ServiceStatus{}, false, false)
+	npw := &nodePortWatcher{
+		serviceInfo: map[ktypes.NamespacedName]*serviceConfig{
+			{Namespace: "namespace1", Name: "etp-local"}:         {service: etpLocal, localEndpoints: sets.New("10.244.0.5")},
+			{Namespace: "namespace1", Name: "etp-local-host-ep"}: {service: etpLocalHostEp, hasLocalHostNetworkEp: true},
+			{Namespace: "namespace1", Name: "cluster-ip"}:        {service: clusterIP},
+		},
+	}
+	metrics.SetServiceIPTRuleCountsFunc(npw.countServiceIPTRules)
+	defer metrics.SetServiceIPTRuleCountsFunc(nil)
+
+	got := map[string]int{}
+	for _, count := range npw.countServiceIPTRules() {
+		got[count.Service] = count.Rules
+	}
+	want := map[string]int{
+		// the NodePort and externalIP DNAT rules, the externalIP DNAT rule towards the
+		// masquerade IP and the management port SNAT exemption of the ETP=local NodePort
+		"namespace1/etp-local": 4,
+		// the NodePort and externalIP DNAT rules, the traffic of the host networked
+		// endpoint is DNAT-ed by the gateway bridge flows
+		"namespace1/etp-local-host-ep": 2,
+		"namespace1/cluster-ip":        0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("countServiceIPTRules() = %v, want %v", got, want)
+	}
+
+	m := &dto.Metric{}
+	if err := metrics.MetricServiceIPTRules.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.GetGauge().GetValue() != 6 {
+		t.Errorf("expected 6 service iptables rules, got %v", m.GetGauge().GetValue())
+	}
+
+	// no rules are programmed in DPU mode
+	npw.dpuMode = true
+	if counts := npw.countServiceIPTRules(); len(counts) != 0 {
+		t.Errorf("expected no service iptables rules in DPU mode, got %v", counts)
+	}
+}
+
+func TestETPLocalHostFlowsPerServiceCookies(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.PerServiceETPCookies = true
+	config.IPv4Mode = true
+	// the ARP bypass flow of the external IP lists the bridge ports
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+		Cmd: "ovs-ofctl show ",
+	})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+
+	ports := []kapi.ServicePort{{Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(443)}}
+	service1 := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{}, true, false)
+	service2 := newService("service2", "namespace1", "10.96.0.11", ports, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, true, false)
+
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		ofm:         &openflowManager{flowCache: map[string][]string{}},
+	}
+	for _, service := range []*kapi.Service{service1, service2} {
+		if err := npw.updateServiceFlowCache(service, true, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, service := range []*kapi.Service{service1, service2} {
+		cookie, err := svcToCookie(service.Namespace, service.Name, "tcp", 31111)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedFlows := []string{
+			fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=ct(commit,zone=64003,nat(dst=192.168.18.15:443),table=6)", cookie),
+			fmt.Sprintf("cookie=%s, priority=110, table=6, tcp, tp_dst=443, actions=output:LOCAL", cookie),
+			fmt.Sprintf("cookie=%s, priority=110, in_port=LOCAL, tcp, tp_src=443, actions=ct(zone=64003 nat,table=7)", cookie),
+			fmt.Sprintf("cookie=%s, priority=110, table=7, tcp, tp_src=31111, actions=output:eth0", cookie),
+		}
+		flows := npw.ofm.flowCache[serviceFlowCacheKey("NodePort", service.Namespace, service.Name, "tcp", "31111")]
+		if !reflect.DeepEqual(flows, expectedFlows) {
+			t.Errorf("expected NodePort flows of service %s:\n%s\ngot:\n%s", service.Name,
+				strings.Join(expectedFlows, "\n"), strings.Join(flows, "\n"))
+		}
+	}
+
+	cookie, err := svcToCookie(service1.Namespace, service1.Name, "1.1.1.1", 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flows := sets.New[string](npw.ofm.flowCache[serviceFlowCacheKey("External", service1.Namespace, service1.Name, "1.1.1.1", "tcp", "8080")]...)
+	for _, flow := range []string{
+		fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, tcp, nw_dst=1.1.1.1, tp_dst=8080, actions=ct(commit,zone=64003,nat(dst=192.168.18.15:443),table=6)", cookie),
+		fmt.Sprintf("cookie=%s, priority=110, table=6, tcp, tp_dst=443, actions=output:LOCAL", cookie),
+		// the reply is unDNAT-ed without commit, like the one of the NodePort
+		fmt.Sprintf("cookie=%s, priority=110, in_port=LOCAL, tcp, tp_src=443, actions=ct(zone=64003 nat,table=7)", cookie),
+		fmt.Sprintf("cookie=%s, priority=110, table=7, tcp, tp_src=8080, actions=output:eth0", cookie),
+	} {
+		if !flows.Has(flow) {
+			t.Errorf("expected external IP flow %q, got:\n%s", flow, strings.Join(sets.List(flows), "\n"))
+		}
+	}
+}
+
+func TestCheckServiceCIDRFlowBudget(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	klog.LogToStderr(false)
+	klog.SetOutput(&out)
+	t.Cleanup(func() {
+		klog.SetOutput(nil)
+		klog.LogToStderr(true)
+	})
+
+	serviceCIDRs := func(n int) []*net.IPNet {
+		cidrs := make([]*net.IPNet, 0, n)
+		for i := 0; i < n; i++ {
+			cidrs = append(cidrs, ovntest.MustParseIPNet(fmt.Sprintf("172.%d.%d.0/24", 16+i/256, i%256)))
+		}
+		return cidrs
+	}
+
+	tests := []struct {
+		desc         string
+		serviceCIDRs int
+		budget       int
+		wantExceeded bool
+	}{
+		{
+			desc:         "within the budget",
+			serviceCIDRs: 2,
+			budget:       64,
+		},
+		{
+			desc:         "many service CIDRs exceeding the budget",
+			serviceCIDRs: 40,
+			budget:       64,
+			wantExceeded: true,
+		},
+		{
+			desc:         "many service CIDRs with the check disabled",
+			serviceCIDRs: 40,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			out.Reset()
+			config.Kubernetes.ServiceCIDRs = serviceCIDRs(tt.serviceCIDRs)
+			config.Gateway.ServiceCIDRFlowBudget = tt.budget
+
+			if exceeded := checkServiceCIDRFlowBudget(); exceeded != tt.wantExceeded {
+				t.Errorf("checkServiceCIDRFlowBudget() = %v, want %v", exceeded, tt.wantExceeded)
+			}
+			klog.Flush()
+			warned := strings.Contains(out.String(), "exceeding the budget")
+			if warned != tt.wantExceeded {
+				t.Errorf("expected a budget warning %v, got log %q", tt.wantExceeded, out.String())
+			}
+
+			m := &dto.Metric{}
+			if err := metrics.MetricServiceCIDRFlows.Write(m); err != nil {
+				t.Fatal(err)
+			}
+			if want := float64(tt.serviceCIDRs * defaultBridgeFlowsPerServiceCIDR); m.GetGauge().GetValue() != want {
+				t.Errorf("expected %v service CIDR flows, got %v", want, m.GetGauge().GetValue())
+			}
+		})
+	}
+}
+
+func TestDefaultFlowsFeatureCookies(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeLocal
+	config.IPv4Mode = true
+	config.OVNKubernetesFeature.EnableEgressIP = true
+	config.Default.ClusterSubnets = []config.CIDRNetworkEntry{{CIDR: ovntest.MustParseIPNet("10.128.0.0/14"), HostSubnetLength: 23}}
+	config.Kubernetes.ServiceCIDRs = []*net.IPNet{ovntest.MustParseIPNet("172.16.1.0/24")}
+
+	bridge := &bridgeConfiguration{
+		bridgeName:  "breth0",
+		ips:         []*net.IPNet{ovntest.MustParseIPNet("192.168.1.10/24")},
+		macAddress:  ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+		ofPortPatch: "patch-breth0_ov",
+		ofPortPhys:  "eth0",
+		ofPortHost:  "LOCAL",
+	}
+	flows, err := flowsForDefaultBridge(bridge, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commonFlows, err := commonFlows([]*net.IPNet{ovntest.MustParseIPNet("10.128.0.0/23")}, bridge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flows = append(flows, commonFlows...)
+
+	tests := []struct {
+		desc   string
+		match  string
+		cookie string
+		count  int
+	}{
+		{
+			desc:   "geneve bypass",
+			match:  fmt.Sprintf("udp_dst=%d", config.Default.EncapPort),
+			cookie: "0xdeff10501",
+			count:  3,
+		},
+		{
+			desc:   "hairpin",
+			match:  "ip_src=192.168.1.10,",
+			cookie: "0xdeff10502",
+			count:  1,
+		},
+		{
+			desc:   "hairpin SNAT",
+			match:  ", table=4,",
+			cookie: "0xdeff10502",
+			count:  1,
+		},
+		{
+			desc:   "hairpin reply",
+			match:  "table=5",
+			cookie: "0xdeff10502",
+			count:  2,
+		},
+		{
+			desc:   "egress IP SNAT",
+			match:  "pkt_mark=" + ovnKubeNodeSNATMark,
+			cookie: "0xdeff10503",
+			count:  1,
+		},
+		{
+			desc:   "egress IP drop",
+			match:  "ip_src=10.128.0.0/14, actions=drop",
+			cookie: "0xdeff10503",
+			count:  1,
+		},
+		{
+			desc:   "BFD",
+			match:  "tp_dst=3784",
+			cookie: "0xdeff10504",
+			count:  2,
+		},
+		{
+			desc:   "conntrack",
+			match:  "ct_state=+trk",
+			cookie: "0xdeff10505",
+			count:  4,
+		},
+		{
+			desc:   "conntrack lookup",
+			match:  "priority=50,",
+			cookie: "0xdeff10505",
+			count:  1,
+		},
+		{
+			desc:   "service CIDR",
+			match:  "172.16.1.0/24",
+			cookie: defaultOpenFlowCookie,
+			count:  3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var matched int
+			for _, flow := range flows {
+				if !strings.Contains(flow, tt.match) {
+					continue
+				}
+				matched++
+				if !strings.HasPrefix(flow, "cookie="+tt.cookie+",") {
+					t.Errorf("expected flow %q to have cookie %s", flow, tt.cookie)
+				}
+			}
+			if matched != tt.count {
+				t.Errorf("expected %d flows matching %q, got %d", tt.count, tt.match, matched)
+			}
+		})
+	}
+}
+
+func TestCommonFlowsBFDPorts(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeLocal
+	config.Gateway.BFDPorts = "4784,4785"
+	config.IPv4Mode = true
+	config.IPv6Mode = true
+	config.Kubernetes.ServiceCIDRs = []*net.IPNet{
+		ovntest.MustParseIPNet("172.16.1.0/24"),
+		ovntest.MustParseIPNet("fd00:10:96::/112"),
+	}
+
+	bridge := &bridgeConfiguration{
+		bridgeName: "breth0",
+		ips: []*net.IPNet{
+			ovntest.MustParseIPNet("192.168.1.10/24"),
+			ovntest.MustParseIPNet("fc00:f853:ccd:e793::3/64"),
+		},
+		macAddress:  ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+		ofPortPatch: "patch-breth0_ov",
+		ofPortPhys:  "eth0",
+		ofPortHost:  "LOCAL",
+	}
+	flows, err := flowsForDefaultBridge(bridge, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commonFlows, err := commonFlows([]*net.IPNet{
+		ovntest.MustParseIPNet("10.128.0.0/23"),
+		ovntest.MustParseIPNet("fd00:10:244:1::/64"),
+	}, bridge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flows = append(flows, commonFlows...)
+	renderedFlows := sets.NewString(flows...)
+
+	for _, port := range []int{4784, 4785} {
+		for _, proto := range []string{"udp", "udp6"} {
+			expected := []string{
+				fmt.Sprintf("cookie=%s, priority=650, table=0, in_port=patch-breth0_ov, %s, tp_dst=%d, actions=output:eth0",
+					bfdOpenFlowCookie, proto, port),
+				fmt.Sprintf("cookie=%s, priority=13, table=1, in_port=eth0, %s, tp_dst=%d, actions=output:patch-breth0_ov,output:LOCAL",
+					bfdOpenFlowCookie, proto, port),
+			}
+			for _, flow := range expected {
+				if !renderedFlows.Has(flow) {
+					t.Errorf("expected BFD flow %q to be rendered", flow)
+				}
+			}
+		}
+	}
+	for _, flow := range flows {
+		if strings.Contains(flow, "tp_dst=3784") {
+			t.Errorf("unexpected BFD flow for the default port: %q", flow)
+		}
+	}
+}
+
+func TestDefaultFlowsServiceCIDRExemptions(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	// a node-local DNS cache listening on an IP of the service CIDR
+	config.Gateway.ServiceCIDRExemptions = "172.16.1.10,fd00:10:96::a"
+	config.IPv4Mode = true
+	config.Kubernetes.ServiceCIDRs = []*net.IPNet{ovntest.MustParseIPNet("172.16.1.0/24")}
+
+	bridge := &bridgeConfiguration{
+		bridgeName:  "breth0",
+		ips:         []*net.IPNet{ovntest.MustParseIPNet("192.168.1.10/24")},
+		macAddress:  ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+		ofPortPatch: "patch-breth0_ov",
+		ofPortPhys:  "eth0",
+		ofPortHost:  "LOCAL",
+	}
+	flows, err := flowsForDefaultBridge(bridge, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderedFlows := sets.NewString(flows...)
+
+	expected := []string{
+		// bypassing the SNAT of the Host -> Service traffic at priority 500
+		"cookie=0xdeff105, priority=550, in_port=LOCAL, ip, ip_dst=172.16.1.10/32, actions=NORMAL",
+		// and the drop of the OVN -> Service traffic at priority 105
+		"cookie=0xdeff105, priority=106, in_port=patch-breth0_ov, ip, ip_dst=172.16.1.10/32, actions=NORMAL",
+		"cookie=0xdeff105, priority=500, in_port=LOCAL, ip, ip_dst=172.16.1.0/24,actions=ct(commit,zone=64001,nat(src=169.254.169.2),table=2)",
+		"cookie=0xdeff105, priority=105, in_port=patch-breth0_ov, ip, ip_dst=172.16.1.0/24,actions=drop",
+	}
+	for _, flow := range expected {
+		if !renderedFlows.Has(flow) {
+			t.Errorf("expected flow %q to be rendered, got:\n%s", flow, strings.Join(flows, "\n"))
+		}
+	}
+	// the IPv6 exemption is skipped on an IPv4 cluster
+	for _, flow := range flows {
+		if strings.Contains(flow, "fd00:10:96::a") {
+			t.Errorf("unexpected flow for the IPv6 exemption: %q", flow)
+		}
+	}
+}
+
+func TestDefaultFlowsHairpinSkipsLinkLocalIPs(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = false
+	config.IPv6Mode = true
+
+	bridge := &bridgeConfiguration{
+		bridgeName:  "breth0",
+		ips:         []*net.IPNet{ovntest.MustParseIPNet("fd00:10:1::10/64")},
+		macAddress:  ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+		ofPortPatch: "patch-breth0_ov",
+		ofPortPhys:  "eth0",
+		ofPortHost:  "LOCAL",
+	}
+	flows, err := flowsForDefaultBridge(bridge, []net.IP{net.ParseIP("fd00:10:1::20"), net.ParseIP("fe80::1234")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "cookie=0xdeff10502, priority=500, in_port=patch-breth0_ov, ipv6, ipv6_dst=fd00:10:1::20, ipv6_src=fd00:10:1::10," +
+		"actions=ct(commit,zone=64001,table=4)"
+	if !sets.NewString(flows...).Has(expected) {
+		t.Errorf("expected hairpin flow %q to be rendered, got:\n%s", expected, strings.Join(flows, "\n"))
+	}
+	for _, flow := range flows {
+		if strings.Contains(flow, "fe80::1234") {
+			t.Errorf("unexpected hairpin flow for the link local IP: %q", flow)
+		}
+	}
+}
+
+func TestCommonFlowsDisableSNATMultipleGWs(t *testing.T) {
+	bridge := &bridgeConfiguration{
+		bridgeName:  "breth0",
+		ips:         []*net.IPNet{ovntest.MustParseIPNet("192.168.1.10/24")},
+		macAddress:  ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+		ofPortPatch: "patch-breth0_ov",
+		ofPortPhys:  "eth0",
+		ofPortHost:  "LOCAL",
+	}
+	subnets := []*net.IPNet{ovntest.MustParseIPNet("10.128.0.0/23")}
+	podSubnetFlow := fmt.Sprintf("cookie=%s, priority=15, table=1, ip, ip_dst=10.128.0.0/14, actions=output:patch-breth0_ov",
+		defaultOpenFlowCookie)
+
+	for _, disableSNATMultipleGWs := range []bool{false, true} {
+		t.Run(fmt.Sprintf("disable-snat-multiple-gws=%t", disableSNATMultipleGWs), func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.Gateway.Mode = config.GatewayModeShared
+			config.Gateway.DisableSNATMultipleGWs = disableSNATMultipleGWs
+			config.IPv4Mode = true
+			config.Default.ClusterSubnets = []config.CIDRNetworkEntry{{CIDR: ovntest.MustParseIPNet("10.128.0.0/14"), HostSubnetLength: 23}}
+			config.Kubernetes.ServiceCIDRs = []*net.IPNet{ovntest.MustParseIPNet("172.16.1.0/24")}
+
+			flows, err := commonFlows(subnets, bridge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sets.NewString(flows...).Has(podSubnetFlow); got != disableSNATMultipleGWs {
+				t.Errorf("expected the pod subnet flow %q to be rendered: %t, got: %t", podSubnetFlow, disableSNATMultipleGWs, got)
+			}
+
+			// the setting is immutable once the gateway started
+			ofm, err := newGatewayOpenFlowManager(bridge, nil, subnets, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config.Gateway.DisableSNATMultipleGWs = !disableSNATMultipleGWs
+			err = ofm.updateBridgeFlowCache(subnets, nil)
+			if err == nil || !strings.Contains(err.Error(), "disable-snat-multiple-gws changed") {
+				t.Errorf("expected an error regenerating the flows after disable-snat-multiple-gws changed, got: %v", err)
+			}
+			if got := sets.NewString(ofm.flowCache["DEFAULT"]...).Has(podSubnetFlow); got != disableSNATMultipleGWs {
+				t.Errorf("expected the pod subnet flow %q to be kept as at startup: %t, got: %t", podSubnetFlow, disableSNATMultipleGWs, got)
+			}
+		})
+	}
+}
+
+func TestUpdateExBridgeFlowCache(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+	config.Default.ClusterSubnets = []config.CIDRNetworkEntry{{CIDR: ovntest.MustParseIPNet("10.128.0.0/14"), HostSubnetLength: 23}}
+	config.Kubernetes.ServiceCIDRs = []*net.IPNet{ovntest.MustParseIPNet("172.16.1.0/24")}
+
+	bridge := &bridgeConfiguration{
+		bridgeName:  "breth0",
+		ips:         []*net.IPNet{ovntest.MustParseIPNet("192.168.1.10/24")},
+		macAddress:  ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+		ofPortPatch: "patch-breth0_ov",
+		ofPortPhys:  "eth0",
+		ofPortHost:  "LOCAL",
+	}
+	exGWBridge := &bridgeConfiguration{
+		bridgeName:  "breth1",
+		ips:         []*net.IPNet{ovntest.MustParseIPNet("192.168.2.10/24")},
+		macAddress:  ovntest.MustParseMAC("0a:58:0a:01:02:01"),
+		ofPortPatch: "patch-breth1_ov",
+		ofPortPhys:  "eth1",
+		ofPortHost:  "LOCAL",
+	}
+	subnets := []*net.IPNet{ovntest.MustParseIPNet("10.128.0.0/23")}
+	ofm, err := newGatewayOpenFlowManager(bridge, exGWBridge, subnets, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultFlows := map[string][]string{}
+	for key, flows := range ofm.flowCache {
+		defaultFlows[key] = flows
+	}
+	exGWFlows := ofm.exGWFlowCache["DEFAULT"]
+	ofm.updateExBridgeFlowCacheEntry("STALE", []string{"stale"})
+	serviceKey := exGWBridgeFlowCacheKey("breth1", serviceFlowCacheKey("External", "namespace1", "service1", "1.1.1.1", "tcp", "8080"))
+	ofm.updateExBridgeFlowCacheEntry(serviceKey, []string{"service"})
+
+	// only the flows of the external gateway bridge are regenerated
+	exGWBridge.ofPortPhys = "eth2"
+	if err := ofm.updateExBridgeFlowCache(subnets); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ofm.flowCache, defaultFlows) {
+		t.Errorf("expected the default bridge flows to be untouched, got: %v", ofm.flowCache)
+	}
+	if _, ok := ofm.exGWFlowCache["STALE"]; ok {
+		t.Error("expected the stale external gateway bridge flows to be drained")
+	}
+	if _, ok := ofm.exGWFlowCache[serviceKey]; !ok {
+		t.Error("expected the service flows of the external gateway bridge to be kept")
+	}
+	if reflect.DeepEqual(ofm.exGWFlowCache["DEFAULT"], exGWFlows) {
+		t.Error("expected the external gateway bridge flows to be regenerated")
+	}
+
+	// and only them are synced
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl -O OpenFlow13 --bundle replace-flows breth1 -"})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+	ofm.syncExBridgeFlows()
+	if !fexec.CalledMatchesExpected() {
+		t.Error(fexec.ErrorDesc())
+	}
+	if _, ok := ofm.getLastSyncTimes()["breth0"]; ok {
+		t.Error("expected the default bridge flows not to be synced")
+	}
+}
+
+func TestBridgeFallbackFlows(t *testing.T) {
+	tests := []struct {
+		desc                   string
+		fallbackAction         string
+		egressGWFallbackAction string
+		expectedFlow           string
+		expectedExGWFlow       string
+	}{
+		{
+			desc:             "both bridges switch the unmatched traffic by default",
+			expectedFlow:     "table=0,priority=0,actions=NORMAL\n",
+			expectedExGWFlow: "table=0,priority=0,actions=NORMAL\n",
+		},
+		{
+			desc:             "the default bridge drops the unmatched traffic",
+			fallbackAction:   config.GatewayFallbackActionDrop,
+			expectedFlow:     "table=0,priority=0,actions=drop\n",
+			expectedExGWFlow: "table=0,priority=0,actions=NORMAL\n",
+		},
+		{
+			desc:                   "the external gateway bridge drops the unmatched traffic",
+			egressGWFallbackAction: config.GatewayFallbackActionDrop,
+			expectedFlow:           "table=0,priority=0,actions=NORMAL\n",
+			expectedExGWFlow:       "table=0,priority=0,actions=drop\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.Gateway.Mode = config.GatewayModeShared
+			config.IPv4Mode = true
+			config.Default.ClusterSubnets = []config.CIDRNetworkEntry{{CIDR: ovntest.MustParseIPNet("10.128.0.0/14"), HostSubnetLength: 23}}
+			config.Kubernetes.ServiceCIDRs = []*net.IPNet{ovntest.MustParseIPNet("172.16.1.0/24")}
+			if tt.fallbackAction != "" {
+				config.Gateway.FallbackAction = tt.fallbackAction
+			}
+			if tt.egressGWFallbackAction != "" {
+				config.Gateway.EgressGWFallbackAction = tt.egressGWFallbackAction
+			}
+
+			bridge := &bridgeConfiguration{
+				bridgeName:  "breth0",
+				ips:         []*net.IPNet{ovntest.MustParseIPNet("192.168.1.10/24")},
+				macAddress:  ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+				ofPortPatch: "patch-breth0_ov",
+				ofPortPhys:  "eth0",
+				ofPortHost:  "LOCAL",
+			}
+			exGWBridge := &bridgeConfiguration{
+				bridgeName:  "breth1",
+				ips:         []*net.IPNet{ovntest.MustParseIPNet("192.168.2.10/24")},
+				macAddress:  ovntest.MustParseMAC("0a:58:0a:01:02:01"),
+				ofPortPatch: "patch-breth1_ov",
+				ofPortPhys:  "eth1",
+				ofPortHost:  "LOCAL",
+			}
+			subnets := []*net.IPNet{ovntest.MustParseIPNet("10.128.0.0/23")}
+			ofm, err := newGatewayOpenFlowManager(bridge, exGWBridge, subnets, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ofm.flowCache["NORMAL"], []string{tt.expectedFlow}) {
+				t.Errorf("expected the default bridge fallback flow %q, got %q", tt.expectedFlow, ofm.flowCache["NORMAL"])
+			}
+			if !reflect.DeepEqual(ofm.exGWFlowCache["NORMAL"], []string{tt.expectedExGWFlow}) {
+				t.Errorf("expected the external gateway bridge fallback flow %q, got %q", tt.expectedExGWFlow, ofm.exGWFlowCache["NORMAL"])
+			}
+		})
+	}
+}
+
+func TestGatewayReconcileFlows(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+	config.Kubernetes.ServiceCIDRs = []*net.IPNet{ovntest.MustParseIPNet("172.16.1.0/24")}
+
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmdsNoOutputNoError([]string{
+		"ovs-ofctl -O OpenFlow13 --bundle replace-flows breth0 -",
+	})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+
+	bridge := &bridgeConfiguration{
+		bridgeName:  "breth0",
+		ips:         []*net.IPNet{ovntest.MustParseIPNet("192.168.18.15/24")},
+		macAddress:  ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+		ofPortPatch: "patch-breth0_ov",
+		ofPortPhys:  "eth0",
+		ofPortHost:  "LOCAL",
+	}
+	// flows modified out of band are replaced
+	ofm := &openflowManager{
+		defaultBridge: bridge,
+		flowCache: map[string][]string{
+			"DEFAULT":                                {"cookie=0xdeff105, priority=1000, actions=drop"},
+			"NodePort_namespace1_service1_tcp_31111": {"cookie=0x0, priority=1000, actions=drop"},
+		},
+		flowChan: make(chan struct{}, 1),
+	}
+	service := newService("service1", "namespace1", "10.96.0.10",
+		[]kapi.ServicePort{{Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111}}, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, false, false)
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		ofm:         ofm,
+		serviceInfo: map[ktypes.NamespacedName]*serviceConfig{
+			{Namespace: "namespace1", Name: "service1"}: {service: service},
+		},
+	}
+	gw := &gateway{
+		openflowManager: ofm,
+		nodeIPManager:   &addressManager{addresses: sets.New("192.168.18.15")},
+		nodePortWatcher: npw,
+		subnets:         []*net.IPNet{ovntest.MustParseIPNet("10.128.0.0/24")},
+	}
+
+	if err := gw.ReconcileFlows(); err != nil {
+		t.Fatalf("ReconcileFlows() unexpected error: %v", err)
+	}
+
+	defaultFlows, err := flowsForDefaultBridge(bridge, []net.IP{net.ParseIP("192.168.18.15")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ofm.flowCache["DEFAULT"]; len(got) <= len(defaultFlows) || !reflect.DeepEqual(got[:len(defaultFlows)], defaultFlows) {
+		t.Errorf("expected the default flows to be regenerated, got:\n%s", strings.Join(got, "\n"))
+	}
+	if _, ok := ofm.flowCache["NORMAL"]; !ok {
+		t.Errorf("expected the NORMAL flow to be regenerated")
+	}
+	expectedServiceFlows := []string{
+		"cookie=0x453ae29bcbbc08bd, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=output:patch-breth0_ov",
+		"cookie=0x453ae29bcbbc08bd, priority=110, in_port=patch-breth0_ov, tcp, tp_src=31111, actions=output:eth0",
+	}
+	if got := ofm.flowCache["NodePort_namespace1_service1_tcp_31111"]; !reflect.DeepEqual(got, expectedServiceFlows) {
+		t.Errorf("expected the service flows:\n%s\ngot:\n%s", strings.Join(expectedServiceFlows, "\n"), strings.Join(got, "\n"))
+	}
+	if !fexec.CalledMatchesExpected() {
+		t.Errorf("expected the flows to be applied: %s", fexec.ErrorDesc())
+	}
+}
+
+func TestGatewayOnNodeIPsChanged(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+	config.Kubernetes.ServiceCIDRs = []*net.IPNet{ovntest.MustParseIPNet("172.16.1.0/24")}
+
+	fexec := ovntest.NewFakeExec()
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+	link := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "breth0", Index: 5}}
+	netlinkMock := &mocks.NetLinkOps{}
+	netlinkMock.On("LinkByName", "breth0").Return(link, nil)
+	netlinkMock.On("LinkSetUp", link).Return(nil)
+	// the neighbor entry of the dummy next hop is up to date, the one of the
+	// OVN masquerade IP was flushed along with the node address
+	dummyNextHopIP := net.ParseIP(types.V4DummyNextHopMasqueradeIP)
+	netlinkMock.On("NeighList", 5, netlink.FAMILY_V4).Return([]netlink.Neigh{{
+		LinkIndex:    5,
+		IP:           dummyNextHopIP,
+		HardwareAddr: util.IPAddrToHWAddr(dummyNextHopIP),
+		State:        netlink.NUD_PERMANENT,
+	}}, nil)
+	var added []string
+	netlinkMock.On("NeighDel", mock.Anything).Return(nil)
+	netlinkMock.On("NeighAdd", mock.Anything).Run(func(args mock.Arguments) {
+		added = append(added, args.Get(0).(*netlink.Neigh).IP.String())
+	}).Return(nil)
+	origNetlinkInst := util.GetNetLinkOps()
+	util.SetNetLinkOpMockInst(netlinkMock)
+	t.Cleanup(func() { util.SetNetLinkOpMockInst(origNetlinkInst) })
+
+	bridge := &bridgeConfiguration{
+		bridgeName:  "breth0",
+		ips:         []*net.IPNet{ovntest.MustParseIPNet("192.168.18.16/24")},
+		macAddress:  ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+		ofPortPatch: "patch-breth0_ov",
+		ofPortPhys:  "eth0",
+		ofPortHost:  "LOCAL",
+	}
+	ofm := &openflowManager{
+		defaultBridge: bridge,
+		flowCache:     map[string][]string{},
+		flowChan:      make(chan struct{}, 1),
+	}
+	gw := &gateway{
+		openflowManager: ofm,
+		nodeIPManager:   &addressManager{addresses: sets.New("192.168.18.16"), gatewayBridge: bridge},
+		subnets:         []*net.IPNet{ovntest.MustParseIPNet("10.128.0.0/24")},
+	}
+
+	gw.onNodeIPsChanged()
+
+	// the flows are regenerated for the new address and a flow sync requested
+	if _, ok := ofm.flowCache["DEFAULT"]; !ok {
+		t.Errorf("expected the default flows to be regenerated")
+	}
+	select {
+	case <-ofm.flowChan:
+	default:
+		t.Errorf("expected a flow sync to be requested")
+	}
+	// and the neighbor entries of the masquerade IPs reconciled
+	if !reflect.DeepEqual(added, []string{types.V4OVNMasqueradeIP}) {
+		t.Errorf("expected the neighbor entry of %s to be added back, got the ones of %v", types.V4OVNMasqueradeIP, added)
+	}
+	netlinkMock.AssertCalled(t, "NeighList", 5, netlink.FAMILY_V4)
+}
+
+func TestGetMasqueradeRouteSourceIPs(t *testing.T) {
+	node := &kapi.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: kapi.NodeStatus{
+			Addresses: []kapi.NodeAddress{
+				{Type: kapi.NodeInternalIP, Address: "192.168.18.15"},
+				{Type: kapi.NodeInternalIP, Address: "fd00::15"},
+			},
+		},
+	}
+	ifAddrs := ovntest.MustParseIPNets("192.168.18.15/24", "192.168.18.100/24", "fd00::15/64", "fd00::100/64")
+
+	tests := []struct {
+		desc         string
+		sourceIPs    string
+		expectedIPv4 net.IP
+		expectedIPv6 net.IP
+		expectErr    bool
+	}{
+		{
+			desc:         "uses the node status IPs by default",
+			expectedIPv4: net.ParseIP("192.168.18.15"),
+			expectedIPv6: net.ParseIP("fd00::15"),
+		},
+		{
+			desc:         "uses the configured IPv4 source IP",
+			sourceIPs:    "192.168.18.100",
+			expectedIPv4: net.ParseIP("192.168.18.100"),
+			expectedIPv6: net.ParseIP("fd00::15"),
+		},
+		{
+			desc:         "uses the configured dual stack source IPs",
+			sourceIPs:    "192.168.18.100,fd00::100",
+			expectedIPv4: net.ParseIP("192.168.18.100"),
+			expectedIPv6: net.ParseIP("fd00::100"),
+		},
+		{
+			desc:      "fails when the configured source IP is not on the interface",
+			sourceIPs: "192.168.18.200",
+			expectErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			config.PrepareTestConfig()
+			config.IPv4Mode = true
+			config.IPv6Mode = true
+			config.Gateway.MasqueradeRouteSourceIPs = tc.sourceIPs
+
+			ipv4, ipv6, err := getMasqueradeRouteSourceIPs(node, ifAddrs)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got source IPs %s and %s", ipv4, ipv6)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !ipv4.Equal(tc.expectedIPv4) || !ipv6.Equal(tc.expectedIPv6) {
+				t.Errorf("expected source IPs %s and %s, got %s and %s", tc.expectedIPv4, tc.expectedIPv6, ipv4, ipv6)
+			}
+		})
+	}
+}
+
+func TestGetLocalEndpointAddressesLogging(t *testing.T) {
+	var out bytes.Buffer
+	klog.LogToStderr(false)
+	klog.SetOutput(&out)
+	var verbosity klog.Level
+	t.Cleanup(func() {
+		_ = verbosity.Set("0")
+		klog.SetOutput(nil)
+		klog.LogToStderr(true)
+	})
+
+	localNode, remoteNode := "node1", "node2"
+	service := &kapi.Service{ObjectMeta: metav1.ObjectMeta{Name: "service1", Namespace: "namespace1"}}
+	epSlice := &discovery.EndpointSlice{
+		ObjectMeta:  metav1.ObjectMeta{Name: "service1-ab23", Namespace: "namespace1"},
+		AddressType: discovery.AddressTypeIPv4,
+		Endpoints: []discovery.Endpoint{
+			{
+				Addresses:  []string{"10.244.0.3"},
+				NodeName:   &localNode,
+				Conditions: discovery.EndpointConditions{Ready: pointer.Bool(true)},
+			},
+			{
+				Addresses:  []string{"10.244.1.3"},
+				NodeName:   &remoteNode,
+				Conditions: discovery.EndpointConditions{Ready: pointer.Bool(true)},
+			},
+			{
+				Addresses: []string{"10.244.0.4"},
+				NodeName:  &localNode,
+				Conditions: discovery.EndpointConditions{
+					Ready: pointer.Bool(false), Serving: pointer.Bool(true), Terminating: pointer.Bool(true),
+				},
+			},
+			{
+				Addresses: []string{"10.244.0.5"},
+				NodeName:  &localNode,
+				Conditions: discovery.EndpointConditions{
+					Ready: pointer.Bool(false), Serving: pointer.Bool(false), Terminating: pointer.Bool(true),
+				},
+			},
+		},
+	}
+	npw := &nodePortWatcher{nodeIPManager: &addressManager{nodeName: localNode}}
+	expected := sets.New("10.244.0.3", "10.244.0.4")
+
+	// nothing is logged at the default verbosity
+	if got := npw.GetLocalEndpointAddresses([]*discovery.EndpointSlice{epSlice}, service); !got.Equal(expected) {
+		t.Fatalf("expected local endpoints %v, got %v", sets.List(expected), sets.List(got))
+	}
+	klog.Flush()
+	if strings.Contains(out.String(), "Selected local endpoints") {
+		t.Errorf("expected no local endpoints log at the default verbosity, got: %s", out.String())
+	}
+
+	if err := verbosity.Set("5"); err != nil {
+		t.Fatal(err)
+	}
+	if got := npw.GetLocalEndpointAddresses([]*discovery.EndpointSlice{epSlice}, service); !got.Equal(expected) {
+		t.Fatalf("expected local endpoints %v, got %v", sets.List(expected), sets.List(got))
+	}
+	klog.Flush()
+	expectedLog := "Selected local endpoints [10.244.0.3 10.244.0.4] of service namespace1/service1 on node node1: " +
+		"10.244.0.3 (local: node name match), 10.244.1.3 (remote: on node node2), " +
+		"10.244.0.4 (local: node name match, terminating but serving), 10.244.0.5 (ignored: not serving)"
+	if !strings.Contains(out.String(), expectedLog) {
+		t.Errorf("expected log %q, got: %s", expectedLog, out.String())
+	}
+}
+
+func TestGetLocalEndpointAddressesLocality(t *testing.T) {
+	localNode, remoteNode := "node1", "node2"
+	zoneA, zoneB := "zone-a", "zone-b"
+	service := &kapi.Service{ObjectMeta: metav1.ObjectMeta{Name: "service1", Namespace: "namespace1"}}
+	epSlice := &discovery.EndpointSlice{
+		ObjectMeta:  metav1.ObjectMeta{Name: "service1-ab23", Namespace: "namespace1"},
+		AddressType: discovery.AddressTypeIPv4,
+		Endpoints: []discovery.Endpoint{
+			{
+				Addresses:  []string{"10.244.0.3"},
+				NodeName:   &localNode,
+				Zone:       &zoneB,
+				Conditions: discovery.EndpointConditions{Ready: pointer.Bool(true)},
+			},
+			{
+				Addresses:  []string{"10.244.1.3"},
+				NodeName:   &remoteNode,
+				Zone:       &zoneA,
+				Conditions: discovery.EndpointConditions{Ready: pointer.Bool(true)},
+			},
+		},
+	}
+	npw := &nodePortWatcher{nodeIPManager: &addressManager{nodeName: localNode}}
+	if got, expected := npw.GetLocalEndpointAddresses([]*discovery.EndpointSlice{epSlice}, service), sets.New("10.244.0.3"); !got.Equal(expected) {
+		t.Errorf("expected local endpoints %v by node name, got %v", sets.List(expected), sets.List(got))
+	}
+
+	var localityNode string
+	npw.endpointLocality = func(endpoint discovery.Endpoint, nodeName string) bool {
+		localityNode = nodeName
+		return endpoint.Zone != nil && *endpoint.Zone == zoneA
+	}
+	if got, expected := npw.GetLocalEndpointAddresses([]*discovery.EndpointSlice{epSlice}, service), sets.New("10.244.1.3"); !got.Equal(expected) {
+		t.Errorf("expected local endpoints %v by zone, got %v", sets.List(expected), sets.List(got))
+	}
+	if localityNode != localNode {
+		t.Errorf("expected the locality predicate to be called for node %s, got %q", localNode, localityNode)
+	}
+	expectedReasons := "10.244.0.3 (remote: on node node1), 10.244.1.3 (local: locality match)"
+	if reasons := describeLocalEndpointSelection([]*discovery.EndpointSlice{epSlice}, service, localNode, npw.endpointLocality); reasons != expectedReasons {
+		t.Errorf("expected local endpoint selection %q, got %q", expectedReasons, reasons)
+	}
+}
+
+func TestNodeIPExternalIPFlows(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+	var out bytes.Buffer
+	klog.LogToStderr(false)
+	klog.SetOutput(&out)
+	t.Cleanup(func() {
+		klog.SetOutput(nil)
+		klog.LogToStderr(true)
+	})
+	// the ARP bypass flows of the external IPs list the bridge ports
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show "})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show "})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show "})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+
+	ports := []kapi.ServicePort{{Port: 8080, Protocol: kapi.ProtocolTCP}}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeClusterIP,
+		[]string{"192.168.18.15", "1.1.1.1"}, kapi.ServiceStatus{}, false, false)
+	nodeIPKey := serviceFlowCacheKey("External", service.Namespace, service.Name, "192.168.18.15", "tcp", "8080")
+	otherKey := serviceFlowCacheKey("External", service.Namespace, service.Name, "1.1.1.1", "tcp", "8080")
+
+	npw := &nodePortWatcher{
+		ofportPhys:    "eth0",
+		ofportPatch:   "patch-breth0_ov",
+		gatewayIPv4:   "192.168.18.15",
+		nodeIPManager: &addressManager{addresses: sets.New("192.168.18.15")},
+		ofm:           &openflowManager{flowCache: map[string][]string{}},
+	}
+
+	// the flows of an externalIP that is a node IP are programmed with a warning by default
+	if err := npw.updateServiceFlowCache(service, true, false); err != nil {
+		t.Fatal(err)
+	}
+	klog.Flush()
+	if _, ok := npw.ofm.flowCache[nodeIPKey]; !ok {
+		t.Errorf("expected the flows of the externalIP that is a node IP")
+	}
+	if !strings.Contains(out.String(), "ExternalIP 192.168.18.15 of service namespace1/service1 is an IP of the node") {
+		t.Errorf("expected a warning for the externalIP that is a node IP, got: %s", out.String())
+	}
+	if strings.Contains(out.String(), "ExternalIP 1.1.1.1") {
+		t.Errorf("expected no warning for the externalIP that is not a node IP, got: %s", out.String())
+	}
+
+	// and skipped if configured
+	config.Gateway.SkipNodeIPExternalIPs = true
+	if err := npw.updateServiceFlowCache(service, true, false); err != nil {
+		t.Fatal(err)
+	}
+	klog.Flush()
+	if flows, ok := npw.ofm.flowCache[nodeIPKey]; ok {
+		t.Errorf("expected no flows for the externalIP that is a node IP, got:\n%s", strings.Join(flows, "\n"))
+	}
+	if _, ok := npw.ofm.flowCache[otherKey]; !ok {
+		t.Errorf("expected the flows of the externalIP that is not a node IP")
+	}
+	if !strings.Contains(out.String(), "Skipping the flows of externalIP 192.168.18.15 of service namespace1/service1") {
+		t.Errorf("expected the flows of the externalIP that is a node IP to be skipped, got: %s", out.String())
+	}
+}
+
+func TestExternalServiceFlowsVLAN(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.ServiceVLANID = 100
+	config.IPv4Mode = true
+	// the ARP bypass flows of the external IPs list the bridge ports
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show "})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show "})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+
+	ports := []kapi.ServicePort{{Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(443)}}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{}, false, false)
+	etpLocalService := newService("service2", "namespace1", "10.96.0.11", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"2.2.2.2"}, kapi.ServiceStatus{}, true, false)
+
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		ofm:         &openflowManager{flowCache: map[string][]string{}},
+	}
+	for _, svc := range []*kapi.Service{service, etpLocalService} {
+		if err := npw.updateServiceFlowCache(svc, true, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cookie, err := svcToCookie(service.Namespace, service.Name, "1.1.1.1", 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etpLocalCookie, err := svcToCookie(etpLocalService.Namespace, etpLocalService.Name, "2.2.2.2", 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		key   string
+		flows []string
+	}{
+		{
+			key: serviceFlowCacheKey("External", service.Namespace, service.Name, "1.1.1.1", "tcp", "8080"),
+			flows: []string{
+				fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, dl_vlan=100, tcp, nw_dst=1.1.1.1, tp_dst=8080, "+
+					"actions=strip_vlan,output:patch-breth0_ov", cookie),
+				fmt.Sprintf("cookie=%s, priority=110, in_port=patch-breth0_ov, tcp, nw_src=1.1.1.1, tp_src=8080, "+
+					"actions=mod_vlan_vid:100,output:eth0", cookie),
+			},
+		},
+		{
+			key: serviceFlowCacheKey("External", etpLocalService.Namespace, etpLocalService.Name, "2.2.2.2", "tcp", "8080"),
+			flows: []string{
+				fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, dl_vlan=100, tcp, nw_dst=2.2.2.2, tp_dst=8080, "+
+					"actions=strip_vlan,ct(commit,zone=64003,nat(dst=192.168.18.15:443),table=6)", etpLocalCookie),
+				fmt.Sprintf("cookie=%s, priority=111, table=7, tcp, nw_src=2.2.2.2, tp_src=8080, "+
+					"actions=mod_vlan_vid:100,output:eth0", etpLocalCookie),
+			},
+		},
+	} {
+		flows := sets.New[string](npw.ofm.flowCache[tc.key]...)
+		for _, flow := range tc.flows {
+			if !flows.Has(flow) {
+				t.Errorf("expected VLAN-aware external service flow %q, got:\n%s", flow, strings.Join(sets.List(flows), "\n"))
+			}
+		}
+	}
+}
+
+func TestExternalIPSourcePreservationFlows(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.DisableARPBypassFlows = true
+	config.IPv4Mode = true
+
+	ports := []kapi.ServicePort{{Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(443)}}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{LoadBalancer: kapi.LoadBalancerStatus{Ingress: []kapi.LoadBalancerIngress{{IP: "5.5.5.5"}}}},
+		false, false)
+	etpLocalService := newService("service2", "namespace1", "10.96.0.11", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"2.2.2.2"}, kapi.ServiceStatus{}, true, false)
+	cookie, err := svcToCookie(service.Namespace, service.Name, "1.1.1.1", 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingressCookie, err := svcToCookie(service.Namespace, service.Name, "5.5.5.5", 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etpLocalCookie, err := svcToCookie(etpLocalService.Namespace, etpLocalService.Name, "2.2.2.2", 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	externalIPKey := serviceFlowCacheKey("External", service.Namespace, service.Name, "1.1.1.1", "tcp", "8080")
+	ovnFlows := []string{
+		fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, tcp, nw_dst=1.1.1.1, tp_dst=8080, "+
+			"actions=output:patch-breth0_ov", cookie),
+		fmt.Sprintf("cookie=%s, priority=110, in_port=patch-breth0_ov, tcp, nw_src=1.1.1.1, tp_src=8080, "+
+			"actions=output:eth0", cookie),
+	}
+
+	for _, tc := range []struct {
+		desc      string
+		preserved bool
+		dpuMode   bool
+		expected  map[string][]string
+	}{
+		{
+			desc: "externalIP traffic is sent to the GR by default",
+			expected: map[string][]string{
+				externalIPKey: ovnFlows,
+			},
+		},
+		{
+			desc:      "externalIP traffic is sent to the host when its source IP is preserved",
+			preserved: true,
+			expected: map[string][]string{
+				externalIPKey: {
+					fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, tcp, nw_dst=1.1.1.1, tp_dst=8080, "+
+						"actions=output:LOCAL", cookie),
+					fmt.Sprintf("cookie=%s, priority=110, in_port=LOCAL, tcp, nw_src=1.1.1.1, tp_src=8080, "+
+						"actions=output:eth0", cookie),
+				},
+				// the LB ingress IPs and ETP=local externalIPs are left as is
+				serviceFlowCacheKey("Ingress", service.Namespace, service.Name, "5.5.5.5", "tcp", "8080"): {
+					fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, tcp, nw_dst=5.5.5.5, tp_dst=8080, "+
+						"actions=output:patch-breth0_ov", ingressCookie),
+					fmt.Sprintf("cookie=%s, priority=110, in_port=patch-breth0_ov, tcp, nw_src=5.5.5.5, tp_src=8080, "+
+						"actions=output:eth0", ingressCookie),
+				},
+				serviceFlowCacheKey("External", etpLocalService.Namespace, etpLocalService.Name, "2.2.2.2", "tcp", "8080"): {
+					fmt.Sprintf("cookie=%s, priority=110, in_port=eth0, tcp, nw_dst=2.2.2.2, tp_dst=8080, "+
+						"actions=output:patch-breth0_ov", etpLocalCookie),
+					fmt.Sprintf("cookie=%s, priority=110, in_port=patch-breth0_ov, tcp, nw_src=2.2.2.2, tp_src=8080, "+
+						"actions=output:eth0", etpLocalCookie),
+				},
+			},
+		},
+		{
+			desc:      "externalIP traffic is sent to the GR on DPUs",
+			preserved: true,
+			dpuMode:   true,
+			expected: map[string][]string{
+				externalIPKey: ovnFlows,
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			config.Gateway.ExternalIPSourcePreservation = tc.preserved
+			npw := &nodePortWatcher{
+				dpuMode:     tc.dpuMode,
+				ofportPhys:  "eth0",
+				ofportPatch: "patch-breth0_ov",
+				gatewayIPv4: "192.168.18.15",
+				ofm:         &openflowManager{flowCache: map[string][]string{}},
+			}
+			for _, svc := range []*kapi.Service{service, etpLocalService} {
+				if err := npw.updateServiceFlowCache(svc, true, false); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for key, expected := range tc.expected {
+				if flows := npw.ofm.flowCache[key]; !reflect.DeepEqual(flows, expected) {
+					t.Errorf("expected flows of %s:\n%s\ngot:\n%s", key, strings.Join(expected, "\n"), strings.Join(flows, "\n"))
+				}
+			}
+		})
+	}
+}
+
+func TestExternalServiceFlowsARPBypass(t *testing.T) {
+	ports := []kapi.ServicePort{{Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(443)}}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{LoadBalancer: kapi.LoadBalancerStatus{Ingress: []kapi.LoadBalancerIngress{{IP: "5.5.5.5"}}}},
+		false, false)
+
+	for _, tc := range []struct {
+		desc    string
+		disable bool
+	}{
+		{desc: "ARP bypass flows enabled", disable: false},
+		{desc: "ARP bypass flows disabled", disable: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.Gateway.Mode = config.GatewayModeShared
+			config.Gateway.DisableARPBypassFlows = tc.disable
+			config.IPv4Mode = true
+			// the ARP bypass flows of the external and ingress IPs list the bridge ports once
+			fexec := ovntest.NewFakeExec()
+			if !tc.disable {
+				fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show "})
+			}
+			if err := util.SetExec(fexec); err != nil {
+				t.Fatal(err)
+			}
+
+			npw := &nodePortWatcher{
+				ofportPhys:  "eth0",
+				ofportPatch: "patch-breth0_ov",
+				gatewayIPv4: "192.168.18.15",
+				ofm:         &openflowManager{flowCache: map[string][]string{}},
+			}
+			if err := npw.updateServiceFlowCache(service, true, false); err != nil {
+				t.Fatal(err)
+			}
+
+			arpFlows := 0
+			for _, key := range []string{
+				serviceFlowCacheKey("External", service.Namespace, service.Name, "1.1.1.1", "tcp", "8080"),
+				serviceFlowCacheKey("Ingress", service.Namespace, service.Name, "5.5.5.5", "tcp", "8080"),
+			} {
+				flows := npw.ofm.flowCache[key]
+				if len(flows) == 0 {
+					t.Errorf("expected the flows of %s to be programmed", key)
+				}
+				for _, flow := range flows {
+					if strings.Contains(flow, "arp_tpa") {
+						arpFlows++
+					}
+				}
+			}
+			if tc.disable && arpFlows != 0 {
+				t.Errorf("expected no ARP bypass flow, got %d", arpFlows)
+			}
+			if !tc.disable && arpFlows != 2 {
+				t.Errorf("expected an ARP bypass flow per IP, got %d", arpFlows)
+			}
+			if !fexec.CalledMatchesExpected() {
+				t.Error(fexec.ErrorDesc())
+			}
+		})
+	}
+}
+
+func TestForceETPAnnotation(t *testing.T) {
+	ports := []kapi.ServicePort{{Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(443)}}
+	for _, tc := range []struct {
+		desc       string
+		isETPLocal bool
+		annotation string
+		allow      bool
+		wantLocal  bool
+		wantPath   serviceFlowPath
+	}{
+		{desc: "forces ETP=local", annotation: "local", allow: true, wantLocal: true, wantPath: serviceFlowPathHost},
+		{desc: "forces ETP=cluster", isETPLocal: true, annotation: "Cluster", allow: true, wantLocal: false, wantPath: serviceFlowPathOVN},
+		{desc: "ignores the annotation when not allowed", annotation: "local", allow: false, wantLocal: false, wantPath: serviceFlowPathOVN},
+		{desc: "ignores an invalid annotation", isETPLocal: true, annotation: "remote", allow: true, wantLocal: true, wantPath: serviceFlowPathHost},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.Gateway.Mode = config.GatewayModeShared
+			config.Gateway.AllowForceETPAnnotation = tc.allow
+			config.IPv4Mode = true
+			service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+				nil, kapi.ServiceStatus{}, tc.isETPLocal, false)
+			service.Annotations = map[string]string{ovnForceETPAnnotation: tc.annotation}
+
+			if got := serviceExternalTrafficPolicyLocal(service); got != tc.wantLocal {
+				t.Errorf("serviceExternalTrafficPolicyLocal() = %v, want %v", got, tc.wantLocal)
+			}
+			if got := getServiceFlowPath(service, true, config.Gateway.Mode); got != tc.wantPath {
+				t.Errorf("getServiceFlowPath() = %v, want %v", got, tc.wantPath)
+			}
+			// the ETP=local NodePort traffic to the local endpoints is not SNAT-ed by the management port
+			etpRules := 0
+			for _, rule := range getGatewayIPTRules(service, []string{"10.244.0.5"}, false) {
+				if rule.Chain == iptableMgmPortChain {
+					etpRules++
+				}
+			}
+			if tc.wantLocal && etpRules == 0 {
+				t.Error("expected the ETP=local iptables rules")
+			}
+			if !tc.wantLocal && etpRules != 0 {
+				t.Errorf("expected no ETP=local iptables rule, got %d", etpRules)
+			}
+		})
+	}
+}
+
+func TestSCTPExternalServiceFlows(t *testing.T) {
+	ports := []kapi.ServicePort{{Port: 8080, Protocol: kapi.ProtocolSCTP, NodePort: 31111, TargetPort: intstr.FromInt(443)}}
+	for _, tc := range []struct {
+		desc                  string
+		externalIP            string
+		hasLocalHostNetworkEp bool
+		want                  []string
+	}{
+		{
+			desc:       "IPv4 externalIP steered into OVN",
+			externalIP: "1.1.1.1",
+			want: []string{
+				"cookie=%s, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=1.1.1.1, actions=output:LOCAL",
+				"cookie=%s, priority=110, in_port=eth0, sctp, nw_dst=1.1.1.1, tp_dst=8080, actions=output:patch-breth0_ov",
+				"cookie=%s, priority=110, in_port=patch-breth0_ov, sctp, nw_src=1.1.1.1, tp_src=8080, actions=output:eth0",
+			},
+		},
+		{
+			desc:       "IPv6 externalIP steered into OVN",
+			externalIP: "fd00::1",
+			want: []string{
+				"cookie=%s, priority=110, in_port=eth0, icmp6, icmp_type=135, icmp_code=0, nd_target=fd00::1, actions=output:LOCAL",
+				"cookie=%s, priority=110, in_port=eth0, sctp6, ipv6_dst=fd00::1, tp_dst=8080, actions=output:patch-breth0_ov",
+				"cookie=%s, priority=110, in_port=patch-breth0_ov, sctp6, ipv6_src=fd00::1, tp_src=8080, actions=output:eth0",
+			},
+		},
+		{
+			desc:                  "IPv4 externalIP DNAT-ed to a local host networked endpoint",
+			externalIP:            "1.1.1.1",
+			hasLocalHostNetworkEp: true,
+			want: []string{
+				"cookie=%s, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=1.1.1.1, actions=output:LOCAL",
+				"cookie=%s, priority=110, in_port=eth0, sctp, nw_dst=1.1.1.1, tp_dst=8080, actions=ct(commit,zone=64003,nat(dst=192.168.18.15:443),table=6)",
+				"cookie=%s, priority=110, in_port=LOCAL, sctp, tp_src=443, actions=ct(zone=64003 nat,table=7)",
+			},
+		},
+		{
+			desc:                  "IPv6 externalIP DNAT-ed to a local host networked endpoint",
+			externalIP:            "fd00::1",
+			hasLocalHostNetworkEp: true,
+			want: []string{
+				"cookie=%s, priority=110, in_port=eth0, icmp6, icmp_type=135, icmp_code=0, nd_target=fd00::1, actions=output:LOCAL",
+				"cookie=%s, priority=110, in_port=eth0, sctp6, ipv6_dst=fd00::1, tp_dst=8080, actions=ct(commit,zone=64003,nat(dst=[fd00::15]:443),table=6)",
+				"cookie=%s, priority=110, in_port=LOCAL, sctp6, tp_src=443, actions=ct(zone=64003 nat,table=7)",
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.Gateway.Mode = config.GatewayModeShared
+			// the bridge ports can't be listed, the ARP/NS bypass flows output to LOCAL
+			fexec := ovntest.NewFakeExec()
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show ", Err: fmt.Errorf("no bridge")})
+			if err := util.SetExec(fexec); err != nil {
+				t.Fatal(err)
+			}
+			service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeClusterIP,
+				[]string{tc.externalIP}, kapi.ServiceStatus{}, tc.hasLocalHostNetworkEp, false)
+			npw := &nodePortWatcher{
+				ofportPhys:  "eth0",
+				ofportPatch: "patch-breth0_ov",
+				gatewayIPv4: "192.168.18.15",
+				gatewayIPv6: "fd00::15",
+				ofm:         &openflowManager{flowCache: map[string][]string{}},
+			}
+			if err := npw.createLbAndExternalSvcFlows(service, &service.Spec.Ports[0], true, tc.hasLocalHostNetworkEp,
+				"sctp", "output:patch-breth0_ov", tc.externalIP, "External", nil); err != nil {
+				t.Fatal(err)
+			}
+			flowProtocol := "sctp"
+			if strings.Contains(tc.externalIP, ":") {
+				flowProtocol = "sctp6"
+			}
+			key := serviceFlowCacheKey("External", service.Namespace, service.Name, tc.externalIP, flowProtocol, "8080")
+			flows := npw.ofm.flowCache[key]
+			cookie, err := svcToCookie(service.Namespace, service.Name, tc.externalIP, 8080)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tc.want {
+				want = fmt.Sprintf(want, cookie)
+				found := false
+				for _, flow := range flows {
+					if flow == want {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("expected flow %q in %v", want, flows)
+				}
+			}
+			if !fexec.CalledMatchesExpected() {
+				t.Error(fexec.ErrorDesc())
+			}
+		})
+	}
+}
+
+func TestLoadBalancerWithoutNodePortAllocationFlows(t *testing.T) {
+	ports := []kapi.ServicePort{{Port: 8080, Protocol: kapi.ProtocolTCP, TargetPort: intstr.FromInt(443)}}
+	status := kapi.ServiceStatus{LoadBalancer: kapi.LoadBalancerStatus{Ingress: []kapi.LoadBalancerIngress{{IP: "5.5.5.5"}}}}
+	for _, tc := range []struct {
+		desc                  string
+		isETPLocal            bool
+		hasLocalHostNetworkEp bool
+		want                  []string
+	}{
+		{
+			desc: "ETP=cluster ingress IP steered into OVN",
+			want: []string{
+				"cookie=%s, priority=110, in_port=eth0, tcp, nw_dst=5.5.5.5, tp_dst=8080, actions=output:patch-breth0_ov",
+				"cookie=%s, priority=110, in_port=patch-breth0_ov, tcp, nw_src=5.5.5.5, tp_src=8080, actions=output:eth0",
+			},
+		},
+		{
+			desc:       "ETP=local ingress IP without local host networked endpoint steered into OVN",
+			isETPLocal: true,
+			want: []string{
+				"cookie=%s, priority=110, in_port=eth0, tcp, nw_dst=5.5.5.5, tp_dst=8080, actions=output:patch-breth0_ov",
+				"cookie=%s, priority=110, in_port=patch-breth0_ov, tcp, nw_src=5.5.5.5, tp_src=8080, actions=output:eth0",
+			},
+		},
+		{
+			desc:                  "ETP=local ingress IP DNAT-ed to a local host networked endpoint",
+			isETPLocal:            true,
+			hasLocalHostNetworkEp: true,
+			want: []string{
+				"cookie=%s, priority=110, in_port=eth0, tcp, nw_dst=5.5.5.5, tp_dst=8080, actions=ct(commit,zone=64003,nat(dst=192.168.18.15:443),table=6)",
+				"cookie=%s, priority=110, in_port=LOCAL, tcp, tp_src=443, actions=ct(zone=64003 nat,table=7)",
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.Gateway.Mode = config.GatewayModeShared
+			config.IPv4Mode = true
+			// the bridge ports can't be listed, the ARP bypass flow outputs to LOCAL
+			fexec := ovntest.NewFakeExec()
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show ", Err: fmt.Errorf("no bridge")})
+			if err := util.SetExec(fexec); err != nil {
+				t.Fatal(err)
+			}
+			service := newServiceWithoutNodePortAllocation("service1", "namespace1", "10.96.0.10", ports,
+				kapi.ServiceTypeLoadBalancer, nil, status, tc.isETPLocal, false)
+			npw := &nodePortWatcher{
+				ofportPhys:  "eth0",
+				ofportPatch: "patch-breth0_ov",
+				gatewayIPv4: "192.168.18.15",
+				ofm:         &openflowManager{flowCache: map[string][]string{}},
+			}
+			if err := npw.updateServiceFlowCache(service, true, tc.hasLocalHostNetworkEp); err != nil {
+				t.Fatal(err)
+			}
+
+			// the service only gets the flows of its ingress IP
+			key := serviceFlowCacheKey("Ingress", service.Namespace, service.Name, "5.5.5.5", "tcp", "8080")
+			for cacheKey := range npw.ofm.flowCache {
+				if cacheKey != key {
+					t.Errorf("unexpected flows %s: %v", cacheKey, npw.ofm.flowCache[cacheKey])
+				}
+			}
+			cookie, err := svcToCookie(service.Namespace, service.Name, "5.5.5.5", 8080)
+			if err != nil {
+				t.Fatal(err)
+			}
+			flows := sets.New[string](npw.ofm.flowCache[key]...)
+			for _, want := range tc.want {
+				if want = fmt.Sprintf(want, cookie); !flows.Has(want) {
+					t.Errorf("expected flow %q, got:\n%s", want, strings.Join(sets.List(flows), "\n"))
+				}
+			}
+			if !fexec.CalledMatchesExpected() {
+				t.Error(fexec.ErrorDesc())
+			}
+
+			// and no NodePort iptables rule
+			for _, rule := range getGatewayIPTRules(service, []string{"10.244.0.5"}, tc.hasLocalHostNetworkEp) {
+				if strings.Contains(strings.Join(rule.Args, " "), "--dst-type LOCAL") {
+					t.Errorf("unexpected NodePort iptables rule %s", rule.String())
+				}
+			}
+		})
+	}
+}
+
+func TestETPLocalClusterNetworkedEndpointsFlows(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+	// the ARP bypass flows of the external IP and the ingress IP list the bridge ports once
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show "})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+
+	ports := []kapi.ServicePort{{Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)}}
+	status := kapi.ServiceStatus{LoadBalancer: kapi.LoadBalancerStatus{Ingress: []kapi.LoadBalancerIngress{{IP: "5.5.5.5"}}}}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"1.1.1.1"}, status, true, false)
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		ofm:         &openflowManager{flowCache: map[string][]string{}},
+	}
+
+	// the service only has local cluster-networked endpoints
+	if err := npw.updateServiceFlowCache(service, true, false); err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{
+		serviceFlowCacheKey("NodePort", service.Namespace, service.Name, "tcp", "31111"),
+		serviceFlowCacheKey("External", service.Namespace, service.Name, "1.1.1.1", "tcp", "8080"),
+		serviceFlowCacheKey("Ingress", service.Namespace, service.Name, "5.5.5.5", "tcp", "8080"),
+	}
+	for _, key := range keys {
+		flows, ok := npw.ofm.flowCache[key]
+		if !ok {
+			t.Errorf("expected the flows of %s", key)
+			continue
+		}
+		for _, flow := range flows {
+			// the traffic is neither DNAT-ed nor SNAT-ed on the bridge nor steered to the host
+			if strings.Contains(flow, "ct(") || strings.Contains(flow, "nat(") ||
+				strings.Contains(flow, "table=6") || strings.Contains(flow, "LOCAL") {
+				t.Errorf("expected the traffic of %s to be steered to the GR of the node as is, got flow: %s", key, flow)
+			}
+			// but sent to the GR of the node through the patch port
+			if strings.Contains(flow, "in_port=eth0") && !strings.Contains(flow, "arp") &&
+				!strings.HasSuffix(flow, "actions=output:patch-breth0_ov") {
+				t.Errorf("expected the traffic of %s to be sent to the patch port, got flow: %s", key, flow)
+			}
+		}
+	}
+	if !fexec.CalledMatchesExpected() {
+		t.Error(fexec.ErrorDesc())
+	}
+}
+
+func TestQueryExternalIPOwnership(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.SkipNodeIPExternalIPs = true
+
+	ports := []kapi.ServicePort{{Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)}}
+	status := kapi.ServiceStatus{LoadBalancer: kapi.LoadBalancerStatus{Ingress: []kapi.LoadBalancerIngress{{IP: "5.5.5.5"}}}}
+	service1 := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"1.1.1.1", "192.168.18.15"}, status, false, false)
+	service2 := newService("service2", "namespace1", "10.96.0.11", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"1.1.1.0/29"}, kapi.ServiceStatus{}, true, false)
+	npw := &nodePortWatcher{
+		ofportPhys:    "eth0",
+		ofportPatch:   "patch-breth0_ov",
+		nodeIPManager: &addressManager{addresses: sets.New("192.168.18.15")},
+		serviceInfo: map[ktypes.NamespacedName]*serviceConfig{
+			{Namespace: "namespace1", Name: "service1"}: {service: service1},
+			{Namespace: "namespace1", Name: "service2"}: {service: service2, hasLocalHostNetworkEp: true},
+		},
+	}
+
+	tests := []struct {
+		desc    string
+		ip      string
+		want    *metrics.ExternalIPOwnership
+		wantErr bool
+	}{
+		{
+			desc: "an externalIP is programmed for each service exposing it",
+			ip:   "1.1.1.1",
+			want: &metrics.ExternalIPOwnership{
+				IP:         "1.1.1.1",
+				Programmed: true,
+				Services: []metrics.ExternalIPServiceOwnership{
+					{
+						Service:    "namespace1/service1",
+						Type:       "External",
+						Programmed: true,
+						InPort:     "eth0",
+						OutPort:    "patch-breth0_ov",
+						Reason:     "shared gateway mode",
+					},
+					{
+						Service:    "namespace1/service2",
+						Type:       "External",
+						Programmed: true,
+						InPort:     "eth0",
+						OutPort:    "LOCAL",
+						Reason:     "externalTrafficPolicy=local with local host networked endpoints",
+					},
+				},
+			},
+		},
+		{
+			desc: "a LoadBalancer ingress IP is programmed",
+			ip:   "5.5.5.5",
+			want: &metrics.ExternalIPOwnership{
+				IP:         "5.5.5.5",
+				Programmed: true,
+				Services: []metrics.ExternalIPServiceOwnership{
+					{
+						Service:    "namespace1/service1",
+						Type:       "Ingress",
+						Programmed: true,
+						InPort:     "eth0",
+						OutPort:    "patch-breth0_ov",
+						Reason:     "shared gateway mode",
+					},
+				},
+			},
+		},
+		{
+			desc: "an externalIP that is an IP of the node is not programmed",
+			ip:   "192.168.18.15",
+			want: &metrics.ExternalIPOwnership{
+				IP: "192.168.18.15",
+				Services: []metrics.ExternalIPServiceOwnership{
+					{
+						Service: "namespace1/service1",
+						Type:    "External",
+						Reason:  "externalIP is an IP of the node",
+					},
+				},
+			},
+		},
+		{
+			desc: "an IP exposed by no service is not programmed",
+			ip:   "2.2.2.2",
+			want: &metrics.ExternalIPOwnership{
+				IP:       "2.2.2.2",
+				Services: []metrics.ExternalIPServiceOwnership{},
+			},
+		},
+		{
+			desc:    "an invalid IP is rejected",
+			ip:      "1.1.1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := npw.queryExternalIPOwnership(tt.ip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("queryExternalIPOwnership() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queryExternalIPOwnership() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceFlowsRequestNoConntrackHelper(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+	// the ARP bypass flows of the external IP and the ingress IP of each port list the bridge ports once
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show "})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+
+	// FTP and TFTP are the protocols OVS has ALG helpers for
+	ports := []kapi.ServicePort{
+		{Name: "ftp", Port: 21, Protocol: kapi.ProtocolTCP, NodePort: 30021, TargetPort: intstr.FromInt(21)},
+		{Name: "tftp", Port: 69, Protocol: kapi.ProtocolUDP, NodePort: 30069, TargetPort: intstr.FromInt(69)},
+	}
+	status := kapi.ServiceStatus{LoadBalancer: kapi.LoadBalancerStatus{Ingress: []kapi.LoadBalancerIngress{{IP: "5.5.5.5"}}}}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"1.1.1.1"}, status, true, false)
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		ofm:         &openflowManager{flowCache: map[string][]string{}},
+	}
+
+	// the service traffic is DNAT-ed on the bridge towards its local host networked endpoints
+	if err := npw.updateServiceFlowCache(service, true, true); err != nil {
+		t.Fatal(err)
+	}
+	dnatFlows := 0
+	for key, flows := range npw.ofm.flowCache {
+		for _, flow := range flows {
+			if strings.Contains(flow, "nat(dst=") {
+				dnatFlows++
+			}
+			if strings.Contains(flow, "alg=") {
+				t.Errorf("expected the flows of %s not to request a conntrack ALG helper, got flow: %s", key, flow)
+			}
+		}
+	}
+	// a DNAT flow for the NodePort, the external IP and the ingress IP of each port
+	if dnatFlows != 6 {
+		t.Errorf("expected 6 DNAT flows, got %d", dnatFlows)
+	}
+	if !fexec.CalledMatchesExpected() {
+		t.Error(fexec.ErrorDesc())
+	}
+}
+
+func TestArpBypassFlowMeter(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	// the ARP bypass flows output to LOCAL when the bridge ports cannot be listed
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show breth0", Err: fmt.Errorf("failed to list ports")})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show breth0", Err: fmt.Errorf("failed to list ports")})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show breth0", Err: fmt.Errorf("failed to list ports")})
+	// the meter is added, or modified if it already exists
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl -O OpenFlow13 add-meter breth0 meter=1,pktps,band=type=drop,rate=100"})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+		Cmd: "ovs-ofctl -O OpenFlow13 add-meter breth0 meter=1,pktps,band=type=drop,rate=100",
+		Err: fmt.Errorf("meter exists"),
+	})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl -O OpenFlow13 mod-meter breth0 meter=1,pktps,band=type=drop,rate=100"})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+	npw := &nodePortWatcher{ofportPhys: "eth0", ofportPatch: "patch-breth0_ov", gwBridge: "breth0"}
+
+	// no meter by default
+	if flow := npw.generateArpBypassFlow("tcp", "1.1.1.1", "0x1", nil); strings.Contains(flow, "meter") {
+		t.Errorf("expected no meter in the ARP bypass flow by default, got: %s", flow)
+	}
+
+	config.Gateway.ARPBypassFlowsMeterRate = 100
+	expectedFlows := []string{
+		"cookie=0x1, priority=110, in_port=eth0, arp, arp_op=1, arp_tpa=1.1.1.1, actions=meter:1,output:LOCAL",
+		"cookie=0x1, priority=110, in_port=eth0, icmp6, icmp_type=135, icmp_code=0, nd_target=fd00::1, actions=meter:1,output:LOCAL",
+	}
+	for i, ip := range []string{"1.1.1.1", "fd00::1"} {
+		if flow := npw.generateArpBypassFlow("tcp", ip, "0x1", nil); flow != expectedFlows[i] {
+			t.Errorf("expected the ARP bypass flow %q, got %q", expectedFlows[i], flow)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := ensureArpBypassMeter("breth0"); err != nil {
+			t.Fatalf("ensureArpBypassMeter() unexpected error: %v", err)
+		}
+	}
+	if !fexec.CalledMatchesExpected() {
+		t.Error(fexec.ErrorDesc())
+	}
+}
+
+func TestServiceFlowProtocols(t *testing.T) {
+	tests := []struct {
+		desc       string
+		ipv4Mode   bool
+		ipv6Mode   bool
+		ipFamilies []kapi.IPFamily
+		want       []string
+	}{
+		{desc: "dual stack IPv4 primary", ipv4Mode: true, ipv6Mode: true,
+			ipFamilies: []kapi.IPFamily{kapi.IPv4Protocol, kapi.IPv6Protocol}, want: []string{"tcp", "tcp6"}},
+		{desc: "dual stack IPv6 primary", ipv4Mode: true, ipv6Mode: true,
+			ipFamilies: []kapi.IPFamily{kapi.IPv6Protocol, kapi.IPv4Protocol}, want: []string{"tcp6", "tcp"}},
+		{desc: "dual stack single stack IPv6 service", ipv4Mode: true, ipv6Mode: true,
+			ipFamilies: []kapi.IPFamily{kapi.IPv6Protocol}, want: []string{"tcp6", "tcp"}},
+		{desc: "dual stack without ipFamilies", ipv4Mode: true, ipv6Mode: true, want: []string{"tcp", "tcp6"}},
+		{desc: "IPv4 only", ipv4Mode: true,
+			ipFamilies: []kapi.IPFamily{kapi.IPv6Protocol, kapi.IPv4Protocol}, want: []string{"tcp"}},
+		{desc: "IPv6 only", ipv6Mode: true,
+			ipFamilies: []kapi.IPFamily{kapi.IPv4Protocol, kapi.IPv6Protocol}, want: []string{"tcp6"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.IPv4Mode = tt.ipv4Mode
+			config.IPv6Mode = tt.ipv6Mode
+			service := &kapi.Service{Spec: kapi.ServiceSpec{IPFamilies: tt.ipFamilies}}
+			if got := serviceFlowProtocols(service, "tcp"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("serviceFlowProtocols() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetServiceTargetPort(t *testing.T) {
+	tests := []struct {
+		desc       string
+		targetPort intstr.IntOrString
+		want       int32
+		wantErr    bool
+	}{
+		{desc: "numeric target port", targetPort: intstr.FromInt(8080), want: 8080},
+		{desc: "numeric string target port", targetPort: intstr.FromString("8080"), want: 8080},
+		{desc: "zero target port", targetPort: intstr.FromInt(0), wantErr: true},
+		{desc: "out of range target port", targetPort: intstr.FromInt(65536), wantErr: true},
+		{desc: "named target port", targetPort: intstr.FromString("http"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			svcPort := &kapi.ServicePort{Port: 80, Protocol: kapi.ProtocolTCP, TargetPort: tt.targetPort}
+			got, err := getServiceTargetPort(svcPort)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getServiceTargetPort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getServiceTargetPort() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestETPLocalHostFlowsInvalidTargetPort(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+	// the ARP bypass flows of the external IP of each port list the bridge ports once
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show "})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+
+	ports := []kapi.ServicePort{
+		{Name: "valid", Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)},
+		{Name: "invalid", Port: 8081, Protocol: kapi.ProtocolTCP, NodePort: 31112, TargetPort: intstr.FromInt(0)},
+	}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{}, true, false)
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		ofm:         &openflowManager{flowCache: map[string][]string{}},
+	}
+	invalidKeys := []string{
+		serviceFlowCacheKey("NodePort", service.Namespace, service.Name, "tcp", "31112"),
+		serviceFlowCacheKey("External", service.Namespace, service.Name, "1.1.1.1", "tcp", "8081"),
+	}
+	// stale flows of the invalid target port are removed too
+	for _, key := range invalidKeys {
+		npw.ofm.flowCache[key] = []string{"stale"}
+	}
+
+	err := npw.updateServiceFlowCache(service, true, true)
+	if err == nil || !strings.Contains(err.Error(), "invalid target port \"0\"") {
+		t.Errorf("expected an invalid target port error, got: %v", err)
+	}
+	for _, key := range invalidKeys {
+		if flows, ok := npw.ofm.flowCache[key]; ok {
+			t.Errorf("expected the flows of %s to be skipped, got: %v", key, flows)
+		}
+	}
+	// the flows of the other service port are still programmed
+	if flows := npw.ofm.flowCache[serviceFlowCacheKey("NodePort", service.Namespace, service.Name, "tcp", "31111")]; len(flows) == 0 ||
+		!strings.Contains(flows[0], "nat(dst=192.168.18.15:8080)") {
+		t.Errorf("expected the NodePort flows of the valid target port, got: %v", flows)
+	}
+	if flows := npw.ofm.flowCache[serviceFlowCacheKey("External", service.Namespace, service.Name, "1.1.1.1", "tcp", "8080")]; len(flows) < 2 ||
+		!strings.Contains(flows[1], "nat(dst=192.168.18.15:8080)") {
+		t.Errorf("expected the External flows of the valid target port, got: %v", flows)
+	}
+	if !fexec.CalledMatchesExpected() {
+		t.Error(fexec.ErrorDesc())
+	}
+}
+
+func TestETPLocalHostFlowsMissingGatewayIPFamily(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.DisableARPBypassFlows = true
+	config.IPv4Mode = true
+	config.IPv6Mode = true
+
+	ports := []kapi.ServicePort{
+		{Name: "http", Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)},
+	}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		[]string{"1.1.1.1", "fd00::1"}, kapi.ServiceStatus{}, true, false)
+	// the node is dual-stack but only has an IPv4 gateway IP
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		ofm:         &openflowManager{flowCache: map[string][]string{}},
+	}
+	v6Key := serviceFlowCacheKey("External", service.Namespace, service.Name, "fd00::1", "tcp6", "8080")
+	// stale flows of the v6 externalIP are removed too
+	npw.ofm.flowCache[v6Key] = []string{"stale"}
+
+	for _, externalIP := range service.Spec.ExternalIPs {
+		if err := npw.createLbAndExternalSvcFlows(service, &service.Spec.Ports[0], true, true, "tcp",
+			"", externalIP, "External", nil); err != nil {
+			t.Fatalf("unexpected error adding the flows of %s: %v", externalIP, err)
+		}
+	}
+	if flows, ok := npw.ofm.flowCache[v6Key]; ok {
+		t.Errorf("expected the host DNAT flows of the v6 externalIP to be skipped, got: %v", flows)
+	}
+	v4Flows := npw.ofm.flowCache[serviceFlowCacheKey("External", service.Namespace, service.Name, "1.1.1.1", "tcp", "8080")]
+	if len(v4Flows) == 0 || !strings.Contains(v4Flows[0], "nat(dst=192.168.18.15:8080)") {
+		t.Errorf("expected the host DNAT flows of the v4 externalIP, got: %v", v4Flows)
+	}
+
+	// once the node has a v6 gateway IP the v6 externalIP gets its flows
+	npw.gatewayIPv6 = "fd00::15"
+	if err := npw.createLbAndExternalSvcFlows(service, &service.Spec.Ports[0], true, true, "tcp",
+		"", "fd00::1", "External", nil); err != nil {
+		t.Fatal(err)
+	}
+	if flows := npw.ofm.flowCache[v6Key]; len(flows) == 0 || !strings.Contains(flows[0], "nat(dst=[fd00::15]:8080)") {
+		t.Errorf("expected the host DNAT flows of the v6 externalIP, got: %v", flows)
+	}
+}
+
+func TestDefaultFlowsGeneveDirectToHost(t *testing.T) {
+	bridge := &bridgeConfiguration{
+		bridgeName: "breth0",
+		ips: []*net.IPNet{
+			ovntest.MustParseIPNet("192.168.1.10/24"),
+			ovntest.MustParseIPNet("fc00:f853:ccd:e793::3/64"),
+		},
+		macAddress:  ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+		ofPortPatch: "patch-breth0_ov",
+		ofPortPhys:  "eth0",
+		ofPortHost:  "LOCAL",
+	}
+	for _, disabled := range []bool{false, true} {
+		if err := config.PrepareTestConfig(); err != nil {
+			t.Fatal(err)
+		}
+		config.Gateway.Mode = config.GatewayModeShared
+		config.Gateway.DisableGeneveDirectToHostFlow = disabled
+		config.IPv4Mode = true
+		config.IPv6Mode = true
+		config.Kubernetes.ServiceCIDRs = []*net.IPNet{
+			ovntest.MustParseIPNet("172.16.1.0/24"),
+			ovntest.MustParseIPNet("fd00:10:96::/112"),
+		}
+
+		flows, err := flowsForDefaultBridge(bridge, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		renderedFlows := sets.NewString(flows...)
+		for _, proto := range []string{"udp", "udp6"} {
+			directFlow := fmt.Sprintf("cookie=%s, priority=205, in_port=eth0, dl_dst=0a:58:0a:01:01:01, %s, udp_dst=%d, actions=output:LOCAL",
+				geneveOpenFlowCookie, proto, config.Default.EncapPort)
+			if renderedFlows.Has(directFlow) == disabled {
+				t.Errorf("expected flow %q rendered: %v, disabled: %v", directFlow, !disabled, disabled)
+			}
+			// the Geneve traffic always takes the NORMAL path otherwise
+			normalFlow := fmt.Sprintf("cookie=%s, priority=200, in_port=eth0, %s, udp_dst=%d, actions=NORMAL",
+				geneveOpenFlowCookie, proto, config.Default.EncapPort)
+			if !renderedFlows.Has(normalFlow) {
+				t.Errorf("expected flow %q to be rendered, disabled: %v", normalFlow, disabled)
+			}
+		}
+	}
+}
+
+func TestServiceFlowTimeouts(t *testing.T) {
+	tests := []struct {
+		desc        string
+		annotations map[string]string
+		want        string
+	}{
+		{
+			desc: "permanent flows by default",
+			want: "",
+		},
+		{
+			desc:        "idle timeout",
+			annotations: map[string]string{ovnFlowIdleTimeoutAnnotation: "30"},
+			want:        "idle_timeout=30, ",
+		},
+		{
+			desc: "idle and hard timeouts",
+			annotations: map[string]string{
+				ovnFlowIdleTimeoutAnnotation: "30",
+				ovnFlowHardTimeoutAnnotation: "600",
+			},
+			want: "idle_timeout=30, hard_timeout=600, ",
+		},
+		{
+			desc: "invalid timeouts are ignored",
+			annotations: map[string]string{
+				ovnFlowIdleTimeoutAnnotation: "0",
+				ovnFlowHardTimeoutAnnotation: "70000",
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			service := newService("service1", "namespace1", "10.96.0.10", nil, kapi.ServiceTypeNodePort,
+				nil, kapi.ServiceStatus{}, false, false)
+			service.Annotations = tt.annotations
+			if got := getServiceFlowTimeouts(service); got != tt.want {
+				t.Errorf("getServiceFlowTimeouts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceFlowsWithTimeouts(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.DisableARPBypassFlows = true
+	config.IPv4Mode = true
+
+	ports := []kapi.ServicePort{
+		{Name: "http", Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)},
+	}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{}, true, false)
+	service.Annotations = map[string]string{
+		ovnFlowIdleTimeoutAnnotation: "30",
+		ovnFlowHardTimeoutAnnotation: "600",
+	}
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		ofm: &openflowManager{
+			defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+			flowCache:     map[string][]string{},
+			lastSyncTime:  map[string]time.Time{},
+		},
+	}
+	keys := []string{
+		serviceFlowCacheKey("NodePort", service.Namespace, service.Name, "tcp", "31111"),
+		serviceFlowCacheKey("External", service.Namespace, service.Name, "1.1.1.1", "tcp", "8080"),
+	}
+	checkFlows := func(hasLocalHostNetworkEp, wantTimeouts bool) {
+		t.Helper()
+		for _, key := range keys {
+			flows := npw.ofm.flowCache[key]
+			if len(flows) == 0 {
+				t.Fatalf("expected the flows of %s", key)
+			}
+			for _, flow := range flows {
+				shared := strings.HasPrefix(flow, "cookie="+etpSvcOpenFlowCookie)
+				hasTimeouts := strings.Contains(flow, ", idle_timeout=30, hard_timeout=600, priority=")
+				// the etp svc host flows shared by all the services never expire
+				if hasTimeouts != (wantTimeouts && !shared) {
+					t.Errorf("unexpected timeouts of flow %q, etp local: %v, want timeouts: %v",
+						flow, hasLocalHostNetworkEp, wantTimeouts)
+				}
+			}
+		}
+	}
+
+	for _, hasLocalHostNetworkEp := range []bool{false, true} {
+		if err := npw.updateServiceFlowCache(service, true, hasLocalHostNetworkEp); err != nil {
+			t.Fatal(err)
+		}
+		checkFlows(hasLocalHostNetworkEp, true)
+	}
+
+	// every flow sync pushes the cached flows again, re-adding the ones that
+	// expired on the bridge while the service still exists
+	fexec := ovntest.NewFakeExec()
+	replaceFlowsCmd := "ovs-ofctl -O OpenFlow13 --bundle replace-flows breth0 -"
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceFlowsCmd})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceFlowsCmd})
+	if err := util.SetExec(fexec); err != nil {
+		t.Fatal(err)
+	}
+	npw.ofm.syncFlows()
+	npw.ofm.syncFlows()
+	if !fexec.CalledMatchesExpected() {
+		t.Error(fexec.ErrorDesc())
+	}
+	checkFlows(true, true)
+
+	// the flows are permanent again once the annotations are removed
+	service.Annotations = nil
+	if err := npw.updateServiceFlowCache(service, true, true); err != nil {
+		t.Fatal(err)
+	}
+	checkFlows(true, false)
+}
+
+func TestQueryExternalIPOwnershipBridge(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.DisableARPBypassFlows = true
+	config.IPv4Mode = true
+
+	ports := []kapi.ServicePort{{Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)}}
+	status := kapi.ServiceStatus{LoadBalancer: kapi.LoadBalancerStatus{Ingress: []kapi.LoadBalancerIngress{{IP: "5.5.5.5"}}}}
+	service1 := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"1.1.1.1"}, status, false, false)
+	service2 := newService("service2", "namespace1", "10.96.0.11", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"2.2.2.2"}, kapi.ServiceStatus{}, false, false)
+	service3 := newService("service3", "namespace1", "10.96.0.12", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"3.3.3.3"}, kapi.ServiceStatus{}, false, false)
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		ofm: &openflowManager{
+			defaultBridge:         &bridgeConfiguration{bridgeName: "breth0"},
+			externalGatewayBridge: &bridgeConfiguration{bridgeName: "breth1"},
+			flowCache:             map[string][]string{},
+			exGWFlowCache:         map[string][]string{},
+		},
+		serviceInfo: map[ktypes.NamespacedName]*serviceConfig{
+			{Namespace: "namespace1", Name: "service1"}: {service: service1},
+			{Namespace: "namespace1", Name: "service2"}: {service: service2},
+			{Namespace: "namespace1", Name: "service3"}: {service: service3},
+		},
+	}
+	// the flows of service1 are on the default bridge, the ones of service2
+	// on the external gateway bridge and the ones of service3 are not cached
+	if err := npw.updateServiceFlowCache(service1, true, false); err != nil {
+		t.Fatal(err)
+	}
+	npw.ofm.updateExBridgeFlowCacheEntry(serviceFlowCacheKey("External", "namespace1", "service2", "2.2.2.2", "tcp", "8080"),
+		[]string{"cookie=0x0, priority=110, in_port=eth1, tcp, nw_dst=2.2.2.2, tp_dst=8080, actions=output:patch-breth1_ov"})
+
+	for ip, want := range map[string]string{
+		"1.1.1.1": "breth0",
+		"5.5.5.5": "breth0",
+		"2.2.2.2": "breth1",
+		"3.3.3.3": "",
+	} {
+		ownership, err := npw.queryExternalIPOwnership(ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ownership.Services) != 1 {
+			t.Fatalf("expected a single service exposing %s, got: %+v", ip, ownership.Services)
+		}
+		if got := ownership.Services[0].Bridge; got != want {
+			t.Errorf("expected the flows of %s on bridge %q, got %q", ip, want, got)
+		}
+	}
+}
+
+func TestCommonFlowsEgressSNATSourceIP(t *testing.T) {
+	tests := []struct {
+		desc          string
+		sourceIPs     string
+		expectedSNATs []string
+	}{
+		{
+			desc:          "defaults to the node IPs",
+			expectedSNATs: []string{"ip, pkt_mark=0x3f0 actions=ct(commit, zone=64000, nat(src=192.168.1.10)", "ipv6, pkt_mark=0x3f0 actions=ct(commit, zone=64000, nat(src=fc00:f853:ccd:e793::3)"},
+		},
+		{
+			desc:          "uses the configured IPv4 source IP",
+			sourceIPs:     "192.168.1.20",
+			expectedSNATs: []string{"ip, pkt_mark=0x3f0 actions=ct(commit, zone=64000, nat(src=192.168.1.20)", "ipv6, pkt_mark=0x3f0 actions=ct(commit, zone=64000, nat(src=fc00:f853:ccd:e793::3)"},
+		},
+		{
+			desc:          "uses the configured IPv6 source IP",
+			sourceIPs:     "fc00:f853:ccd:e793::20",
+			expectedSNATs: []string{"ip, pkt_mark=0x3f0 actions=ct(commit, zone=64000, nat(src=192.168.1.10)", "ipv6, pkt_mark=0x3f0 actions=ct(commit, zone=64000, nat(src=fc00:f853:ccd:e793::20)"},
+		},
+		{
+			desc:          "uses the configured dual stack source IPs",
+			sourceIPs:     "192.168.1.20,fc00:f853:ccd:e793::20",
+			expectedSNATs: []string{"ip, pkt_mark=0x3f0 actions=ct(commit, zone=64000, nat(src=192.168.1.20)", "ipv6, pkt_mark=0x3f0 actions=ct(commit, zone=64000, nat(src=fc00:f853:ccd:e793::20)"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.IPv4Mode = true
+			config.IPv6Mode = true
+			config.Gateway.EgressSNATSourceIPs = tc.sourceIPs
+
+			bridge := &bridgeConfiguration{
+				bridgeName: "breth0",
+				ips: []*net.IPNet{
+					ovntest.MustParseIPNet("192.168.1.10/24"),
+					ovntest.MustParseIPNet("192.168.1.20/24"),
+					ovntest.MustParseIPNet("fc00:f853:ccd:e793::3/64"),
+					ovntest.MustParseIPNet("fc00:f853:ccd:e793::20/64"),
+				},
+				macAddress:  ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+				ofPortPatch: "patch-breth0_ov",
+				ofPortPhys:  "eth0",
+				ofPortHost:  "LOCAL",
+			}
+			if err := validateEgressSNATSourceIPs(bridge.ips); err != nil {
+				t.Fatal(err)
+			}
+			flows, err := commonFlows([]*net.IPNet{
+				ovntest.MustParseIPNet("10.128.0.0/23"),
+				ovntest.MustParseIPNet("fd00:10:244:1::/64"),
+			}, bridge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var snatFlows []string
+			for _, flow := range flows {
+				if strings.Contains(flow, "priority=105,") {
+					snatFlows = append(snatFlows, flow)
+				}
+			}
+			if len(snatFlows) != len(tc.expectedSNATs) {
+				t.Fatalf("expected %d egress SNAT flows, got %v", len(tc.expectedSNATs), snatFlows)
+			}
+			for i, expected := range tc.expectedSNATs {
+				if !strings.Contains(snatFlows[i], expected) {
+					t.Errorf("expected egress SNAT flow %q to contain %q", snatFlows[i], expected)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateEgressSNATSourceIPs(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.EgressSNATSourceIPs = "192.168.1.10,fc00:f853:ccd:e793::20"
+	err := validateEgressSNATSourceIPs([]*net.IPNet{
+		ovntest.MustParseIPNet("192.168.1.10/24"),
+		ovntest.MustParseIPNet("fc00:f853:ccd:e793::3/64"),
+	})
+	expected := "egress SNAT source IP fc00:f853:ccd:e793::20 is not configured on the gateway bridge"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestNodePortFlowsNodePortAddresses(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.DisableARPBypassFlows = true
+	config.Gateway.NodePortAddresses = "192.168.18.0/24,10.0.0.0/24,fd00:18::/64"
+	config.IPv4Mode = true
+	config.IPv6Mode = true
+
+	ports := []kapi.ServicePort{
+		{Name: "http", Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)},
+	}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, true, false)
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		gatewayIPv6: "fd00:18::15",
+		ofm: &openflowManager{
+			defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+			flowCache:     map[string][]string{},
+			lastSyncTime:  map[string]time.Time{},
+		},
+	}
+
+	tests := []struct {
+		desc                  string
+		hasLocalHostNetworkEp bool
+		actions               string
+	}{
+		{
+			desc:    "service traffic sent to OVN",
+			actions: "output:patch-breth0_ov",
+		},
+		{
+			desc:                  "ETP=local traffic sent to the local host networked endpoints",
+			hasLocalHostNetworkEp: true,
+			actions:               "ct(commit,zone=64003,nat(dst=",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := npw.updateServiceFlowCache(service, true, tc.hasLocalHostNetworkEp); err != nil {
+				t.Fatal(err)
+			}
+			expected := map[string][]string{
+				"tcp": {
+					"in_port=eth0, tcp, nw_dst=192.168.18.0/24, tp_dst=31111, actions=" + tc.actions,
+					"in_port=eth0, tcp, nw_dst=10.0.0.0/24, tp_dst=31111, actions=" + tc.actions,
+				},
+				"tcp6": {
+					"in_port=eth0, tcp6, ipv6_dst=fd00:18::/64, tp_dst=31111, actions=" + tc.actions,
+				},
+			}
+			for flowProtocol, expectedFlows := range expected {
+				var nodePortFlows []string
+				for _, flow := range npw.ofm.flowCache[serviceFlowCacheKey("NodePort", service.Namespace, service.Name, flowProtocol, "31111")] {
+					if strings.Contains(flow, "tp_dst=31111") {
+						nodePortFlows = append(nodePortFlows, flow)
+					}
+				}
+				if len(nodePortFlows) != len(expectedFlows) {
+					t.Fatalf("expected %d %s NodePort flows, got %v", len(expectedFlows), flowProtocol, nodePortFlows)
+				}
+				for i, expectedFlow := range expectedFlows {
+					if !strings.Contains(nodePortFlows[i], expectedFlow) {
+						t.Errorf("expected NodePort flow %q to contain %q", nodePortFlows[i], expectedFlow)
+					}
+				}
+			}
+		})
+	}
+
+	// no NodePort flow accepts the traffic of a family without a NodePort address
+	config.Gateway.NodePortAddresses = "192.168.18.0/24"
+	if err := npw.updateServiceFlowCache(service, true, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, flow := range npw.ofm.flowCache[serviceFlowCacheKey("NodePort", service.Namespace, service.Name, "tcp6", "31111")] {
+		if strings.Contains(flow, "tp_dst=31111") {
+			t.Errorf("unexpected IPv6 NodePort flow %q", flow)
+		}
+	}
+}
+
+func TestHostRepresentorUnavailableFlows(t *testing.T) {
+	tests := []struct {
+		action string
+		// flows expected in place of the ones outputting to the host representor
+		want []string
+		// matches of the flows expected to be held
+		held []string
+	}{
+		{
+			action: config.DPUHostRepresentorUnavailableActionHold,
+			held: []string{
+				"priority=10, table=0, in_port=eth0, dl_dst=0a:58:0a:01:01:01,",
+				"priority=10, table=1, dl_dst=0a:58:0a:01:01:01,",
+			},
+		},
+		{
+			action: config.DPUHostRepresentorUnavailableActionDrop,
+			want: []string{
+				"cookie=0xdeff105, priority=10, table=0, in_port=eth0, dl_dst=0a:58:0a:01:01:01, actions=output:patch-breth0_ov",
+				"cookie=0xdeff105, priority=10, table=1, dl_dst=0a:58:0a:01:01:01, actions=drop",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.IPv4Mode = true
+			config.OvnKubeNode.Mode = types.NodeModeDPU
+			config.OvnKubeNode.DPUHostRepresentorUnavailableAction = tt.action
+			config.Kubernetes.ServiceCIDRs = []*net.IPNet{ovntest.MustParseIPNet("172.16.1.0/24")}
+
+			bridge := &bridgeConfiguration{
+				bridgeName:      "breth0",
+				ips:             []*net.IPNet{ovntest.MustParseIPNet("192.168.1.10/24")},
+				macAddress:      ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+				ofPortPatch:     "patch-breth0_ov",
+				ofPortPhys:      "eth0",
+				ofPortHost:      ofPortHostUnavailable,
+				hostRepresentor: "pf0hpf",
+			}
+			ofm := &openflowManager{
+				defaultBridge: bridge,
+				flowCache:     map[string][]string{},
+			}
+			if err := ofm.updateBridgeFlowCache([]*net.IPNet{ovntest.MustParseIPNet("10.128.0.0/23")}, nil); err != nil {
+				t.Fatal(err)
+			}
+			flows := ofm.flowCache["DEFAULT"]
+			if len(flows) == 0 {
+				t.Fatal("expected the default flows")
+			}
+			renderedFlows := sets.NewString(flows...)
+			for _, flow := range flows {
+				if strings.Contains(flow, ofPortHostUnavailable) {
+					t.Errorf("unexpected flow referencing the unavailable host representor: %q", flow)
+				}
+			}
+			for _, flow := range tt.want {
+				if !renderedFlows.Has(flow) {
+					t.Errorf("expected flow %q, got:\n%s", flow, strings.Join(flows, "\n"))
+				}
+			}
+			for _, flow := range flows {
+				for _, match := range tt.held {
+					if strings.Contains(flow, match) {
+						t.Errorf("unexpected flow %q, expected it to be held", flow)
+					}
+				}
+			}
+
+			// the flows are regenerated with the ofport of the host representor once it is back
+			fexec := ovntest.NewFakeExec()
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-vsctl --timeout=15 get interface pf0hpf ofport", Output: "[]"})
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-vsctl --timeout=15 get interface pf0hpf ofport", Output: "9"})
+			if err := util.SetExec(fexec); err != nil {
+				t.Fatal(err)
+			}
+			if ofm.refreshHostRepresentorOfPorts() {
+				t.Error("expected the host representor to still be unavailable")
+			}
+			if !ofm.refreshHostRepresentorOfPorts() {
+				t.Error("expected the host representor to be back")
+			}
+			if !fexec.CalledMatchesExpected() {
+				t.Error(fexec.ErrorDesc())
+			}
+			if err := ofm.updateBridgeFlowCache([]*net.IPNet{ovntest.MustParseIPNet("10.128.0.0/23")}, nil); err != nil {
+				t.Fatal(err)
+			}
+			if !sets.NewString(ofm.flowCache["DEFAULT"]...).Has(
+				"cookie=0xdeff105, priority=10, table=1, dl_dst=0a:58:0a:01:01:01, actions=output:9") {
+				t.Errorf("expected the flows outputting to the host representor, got:\n%s",
+					strings.Join(ofm.flowCache["DEFAULT"], "\n"))
+			}
+		})
+	}
+}
+
+func TestCommonFlowsIPv6AdvertisementFlood(t *testing.T) {
+	tests := []struct {
+		desc          string
+		flood         string
+		expectedFlows []string
+	}{
+		{
+			desc:  "floods the advertisements by default",
+			flood: config.GatewayIPv6AdvertisementFlood,
+			expectedFlows: []string{
+				"cookie=0xdeff105, priority=14, table=1,icmp6,icmpv6_type=134 actions=FLOOD",
+				"cookie=0xdeff105, priority=14, table=1,icmp6,icmpv6_type=136 actions=FLOOD",
+			},
+		},
+		{
+			desc:  "restricts the advertisements to OVN and the host",
+			flood: config.GatewayIPv6AdvertisementRestrict,
+			expectedFlows: []string{
+				"cookie=0xdeff105, priority=14, table=1,icmp6,icmpv6_type=134 actions=output:patch-breth0_ov,output:LOCAL",
+				"cookie=0xdeff105, priority=14, table=1,icmp6,icmpv6_type=136 actions=output:patch-breth0_ov,output:LOCAL",
+			},
+		},
+		{
+			desc:  "does not flood the advertisements when disabled",
+			flood: config.GatewayIPv6AdvertisementDisable,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := config.PrepareTestConfig(); err != nil {
+				t.Fatal(err)
+			}
+			config.IPv4Mode = false
+			config.IPv6Mode = true
+			config.Gateway.IPv6AdvertisementFlood = tc.flood
+
+			bridge := &bridgeConfiguration{
+				bridgeName:  "breth0",
+				ips:         []*net.IPNet{ovntest.MustParseIPNet("fc00:f853:ccd:e793::3/64")},
+				macAddress:  ovntest.MustParseMAC("0a:58:0a:01:01:01"),
+				ofPortPatch: "patch-breth0_ov",
+				ofPortPhys:  "eth0",
+				ofPortHost:  "LOCAL",
+			}
+			flows, err := commonFlows([]*net.IPNet{ovntest.MustParseIPNet("fd00:10:244:1::/64")}, bridge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var icmpFlows []string
+			for _, flow := range flows {
+				if strings.Contains(flow, "icmpv6_type=") {
+					icmpFlows = append(icmpFlows, flow)
+				}
+			}
+			if !reflect.DeepEqual(icmpFlows, tc.expectedFlows) {
+				t.Errorf("expected the ICMPv6 advertisement flows %v, got %v", tc.expectedFlows, icmpFlows)
+			}
+		})
+	}
+}
+
+func TestHealthCheckNodePortFlows(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.DisableARPBypassFlows = true
+	config.IPv4Mode = true
+	config.IPv6Mode = true
+
+	ports := []kapi.ServicePort{
+		{Name: "http", Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)},
+	}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeLoadBalancer,
+		nil, kapi.ServiceStatus{}, true, false)
+	service.Spec.HealthCheckNodePort = 32222
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		gatewayIPv6: "fd00:18::15",
+		ofm: &openflowManager{
+			defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+			flowCache:     map[string][]string{},
+			lastSyncTime:  map[string]time.Time{},
+		},
+	}
+	key := serviceFlowCacheKey("HealthCheckNodePort", service.Namespace, service.Name, "32222")
+
+	// the health checks are not restricted by default
+	if err := npw.updateServiceFlowCache(service, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if flows, ok := npw.ofm.flowCache[key]; ok {
+		t.Fatalf("unexpected health check flows %v", flows)
+	}
+
+	// the IPv4 health checks are restricted to the configured address, the IPv6 ones are not
+	config.Gateway.HealthCheckNodePortAddresses = "192.168.18.15"
+	if err := npw.updateServiceFlowCache(service, true, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"priority=111, in_port=eth0, tcp, nw_dst=192.168.18.15, tp_dst=32222, actions=ct(zone=64000, nat, table=1)",
+		"priority=110, in_port=eth0, tcp, tp_dst=32222, actions=drop",
+	}
+	flows := npw.ofm.flowCache[key]
+	if len(flows) != len(expected) {
+		t.Fatalf("expected %d health check flows, got %v", len(expected), flows)
+	}
+	for i, expectedFlow := range expected {
+		if !strings.Contains(flows[i], expectedFlow) {
+			t.Errorf("expected health check flow %q to contain %q", flows[i], expectedFlow)
+		}
+	}
+
+	// the IPv6 health checks are restricted too once an IPv6 address is configured
+	config.Gateway.HealthCheckNodePortAddresses = "192.168.18.15,fd00:18::15"
+	if err := npw.updateServiceFlowCache(service, true, false); err != nil {
+		t.Fatal(err)
+	}
+	expected = append(expected,
+		"priority=111, in_port=eth0, tcp6, ipv6_dst=fd00:18::15, tp_dst=32222, actions=ct(zone=64000, nat, table=1)",
+		"priority=110, in_port=eth0, tcp6, tp_dst=32222, actions=drop",
+	)
+	flows = npw.ofm.flowCache[key]
+	if len(flows) != len(expected) {
+		t.Fatalf("expected %d health check flows, got %v", len(expected), flows)
+	}
+	for i, expectedFlow := range expected {
+		if !strings.Contains(flows[i], expectedFlow) {
+			t.Errorf("expected health check flow %q to contain %q", flows[i], expectedFlow)
+		}
+	}
+
+	// the flows are deleted with the service
+	if err := npw.updateServiceFlowCache(service, false, false); err != nil {
+		t.Fatal(err)
+	}
+	if flows, ok := npw.ofm.flowCache[key]; ok {
+		t.Errorf("unexpected health check flows %v after the service deletion", flows)
+	}
+}
+
+func TestServiceFlowsStableOrder(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.DisableARPBypassFlows = true
+	config.IPv4Mode = true
+	config.IPv6Mode = true
+
+	ports := []kapi.ServicePort{
+		{Name: "http", Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)},
+		{Name: "dns", Port: 53, Protocol: kapi.ProtocolUDP, NodePort: 31112, TargetPort: intstr.FromInt(5353)},
+	}
+	status := kapi.ServiceStatus{
+		LoadBalancer: kapi.LoadBalancerStatus{
+			Ingress: []kapi.LoadBalancerIngress{{IP: "5.5.5.5"}, {IP: "fd00:5::5"}},
+		},
+	}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"1.1.1.1", "fd00:1::1"}, status, true, false)
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		gatewayIPv6: "fd00:18::15",
+		ofm: &openflowManager{
+			defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+			flowCache:     map[string][]string{},
+			lastSyncTime:  map[string]time.Time{},
+		},
+	}
+	if err := npw.updateServiceFlowCache(service, true, false); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for key := range npw.ofm.flowCache {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var flows, reversed []string
+	for _, key := range keys {
+		flows = append(flows, npw.ofm.flowCache[key]...)
+	}
+	if len(flows) == 0 {
+		t.Fatal("expected service flows to be generated")
+	}
+	for i := len(flows) - 1; i >= 0; i-- {
+		reversed = append(reversed, flows[i])
+	}
+
+	// the flows are sorted the same whatever the order they were cached in
+	sortFlows(flows)
+	sortFlows(reversed)
+	if !reflect.DeepEqual(flows, reversed) {
+		t.Fatalf("expected the flows to be sorted the same, got:\n%s\nand:\n%s",
+			strings.Join(flows, "\n"), strings.Join(reversed, "\n"))
+	}
+	// by table and by decreasing priority
+	for i := 1; i < len(flows); i++ {
+		previous, current := getFlowSortKey(flows[i-1]), getFlowSortKey(flows[i])
+		if previous.table > current.table ||
+			previous.table == current.table && previous.priority < current.priority {
+			t.Errorf("expected flow %q to be sorted before flow %q", flows[i], flows[i-1])
+		}
+	}
+}
+
+func TestExGWBridgeServiceFlows(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.DisableARPBypassFlows = true
+	config.IPv4Mode = true
+
+	ports := []kapi.ServicePort{
+		{Name: "http", Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)},
+	}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeLoadBalancer,
+		[]string{"1.1.1.1"}, kapi.ServiceStatus{LoadBalancer: kapi.LoadBalancerStatus{
+			Ingress: []kapi.LoadBalancerIngress{{IP: "5.5.5.5"}},
+		}}, false, false)
+	service.Annotations = map[string]string{ovnExGWBridgeAnnotation: "true"}
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		ofm: &openflowManager{
+			defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+			externalGatewayBridge: &bridgeConfiguration{
+				bridgeName:  "breth1",
+				ofPortPhys:  "eth1",
+				ofPortPatch: "patch-breth1_ov",
+			},
+			flowCache:     map[string][]string{},
+			exGWFlowCache: map[string][]string{},
+			lastSyncTime:  map[string]time.Time{},
+		},
+	}
+	externalKey := serviceFlowCacheKey("External", "namespace1", "service1", "1.1.1.1", "tcp", "8080")
+	ingressKey := serviceFlowCacheKey("Ingress", "namespace1", "service1", "5.5.5.5", "tcp", "8080")
+
+	// the flows of the service are only programmed on the default bridge by default
+	if err := npw.updateServiceFlowCache(service, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(npw.ofm.exGWFlowCache) > 0 {
+		t.Fatalf("unexpected external gateway bridge flows %v", npw.ofm.exGWFlowCache)
+	}
+
+	// and on the external gateway bridge too once enabled, with its ofports
+	config.Gateway.ExGWBridgeServices = true
+	if err := npw.updateServiceFlowCache(service, true, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		exGWBridgeFlowCacheKey("breth1", externalKey): {
+			"priority=110, in_port=eth1, tcp, nw_dst=1.1.1.1, tp_dst=8080, actions=output:patch-breth1_ov",
+			"priority=110, in_port=patch-breth1_ov, tcp, nw_src=1.1.1.1, tp_src=8080, actions=output:eth1",
+		},
+		exGWBridgeFlowCacheKey("breth1", ingressKey): {
+			"priority=110, in_port=eth1, tcp, nw_dst=5.5.5.5, tp_dst=8080, actions=output:patch-breth1_ov",
+			"priority=110, in_port=patch-breth1_ov, tcp, nw_src=5.5.5.5, tp_src=8080, actions=output:eth1",
+		},
+	}
+	if len(npw.ofm.exGWFlowCache) != len(expected) {
+		t.Fatalf("expected the external gateway bridge flows of %d keys, got %v", len(expected), npw.ofm.exGWFlowCache)
+	}
+	for key, expectedFlows := range expected {
+		flows := npw.ofm.exGWFlowCache[key]
+		if len(flows) != len(expectedFlows) {
+			t.Fatalf("expected %d flows under key %s, got %v", len(expectedFlows), key, flows)
+		}
+		for i, expectedFlow := range expectedFlows {
+			if !strings.Contains(flows[i], expectedFlow) {
+				t.Errorf("expected flow %q to contain %q", flows[i], expectedFlow)
+			}
+		}
+	}
+	// the flows of the default bridge still use its own ofports
+	for _, key := range []string{externalKey, ingressKey} {
+		for _, flow := range npw.ofm.flowCache[key] {
+			if strings.Contains(flow, "eth1") || strings.Contains(flow, "breth1") {
+				t.Errorf("unexpected external gateway bridge ofport in default bridge flow %q", flow)
+			}
+		}
+	}
+
+	// the flows are deleted with the service
+	if err := npw.updateServiceFlowCache(service, false, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(npw.ofm.exGWFlowCache) > 0 {
+		t.Errorf("unexpected external gateway bridge flows %v after the service delete", npw.ofm.exGWFlowCache)
+	}
+}
+
+// endpointSliceErrorWatchFactory fails to get the endpointslices of one service
+type endpointSliceErrorWatchFactory struct {
+	factory.NodeWatchFactory
+	failingService string
+}
+
+func (f *endpointSliceErrorWatchFactory) GetEndpointSlices(namespace, svcName string) ([]*discovery.EndpointSlice, error) {
+	if svcName == f.failingService {
+		return nil, fmt.Errorf("stale informer cache")
+	}
+	return []*discovery.EndpointSlice{}, nil
+}
+
+func TestSyncServicesContinueOnError(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+
+	ports := []kapi.ServicePort{
+		{Name: "http", Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)},
+	}
+	service1 := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, false, false)
+	service2 := newService("service2", "namespace1", "10.96.0.11", ports, kapi.ServiceTypeNodePort,
+		nil, kapi.ServiceStatus{}, false, false)
+	service2.Spec.Ports[0].NodePort = 31112
+	newNodePortWatcher := func() *nodePortWatcher {
+		return &nodePortWatcher{
+			dpuMode:       true,
+			ofportPhys:    "eth0",
+			ofportPatch:   "patch-breth0_ov",
+			gatewayIPv4:   "192.168.18.15",
+			serviceInfo:   map[ktypes.NamespacedName]*serviceConfig{},
+			nodeIPManager: &addressManager{addresses: sets.New("192.168.18.15")},
+			watchFactory:  &endpointSliceErrorWatchFactory{failingService: "service1"},
+			ofm: &openflowManager{
+				defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+				flowCache:     map[string][]string{},
+				flowChan:      make(chan struct{}, 1),
+			},
+		}
+	}
+	hasFlows := func(npw *nodePortWatcher, name string) bool {
+		for key, flows := range npw.ofm.flowCache {
+			if strings.Contains(key, "_namespace1_"+name+"_") && len(flows) > 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	// by default the sync is aborted on the service whose endpointslices cannot be retrieved
+	npw := newNodePortWatcher()
+	err := npw.SyncServices([]interface{}{service1, service2})
+	if err == nil || !strings.Contains(err.Error(), "stale informer cache") {
+		t.Fatalf("expected the endpointslice error, got %v", err)
+	}
+	if hasFlows(npw, "service2") {
+		t.Errorf("unexpected flows of service2 after an aborted sync")
+	}
+
+	// the other services are still programmed once enabled, and the error returned
+	config.Gateway.SyncServicesContinueOnError = true
+	npw = newNodePortWatcher()
+	err = npw.SyncServices([]interface{}{service1, service2})
+	if err == nil || !strings.Contains(err.Error(), "endpointslices for service namespace1/service1") {
+		t.Fatalf("expected the endpointslice error of service1, got %v", err)
+	}
+	if !hasFlows(npw, "service2") {
+		t.Errorf("expected the flows of service2 to be programmed, got %v", npw.ofm.flowCache)
+	}
+	if _, ok := npw.getServiceInfo(ktypes.NamespacedName{Namespace: "namespace1", Name: "service1"}); ok {
+		t.Errorf("unexpected service info of service1")
+	}
+}
+
+func BenchmarkLoadBalancerIngressFlows(b *testing.B) {
+	if err := config.PrepareTestConfig(); err != nil {
+		b.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.IPv4Mode = true
+
+	ports := []kapi.ServicePort{
+		{Name: "http", Port: 80, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)},
+		{Name: "https", Port: 443, Protocol: kapi.ProtocolTCP, NodePort: 31112, TargetPort: intstr.FromInt(8443)},
+	}
+	ingress := []kapi.LoadBalancerIngress{}
+	for i := 1; i <= 20; i++ {
+		ingress = append(ingress, kapi.LoadBalancerIngress{IP: fmt.Sprintf("5.5.5.%d", i)})
+	}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeLoadBalancer,
+		nil, kapi.ServiceStatus{LoadBalancer: kapi.LoadBalancerStatus{Ingress: ingress}}, false, false)
+	npw := &nodePortWatcher{
+		ofportPhys:  "1",
+		ofportPatch: "2",
+		gatewayIPv4: "192.168.18.15",
+		gwBridge:    "breth0",
+		ofm: &openflowManager{
+			defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+			flowCache:     map[string][]string{},
+			flowChan:      make(chan struct{}, 1),
+		},
+	}
+	showOutput := " 1(eth0): addr:00:00:00:00:00:01\n 2(patch-breth0_ov): addr:00:00:00:00:00:02\n LOCAL(breth0): addr:00:00:00:00:00:03\n"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		// the bridge ports are listed once for all the ingress IPs and ports
+		fexec := ovntest.NewFakeExec()
+		fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl show breth0", Output: showOutput})
+		if err := util.SetExec(fexec); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if err := npw.updateServiceFlowCache(service, true, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
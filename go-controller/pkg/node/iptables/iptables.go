@@ -17,6 +17,16 @@ type Rule struct {
 	Protocol iptables.Protocol
 }
 
+// String renders the rule as the iptables or ip6tables command appending it,
+// e.g. "iptables -t nat -A OVN-KUBE-NODEPORT -p TCP ... -j DNAT ...".
+func (r Rule) String() string {
+	command := "iptables"
+	if r.Protocol == iptables.ProtocolIPv6 {
+		command = "ip6tables"
+	}
+	return strings.Join(append([]string{command, "-t", r.Table, "-A", r.Chain}, r.Args...), " ")
+}
+
 // AddRules adds the given rules to iptables.
 func AddRules(rules []Rule, append bool) error {
 	addErrors := errors.New("")
@@ -1,38 +1,263 @@
 package node
 
 import (
+	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/metrics"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 	"github.com/pkg/errors"
 
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 )
 
+// flowSyncDrainTimeout is the time a flow sync pending when the flow sync loop
+// is stopped is waited for
+const flowSyncDrainTimeout = 5 * time.Second
+
+// flowDiffLogLevel is the verbosity from which the difference between the
+// flows of the bridges and the expected ones is logged on startup
+const flowDiffLogLevel = 5
+
+// maxFlowLength is the maximum length of a flow or group string handed to
+// ovs-ofctl. OpenFlow messages are limited to 64KB: a longer string, like the
+// group of a service with many endpoints, cannot be encoded in one and would
+// fail the replacement of all the flows of the bridge.
+const maxFlowLength = 65535
+
+// flowSyncWatchdogTimeout is the time after which a flow sync requested but
+// not picked up by the flow sync loop is reported as stalled
+const flowSyncWatchdogTimeout = time.Minute
+
 type openflowManager struct {
 	defaultBridge         *bridgeConfiguration
 	externalGatewayBridge *bridgeConfiguration
 	// flow cache, use map instead of array for readability when debugging
-	flowCache     map[string][]string
+	flowCache map[string][]string
+	// group cache of the default bridge, the group cached under a key is
+	// referenced by the flows cached under the same key. Protected by
+	// flowMutex.
+	groupCache map[string]string
+	// group IDs allocated to the keys of the group cache, kept while the
+	// key has flows so that the ID referenced by them stays stable.
+	// Protected by flowMutex.
+	groupIDs map[string]uint32
+	// groupsSynced is set once groups were programmed on the default
+	// bridge, from which point they are replaced on each flow sync so that
+	// the stale ones are removed. Protected by flowMutex.
+	groupsSynced  bool
 	flowMutex     sync.Mutex
 	exGWFlowCache map[string][]string
 	exGWFlowMutex sync.Mutex
 	// channel to indicate we need to update flows immediately
 	flowChan chan struct{}
+	// flowSyncRequestedAt is the time the flow sync pending on flowChan was
+	// requested, zero if none is pending. flowSyncStallReported is set once
+	// the pending sync was reported as stalled by the watchdog. Both are
+	// protected by flowSyncRequestLock.
+	flowSyncRequestedAt   time.Time
+	flowSyncStallReported bool
+	flowSyncRequestLock   sync.Mutex
+	// frozen is set while the flow cache is frozen for debugging, see
+	// SetFrozen, and frozenSyncPending once a flow sync was requested in the
+	// meantime. Both are protected by flowSyncRequestLock.
+	frozen            bool
+	frozenSyncPending bool
+	// flowSyncWatchdogTimeout overrides flowSyncWatchdogTimeout if set
+	flowSyncWatchdogTimeout time.Duration
+	// onFlowSyncStalled, if set, is called when the watchdog reports a
+	// pending flow sync as stalled, with the time it has been pending for
+	onFlowSyncStalled func(pending time.Duration)
+	// time of the last successful flow sync of each bridge
+	lastSyncTime     map[string]time.Time
+	lastSyncTimeLock sync.Mutex
+	// onPortsChecked, if set, is called on each periodic flow sync once the
+	// bridge ports have been checked
+	onPortsChecked func()
+	// onHostRepresentorAvailable, if set, is called on each periodic flow sync
+	// once the DPU host representor of a bridge is back, to regenerate the
+	// flows held or dropped while it had no valid ofport
+	onHostRepresentorAvailable func()
+	// patchPortDown is set while the link of the default bridge patch port is
+	// down, the default bridge flows are held until it comes back up.
+	// Protected by flowMutex.
+	patchPortDown bool
+	// disableSNATMultipleGWs is the config.Gateway.DisableSNATMultipleGWs the
+	// bridge flows were generated with at startup. The setting is not reloaded
+	// at runtime, changing it requires a restart.
+	disableSNATMultipleGWs bool
 }
 
+// updateFlowCacheEntry caches the flows under key, replacing the flows and
+// the group previously cached under it
 func (c *openflowManager) updateFlowCacheEntry(key string, flows []string) {
 	c.flowMutex.Lock()
 	defer c.flowMutex.Unlock()
 	c.flowCache[key] = flows
+	c.deleteGroupLocked(key)
+}
+
+// updateFlowCacheEntryWithGroup caches the flows under key along with the
+// group they reference, which must have been allocated with getGroupID
+func (c *openflowManager) updateFlowCacheEntryWithGroup(key string, flows []string, group string) {
+	c.flowMutex.Lock()
+	defer c.flowMutex.Unlock()
+	c.flowCache[key] = flows
+	if c.groupCache == nil {
+		c.groupCache = map[string]string{}
+	}
+	c.groupCache[key] = group
+}
+
+// getGroupID returns the group ID of key, allocating the lowest unused one
+// if the key has none yet
+func (c *openflowManager) getGroupID(key string) uint32 {
+	c.flowMutex.Lock()
+	defer c.flowMutex.Unlock()
+	if groupID, ok := c.groupIDs[key]; ok {
+		return groupID
+	}
+	if c.groupIDs == nil {
+		c.groupIDs = map[string]uint32{}
+	}
+	used := make(map[uint32]bool, len(c.groupIDs))
+	for _, groupID := range c.groupIDs {
+		used[groupID] = true
+	}
+	groupID := uint32(1)
+	for used[groupID] {
+		groupID++
+	}
+	c.groupIDs[key] = groupID
+	return groupID
+}
+
+// deleteGroupLocked deletes the group cached under key and releases its ID,
+// must be called with flowMutex held
+func (c *openflowManager) deleteGroupLocked(key string) {
+	delete(c.groupCache, key)
+	delete(c.groupIDs, key)
 }
 
 func (c *openflowManager) deleteFlowsByKey(key string) {
 	c.flowMutex.Lock()
 	defer c.flowMutex.Unlock()
 	delete(c.flowCache, key)
+	c.deleteGroupLocked(key)
+}
+
+// deleteFlowsByKeyFunc deletes the flows of all the keys for which fn returns
+// true
+func (c *openflowManager) deleteFlowsByKeyFunc(fn func(key string) bool) {
+	c.flowMutex.Lock()
+	defer c.flowMutex.Unlock()
+	for key := range c.flowCache {
+		if fn(key) {
+			klog.V(5).Infof("Deleting flows cached under key %s", key)
+			delete(c.flowCache, key)
+			c.deleteGroupLocked(key)
+		}
+	}
+}
+
+// getFlowCacheBridge returns the name of the bridge whose flow cache holds the
+// flows of key, the default or the external gateway bridge, and false if none
+// does
+func (c *openflowManager) getFlowCacheBridge(key string) (string, bool) {
+	c.flowMutex.Lock()
+	_, ok := c.flowCache[key]
+	c.flowMutex.Unlock()
+	if ok {
+		return c.defaultBridge.bridgeName, true
+	}
+	if c.externalGatewayBridge == nil {
+		return "", false
+	}
+	c.exGWFlowMutex.Lock()
+	defer c.exGWFlowMutex.Unlock()
+	if _, ok := c.exGWFlowCache[key]; ok {
+		return c.externalGatewayBridge.bridgeName, true
+	}
+	return "", false
+}
+
+// ofPortReference is a key of the flow cache of a bridge whose flows or group
+// reference an ofport
+type ofPortReference struct {
+	bridgeName string
+	key        string
+	// flows holds the flows of the key that reference the ofport
+	flows []string
+	// group is the group of the key if it references the ofport
+	group string
+}
+
+// getOfPortReferences returns the keys of the flow caches whose flows or group
+// match on or output to ofport, sorted by bridge and key. Used to audit which
+// flows are resynced when an ofport changes and to debug stale ofports.
+func (c *openflowManager) getOfPortReferences(ofport string) []ofPortReference {
+	var references []ofPortReference
+	c.flowMutex.Lock()
+	for key, flows := range c.flowCache {
+		reference := ofPortReference{bridgeName: c.defaultBridge.bridgeName, key: key}
+		for _, flow := range flows {
+			if flowReferencesOfPort(flow, ofport) {
+				reference.flows = append(reference.flows, flow)
+			}
+		}
+		if group := c.groupCache[key]; flowReferencesOfPort(group, ofport) {
+			reference.group = group
+		}
+		if len(reference.flows) > 0 || reference.group != "" {
+			references = append(references, reference)
+		}
+	}
+	c.flowMutex.Unlock()
+
+	if c.externalGatewayBridge != nil {
+		c.exGWFlowMutex.Lock()
+		for key, flows := range c.exGWFlowCache {
+			reference := ofPortReference{bridgeName: c.externalGatewayBridge.bridgeName, key: key}
+			for _, flow := range flows {
+				if flowReferencesOfPort(flow, ofport) {
+					reference.flows = append(reference.flows, flow)
+				}
+			}
+			if len(reference.flows) > 0 {
+				references = append(references, reference)
+			}
+		}
+		c.exGWFlowMutex.Unlock()
+	}
+
+	sort.Slice(references, func(i, j int) bool {
+		if references[i].bridgeName != references[j].bridgeName {
+			return references[i].bridgeName < references[j].bridgeName
+		}
+		return references[i].key < references[j].key
+	})
+	return references
+}
+
+// flowReferencesOfPort returns true if the flow or group matches on ofport with
+// in_port or outputs to it
+func flowReferencesOfPort(flow, ofport string) bool {
+	for _, field := range strings.FieldsFunc(flow, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if field == "in_port="+ofport {
+			return true
+		}
+		// strip the actions= or bucket=actions= prefix of the first action
+		if field[strings.LastIndex(field, "=")+1:] == "output:"+ofport {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *openflowManager) updateExBridgeFlowCacheEntry(key string, flows []string) {
@@ -41,15 +266,132 @@ func (c *openflowManager) updateExBridgeFlowCacheEntry(key string, flows []strin
 	c.exGWFlowCache[key] = flows
 }
 
+// deleteExBridgeFlowsByKey deletes the flows cached under key for the
+// external gateway bridge
+func (c *openflowManager) deleteExBridgeFlowsByKey(key string) {
+	c.exGWFlowMutex.Lock()
+	defer c.exGWFlowMutex.Unlock()
+	delete(c.exGWFlowCache, key)
+}
+
 func (c *openflowManager) requestFlowSync() {
+	c.flowSyncRequestLock.Lock()
+	defer c.flowSyncRequestLock.Unlock()
+	c.requestFlowSyncLocked()
+}
+
+// requestFlowSyncLocked requests a flow sync, must be called with
+// flowSyncRequestLock held
+func (c *openflowManager) requestFlowSyncLocked() {
+	if c.frozen {
+		c.frozenSyncPending = true
+		klog.V(5).Infof("Gateway OpenFlow sync requested while the flows are frozen, deferring it")
+		return
+	}
 	select {
 	case c.flowChan <- struct{}{}:
+		if c.flowSyncRequestedAt.IsZero() {
+			c.flowSyncRequestedAt = time.Now()
+		}
 		klog.V(5).Infof("Gateway OpenFlow sync requested")
 	default:
 		klog.V(5).Infof("Gateway OpenFlow sync already requested")
 	}
 }
 
+// SetFrozen freezes or thaws the flows of the bridges, for debugging. While
+// frozen, the flow syncs are deferred so that the flows can be experimented
+// with, e.g. with ovs-ofctl, without being replaced; the flow cache is still
+// updated. On thaw, a single flow sync applies its latest state if any sync
+// was deferred.
+func (c *openflowManager) SetFrozen(frozen bool) {
+	c.flowSyncRequestLock.Lock()
+	defer c.flowSyncRequestLock.Unlock()
+	if c.frozen == frozen {
+		return
+	}
+	c.frozen = frozen
+	if frozen {
+		klog.Warningf("Gateway OpenFlow flows frozen, the flow syncs are deferred until thawed")
+		return
+	}
+	klog.Infof("Gateway OpenFlow flows thawed")
+	if c.frozenSyncPending {
+		c.frozenSyncPending = false
+		c.requestFlowSyncLocked()
+	}
+}
+
+// deferFlowSyncIfFrozen returns true, recording a deferred flow sync, if the
+// flows are frozen
+func (c *openflowManager) deferFlowSyncIfFrozen() bool {
+	c.flowSyncRequestLock.Lock()
+	defer c.flowSyncRequestLock.Unlock()
+	if c.frozen {
+		c.frozenSyncPending = true
+	}
+	return c.frozen
+}
+
+// flowSyncPickedUp is called by the flow sync loop once it received a sync
+// request from flowChan. A request sent in the meantime stays pending and is
+// timed from now on.
+func (c *openflowManager) flowSyncPickedUp() {
+	c.flowSyncRequestLock.Lock()
+	defer c.flowSyncRequestLock.Unlock()
+	c.flowSyncRequestedAt = time.Time{}
+	if len(c.flowChan) > 0 {
+		c.flowSyncRequestedAt = time.Now()
+	}
+	c.flowSyncStallReported = false
+}
+
+// checkFlowSyncStalled reports the pending flow sync as stalled if it was not
+// picked up by the flow sync loop within timeout, which happens when the loop
+// is wedged, e.g. on a blocking OVS call. A stall is reported once. Returns
+// true if it was reported.
+func (c *openflowManager) checkFlowSyncStalled(timeout time.Duration) bool {
+	c.flowSyncRequestLock.Lock()
+	defer c.flowSyncRequestLock.Unlock()
+	if c.flowSyncRequestedAt.IsZero() || c.flowSyncStallReported {
+		return false
+	}
+	pending := time.Since(c.flowSyncRequestedAt)
+	if pending < timeout {
+		return false
+	}
+	c.flowSyncStallReported = true
+	klog.Errorf("Gateway OpenFlow sync requested %v ago was not picked up yet, "+
+		"the flow sync loop may be stuck and service updates are not applied", pending.Round(time.Second))
+	metrics.MetricGatewayFlowSyncStalls.Inc()
+	if c.onFlowSyncStalled != nil {
+		c.onFlowSyncStalled(pending)
+	}
+	return true
+}
+
+// runFlowSyncWatchdog checks for a stalled flow sync until stopChan is closed
+func (c *openflowManager) runFlowSyncWatchdog(stopChan <-chan struct{}, doneWg *sync.WaitGroup) {
+	timeout := c.flowSyncWatchdogTimeout
+	if timeout == 0 {
+		timeout = flowSyncWatchdogTimeout
+	}
+	doneWg.Add(1)
+	go func() {
+		defer doneWg.Done()
+		ticker := time.NewTicker(timeout / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.checkFlowSyncStalled(timeout)
+			case <-stopChan:
+				return
+			}
+		}
+	}()
+}
+
 func (c *openflowManager) syncFlows() {
 	// protect gwBridge config from being updated by gw.nodeIPManager
 	c.defaultBridge.Lock()
@@ -58,35 +400,348 @@ func (c *openflowManager) syncFlows() {
 	c.flowMutex.Lock()
 	defer c.flowMutex.Unlock()
 
+	flows, invalidKeys := validFlowCacheFlows(c.defaultBridge.bridgeName, c.flowCache, c.groupCache)
+	sortFlows(flows)
+
+	if c.patchPortDown {
+		// flows towards the patch port would be black-holed, keep the ones
+		// already programmed until the port is back up
+		klog.Warningf("Holding the flows of bridge %s while its patch port %s is down",
+			c.defaultBridge.bridgeName, c.defaultBridge.patchPort)
+	} else if err := c.syncGroups(invalidKeys); err != nil {
+		// the flows referencing a missing group would be rejected
+		klog.Errorf("Failed to replace groups, error: %v, groups: %s", err, c.groupCache)
+	} else if _, stderr, err := util.ReplaceOFFlows(c.defaultBridge.bridgeName, flows); err != nil {
+		klog.Errorf("Failed to add flows, error: %v, stderr, %s, flows: %s", err, stderr, c.flowCache)
+	} else {
+		c.setLastSyncTime(c.defaultBridge.bridgeName)
+	}
+
+	c.syncExBridgeFlows()
+}
+
+// syncExBridgeFlows replaces the flows of the external gateway bridge, if
+// any, with the cached ones without touching the ones of the default bridge
+func (c *openflowManager) syncExBridgeFlows() {
+	if c.externalGatewayBridge == nil {
+		return
+	}
+	c.exGWFlowMutex.Lock()
+	defer c.exGWFlowMutex.Unlock()
+
+	flows, _ := validFlowCacheFlows(c.externalGatewayBridge.bridgeName, c.exGWFlowCache, nil)
+	sortFlows(flows)
+
+	_, stderr, err := util.ReplaceOFFlows(c.externalGatewayBridge.bridgeName, flows)
+	if err != nil {
+		klog.Errorf("Failed to add flows, error: %v, stderr, %s, flows: %s", err, stderr, c.exGWFlowCache)
+	} else {
+		c.setLastSyncTime(c.externalGatewayBridge.bridgeName)
+	}
+}
+
+// syncGroups replaces the groups of the default bridge with the cached ones,
+// ahead of the flows that reference them, but the groups of the given keys.
+// Groups are only replaced once some were cached, must be called with
+// flowMutex held.
+func (c *openflowManager) syncGroups(skippedKeys sets.Set[string]) error {
+	if len(c.groupCache) == 0 && !c.groupsSynced {
+		return nil
+	}
+	groups := make([]string, 0, len(c.groupCache))
+	for key, group := range c.groupCache {
+		if skippedKeys.Has(key) {
+			continue
+		}
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	if _, stderr, err := util.ReplaceOFGroups(c.defaultBridge.bridgeName, groups); err != nil {
+		return fmt.Errorf("%v, stderr: %s", err, stderr)
+	}
+	c.groupsSynced = true
+	return nil
+}
+
+// validateFlowCacheEntry returns an error naming the flow cache key if one
+// of its flows, or its group if any, is longer than maxFlowLength
+func validateFlowCacheEntry(key string, flows []string, group string) error {
+	for _, flow := range flows {
+		if len(flow) > maxFlowLength {
+			return fmt.Errorf("a flow of key %s is %d characters long, more than the maximum of %d: %.200s...",
+				key, len(flow), maxFlowLength, flow)
+		}
+	}
+	if len(group) > maxFlowLength {
+		return fmt.Errorf("the group of key %s is %d characters long, more than the maximum of %d: %.200s...",
+			key, len(group), maxFlowLength, group)
+	}
+	return nil
+}
+
+// validFlowCacheFlows returns the flows of the given flow cache of the bridge
+// whose key has no flow nor group longer than maxFlowLength, along with the
+// keys whose flows were left out. These are logged so that a single key does
+// not fail the replacement of all the flows of the bridge.
+func validFlowCacheFlows(bridgeName string, flowCache map[string][]string, groupCache map[string]string) ([]string, sets.Set[string]) {
 	flows := []string{}
-	for _, entry := range c.flowCache {
+	invalidKeys := sets.New[string]()
+	for key, entry := range flowCache {
+		if err := validateFlowCacheEntry(key, entry, groupCache[key]); err != nil {
+			klog.Errorf("Skipping the flows of key %s on bridge %s: %v", key, bridgeName, err)
+			invalidKeys.Insert(key)
+			continue
+		}
 		flows = append(flows, entry...)
 	}
+	return flows, invalidKeys
+}
 
-	_, stderr, err := util.ReplaceOFFlows(c.defaultBridge.bridgeName, flows)
-	if err != nil {
-		klog.Errorf("Failed to add flows, error: %v, stderr, %s, flows: %s", err, stderr, c.flowCache)
+// logFlowDiff logs, for each bridge, the flows about to be applied that are
+// missing from the bridge and the flows of the bridge about to be removed.
+// Used on startup to debug the drift introduced by a previous version or by
+// manual edits, only at a high verbosity as it dumps all the bridge flows.
+func (c *openflowManager) logFlowDiff() {
+	if !klog.V(flowDiffLogLevel).Enabled() {
+		return
+	}
+	c.flowMutex.Lock()
+	expected := []string{}
+	for _, entry := range c.flowCache {
+		expected = append(expected, entry...)
 	}
+	c.flowMutex.Unlock()
+	logBridgeFlowDiff(c.defaultBridge.bridgeName, expected)
 
 	if c.externalGatewayBridge != nil {
 		c.exGWFlowMutex.Lock()
-		defer c.exGWFlowMutex.Unlock()
-
-		flows := []string{}
+		expected := []string{}
 		for _, entry := range c.exGWFlowCache {
-			flows = append(flows, entry...)
+			expected = append(expected, entry...)
+		}
+		c.exGWFlowMutex.Unlock()
+		logBridgeFlowDiff(c.externalGatewayBridge.bridgeName, expected)
+	}
+}
+
+func logBridgeFlowDiff(bridgeName string, expected []string) {
+	current, err := util.GetOFFlows(bridgeName)
+	if err != nil {
+		klog.Warningf("Failed to get the flows of bridge %s to compare them with the expected ones: %v", bridgeName, err)
+		return
+	}
+	added, removed := flowDiff(current, expected)
+	if len(added) == 0 && len(removed) == 0 {
+		klog.V(flowDiffLogLevel).Infof("The flows of bridge %s match the expected ones", bridgeName)
+		return
+	}
+	klog.V(flowDiffLogLevel).Infof("The flows of bridge %s differ from the expected ones, flows to be added:\n%s\nflows to be removed:\n%s",
+		bridgeName, strings.Join(added, "\n"), strings.Join(removed, "\n"))
+}
+
+// flowDiff returns the expected flows missing from the current ones and the
+// current flows missing from the expected ones. Flows are compared in their
+// normalized form, as OVS reports them differently than they are written.
+func flowDiff(current, expected []string) (added, removed []string) {
+	currentFlows := map[string]string{}
+	for _, flow := range current {
+		currentFlows[normalizeFlow(flow)] = flow
+	}
+	expectedFlows := map[string]string{}
+	for _, flow := range expected {
+		expectedFlows[normalizeFlow(flow)] = flow
+	}
+	for normalized, flow := range expectedFlows {
+		if _, ok := currentFlows[normalized]; !ok {
+			added = append(added, flow)
+		}
+	}
+	for normalized, flow := range currentFlows {
+		if _, ok := expectedFlows[normalized]; !ok {
+			removed = append(removed, flow)
 		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// flowMatchFieldAliases maps the match fields OVS reports under another name
+// to the name it reports them with
+var flowMatchFieldAliases = map[string]string{
+	"ip_src": "nw_src",
+	"ip_dst": "nw_dst",
+}
 
-		_, stderr, err := util.ReplaceOFFlows(c.externalGatewayBridge.bridgeName, flows)
-		if err != nil {
-			klog.Errorf("Failed to add flows, error: %v, stderr, %s, flows: %s", err, stderr, c.exGWFlowCache)
+// normalizeFlow returns the flow without whitespace, with its match fields
+// sorted and the default table omitted, as OVS reports it. The actions are
+// kept as they are.
+func normalizeFlow(flow string) string {
+	flow = strings.Join(strings.Fields(flow), "")
+	match, actions, _ := strings.Cut(flow, "actions=")
+	fields := []string{}
+	for _, field := range strings.Split(match, ",") {
+		if field == "" || field == "table=0" {
+			continue
 		}
+		name, value, hasValue := strings.Cut(field, "=")
+		if alias, ok := flowMatchFieldAliases[name]; ok && hasValue {
+			field = alias + "=" + value
+		}
+		fields = append(fields, field)
 	}
+	sort.Strings(fields)
+	return strings.Join(fields, ",") + ",actions=" + actions
+}
+
+// defaultFlowPriority is the priority OVS gives to the flows without one
+const defaultFlowPriority = 32768
+
+// flowSortKey is the canonical sort key of a flow, see sortFlows
+type flowSortKey struct {
+	table    int
+	priority int
+	match    string
+}
+
+// getFlowSortKey returns the table, the priority and the normalized match of
+// the flow, its match fields sorted without the cookie, the table and the
+// priority
+func getFlowSortKey(flow string) flowSortKey {
+	key := flowSortKey{priority: defaultFlowPriority}
+	flow = strings.Join(strings.Fields(flow), "")
+	match, _, _ := strings.Cut(flow, "actions=")
+	fields := []string{}
+	for _, field := range strings.Split(match, ",") {
+		name, value, hasValue := strings.Cut(field, "=")
+		switch name {
+		case "", "cookie":
+			continue
+		case "table":
+			if table, err := strconv.Atoi(value); err == nil {
+				key.table = table
+			}
+			continue
+		case "priority":
+			if priority, err := strconv.Atoi(value); err == nil {
+				key.priority = priority
+			}
+			continue
+		}
+		if alias, ok := flowMatchFieldAliases[name]; ok && hasValue {
+			field = alias + "=" + value
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	key.match = strings.Join(fields, ",")
+	return key
+}
+
+// sortFlows sorts the flows canonically, by table, by decreasing priority, by
+// normalized match and then by the flows themselves, so that the bridges are
+// programmed in the same order whatever the order the flows were generated and
+// cached in, for stable diffs. OVS matches the flows by priority, their order
+// does not change the matching.
+func sortFlows(flows []string) {
+	keys := make(map[string]flowSortKey, len(flows))
+	for _, flow := range flows {
+		if _, ok := keys[flow]; !ok {
+			keys[flow] = getFlowSortKey(flow)
+		}
+	}
+	sort.Slice(flows, func(i, j int) bool {
+		ki, kj := keys[flows[i]], keys[flows[j]]
+		if ki.table != kj.table {
+			return ki.table < kj.table
+		}
+		if ki.priority != kj.priority {
+			return ki.priority > kj.priority
+		}
+		if ki.match != kj.match {
+			return ki.match < kj.match
+		}
+		return flows[i] < flows[j]
+	})
+}
+
+func (c *openflowManager) setLastSyncTime(bridgeName string) {
+	c.lastSyncTimeLock.Lock()
+	defer c.lastSyncTimeLock.Unlock()
+	if c.lastSyncTime == nil {
+		c.lastSyncTime = map[string]time.Time{}
+	}
+	c.lastSyncTime[bridgeName] = time.Now()
+}
+
+// getLastSyncTimes returns the time of the last successful flow sync of each
+// bridge. Bridges whose flows were never synced successfully are not included.
+func (c *openflowManager) getLastSyncTimes() map[string]time.Time {
+	c.lastSyncTimeLock.Lock()
+	defer c.lastSyncTimeLock.Unlock()
+	lastSyncTimes := make(map[string]time.Time, len(c.lastSyncTime))
+	for bridgeName, lastSyncTime := range c.lastSyncTime {
+		lastSyncTimes[bridgeName] = lastSyncTime
+	}
+	return lastSyncTimes
+}
+
+// updatePatchPortLinkState updates the link state of the default bridge patch
+// port. Returns true if the port came back up and the held flows have to be
+// synced.
+func (c *openflowManager) updatePatchPortLinkState() bool {
+	stdout, stderr, err := util.RunOVSVsctl("--if-exists", "get", "Interface", c.defaultBridge.patchPort, "link_state")
+	if err != nil {
+		klog.Errorf("Failed to get the link state of patch port %s, stderr: %q, error: %v", c.defaultBridge.patchPort, stderr, err)
+		return false
+	}
+	// an unknown link state is not considered down
+	down := stdout == "down"
+
+	c.flowMutex.Lock()
+	defer c.flowMutex.Unlock()
+	if down == c.patchPortDown {
+		return false
+	}
+	c.patchPortDown = down
+	if down {
+		klog.Warningf("Patch port %s of bridge %s is down", c.defaultBridge.patchPort, c.defaultBridge.bridgeName)
+		return false
+	}
+	klog.Infof("Patch port %s of bridge %s is back up, resyncing its flows", c.defaultBridge.patchPort, c.defaultBridge.bridgeName)
+	return true
+}
+
+// refreshHostRepresentorOfPorts gets the ofport of the DPU host representor of
+// the bridges it was unavailable for again. Returns true if it is back for any
+// of them.
+func (c *openflowManager) refreshHostRepresentorOfPorts() bool {
+	refreshed := false
+	for _, bridge := range []*bridgeConfiguration{c.defaultBridge, c.externalGatewayBridge} {
+		if bridge == nil {
+			continue
+		}
+		bridge.Lock()
+		if bridge.ofPortHost == ofPortHostUnavailable {
+			ofport, err := getHostRepresentorOfPort(bridge.hostRepresentor)
+			if err != nil {
+				klog.Errorf("Failed to refresh the host representor ofport of bridge %s: %v", bridge.bridgeName, err)
+			} else if ofport != ofPortHostUnavailable {
+				klog.Infof("Host representor %s of bridge %s is back with ofport %s, regenerating its flows",
+					bridge.hostRepresentor, bridge.bridgeName, ofport)
+				bridge.ofPortHost = ofport
+				refreshed = true
+			}
+		}
+		bridge.Unlock()
+	}
+	return refreshed
 }
 
 // checkDefaultOpenFlow checks for the existence of default OpenFlow rules and
 // exits if the output is not as expected
 func (c *openflowManager) Run(stopChan <-chan struct{}, doneWg *sync.WaitGroup) {
+	c.runFlowSyncWatchdog(stopChan, doneWg)
 	doneWg.Add(1)
 	go func() {
 		defer doneWg.Done()
@@ -96,6 +751,12 @@ func (c *openflowManager) Run(stopChan <-chan struct{}, doneWg *sync.WaitGroup)
 		for {
 			select {
 			case <-timer.C:
+				if c.deferFlowSyncIfFrozen() {
+					continue
+				}
+				if c.updatePatchPortLinkState() {
+					c.requestFlowSync()
+				}
 				if err := checkPorts(c.defaultBridge.patchPort, c.defaultBridge.ofPortPatch,
 					c.defaultBridge.uplinkName, c.defaultBridge.ofPortPhys); err != nil {
 					klog.Errorf("Checkports failed %v", err)
@@ -109,17 +770,52 @@ func (c *openflowManager) Run(stopChan <-chan struct{}, doneWg *sync.WaitGroup)
 						continue
 					}
 				}
+				if c.onPortsChecked != nil {
+					c.onPortsChecked()
+				}
+				if c.refreshHostRepresentorOfPorts() && c.onHostRepresentorAvailable != nil {
+					c.onHostRepresentorAvailable()
+				}
 				c.syncFlows()
 			case <-c.flowChan:
+				c.flowSyncPickedUp()
+				// a sync requested right before the flows were frozen
+				if c.deferFlowSyncIfFrozen() {
+					continue
+				}
 				c.syncFlows()
 				timer.Reset(syncPeriod)
 			case <-stopChan:
+				c.drainFlowSync(flowSyncDrainTimeout)
 				return
 			}
 		}
 	}()
 }
 
+// drainFlowSync applies a flow sync requested but not processed yet when the
+// flow sync loop is stopped, so that the last intended flows are not missing
+// from the bridges until restart. Gives up waiting for it after timeout.
+func (c *openflowManager) drainFlowSync(timeout time.Duration) {
+	select {
+	case <-c.flowChan:
+		c.flowSyncPickedUp()
+	default:
+		return
+	}
+	klog.Infof("Applying the pending gateway OpenFlow sync before stopping")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.syncFlows()
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		klog.Warningf("Timed out after %v applying the pending gateway OpenFlow sync before stopping", timeout)
+	}
+}
+
 func checkPorts(patchIntf, ofPortPatch, physIntf, ofPortPhys string) error {
 	// it could be that the ovn-controller recreated the patch between the host OVS bridge and
 	// the integration bridge, as a result the ofport number changed for that patch interface
@@ -140,6 +836,14 @@ func checkPorts(patchIntf, ofPortPatch, physIntf, ofPortPhys string) error {
 	if err != nil {
 		return errors.Wrapf(err, "Failed to get ofport of %s, stderr: %q", physIntf, stderr)
 	}
+	if curOfportPhys == "" {
+		// the physical interface might be an OVS bond, its ofport does not
+		// change when its members flap
+		curOfportPhys, err = getBondOfPort(physIntf, false)
+		if err != nil && err != errNotABond {
+			return errors.Wrapf(err, "Failed to get ofport of bond %s", physIntf)
+		}
+	}
 	if ofPortPhys != curOfportPhys {
 		klog.Errorf("Fatal error: phys port %s ofport changed from %s to %s",
 			physIntf, ofPortPhys, curOfportPhys)
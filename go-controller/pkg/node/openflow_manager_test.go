@@ -0,0 +1,431 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	ovntest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestOpenflowManagerLastSyncTime(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	fexec := ovntest.NewFakeExec()
+	err := util.SetExec(fexec)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	replaceFlowsCmd := "ovs-ofctl -O OpenFlow13 --bundle replace-flows breth0 -"
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceFlowsCmd})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceFlowsCmd})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceFlowsCmd, Err: fmt.Errorf("failed to replace flows")})
+
+	ofm := &openflowManager{
+		defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+		flowCache:     map[string][]string{},
+		flowChan:      make(chan struct{}, 1),
+		lastSyncTime:  map[string]time.Time{},
+	}
+	// bridges are not reported until their flows are synced
+	g.Expect(ofm.getLastSyncTimes()).To(gomega.BeEmpty())
+
+	ofm.syncFlows()
+	first, ok := ofm.getLastSyncTimes()["breth0"]
+	g.Expect(ok).To(gomega.BeTrue())
+
+	// the time advances on a successful sync
+	time.Sleep(10 * time.Millisecond)
+	ofm.syncFlows()
+	second := ofm.getLastSyncTimes()["breth0"]
+	g.Expect(second.After(first)).To(gomega.BeTrue())
+
+	// and does not on a failed one
+	ofm.syncFlows()
+	g.Expect(ofm.getLastSyncTimes()["breth0"]).To(gomega.Equal(second))
+	g.Expect(fexec.CalledMatchesExpected()).To(gomega.BeTrue(), fexec.ErrorDesc)
+}
+
+func TestOpenflowManagerPatchPortDown(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	fexec := ovntest.NewFakeExec()
+	err := util.SetExec(fexec)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	linkStateCmd := "ovs-vsctl --timeout=15 --if-exists get Interface patch-breth0_ov link_state"
+	replaceFlowsCmd := "ovs-ofctl -O OpenFlow13 --bundle replace-flows breth0 -"
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: linkStateCmd, Output: "down"})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: linkStateCmd, Output: "up"})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceFlowsCmd})
+
+	ofm := &openflowManager{
+		defaultBridge: &bridgeConfiguration{bridgeName: "breth0", patchPort: "patch-breth0_ov"},
+		flowCache:     map[string][]string{},
+		flowChan:      make(chan struct{}, 1),
+		lastSyncTime:  map[string]time.Time{},
+	}
+
+	// flows are held while the patch port is down
+	g.Expect(ofm.updatePatchPortLinkState()).To(gomega.BeFalse())
+	ofm.syncFlows()
+	g.Expect(ofm.getLastSyncTimes()).To(gomega.BeEmpty())
+
+	// and resynced once it is back up
+	g.Expect(ofm.updatePatchPortLinkState()).To(gomega.BeTrue())
+	ofm.syncFlows()
+	g.Expect(ofm.getLastSyncTimes()).To(gomega.HaveKey("breth0"))
+	g.Expect(fexec.CalledMatchesExpected()).To(gomega.BeTrue(), fexec.ErrorDesc)
+}
+
+func TestOpenflowManagerDrainFlowSyncOnStop(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	fexec := ovntest.NewFakeExec()
+	err := util.SetExec(fexec)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl -O OpenFlow13 --bundle replace-flows breth0 -"})
+
+	ofm := &openflowManager{
+		defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+		flowCache:     map[string][]string{},
+		flowChan:      make(chan struct{}, 1),
+		lastSyncTime:  map[string]time.Time{},
+	}
+
+	// nothing is applied without a pending sync
+	ofm.drainFlowSync(flowSyncDrainTimeout)
+	g.Expect(ofm.getLastSyncTimes()).To(gomega.BeEmpty())
+
+	// a sync requested right before stop is applied before the loop exits
+	stopChan := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	ofm.updateFlowCacheEntry("NORMAL", []string{"cookie=0xdeff105, priority=100, actions=NORMAL"})
+	ofm.requestFlowSync()
+	close(stopChan)
+	ofm.Run(stopChan, wg)
+	wg.Wait()
+
+	g.Expect(fexec.CalledMatchesExpected()).To(gomega.BeTrue(), fexec.ErrorDesc)
+	g.Expect(ofm.getLastSyncTimes()).To(gomega.HaveKey("breth0"))
+}
+
+func TestOpenflowManagerFlowSyncWatchdog(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	ofm := &openflowManager{
+		defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+		flowCache:     map[string][]string{},
+		flowChan:      make(chan struct{}, 1),
+		lastSyncTime:  map[string]time.Time{},
+	}
+
+	// nothing is reported without a pending sync
+	g.Expect(ofm.checkFlowSyncStalled(0)).To(gomega.BeFalse())
+
+	// a pending sync is reported once it is pending for longer than the timeout
+	ofm.requestFlowSync()
+	g.Expect(ofm.checkFlowSyncStalled(time.Hour)).To(gomega.BeFalse())
+	g.Expect(ofm.checkFlowSyncStalled(0)).To(gomega.BeTrue())
+	// and only once
+	ofm.requestFlowSync()
+	g.Expect(ofm.checkFlowSyncStalled(0)).To(gomega.BeFalse())
+
+	// a sync picked up is not pending anymore
+	<-ofm.flowChan
+	ofm.flowSyncPickedUp()
+	g.Expect(ofm.checkFlowSyncStalled(0)).To(gomega.BeFalse())
+}
+
+func TestOpenflowManagerFlowSyncWatchdogStuckLoop(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	fexec := ovntest.NewFakeExec()
+	err := util.SetExec(fexec)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	replaceFlowsCmd := "ovs-ofctl -O OpenFlow13 --bundle replace-flows breth0 -"
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceFlowsCmd})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceFlowsCmd})
+
+	stalled := make(chan time.Duration, 1)
+	ofm := &openflowManager{
+		defaultBridge:           &bridgeConfiguration{bridgeName: "breth0"},
+		flowCache:               map[string][]string{},
+		flowChan:                make(chan struct{}, 1),
+		lastSyncTime:            map[string]time.Time{},
+		flowSyncWatchdogTimeout: 100 * time.Millisecond,
+		onFlowSyncStalled: func(pending time.Duration) {
+			stalled <- pending
+		},
+	}
+
+	stopChan := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	ofm.Run(stopChan, wg)
+	defer func() {
+		close(stopChan)
+		wg.Wait()
+	}()
+
+	// the flow sync loop wedges on the bridge lock, like on a blocking OVS
+	// call, and the next sync request is never picked up
+	ofm.defaultBridge.Lock()
+	ofm.requestFlowSync()
+	g.Eventually(func() int { return len(ofm.flowChan) }, time.Second).Should(gomega.BeZero())
+	ofm.requestFlowSync()
+	var pending time.Duration
+	g.Eventually(stalled, time.Second).Should(gomega.Receive(&pending))
+	g.Expect(pending).To(gomega.BeNumerically(">=", ofm.flowSyncWatchdogTimeout))
+
+	// the loop recovers once unwedged
+	ofm.defaultBridge.Unlock()
+	g.Eventually(fexec.CalledMatchesExpected, time.Second).Should(gomega.BeTrue(), fexec.ErrorDesc)
+	g.Expect(ofm.checkFlowSyncStalled(0)).To(gomega.BeFalse())
+}
+
+func TestOpenflowManagerGroups(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	fexec := ovntest.NewFakeExec()
+	err := util.SetExec(fexec)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	replaceGroupsCmd := "ovs-ofctl -O OpenFlow13 replace-groups breth0 -"
+	replaceFlowsCmd := "ovs-ofctl -O OpenFlow13 --bundle replace-flows breth0 -"
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceFlowsCmd})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceGroupsCmd})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceFlowsCmd})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceGroupsCmd})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceFlowsCmd})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: replaceGroupsCmd, Err: fmt.Errorf("failed to replace groups")})
+
+	ofm := &openflowManager{
+		defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+		flowCache:     map[string][]string{},
+		flowChan:      make(chan struct{}, 1),
+		lastSyncTime:  map[string]time.Time{},
+	}
+
+	// groups are not replaced until some were cached
+	ofm.updateFlowCacheEntry("NORMAL", []string{"cookie=0xdeff105, priority=100, actions=NORMAL"})
+	ofm.syncFlows()
+
+	// group IDs are stable per key
+	g.Expect(ofm.getGroupID("key1")).To(gomega.Equal(uint32(1)))
+	g.Expect(ofm.getGroupID("key2")).To(gomega.Equal(uint32(2)))
+	g.Expect(ofm.getGroupID("key1")).To(gomega.Equal(uint32(1)))
+	ofm.updateFlowCacheEntryWithGroup("key1", []string{"priority=110, tcp, tp_dst=31111, actions=group:1"},
+		"group_id=1,type=select,bucket=actions=output:LOCAL")
+	ofm.updateFlowCacheEntryWithGroup("key2", []string{"priority=110, tcp, tp_dst=31112, actions=group:2"},
+		"group_id=2,type=select,bucket=actions=output:LOCAL")
+	ofm.syncFlows()
+	g.Expect(ofm.groupCache).To(gomega.HaveLen(2))
+
+	// the group and its ID are released along with the flows of their key
+	ofm.deleteFlowsByKey("key1")
+	ofm.updateFlowCacheEntry("key2", []string{"priority=110, tcp, tp_dst=31112, actions=output:LOCAL"})
+	g.Expect(ofm.groupCache).To(gomega.BeEmpty())
+	g.Expect(ofm.getGroupID("key3")).To(gomega.Equal(uint32(1)))
+
+	// the stale groups are still removed once none are cached
+	ofm.syncFlows()
+	lastSyncTime := ofm.getLastSyncTimes()["breth0"]
+
+	// and the flows are not replaced if the groups they reference could not be
+	time.Sleep(10 * time.Millisecond)
+	ofm.syncFlows()
+	g.Expect(ofm.getLastSyncTimes()["breth0"]).To(gomega.Equal(lastSyncTime))
+	g.Expect(fexec.CalledMatchesExpected()).To(gomega.BeTrue(), fexec.ErrorDesc)
+}
+
+func TestFlowDiff(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	fexec := ovntest.NewFakeExec()
+	err := util.SetExec(fexec)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+		Cmd: "ovs-ofctl -O OpenFlow13 --no-stats --no-names dump-flows breth0",
+		Output: "OFPST_FLOW reply (OF1.3) (xid=0x2):\n" +
+			" cookie=0xdeff105, priority=500,ip,in_port=2,nw_dst=10.96.0.0/16 actions=ct(commit,table=2,zone=64001)\n" +
+			" cookie=0xdeff105, table=1, priority=0 actions=NORMAL\n" +
+			" cookie=0xdeff105, priority=100,ip,in_port=1 actions=drop\n",
+	})
+	current, err := util.GetOFFlows("breth0")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(fexec.CalledMatchesExpected()).To(gomega.BeTrue(), fexec.ErrorDesc)
+
+	expected := []string{
+		// reported differently but equivalent
+		"cookie=0xdeff105, priority=500, in_port=2, ip, ip_dst=10.96.0.0/16, actions=ct(commit,table=2,zone=64001)",
+		"cookie=0xdeff105, table=1, priority=0, actions=NORMAL",
+		// missing from the bridge
+		"cookie=0xdeff105, priority=10, table=0, in_port=1, dl_dst=0a:58:0a:01:01:01, actions=output:2",
+	}
+	added, removed := flowDiff(current, expected)
+	g.Expect(added).To(gomega.Equal([]string{
+		"cookie=0xdeff105, priority=10, table=0, in_port=1, dl_dst=0a:58:0a:01:01:01, actions=output:2",
+	}))
+	g.Expect(removed).To(gomega.Equal([]string{
+		"cookie=0xdeff105, priority=100,ip,in_port=1 actions=drop",
+	}))
+}
+
+func TestOpenflowManagerFrozen(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	fexec := ovntest.NewFakeExec()
+	err := util.SetExec(fexec)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl -O OpenFlow13 --bundle replace-flows breth0 -"})
+
+	ofm := &openflowManager{
+		defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+		flowCache:     map[string][]string{},
+		flowChan:      make(chan struct{}, 1),
+		lastSyncTime:  map[string]time.Time{},
+	}
+	stopChan := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	ofm.Run(stopChan, wg)
+	defer func() {
+		close(stopChan)
+		wg.Wait()
+	}()
+
+	// no sync is applied while frozen, nor reported as stalled
+	ofm.SetFrozen(true)
+	for i := 0; i < 3; i++ {
+		ofm.updateFlowCacheEntry("NORMAL", []string{fmt.Sprintf("cookie=0xdeff105, priority=%d, actions=NORMAL", 100+i)})
+		ofm.requestFlowSync()
+	}
+	g.Consistently(ofm.getLastSyncTimes, 200*time.Millisecond).Should(gomega.BeEmpty())
+	g.Expect(ofm.checkFlowSyncStalled(0)).To(gomega.BeFalse())
+
+	// a single sync applies the latest flows on thaw
+	ofm.SetFrozen(false)
+	g.Eventually(ofm.getLastSyncTimes, time.Second).Should(gomega.HaveKey("breth0"))
+	g.Consistently(func() bool { return fexec.CalledMatchesExpected() }, 200*time.Millisecond).Should(gomega.BeTrue(), fexec.ErrorDesc)
+	g.Expect(ofm.flowCache["NORMAL"]).To(gomega.Equal([]string{"cookie=0xdeff105, priority=102, actions=NORMAL"}))
+
+	// thawing again without a deferred sync does not sync
+	ofm.SetFrozen(true)
+	ofm.SetFrozen(false)
+	g.Consistently(func() bool { return fexec.CalledMatchesExpected() }, 200*time.Millisecond).Should(gomega.BeTrue(), fexec.ErrorDesc)
+}
+
+func TestSortFlows(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	flows := []string{
+		"cookie=0xdeff105, priority=10, table=1, dl_dst=0a:58:0a:01:01:01, actions=output:LOCAL",
+		"cookie=0x1, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=output:patch-breth0_ov",
+		"cookie=0xdeff105, priority=50, in_port=eth0, ip, actions=ct(zone=64000, nat, table=1)",
+		"table=0, actions=NORMAL",
+		"cookie=0x2, priority=110, in_port=eth0, tcp, tp_dst=30000, actions=output:patch-breth0_ov",
+		"cookie=0xdeff105, priority=0, table=1, actions=NORMAL",
+		"cookie=0x1, priority=110, in_port=patch-breth0_ov, tcp, tp_src=31111, actions=output:eth0",
+	}
+	sortFlows(flows)
+	g.Expect(flows).To(gomega.Equal([]string{
+		// OVS defaults the priority to 32768
+		"table=0, actions=NORMAL",
+		// the flows of the same priority are sorted by match, whatever their cookie
+		"cookie=0x2, priority=110, in_port=eth0, tcp, tp_dst=30000, actions=output:patch-breth0_ov",
+		"cookie=0x1, priority=110, in_port=eth0, tcp, tp_dst=31111, actions=output:patch-breth0_ov",
+		"cookie=0x1, priority=110, in_port=patch-breth0_ov, tcp, tp_src=31111, actions=output:eth0",
+		"cookie=0xdeff105, priority=50, in_port=eth0, ip, actions=ct(zone=64000, nat, table=1)",
+		"cookie=0xdeff105, priority=10, table=1, dl_dst=0a:58:0a:01:01:01, actions=output:LOCAL",
+		"cookie=0xdeff105, priority=0, table=1, actions=NORMAL",
+	}))
+}
+
+func TestOpenflowManagerFlowLength(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	fexec := ovntest.NewFakeExec()
+	err := util.SetExec(fexec)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl -O OpenFlow13 replace-groups breth0 -"})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "ovs-ofctl -O OpenFlow13 --bundle replace-flows breth0 -"})
+
+	// a flow outputting to more ports than OVS can take in one message
+	longFlow := "priority=110, tcp, tp_dst=31111, actions=" + strings.Repeat("output:100,", maxFlowLength/11+1)
+	normalFlow := "cookie=0xdeff105, priority=100, actions=NORMAL"
+	err = validateFlowCacheEntry("NodePort_namespace1_service1_tcp_31111", []string{normalFlow, longFlow}, "")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(
+		"a flow of key NodePort_namespace1_service1_tcp_31111 is %d characters long, more than the maximum of %d",
+		len(longFlow), maxFlowLength)))
+	longGroup := "group_id=1,type=select" + strings.Repeat(",bucket=actions=output:LOCAL", maxFlowLength/28+1)
+	err = validateFlowCacheEntry("NodePort_namespace1_service2_tcp_31112", []string{normalFlow}, longGroup)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("the group of key NodePort_namespace1_service2_tcp_31112")))
+	g.Expect(validateFlowCacheEntry("NORMAL", []string{normalFlow}, "")).To(gomega.Succeed())
+
+	ofm := &openflowManager{
+		defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+		flowCache:     map[string][]string{},
+		flowChan:      make(chan struct{}, 1),
+		lastSyncTime:  map[string]time.Time{},
+	}
+	ofm.updateFlowCacheEntry("NORMAL", []string{normalFlow})
+	ofm.updateFlowCacheEntry("NodePort_namespace1_service1_tcp_31111", []string{longFlow})
+	ofm.updateFlowCacheEntryWithGroup("NodePort_namespace1_service2_tcp_31112",
+		[]string{"priority=110, tcp, tp_dst=31112, actions=group:1"}, longGroup)
+
+	// the flows of the keys with a flow or group too long are left out
+	flows, invalidKeys := validFlowCacheFlows("breth0", ofm.flowCache, ofm.groupCache)
+	g.Expect(flows).To(gomega.Equal([]string{normalFlow}))
+	g.Expect(sets.List(invalidKeys)).To(gomega.Equal([]string{
+		"NodePort_namespace1_service1_tcp_31111",
+		"NodePort_namespace1_service2_tcp_31112",
+	}))
+
+	// and do not fail the sync of the other flows of the bridge
+	ofm.syncFlows()
+	_, ok := ofm.getLastSyncTimes()["breth0"]
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(fexec.CalledMatchesExpected()).To(gomega.BeTrue(), fexec.ErrorDesc)
+}
+
+func TestOpenflowManagerOfPortReferences(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	ofm := &openflowManager{
+		defaultBridge:         &bridgeConfiguration{bridgeName: "breth0"},
+		externalGatewayBridge: &bridgeConfiguration{bridgeName: "breth1"},
+		flowCache:             map[string][]string{},
+		exGWFlowCache:         map[string][]string{},
+		flowChan:              make(chan struct{}, 1),
+	}
+	normalFlow := "cookie=0xdeff105, priority=100, actions=NORMAL"
+	inPortFlow := "cookie=0xdeff105, priority=110, in_port=2, tcp, nw_dst=1.1.1.1, tp_dst=80, actions=ct(commit,zone=64001,table=1)"
+	outputFlow := "cookie=0xdeff105, priority=100, table=1, ip, actions=ct(zone=64001),output:2"
+	otherPortFlow := "cookie=0xdeff105, priority=110, in_port=12, actions=output:LOCAL"
+	ofm.updateFlowCacheEntry("NORMAL", []string{normalFlow, otherPortFlow})
+	ofm.updateFlowCacheEntry("External_namespace1_service1_1.1.1.1_80", []string{inPortFlow, outputFlow})
+	group := "group_id=1,type=select,bucket=actions=output:2,bucket=actions=output:LOCAL"
+	ofm.updateFlowCacheEntryWithGroup("NodePort_namespace1_service2_tcp_31112",
+		[]string{"cookie=0xdeff105, priority=110, in_port=1, tcp, tp_dst=31112, actions=group:1"}, group)
+	ofm.updateExBridgeFlowCacheEntry("NORMAL", []string{"cookie=0xdeff105, priority=110, in_port=2, actions=output:1"})
+
+	g.Expect(ofm.getOfPortReferences("2")).To(gomega.Equal([]ofPortReference{
+		{bridgeName: "breth0", key: "External_namespace1_service1_1.1.1.1_80", flows: []string{inPortFlow, outputFlow}},
+		{bridgeName: "breth0", key: "NodePort_namespace1_service2_tcp_31112", group: group},
+		{bridgeName: "breth1", key: "NORMAL", flows: []string{"cookie=0xdeff105, priority=110, in_port=2, actions=output:1"}},
+	}))
+	// ofports are not matched on a prefix, nor on other fields with the same value
+	g.Expect(ofm.getOfPortReferences("1")).To(gomega.Equal([]ofPortReference{
+		{bridgeName: "breth0", key: "NodePort_namespace1_service2_tcp_31112",
+			flows: []string{"cookie=0xdeff105, priority=110, in_port=1, tcp, tp_dst=31112, actions=group:1"}},
+		{bridgeName: "breth1", key: "NORMAL", flows: []string{"cookie=0xdeff105, priority=110, in_port=2, actions=output:1"}},
+	}))
+	g.Expect(ofm.getOfPortReferences("3")).To(gomega.BeEmpty())
+}
@@ -0,0 +1,74 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	kapi "k8s.io/api/core/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	serviceFlowErrorEventReason = "GatewayFlowError"
+	// serviceFlowErrorEventInterval is the minimum interval between two events
+	// of a service for the same flow generation error
+	serviceFlowErrorEventInterval = 5 * time.Minute
+)
+
+// serviceFlowError is the last flow generation error reported on a service
+type serviceFlowError struct {
+	message  string
+	reported time.Time
+}
+
+// serviceFlowErrorReporter emits events on the services whose gateway bridge
+// flows could not be generated, like because of an invalid externalIP, so that
+// their users see why. The same error of a service is reported at most once per
+// serviceFlowErrorEventInterval.
+type serviceFlowErrorReporter struct {
+	recorder record.EventRecorder
+	// now returns the current time, time.Now if nil
+	now func() time.Time
+	// lock protects lastErrors
+	lock sync.Mutex
+	// lastErrors holds the last error reported on each service, until its
+	// flows are generated without error or it is deleted
+	lastErrors map[ktypes.NamespacedName]serviceFlowError
+}
+
+func newServiceFlowErrorReporter(recorder record.EventRecorder) *serviceFlowErrorReporter {
+	return &serviceFlowErrorReporter{
+		recorder:   recorder,
+		lastErrors: map[ktypes.NamespacedName]serviceFlowError{},
+	}
+}
+
+// report emits an event on the service for the flow generation error, unless
+// it was already reported within serviceFlowErrorEventInterval. A nil error
+// clears the last error of the service.
+func (r *serviceFlowErrorReporter) report(service *kapi.Service, err error) {
+	name := ktypes.NamespacedName{Namespace: service.Namespace, Name: service.Name}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err == nil {
+		delete(r.lastErrors, name)
+		return
+	}
+	now := time.Now()
+	if r.now != nil {
+		now = r.now()
+	}
+	message := err.Error()
+	if last, ok := r.lastErrors[name]; ok && last.message == message && now.Sub(last.reported) < serviceFlowErrorEventInterval {
+		return
+	}
+	r.lastErrors[name] = serviceFlowError{message: message, reported: now}
+	serviceRef := kapi.ObjectReference{
+		Kind:      "Service",
+		Namespace: service.Namespace,
+		Name:      service.Name,
+	}
+	r.recorder.Eventf(&serviceRef, kapi.EventTypeWarning, serviceFlowErrorEventReason,
+		"Failed to program the gateway bridge flows of the service: %s", message)
+}
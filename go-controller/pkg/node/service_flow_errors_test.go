@@ -0,0 +1,91 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+
+	kapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestServiceFlowErrorEvents(t *testing.T) {
+	if err := config.PrepareTestConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config.Gateway.Mode = config.GatewayModeShared
+	config.Gateway.DisableARPBypassFlows = true
+	config.IPv4Mode = true
+
+	ports := []kapi.ServicePort{
+		{Name: "http", Port: 8080, Protocol: kapi.ProtocolTCP, NodePort: 31111, TargetPort: intstr.FromInt(8080)},
+	}
+	service := newService("service1", "namespace1", "10.96.0.10", ports, kapi.ServiceTypeNodePort,
+		[]string{"1.2.3"}, kapi.ServiceStatus{}, false, false)
+	recorder := record.NewFakeRecorder(10)
+	now := time.Now()
+	npw := &nodePortWatcher{
+		ofportPhys:  "eth0",
+		ofportPatch: "patch-breth0_ov",
+		gatewayIPv4: "192.168.18.15",
+		ofm: &openflowManager{
+			defaultBridge: &bridgeConfiguration{bridgeName: "breth0"},
+			flowCache:     map[string][]string{},
+		},
+		flowErrorReporter: newServiceFlowErrorReporter(recorder),
+	}
+	npw.flowErrorReporter.now = func() time.Time { return now }
+
+	expectEvents := func(desc string, expected ...string) {
+		t.Helper()
+		var received []string
+		for len(recorder.Events) > 0 {
+			received = append(received, <-recorder.Events)
+		}
+		if len(received) != len(expected) {
+			t.Fatalf("%s: expected events %v, got %v", desc, expected, received)
+		}
+		for i := range expected {
+			if received[i] != expected[i] {
+				t.Errorf("%s: expected event %q, got %q", desc, expected[i], received[i])
+			}
+		}
+	}
+	event := "Warning GatewayFlowError Failed to program the gateway bridge flows of the service: " +
+		"failed to parse External IP: \"1.2.3\""
+
+	if err := npw.updateServiceFlowCache(service, true, false); err == nil {
+		t.Fatal("expected the malformed externalIP to be reported")
+	}
+	expectEvents("malformed externalIP", event)
+
+	// repeats of the same error are rate limited
+	if err := npw.updateServiceFlowCache(service, true, false); err == nil {
+		t.Fatal("expected the malformed externalIP to be reported")
+	}
+	expectEvents("repeated error")
+	now = now.Add(serviceFlowErrorEventInterval)
+	if err := npw.updateServiceFlowCache(service, true, false); err == nil {
+		t.Fatal("expected the malformed externalIP to be reported")
+	}
+	expectEvents("repeated error after the interval", event)
+
+	// the error is reported again as soon as it comes back once fixed
+	fixed := service.DeepCopy()
+	fixed.Spec.ExternalIPs = []string{"1.2.3.4"}
+	if err := npw.updateServiceFlowCache(fixed, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := npw.updateServiceFlowCache(service, true, false); err == nil {
+		t.Fatal("expected the malformed externalIP to be reported")
+	}
+	expectEvents("error back once fixed", event)
+
+	// no event for the removal of the flows
+	if err := npw.updateServiceFlowCache(service, false, false); err == nil {
+		t.Fatal("expected the malformed externalIP to be reported")
+	}
+	expectEvents("removal of the flows")
+}
@@ -1,8 +1,10 @@
 package egressservice
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -33,6 +35,7 @@ import (
 	corelisters "k8s.io/client-go/listers/core/v1"
 	discoverylisters "k8s.io/client-go/listers/discovery/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	utilnet "k8s.io/utils/net"
@@ -41,6 +44,17 @@ import (
 const (
 	maxRetries       = 10
 	svcExternalIDKey = "EgressSVC" // key set on lrps to identify to which egress service it belongs
+
+	// endpointSelectionEventReason is the reason of the events summarizing the endpoints
+	// an egress service reroutes, recorded on the EgressService after a successful sync.
+	endpointSelectionEventReason = "EndpointsSelected"
+	// endpointSelectionEventInterval is the minimum interval between two endpoint
+	// selection events of the same egress service.
+	endpointSelectionEventInterval = time.Minute
+	// egressIPConflictEventReason is the reason of the warning events listing the endpoints
+	// an egress service reroutes that are also rerouted by an egressIP policy competing with
+	// or taking precedence over the egress service one.
+	egressIPConflictEventReason = "EgressIPConflict"
 )
 
 type InitClusterEgressPoliciesFunc func(client libovsdbclient.Client, addressSetFactory addressset.AddressSetFactory,
@@ -52,6 +66,7 @@ type DeleteLegacyDefaultNoRerouteNodePoliciesFunc func(libovsdbclient.Client, st
 type Controller struct {
 	controllerName string
 	client         kubernetes.Interface
+	recorder       record.EventRecorder
 	nbClient       libovsdbclient.Client
 	stopCh         <-chan struct{}
 	sync.Mutex
@@ -74,6 +89,9 @@ type Controller struct {
 	endpointSliceLister  discoverylisters.EndpointSliceLister
 	endpointSlicesSynced cache.InformerSynced
 
+	podLister  corelisters.PodLister
+	podsSynced cache.InformerSynced
+
 	nodeLister  corelisters.NodeLister
 	nodesSynced cache.InformerSynced
 	nodesQueue  workqueue.RateLimitingInterface
@@ -94,7 +112,21 @@ type svcState struct {
 	// only used when IC is enabled
 	v4RemoteEndpoints sets.Set[string]
 	v6RemoteEndpoints sets.Set[string]
-	stale             bool
+
+	// the nexthops and zone locality of the service node the current
+	// policies and routes were configured with
+	v4NextHop          string
+	v6NextHop          string
+	svcNodeInLocalZone bool
+
+	// the last endpoint selection event recorded for the service and when it was recorded
+	lastSelectionEvent     string
+	lastSelectionEventTime time.Time
+
+	// the last egressIP conflicts reported for the service, see reportEgressIPConflicts
+	lastEgressIPConflicts string
+
+	stale bool
 }
 
 type nodeState struct {
@@ -106,11 +138,16 @@ type nodeState struct {
 	// node router IPs in the transit switch subnet, only used when IC is enabled
 	transitIPV4 net.IP
 	transitIPV6 net.IP
+
+	// gateway router join port IPs, only used when the traffic is rerouted to the gateway router
+	gwRouterIPV4 net.IP
+	gwRouterIPV6 net.IP
 }
 
 func NewController(
 	controllerName string,
 	client kubernetes.Interface,
+	recorder record.EventRecorder,
 	nbClient libovsdbclient.Client,
 	addressSetFactory addressset.AddressSetFactory,
 	initClusterEgressPolicies InitClusterEgressPoliciesFunc,
@@ -120,6 +157,7 @@ func NewController(
 	esInformer egressserviceinformer.EgressServiceInformer,
 	serviceInformer coreinformers.ServiceInformer,
 	endpointSliceInformer discoveryinformers.EndpointSliceInformer,
+	podInformer coreinformers.PodInformer,
 	nodeInformer coreinformers.NodeInformer,
 	zone string) (*Controller, error) {
 	klog.Info("Setting up event handlers for Egress Services")
@@ -127,6 +165,7 @@ func NewController(
 	c := &Controller{
 		controllerName:                           controllerName,
 		client:                                   client,
+		recorder:                                 recorder,
 		nbClient:                                 nbClient,
 		addressSetFactory:                        addressSetFactory,
 		initClusterEgressPolicies:                initClusterEgressPolicies,
@@ -176,6 +215,15 @@ func NewController(
 		return nil, err
 	}
 
+	c.podLister = podInformer.Lister()
+	c.podsSynced = podInformer.Informer().HasSynced
+	_, err = podInformer.Informer().AddEventHandler(factory.WithUpdateHandlingForObjReplace(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: c.onPodDelete,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
 	c.nodeLister = nodeInformer.Lister()
 	c.nodesSynced = nodeInformer.Informer().HasSynced
 	c.nodesQueue = workqueue.NewNamedRateLimitingQueue(
@@ -211,6 +259,10 @@ func (c *Controller) Run(wg *sync.WaitGroup, threadiness int) error {
 		return fmt.Errorf("timed out waiting for caches to sync")
 	}
 
+	if !util.WaitForNamedCacheSyncWithTimeout("egressservices_pods", c.stopCh, c.podsSynced) {
+		return fmt.Errorf("timed out waiting for caches to sync")
+	}
+
 	if !util.WaitForNamedCacheSyncWithTimeout("egressservices_nodes", c.stopCh, c.nodesSynced) {
 		return fmt.Errorf("timed out waiting for caches to sync")
 	}
@@ -265,6 +317,8 @@ func (c *Controller) Run(wg *sync.WaitGroup, threadiness int) error {
 // there's no ovnkube-master running for a while.
 // It deletes all logical router policies from OVN that belong to services which are no longer
 // egress services, and the policies of endpoints that do not belong to an egress service.
+// The served pods address set entries of the deleted policies and the static routes of
+// services which are no longer egress services are removed as well.
 func (c *Controller) repair() error {
 	c.Lock()
 	defer c.Unlock()
@@ -341,14 +395,14 @@ func (c *Controller) repair() error {
 			continue
 		}
 
-		v4Local, v6Local, v4Remote, v6Remote, err := c.allEndpointsFor(svc)
+		v4Local, v6Local, v4Remote, v6Remote, epsSummary, err := c.allEndpointsFor(svc)
 		if err != nil {
 			klog.Errorf("Can't fetch all endpoints for egress service %s, err: %v", key, err)
 			continue
 		}
+		v4Local, v6Local, v4Remote, v6Remote, epsSummary = reroutableEndpointsFor(svc, es.Spec.IPFamily, v4Local, v6Local, v4Remote, v6Remote)
 
-		totalEps := len(v4Local) + len(v6Local) + len(v4Remote) + len(v6Remote)
-		if totalEps == 0 {
+		if epsSummary.total() == 0 {
 			klog.Infof("Egress service %s has no endpoints", key)
 			continue
 		}
@@ -374,12 +428,22 @@ func (c *Controller) repair() error {
 			v4RemoteEndpoints: sets.New[string](),
 			v6RemoteEndpoints: sets.New[string](),
 		}
+		// the configured policies and routes are validated against these nexthops below
+		svcState.v4NextHop, svcState.v6NextHop, svcState.svcNodeInLocalZone, err = c.nextHopsFor(nodeState)
+		if err != nil {
+			klog.Errorf("Can't determine egress service %s nexthops, err: %v", key, err)
+		}
 		c.nodes[svcHost] = nodeState
 		c.services[key] = svcState
 	}
 
 	lrpPredicate := func(item *nbdb.LogicalRouterPolicy) bool {
-		if item.Priority != ovntypes.EgressSVCReroutePriority {
+		if item.Priority != config.OVNKubernetesFeature.EgressServiceReroutePriority {
+			// the policy could have been created with a previously configured priority
+			if svcKey, found := item.ExternalIDs[svcExternalIDKey]; found {
+				klog.Infof("Egress service repair will delete lrp for service %s because it does not use the configured priority: %v", svcKey, item)
+				return true
+			}
 			return false
 		}
 
@@ -401,32 +465,28 @@ func (c *Controller) repair() error {
 		v6Eps := svcKeyToLocalV6Endpoints[svcKey]
 
 		// we extract the IP from the match: "ip4.src == IP" / "ip6.src == IP"
-		splitMatch := strings.Split(item.Match, " ")
-		logicalIP := splitMatch[len(splitMatch)-1]
+		logicalIP := rerouteLRPMatchEndpoint(item.Match)
 		if !v4Eps.Has(logicalIP) && !v6Eps.Has(logicalIP) {
 			klog.Infof("Egress service repair will delete lrp for service %s: Cannot find a valid endpoint within match criteria: %v", svcKey, item)
 			return true
 		}
 
+		// the policy could have been created with previously configured excluded destinations
+		if item.Match != rerouteLRPMatch(logicalIP) {
+			klog.Infof("Egress service repair will delete lrp for service %s because it does not use the configured excluded destinations: %v", svcKey, item)
+			return true
+		}
+
 		if len(item.Nexthops) != 1 {
 			klog.Infof("Egress service repair will delete lrp for service %s because it has more than one nexthop: %v", svcKey, item)
 			return true
 		}
 
-		node := c.nodes[svc.node]
-		nextHopV4 := node.v4MgmtIP.String()
-		nextHopV6 := node.v6MgmtIP.String()
-		if config.OVNKubernetesFeature.EnableInterconnect {
-			svcNodeInLocalZone, zoneKnown := c.nodesZoneState[node.name]
-			if !zoneKnown {
-				klog.Errorf("Failed to verify whether the svc node: %s is in the local zone, deleting lrp", node.name)
-				return true
-			}
-			if !svcNodeInLocalZone {
-				nextHopV4 = node.transitIPV4.String()
-				nextHopV6 = node.transitIPV6.String()
-			}
+		if svc.v4NextHop == "" && svc.v6NextHop == "" {
+			klog.Errorf("Failed to verify whether the svc node: %s is in the local zone, deleting lrp", svc.node)
+			return true
 		}
+		nextHopV4, nextHopV6 := svc.v4NextHop, svc.v6NextHop
 
 		if item.Nexthops[0] != nextHopV4 && item.Nexthops[0] != nextHopV6 {
 			klog.Infof("Egress service repair will delete %s because it is uses a stale nexthop for service %s: %v", logicalIP, svcKey, item)
@@ -442,14 +502,47 @@ func (c *Controller) repair() error {
 		return false
 	}
 
+	// the endpoints of the deleted policies that belong to an egress service,
+	// their served pods address set entries are stale too.
+	staleLRPEndpoints := sets.New[string]()
+	staleLRPPredicate := func(item *nbdb.LogicalRouterPolicy) bool {
+		if !lrpPredicate(item) {
+			return false
+		}
+		if _, found := item.ExternalIDs[svcExternalIDKey]; found {
+			if logicalIP := rerouteLRPMatchEndpoint(item.Match); logicalIP != "" {
+				staleLRPEndpoints.Insert(logicalIP)
+			}
+		}
+		return true
+	}
+
 	errorList := []error{}
 	ops := []libovsdb.Operation{}
-	ops, err = libovsdbops.DeleteLogicalRouterPolicyWithPredicateOps(c.nbClient, ops, ovntypes.OVNClusterRouter, lrpPredicate)
+	ops, err = libovsdbops.DeleteLogicalRouterPolicyWithPredicateOps(c.nbClient, ops, ovntypes.OVNClusterRouter, staleLRPPredicate)
 	if err != nil {
 		errorList = append(errorList,
 			fmt.Errorf("failed to create ops for deleting stale logical router policies from router %s: %v", ovntypes.OVNClusterRouter, err))
 	}
 
+	// an endpoint could still be served by another egress service
+	for _, eps := range svcKeyToLocalConfiguredV4Endpoints {
+		staleLRPEndpoints.Delete(eps...)
+	}
+	for _, eps := range svcKeyToLocalConfiguredV6Endpoints {
+		staleLRPEndpoints.Delete(eps...)
+	}
+	if staleLRPEndpoints.Len() > 0 {
+		klog.Infof("Egress service repair will delete stale served pods address set entries: %v", sets.List(staleLRPEndpoints))
+		delAddrSetOps, err := c.deletePodIPsFromAddressSetOps(createIPAddressNetSlice(sets.List(staleLRPEndpoints), nil))
+		if err != nil {
+			errorList = append(errorList,
+				fmt.Errorf("failed to create ops for deleting stale served pods address set entries: %v", err))
+		} else {
+			ops = append(ops, delAddrSetOps...)
+		}
+	}
+
 	if config.OVNKubernetesFeature.EnableInterconnect {
 		lrsrPredicate := func(item *nbdb.LogicalRouterStaticRoute) bool {
 			svcKey, found := item.ExternalIDs[svcExternalIDKey]
@@ -494,7 +587,8 @@ func (c *Controller) repair() error {
 				return true
 			}
 
-			if item.Nexthop != node.v4MgmtIP.String() && item.Nexthop != node.v6MgmtIP.String() {
+			v4NextHop, v6NextHop := localNextHopsFor(node)
+			if item.Nexthop != v4NextHop && item.Nexthop != v6NextHop {
 				klog.Infof("Egress service repair will delete %s lrsr because it is uses a stale nexthop for service %s: %v", logicalIP, svcKey, item)
 				return true
 			}
@@ -511,6 +605,25 @@ func (c *Controller) repair() error {
 			errorList = append(errorList,
 				fmt.Errorf("failed to create ops for deleting stale logical router static routes from router %s: %v", ovntypes.OVNClusterRouter, err))
 		}
+	} else {
+		// static routes are only configured with interconnect, still remove the
+		// ones left over for services which are no longer egress services
+		lrsrPredicate := func(item *nbdb.LogicalRouterStaticRoute) bool {
+			svcKey, found := item.ExternalIDs[svcExternalIDKey]
+			if !found {
+				return false
+			}
+			if _, found := c.services[svcKey]; !found {
+				klog.Infof("Egress service repair will delete lrsr for service %s because it is no longer a valid egress service: %v", svcKey, item)
+				return true
+			}
+			return false
+		}
+		ops, err = libovsdbops.DeleteLogicalRouterStaticRoutesWithPredicateOps(c.nbClient, ops, ovntypes.OVNClusterRouter, lrsrPredicate)
+		if err != nil {
+			errorList = append(errorList,
+				fmt.Errorf("failed to create ops for deleting stale logical router static routes from router %s: %v", ovntypes.OVNClusterRouter, err))
+		}
 	}
 
 	if _, err := libovsdbops.TransactAndCheck(c.nbClient, ops); err != nil {
@@ -621,39 +734,74 @@ func (c *Controller) syncEgressService(key string) error {
 		klog.V(4).Infof("Finished syncing Egress Service %s/%s : %v", namespace, name, time.Since(startTime))
 	}()
 
+	_, err = c.reconcileEgressService(key, false)
+	return err
+}
+
+// PlanEgressService returns a human readable plan of the OVN northbound operations
+// the next sync of the egress service would transact, without transacting them
+// or modifying the controller's state.
+func (c *Controller) PlanEgressService(namespace, name string) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	key := namespace + "/" + name
+	ops, err := c.reconcileEgressService(key, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to plan egress service %s: %v", key, err)
+	}
+	return formatOperationsPlan(ops), nil
+}
+
+// Reconciles the OVN configuration of the egress service of the given key.
+// When dryRun is set the operations are only assembled and returned, nothing is transacted
+// and the controller's state is left untouched.
+// This should only be called with the controller locked.
+func (c *Controller) reconcileEgressService(key string, dryRun bool) ([]libovsdb.Operation, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, err
+	}
+
 	es, err := c.egressServiceLister.EgressServices(namespace).Get(name)
 	if err != nil && !apierrors.IsNotFound(err) {
-		return err
+		return nil, err
 	}
 
 	svc, err := c.serviceLister.Services(namespace).Get(name)
 	if err != nil && !apierrors.IsNotFound(err) {
-		return err
+		return nil, err
 	}
 
 	state := c.services[key]
+	clearServiceResources := func() ([]libovsdb.Operation, error) {
+		if dryRun {
+			return c.clearServiceResourcesOps(key, state)
+		}
+		return nil, c.clearServiceResourcesAndRequeue(key, state)
+	}
 
 	// Clean up the service if it is not assigned to any host or was removed
 	if es == nil || len(es.Status.Host) == 0 {
 		klog.V(5).Infof("Egress service %s was removed or is not assigned to any host", key)
 		if state == nil {
 			// The egress service was not configured, nothing to do
-			return nil
+			return nil, nil
 		}
 		// The egress service is configured, but it no longer exists/is assigned to a node,
 		// meaning we should clear all of its resources.
-		return c.clearServiceResourcesAndRequeue(key, state)
+		return clearServiceResources()
 	}
 
 	if svc == nil {
 		klog.V(5).Infof("Service %s doesn't exist", key)
 		if state == nil {
 			// The service object was deleted, and the egress service was not configured, nothing to do.
-			return nil
+			return nil, nil
 		}
 		// The egress service is configured, but the service object was deleted,
 		// meaning we should clear all of its resources.
-		return c.clearServiceResourcesAndRequeue(key, state)
+		return clearServiceResources()
 	}
 
 	// We check if it its host == noSNATHost (cluster manager detected sourceIPBy=Network)
@@ -661,42 +809,43 @@ func (c *Controller) syncEgressService(key string) error {
 	if es.Status.Host == ovntypes.EgressServiceNoSNATHost {
 		if state == nil {
 			// The service does not need SNAT LRPs and was not an allocated egress service.
-			return nil
+			return nil, nil
 		}
 
 		// The EgressService was previously configured but its host changed to ALL.
 		// We don't need to create any OVN objects when host=ALL (or cache the resource) so we delete its existing configuration.
-		return c.clearServiceResourcesAndRequeue(key, state)
+		return clearServiceResources()
 	}
 
 	if state != nil && state.stale {
 		// The service is marked stale because something failed when trying to delete it.
 		// We try to delete it again before doing anything else.
 		klog.Warningf("Cleaning up stale egress service %s", key)
-		return c.clearServiceResourcesAndRequeue(key, state)
+		return clearServiceResources()
 	}
 
 	if len(svc.Status.LoadBalancer.Ingress) == 0 {
 		klog.Infof("EgressService %s/%s does not have an ingress IP")
 		if state == nil {
 			// The service object doesn't have an ingress IP, and the egress service was not configured, nothing to do.
-			return nil
+			return nil, nil
 		}
 		// The egress service is configured, but the service object doesn't have an ingress IP,
 		// meaning we should clear all of its resources.
-		return c.clearServiceResourcesAndRequeue(key, state)
+		return clearServiceResources()
 	}
 
-	v4LocalEndpoints, v6LocalEndpoints, v4RemoteEndpoints, v6RemoteEndpoints, err := c.allEndpointsFor(svc)
+	v4LocalEndpoints, v6LocalEndpoints, v4RemoteEndpoints, v6RemoteEndpoints, epsSummary, err := c.allEndpointsFor(svc)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	skipped := epsSummary.skipped
+	v4LocalEndpoints, v6LocalEndpoints, v4RemoteEndpoints, v6RemoteEndpoints, epsSummary = reroutableEndpointsFor(svc, es.Spec.IPFamily,
+		v4LocalEndpoints, v6LocalEndpoints, v4RemoteEndpoints, v6RemoteEndpoints)
 
-	totalEps := len(v4LocalEndpoints) + len(v6LocalEndpoints) + len(v4RemoteEndpoints) + len(v6RemoteEndpoints)
-
-	if totalEps == 0 && state != nil {
+	if epsSummary.total() == 0 && state != nil {
 		klog.V(4).Infof("EgressService %s/%s does not have any endpoints, removing any existing configuration", namespace, name)
-		return c.clearServiceResourcesAndRequeue(key, state)
+		return clearServiceResources()
 	}
 
 	if state == nil {
@@ -709,11 +858,17 @@ func (c *Controller) syncEgressService(key string) error {
 			v4RemoteEndpoints: sets.New[string](),
 			v6RemoteEndpoints: sets.New[string](),
 		}
-		c.services[key] = newState
+		if !dryRun {
+			c.services[key] = newState
+		}
 		if _, exists := c.nodes[nodeName]; !exists {
 			nodeState, err := c.nodeStateFor(nodeName)
 			if err != nil {
-				return err
+				return nil, err
+			}
+			if dryRun {
+				// the node is only cached while planning, so the nexthops can be validated
+				defer delete(c.nodes, nodeName)
 			}
 			c.nodes[nodeName] = nodeState
 		}
@@ -722,13 +877,13 @@ func (c *Controller) syncEgressService(key string) error {
 
 	if state.node != es.Status.Host {
 		klog.Errorf("EgressService %s/%s is configured for %s instead of %s, removing any existing configuration", namespace, name, state.node, es.Status.Host)
-		return c.clearServiceResourcesAndRequeue(key, state)
+		return clearServiceResources()
 	}
 
 	node, ok := c.nodes[state.node]
 	if !ok || node.draining {
 		klog.Warningf("EgressService %s/%s is configured on non-existing or not ready node %s, removing", namespace, name, state.node)
-		return c.clearServiceResourcesAndRequeue(key, state)
+		return clearServiceResources()
 	}
 
 	// At this point the states are valid and we should create the proper logical router policies and static routes.
@@ -751,42 +906,94 @@ func (c *Controller) syncEgressService(key string) error {
 	// v[4|6]LocalEndpoints represents endpoints local to the current zone.
 	// v[4|6]RemoteEndpoints represents endpoints remote to the current zone.
 	// If a service is hosted in the local zone:
-	//  - create LRPs for local endpoints with mgmt IP as a nextHop
-	//  - create LRSRs for remote endpoints with mgmt IP as a nextHop
+	//  - create LRPs for local endpoints with mgmt (or gateway router) IP as a nextHop
+	//  - create LRSRs for remote endpoints with mgmt (or gateway router) IP as a nextHop
 	// If a service is hosted in a remote zone:
 	//  - create LRPs for local endpoints with node router transit IP as a next hop as a nextHop
 	//  - do nothing for remote endpoints
 	// When IC is disabled v[4|6]RemoteEndpoints are empty,
 	// service is considered to be local and LRSRs are not modified.
 
-	nextHopV4 := node.v4MgmtIP.String()
-	nextHopV6 := node.v6MgmtIP.String()
-	svcNodeInLocalZone := true
-	if config.OVNKubernetesFeature.EnableInterconnect {
-		var zoneKnown bool
-		svcNodeInLocalZone, zoneKnown = c.nodesZoneState[node.name]
-		if !zoneKnown {
-			return fmt.Errorf("failed to verify whether the svc node %s is in the local zone", node.name)
+	nextHopV4, nextHopV6, svcNodeInLocalZone, err := c.nextHopsFor(node)
+	if err != nil {
+		return nil, err
+	}
+
+	// The nexthops of the endpoints that are already configured change when the service
+	// node moves between zones or its addresses change. In that case we re-program the
+	// policies of all the local endpoints and the static routes of all the remote ones,
+	// removing the routes if the service node is no longer in the local zone.
+	v4LocalToUpdate, v6LocalToUpdate := []string{}, []string{}
+	v4RemoteToUpdate, v6RemoteToUpdate := []string{}, []string{}
+	v4RemoteStaleRoutes, v6RemoteStaleRoutes := []string{}, []string{}
+	if nextHopV4 != state.v4NextHop || nextHopV6 != state.v6NextHop || svcNodeInLocalZone != state.svcNodeInLocalZone {
+		klog.V(4).Infof("EgressService %s/%s nexthops changed from %s/%s to %s/%s, updating existing configuration",
+			namespace, name, state.v4NextHop, state.v6NextHop, nextHopV4, nextHopV6)
+		v4LocalToUpdate = v4LocalEndpoints.Intersection(state.v4LocalEndpoints).UnsortedList()
+		v6LocalToUpdate = v6LocalEndpoints.Intersection(state.v6LocalEndpoints).UnsortedList()
+		if svcNodeInLocalZone {
+			v4RemoteToUpdate = v4RemoteEndpoints.Intersection(state.v4RemoteEndpoints).UnsortedList()
+			v6RemoteToUpdate = v6RemoteEndpoints.Intersection(state.v6RemoteEndpoints).UnsortedList()
+		} else {
+			v4RemoteStaleRoutes = v4RemoteEndpoints.Intersection(state.v4RemoteEndpoints).UnsortedList()
+			v6RemoteStaleRoutes = v6RemoteEndpoints.Intersection(state.v6RemoteEndpoints).UnsortedList()
+		}
+	}
+
+	// Skip programming reroutes for a family that has no endpoints at all,
+	// its nexthop may not even be set on single stack nodes.
+	if epsSummary.hasEmptyFamily() {
+		if !epsSummary.hasV4() {
+			klog.V(5).Infof("EgressService %s/%s has no IPv4 endpoints, skipping IPv4 reroutes", namespace, name)
+			v4LocalToAdd, v4RemoteToAdd = nil, nil
+			v4LocalToUpdate, v4RemoteToUpdate = nil, nil
 		}
-		if !svcNodeInLocalZone {
-			nextHopV4 = node.transitIPV4.String()
-			nextHopV6 = node.transitIPV6.String()
+		if !epsSummary.hasV6() {
+			klog.V(5).Infof("EgressService %s/%s has no IPv6 endpoints, skipping IPv6 reroutes", namespace, name)
+			v6LocalToAdd, v6RemoteToAdd = nil, nil
+			v6LocalToUpdate, v6RemoteToUpdate = nil, nil
 		}
 	}
 
 	allOps := []libovsdb.Operation{}
-	createOps, err := c.createOrUpdateLogicalRouterPoliciesOps(key, nextHopV4, nextHopV6, v4LocalToAdd, v6LocalToAdd)
+
+	// An endpoint moved from another egress service, whose reconcile did not remove
+	// it yet, would be rerouted by the policies of both services: the policies and
+	// routes of the other service are removed for the endpoints claimed here.
+	moved, err := c.endpointsMovedFromOtherServices(key, v4LocalToAdd, v6LocalToAdd, v4RemoteToAdd, v6RemoteToAdd)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for _, m := range moved {
+		klog.Infof("Endpoints %v of egress service %s moved to %s, removing their existing configuration",
+			m.all(), m.key, key)
+		deleteOps, err := c.deleteLogicalRouterPoliciesOps(m.key, m.v4Local, m.v6Local)
+		if err != nil {
+			return nil, err
+		}
+		allOps = append(allOps, deleteOps...)
+		deleteOps, err = c.deleteLogicalRouterStaticRoutesOps(m.key, m.v4Remote, m.v6Remote)
+		if err != nil {
+			return nil, err
+		}
+		allOps = append(allOps, deleteOps...)
+	}
+
+	createOps, err := c.createOrUpdateLogicalRouterPoliciesOps(key, nextHopV4, nextHopV6,
+		append(v4LocalToAdd, v4LocalToUpdate...), append(v6LocalToAdd, v6LocalToUpdate...))
+	if err != nil {
+		return nil, err
 	}
 	allOps = append(allOps, createOps...)
 
-	if svcNodeInLocalZone && (len(v4RemoteToAdd)+len(v6RemoteToAdd)) > 0 {
+	v4RemoteToConfigure := append(v4RemoteToAdd, v4RemoteToUpdate...)
+	v6RemoteToConfigure := append(v6RemoteToAdd, v6RemoteToUpdate...)
+	if svcNodeInLocalZone && (len(v4RemoteToConfigure)+len(v6RemoteToConfigure)) > 0 {
 		// when IC is disabled v[4|6]RemoteToRemove are empty and no ops are created
 		// with IC enabled, when service is hosted in the local zone, create static routes for remote endpoints
-		createOps, err = c.createOrUpdateLogicalRouterStaticRoutesOps(key, node.v4MgmtIP.String(), node.v6MgmtIP.String(), v4RemoteToAdd, v6RemoteToAdd)
+		createOps, err = c.createOrUpdateLogicalRouterStaticRoutesOps(key, nextHopV4, nextHopV6, v4RemoteToConfigure, v6RemoteToConfigure)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		allOps = append(allOps, createOps...)
 	}
@@ -796,33 +1003,51 @@ func (c *Controller) syncEgressService(key string) error {
 	// https://github.com/ovn-org/ovn-kubernetes/blob/master/docs/egress-ip.md#pod-to-node-ip-traffic
 	createOps, err = c.addPodIPsToAddressSetOps(createIPAddressNetSlice(v4LocalToAdd, v6LocalToAdd))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	allOps = append(allOps, createOps...)
 
 	deleteOps, err := c.deleteLogicalRouterPoliciesOps(key, v4LocalToRemove, v6LocalToRemove)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	allOps = append(allOps, deleteOps...)
 
 	// when IC is disabled v[4|6]RemoteToRemove are empty and no ops are created
 	// with IC enabled, it is safer to avoid checking whether the service is local
 	// as we want to remove the static routes configured for the specific remote pods.
-	deleteOps, err = c.deleteLogicalRouterStaticRoutesOps(key, v4RemoteToRemove, v6RemoteToRemove)
+	deleteOps, err = c.deleteLogicalRouterStaticRoutesOps(key,
+		append(v4RemoteToRemove, v4RemoteStaleRoutes...), append(v6RemoteToRemove, v6RemoteStaleRoutes...))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	allOps = append(allOps, deleteOps...)
 
 	deleteOps, err = c.deletePodIPsFromAddressSetOps(createIPAddressNetSlice(v4LocalToRemove, v6LocalToRemove))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	allOps = append(allOps, deleteOps...)
 
+	if dryRun {
+		return allOps, nil
+	}
+
+	// reported ahead of the transaction, which fails if an egressIP policy has the same
+	// match and priority as the policy of an endpoint
+	c.reportEgressIPConflicts(es, state, v4LocalEndpoints.UnsortedList(), v6LocalEndpoints.UnsortedList())
+
 	if _, err := libovsdbops.TransactAndCheck(c.nbClient, allOps); err != nil {
-		return fmt.Errorf("failed to update router policies for %s, err: %v", key, err)
+		return nil, fmt.Errorf("failed to update router policies for %s, err: %v", key, err)
+	}
+
+	for _, m := range moved {
+		if other, found := c.services[m.key]; found {
+			other.v4LocalEndpoints.Delete(m.v4Local...)
+			other.v6LocalEndpoints.Delete(m.v6Local...)
+			other.v4RemoteEndpoints.Delete(m.v4Remote...)
+			other.v6RemoteEndpoints.Delete(m.v6Remote...)
+		}
 	}
 
 	state.v4LocalEndpoints.Insert(v4LocalToAdd...)
@@ -834,7 +1059,141 @@ func (c *Controller) syncEgressService(key string) error {
 	state.v4RemoteEndpoints.Delete(v4RemoteToRemove...)
 	state.v6RemoteEndpoints.Insert(v6RemoteToAdd...)
 	state.v6RemoteEndpoints.Delete(v6RemoteToRemove...)
-	return nil
+
+	state.v4NextHop = nextHopV4
+	state.v6NextHop = nextHopV6
+	state.svcNodeInLocalZone = svcNodeInLocalZone
+
+	// Endpoints remote to the zone are only rerouted by the zone hosting the service node,
+	// the zone of each endpoint reroutes its own endpoints otherwise.
+	rerouted := len(state.v4LocalEndpoints) + len(state.v6LocalEndpoints)
+	skippedRemoteZone := len(state.v4RemoteEndpoints) + len(state.v6RemoteEndpoints)
+	if svcNodeInLocalZone {
+		rerouted += skippedRemoteZone
+		skippedRemoteZone = 0
+	}
+	c.recordEndpointSelectionEvent(key, es, state, fmt.Sprintf("Selected endpoints: rerouted=%d, skipped-host=%d, skipped-fqdn=%d, skipped-remote-zone=%d",
+		rerouted, skipped.host, skipped.fqdn, skippedRemoteZone))
+	return allOps, nil
+}
+
+// movedEndpoints holds the endpoints configured for the egress service key that
+// are claimed by another egress service
+type movedEndpoints struct {
+	key      string
+	v4Local  []string
+	v6Local  []string
+	v4Remote []string
+	v6Remote []string
+}
+
+func (m movedEndpoints) all() []string {
+	all := append(append([]string{}, m.v4Local...), m.v6Local...)
+	return append(append(all, m.v4Remote...), m.v6Remote...)
+}
+
+// Returns, sorted by service key, the endpoints to add for the egress service key that are
+// still configured for other egress services that no longer select them, like an endpoint
+// that moved from one service to another before the reconcile of the service it left.
+// An endpoint selected by several egress services at once is not considered moved.
+// This should only be called with the controller locked.
+func (c *Controller) endpointsMovedFromOtherServices(key string, v4LocalToAdd, v6LocalToAdd, v4RemoteToAdd, v6RemoteToAdd []string) ([]movedEndpoints, error) {
+	claimed := func(configured, selected sets.Set[string], toAdd []string) []string {
+		var found []string
+		for _, ep := range toAdd {
+			if configured.Has(ep) && !selected.Has(ep) {
+				found = append(found, ep)
+			}
+		}
+		return found
+	}
+	moved := []movedEndpoints{}
+	for otherKey, other := range c.services {
+		if otherKey == key {
+			continue
+		}
+		namespace, name, err := cache.SplitMetaNamespaceKey(otherKey)
+		if err != nil {
+			return nil, err
+		}
+		// The endpoints still selected by the other service, none if it was removed
+		v4Local, v6Local, v4Remote, v6Remote := sets.New[string](), sets.New[string](), sets.New[string](), sets.New[string]()
+		otherSvc, err := c.serviceLister.Services(namespace).Get(name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if otherSvc != nil {
+			v4Local, v6Local, v4Remote, v6Remote, _, err = c.allEndpointsFor(otherSvc)
+			if err != nil {
+				return nil, err
+			}
+		}
+		m := movedEndpoints{
+			key:      otherKey,
+			v4Local:  claimed(other.v4LocalEndpoints, v4Local, v4LocalToAdd),
+			v6Local:  claimed(other.v6LocalEndpoints, v6Local, v6LocalToAdd),
+			v4Remote: claimed(other.v4RemoteEndpoints, v4Remote, v4RemoteToAdd),
+			v6Remote: claimed(other.v6RemoteEndpoints, v6Remote, v6RemoteToAdd),
+		}
+		if len(m.all()) > 0 {
+			moved = append(moved, m)
+		}
+	}
+	sort.Slice(moved, func(i, j int) bool { return moved[i].key < moved[j].key })
+	return moved, nil
+}
+
+// Records an event with the given endpoint selection summary on the EgressService.
+// The event is only recorded when the summary differs from the last one recorded for
+// the service, and at most once every endpointSelectionEventInterval so a service
+// with churning endpoints does not flood the events. A summary arriving before the
+// interval elapsed requeues the service for the remainder of the interval, so that
+// the latest summary is eventually recorded.
+// This should only be called with the controller locked.
+func (c *Controller) recordEndpointSelectionEvent(key string, es *egressserviceapi.EgressService, state *svcState, message string) {
+	if c.recorder == nil || message == state.lastSelectionEvent {
+		return
+	}
+	now := time.Now()
+	if elapsed := now.Sub(state.lastSelectionEventTime); elapsed < endpointSelectionEventInterval {
+		klog.V(5).Infof("Delaying endpoint selection event for EgressService %s/%s: %s", es.Namespace, es.Name, message)
+		if c.egressServiceQueue != nil {
+			c.egressServiceQueue.AddAfter(key, endpointSelectionEventInterval-elapsed)
+		}
+		return
+	}
+	c.recorder.Event(es, corev1.EventTypeNormal, endpointSelectionEventReason, message)
+	state.lastSelectionEvent = message
+	state.lastSelectionEventTime = now
+}
+
+// Logs and records a warning event on the EgressService listing the given local endpoints it
+// reroutes that are also rerouted by an egressIP logical router policy of a higher or equal priority,
+// which takes precedence over or competes with the egress service reroute. The conflicts are
+// only reported when they change, so a service whose conflicts persist does not flood the events.
+// This should only be called with the controller locked.
+func (c *Controller) reportEgressIPConflicts(es *egressserviceapi.EgressService, state *svcState, v4Endpoints, v6Endpoints []string) {
+	conflicts, err := c.findEgressIPConflicts(v4Endpoints, v6Endpoints)
+	if err != nil {
+		klog.Errorf("Failed to check the egressIP conflicts of EgressService %s/%s: %v", es.Namespace, es.Name, err)
+		return
+	}
+	message := ""
+	if len(conflicts) > 0 {
+		message = "Endpoints also rerouted by egressIP policies of a higher or equal priority: " + strings.Join(conflicts, ", ")
+	}
+	if message == state.lastEgressIPConflicts {
+		return
+	}
+	state.lastEgressIPConflicts = message
+	if message == "" {
+		klog.Infof("EgressService %s/%s endpoints no longer conflict with egressIP policies", es.Namespace, es.Name)
+		return
+	}
+	klog.Warningf("EgressService %s/%s: %s", es.Namespace, es.Name, message)
+	if c.recorder != nil {
+		c.recorder.Event(es, corev1.EventTypeWarning, egressIPConflictEventReason, message)
+	}
 }
 
 // Removes all the logical router policies that belong to the egress service.
@@ -844,6 +1203,23 @@ func (c *Controller) syncEgressService(key string) error {
 func (c *Controller) clearServiceResourcesAndRequeue(key string, svcState *svcState) error {
 	svcState.stale = true
 
+	deleteOps, err := c.clearServiceResourcesOps(key, svcState)
+	if err != nil {
+		return err
+	}
+
+	if _, err := libovsdbops.TransactAndCheck(c.nbClient, deleteOps); err != nil {
+		return fmt.Errorf("failed to clean egress service resources for %s, err: %v", key, err)
+	}
+
+	delete(c.services, key)
+	c.egressServiceQueue.Add(key)
+	return nil
+}
+
+// Returns the operations that remove all the logical router policies, static routes
+// and address set entries that belong to the egress service.
+func (c *Controller) clearServiceResourcesOps(key string, svcState *svcState) ([]libovsdb.Operation, error) {
 	p := func(item *nbdb.LogicalRouterPolicy) bool {
 		return item.ExternalIDs[svcExternalIDKey] == key
 	}
@@ -851,12 +1227,12 @@ func (c *Controller) clearServiceResourcesAndRequeue(key string, svcState *svcSt
 	deleteOps := []libovsdb.Operation{}
 	deleteOps, err := libovsdbops.DeleteLogicalRouterPolicyWithPredicateOps(c.nbClient, deleteOps, ovntypes.OVNClusterRouter, p)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	delAddrSetOps, err := c.deletePodIPsFromAddressSetOps(createIPAddressNetSlice(svcState.v4LocalEndpoints.UnsortedList(), svcState.v6LocalEndpoints.UnsortedList()))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	deleteOps = append(deleteOps, delAddrSetOps...)
 
@@ -866,15 +1242,44 @@ func (c *Controller) clearServiceResourcesAndRequeue(key string, svcState *svcSt
 		}
 		deleteOps, err = libovsdbops.DeleteLogicalRouterStaticRoutesWithPredicateOps(c.nbClient, deleteOps, ovntypes.OVNClusterRouter, p)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	if _, err := libovsdbops.TransactAndCheck(c.nbClient, deleteOps); err != nil {
-		return fmt.Errorf("failed to clean egress service resources for %s, err: %v", key, err)
-	}
+	return deleteOps, nil
+}
 
-	delete(c.services, key)
-	c.egressServiceQueue.Add(key)
-	return nil
+// Returns a human readable plan of the given operations, one operation per line
+// with the table it applies to, its row, mutations and conditions.
+func formatOperationsPlan(ops []libovsdb.Operation) string {
+	if len(ops) == 0 {
+		return "no changes"
+	}
+	lines := make([]string, 0, len(ops))
+	for _, op := range ops {
+		line := op.Op + " " + op.Table
+		if len(op.Row) > 0 {
+			row, err := json.Marshal(op.Row)
+			if err != nil {
+				row = []byte(fmt.Sprintf("%v", op.Row))
+			}
+			line += " row=" + string(row)
+		}
+		if len(op.Mutations) > 0 {
+			mutations, err := json.Marshal(op.Mutations)
+			if err != nil {
+				mutations = []byte(fmt.Sprintf("%v", op.Mutations))
+			}
+			line += " mutations=" + string(mutations)
+		}
+		if len(op.Where) > 0 {
+			conditions := make([]string, 0, len(op.Where))
+			for _, condition := range op.Where {
+				conditions = append(conditions, fmt.Sprintf("%s %s %v", condition.Column, condition.Function, condition.Value))
+			}
+			line += " where=" + strings.Join(conditions, " && ")
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
 }
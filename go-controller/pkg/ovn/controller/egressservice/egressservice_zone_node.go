@@ -41,11 +41,14 @@ func (c *Controller) onNodeUpdate(oldObj, newObj interface{}) {
 	oldNodeReady := nodeIsReady(oldNode)
 	newNodeReady := nodeIsReady(newNode)
 
-	// We only care about node updates that relate to readiness, labels or
-	// addresses
+	// We only care about node updates that relate to readiness, labels,
+	// addresses, zone or the management and transit switch IPs used as nexthops
 	if labels.Equals(oldNodeLabels, newNodeLabels) &&
 		oldNodeReady == newNodeReady &&
-		!util.NodeHostAddressesAnnotationChanged(oldNode, newNode) {
+		!util.NodeHostAddressesAnnotationChanged(oldNode, newNode) &&
+		!util.NodeZoneAnnotationChanged(oldNode, newNode) &&
+		!util.NodeSubnetAnnotationChanged(oldNode, newNode) &&
+		!util.NodeTransitSwitchPortAddrAnnotationChanged(oldNode, newNode) {
 		return
 	}
 
@@ -116,12 +119,23 @@ func (c *Controller) syncNode(key string) error {
 	if err != nil && !apierrors.IsNotFound(err) {
 		return err
 	}
+	wasInLocalZone, zoneKnown := c.nodesZoneState[nodeName]
 	if n != nil {
 		c.nodesZoneState[nodeName] = c.isNodeInLocalZone(n)
 	} else {
 		delete(c.nodesZoneState, nodeName)
 	}
 
+	// When the node moves between the local and a remote zone the nexthops of
+	// the services hosted on it and of the endpoints it hosts change, so we
+	// requeue all of the configured services to update their policies and routes.
+	if n != nil && zoneKnown && wasInLocalZone != c.nodesZoneState[nodeName] {
+		klog.V(4).Infof("Node %s moved zones, requeueing all egress services", nodeName)
+		for svcKey := range c.services {
+			c.egressServiceQueue.Add(svcKey)
+		}
+	}
+
 	if err := c.deleteLegacyDefaultNoRerouteNodePolicies(c.nbClient, nodeName); err != nil {
 		return err
 	}
@@ -177,6 +191,28 @@ func (c *Controller) syncNode(key string) error {
 			}
 		}
 		delete(c.nodes, nodeName)
+		return nil
+	}
+
+	// The management, transit switch or gateway router IPs of the node changed, the services
+	// hosted on it use stale nexthops so we update the cached IPs and requeue
+	// the services to update their policies and routes.
+	newState, err := c.nodeStateFor(nodeName)
+	if err != nil {
+		return err
+	}
+	if !newState.v4MgmtIP.Equal(state.v4MgmtIP) || !newState.v6MgmtIP.Equal(state.v6MgmtIP) ||
+		!newState.transitIPV4.Equal(state.transitIPV4) || !newState.transitIPV6.Equal(state.transitIPV6) ||
+		!newState.gwRouterIPV4.Equal(state.gwRouterIPV4) || !newState.gwRouterIPV6.Equal(state.gwRouterIPV6) {
+		klog.V(4).Infof("Node %s nexthop IPs changed, requeueing the egress services it hosts", nodeName)
+		state.v4MgmtIP, state.v6MgmtIP = newState.v4MgmtIP, newState.v6MgmtIP
+		state.transitIPV4, state.transitIPV6 = newState.transitIPV4, newState.transitIPV6
+		state.gwRouterIPV4, state.gwRouterIPV6 = newState.gwRouterIPV4, newState.gwRouterIPV6
+		for svcKey, svcState := range c.services {
+			if svcState.node == nodeName {
+				c.egressServiceQueue.Add(svcKey)
+			}
+		}
 	}
 
 	return nil
@@ -233,7 +269,54 @@ func (c *Controller) nodeStateFor(name string) (*nodeState, error) {
 		}
 	}
 
-	return &nodeState{name: name, v4MgmtIP: v4IP, v6MgmtIP: v6IP, transitIPV4: transitIPV4, transitIPV6: transitIPV6}, nil
+	var gwRouterIPV4, gwRouterIPV6 net.IP
+	if config.OVNKubernetesFeature.EgressServiceRerouteTarget == config.EgressServiceRerouteGatewayRouter {
+		gwRouterIPs, err := util.ParseNodeGatewayRouterLRPAddrs(node)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch gateway router IP for node %s: %w", node.Name, err)
+		}
+		for _, ip := range gwRouterIPs {
+			if utilnet.IsIPv4(ip.IP) {
+				gwRouterIPV4 = ip.IP
+			} else if utilnet.IsIPv6(ip.IP) {
+				gwRouterIPV6 = ip.IP
+			}
+		}
+	}
+
+	return &nodeState{name: name, v4MgmtIP: v4IP, v6MgmtIP: v6IP, transitIPV4: transitIPV4, transitIPV6: transitIPV6,
+		gwRouterIPV4: gwRouterIPV4, gwRouterIPV6: gwRouterIPV6}, nil
+}
+
+// Returns the nexthops the egress service reroutes should use for a service
+// hosted on the given node when it is in the local zone: the node's mgmt IPs,
+// or its gateway router join port IPs if the traffic is rerouted to the
+// gateway router.
+func localNextHopsFor(node *nodeState) (string, string) {
+	if config.OVNKubernetesFeature.EgressServiceRerouteTarget == config.EgressServiceRerouteGatewayRouter {
+		return node.gwRouterIPV4.String(), node.gwRouterIPV6.String()
+	}
+	return node.v4MgmtIP.String(), node.v6MgmtIP.String()
+}
+
+// Returns the nexthops the egress service reroutes should use for a service
+// hosted on the given node and whether the node is in the local zone.
+// The nexthops are the node's local nexthops (see localNextHopsFor), or its
+// transit switch IPs when IC is enabled and the node is in a remote zone.
+func (c *Controller) nextHopsFor(node *nodeState) (string, string, bool, error) {
+	if !config.OVNKubernetesFeature.EnableInterconnect {
+		v4NextHop, v6NextHop := localNextHopsFor(node)
+		return v4NextHop, v6NextHop, true, nil
+	}
+	inLocalZone, zoneKnown := c.nodesZoneState[node.name]
+	if !zoneKnown {
+		return "", "", false, fmt.Errorf("failed to verify whether the svc node %s is in the local zone", node.name)
+	}
+	if !inLocalZone {
+		return node.transitIPV4.String(), node.transitIPV6.String(), false, nil
+	}
+	v4NextHop, v6NextHop := localNextHopsFor(node)
+	return v4NextHop, v6NextHop, true, nil
 }
 
 // isNodeInLocalZone returns whether the provided node is in a zone local to the zone controller
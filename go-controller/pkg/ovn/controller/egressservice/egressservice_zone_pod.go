@@ -0,0 +1,62 @@
+package egressservice
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+)
+
+/*
+	We only care about pod deletions: the served pods address set and the
+	logical router policies of a deleted pod that backs an egress service must be
+	removed even if its endpointslice was not updated yet. We queue the egress services
+	that have the pod's IPs as local endpoints, and their sync ignores the endpoints
+	of pods that no longer exist.
+*/
+
+func (c *Controller) onPodDelete(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("couldn't get object from tombstone %#v", obj))
+			return
+		}
+		pod, ok = tombstone.Obj.(*corev1.Pod)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("tombstone contained object that is not a Pod: %#v", obj))
+			return
+		}
+	}
+
+	if pod == nil || pod.Spec.HostNetwork {
+		return
+	}
+	c.queueServicesForPod(pod)
+}
+
+func (c *Controller) queueServicesForPod(pod *corev1.Pod) {
+	podIPs := []string{}
+	for _, podIP := range pod.Status.PodIPs {
+		podIPs = append(podIPs, utilnet.ParseIPSloppy(podIP.IP).String())
+	}
+	if len(podIPs) == 0 {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	for key, state := range c.services {
+		for _, ip := range podIPs {
+			if state.v4LocalEndpoints.Has(ip) || state.v6LocalEndpoints.Has(ip) {
+				klog.V(5).Infof("Queueing egress service %s for deleted pod %s/%s", key, pod.Namespace, pod.Name)
+				c.egressServiceQueue.Add(key)
+				break
+			}
+		}
+	}
+}
@@ -3,9 +3,12 @@ package egressservice
 import (
 	"fmt"
 	"net"
+	"sort"
+	"strings"
 
 	libovsdb "github.com/ovn-org/libovsdb/ovsdb"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	egressserviceapi "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/egressservice/v1"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/libovsdbops"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/controller/services"
@@ -128,13 +131,65 @@ func (c *Controller) onServiceDelete(obj interface{}) {
 	c.egressServiceQueue.Add(key)
 }
 
+// endpointsSummary holds the per IP family endpoint counts of a service as
+// returned by allEndpointsFor, so callers don't have to recombine the sets.
+type endpointsSummary struct {
+	v4Count int
+	v6Count int
+
+	// endpoint addresses that were not selected for reroutes
+	skipped skippedEndpoints
+}
+
+// skippedEndpoints holds the counts of the endpoint addresses of a service
+// that are never rerouted, by the reason they were skipped for.
+type skippedEndpoints struct {
+	host int
+	fqdn int
+	// addresses that are not IPs of the address type of their slice
+	invalid int
+}
+
+// newEndpointsSummary computes the summary for the given endpoint sets.
+func newEndpointsSummary(v4LocalEndpoints, v6LocalEndpoints, v4RemoteEndpoints, v6RemoteEndpoints sets.Set[string]) endpointsSummary {
+	return endpointsSummary{
+		v4Count: len(v4LocalEndpoints) + len(v4RemoteEndpoints),
+		v6Count: len(v6LocalEndpoints) + len(v6RemoteEndpoints),
+	}
+}
+
+// total returns the number of endpoints across both IP families.
+func (s endpointsSummary) total() int {
+	return s.v4Count + s.v6Count
+}
+
+// hasV4 returns true if the service has at least one IPv4 endpoint.
+func (s endpointsSummary) hasV4() bool {
+	return s.v4Count > 0
+}
+
+// hasV6 returns true if the service has at least one IPv6 endpoint.
+func (s endpointsSummary) hasV6() bool {
+	return s.v6Count > 0
+}
+
+// hasEmptyFamily returns true if any of the IP families has no endpoints.
+func (s endpointsSummary) hasEmptyFamily() bool {
+	return !s.hasV4() || !s.hasV6()
+}
+
 // Returns cluster-networked endpoints for the given service grouped by IPv4/IPv6.
 // When IC is disabled v[4|6]LocalEndpoints contains all service endpoints and v[4|6]RemoteEndpoints is not set
 // When IC is enabled v[4|6]LocalEndpoints contains endpoints hosted in the local zone and
 // v[4|6]RemoteEndpoints contains endpoints hosted in remote zones
+// The returned summary holds the per family endpoint counts of all the sets
+// and the counts of the host, FQDN and invalid endpoint addresses that were skipped.
+// Host endpoints are the ones outside of the cluster subnets, invalid ones the
+// addresses of a corrupted slice that are not IPs of its address type, which would
+// otherwise produce bogus reroute policies.
 func (c *Controller) allEndpointsFor(svc *corev1.Service) (
 	v4LocalEndpoints, v6LocalEndpoints, v4RemoteEndpoints, v6RemoteEndpoints sets.Set[string],
-	err error) {
+	summary endpointsSummary, err error) {
 	// Get the endpoint slices associated to the Service
 	esLabelSelector := labels.Set(map[string]string{
 		discovery.LabelServiceName: svc.Name,
@@ -149,17 +204,24 @@ func (c *Controller) allEndpointsFor(svc *corev1.Service) (
 	v6LocalEndpoints = sets.Set[string]{}
 	v4RemoteEndpoints = sets.Set[string]{}
 	v6RemoteEndpoints = sets.Set[string]{}
+	skipped := skippedEndpoints{}
 
 	for _, eps := range endpointSlices {
-		if eps.AddressType == discovery.AddressTypeFQDN {
+		var localEndpoints, remoteEndpoints sets.Set[string]
+		switch eps.AddressType {
+		case discovery.AddressTypeIPv4:
+			localEndpoints, remoteEndpoints = v4LocalEndpoints, v4RemoteEndpoints
+		case discovery.AddressTypeIPv6:
+			localEndpoints, remoteEndpoints = v6LocalEndpoints, v6RemoteEndpoints
+		case discovery.AddressTypeFQDN:
+			for _, ep := range eps.Endpoints {
+				skipped.fqdn += len(ep.Addresses)
+			}
+			continue
+		default:
+			klog.Warningf("Ignoring endpointslice %s/%s of service %s/%s with unsupported address type %q",
+				eps.Namespace, eps.Name, svc.Namespace, svc.Name, eps.AddressType)
 			continue
-		}
-
-		localEndpoints := v4LocalEndpoints
-		remoteEndpoints := v4RemoteEndpoints
-		if eps.AddressType == discovery.AddressTypeIPv6 {
-			localEndpoints = v6LocalEndpoints
-			remoteEndpoints = v6RemoteEndpoints
 		}
 
 		for _, ep := range eps.Endpoints {
@@ -167,6 +229,10 @@ func (c *Controller) allEndpointsFor(svc *corev1.Service) (
 				// ignore endpoints without a node
 				continue
 			}
+			if c.isDeletedPodEndpoint(ep) {
+				// ignore endpoints of deleted pods that were not removed from the slice yet
+				continue
+			}
 			isEpLocal := true
 			if config.OVNKubernetesFeature.EnableInterconnect {
 				var zoneKnown bool
@@ -177,21 +243,76 @@ func (c *Controller) allEndpointsFor(svc *corev1.Service) (
 				}
 			}
 			for _, ip := range ep.Addresses {
-				ipStr := utilnet.ParseIPSloppy(ip).String()
-				if !services.IsHostEndpoint(ipStr) {
-					if isEpLocal {
-						localEndpoints.Insert(ipStr)
-					} else {
-						remoteEndpoints.Insert(ipStr)
-					}
-
+				parsedIP := utilnet.ParseIPSloppy(ip)
+				if parsedIP == nil || utilnet.IsIPv6(parsedIP) != (eps.AddressType == discovery.AddressTypeIPv6) {
+					klog.Warningf("Ignoring invalid address %q of endpointslice %s/%s of service %s/%s with address type %q",
+						ip, eps.Namespace, eps.Name, svc.Namespace, svc.Name, eps.AddressType)
+					skipped.invalid++
+					continue
+				}
+				ipStr := parsedIP.String()
+				if services.IsHostEndpoint(ipStr) {
+					skipped.host++
+					continue
+				}
+				if isEpLocal {
+					localEndpoints.Insert(ipStr)
+				} else {
+					remoteEndpoints.Insert(ipStr)
 				}
 			}
 		}
 	}
+	summary = newEndpointsSummary(v4LocalEndpoints, v6LocalEndpoints, v4RemoteEndpoints, v6RemoteEndpoints)
+	summary.skipped = skipped
 	return
 }
 
+// Returns the endpoints of the service that can be rerouted to its host.
+// The host SNATs the egress traffic of the endpoints to the service's ingress IP
+// of the same family, so the endpoints of a family the service has no ingress IP for
+// are dropped: rerouting them would send their traffic out of the host without the SNAT.
+// Syncing the service with these sets creates and deletes the reroutes of a family
+// together with the SNAT of its ingress IP.
+// The endpoints of a family the egress service is not restricted to are dropped as well.
+func reroutableEndpointsFor(svc *corev1.Service, ipFamily egressserviceapi.IPFamilyMode,
+	v4LocalEndpoints, v6LocalEndpoints, v4RemoteEndpoints, v6RemoteEndpoints sets.Set[string]) (
+	sets.Set[string], sets.Set[string], sets.Set[string], sets.Set[string], endpointsSummary) {
+	rerouteV4, rerouteV6 := false, false
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if utilnet.IsIPv4String(ingress.IP) {
+			rerouteV4 = true
+			continue
+		}
+		if utilnet.IsIPv6String(ingress.IP) {
+			rerouteV6 = true
+		}
+	}
+	switch ipFamily {
+	case egressserviceapi.IPFamilyIPv4:
+		rerouteV6 = false
+	case egressserviceapi.IPFamilyIPv6:
+		rerouteV4 = false
+	}
+	if !rerouteV4 {
+		v4LocalEndpoints, v4RemoteEndpoints = sets.New[string](), sets.New[string]()
+	}
+	if !rerouteV6 {
+		v6LocalEndpoints, v6RemoteEndpoints = sets.New[string](), sets.New[string]()
+	}
+	return v4LocalEndpoints, v6LocalEndpoints, v4RemoteEndpoints, v6RemoteEndpoints,
+		newEndpointsSummary(v4LocalEndpoints, v6LocalEndpoints, v4RemoteEndpoints, v6RemoteEndpoints)
+}
+
+// Returns true if the endpoint targets a pod that no longer exists.
+func (c *Controller) isDeletedPodEndpoint(ep discovery.Endpoint) bool {
+	if c.podLister == nil || ep.TargetRef == nil || ep.TargetRef.Kind != "Pod" {
+		return false
+	}
+	_, err := c.podLister.Pods(ep.TargetRef.Namespace).Get(ep.TargetRef.Name)
+	return apierrors.IsNotFound(err)
+}
+
 func createIPAddressNetSlice(v4ips, v6ips []string) []net.IP {
 	ipAddrs := make([]net.IP, 0)
 	for _, ip := range v4ips {
@@ -231,24 +352,66 @@ func (c *Controller) deletePodIPsFromAddressSetOps(addrSetIPs []net.IP) ([]libov
 	return ops, nil
 }
 
+// Returns an error if the nexthop of a family that has endpoints to reroute is not a valid IP
+// of that family or is not the management or transit switch IP of a node known to the controller.
+// A reroute to a stale nexthop black holes the egress traffic of the endpoints.
+// This should only be called with the controller locked.
+func (c *Controller) validateNextHops(v4NextHop, v6NextHop string, hasV4Endpoints, hasV6Endpoints bool) error {
+	if hasV4Endpoints {
+		ip := utilnet.ParseIPSloppy(v4NextHop)
+		if ip == nil || !utilnet.IsIPv4(ip) {
+			return fmt.Errorf("invalid IPv4 nexthop %q", v4NextHop)
+		}
+		if !c.isNodeNextHop(ip) {
+			return fmt.Errorf("IPv4 nexthop %s does not belong to any known node", ip)
+		}
+	}
+	if hasV6Endpoints {
+		ip := utilnet.ParseIPSloppy(v6NextHop)
+		if ip == nil || !utilnet.IsIPv6(ip) {
+			return fmt.Errorf("invalid IPv6 nexthop %q", v6NextHop)
+		}
+		if !c.isNodeNextHop(ip) {
+			return fmt.Errorf("IPv6 nexthop %s does not belong to any known node", ip)
+		}
+	}
+	return nil
+}
+
+// Returns true if the given IP is the management, transit switch or gateway router IP of a node known to the controller.
+func (c *Controller) isNodeNextHop(ip net.IP) bool {
+	for _, node := range c.nodes {
+		if ip.Equal(node.v4MgmtIP) || ip.Equal(node.v6MgmtIP) ||
+			ip.Equal(node.transitIPV4) || ip.Equal(node.transitIPV6) ||
+			ip.Equal(node.gwRouterIPV4) || ip.Equal(node.gwRouterIPV6) {
+			return true
+		}
+	}
+	return false
+}
+
 // Returns the libovsdb operations to create or updates the logical router policies for the service,
-// given its key, the nexthops (mgmt ips) and endpoints to add.
-func (c *Controller) createOrUpdateLogicalRouterPoliciesOps(key, v4MgmtIP, v6MgmtIP string, v4Endpoints, v6Endpoints []string) ([]libovsdb.Operation, error) {
+// given its key, the nexthops (mgmt, gateway router or transit switch ips, see nextHopsFor) and endpoints to add.
+func (c *Controller) createOrUpdateLogicalRouterPoliciesOps(key, v4NextHop, v6NextHop string, v4Endpoints, v6Endpoints []string) ([]libovsdb.Operation, error) {
+	if err := c.validateNextHops(v4NextHop, v6NextHop, len(v4Endpoints) > 0, len(v6Endpoints) > 0); err != nil {
+		return nil, fmt.Errorf("cannot create logical router policies for %s: %v", key, err)
+	}
+
 	allOps := []libovsdb.Operation{}
 	var err error
 
 	for _, addr := range v4Endpoints {
 		lrp := &nbdb.LogicalRouterPolicy{
-			Match:    fmt.Sprintf("ip4.src == %s", addr),
-			Priority: ovntypes.EgressSVCReroutePriority,
-			Nexthops: []string{v4MgmtIP},
+			Match:    rerouteLRPMatch(addr),
+			Priority: config.OVNKubernetesFeature.EgressServiceReroutePriority,
+			Nexthops: []string{v4NextHop},
 			Action:   nbdb.LogicalRouterPolicyActionReroute,
 			ExternalIDs: map[string]string{
 				svcExternalIDKey: key,
 			},
 		}
 		p := func(item *nbdb.LogicalRouterPolicy) bool {
-			return item.Match == lrp.Match && item.Priority == lrp.Priority && item.ExternalIDs[svcExternalIDKey] == key
+			return rerouteLRPMatchEndpoint(item.Match) == addr && item.Priority == lrp.Priority && item.ExternalIDs[svcExternalIDKey] == key
 		}
 
 		allOps, err = libovsdbops.CreateOrUpdateLogicalRouterPolicyWithPredicateOps(c.nbClient, allOps, ovntypes.OVNClusterRouter, lrp, p)
@@ -259,16 +422,16 @@ func (c *Controller) createOrUpdateLogicalRouterPoliciesOps(key, v4MgmtIP, v6Mgm
 
 	for _, addr := range v6Endpoints {
 		lrp := &nbdb.LogicalRouterPolicy{
-			Match:    fmt.Sprintf("ip6.src == %s", addr),
-			Priority: ovntypes.EgressSVCReroutePriority,
-			Nexthops: []string{v6MgmtIP},
+			Match:    rerouteLRPMatch(addr),
+			Priority: config.OVNKubernetesFeature.EgressServiceReroutePriority,
+			Nexthops: []string{v6NextHop},
 			Action:   nbdb.LogicalRouterPolicyActionReroute,
 			ExternalIDs: map[string]string{
 				svcExternalIDKey: key,
 			},
 		}
 		p := func(item *nbdb.LogicalRouterPolicy) bool {
-			return item.Match == lrp.Match && item.Priority == lrp.Priority && item.ExternalIDs[svcExternalIDKey] == key
+			return rerouteLRPMatchEndpoint(item.Match) == addr && item.Priority == lrp.Priority && item.ExternalIDs[svcExternalIDKey] == key
 		}
 
 		allOps, err = libovsdbops.CreateOrUpdateLogicalRouterPolicyWithPredicateOps(c.nbClient, allOps, ovntypes.OVNClusterRouter, lrp, p)
@@ -280,28 +443,87 @@ func (c *Controller) createOrUpdateLogicalRouterPoliciesOps(key, v4MgmtIP, v6Mgm
 	return allOps, nil
 }
 
-// Returns the libovsdb operations to delete the logical router policies for the service,
-// given its key and endpoints to delete.
-func (c *Controller) deleteLogicalRouterPoliciesOps(key string, v4Endpoints, v6Endpoints []string) ([]libovsdb.Operation, error) {
-	allOps := []libovsdb.Operation{}
-	var err error
+// Returns the match of the logical router policy rerouting the egress traffic of the endpoint,
+// which excludes the traffic towards the configured excluded destination CIDRs of its family.
+func rerouteLRPMatch(addr string) string {
+	isIPv6 := utilnet.IsIPv6String(addr)
+	ipPrefix := "ip4"
+	if isIPv6 {
+		ipPrefix = "ip6"
+	}
+	match := fmt.Sprintf("%s.src == %s", ipPrefix, addr)
+	excluded := []string{}
+	for _, cidr := range config.OVNKubernetesFeature.GetEgressServiceRerouteExcludedCIDRs() {
+		if utilnet.IsIPv6CIDR(cidr) == isIPv6 {
+			excluded = append(excluded, cidr.String())
+		}
+	}
+	switch len(excluded) {
+	case 0:
+		return match
+	case 1:
+		return fmt.Sprintf("%s && %s.dst != %s", match, ipPrefix, excluded[0])
+	}
+	return fmt.Sprintf("%s && %s.dst != {%s}", match, ipPrefix, strings.Join(excluded, ", "))
+}
 
+// Returns the endpoint whose egress traffic is rerouted by a logical router policy with
+// the given match, see rerouteLRPMatch, or an empty string if the match is not one of those.
+func rerouteLRPMatchEndpoint(match string) string {
+	fields := strings.Fields(match)
+	if len(fields) < 3 || (fields[0] != "ip4.src" && fields[0] != "ip6.src") || fields[1] != "==" {
+		return ""
+	}
+	return fields[2]
+}
+
+// Returns, sorted, a description of each of the given endpoints that is also rerouted by a logical
+// router policy not owned by an egress service, like the egressIP ones, of a priority higher than
+// or equal to the egress service reroute priority.
+func (c *Controller) findEgressIPConflicts(v4Endpoints, v6Endpoints []string) ([]string, error) {
+	matches := map[string]string{}
 	for _, addr := range v4Endpoints {
-		match := fmt.Sprintf("ip4.src == %s", addr)
-		p := func(item *nbdb.LogicalRouterPolicy) bool {
-			return item.Match == match && item.Priority == ovntypes.EgressSVCReroutePriority && item.ExternalIDs[svcExternalIDKey] == key
+		matches[fmt.Sprintf("ip4.src == %s", addr)] = addr
+	}
+	for _, addr := range v6Endpoints {
+		matches[fmt.Sprintf("ip6.src == %s", addr)] = addr
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	p := func(item *nbdb.LogicalRouterPolicy) bool {
+		if _, found := item.ExternalIDs[svcExternalIDKey]; found {
+			return false
 		}
-
-		allOps, err = libovsdbops.DeleteLogicalRouterPolicyWithPredicateOps(c.nbClient, allOps, ovntypes.OVNClusterRouter, p)
-		if err != nil {
-			return nil, err
+		_, found := matches[item.Match]
+		return found && item.Action == nbdb.LogicalRouterPolicyActionReroute &&
+			item.Priority >= config.OVNKubernetesFeature.EgressServiceReroutePriority
+	}
+	lrps, err := libovsdbops.FindLogicalRouterPoliciesWithPredicate(c.nbClient, p)
+	if err != nil {
+		return nil, err
+	}
+	conflicts := make([]string, 0, len(lrps))
+	for _, lrp := range lrps {
+		owner := lrp.UUID
+		if name, found := lrp.ExternalIDs["name"]; found {
+			owner = "egressIP " + name
 		}
+		conflicts = append(conflicts, fmt.Sprintf("%s (%s, priority %d)", matches[lrp.Match], owner, lrp.Priority))
 	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
 
-	for _, addr := range v6Endpoints {
-		match := fmt.Sprintf("ip6.src == %s", addr)
+// Returns the libovsdb operations to delete the logical router policies for the service,
+// given its key and endpoints to delete.
+func (c *Controller) deleteLogicalRouterPoliciesOps(key string, v4Endpoints, v6Endpoints []string) ([]libovsdb.Operation, error) {
+	allOps := []libovsdb.Operation{}
+	var err error
+
+	for _, addr := range append(v4Endpoints, v6Endpoints...) {
 		p := func(item *nbdb.LogicalRouterPolicy) bool {
-			return item.Match == match && item.Priority == ovntypes.EgressSVCReroutePriority && item.ExternalIDs[svcExternalIDKey] == key
+			return rerouteLRPMatchEndpoint(item.Match) == addr && item.Priority == config.OVNKubernetesFeature.EgressServiceReroutePriority && item.ExternalIDs[svcExternalIDKey] == key
 		}
 
 		allOps, err = libovsdbops.DeleteLogicalRouterPolicyWithPredicateOps(c.nbClient, allOps, ovntypes.OVNClusterRouter, p)
@@ -314,15 +536,19 @@ func (c *Controller) deleteLogicalRouterPoliciesOps(key string, v4Endpoints, v6E
 }
 
 // Returns the libovsdb operations to create or update the logical router static routes for the service,
-// given its key, the nexthop (mgmt ip) and endpoints to add.
-func (c *Controller) createOrUpdateLogicalRouterStaticRoutesOps(key, v4MgmtIP, v6MgmtIP string, v4Endpoints, v6Endpoints []string) ([]libovsdb.Operation, error) {
+// given its key, the nexthop (mgmt or gateway router ip, see localNextHopsFor) and endpoints to add.
+func (c *Controller) createOrUpdateLogicalRouterStaticRoutesOps(key, v4NextHop, v6NextHop string, v4Endpoints, v6Endpoints []string) ([]libovsdb.Operation, error) {
+	if err := c.validateNextHops(v4NextHop, v6NextHop, len(v4Endpoints) > 0, len(v6Endpoints) > 0); err != nil {
+		return nil, fmt.Errorf("cannot create logical router static routes for %s: %v", key, err)
+	}
+
 	allOps := []libovsdb.Operation{}
 	var err error
 
 	for _, addr := range v4Endpoints {
 		lrsr := &nbdb.LogicalRouterStaticRoute{
 			IPPrefix: addr,
-			Nexthop:  v4MgmtIP,
+			Nexthop:  v4NextHop,
 			Policy:   &nbdb.LogicalRouterStaticRoutePolicySrcIP,
 			ExternalIDs: map[string]string{
 				svcExternalIDKey: key,
@@ -341,14 +567,14 @@ func (c *Controller) createOrUpdateLogicalRouterStaticRoutesOps(key, v4MgmtIP, v
 	for _, addr := range v6Endpoints {
 		lrsr := &nbdb.LogicalRouterStaticRoute{
 			IPPrefix: addr,
-			Nexthop:  v6MgmtIP,
+			Nexthop:  v6NextHop,
 			Policy:   &nbdb.LogicalRouterStaticRoutePolicySrcIP,
 			ExternalIDs: map[string]string{
 				svcExternalIDKey: key,
 			},
 		}
 		p := func(item *nbdb.LogicalRouterStaticRoute) bool {
-			return item.IPPrefix == lrsr.IPPrefix && item.ExternalIDs[svcExternalIDKey] == key && item.Nexthop == lrsr.Nexthop && item.Policy != nil && *item.Policy == nbdb.LogicalRouterStaticRoutePolicySrcIP
+			return item.IPPrefix == lrsr.IPPrefix && item.ExternalIDs[svcExternalIDKey] == key && item.Policy != nil && *item.Policy == nbdb.LogicalRouterStaticRoutePolicySrcIP
 		}
 
 		allOps, err = libovsdbops.CreateOrUpdateLogicalRouterStaticRoutesWithPredicateOps(c.nbClient, allOps, ovntypes.OVNClusterRouter, lrsr, p)
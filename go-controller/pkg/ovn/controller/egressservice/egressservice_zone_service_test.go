@@ -0,0 +1,941 @@
+package egressservice
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	egressserviceapi "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/egressservice/v1"
+	egressservicelisters "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/egressservice/v1/apis/listers/egressservice/v1"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/libovsdbops"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	addressset "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/address_set"
+	libovsdbtest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/libovsdb"
+	ovntypes "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+func newTestEndpointSlice(name, namespace, svcName string, addressType discovery.AddressType, endpoints ...discovery.Endpoint) *discovery.EndpointSlice {
+	return &discovery.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{discovery.LabelServiceName: svcName},
+		},
+		AddressType: addressType,
+		Endpoints:   endpoints,
+	}
+}
+
+func newTestEndpoint(node string, addresses ...string) discovery.Endpoint {
+	return discovery.Endpoint{
+		Addresses: addresses,
+		NodeName:  utilpointer.String(node),
+	}
+}
+
+func Test_allEndpointsForSummary(t *testing.T) {
+	oldClusterSubnet := config.Default.ClusterSubnets
+	oldIC := config.OVNKubernetesFeature.EnableInterconnect
+	defer func() {
+		config.Default.ClusterSubnets = oldClusterSubnet
+		config.OVNKubernetesFeature.EnableInterconnect = oldIC
+	}()
+	_, cidr4, _ := net.ParseCIDR("10.128.0.0/16")
+	_, cidr6, _ := net.ParseCIDR("fe00::/64")
+	config.Default.ClusterSubnets = []config.CIDRNetworkEntry{{CIDR: cidr4, HostSubnetLength: 24}, {CIDR: cidr6, HostSubnetLength: 64}}
+
+	ns := "testns"
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: ns}}
+
+	tests := []struct {
+		name              string
+		interconnect      bool
+		slices            []*discovery.EndpointSlice
+		wantV4Local       []string
+		wantV6Local       []string
+		wantV4Remote      []string
+		wantV6Remote      []string
+		wantSummary       endpointsSummary
+		wantEmptyFamilies bool
+	}{
+		{
+			name: "dual stack without IC",
+			slices: []*discovery.EndpointSlice{
+				newTestEndpointSlice("svc1-ipv4", ns, svc.Name, discovery.AddressTypeIPv4,
+					newTestEndpoint("node1", "10.128.0.5"), newTestEndpoint("node2", "10.128.1.5")),
+				newTestEndpointSlice("svc1-ipv6", ns, svc.Name, discovery.AddressTypeIPv6,
+					newTestEndpoint("node1", "fe00::5")),
+			},
+			wantV4Local:       []string{"10.128.0.5", "10.128.1.5"},
+			wantV6Local:       []string{"fe00::5"},
+			wantSummary:       endpointsSummary{v4Count: 2, v6Count: 1},
+			wantEmptyFamilies: false,
+		},
+		{
+			name: "single stack without IC ignores host and node-less endpoints",
+			slices: []*discovery.EndpointSlice{
+				newTestEndpointSlice("svc1-ipv4", ns, svc.Name, discovery.AddressTypeIPv4,
+					newTestEndpoint("node1", "10.128.0.5"), newTestEndpoint("node1", "192.168.0.5"),
+					discovery.Endpoint{Addresses: []string{"10.128.0.6"}}),
+			},
+			wantV4Local:       []string{"10.128.0.5"},
+			wantSummary:       endpointsSummary{v4Count: 1, skipped: skippedEndpoints{host: 1}},
+			wantEmptyFamilies: true,
+		},
+		{
+			name:         "dual stack with IC splits local and remote endpoints",
+			interconnect: true,
+			slices: []*discovery.EndpointSlice{
+				newTestEndpointSlice("svc1-ipv4", ns, svc.Name, discovery.AddressTypeIPv4,
+					newTestEndpoint("node1", "10.128.0.5"), newTestEndpoint("node2", "10.128.1.5")),
+				newTestEndpointSlice("svc1-ipv6", ns, svc.Name, discovery.AddressTypeIPv6,
+					newTestEndpoint("node2", "fe00::6")),
+			},
+			wantV4Local:       []string{"10.128.0.5"},
+			wantV4Remote:      []string{"10.128.1.5"},
+			wantV6Remote:      []string{"fe00::6"},
+			wantSummary:       endpointsSummary{v4Count: 2, v6Count: 1},
+			wantEmptyFamilies: false,
+		},
+		{
+			name:         "IC ignores endpoints on nodes with unknown zone",
+			interconnect: true,
+			slices: []*discovery.EndpointSlice{
+				newTestEndpointSlice("svc1-ipv6", ns, svc.Name, discovery.AddressTypeIPv6,
+					newTestEndpoint("node1", "fe00::5"), newTestEndpoint("node3", "fe00::7")),
+			},
+			wantV6Local:       []string{"fe00::5"},
+			wantSummary:       endpointsSummary{v6Count: 1},
+			wantEmptyFamilies: true,
+		},
+		{
+			name: "skips addresses outside of the cluster subnets and invalid addresses",
+			slices: []*discovery.EndpointSlice{
+				newTestEndpointSlice("svc1-ipv4", ns, svc.Name, discovery.AddressTypeIPv4,
+					newTestEndpoint("node1", "10.128.0.5", "172.16.0.5"),
+					newTestEndpoint("node1", "fe00::8", "not-an-ip")),
+			},
+			wantV4Local:       []string{"10.128.0.5"},
+			wantSummary:       endpointsSummary{v4Count: 1, skipped: skippedEndpoints{host: 1, invalid: 2}},
+			wantEmptyFamilies: true,
+		},
+		{
+			name: "skips slices of unsupported address types",
+			slices: []*discovery.EndpointSlice{
+				newTestEndpointSlice("svc1-ipv4", ns, svc.Name, discovery.AddressTypeIPv4,
+					newTestEndpoint("node1", "10.128.0.5")),
+				newTestEndpointSlice("svc1-fqdn", ns, svc.Name, discovery.AddressTypeFQDN,
+					newTestEndpoint("node1", "pod.example.com")),
+				newTestEndpointSlice("svc1-unknown", ns, svc.Name, discovery.AddressType("IPv8"),
+					newTestEndpoint("node1", "10.128.0.6")),
+			},
+			wantV4Local:       []string{"10.128.0.5"},
+			wantSummary:       endpointsSummary{v4Count: 1, skipped: skippedEndpoints{fqdn: 1}},
+			wantEmptyFamilies: true,
+		},
+		{
+			name:              "no endpoints",
+			wantSummary:       endpointsSummary{},
+			wantEmptyFamilies: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.OVNKubernetesFeature.EnableInterconnect = tt.interconnect
+
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, slice := range tt.slices {
+				assert.NoError(t, indexer.Add(slice))
+			}
+			c := &Controller{
+				endpointSliceLister: discoverylisters.NewEndpointSliceLister(indexer),
+				nodesZoneState:      map[string]bool{"node1": true, "node2": false},
+			}
+
+			v4Local, v6Local, v4Remote, v6Remote, summary, err := c.allEndpointsFor(svc)
+			assert.NoError(t, err)
+			assert.Equal(t, sets.New(tt.wantV4Local...), v4Local)
+			assert.Equal(t, sets.New(tt.wantV6Local...), v6Local)
+			assert.Equal(t, sets.New(tt.wantV4Remote...), v4Remote)
+			assert.Equal(t, sets.New(tt.wantV6Remote...), v6Remote)
+			assert.Equal(t, tt.wantSummary, summary)
+			assert.Equal(t, len(v4Local)+len(v6Local)+len(v4Remote)+len(v6Remote), summary.total())
+			assert.Equal(t, len(v4Local)+len(v4Remote) > 0, summary.hasV4())
+			assert.Equal(t, len(v6Local)+len(v6Remote) > 0, summary.hasV6())
+			assert.Equal(t, tt.wantEmptyFamilies, summary.hasEmptyFamily())
+		})
+	}
+}
+
+func Test_logicalRouterPoliciesCustomPriority(t *testing.T) {
+	oldPriority := config.OVNKubernetesFeature.EgressServiceReroutePriority
+	defer func() {
+		config.OVNKubernetesFeature.EgressServiceReroutePriority = oldPriority
+	}()
+	config.OVNKubernetesFeature.EgressServiceReroutePriority = 90
+
+	g := gomega.NewGomegaWithT(t)
+	clusterRouter := &nbdb.LogicalRouter{
+		Name: ovntypes.OVNClusterRouter,
+		UUID: ovntypes.OVNClusterRouter + "-UUID",
+	}
+	otherLRP := &nbdb.LogicalRouterPolicy{
+		UUID:        "other-lrp-UUID",
+		Match:       "ip4.src == 10.128.0.5",
+		Priority:    ovntypes.EgressSVCReroutePriority,
+		Action:      nbdb.LogicalRouterPolicyActionReroute,
+		Nexthops:    []string{"10.128.0.2"},
+		ExternalIDs: map[string]string{svcExternalIDKey: "testns/svc2"},
+	}
+	clusterRouter.Policies = []string{otherLRP.UUID}
+	nbClient, cleanup, err := libovsdbtest.NewNBTestHarness(libovsdbtest.TestSetup{
+		NBData: []libovsdbtest.TestData{clusterRouter.DeepCopy(), otherLRP.DeepCopy()},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Error creating NB: %v", err)
+	}
+	t.Cleanup(cleanup.Cleanup)
+
+	c := &Controller{
+		nbClient: nbClient,
+		nodes: map[string]*nodeState{
+			"node1": {name: "node1", v4MgmtIP: net.ParseIP("10.128.1.2"), v6MgmtIP: net.ParseIP("fe00:10:128:1::2")},
+		},
+	}
+	key := "testns/svc1"
+
+	ops, err := c.createOrUpdateLogicalRouterPoliciesOps(key, "10.128.1.2", "fe00:10:128:1::2", []string{"10.128.1.5"}, []string{"fe00:10:128:1::5"})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = libovsdbops.TransactAndCheck(nbClient, ops)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	v4LRP := &nbdb.LogicalRouterPolicy{
+		UUID:        "v4-lrp-UUID",
+		Match:       "ip4.src == 10.128.1.5",
+		Priority:    90,
+		Action:      nbdb.LogicalRouterPolicyActionReroute,
+		Nexthops:    []string{"10.128.1.2"},
+		ExternalIDs: map[string]string{svcExternalIDKey: key},
+	}
+	v6LRP := &nbdb.LogicalRouterPolicy{
+		UUID:        "v6-lrp-UUID",
+		Match:       "ip6.src == fe00:10:128:1::5",
+		Priority:    90,
+		Action:      nbdb.LogicalRouterPolicyActionReroute,
+		Nexthops:    []string{"fe00:10:128:1::2"},
+		ExternalIDs: map[string]string{svcExternalIDKey: key},
+	}
+	clusterRouter.Policies = []string{otherLRP.UUID, v4LRP.UUID, v6LRP.UUID}
+	g.Expect(nbClient).To(libovsdbtest.HaveData([]libovsdbtest.TestData{clusterRouter, otherLRP, v4LRP, v6LRP}))
+
+	ops, err = c.deleteLogicalRouterPoliciesOps(key, []string{"10.128.1.5"}, []string{"fe00:10:128:1::5"})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = libovsdbops.TransactAndCheck(nbClient, ops)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	clusterRouter.Policies = []string{otherLRP.UUID}
+	g.Expect(nbClient).To(libovsdbtest.HaveData([]libovsdbtest.TestData{clusterRouter, otherLRP}))
+}
+
+func Test_logicalRouterPoliciesExcludedCIDRs(t *testing.T) {
+	oldExcludedCIDRs := config.OVNKubernetesFeature.EgressServiceRerouteExcludedCIDRs
+	defer func() {
+		config.OVNKubernetesFeature.EgressServiceRerouteExcludedCIDRs = oldExcludedCIDRs
+	}()
+
+	g := gomega.NewGomegaWithT(t)
+	clusterRouter := &nbdb.LogicalRouter{
+		Name: ovntypes.OVNClusterRouter,
+		UUID: ovntypes.OVNClusterRouter + "-UUID",
+	}
+	nbClient, cleanup, err := libovsdbtest.NewNBTestHarness(libovsdbtest.TestSetup{
+		NBData: []libovsdbtest.TestData{clusterRouter.DeepCopy()},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Error creating NB: %v", err)
+	}
+	t.Cleanup(cleanup.Cleanup)
+
+	c := &Controller{
+		nbClient: nbClient,
+		nodes: map[string]*nodeState{
+			"node1": {name: "node1", v4MgmtIP: net.ParseIP("10.128.1.2"), v6MgmtIP: net.ParseIP("fe00:10:128:1::2")},
+		},
+	}
+	key := "testns/svc1"
+	policies := func() []string {
+		lrps, err := libovsdbops.FindLogicalRouterPoliciesWithPredicate(nbClient, func(item *nbdb.LogicalRouterPolicy) bool {
+			return item.ExternalIDs[svcExternalIDKey] == key
+		})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		matches := []string{}
+		for _, lrp := range lrps {
+			matches = append(matches, lrp.Match)
+		}
+		return matches
+	}
+	createPolicies := func() {
+		ops, err := c.createOrUpdateLogicalRouterPoliciesOps(key, "10.128.1.2", "fe00:10:128:1::2", []string{"10.128.1.5"}, []string{"fe00:10:128:1::5"})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		_, err = libovsdbops.TransactAndCheck(nbClient, ops)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+
+	// only the source is matched by default
+	createPolicies()
+	g.Expect(policies()).To(gomega.ConsistOf("ip4.src == 10.128.1.5", "ip6.src == fe00:10:128:1::5"))
+
+	// the existing policies are updated with the excluded destinations of their family
+	config.OVNKubernetesFeature.EgressServiceRerouteExcludedCIDRs = "10.128.0.0/14, fe00:10:128::/48,172.30.0.0/16"
+	createPolicies()
+	g.Expect(policies()).To(gomega.ConsistOf(
+		"ip4.src == 10.128.1.5 && ip4.dst != {10.128.0.0/14, 172.30.0.0/16}",
+		"ip6.src == fe00:10:128:1::5 && ip6.dst != fe00:10:128::/48"))
+	g.Expect(rerouteLRPMatchEndpoint("ip4.src == 10.128.1.5 && ip4.dst != {10.128.0.0/14, 172.30.0.0/16}")).To(gomega.Equal("10.128.1.5"))
+	g.Expect(rerouteLRPMatchEndpoint("ip6.src == fe00:10:128:1::5 && ip6.dst != fe00:10:128::/48")).To(gomega.Equal("fe00:10:128:1::5"))
+	g.Expect(rerouteLRPMatchEndpoint("ip4.dst == 10.128.1.5")).To(gomega.BeEmpty())
+
+	ops, err := c.deleteLogicalRouterPoliciesOps(key, []string{"10.128.1.5"}, []string{"fe00:10:128:1::5"})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = libovsdbops.TransactAndCheck(nbClient, ops)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(policies()).To(gomega.BeEmpty())
+}
+
+func Test_logicalRouterPoliciesRerouteTarget(t *testing.T) {
+	oldTarget := config.OVNKubernetesFeature.EgressServiceRerouteTarget
+	oldIC := config.OVNKubernetesFeature.EnableInterconnect
+	defer func() {
+		config.OVNKubernetesFeature.EgressServiceRerouteTarget = oldTarget
+		config.OVNKubernetesFeature.EnableInterconnect = oldIC
+	}()
+	config.OVNKubernetesFeature.EnableInterconnect = false
+
+	tests := []struct {
+		name          string
+		target        string
+		wantV4NextHop string
+		wantV6NextHop string
+	}{
+		{
+			name:          "reroute to the management port",
+			target:        config.EgressServiceRerouteManagementPort,
+			wantV4NextHop: "10.128.1.2",
+			wantV6NextHop: "fe00:10:128:1::2",
+		},
+		{
+			name:          "reroute to the gateway router port",
+			target:        config.EgressServiceRerouteGatewayRouter,
+			wantV4NextHop: "100.64.0.2",
+			wantV6NextHop: "fd98::2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.OVNKubernetesFeature.EgressServiceRerouteTarget = tt.target
+			g := gomega.NewGomegaWithT(t)
+			clusterRouter := &nbdb.LogicalRouter{
+				Name: ovntypes.OVNClusterRouter,
+				UUID: ovntypes.OVNClusterRouter + "-UUID",
+			}
+			nbClient, cleanup, err := libovsdbtest.NewNBTestHarness(libovsdbtest.TestSetup{
+				NBData: []libovsdbtest.TestData{clusterRouter.DeepCopy()},
+			}, nil)
+			if err != nil {
+				t.Fatalf("Error creating NB: %v", err)
+			}
+			t.Cleanup(cleanup.Cleanup)
+
+			node := &nodeState{
+				name:         "node1",
+				v4MgmtIP:     net.ParseIP("10.128.1.2"),
+				v6MgmtIP:     net.ParseIP("fe00:10:128:1::2"),
+				gwRouterIPV4: net.ParseIP("100.64.0.2"),
+				gwRouterIPV6: net.ParseIP("fd98::2"),
+			}
+			c := &Controller{
+				nbClient: nbClient,
+				nodes:    map[string]*nodeState{"node1": node},
+			}
+			key := "testns/svc1"
+
+			v4NextHop, v6NextHop, inLocalZone, err := c.nextHopsFor(node)
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(inLocalZone).To(gomega.BeTrue())
+			ops, err := c.createOrUpdateLogicalRouterPoliciesOps(key, v4NextHop, v6NextHop, []string{"10.128.1.5"}, []string{"fe00:10:128:1::5"})
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			_, err = libovsdbops.TransactAndCheck(nbClient, ops)
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+
+			v4LRP := &nbdb.LogicalRouterPolicy{
+				UUID:        "v4-lrp-UUID",
+				Match:       "ip4.src == 10.128.1.5",
+				Priority:    ovntypes.EgressSVCReroutePriority,
+				Action:      nbdb.LogicalRouterPolicyActionReroute,
+				Nexthops:    []string{tt.wantV4NextHop},
+				ExternalIDs: map[string]string{svcExternalIDKey: key},
+			}
+			v6LRP := &nbdb.LogicalRouterPolicy{
+				UUID:        "v6-lrp-UUID",
+				Match:       "ip6.src == fe00:10:128:1::5",
+				Priority:    ovntypes.EgressSVCReroutePriority,
+				Action:      nbdb.LogicalRouterPolicyActionReroute,
+				Nexthops:    []string{tt.wantV6NextHop},
+				ExternalIDs: map[string]string{svcExternalIDKey: key},
+			}
+			clusterRouter.Policies = []string{v4LRP.UUID, v6LRP.UUID}
+			g.Expect(nbClient).To(libovsdbtest.HaveData([]libovsdbtest.TestData{clusterRouter, v4LRP, v6LRP}))
+		})
+	}
+}
+
+func Test_validateNextHops(t *testing.T) {
+	c := &Controller{
+		nodes: map[string]*nodeState{
+			"node1": {
+				name:        "node1",
+				v4MgmtIP:    net.ParseIP("10.128.1.2"),
+				v6MgmtIP:    net.ParseIP("fe00:10:128:1::2"),
+				transitIPV4: net.ParseIP("100.88.0.2"),
+				transitIPV6: net.ParseIP("fd97::2"),
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		v4NextHop      string
+		v6NextHop      string
+		hasV4Endpoints bool
+		hasV6Endpoints bool
+		wantErr        bool
+	}{
+		{
+			name:           "management IPs of a known node",
+			v4NextHop:      "10.128.1.2",
+			v6NextHop:      "fe00:10:128:1::2",
+			hasV4Endpoints: true,
+			hasV6Endpoints: true,
+		},
+		{
+			name:           "transit switch IPs of a known node",
+			v4NextHop:      "100.88.0.2",
+			v6NextHop:      "fd97::2",
+			hasV4Endpoints: true,
+			hasV6Endpoints: true,
+		},
+		{
+			name:           "unset nexthop of a family without endpoints",
+			v4NextHop:      "10.128.1.2",
+			v6NextHop:      "<nil>",
+			hasV4Endpoints: true,
+		},
+		{
+			name:           "stale management IP",
+			v4NextHop:      "10.128.3.2",
+			hasV4Endpoints: true,
+			wantErr:        true,
+		},
+		{
+			name:           "unparsable management IP",
+			v6NextHop:      "<nil>",
+			hasV6Endpoints: true,
+			wantErr:        true,
+		},
+		{
+			name:           "management IP of the wrong family",
+			v4NextHop:      "fe00:10:128:1::2",
+			hasV4Endpoints: true,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.validateNextHops(tt.v4NextHop, tt.v6NextHop, tt.hasV4Endpoints, tt.hasV6Endpoints)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	_, err := c.createOrUpdateLogicalRouterPoliciesOps("testns/svc1", "10.128.3.2", "", []string{"10.128.1.5"}, nil)
+	assert.ErrorContains(t, err, "IPv4 nexthop 10.128.3.2 does not belong to any known node")
+}
+
+func Test_planEgressService(t *testing.T) {
+	oldClusterSubnet := config.Default.ClusterSubnets
+	oldIC := config.OVNKubernetesFeature.EnableInterconnect
+	defer func() {
+		config.Default.ClusterSubnets = oldClusterSubnet
+		config.OVNKubernetesFeature.EnableInterconnect = oldIC
+	}()
+	_, cidr4, _ := net.ParseCIDR("10.128.0.0/16")
+	config.Default.ClusterSubnets = []config.CIDRNetworkEntry{{CIDR: cidr4, HostSubnetLength: 24}}
+	config.OVNKubernetesFeature.EnableInterconnect = true
+
+	g := gomega.NewGomegaWithT(t)
+	clusterRouter := &nbdb.LogicalRouter{
+		Name: ovntypes.OVNClusterRouter,
+		UUID: ovntypes.OVNClusterRouter + "-UUID",
+	}
+	nbClient, cleanup, err := libovsdbtest.NewNBTestHarness(libovsdbtest.TestSetup{
+		NBData: []libovsdbtest.TestData{clusterRouter.DeepCopy()},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Error creating NB: %v", err)
+	}
+	t.Cleanup(cleanup.Cleanup)
+
+	controllerName := "test-controller"
+	addressSetFactory := addressset.NewOvnAddressSetFactory(nbClient, true, false)
+	_, err = addressSetFactory.EnsureAddressSet(GetEgressServiceAddrSetDbIDs(controllerName))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	newIndexer := func(objs ...interface{}) cache.Indexer {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		for _, obj := range objs {
+			g.Expect(indexer.Add(obj)).To(gomega.Succeed())
+		}
+		return indexer
+	}
+	es := &egressserviceapi.EgressService{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "testns"},
+		Spec:       egressserviceapi.EgressServiceSpec{SourceIPBy: egressserviceapi.SourceIPLoadBalancer},
+		Status:     egressserviceapi.EgressServiceStatus{Host: "node1"},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "testns"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "1.1.1.1"}}},
+		},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node1",
+			Annotations: map[string]string{
+				"k8s.ovn.org/node-subnets":                    "{\"default\":[\"10.128.1.0/24\"]}",
+				"k8s.ovn.org/node-transit-switch-port-ifaddr": "{\"ipv4\":\"100.88.0.2/16\"}",
+			},
+		},
+	}
+	slice := newTestEndpointSlice("svc1-ipv4", "testns", "svc1", discovery.AddressTypeIPv4,
+		newTestEndpoint("node1", "10.128.1.5"), newTestEndpoint("node2", "10.128.2.5"))
+
+	c := &Controller{
+		controllerName:      controllerName,
+		nbClient:            nbClient,
+		addressSetFactory:   addressSetFactory,
+		services:            map[string]*svcState{},
+		nodes:               map[string]*nodeState{},
+		nodesZoneState:      map[string]bool{"node1": true, "node2": false},
+		egressServiceLister: egressservicelisters.NewEgressServiceLister(newIndexer(es)),
+		serviceLister:       corelisters.NewServiceLister(newIndexer(svc)),
+		endpointSliceLister: discoverylisters.NewEndpointSliceLister(newIndexer(slice)),
+		nodeLister:          corelisters.NewNodeLister(newIndexer(node)),
+	}
+
+	plan, err := c.PlanEgressService("testns", "svc1")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(plan).To(gomega.MatchRegexp(`insert Logical_Router_Policy row=.*"match":"ip4.src == 10.128.1.5".*"nexthops":"10.128.1.2"`))
+	g.Expect(plan).To(gomega.MatchRegexp(`insert Logical_Router_Static_Route row=.*"ip_prefix":"10.128.2.5".*"nexthop":"10.128.1.2"`))
+	g.Expect(plan).To(gomega.MatchRegexp(`mutate Address_Set mutations=.*"10.128.1.5"`))
+	g.Expect(plan).To(gomega.MatchRegexp(`mutate Logical_Router mutations=.*"policies","insert"`))
+	g.Expect(plan).To(gomega.MatchRegexp(`mutate Logical_Router mutations=.*"static_routes","insert"`))
+	g.Expect(plan).NotTo(gomega.ContainSubstring(`"ip4.src == 10.128.2.5"`))
+
+	// planning leaves both the database and the controller's state untouched
+	g.Expect(c.services).To(gomega.BeEmpty())
+	g.Expect(c.nodes).To(gomega.BeEmpty())
+	addrSet, err := addressSetFactory.GetAddressSet(GetEgressServiceAddrSetDbIDs(controllerName))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	v4IPs, _ := addrSet.GetIPs()
+	g.Expect(v4IPs).To(gomega.BeEmpty())
+	router, err := libovsdbops.GetLogicalRouter(nbClient, &nbdb.LogicalRouter{Name: ovntypes.OVNClusterRouter})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(router.Policies).To(gomega.BeEmpty())
+	g.Expect(router.StaticRoutes).To(gomega.BeEmpty())
+}
+
+func Test_endpointSelectionEvent(t *testing.T) {
+	oldClusterSubnet := config.Default.ClusterSubnets
+	oldIC := config.OVNKubernetesFeature.EnableInterconnect
+	defer func() {
+		config.Default.ClusterSubnets = oldClusterSubnet
+		config.OVNKubernetesFeature.EnableInterconnect = oldIC
+	}()
+	_, cidr4, _ := net.ParseCIDR("10.128.0.0/16")
+	config.Default.ClusterSubnets = []config.CIDRNetworkEntry{{CIDR: cidr4, HostSubnetLength: 24}}
+	config.OVNKubernetesFeature.EnableInterconnect = true
+
+	g := gomega.NewGomegaWithT(t)
+	clusterRouter := &nbdb.LogicalRouter{
+		Name: ovntypes.OVNClusterRouter,
+		UUID: ovntypes.OVNClusterRouter + "-UUID",
+	}
+	nbClient, cleanup, err := libovsdbtest.NewNBTestHarness(libovsdbtest.TestSetup{
+		NBData: []libovsdbtest.TestData{clusterRouter.DeepCopy()},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Error creating NB: %v", err)
+	}
+	t.Cleanup(cleanup.Cleanup)
+
+	controllerName := "test-controller"
+	addressSetFactory := addressset.NewOvnAddressSetFactory(nbClient, true, false)
+	_, err = addressSetFactory.EnsureAddressSet(GetEgressServiceAddrSetDbIDs(controllerName))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	newIndexer := func(objs ...interface{}) cache.Indexer {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		for _, obj := range objs {
+			g.Expect(indexer.Add(obj)).To(gomega.Succeed())
+		}
+		return indexer
+	}
+	// the service is hosted on node2, in a zone remote to the controller's
+	es := &egressserviceapi.EgressService{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "testns"},
+		Spec:       egressserviceapi.EgressServiceSpec{SourceIPBy: egressserviceapi.SourceIPLoadBalancer},
+		Status:     egressserviceapi.EgressServiceStatus{Host: "node2"},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "testns"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "1.1.1.1"}}},
+		},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node2",
+			Annotations: map[string]string{
+				"k8s.ovn.org/node-subnets":                    "{\"default\":[\"10.128.2.0/24\"]}",
+				"k8s.ovn.org/node-transit-switch-port-ifaddr": "{\"ipv4\":\"100.88.0.3/16\"}",
+			},
+		},
+	}
+	ipv4Slice := newTestEndpointSlice("svc1-ipv4", "testns", "svc1", discovery.AddressTypeIPv4,
+		newTestEndpoint("node1", "10.128.1.5", "10.128.1.6"), newTestEndpoint("node2", "10.128.2.5"),
+		newTestEndpoint("node1", "192.168.0.5"))
+	fqdnSlice := newTestEndpointSlice("svc1-fqdn", "testns", "svc1", discovery.AddressTypeFQDN,
+		newTestEndpoint("node1", "pod.example.com"))
+
+	recorder := record.NewFakeRecorder(10)
+	queue := &delayRecordingQueue{}
+	c := &Controller{
+		controllerName:      controllerName,
+		recorder:            recorder,
+		egressServiceQueue:  queue,
+		nbClient:            nbClient,
+		addressSetFactory:   addressSetFactory,
+		services:            map[string]*svcState{},
+		nodes:               map[string]*nodeState{},
+		nodesZoneState:      map[string]bool{"node1": true, "node2": false},
+		egressServiceLister: egressservicelisters.NewEgressServiceLister(newIndexer(es)),
+		serviceLister:       corelisters.NewServiceLister(newIndexer(svc)),
+		endpointSliceLister: discoverylisters.NewEndpointSliceLister(newIndexer(ipv4Slice, fqdnSlice)),
+		nodeLister:          corelisters.NewNodeLister(newIndexer(node)),
+	}
+
+	key := "testns/svc1"
+	g.Expect(c.syncEgressService(key)).To(gomega.Succeed())
+	g.Expect(recorder.Events).To(gomega.Receive(gomega.Equal(
+		"Normal EndpointsSelected Selected endpoints: rerouted=2, skipped-host=1, skipped-fqdn=1, skipped-remote-zone=1")))
+
+	// syncing the service again with the same endpoints does not record another event
+	g.Expect(c.syncEgressService(key)).To(gomega.Succeed())
+	g.Expect(recorder.Events).NotTo(gomega.Receive())
+
+	// changed summaries are not recorded until the interval since the last event
+	// elapsed, the service being requeued for the remainder of the interval instead
+	ipv4Slice.Endpoints = ipv4Slice.Endpoints[1:]
+	g.Expect(c.syncEgressService(key)).To(gomega.Succeed())
+	g.Expect(recorder.Events).NotTo(gomega.Receive())
+	ipv4Slice.Endpoints = append(ipv4Slice.Endpoints, newTestEndpoint("node1", "10.128.1.7"))
+	g.Expect(c.syncEgressService(key)).To(gomega.Succeed())
+	g.Expect(recorder.Events).NotTo(gomega.Receive())
+	g.Expect(queue.delayed).To(gomega.HaveLen(2))
+	for _, item := range queue.delayed {
+		g.Expect(item.key).To(gomega.Equal(key))
+		g.Expect(item.delay).To(gomega.And(gomega.BeNumerically(">", 0), gomega.BeNumerically("<=", endpointSelectionEventInterval)))
+	}
+
+	// the requeued sync, once the interval elapsed, records the latest summary only
+	c.services[key].lastSelectionEventTime = time.Now().Add(-endpointSelectionEventInterval)
+	g.Expect(c.syncEgressService(key)).To(gomega.Succeed())
+	g.Expect(recorder.Events).To(gomega.Receive(gomega.Equal(
+		"Normal EndpointsSelected Selected endpoints: rerouted=1, skipped-host=1, skipped-fqdn=1, skipped-remote-zone=1")))
+	g.Expect(recorder.Events).NotTo(gomega.Receive())
+}
+
+// delayRecordingQueue records the items added to the queue with a delay
+type delayRecordingQueue struct {
+	workqueue.RateLimitingInterface
+	delayed []delayedItem
+}
+
+type delayedItem struct {
+	key   interface{}
+	delay time.Duration
+}
+
+func (q *delayRecordingQueue) AddAfter(item interface{}, duration time.Duration) {
+	q.delayed = append(q.delayed, delayedItem{key: item, delay: duration})
+}
+
+func Test_egressIPConflictEvent(t *testing.T) {
+	oldClusterSubnet := config.Default.ClusterSubnets
+	oldIC := config.OVNKubernetesFeature.EnableInterconnect
+	defer func() {
+		config.Default.ClusterSubnets = oldClusterSubnet
+		config.OVNKubernetesFeature.EnableInterconnect = oldIC
+	}()
+	_, cidr4, _ := net.ParseCIDR("10.128.0.0/16")
+	config.Default.ClusterSubnets = []config.CIDRNetworkEntry{{CIDR: cidr4, HostSubnetLength: 24}}
+	config.OVNKubernetesFeature.EnableInterconnect = true
+
+	g := gomega.NewGomegaWithT(t)
+	// 10.128.1.5 is also rerouted by an egressIP policy of a higher priority than the egress
+	// service one, 10.128.1.6 by one of a lower priority the egress service reroute wins over
+	conflictingLRP := &nbdb.LogicalRouterPolicy{
+		UUID:        "eip1-lrp-UUID",
+		Match:       "ip4.src == 10.128.1.5",
+		Priority:    config.OVNKubernetesFeature.EgressServiceReroutePriority + 1,
+		Action:      nbdb.LogicalRouterPolicyActionReroute,
+		Nexthops:    []string{"100.64.0.4"},
+		ExternalIDs: map[string]string{"name": "eip1"},
+	}
+	lowerPriorityLRP := &nbdb.LogicalRouterPolicy{
+		UUID:        "eip2-lrp-UUID",
+		Match:       "ip4.src == 10.128.1.6",
+		Priority:    ovntypes.EgressIPReroutePriority,
+		Action:      nbdb.LogicalRouterPolicyActionReroute,
+		Nexthops:    []string{"100.64.0.4"},
+		ExternalIDs: map[string]string{"name": "eip2"},
+	}
+	clusterRouter := &nbdb.LogicalRouter{
+		Name:     ovntypes.OVNClusterRouter,
+		UUID:     ovntypes.OVNClusterRouter + "-UUID",
+		Policies: []string{conflictingLRP.UUID, lowerPriorityLRP.UUID},
+	}
+	nbClient, cleanup, err := libovsdbtest.NewNBTestHarness(libovsdbtest.TestSetup{
+		NBData: []libovsdbtest.TestData{conflictingLRP, lowerPriorityLRP, clusterRouter},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Error creating NB: %v", err)
+	}
+	t.Cleanup(cleanup.Cleanup)
+
+	controllerName := "test-controller"
+	addressSetFactory := addressset.NewOvnAddressSetFactory(nbClient, true, false)
+	_, err = addressSetFactory.EnsureAddressSet(GetEgressServiceAddrSetDbIDs(controllerName))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	newIndexer := func(objs ...interface{}) cache.Indexer {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		for _, obj := range objs {
+			g.Expect(indexer.Add(obj)).To(gomega.Succeed())
+		}
+		return indexer
+	}
+	es := &egressserviceapi.EgressService{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "testns"},
+		Spec:       egressserviceapi.EgressServiceSpec{SourceIPBy: egressserviceapi.SourceIPLoadBalancer},
+		Status:     egressserviceapi.EgressServiceStatus{Host: "node2"},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "testns"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "1.1.1.1"}}},
+		},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node2",
+			Annotations: map[string]string{
+				"k8s.ovn.org/node-subnets":                    "{\"default\":[\"10.128.2.0/24\"]}",
+				"k8s.ovn.org/node-transit-switch-port-ifaddr": "{\"ipv4\":\"100.88.0.3/16\"}",
+			},
+		},
+	}
+	ipv4Slice := newTestEndpointSlice("svc1-ipv4", "testns", "svc1", discovery.AddressTypeIPv4,
+		newTestEndpoint("node1", "10.128.1.5"), newTestEndpoint("node1", "10.128.1.6"))
+
+	recorder := record.NewFakeRecorder(10)
+	c := &Controller{
+		controllerName:      controllerName,
+		recorder:            recorder,
+		nbClient:            nbClient,
+		addressSetFactory:   addressSetFactory,
+		services:            map[string]*svcState{},
+		nodes:               map[string]*nodeState{},
+		nodesZoneState:      map[string]bool{"node1": true, "node2": false},
+		egressServiceLister: egressservicelisters.NewEgressServiceLister(newIndexer(es)),
+		serviceLister:       corelisters.NewServiceLister(newIndexer(svc)),
+		endpointSliceLister: discoverylisters.NewEndpointSliceLister(newIndexer(ipv4Slice)),
+		nodeLister:          corelisters.NewNodeLister(newIndexer(node)),
+	}
+
+	key := "testns/svc1"
+	g.Expect(c.syncEgressService(key)).To(gomega.Succeed())
+	g.Expect(recorder.Events).To(gomega.Receive(gomega.Equal(
+		"Warning EgressIPConflict Endpoints also rerouted by egressIP policies of a higher or equal priority: " +
+			"10.128.1.5 (egressIP eip1, priority 102)")))
+	g.Expect(recorder.Events).To(gomega.Receive(gomega.HavePrefix("Normal EndpointsSelected")))
+
+	// the same conflicts are not reported again
+	g.Expect(c.syncEgressService(key)).To(gomega.Succeed())
+	g.Expect(recorder.Events).NotTo(gomega.Receive())
+
+	// nor is the lack of conflicts once the conflicting endpoint is gone
+	ipv4Slice.Endpoints = ipv4Slice.Endpoints[1:]
+	g.Expect(c.syncEgressService(key)).To(gomega.Succeed())
+	g.Expect(recorder.Events).NotTo(gomega.Receive())
+	g.Expect(c.services[key].lastEgressIPConflicts).To(gomega.BeEmpty())
+}
+
+func Test_endpointMovedBetweenServices(t *testing.T) {
+	oldClusterSubnet := config.Default.ClusterSubnets
+	oldIC := config.OVNKubernetesFeature.EnableInterconnect
+	defer func() {
+		config.Default.ClusterSubnets = oldClusterSubnet
+		config.OVNKubernetesFeature.EnableInterconnect = oldIC
+	}()
+	_, cidr4, _ := net.ParseCIDR("10.128.0.0/16")
+	config.Default.ClusterSubnets = []config.CIDRNetworkEntry{{CIDR: cidr4, HostSubnetLength: 24}}
+	config.OVNKubernetesFeature.EnableInterconnect = true
+
+	g := gomega.NewGomegaWithT(t)
+	clusterRouter := &nbdb.LogicalRouter{
+		Name: ovntypes.OVNClusterRouter,
+		UUID: ovntypes.OVNClusterRouter + "-UUID",
+	}
+	nbClient, cleanup, err := libovsdbtest.NewNBTestHarness(libovsdbtest.TestSetup{
+		NBData: []libovsdbtest.TestData{clusterRouter.DeepCopy()},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Error creating NB: %v", err)
+	}
+	t.Cleanup(cleanup.Cleanup)
+
+	controllerName := "test-controller"
+	addressSetFactory := addressset.NewOvnAddressSetFactory(nbClient, true, false)
+	_, err = addressSetFactory.EnsureAddressSet(GetEgressServiceAddrSetDbIDs(controllerName))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	newIndexer := func(objs ...interface{}) cache.Indexer {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		for _, obj := range objs {
+			g.Expect(indexer.Add(obj)).To(gomega.Succeed())
+		}
+		return indexer
+	}
+	newEgressService := func(name string) (*egressserviceapi.EgressService, *corev1.Service) {
+		es := &egressserviceapi.EgressService{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "testns"},
+			Spec:       egressserviceapi.EgressServiceSpec{SourceIPBy: egressserviceapi.SourceIPLoadBalancer},
+			Status:     egressserviceapi.EgressServiceStatus{Host: "node1"},
+		}
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "testns"},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+			Status: corev1.ServiceStatus{
+				LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "1.1.1.1"}}},
+			},
+		}
+		return es, svc
+	}
+	es1, svc1 := newEgressService("svc1")
+	es2, svc2 := newEgressService("svc2")
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node1",
+			Annotations: map[string]string{
+				"k8s.ovn.org/node-subnets":                    "{\"default\":[\"10.128.1.0/24\"]}",
+				"k8s.ovn.org/node-transit-switch-port-ifaddr": "{\"ipv4\":\"100.88.0.2/16\"}",
+			},
+		},
+	}
+	slice1 := newTestEndpointSlice("svc1-ipv4", "testns", "svc1", discovery.AddressTypeIPv4,
+		newTestEndpoint("node1", "10.128.1.5"), newTestEndpoint("node1", "10.128.1.6"))
+	slice2 := newTestEndpointSlice("svc2-ipv4", "testns", "svc2", discovery.AddressTypeIPv4,
+		newTestEndpoint("node1", "10.128.1.7"))
+
+	c := &Controller{
+		controllerName:      controllerName,
+		nbClient:            nbClient,
+		addressSetFactory:   addressSetFactory,
+		services:            map[string]*svcState{},
+		nodes:               map[string]*nodeState{},
+		nodesZoneState:      map[string]bool{"node1": true},
+		egressServiceLister: egressservicelisters.NewEgressServiceLister(newIndexer(es1, es2)),
+		serviceLister:       corelisters.NewServiceLister(newIndexer(svc1, svc2)),
+		endpointSliceLister: discoverylisters.NewEndpointSliceLister(newIndexer(slice1, slice2)),
+		nodeLister:          corelisters.NewNodeLister(newIndexer(node)),
+	}
+	policiesOf := func(key string) []string {
+		lrps, err := libovsdbops.FindLogicalRouterPoliciesWithPredicate(nbClient, func(item *nbdb.LogicalRouterPolicy) bool {
+			return item.ExternalIDs[svcExternalIDKey] == key
+		})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		matches := []string{}
+		for _, lrp := range lrps {
+			matches = append(matches, lrp.Match)
+		}
+		return matches
+	}
+
+	g.Expect(c.syncEgressService("testns/svc1")).To(gomega.Succeed())
+	g.Expect(c.syncEgressService("testns/svc2")).To(gomega.Succeed())
+	g.Expect(policiesOf("testns/svc1")).To(gomega.ConsistOf("ip4.src == 10.128.1.5", "ip4.src == 10.128.1.6"))
+	g.Expect(policiesOf("testns/svc2")).To(gomega.ConsistOf("ip4.src == 10.128.1.7"))
+
+	// 10.128.1.6 moves to svc2, which is reconciled before svc1: the policy of svc1 is removed
+	slice1.Endpoints = slice1.Endpoints[:1]
+	slice2.Endpoints = append(slice2.Endpoints, newTestEndpoint("node1", "10.128.1.6"))
+	g.Expect(c.syncEgressService("testns/svc2")).To(gomega.Succeed())
+	g.Expect(policiesOf("testns/svc1")).To(gomega.ConsistOf("ip4.src == 10.128.1.5"))
+	g.Expect(policiesOf("testns/svc2")).To(gomega.ConsistOf("ip4.src == 10.128.1.6", "ip4.src == 10.128.1.7"))
+	g.Expect(c.services["testns/svc1"].v4LocalEndpoints.UnsortedList()).To(gomega.ConsistOf("10.128.1.5"))
+
+	// the later reconcile of svc1 leaves the endpoint of svc2 in place, served pods included
+	g.Expect(c.syncEgressService("testns/svc1")).To(gomega.Succeed())
+	g.Expect(policiesOf("testns/svc1")).To(gomega.ConsistOf("ip4.src == 10.128.1.5"))
+	g.Expect(policiesOf("testns/svc2")).To(gomega.ConsistOf("ip4.src == 10.128.1.6", "ip4.src == 10.128.1.7"))
+	addrSet, err := addressSetFactory.GetAddressSet(GetEgressServiceAddrSetDbIDs(controllerName))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	v4IPs, _ := addrSet.GetIPs()
+	g.Expect(v4IPs).To(gomega.ConsistOf("10.128.1.5", "10.128.1.6", "10.128.1.7"))
+
+	// 10.128.1.5 is selected by svc2 as well while svc1 still selects it: it did not
+	// move, so the policy of svc1 is kept and the conflicting policy of svc2 fails
+	// its sync until svc1 releases the endpoint, no policy being deleted meanwhile
+	slice2.Endpoints = append(slice2.Endpoints, newTestEndpoint("node1", "10.128.1.5"))
+	g.Expect(c.syncEgressService("testns/svc2")).NotTo(gomega.Succeed())
+	g.Expect(policiesOf("testns/svc1")).To(gomega.ConsistOf("ip4.src == 10.128.1.5"))
+	g.Expect(policiesOf("testns/svc2")).To(gomega.ConsistOf("ip4.src == 10.128.1.6", "ip4.src == 10.128.1.7"))
+	g.Expect(c.services["testns/svc1"].v4LocalEndpoints.UnsortedList()).To(gomega.ConsistOf("10.128.1.5"))
+	g.Expect(c.services["testns/svc2"].v4LocalEndpoints.UnsortedList()).To(gomega.ConsistOf("10.128.1.6", "10.128.1.7"))
+	g.Expect(c.syncEgressService("testns/svc1")).To(gomega.Succeed())
+	g.Expect(policiesOf("testns/svc1")).To(gomega.ConsistOf("ip4.src == 10.128.1.5"))
+	g.Expect(policiesOf("testns/svc2")).To(gomega.ConsistOf("ip4.src == 10.128.1.6", "ip4.src == 10.128.1.7"))
+}
@@ -510,6 +510,7 @@ func (oc *DefaultNetworkController) Run(ctx context.Context) error {
 		if err = oc.egressSvcController.Run(oc.wg, 1); err != nil {
 			return err
 		}
+		metrics.SetEgressServicePlanFunc(oc.egressSvcController.PlanEgressService)
 	}
 
 	if config.OVNKubernetesFeature.EnableMultiExternalGateway {
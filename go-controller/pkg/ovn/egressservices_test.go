@@ -146,6 +146,7 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 							discovery.LabelServiceName: "svc1",
 						},
 					},
+					AddressType: discovery.AddressTypeIPv4,
 					Endpoints: []discovery.Endpoint{
 						{
 							Addresses: []string{"10.128.1.5"},
@@ -162,6 +163,7 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 							discovery.LabelServiceName: "svc2",
 						},
 					},
+					AddressType: discovery.AddressTypeIPv4,
 					Endpoints: []discovery.Endpoint{
 						{
 							Addresses: []string{"10.128.2.5"},
@@ -178,6 +180,7 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 							discovery.LabelServiceName: "svc3",
 						},
 					},
+					AddressType: discovery.AddressTypeIPv4,
 					Endpoints: []discovery.Endpoint{
 						{
 							Addresses: []string{"10.128.2.50"},
@@ -194,6 +197,7 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 							discovery.LabelServiceName: "svc4",
 						},
 					},
+					AddressType: discovery.AddressTypeIPv4,
 					Endpoints: []discovery.Endpoint{
 						{
 							Addresses: []string{"10.128.1.27"},
@@ -350,6 +354,116 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 		})
 
+		ginkgo.It("should delete the stale resources of a service deleted while the controller was down", func() {
+			app.Action = func(ctx *cli.Context) error {
+				namespaceT := *newNamespace("testns")
+				node1 := nodeFor(node1Name, node1IPv4, node1IPv6, node1IPv4Subnet, node1IPv6Subnet, "", "")
+				config.IPv6Mode = true
+
+				esvc1 := egressserviceapi.EgressService{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1",
+						Namespace: "testns",
+					},
+					Spec: egressserviceapi.EgressServiceSpec{
+						SourceIPBy: egressserviceapi.SourceIPLoadBalancer,
+					},
+					Status: egressserviceapi.EgressServiceStatus{
+						Host: node1Name,
+					},
+				}
+				svc1 := lbSvcFor("testns", "svc1")
+
+				svc1EpSlice := discovery.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1-epslice",
+						Namespace: "testns",
+						Labels: map[string]string{
+							discovery.LabelServiceName: "svc1",
+						},
+					},
+					AddressType: discovery.AddressTypeIPv4,
+					Endpoints: []discovery.Endpoint{
+						{
+							Addresses: []string{"10.128.1.5"},
+							NodeName:  &node1.Name,
+						},
+					},
+				}
+
+				staleLRP := egressServiceRouterPolicy("staleLRP-UUID", "testns/gonesvc", "10.128.1.6", "10.128.1.2") // the service was deleted
+				staleLRSR := egressServiceStaticRoute("staleLRSR-UUID", "testns/gonesvc", "10.128.2.6", "10.128.1.2")
+				toKeepLRP := egressServiceRouterPolicy("toKeepLRP-UUID", "testns/svc1", "10.128.1.5", "10.128.1.2")
+
+				clusterRouter := &nbdb.LogicalRouter{
+					Name:         types.OVNClusterRouter,
+					UUID:         types.OVNClusterRouter + "-UUID",
+					Policies:     []string{"staleLRP-UUID", "toKeepLRP-UUID"},
+					StaticRoutes: []string{"staleLRSR-UUID"},
+				}
+
+				dbSetup := libovsdbtest.TestSetup{
+					NBData: []libovsdbtest.TestData{
+						staleLRP,
+						staleLRSR,
+						toKeepLRP,
+						clusterRouter,
+					},
+				}
+
+				fakeOVN.startWithDBSetup(dbSetup,
+					&v1.NamespaceList{
+						Items: []v1.Namespace{
+							namespaceT,
+						},
+					},
+					&v1.NodeList{
+						Items: []v1.Node{
+							*node1,
+						},
+					},
+					&v1.ServiceList{
+						Items: []v1.Service{
+							svc1,
+						},
+					},
+					&discovery.EndpointSliceList{
+						Items: []discovery.EndpointSlice{
+							svc1EpSlice,
+						},
+					},
+					&egressserviceapi.EgressServiceList{
+						Items: []egressserviceapi.EgressService{
+							esvc1,
+						},
+					},
+				)
+
+				servedPodsASdbIDs := egresssvc.GetEgressServiceAddrSetDbIDs(DefaultNetworkControllerName)
+				_, err := fakeOVN.asf.NewAddressSet(servedPodsASdbIDs, []net.IP{net.ParseIP("10.128.1.5"), net.ParseIP("10.128.1.6")})
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+				fakeOVN.InitAndRunEgressSVCController()
+				clusterRouter.Policies = []string{"toKeepLRP-UUID"}
+				clusterRouter.StaticRoutes = []string{}
+				expectedDatabaseState := []libovsdbtest.TestData{
+					toKeepLRP,
+					clusterRouter,
+				}
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+				fakeOVN.asf.EventuallyExpectAddressSetWithIPs(servedPodsASdbIDs, []string{"10.128.1.5"})
+				return nil
+			}
+
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+
 		ginkgo.It("OVN-IC: should delete stale logical router policies and static routes", func() {
 			app.Action = func(ctx *cli.Context) error {
 				namespaceT := *newNamespace("testns")
@@ -413,6 +527,7 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 							discovery.LabelServiceName: "svc1",
 						},
 					},
+					AddressType: discovery.AddressTypeIPv4,
 					Endpoints: []discovery.Endpoint{
 						{
 							Addresses: []string{"10.128.1.5"},
@@ -433,6 +548,7 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 							discovery.LabelServiceName: "svc2",
 						},
 					},
+					AddressType: discovery.AddressTypeIPv4,
 					Endpoints: []discovery.Endpoint{
 						{
 							Addresses: []string{"10.128.2.6"},
@@ -775,13 +891,11 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 			ginkgotable.Entry("IC Enabled, node1 is in the local zone, node2 in remote", true),
 		)
 
-		ginkgotable.DescribeTable("should delete resources when host changes to ALL", func(interconnectEnabled bool) {
+		ginkgo.It("should create/delete the lrps of an IP family together with its load balancer ingress IP", func() {
 			app.Action = func(ctx *cli.Context) error {
 				namespaceT := *newNamespace("testns")
 				config.IPv6Mode = true
-				config.OVNKubernetesFeature.EnableInterconnect = interconnectEnabled
 				node1 := nodeFor(node1Name, node1IPv4, node1IPv6, node1IPv4Subnet, node1IPv6Subnet, node1transitIPv4, node1transitIPv6)
-				node2 := nodeFor(node2Name, node2IPv4, node2IPv6, node2IPv4Subnet, node2IPv6Subnet, node2transitIPv4, node2transitIPv6)
 				clusterRouter := &nbdb.LogicalRouter{
 					Name: types.OVNClusterRouter,
 					UUID: types.OVNClusterRouter + "-UUID",
@@ -793,7 +907,7 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					},
 				}
 
-				ginkgo.By("creating a service with v4 and v6 endpoints allocated on the first node")
+				ginkgo.By("creating a service with v4 and v6 endpoints that has only a v4 ingress IP")
 				esvc1 := egressserviceapi.EgressService{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "svc1",
@@ -802,9 +916,12 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					Spec: egressserviceapi.EgressServiceSpec{
 						SourceIPBy: egressserviceapi.SourceIPLoadBalancer,
 					},
-					Status: egressserviceapi.EgressServiceStatus{Host: node1Name},
+					Status: egressserviceapi.EgressServiceStatus{
+						Host: node1Name,
+					},
 				}
 				svc1 := lbSvcFor("testns", "svc1")
+				svc1.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "1.1.1.1"}}
 
 				v4EpSlice := discovery.EndpointSlice{
 					ObjectMeta: metav1.ObjectMeta{
@@ -820,10 +937,6 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 							Addresses: []string{"10.128.1.5"},
 							NodeName:  &node1.Name,
 						},
-						{
-							Addresses: []string{"10.128.2.5"},
-							NodeName:  &node2.Name,
-						},
 					},
 				}
 
@@ -841,10 +954,6 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 							Addresses: []string{"fe00:10:128:1::5"},
 							NodeName:  &node1.Name,
 						},
-						{
-							Addresses: []string{"fe00:10:128:2::5"},
-							NodeName:  &node2.Name,
-						},
 					},
 				}
 
@@ -857,7 +966,6 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					&v1.NodeList{
 						Items: []v1.Node{
 							*node1,
-							*node2,
 						},
 					},
 					&v1.ServiceList{
@@ -878,53 +986,64 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					},
 				)
 
-				if interconnectEnabled {
-					fakeOVN.controller.zone = node1Name
-				}
 				fakeOVN.InitAndRunEgressSVCController()
 
 				v4lrp1 := egressServiceRouterPolicy("v4lrp1-UUID", "testns/svc1", "10.128.1.5", "10.128.1.2")
-				v4lrp2 := egressServiceRouterPolicy("v4lrp2-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
 				v6lrp1 := egressServiceRouterPolicy("v6lrp1-UUID", "testns/svc1", "fe00:10:128:1::5", "fe00:10:128:1::2")
-				v6lrp2 := egressServiceRouterPolicy("v6lrp2-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
-				v4lrsr := egressServiceStaticRoute("v4lrsr-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
-				v6lrsr := egressServiceStaticRoute("v6lrsr-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
 
-				clusterRouter.Policies = []string{"v4lrp1-UUID", "v4lrp2-UUID", "v6lrp1-UUID", "v6lrp2-UUID"}
+				clusterRouter.Policies = []string{"v4lrp1-UUID"}
 				expectedDatabaseState := []libovsdbtest.TestData{
 					clusterRouter,
 					v4lrp1,
-					v4lrp2,
-					v6lrp1,
-					v6lrp2,
 				}
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
 
-				if interconnectEnabled {
-					clusterRouter.Policies = []string{"v4lrp1-UUID", "v6lrp1-UUID"}
-					clusterRouter.StaticRoutes = []string{"v4lrsr-UUID", "v6lrsr-UUID"}
-					expectedDatabaseState = []libovsdbtest.TestData{
-						clusterRouter,
-						v4lrp1,
-						v6lrp1,
-						v4lrsr,
-						v6lrsr,
-					}
+				ginkgo.By("adding a v6 ingress IP to the service the v6 lrps will be created")
+				svc1.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "1.1.1.1"}, {IP: "2001::1"}}
+				svc1.ResourceVersion = "2"
+				_, err := fakeOVN.fakeClient.KubeClient.CoreV1().Services("testns").Update(context.TODO(), &svc1, metav1.UpdateOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				clusterRouter.Policies = []string{"v4lrp1-UUID", "v6lrp1-UUID"}
+				expectedDatabaseState = []libovsdbtest.TestData{
+					clusterRouter,
+					v4lrp1,
+					v6lrp1,
+				}
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
 				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
 
+				ginkgo.By("removing the v4 ingress IP of the service the v4 lrps will be deleted")
+				svc1.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "2001::1"}}
+				svc1.ResourceVersion = "3"
+				_, err = fakeOVN.fakeClient.KubeClient.CoreV1().Services("testns").Update(context.TODO(), &svc1, metav1.UpdateOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				clusterRouter.Policies = []string{"v6lrp1-UUID"}
+				expectedDatabaseState = []libovsdbtest.TestData{
+					clusterRouter,
+					v6lrp1,
+				}
 				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
 					expectedDatabaseState = append(expectedDatabaseState, lrp)
 					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
 				}
 				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
 
-				ginkgo.By("setting the EgressService's host to ALL the resources should be removed")
-				esvc1.Status.Host = "ALL"
-				esvc1.ResourceVersion = "2"
-				_, err := fakeOVN.fakeClient.EgressServiceClient.K8sV1().EgressServices("testns").Update(context.TODO(), &esvc1, metav1.UpdateOptions{})
+				ginkgo.By("removing all the ingress IPs of the service all of its lrps will be deleted")
+				svc1.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{}
+				svc1.ResourceVersion = "4"
+				_, err = fakeOVN.fakeClient.KubeClient.CoreV1().Services("testns").Update(context.TODO(), &svc1, metav1.UpdateOptions{})
 				gomega.Expect(err).ToNot(gomega.HaveOccurred())
 
 				clusterRouter.Policies = []string{}
-				clusterRouter.StaticRoutes = []string{}
 				expectedDatabaseState = []libovsdbtest.TestData{clusterRouter}
 				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
 					expectedDatabaseState = append(expectedDatabaseState, lrp)
@@ -936,20 +1055,15 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 			}
 			err := app.Run([]string{app.Name})
 			gomega.Expect(err).ToNot(gomega.HaveOccurred())
-		},
-			ginkgotable.Entry("IC Disabled, all nodes are in a single zone", false),
-			ginkgotable.Entry("IC Enabled, node1 is in the local zone, node2 in remote", true))
-	})
+		})
 
-	ginkgo.Context("on endpointslices changes", func() {
-		ginkgotable.DescribeTable("should create/update/delete OVN configuration", func(interconnectEnabled bool) {
+		ginkgotable.DescribeTable("should only create OVN configuration for the IP family of the EgressService", func(interconnectEnabled bool) {
 			app.Action = func(ctx *cli.Context) error {
 				namespaceT := *newNamespace("testns")
 				config.IPv6Mode = true
 				config.OVNKubernetesFeature.EnableInterconnect = interconnectEnabled
 				node1 := nodeFor(node1Name, node1IPv4, node1IPv6, node1IPv4Subnet, node1IPv6Subnet, node1transitIPv4, node1transitIPv6)
 				node2 := nodeFor(node2Name, node2IPv4, node2IPv6, node2IPv4Subnet, node2IPv6Subnet, node2transitIPv4, node2transitIPv6)
-
 				clusterRouter := &nbdb.LogicalRouter{
 					Name: types.OVNClusterRouter,
 					UUID: types.OVNClusterRouter + "-UUID",
@@ -961,7 +1075,7 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					},
 				}
 
-				ginkgo.By("creating a service with v4 endpoints that will be allocated on the node")
+				ginkgo.By("creating a service with v4 and v6 endpoints restricted to IPv4 allocated on the first node")
 				esvc1 := egressserviceapi.EgressService{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "svc1",
@@ -969,21 +1083,21 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					},
 					Spec: egressserviceapi.EgressServiceSpec{
 						SourceIPBy: egressserviceapi.SourceIPLoadBalancer,
+						IPFamily:   egressserviceapi.IPFamilyIPv4,
 					},
 					Status: egressserviceapi.EgressServiceStatus{
-						Host: node1.Name,
+						Host: node1Name,
 					},
 				}
 				svc1 := lbSvcFor("testns", "svc1")
 
-				v4EpSlice := &discovery.EndpointSlice{
+				v4EpSlice := discovery.EndpointSlice{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "svc1-ipv4-epslice",
 						Namespace: "testns",
 						Labels: map[string]string{
 							discovery.LabelServiceName: "svc1",
 						},
-						ResourceVersion: "1",
 					},
 					AddressType: discovery.AddressTypeIPv4,
 					Endpoints: []discovery.Endpoint{
@@ -998,14 +1112,13 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					},
 				}
 
-				v6EpSlice := &discovery.EndpointSlice{
+				v6EpSlice := discovery.EndpointSlice{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "svc1-ipv6-epslice",
 						Namespace: "testns",
 						Labels: map[string]string{
 							discovery.LabelServiceName: "svc1",
 						},
-						ResourceVersion: "1",
 					},
 					AddressType: discovery.AddressTypeIPv6,
 					Endpoints: []discovery.Endpoint{
@@ -1039,7 +1152,8 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					},
 					&discovery.EndpointSliceList{
 						Items: []discovery.EndpointSlice{
-							*v4EpSlice,
+							v4EpSlice,
+							v6EpSlice,
 						},
 					},
 					&egressserviceapi.EgressServiceList{
@@ -1056,9 +1170,12 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 
 				v4lrp1 := egressServiceRouterPolicy("v4lrp1-UUID", "testns/svc1", "10.128.1.5", "10.128.1.2")
 				v4lrp2 := egressServiceRouterPolicy("v4lrp2-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
+				v6lrp1 := egressServiceRouterPolicy("v6lrp1-UUID", "testns/svc1", "fe00:10:128:1::5", "fe00:10:128:1::2")
+				v6lrp2 := egressServiceRouterPolicy("v6lrp2-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
 				v4lrsr := egressServiceStaticRoute("v4lrsr-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
-				expectedDatabaseState := []libovsdbtest.TestData{}
+				v6lrsr := egressServiceStaticRoute("v6lrsr-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
 
+				expectedDatabaseState := []libovsdbtest.TestData{}
 				if !interconnectEnabled {
 					clusterRouter.Policies = []string{"v4lrp1-UUID", "v4lrp2-UUID"}
 					expectedDatabaseState = []libovsdbtest.TestData{
@@ -1082,116 +1199,1045 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 				}
 				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
 
-				ginkgo.By("creating the v6 endpoints corresponding lrps will be added")
-				v6EpSlice, err := fakeOVN.fakeClient.KubeClient.DiscoveryV1().EndpointSlices("testns").Create(context.TODO(), v6EpSlice, metav1.CreateOptions{})
+				ginkgo.By("restricting the EgressService to IPv6 the v4 configuration is replaced by the v6 one")
+				esvc1.Spec.IPFamily = egressserviceapi.IPFamilyIPv6
+				esvc1.ResourceVersion = "2"
+				_, err := fakeOVN.fakeClient.EgressServiceClient.K8sV1().EgressServices("testns").Update(context.TODO(), &esvc1, metav1.UpdateOptions{})
 				gomega.Expect(err).ToNot(gomega.HaveOccurred())
 
-				v6lrp1 := egressServiceRouterPolicy("v6lrp1-UUID", "testns/svc1", "fe00:10:128:1::5", "fe00:10:128:1::2")
-				v6lrp2 := egressServiceRouterPolicy("v6lrp2-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
-				v6lrsr := egressServiceStaticRoute("v6lrsr-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
-
 				if !interconnectEnabled {
-					clusterRouter.Policies = []string{"v4lrp1-UUID", "v4lrp2-UUID", "v6lrp1-UUID", "v6lrp2-UUID"}
+					clusterRouter.Policies = []string{"v6lrp1-UUID", "v6lrp2-UUID"}
 					expectedDatabaseState = []libovsdbtest.TestData{
 						clusterRouter,
-						v4lrp1,
-						v4lrp2,
 						v6lrp1,
 						v6lrp2,
 					}
 				} else {
-					clusterRouter.Policies = []string{"v4lrp1-UUID", "v6lrp1-UUID"}
-					clusterRouter.StaticRoutes = []string{"v4lrsr-UUID", "v6lrsr-UUID"}
+					clusterRouter.Policies = []string{"v6lrp1-UUID"}
+					clusterRouter.StaticRoutes = []string{"v6lrsr-UUID"}
 					expectedDatabaseState = []libovsdbtest.TestData{
 						clusterRouter,
-						v4lrp1,
-						v4lrsr,
 						v6lrp1,
 						v6lrsr,
 					}
 				}
+
 				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
 					expectedDatabaseState = append(expectedDatabaseState, lrp)
 					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
 				}
 				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
 
-				ginkgo.By("updating the v4 and v6 endpoints OVN will be updated accordingly")
-				v4EpSlice.Endpoints = append(v4EpSlice.Endpoints, discovery.Endpoint{Addresses: []string{"10.128.1.7"}, NodeName: &node1.Name})
-				v4EpSlice.ResourceVersion = "2"
-				v4EpSlice, err = fakeOVN.fakeClient.KubeClient.DiscoveryV1().EndpointSlices("testns").Update(context.TODO(), v4EpSlice, metav1.UpdateOptions{})
-				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		},
+			ginkgotable.Entry("IC Disabled, all nodes are in a single zone", false),
+			ginkgotable.Entry("IC Enabled, node1 is in the local zone, node2 in remote", true),
+		)
 
-				v6EpSlice.Endpoints = []discovery.Endpoint{{Addresses: []string{"fe00:10:128:2::5"}, NodeName: &node2.Name}}
-				v6EpSlice.ResourceVersion = "2"
-				v6EpSlice, err = fakeOVN.fakeClient.KubeClient.DiscoveryV1().EndpointSlices("testns").Update(context.TODO(), v6EpSlice, metav1.UpdateOptions{})
-				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		ginkgotable.DescribeTable("should delete resources when host changes to ALL", func(interconnectEnabled bool) {
+			app.Action = func(ctx *cli.Context) error {
+				namespaceT := *newNamespace("testns")
+				config.IPv6Mode = true
+				config.OVNKubernetesFeature.EnableInterconnect = interconnectEnabled
+				node1 := nodeFor(node1Name, node1IPv4, node1IPv6, node1IPv4Subnet, node1IPv6Subnet, node1transitIPv4, node1transitIPv6)
+				node2 := nodeFor(node2Name, node2IPv4, node2IPv6, node2IPv4Subnet, node2IPv6Subnet, node2transitIPv4, node2transitIPv6)
+				clusterRouter := &nbdb.LogicalRouter{
+					Name: types.OVNClusterRouter,
+					UUID: types.OVNClusterRouter + "-UUID",
+				}
 
-				v4lrp3 := egressServiceRouterPolicy("v4lrp3-UUID", "testns/svc1", "10.128.1.7", "10.128.1.2")
-				if !interconnectEnabled {
-					clusterRouter.Policies = []string{"v4lrp1-UUID", "v4lrp2-UUID", "v4lrp3-UUID", "v6lrp2-UUID"}
-					expectedDatabaseState = []libovsdbtest.TestData{
-						clusterRouter,
-						v4lrp1,
-						v4lrp2,
-						v4lrp3,
-						v6lrp2,
-					}
-				} else {
-					clusterRouter.Policies = []string{"v4lrp1-UUID", "v4lrp3-UUID"}
-					clusterRouter.StaticRoutes = []string{"v4lrsr-UUID", "v6lrsr-UUID"}
-					expectedDatabaseState = []libovsdbtest.TestData{
+				dbSetup := libovsdbtest.TestSetup{
+					NBData: []libovsdbtest.TestData{
 						clusterRouter,
-						v4lrp1,
-						v4lrsr,
-						v4lrp3,
+					},
+				}
+
+				ginkgo.By("creating a service with v4 and v6 endpoints allocated on the first node")
+				esvc1 := egressserviceapi.EgressService{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1",
+						Namespace: "testns",
+					},
+					Spec: egressserviceapi.EgressServiceSpec{
+						SourceIPBy: egressserviceapi.SourceIPLoadBalancer,
+					},
+					Status: egressserviceapi.EgressServiceStatus{Host: node1Name},
+				}
+				svc1 := lbSvcFor("testns", "svc1")
+
+				v4EpSlice := discovery.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1-ipv4-epslice",
+						Namespace: "testns",
+						Labels: map[string]string{
+							discovery.LabelServiceName: "svc1",
+						},
+					},
+					AddressType: discovery.AddressTypeIPv4,
+					Endpoints: []discovery.Endpoint{
+						{
+							Addresses: []string{"10.128.1.5"},
+							NodeName:  &node1.Name,
+						},
+						{
+							Addresses: []string{"10.128.2.5"},
+							NodeName:  &node2.Name,
+						},
+					},
+				}
+
+				v6EpSlice := discovery.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1-ipv6-epslice",
+						Namespace: "testns",
+						Labels: map[string]string{
+							discovery.LabelServiceName: "svc1",
+						},
+					},
+					AddressType: discovery.AddressTypeIPv6,
+					Endpoints: []discovery.Endpoint{
+						{
+							Addresses: []string{"fe00:10:128:1::5"},
+							NodeName:  &node1.Name,
+						},
+						{
+							Addresses: []string{"fe00:10:128:2::5"},
+							NodeName:  &node2.Name,
+						},
+					},
+				}
+
+				fakeOVN.startWithDBSetup(dbSetup,
+					&v1.NamespaceList{
+						Items: []v1.Namespace{
+							namespaceT,
+						},
+					},
+					&v1.NodeList{
+						Items: []v1.Node{
+							*node1,
+							*node2,
+						},
+					},
+					&v1.ServiceList{
+						Items: []v1.Service{
+							svc1,
+						},
+					},
+					&discovery.EndpointSliceList{
+						Items: []discovery.EndpointSlice{
+							v4EpSlice,
+							v6EpSlice,
+						},
+					},
+					&egressserviceapi.EgressServiceList{
+						Items: []egressserviceapi.EgressService{
+							esvc1,
+						},
+					},
+				)
+
+				if interconnectEnabled {
+					fakeOVN.controller.zone = node1Name
+				}
+				fakeOVN.InitAndRunEgressSVCController()
+
+				v4lrp1 := egressServiceRouterPolicy("v4lrp1-UUID", "testns/svc1", "10.128.1.5", "10.128.1.2")
+				v4lrp2 := egressServiceRouterPolicy("v4lrp2-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
+				v6lrp1 := egressServiceRouterPolicy("v6lrp1-UUID", "testns/svc1", "fe00:10:128:1::5", "fe00:10:128:1::2")
+				v6lrp2 := egressServiceRouterPolicy("v6lrp2-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
+				v4lrsr := egressServiceStaticRoute("v4lrsr-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
+				v6lrsr := egressServiceStaticRoute("v6lrsr-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
+
+				clusterRouter.Policies = []string{"v4lrp1-UUID", "v4lrp2-UUID", "v6lrp1-UUID", "v6lrp2-UUID"}
+				expectedDatabaseState := []libovsdbtest.TestData{
+					clusterRouter,
+					v4lrp1,
+					v4lrp2,
+					v6lrp1,
+					v6lrp2,
+				}
+
+				if interconnectEnabled {
+					clusterRouter.Policies = []string{"v4lrp1-UUID", "v6lrp1-UUID"}
+					clusterRouter.StaticRoutes = []string{"v4lrsr-UUID", "v6lrsr-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						v4lrp1,
+						v6lrp1,
+						v4lrsr,
 						v6lrsr,
 					}
 				}
 
-				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
-					expectedDatabaseState = append(expectedDatabaseState, lrp)
-					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+
+				ginkgo.By("setting the EgressService's host to ALL the resources should be removed")
+				esvc1.Status.Host = "ALL"
+				esvc1.ResourceVersion = "2"
+				_, err := fakeOVN.fakeClient.EgressServiceClient.K8sV1().EgressServices("testns").Update(context.TODO(), &esvc1, metav1.UpdateOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				clusterRouter.Policies = []string{}
+				clusterRouter.StaticRoutes = []string{}
+				expectedDatabaseState = []libovsdbtest.TestData{clusterRouter}
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		},
+			ginkgotable.Entry("IC Disabled, all nodes are in a single zone", false),
+			ginkgotable.Entry("IC Enabled, node1 is in the local zone, node2 in remote", true))
+	})
+
+	ginkgo.Context("on endpointslices changes", func() {
+		ginkgotable.DescribeTable("should create/update/delete OVN configuration", func(interconnectEnabled bool) {
+			app.Action = func(ctx *cli.Context) error {
+				namespaceT := *newNamespace("testns")
+				config.IPv6Mode = true
+				config.OVNKubernetesFeature.EnableInterconnect = interconnectEnabled
+				node1 := nodeFor(node1Name, node1IPv4, node1IPv6, node1IPv4Subnet, node1IPv6Subnet, node1transitIPv4, node1transitIPv6)
+				node2 := nodeFor(node2Name, node2IPv4, node2IPv6, node2IPv4Subnet, node2IPv6Subnet, node2transitIPv4, node2transitIPv6)
+
+				clusterRouter := &nbdb.LogicalRouter{
+					Name: types.OVNClusterRouter,
+					UUID: types.OVNClusterRouter + "-UUID",
+				}
+
+				dbSetup := libovsdbtest.TestSetup{
+					NBData: []libovsdbtest.TestData{
+						clusterRouter,
+					},
+				}
+
+				ginkgo.By("creating a service with v4 endpoints that will be allocated on the node")
+				esvc1 := egressserviceapi.EgressService{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1",
+						Namespace: "testns",
+					},
+					Spec: egressserviceapi.EgressServiceSpec{
+						SourceIPBy: egressserviceapi.SourceIPLoadBalancer,
+					},
+					Status: egressserviceapi.EgressServiceStatus{
+						Host: node1.Name,
+					},
+				}
+				svc1 := lbSvcFor("testns", "svc1")
+
+				v4EpSlice := &discovery.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1-ipv4-epslice",
+						Namespace: "testns",
+						Labels: map[string]string{
+							discovery.LabelServiceName: "svc1",
+						},
+						ResourceVersion: "1",
+					},
+					AddressType: discovery.AddressTypeIPv4,
+					Endpoints: []discovery.Endpoint{
+						{
+							Addresses: []string{"10.128.1.5"},
+							NodeName:  &node1.Name,
+						},
+						{
+							Addresses: []string{"10.128.2.5"},
+							NodeName:  &node2.Name,
+						},
+					},
+				}
+
+				v6EpSlice := &discovery.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1-ipv6-epslice",
+						Namespace: "testns",
+						Labels: map[string]string{
+							discovery.LabelServiceName: "svc1",
+						},
+						ResourceVersion: "1",
+					},
+					AddressType: discovery.AddressTypeIPv6,
+					Endpoints: []discovery.Endpoint{
+						{
+							Addresses: []string{"fe00:10:128:1::5"},
+							NodeName:  &node1.Name,
+						},
+						{
+							Addresses: []string{"fe00:10:128:2::5"},
+							NodeName:  &node2.Name,
+						},
+					},
+				}
+
+				fakeOVN.startWithDBSetup(dbSetup,
+					&v1.NamespaceList{
+						Items: []v1.Namespace{
+							namespaceT,
+						},
+					},
+					&v1.NodeList{
+						Items: []v1.Node{
+							*node1,
+							*node2,
+						},
+					},
+					&v1.ServiceList{
+						Items: []v1.Service{
+							svc1,
+						},
+					},
+					&discovery.EndpointSliceList{
+						Items: []discovery.EndpointSlice{
+							*v4EpSlice,
+						},
+					},
+					&egressserviceapi.EgressServiceList{
+						Items: []egressserviceapi.EgressService{
+							esvc1,
+						},
+					},
+				)
+
+				if interconnectEnabled {
+					fakeOVN.controller.zone = node1Name
+				}
+				fakeOVN.InitAndRunEgressSVCController()
+
+				v4lrp1 := egressServiceRouterPolicy("v4lrp1-UUID", "testns/svc1", "10.128.1.5", "10.128.1.2")
+				v4lrp2 := egressServiceRouterPolicy("v4lrp2-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
+				v4lrsr := egressServiceStaticRoute("v4lrsr-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
+				expectedDatabaseState := []libovsdbtest.TestData{}
+
+				if !interconnectEnabled {
+					clusterRouter.Policies = []string{"v4lrp1-UUID", "v4lrp2-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						v4lrp1,
+						v4lrp2,
+					}
+				} else {
+					clusterRouter.Policies = []string{"v4lrp1-UUID"}
+					clusterRouter.StaticRoutes = []string{"v4lrsr-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						v4lrp1,
+						v4lrsr,
+					}
+				}
+
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+
+				ginkgo.By("creating the v6 endpoints corresponding lrps will be added")
+				v6EpSlice, err := fakeOVN.fakeClient.KubeClient.DiscoveryV1().EndpointSlices("testns").Create(context.TODO(), v6EpSlice, metav1.CreateOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				v6lrp1 := egressServiceRouterPolicy("v6lrp1-UUID", "testns/svc1", "fe00:10:128:1::5", "fe00:10:128:1::2")
+				v6lrp2 := egressServiceRouterPolicy("v6lrp2-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
+				v6lrsr := egressServiceStaticRoute("v6lrsr-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
+
+				if !interconnectEnabled {
+					clusterRouter.Policies = []string{"v4lrp1-UUID", "v4lrp2-UUID", "v6lrp1-UUID", "v6lrp2-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						v4lrp1,
+						v4lrp2,
+						v6lrp1,
+						v6lrp2,
+					}
+				} else {
+					clusterRouter.Policies = []string{"v4lrp1-UUID", "v6lrp1-UUID"}
+					clusterRouter.StaticRoutes = []string{"v4lrsr-UUID", "v6lrsr-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						v4lrp1,
+						v4lrsr,
+						v6lrp1,
+						v6lrsr,
+					}
+				}
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+
+				ginkgo.By("updating the v4 and v6 endpoints OVN will be updated accordingly")
+				v4EpSlice.Endpoints = append(v4EpSlice.Endpoints, discovery.Endpoint{Addresses: []string{"10.128.1.7"}, NodeName: &node1.Name})
+				v4EpSlice.ResourceVersion = "2"
+				v4EpSlice, err = fakeOVN.fakeClient.KubeClient.DiscoveryV1().EndpointSlices("testns").Update(context.TODO(), v4EpSlice, metav1.UpdateOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				v6EpSlice.Endpoints = []discovery.Endpoint{{Addresses: []string{"fe00:10:128:2::5"}, NodeName: &node2.Name}}
+				v6EpSlice.ResourceVersion = "2"
+				v6EpSlice, err = fakeOVN.fakeClient.KubeClient.DiscoveryV1().EndpointSlices("testns").Update(context.TODO(), v6EpSlice, metav1.UpdateOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				v4lrp3 := egressServiceRouterPolicy("v4lrp3-UUID", "testns/svc1", "10.128.1.7", "10.128.1.2")
+				if !interconnectEnabled {
+					clusterRouter.Policies = []string{"v4lrp1-UUID", "v4lrp2-UUID", "v4lrp3-UUID", "v6lrp2-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						v4lrp1,
+						v4lrp2,
+						v4lrp3,
+						v6lrp2,
+					}
+				} else {
+					clusterRouter.Policies = []string{"v4lrp1-UUID", "v4lrp3-UUID"}
+					clusterRouter.StaticRoutes = []string{"v4lrsr-UUID", "v6lrsr-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						v4lrp1,
+						v4lrsr,
+						v4lrp3,
+						v6lrsr,
+					}
+				}
+
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+
+				ginkgo.By("deleting the v4 endpoints the corresponding entries will be deleted")
+				err = fakeOVN.fakeClient.KubeClient.DiscoveryV1().EndpointSlices("testns").Delete(context.TODO(), v4EpSlice.Name, metav1.DeleteOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				if !interconnectEnabled {
+					clusterRouter.Policies = []string{"v6lrp2-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						v6lrp2,
+					}
+				} else {
+					clusterRouter.Policies = []string{}
+					clusterRouter.StaticRoutes = []string{"v6lrsr-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						v6lrsr,
+					}
+				}
+
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+
+				ginkgo.By("deleting the v6 endpoints the corresponding lrps will be deleted")
+				err = fakeOVN.fakeClient.KubeClient.DiscoveryV1().EndpointSlices("testns").Delete(context.TODO(), v6EpSlice.Name, metav1.DeleteOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				clusterRouter.Policies = []string{}
+				clusterRouter.StaticRoutes = []string{}
+				expectedDatabaseState = []libovsdbtest.TestData{
+					clusterRouter,
+				}
+
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		},
+			ginkgotable.Entry("IC Disabled, all nodes are in a single zone", false),
+			ginkgotable.Entry("IC Enabled, node1 is in the local zone, node2 in remote", true))
+	})
+
+	ginkgo.Context("on pods changes", func() {
+		ginkgo.It("should remove the OVN configuration of a deleted pod before its endpointslice is updated", func() {
+			app.Action = func(ctx *cli.Context) error {
+				namespaceT := *newNamespace("testns")
+				config.IPv6Mode = true
+				node1 := nodeFor(node1Name, node1IPv4, node1IPv6, node1IPv4Subnet, node1IPv6Subnet, node1transitIPv4, node1transitIPv6)
+				clusterRouter := &nbdb.LogicalRouter{
+					Name: types.OVNClusterRouter,
+					UUID: types.OVNClusterRouter + "-UUID",
+				}
+
+				dbSetup := libovsdbtest.TestSetup{
+					NBData: []libovsdbtest.TestData{
+						clusterRouter,
+					},
+				}
+
+				ginkgo.By("creating an egress service backed by a pod")
+				pod1 := newPod("testns", "pod1", node1Name, "10.128.1.5")
+				esvc1 := egressserviceapi.EgressService{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1",
+						Namespace: "testns",
+					},
+					Spec: egressserviceapi.EgressServiceSpec{
+						SourceIPBy: egressserviceapi.SourceIPLoadBalancer,
+					},
+					Status: egressserviceapi.EgressServiceStatus{
+						Host: node1Name,
+					},
+				}
+				svc1 := lbSvcFor("testns", "svc1")
+
+				v4EpSlice := discovery.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1-ipv4-epslice",
+						Namespace: "testns",
+						Labels: map[string]string{
+							discovery.LabelServiceName: "svc1",
+						},
+					},
+					AddressType: discovery.AddressTypeIPv4,
+					Endpoints: []discovery.Endpoint{
+						{
+							Addresses: []string{"10.128.1.5"},
+							NodeName:  &node1.Name,
+							TargetRef: &v1.ObjectReference{Kind: "Pod", Namespace: "testns", Name: pod1.Name},
+						},
+					},
+				}
+
+				fakeOVN.startWithDBSetup(dbSetup,
+					&v1.NamespaceList{
+						Items: []v1.Namespace{
+							namespaceT,
+						},
+					},
+					&v1.NodeList{
+						Items: []v1.Node{
+							*node1,
+						},
+					},
+					&v1.PodList{
+						Items: []v1.Pod{
+							*pod1,
+						},
+					},
+					&v1.ServiceList{
+						Items: []v1.Service{
+							svc1,
+						},
+					},
+					&discovery.EndpointSliceList{
+						Items: []discovery.EndpointSlice{
+							v4EpSlice,
+						},
+					},
+					&egressserviceapi.EgressServiceList{
+						Items: []egressserviceapi.EgressService{
+							esvc1,
+						},
+					},
+				)
+
+				fakeOVN.InitAndRunEgressSVCController()
+
+				lrp1 := egressServiceRouterPolicy("lrp1-UUID", "testns/svc1", "10.128.1.5", "10.128.1.2")
+				clusterRouter.Policies = []string{"lrp1-UUID"}
+				expectedDatabaseState := []libovsdbtest.TestData{
+					clusterRouter,
+					lrp1,
+				}
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+				servedPodsASdbIDs := egresssvc.GetEgressServiceAddrSetDbIDs(DefaultNetworkControllerName)
+				fakeOVN.asf.EventuallyExpectAddressSetWithIPs(servedPodsASdbIDs, []string{"10.128.1.5"})
+
+				ginkgo.By("deleting the pod its lrp and address set entry are removed before its endpointslice is updated")
+				err := fakeOVN.fakeClient.KubeClient.CoreV1().Pods("testns").Delete(context.TODO(), pod1.Name, metav1.DeleteOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				clusterRouter.Policies = []string{}
+				expectedDatabaseState = []libovsdbtest.TestData{clusterRouter}
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+				fakeOVN.asf.EventuallyExpectEmptyAddressSetExist(servedPodsASdbIDs)
+
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		})
+	})
+
+	ginkgo.Context("on nodes changes", func() {
+		ginkgotable.DescribeTable("should create/update/delete logical router policies and address sets", func(interconnectEnabled bool) {
+			app.Action = func(ctx *cli.Context) error {
+				namespaceT := *newNamespace("testns")
+				config.IPv6Mode = true
+				config.OVNKubernetesFeature.EnableInterconnect = interconnectEnabled
+				node1 := nodeFor(node1Name, node1IPv4, node1IPv6, node1IPv4Subnet, node1IPv6Subnet, node1transitIPv4, node1transitIPv6)
+				node1.Labels = map[string]string{"home": "pineapple"}
+				node2 := nodeFor(node2Name, node2IPv4, node2IPv6, node2IPv4Subnet, node2IPv6Subnet, node2transitIPv4, node2transitIPv6)
+				node2.Labels = map[string]string{"home": "rock"}
+
+				clusterRouter := &nbdb.LogicalRouter{
+					Name: types.OVNClusterRouter,
+					UUID: types.OVNClusterRouter + "-UUID",
+				}
+
+				dbSetup := libovsdbtest.TestSetup{
+					NBData: []libovsdbtest.TestData{
+						clusterRouter,
+					},
+				}
+
+				ginkgo.By("creating an egress service assigned to the first node")
+				esvc1 := egressserviceapi.EgressService{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1",
+						Namespace: "testns",
+					},
+					Spec: egressserviceapi.EgressServiceSpec{
+						SourceIPBy: egressserviceapi.SourceIPLoadBalancer,
+						NodeSelector: metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								"home": "pineapple",
+							},
+						},
+					},
+					Status: egressserviceapi.EgressServiceStatus{Host: node1Name},
+				}
+				svc1 := lbSvcFor("testns", "svc1")
+
+				svc1V4EpSlice := discovery.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1-ipv4-epslice",
+						Namespace: "testns",
+						Labels: map[string]string{
+							discovery.LabelServiceName: "svc1",
+						},
+					},
+					AddressType: discovery.AddressTypeIPv4,
+					Endpoints: []discovery.Endpoint{
+						{
+							Addresses: []string{"10.128.2.5"},
+							NodeName:  &node2.Name,
+						},
+					},
+				}
+
+				svc1V6EpSlice := discovery.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1-ipv6-epslice",
+						Namespace: "testns",
+						Labels: map[string]string{
+							discovery.LabelServiceName: "svc1",
+						},
+					},
+					AddressType: discovery.AddressTypeIPv6,
+					Endpoints: []discovery.Endpoint{
+						{
+							Addresses: []string{"fe00:10:128:2::5"},
+							NodeName:  &node2.Name,
+						},
+					},
+				}
+
+				svc2 := lbSvcFor("testns", "svc2")
+
+				svc2V4EpSlice := discovery.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc2-ipv4-epslice",
+						Namespace: "testns",
+						Labels: map[string]string{
+							discovery.LabelServiceName: "svc2",
+						},
+					},
+					AddressType: discovery.AddressTypeIPv4,
+					Endpoints: []discovery.Endpoint{
+						{
+							Addresses: []string{"10.128.1.6"},
+							NodeName:  &node1.Name,
+						},
+					},
+				}
+
+				svc2V6EpSlice := discovery.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc2-ipv6-epslice",
+						Namespace: "testns",
+						Labels: map[string]string{
+							discovery.LabelServiceName: "svc2",
+						},
+					},
+					AddressType: discovery.AddressTypeIPv6,
+					Endpoints: []discovery.Endpoint{
+						{
+							Addresses: []string{"fe00:10:128:1::6"},
+							NodeName:  &node1.Name,
+						},
+					},
+				}
+
+				fakeOVN.startWithDBSetup(dbSetup,
+					&v1.NamespaceList{
+						Items: []v1.Namespace{
+							namespaceT,
+						},
+					},
+					&v1.NodeList{
+						Items: []v1.Node{
+							*node1,
+							*node2,
+						},
+					},
+					&v1.ServiceList{
+						Items: []v1.Service{
+							svc1,
+							svc2,
+						},
+					},
+					&discovery.EndpointSliceList{
+						Items: []discovery.EndpointSlice{
+							svc1V4EpSlice,
+							svc1V6EpSlice,
+							svc2V4EpSlice,
+							svc2V6EpSlice,
+						},
+					},
+					&egressserviceapi.EgressServiceList{
+						Items: []egressserviceapi.EgressService{
+							esvc1,
+						},
+					},
+				)
+
+				if interconnectEnabled {
+					fakeOVN.controller.zone = node1Name
+				}
+				fakeOVN.InitAndRunEgressSVCController()
+
+				svc1v4lrp1 := egressServiceRouterPolicy("svc1v4lrp1-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
+				svc1v6lrp1 := egressServiceRouterPolicy("svc1v6lrp1-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
+				svc1v4lrsr1 := egressServiceStaticRoute("svc1v4lrsr1-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
+				svc1v6lrsr1 := egressServiceStaticRoute("svc1v6lrsr1-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
+
+				expectedDatabaseState := []libovsdbtest.TestData{}
+				if !interconnectEnabled {
+					clusterRouter.Policies = []string{"svc1v4lrp1-UUID", "svc1v6lrp1-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						svc1v4lrp1,
+						svc1v6lrp1,
+					}
+				} else {
+					clusterRouter.Policies = []string{}
+					clusterRouter.StaticRoutes = []string{"svc1v4lrsr1-UUID", "svc1v6lrsr1-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						svc1v4lrsr1,
+						svc1v6lrsr1,
+					}
+				}
+
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+
+				ginkgo.By("creating an additional egress service assigned to the second node")
+				esvc2 := egressserviceapi.EgressService{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc2",
+						Namespace: "testns",
+					},
+					Spec: egressserviceapi.EgressServiceSpec{
+						SourceIPBy: egressserviceapi.SourceIPLoadBalancer,
+						NodeSelector: metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								"home": "moai",
+							},
+						},
+					},
+					Status: egressserviceapi.EgressServiceStatus{Host: node2Name},
+				}
+				_, err := fakeOVN.fakeClient.EgressServiceClient.K8sV1().EgressServices("testns").Create(context.TODO(), &esvc2, metav1.CreateOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				svc2v4lrp1 := egressServiceRouterPolicy("svc2v4lrp1-UUID", "testns/svc2", "10.128.1.6", "10.128.2.2")
+				svc2v6lrp1 := egressServiceRouterPolicy("svc2v6lrp1-UUID", "testns/svc2", "fe00:10:128:1::6", "fe00:10:128:2::2")
+				svc2v4lrp1remote := egressServiceRouterPolicy("svc2v4lrp1remote-UUID", "testns/svc2", "10.128.1.6", node2transitIPv4)
+				svc2v6lrp1remote := egressServiceRouterPolicy("svc2v6lrp1remote-UUID", "testns/svc2", "fe00:10:128:1::6", node2transitIPv6)
+
+				if !interconnectEnabled {
+					clusterRouter.Policies = []string{"svc1v4lrp1-UUID", "svc1v6lrp1-UUID", "svc2v4lrp1-UUID", "svc2v6lrp1-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						svc1v4lrp1,
+						svc1v6lrp1,
+						svc2v4lrp1,
+						svc2v6lrp1,
+					}
+				} else {
+					clusterRouter.Policies = []string{"svc2v4lrp1remote-UUID", "svc2v6lrp1remote-UUID"}
+					clusterRouter.StaticRoutes = []string{"svc1v4lrsr1-UUID", "svc1v6lrsr1-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						svc1v4lrsr1,
+						svc1v6lrsr1,
+						svc2v4lrp1remote,
+						svc2v6lrp1remote,
+					}
+
+				}
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+
+				ginkgo.By("updating the second node host addresses the node ip no re-route address set will be updated")
+				nodeIPsASdbIDs := getEgressIPAddrSetDbIDs(NodeIPAddrSetName, DefaultNetworkControllerName)
+				fakeOVN.asf.EventuallyExpectAddressSetWithIPs(nodeIPsASdbIDs, []string{node1IPv4, node2IPv4, node1IPv6, node2IPv6})
+
+				node2.ObjectMeta.Annotations["k8s.ovn.org/host-addresses"] = fmt.Sprintf("[\"%s\", \"%s\", \"%s\", \"%s\"]", node2IPv4, node2IPv6, vipIPv4, vipIPv6)
+				node2.ResourceVersion = "3"
+				_, err = fakeOVN.fakeClient.KubeClient.CoreV1().Nodes().Update(context.TODO(), node2, metav1.UpdateOptions{})
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+				fakeOVN.asf.EventuallyExpectAddressSetWithIPs(nodeIPsASdbIDs, []string{node1IPv4, node2IPv4, node1IPv6, node2IPv6, vipIPv4, vipIPv6})
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+
+				node2.ObjectMeta.Annotations["k8s.ovn.org/host-addresses"] = fmt.Sprintf("[\"%s\", \"%s\"]", node2IPv4, node2IPv6)
+				node2.ResourceVersion = "4"
+				_, err = fakeOVN.fakeClient.KubeClient.CoreV1().Nodes().Update(context.TODO(), node2, metav1.UpdateOptions{})
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+				fakeOVN.asf.EventuallyExpectAddressSetWithIPs(nodeIPsASdbIDs, []string{node1IPv4, node2IPv4, node1IPv6, node2IPv6})
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+
+				ginkgo.By("deleting the first node, the node ip no re-route address set will be updated and service resources will be deleted")
+				err = fakeOVN.fakeClient.KubeClient.CoreV1().Nodes().Delete(context.TODO(), node1.Name, metav1.DeleteOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				fakeOVN.asf.EventuallyExpectAddressSetWithIPs(nodeIPsASdbIDs, []string{node2IPv4, node2IPv6})
+
+				if !interconnectEnabled {
+					clusterRouter.Policies = []string{"svc2v4lrp1-UUID", "svc2v6lrp1-UUID"}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						svc2v4lrp1,
+						svc2v6lrp1,
+					}
+				} else {
+					clusterRouter.Policies = []string{"svc2v4lrp1remote-UUID", "svc2v6lrp1remote-UUID"}
+					clusterRouter.StaticRoutes = []string{}
+					expectedDatabaseState = []libovsdbtest.TestData{
+						clusterRouter,
+						svc2v4lrp1remote,
+						svc2v6lrp1remote,
+					}
+				}
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+
+				ginkgo.By("deleting the second node the second service's resources will be deleted")
+				err = fakeOVN.fakeClient.KubeClient.CoreV1().Nodes().Delete(context.TODO(), node2.Name, metav1.DeleteOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				clusterRouter.Policies = []string{}
+				clusterRouter.StaticRoutes = []string{}
+				expectedDatabaseState = []libovsdbtest.TestData{
+					clusterRouter,
+				}
+				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
+					expectedDatabaseState = append(expectedDatabaseState, lrp)
+					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
+				}
+				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+				fakeOVN.asf.EventuallyExpectEmptyAddressSetExist(nodeIPsASdbIDs)
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		},
+			ginkgotable.Entry("IC Disabled, all nodes are in a single zone", false),
+			ginkgotable.Entry("IC Enabled, node1 is in the local zone, node2 in remote", true))
+
+		ginkgotable.DescribeTable("should update the nexthops of the services hosted on a node when its management IPs change", func(interconnectEnabled bool) {
+			app.Action = func(ctx *cli.Context) error {
+				namespaceT := *newNamespace("testns")
+				config.IPv6Mode = true
+				config.OVNKubernetesFeature.EnableInterconnect = interconnectEnabled
+				node1 := nodeFor(node1Name, node1IPv4, node1IPv6, node1IPv4Subnet, node1IPv6Subnet, node1transitIPv4, node1transitIPv6)
+				node2 := nodeFor(node2Name, node2IPv4, node2IPv6, node2IPv4Subnet, node2IPv6Subnet, node2transitIPv4, node2transitIPv6)
+
+				clusterRouter := &nbdb.LogicalRouter{
+					Name: types.OVNClusterRouter,
+					UUID: types.OVNClusterRouter + "-UUID",
+				}
+
+				dbSetup := libovsdbtest.TestSetup{
+					NBData: []libovsdbtest.TestData{
+						clusterRouter,
+					},
+				}
+
+				esvc1 := egressserviceapi.EgressService{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1",
+						Namespace: "testns",
+					},
+					Spec: egressserviceapi.EgressServiceSpec{
+						SourceIPBy: egressserviceapi.SourceIPLoadBalancer,
+					},
+					Status: egressserviceapi.EgressServiceStatus{Host: node1Name},
+				}
+				svc1 := lbSvcFor("testns", "svc1")
+
+				svc1V4EpSlice := discovery.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1-ipv4-epslice",
+						Namespace: "testns",
+						Labels: map[string]string{
+							discovery.LabelServiceName: "svc1",
+						},
+					},
+					AddressType: discovery.AddressTypeIPv4,
+					Endpoints: []discovery.Endpoint{
+						{
+							Addresses: []string{"10.128.1.5"},
+							NodeName:  &node1.Name,
+						},
+						{
+							Addresses: []string{"10.128.2.5"},
+							NodeName:  &node2.Name,
+						},
+					},
+				}
+
+				svc1V6EpSlice := discovery.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "svc1-ipv6-epslice",
+						Namespace: "testns",
+						Labels: map[string]string{
+							discovery.LabelServiceName: "svc1",
+						},
+					},
+					AddressType: discovery.AddressTypeIPv6,
+					Endpoints: []discovery.Endpoint{
+						{
+							Addresses: []string{"fe00:10:128:1::5"},
+							NodeName:  &node1.Name,
+						},
+						{
+							Addresses: []string{"fe00:10:128:2::5"},
+							NodeName:  &node2.Name,
+						},
+					},
+				}
+
+				fakeOVN.startWithDBSetup(dbSetup,
+					&v1.NamespaceList{
+						Items: []v1.Namespace{
+							namespaceT,
+						},
+					},
+					&v1.NodeList{
+						Items: []v1.Node{
+							*node1,
+							*node2,
+						},
+					},
+					&v1.ServiceList{
+						Items: []v1.Service{
+							svc1,
+						},
+					},
+					&discovery.EndpointSliceList{
+						Items: []discovery.EndpointSlice{
+							svc1V4EpSlice,
+							svc1V6EpSlice,
+						},
+					},
+					&egressserviceapi.EgressServiceList{
+						Items: []egressserviceapi.EgressService{
+							esvc1,
+						},
+					},
+				)
+
+				if interconnectEnabled {
+					fakeOVN.controller.zone = node1Name
 				}
-				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
+				fakeOVN.InitAndRunEgressSVCController()
 
-				ginkgo.By("deleting the v4 endpoints the corresponding entries will be deleted")
-				err = fakeOVN.fakeClient.KubeClient.DiscoveryV1().EndpointSlices("testns").Delete(context.TODO(), v4EpSlice.Name, metav1.DeleteOptions{})
-				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				v4lrp1 := egressServiceRouterPolicy("v4lrp1-UUID", "testns/svc1", "10.128.1.5", "10.128.1.2")
+				v4lrp2 := egressServiceRouterPolicy("v4lrp2-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
+				v6lrp1 := egressServiceRouterPolicy("v6lrp1-UUID", "testns/svc1", "fe00:10:128:1::5", "fe00:10:128:1::2")
+				v6lrp2 := egressServiceRouterPolicy("v6lrp2-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
+				v4lrsr := egressServiceStaticRoute("v4lrsr-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
+				v6lrsr := egressServiceStaticRoute("v6lrsr-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
+
+				expectedDatabaseState := []libovsdbtest.TestData{}
 				if !interconnectEnabled {
-					clusterRouter.Policies = []string{"v6lrp2-UUID"}
+					clusterRouter.Policies = []string{"v4lrp1-UUID", "v4lrp2-UUID", "v6lrp1-UUID", "v6lrp2-UUID"}
 					expectedDatabaseState = []libovsdbtest.TestData{
 						clusterRouter,
+						v4lrp1,
+						v4lrp2,
+						v6lrp1,
 						v6lrp2,
 					}
 				} else {
-					clusterRouter.Policies = []string{}
-					clusterRouter.StaticRoutes = []string{"v6lrsr-UUID"}
+					clusterRouter.Policies = []string{"v4lrp1-UUID", "v6lrp1-UUID"}
+					clusterRouter.StaticRoutes = []string{"v4lrsr-UUID", "v6lrsr-UUID"}
 					expectedDatabaseState = []libovsdbtest.TestData{
 						clusterRouter,
+						v4lrp1,
+						v6lrp1,
+						v4lrsr,
 						v6lrsr,
 					}
 				}
-
 				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
 					expectedDatabaseState = append(expectedDatabaseState, lrp)
 					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
 				}
 				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
 
-				ginkgo.By("deleting the v6 endpoints the corresponding lrps will be deleted")
-				err = fakeOVN.fakeClient.KubeClient.DiscoveryV1().EndpointSlices("testns").Delete(context.TODO(), v6EpSlice.Name, metav1.DeleteOptions{})
+				ginkgo.By("changing the subnets of the service node the nexthops are updated to its new mgmt IPs")
+				node1.Annotations["k8s.ovn.org/node-subnets"] = "{\"default\":[\"10.128.3.0/24\",\"fe00:10:128:3::/64\"]}"
+				node1.ResourceVersion = "2"
+				_, err := fakeOVN.fakeClient.KubeClient.CoreV1().Nodes().Update(context.TODO(), node1, metav1.UpdateOptions{})
 				gomega.Expect(err).ToNot(gomega.HaveOccurred())
-				clusterRouter.Policies = []string{}
-				clusterRouter.StaticRoutes = []string{}
-				expectedDatabaseState = []libovsdbtest.TestData{
-					clusterRouter,
-				}
 
-				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
-					expectedDatabaseState = append(expectedDatabaseState, lrp)
-					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
-				}
+				v4lrp1.Nexthops = []string{"10.128.3.2"}
+				v4lrp2.Nexthops = []string{"10.128.3.2"}
+				v6lrp1.Nexthops = []string{"fe00:10:128:3::2"}
+				v6lrp2.Nexthops = []string{"fe00:10:128:3::2"}
+				v4lrsr.Nexthop = "10.128.3.2"
+				v6lrsr.Nexthop = "fe00:10:128:3::2"
 				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
 
 				return nil
@@ -1200,19 +2246,16 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 			gomega.Expect(err).ToNot(gomega.HaveOccurred())
 		},
 			ginkgotable.Entry("IC Disabled, all nodes are in a single zone", false),
-			ginkgotable.Entry("IC Enabled, node1 is in the local zone, node2 in remote", true))
-	})
+			ginkgotable.Entry("IC Enabled, node1 is in the local zone, node2 in remote", true),
+		)
 
-	ginkgo.Context("on nodes changes", func() {
-		ginkgotable.DescribeTable("should create/update/delete logical router policies and address sets", func(interconnectEnabled bool) {
+		ginkgo.It("OVN-IC: should update the nexthops of existing endpoints when nodes move between zones", func() {
 			app.Action = func(ctx *cli.Context) error {
 				namespaceT := *newNamespace("testns")
 				config.IPv6Mode = true
-				config.OVNKubernetesFeature.EnableInterconnect = interconnectEnabled
+				config.OVNKubernetesFeature.EnableInterconnect = true
 				node1 := nodeFor(node1Name, node1IPv4, node1IPv6, node1IPv4Subnet, node1IPv6Subnet, node1transitIPv4, node1transitIPv6)
-				node1.Labels = map[string]string{"home": "pineapple"}
 				node2 := nodeFor(node2Name, node2IPv4, node2IPv6, node2IPv4Subnet, node2IPv6Subnet, node2transitIPv4, node2transitIPv6)
-				node2.Labels = map[string]string{"home": "rock"}
 
 				clusterRouter := &nbdb.LogicalRouter{
 					Name: types.OVNClusterRouter,
@@ -1225,7 +2268,6 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					},
 				}
 
-				ginkgo.By("creating an egress service assigned to the first node")
 				esvc1 := egressserviceapi.EgressService{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "svc1",
@@ -1233,11 +2275,6 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					},
 					Spec: egressserviceapi.EgressServiceSpec{
 						SourceIPBy: egressserviceapi.SourceIPLoadBalancer,
-						NodeSelector: metav1.LabelSelector{
-							MatchLabels: map[string]string{
-								"home": "pineapple",
-							},
-						},
 					},
 					Status: egressserviceapi.EgressServiceStatus{Host: node1Name},
 				}
@@ -1277,42 +2314,6 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					},
 				}
 
-				svc2 := lbSvcFor("testns", "svc2")
-
-				svc2V4EpSlice := discovery.EndpointSlice{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "svc2-ipv4-epslice",
-						Namespace: "testns",
-						Labels: map[string]string{
-							discovery.LabelServiceName: "svc2",
-						},
-					},
-					AddressType: discovery.AddressTypeIPv4,
-					Endpoints: []discovery.Endpoint{
-						{
-							Addresses: []string{"10.128.1.6"},
-							NodeName:  &node1.Name,
-						},
-					},
-				}
-
-				svc2V6EpSlice := discovery.EndpointSlice{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "svc2-ipv6-epslice",
-						Namespace: "testns",
-						Labels: map[string]string{
-							discovery.LabelServiceName: "svc2",
-						},
-					},
-					AddressType: discovery.AddressTypeIPv6,
-					Endpoints: []discovery.Endpoint{
-						{
-							Addresses: []string{"fe00:10:128:1::6"},
-							NodeName:  &node1.Name,
-						},
-					},
-				}
-
 				fakeOVN.startWithDBSetup(dbSetup,
 					&v1.NamespaceList{
 						Items: []v1.Namespace{
@@ -1328,15 +2329,12 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					&v1.ServiceList{
 						Items: []v1.Service{
 							svc1,
-							svc2,
 						},
 					},
 					&discovery.EndpointSliceList{
 						Items: []discovery.EndpointSlice{
 							svc1V4EpSlice,
 							svc1V6EpSlice,
-							svc2V4EpSlice,
-							svc2V6EpSlice,
 						},
 					},
 					&egressserviceapi.EgressServiceList{
@@ -1346,84 +2344,41 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 					},
 				)
 
-				if interconnectEnabled {
-					fakeOVN.controller.zone = node1Name
-				}
+				fakeOVN.controller.zone = node1Name
 				fakeOVN.InitAndRunEgressSVCController()
 
-				svc1v4lrp1 := egressServiceRouterPolicy("svc1v4lrp1-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
-				svc1v6lrp1 := egressServiceRouterPolicy("svc1v6lrp1-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
+				ginkgo.By("the endpoints being in a remote zone static routes are created with the mgmt IP nexthop")
 				svc1v4lrsr1 := egressServiceStaticRoute("svc1v4lrsr1-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
 				svc1v6lrsr1 := egressServiceStaticRoute("svc1v6lrsr1-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
 
-				expectedDatabaseState := []libovsdbtest.TestData{}
-				if !interconnectEnabled {
-					clusterRouter.Policies = []string{"svc1v4lrp1-UUID", "svc1v6lrp1-UUID"}
-					expectedDatabaseState = []libovsdbtest.TestData{
-						clusterRouter,
-						svc1v4lrp1,
-						svc1v6lrp1,
-					}
-				} else {
-					clusterRouter.Policies = []string{}
-					clusterRouter.StaticRoutes = []string{"svc1v4lrsr1-UUID", "svc1v6lrsr1-UUID"}
-					expectedDatabaseState = []libovsdbtest.TestData{
-						clusterRouter,
-						svc1v4lrsr1,
-						svc1v6lrsr1,
-					}
+				clusterRouter.Policies = []string{}
+				clusterRouter.StaticRoutes = []string{"svc1v4lrsr1-UUID", "svc1v6lrsr1-UUID"}
+				expectedDatabaseState := []libovsdbtest.TestData{
+					clusterRouter,
+					svc1v4lrsr1,
+					svc1v6lrsr1,
 				}
-
 				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
 					expectedDatabaseState = append(expectedDatabaseState, lrp)
 					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
 				}
 				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
 
-				ginkgo.By("creating an additional egress service assigned to the second node")
-				esvc2 := egressserviceapi.EgressService{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "svc2",
-						Namespace: "testns",
-					},
-					Spec: egressserviceapi.EgressServiceSpec{
-						SourceIPBy: egressserviceapi.SourceIPLoadBalancer,
-						NodeSelector: metav1.LabelSelector{
-							MatchLabels: map[string]string{
-								"home": "moai",
-							},
-						},
-					},
-					Status: egressserviceapi.EgressServiceStatus{Host: node2Name},
-				}
-				_, err := fakeOVN.fakeClient.EgressServiceClient.K8sV1().EgressServices("testns").Create(context.TODO(), &esvc2, metav1.CreateOptions{})
+				ginkgo.By("moving the endpoints node to the local zone the static routes are replaced by policies with the mgmt IP nexthop")
+				node2.Annotations["k8s.ovn.org/zone-name"] = node1Name
+				node2.ResourceVersion = "2"
+				_, err := fakeOVN.fakeClient.KubeClient.CoreV1().Nodes().Update(context.TODO(), node2, metav1.UpdateOptions{})
 				gomega.Expect(err).ToNot(gomega.HaveOccurred())
 
-				svc2v4lrp1 := egressServiceRouterPolicy("svc2v4lrp1-UUID", "testns/svc2", "10.128.1.6", "10.128.2.2")
-				svc2v6lrp1 := egressServiceRouterPolicy("svc2v6lrp1-UUID", "testns/svc2", "fe00:10:128:1::6", "fe00:10:128:2::2")
-				svc2v4lrp1remote := egressServiceRouterPolicy("svc2v4lrp1remote-UUID", "testns/svc2", "10.128.1.6", node2transitIPv4)
-				svc2v6lrp1remote := egressServiceRouterPolicy("svc2v6lrp1remote-UUID", "testns/svc2", "fe00:10:128:1::6", node2transitIPv6)
-
-				if !interconnectEnabled {
-					clusterRouter.Policies = []string{"svc1v4lrp1-UUID", "svc1v6lrp1-UUID", "svc2v4lrp1-UUID", "svc2v6lrp1-UUID"}
-					expectedDatabaseState = []libovsdbtest.TestData{
-						clusterRouter,
-						svc1v4lrp1,
-						svc1v6lrp1,
-						svc2v4lrp1,
-						svc2v6lrp1,
-					}
-				} else {
-					clusterRouter.Policies = []string{"svc2v4lrp1remote-UUID", "svc2v6lrp1remote-UUID"}
-					clusterRouter.StaticRoutes = []string{"svc1v4lrsr1-UUID", "svc1v6lrsr1-UUID"}
-					expectedDatabaseState = []libovsdbtest.TestData{
-						clusterRouter,
-						svc1v4lrsr1,
-						svc1v6lrsr1,
-						svc2v4lrp1remote,
-						svc2v6lrp1remote,
-					}
+				svc1v4lrp1 := egressServiceRouterPolicy("svc1v4lrp1-UUID", "testns/svc1", "10.128.2.5", "10.128.1.2")
+				svc1v6lrp1 := egressServiceRouterPolicy("svc1v6lrp1-UUID", "testns/svc1", "fe00:10:128:2::5", "fe00:10:128:1::2")
 
+				clusterRouter.Policies = []string{"svc1v4lrp1-UUID", "svc1v6lrp1-UUID"}
+				clusterRouter.StaticRoutes = []string{}
+				expectedDatabaseState = []libovsdbtest.TestData{
+					clusterRouter,
+					svc1v4lrp1,
+					svc1v6lrp1,
 				}
 				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
 					expectedDatabaseState = append(expectedDatabaseState, lrp)
@@ -1431,76 +2386,30 @@ var _ = ginkgo.Describe("OVN Egress Service Operations", func() {
 				}
 				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
 
-				ginkgo.By("updating the second node host addresses the node ip no re-route address set will be updated")
-				nodeIPsASdbIDs := getEgressIPAddrSetDbIDs(NodeIPAddrSetName, DefaultNetworkControllerName)
-				fakeOVN.asf.EventuallyExpectAddressSetWithIPs(nodeIPsASdbIDs, []string{node1IPv4, node2IPv4, node1IPv6, node2IPv6})
-
-				node2.ObjectMeta.Annotations["k8s.ovn.org/host-addresses"] = fmt.Sprintf("[\"%s\", \"%s\", \"%s\", \"%s\"]", node2IPv4, node2IPv6, vipIPv4, vipIPv6)
-				node2.ResourceVersion = "3"
-				_, err = fakeOVN.fakeClient.KubeClient.CoreV1().Nodes().Update(context.TODO(), node2, metav1.UpdateOptions{})
-				gomega.Expect(err).NotTo(gomega.HaveOccurred())
-
-				fakeOVN.asf.EventuallyExpectAddressSetWithIPs(nodeIPsASdbIDs, []string{node1IPv4, node2IPv4, node1IPv6, node2IPv6, vipIPv4, vipIPv6})
-				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
-
-				node2.ObjectMeta.Annotations["k8s.ovn.org/host-addresses"] = fmt.Sprintf("[\"%s\", \"%s\"]", node2IPv4, node2IPv6)
-				node2.ResourceVersion = "4"
-				_, err = fakeOVN.fakeClient.KubeClient.CoreV1().Nodes().Update(context.TODO(), node2, metav1.UpdateOptions{})
-				gomega.Expect(err).NotTo(gomega.HaveOccurred())
-
-				fakeOVN.asf.EventuallyExpectAddressSetWithIPs(nodeIPsASdbIDs, []string{node1IPv4, node2IPv4, node1IPv6, node2IPv6})
-				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
-
-				ginkgo.By("deleting the first node, the node ip no re-route address set will be updated and service resources will be deleted")
-				err = fakeOVN.fakeClient.KubeClient.CoreV1().Nodes().Delete(context.TODO(), node1.Name, metav1.DeleteOptions{})
+				ginkgo.By("moving the service node to a remote zone the policies nexthops are updated to its transit IP")
+				node1.Annotations["k8s.ovn.org/zone-name"] = "remote"
+				node1.ResourceVersion = "2"
+				_, err = fakeOVN.fakeClient.KubeClient.CoreV1().Nodes().Update(context.TODO(), node1, metav1.UpdateOptions{})
 				gomega.Expect(err).ToNot(gomega.HaveOccurred())
 
-				fakeOVN.asf.EventuallyExpectAddressSetWithIPs(nodeIPsASdbIDs, []string{node2IPv4, node2IPv6})
-
-				if !interconnectEnabled {
-					clusterRouter.Policies = []string{"svc2v4lrp1-UUID", "svc2v6lrp1-UUID"}
-					expectedDatabaseState = []libovsdbtest.TestData{
-						clusterRouter,
-						svc2v4lrp1,
-						svc2v6lrp1,
-					}
-				} else {
-					clusterRouter.Policies = []string{"svc2v4lrp1remote-UUID", "svc2v6lrp1remote-UUID"}
-					clusterRouter.StaticRoutes = []string{}
-					expectedDatabaseState = []libovsdbtest.TestData{
-						clusterRouter,
-						svc2v4lrp1remote,
-						svc2v6lrp1remote,
-					}
-				}
-				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
-					expectedDatabaseState = append(expectedDatabaseState, lrp)
-					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
-				}
+				svc1v4lrp1.Nexthops = []string{node1transitIPv4}
+				svc1v6lrp1.Nexthops = []string{node1transitIPv6}
 				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
 
-				ginkgo.By("deleting the second node the second service's resources will be deleted")
-				err = fakeOVN.fakeClient.KubeClient.CoreV1().Nodes().Delete(context.TODO(), node2.Name, metav1.DeleteOptions{})
+				ginkgo.By("moving the service node back to the local zone the policies nexthops are updated to its mgmt IP")
+				node1.Annotations["k8s.ovn.org/zone-name"] = node1Name
+				node1.ResourceVersion = "3"
+				_, err = fakeOVN.fakeClient.KubeClient.CoreV1().Nodes().Update(context.TODO(), node1, metav1.UpdateOptions{})
 				gomega.Expect(err).ToNot(gomega.HaveOccurred())
 
-				clusterRouter.Policies = []string{}
-				clusterRouter.StaticRoutes = []string{}
-				expectedDatabaseState = []libovsdbtest.TestData{
-					clusterRouter,
-				}
-				for _, lrp := range getDefaultNoReroutePolicies(controllerName) {
-					expectedDatabaseState = append(expectedDatabaseState, lrp)
-					clusterRouter.Policies = append(clusterRouter.Policies, lrp.UUID)
-				}
+				svc1v4lrp1.Nexthops = []string{"10.128.1.2"}
+				svc1v6lrp1.Nexthops = []string{"fe00:10:128:1::2"}
 				gomega.Eventually(fakeOVN.nbClient).Should(libovsdbtest.HaveData(expectedDatabaseState))
-				fakeOVN.asf.EventuallyExpectEmptyAddressSetExist(nodeIPsASdbIDs)
 				return nil
 			}
 			err := app.Run([]string{app.Name})
 			gomega.Expect(err).ToNot(gomega.HaveOccurred())
-		},
-			ginkgotable.Entry("IC Disabled, all nodes are in a single zone", false),
-			ginkgotable.Entry("IC Enabled, node1 is in the local zone, node2 in remote", true))
+		})
 	})
 
 })
@@ -1539,7 +2448,7 @@ func egressServiceRouterPolicy(uuid, key, addr, nexthop string) *nbdb.LogicalRou
 		ExternalIDs: map[string]string{"EgressSVC": key},
 		Match:       match,
 		Nexthops:    []string{nexthop},
-		Priority:    types.EgressSVCReroutePriority,
+		Priority:    config.OVNKubernetesFeature.EgressServiceReroutePriority,
 	}
 }
 
@@ -1611,7 +2520,10 @@ func lbSvcFor(namespace, name string) v1.Service {
 			LoadBalancer: v1.LoadBalancerStatus{
 				Ingress: []v1.LoadBalancerIngress{
 					{
-						IP: "1.1.1.1", // arbitrary ip, we don't care about it for the lrps as long as it's there
+						IP: "1.1.1.1", // arbitrary ips, we don't care about them for the lrps as long as there is one per family
+					},
+					{
+						IP: "2001::1",
 					},
 				},
 			},
@@ -459,9 +459,9 @@ func (oc *DefaultNetworkController) InitEgressServiceZoneController() (*egresssv
 		deleteLegacyDefaultNoRerouteNodePolicies = DeleteLegacyDefaultNoRerouteNodePolicies
 	}
 
-	return egresssvc_zone.NewController(DefaultNetworkControllerName, oc.client, oc.nbClient, oc.addressSetFactory,
+	return egresssvc_zone.NewController(DefaultNetworkControllerName, oc.client, oc.recorder, oc.nbClient, oc.addressSetFactory,
 		initClusterEgressPolicies, ensureNodeNoReroutePolicies, deleteLegacyDefaultNoRerouteNodePolicies,
 		oc.stopChan, oc.watchFactory.EgressServiceInformer(), oc.watchFactory.ServiceCoreInformer(),
-		oc.watchFactory.EndpointSliceCoreInformer(),
+		oc.watchFactory.EndpointSliceCoreInformer(), oc.watchFactory.PodCoreInformer(),
 		oc.watchFactory.NodeCoreInformer(), oc.zone)
 }
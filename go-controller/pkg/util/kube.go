@@ -610,10 +610,28 @@ func GetEndpointAddresses(endpointSlices []*discovery.EndpointSlice, service *ka
 	return GetEndpointAddressesWithCondition(endpointSlices, service, nil)
 }
 
+// EndpointLocalityFunc returns whether an endpoint is local to the node of the given name
+type EndpointLocalityFunc func(endpoint discovery.Endpoint, nodeName string) bool
+
+// IsEndpointOnNode is the default EndpointLocalityFunc: an endpoint is local to the node it runs on
+func IsEndpointOnNode(endpoint discovery.Endpoint, nodeName string) bool {
+	return endpoint.NodeName != nil && *endpoint.NodeName == nodeName
+}
+
 // GetLocalEndpointAddresses returns a list of endpoints that are local to the specified node
 func GetLocalEndpointAddresses(endpointSlices []*discovery.EndpointSlice, service *kapi.Service, nodeName string) sets.Set[string] {
+	return GetLocalEndpointAddressesWithLocality(endpointSlices, service, nodeName, IsEndpointOnNode)
+}
+
+// GetLocalEndpointAddressesWithLocality returns a list of endpoints that are local to the specified node
+// according to isLocal, IsEndpointOnNode if nil
+func GetLocalEndpointAddressesWithLocality(endpointSlices []*discovery.EndpointSlice, service *kapi.Service, nodeName string,
+	isLocal EndpointLocalityFunc) sets.Set[string] {
+	if isLocal == nil {
+		isLocal = IsEndpointOnNode
+	}
 	return GetEndpointAddressesWithCondition(endpointSlices, service, func(endpoint discovery.Endpoint) bool {
-		return endpoint.NodeName != nil && *endpoint.NodeName == nodeName
+		return isLocal(endpoint, nodeName)
 	})
 }
 
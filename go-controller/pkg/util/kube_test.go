@@ -1023,6 +1023,51 @@ func TestGetLocalEndpointAddresses(t *testing.T) {
 	}
 }
 
+func TestGetLocalEndpointAddressesWithLocality(t *testing.T) {
+	service := getSampleService(false)
+	zoneA, zoneB := "zone-a", "zone-b"
+	byZone := func(zone string) EndpointLocalityFunc {
+		return func(endpoint discovery.Endpoint, nodeName string) bool {
+			return endpoint.Zone != nil && *endpoint.Zone == zone
+		}
+	}
+	endpointSlice := setAllEndpointsToReady(getSampleEndpointSlice(service))
+	// ep1 runs on the node but in another zone, ep3 on another node in the node zone
+	endpointSlice.Endpoints[0].Zone = &zoneB
+	endpointSlice.Endpoints[1].Zone = &zoneA
+	endpointSlice.Endpoints[2].Zone = &zoneA
+	var tests = []struct {
+		name    string
+		isLocal EndpointLocalityFunc
+		want    sets.Set[string]
+	}{
+		{
+			"Tests the default node name locality",
+			nil,
+			sets.New(ep1Address, ep2Address),
+		},
+		{
+			"Tests a zone locality",
+			byZone(zoneA),
+			sets.New(ep2Address, ep3Address),
+		},
+		{
+			"Tests a locality that selects no endpoint",
+			byZone("zone-c"),
+			sets.New[string](),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer := GetLocalEndpointAddressesWithLocality([]*discovery.EndpointSlice{endpointSlice}, service, testNode, tt.isLocal)
+			if !reflect.DeepEqual(answer, tt.want) {
+				t.Errorf("got %v, want %v", answer, tt.want)
+			}
+		})
+	}
+}
+
 func TestDoesEndpointSliceContainEndpoint(t *testing.T) {
 	service := getSampleService(false)
 	var tests = []struct {
@@ -391,53 +391,135 @@ func LinkNeighExists(link netlink.Link, neighIP net.IP, neighMAC net.HardwareAdd
 }
 
 func DeleteConntrack(ip string, port int32, protocol kapi.Protocol, ipFilterType netlink.ConntrackFilterType, labels [][]byte) error {
+	filter, family, err := newConntrackFilter(ip, port, protocol, ipFilterType, labels)
+	if err != nil {
+		return err
+	}
+	_, err = netLinkOps.ConntrackDeleteFilter(netlink.ConntrackTable, family, filter)
+	return err
+}
+
+// newConntrackFilter returns the conntrack filter matching the given IP, port,
+// protocol and labels, along with the family of the IP
+func newConntrackFilter(ip string, port int32, protocol kapi.Protocol, ipFilterType netlink.ConntrackFilterType,
+	labels [][]byte) (*netlink.ConntrackFilter, netlink.InetFamily, error) {
 	ipAddress := net.ParseIP(ip)
 	if ipAddress == nil {
-		return fmt.Errorf("value %q passed to DeleteConntrack is not an IP address", ipAddress)
+		return nil, 0, fmt.Errorf("value %q passed to DeleteConntrack is not an IP address", ipAddress)
 	}
 
 	filter := &netlink.ConntrackFilter{}
 	if protocol == kapi.ProtocolUDP {
 		// 17 = UDP protocol
 		if err := filter.AddProtocol(17); err != nil {
-			return fmt.Errorf("could not add Protocol UDP to conntrack filter %v", err)
+			return nil, 0, fmt.Errorf("could not add Protocol UDP to conntrack filter %v", err)
 		}
 	} else if protocol == kapi.ProtocolSCTP {
 		// 132 = SCTP protocol
 		if err := filter.AddProtocol(132); err != nil {
-			return fmt.Errorf("could not add Protocol SCTP to conntrack filter %v", err)
+			return nil, 0, fmt.Errorf("could not add Protocol SCTP to conntrack filter %v", err)
 		}
 	} else if protocol == kapi.ProtocolTCP {
 		// 6 = TCP protocol
 		if err := filter.AddProtocol(6); err != nil {
-			return fmt.Errorf("could not add Protocol TCP to conntrack filter %v", err)
+			return nil, 0, fmt.Errorf("could not add Protocol TCP to conntrack filter %v", err)
 		}
 	}
 	if port > 0 {
 		if err := filter.AddPort(netlink.ConntrackOrigDstPort, uint16(port)); err != nil {
-			return fmt.Errorf("could not add port %d to conntrack filter: %v", port, err)
+			return nil, 0, fmt.Errorf("could not add port %d to conntrack filter: %v", port, err)
 		}
 	}
 	if err := filter.AddIP(ipFilterType, ipAddress); err != nil {
-		return fmt.Errorf("could not add IP: %s to conntrack filter: %v", ipAddress, err)
+		return nil, 0, fmt.Errorf("could not add IP: %s to conntrack filter: %v", ipAddress, err)
 	}
 
 	if len(labels) > 0 {
 		// for now we only need unmatch label, we can add match label later if needed
 		if err := filter.AddLabels(netlink.ConntrackUnmatchLabels, labels); err != nil {
-			return fmt.Errorf("could not add label %s to conntrack filter: %v", labels, err)
+			return nil, 0, fmt.Errorf("could not add label %s to conntrack filter: %v", labels, err)
 		}
 	}
 	if ipAddress.To4() != nil {
-		if _, err := netLinkOps.ConntrackDeleteFilter(netlink.ConntrackTable, netlink.FAMILY_V4, filter); err != nil {
-			return err
+		return filter, netlink.FAMILY_V4, nil
+	}
+	return filter, netlink.FAMILY_V6, nil
+}
+
+// chunkedConntrackFilter matches at most limit of the conntrack flows matched
+// by filter
+type chunkedConntrackFilter struct {
+	filter  netlink.CustomConntrackFilter
+	limit   uint
+	matched uint
+}
+
+func (f *chunkedConntrackFilter) MatchConntrackFlow(flow *netlink.ConntrackFlow) bool {
+	if f.matched >= f.limit || !f.filter.MatchConntrackFlow(flow) {
+		return false
+	}
+	f.matched++
+	return true
+}
+
+// limitConntrackFilter returns a filter matching at most limit of the conntrack
+// flows matched by filter, all of them if limit is 0
+func limitConntrackFilter(filter netlink.CustomConntrackFilter, limit uint) netlink.CustomConntrackFilter {
+	if limit == 0 {
+		return filter
+	}
+	return &chunkedConntrackFilter{filter: filter, limit: limit}
+}
+
+// DeleteConntrackUpTo is like DeleteConntrack but deletes at most limit
+// conntrack entries, all of them if limit is 0, and returns the number of
+// deleted entries.
+func DeleteConntrackUpTo(ip string, port int32, protocol kapi.Protocol, ipFilterType netlink.ConntrackFilterType,
+	labels [][]byte, limit uint) (uint, error) {
+	filter, family, err := newConntrackFilter(ip, port, protocol, ipFilterType, labels)
+	if err != nil {
+		return 0, err
+	}
+	return netLinkOps.ConntrackDeleteFilter(netlink.ConntrackTable, family, limitConntrackFilter(filter, limit))
+}
+
+// DeleteConntrackInChunks is like DeleteConntrackUpTo but deletes at most
+// chunkSize conntrack entries at a time, waiting interval between chunks, to
+// spread the deletion of many entries over time. Once the deadline is reached,
+// all the remaining entries, up to limit, are deleted at once.
+func DeleteConntrackInChunks(ip string, port int32, protocol kapi.Protocol, ipFilterType netlink.ConntrackFilterType,
+	labels [][]byte, chunkSize uint, interval time.Duration, deadline time.Time, limit uint) (uint, error) {
+	filter, family, err := newConntrackFilter(ip, port, protocol, ipFilterType, labels)
+	if err != nil {
+		return 0, err
+	}
+	var total uint
+	for {
+		// total is below limit here, so remaining is 0 only if there is no limit
+		var remaining uint
+		if limit > 0 {
+			remaining = limit - total
 		}
-	} else {
-		if _, err := netLinkOps.ConntrackDeleteFilter(netlink.ConntrackTable, netlink.FAMILY_V6, filter); err != nil {
-			return err
+		if !time.Now().Before(deadline) {
+			deleted, err := netLinkOps.ConntrackDeleteFilter(netlink.ConntrackTable, family,
+				limitConntrackFilter(filter, remaining))
+			return total + deleted, err
 		}
+		chunk := chunkSize
+		if remaining > 0 && remaining < chunk {
+			chunk = remaining
+		}
+		deleted, err := netLinkOps.ConntrackDeleteFilter(netlink.ConntrackTable, family,
+			&chunkedConntrackFilter{filter: filter, limit: chunk})
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		if deleted < chunk || (limit > 0 && total >= limit) {
+			return total, nil
+		}
+		time.Sleep(interval)
 	}
-	return nil
 }
 
 // DeleteConntrackServicePort is a wrapper around DeleteConntrack for the purpose of deleting conntrack entries that
@@ -453,6 +535,54 @@ func DeleteConntrackServicePort(ip string, port int32, protocol kapi.Protocol, i
 	return DeleteConntrack(ip, port, protocol, ipFilterType, labels)
 }
 
+// DeleteConntrackServicePortUpTo is like DeleteConntrackServicePort but deletes
+// at most limit conntrack entries, see DeleteConntrackUpTo.
+func DeleteConntrackServicePortUpTo(ip string, port int32, protocol kapi.Protocol, ipFilterType netlink.ConntrackFilterType,
+	labels [][]byte, limit uint) (uint, error) {
+	if err := ValidatePort(protocol, port); err != nil {
+		klog.V(5).Infof("Skipping conntrack deletion for IP %q, protocol %q, port \"%d\", err: %q",
+			ip, protocol, port, err)
+		return 0, nil
+	}
+	return DeleteConntrackUpTo(ip, port, protocol, ipFilterType, labels, limit)
+}
+
+// DeleteConntrackServicePortInChunks is like DeleteConntrackServicePortUpTo but
+// deletes the conntrack entries in chunks, see DeleteConntrackInChunks.
+func DeleteConntrackServicePortInChunks(ip string, port int32, protocol kapi.Protocol, ipFilterType netlink.ConntrackFilterType,
+	labels [][]byte, chunkSize uint, interval time.Duration, deadline time.Time, limit uint) (uint, error) {
+	if err := ValidatePort(protocol, port); err != nil {
+		klog.V(5).Infof("Skipping conntrack deletion for IP %q, protocol %q, port \"%d\", err: %q",
+			ip, protocol, port, err)
+		return 0, nil
+	}
+	return DeleteConntrackInChunks(ip, port, protocol, ipFilterType, labels, chunkSize, interval, deadline, limit)
+}
+
+// DeleteConntrackServiceEndpoint deletes the conntrack entries of the connections
+// towards the given service IP and port that were DNATed to the given endpoint
+// IP, and returns the number of deleted entries. Like DeleteConntrackServicePort,
+// it simply returns if the port is invalid.
+func DeleteConntrackServiceEndpoint(svcIP string, port int32, protocol kapi.Protocol, endpointIP string) (uint, error) {
+	if err := ValidatePort(protocol, port); err != nil {
+		klog.V(5).Infof("Skipping conntrack deletion for IP %q, protocol %q, port \"%d\", err: %q",
+			svcIP, protocol, port, err)
+		return 0, nil
+	}
+	filter, family, err := newConntrackFilter(svcIP, port, protocol, netlink.ConntrackOrigDstIP, nil)
+	if err != nil {
+		return 0, err
+	}
+	endpointAddress := net.ParseIP(endpointIP)
+	if endpointAddress == nil {
+		return 0, fmt.Errorf("value %q passed to DeleteConntrackServiceEndpoint is not an IP address", endpointIP)
+	}
+	if err := filter.AddIP(netlink.ConntrackReplySrcIP, endpointAddress); err != nil {
+		return 0, fmt.Errorf("could not add endpoint IP: %s to conntrack filter: %v", endpointAddress, err)
+	}
+	return netLinkOps.ConntrackDeleteFilter(netlink.ConntrackTable, family, filter)
+}
+
 // GetNetworkInterfaceIPs returns the IP addresses for the network interface 'iface'.
 // We filter out addresses that are link local, reserved for internal use or added by keepalived.
 func GetNetworkInterfaceIPs(iface string) ([]*net.IPNet, error) {
@@ -483,6 +613,25 @@ func GetNetworkInterfaceIPs(iface string) ([]*net.IPNet, error) {
 	return ips, nil
 }
 
+// GetHostIPs returns the IP addresses of all the network interfaces of the host,
+// secondary ones included. We filter out addresses that are loopback, link local or
+// reserved for internal use.
+func GetHostIPs() ([]net.IP, error) {
+	addrs, err := netLinkOps.AddrList(nil, netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list host addresses: %v", err)
+	}
+
+	var ips []net.IP
+	for _, addr := range addrs {
+		if addr.IP.IsLoopback() || addr.IP.IsLinkLocalUnicast() || IsAddressReservedForInternalUse(addr.IP) {
+			continue
+		}
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
 func IsAddressReservedForInternalUse(addr net.IP) bool {
 	var subnetStr string
 	if addr.To4() != nil {
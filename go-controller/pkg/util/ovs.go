@@ -657,6 +657,46 @@ func ReplaceOFFlows(bridgeName string, flows []string) (string, string, error) {
 	return strings.Trim(stdout.String(), "\" \n"), stderr.String(), err
 }
 
+// ReplaceOFGroups replaces the groups of the bridge with the given ones
+func ReplaceOFGroups(bridgeName string, groups []string) (string, string, error) {
+	args := []string{"-O", "OpenFlow13", "replace-groups", bridgeName, "-"}
+	stdin := &bytes.Buffer{}
+	stdin.Write([]byte(strings.Join(groups, "\n")))
+
+	cmd := runner.exec.Command(runner.ofctlPath, args...)
+	cmd.SetStdin(stdin)
+	stdout, stderr, err := runCmd(cmd, runner.ofctlPath, args...)
+	return strings.Trim(stdout.String(), "\" \n"), stderr.String(), err
+}
+
+// SetOFMeter adds the given meter to the bridge, or modifies it if it already
+// exists. Deleting and re-adding it instead would delete the flows using it.
+func SetOFMeter(bridgeName, meter string) (string, string, error) {
+	stdout, _, err := RunOVSOfctl("-O", "OpenFlow13", "add-meter", bridgeName, meter)
+	if err == nil {
+		return stdout, "", nil
+	}
+	return RunOVSOfctl("-O", "OpenFlow13", "mod-meter", bridgeName, meter)
+}
+
+// GetOFFlows returns the flows of the bridge, without their statistics and
+// with port numbers instead of names
+func GetOFFlows(bridgeName string) ([]string, error) {
+	stdout, stderr, err := RunOVSOfctl("-O", "OpenFlow13", "--no-stats", "--no-names", "dump-flows", bridgeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump the flows of bridge %q, stderr: %q, error: %v",
+			bridgeName, stderr, err)
+	}
+	var flows []string
+	for _, line := range strings.Split(stdout, "\n") {
+		// skip the reply header
+		if line = strings.TrimSpace(line); strings.Contains(line, "actions=") {
+			flows = append(flows, line)
+		}
+	}
+	return flows, nil
+}
+
 // Get OpenFlow Port names or numbers for a given bridge
 func GetOpenFlowPorts(bridgeName string, namedPorts bool) ([]string, error) {
 	stdout, stderr, err := RunOVSOfctl("show", bridgeName)